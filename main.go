@@ -14,6 +14,7 @@ import (
 
 	"github.com/nginx/agent/sdk/v2/agent/events"
 	sdkGRPC "github.com/nginx/agent/sdk/v2/grpc"
+	"github.com/nginx/agent/v2/src/cli"
 	"github.com/nginx/agent/v2/src/core"
 	"github.com/nginx/agent/v2/src/core/config"
 	"github.com/nginx/agent/v2/src/core/logger"
@@ -85,12 +86,15 @@ func main() {
 		corePlugins, extensionPlugins := plugins.LoadPlugins(commander, binary, env, reporter, loadedConfig, eventMeta)
 
 		pipe := core.InitializePipe(ctx, corePlugins, extensionPlugins, loadedConfig.QueueSize)
+		pipe.GetStats().SetSlowSubscriberDeadline(loadedConfig.MessagePipe.SlowSubscriberDeadline)
 		pipe.Process(core.NewMessage(core.AgentStarted, eventMeta))
 		core.HandleSignals(ctx, commander, loadedConfig, env, pipe, cancel, controller)
 
 		pipe.Run()
 	})
 
+	config.RegisterCommands(cli.Commands(env)...)
+
 	if err := config.Execute(); err != nil {
 		log.Fatal(err)
 	}
@@ -32,6 +32,7 @@ const (
 	FeatureFileWatcherThrottle = "file-watch-throttle"
 	FeatureActivityEvents      = "activity-events"
 	FeatureAgentAPI            = "agent-api"
+	FeatureNginxLifecycle      = "nginx-lifecycle"
 
 	CommanderPlugin    = "commander"
 	ConfigReaderPlugin = "config-reader-plugin"
@@ -50,6 +51,7 @@ const (
 	AdvancedMetricsExtensionPluginConfigKey           = "advanced_metrics"
 	NginxAppProtectExtensionPluginConfigKey           = "nginx_app_protect"
 	NginxAppProtectMonitoringExtensionPluginConfigKey = "nap_monitoring"
+	PhpFpmMetricsExtensionPluginConfigKey             = "php_fpm_metrics"
 )
 
 func GetKnownExtensions() []string {
@@ -993,6 +993,157 @@ func GetErrorAndAccessLogsWithIgnoreDirectives(confFile string, ignoreDirectives
 	return nginxConfig.ErrorLogs, nginxConfig.AccessLogs, err
 }
 
+// GetUpstreamServers returns the addresses of the servers of each upstream block in the NGINX
+// configuration, keyed by the name of the upstream
+func GetUpstreamServers(confFile string, ignoreDirectives []string) (map[string][]string, error) {
+	payload, err := crossplane.Parse(confFile,
+		&crossplane.ParseOptions{
+			IgnoreDirectives:   ignoreDirectives,
+			SingleFile:         false,
+			StopParsingOnError: true,
+			CombineConfigs:     true,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config from %s, error: %s", confFile, err)
+	}
+
+	upstreams := make(map[string][]string)
+	for _, xpConf := range payload.Config {
+		err = CrossplaneConfigTraverse(&xpConf,
+			func(parent *crossplane.Directive, current *crossplane.Directive) (bool, error) {
+				if current.Directive != "upstream" || len(current.Args) == 0 {
+					return true, nil
+				}
+				servers, ok := upstreams[current.Args[0]]
+				if !ok {
+					servers = []string{}
+				}
+				for _, directive := range current.Block {
+					if directive.Directive == "server" && len(directive.Args) > 0 {
+						servers = append(servers, directive.Args[0])
+					}
+				}
+				upstreams[current.Args[0]] = servers
+				return true, nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return upstreams, nil
+}
+
+// GetCertificateFiles returns the certificate files referenced by the ssl_certificate, proxy_ssl_certificate,
+// ssl_client_certificate, ssl_trusted_certificate and proxy_ssl_trusted_certificate directives of an NGINX
+// configuration, keyed by the file with the first directive referencing it. Files with variables in their
+// path are skipped.
+func GetCertificateFiles(confFile string, ignoreDirectives []string) (map[string]string, error) {
+	payload, err := crossplane.Parse(confFile,
+		&crossplane.ParseOptions{
+			IgnoreDirectives:   ignoreDirectives,
+			SingleFile:         false,
+			StopParsingOnError: true,
+			CombineConfigs:     true,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config from %s, error: %s", confFile, err)
+	}
+
+	certificates := make(map[string]string)
+	for _, xpConf := range payload.Config {
+		err = CrossplaneConfigTraverse(&xpConf,
+			func(parent *crossplane.Directive, current *crossplane.Directive) (bool, error) {
+				switch current.Directive {
+				case "ssl_certificate", "proxy_ssl_certificate", "ssl_client_certificate", "ssl_trusted_certificate", "proxy_ssl_trusted_certificate":
+				default:
+					return true, nil
+				}
+				if len(current.Args) == 0 || strings.Contains(current.Args[0], "$") || strings.HasPrefix(current.Args[0], "data:") {
+					return true, nil
+				}
+
+				file := current.Args[0]
+				if !filepath.IsAbs(file) {
+					file = filepath.Join(filepath.Dir(confFile), file)
+				}
+				if _, ok := certificates[file]; !ok {
+					certificates[file] = current.Directive
+				}
+				return true, nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return certificates, nil
+}
+
+// GetCertificateKeyPairs returns the server certificate files referenced by the ssl_certificate
+// directives of an NGINX config, with the file of the ssl_certificate_key directive at the same
+// position in the same block as value. Certificates loaded from variables or data: values, and
+// certificates without a key in the same block, are skipped.
+func GetCertificateKeyPairs(confFile string, ignoreDirectives []string) (map[string]string, error) {
+	payload, err := crossplane.Parse(confFile,
+		&crossplane.ParseOptions{
+			IgnoreDirectives:   ignoreDirectives,
+			SingleFile:         false,
+			StopParsingOnError: true,
+			CombineConfigs:     true,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config from %s, error: %s", confFile, err)
+	}
+
+	certificates := make(map[*crossplane.Directive][]string)
+	keys := make(map[*crossplane.Directive][]string)
+	for _, xpConf := range payload.Config {
+		err = CrossplaneConfigTraverse(&xpConf,
+			func(parent *crossplane.Directive, current *crossplane.Directive) (bool, error) {
+				if len(current.Args) == 0 {
+					return true, nil
+				}
+				switch current.Directive {
+				case "ssl_certificate":
+					certificates[parent] = append(certificates[parent], current.Args[0])
+				case "ssl_certificate_key":
+					keys[parent] = append(keys[parent], current.Args[0])
+				}
+				return true, nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pairs := make(map[string]string)
+	for block, blockCertificates := range certificates {
+		for i, certificate := range blockCertificates {
+			if i >= len(keys[block]) {
+				break
+			}
+			key := keys[block][i]
+			if strings.Contains(certificate, "$") || strings.HasPrefix(certificate, "data:") ||
+				strings.Contains(key, "$") || strings.HasPrefix(key, "data:") {
+				continue
+			}
+			if !filepath.IsAbs(certificate) {
+				certificate = filepath.Join(filepath.Dir(confFile), certificate)
+			}
+			if !filepath.IsAbs(key) {
+				key = filepath.Join(filepath.Dir(confFile), key)
+			}
+			pairs[certificate] = key
+		}
+	}
+
+	return pairs, nil
+}
+
 // to ignore directives use GetErrorAndAccessLogsWithIgnoreDirectives()
 func GetErrorAndAccessLogs(confFile string) (*proto.ErrorLogs, *proto.AccessLogs, error) {
 	return GetErrorAndAccessLogsWithIgnoreDirectives(confFile, []string{})
@@ -1416,6 +1416,141 @@ func TestGetErrorAndAccessLogs(t *testing.T) {
 	}
 }
 
+func TestGetUpstreamServers(t *testing.T) {
+	err := setUpDirectories()
+	require.NoError(t, err)
+	defer tearDownDirectories()
+
+	confFile := "/tmp/testdata/nginx/upstreams.conf"
+	err = setUpFile(confFile, []byte(`daemon            off;
+events {
+    worker_connections  1024;
+}
+http {
+    upstream backend {
+        zone backend 64k;
+        server 127.0.0.1:8080 weight=5;
+        server 127.0.0.1:8081 max_fails=3;
+        server unix:/tmp/backend.sock;
+    }
+    upstream empty {
+    }
+    server {
+        listen       8089;
+        location / {
+            proxy_pass http://backend;
+        }
+    }
+}
+stream {
+    upstream dns {
+        server 10.0.0.1:53;
+    }
+}
+`))
+	require.NoError(t, err)
+
+	upstreams, err := GetUpstreamServers(confFile, []string{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"backend": {"127.0.0.1:8080", "127.0.0.1:8081", "unix:/tmp/backend.sock"},
+		"empty":   {},
+		"dns":     {"10.0.0.1:53"},
+	}, upstreams)
+
+	_, err = GetUpstreamServers("/tmp/testdata/nginx/missing.conf", []string{})
+	assert.Error(t, err)
+}
+
+func TestGetCertificateFiles(t *testing.T) {
+	err := setUpDirectories()
+	require.NoError(t, err)
+	defer tearDownDirectories()
+
+	confFile := "/tmp/testdata/nginx/certificates.conf"
+	err = setUpFile(confFile, []byte(`daemon            off;
+events {
+    worker_connections  1024;
+}
+http {
+    ssl_certificate /etc/nginx/certs/example.crt;
+    ssl_certificate_key /etc/nginx/certs/example.key;
+    server {
+        listen       443 ssl;
+        ssl_certificate certs/server.crt;
+        ssl_client_certificate /etc/nginx/certs/clients.crt;
+        location / {
+            proxy_ssl_certificate /etc/nginx/certs/example.crt;
+            proxy_ssl_trusted_certificate /etc/nginx/certs/upstream-ca.crt;
+            proxy_pass https://backend;
+        }
+    }
+    server {
+        listen       8443 ssl;
+        ssl_certificate $ssl_server_name.crt;
+        ssl_certificate data:$certificate;
+        ssl_trusted_certificate /etc/nginx/certs/ca.crt;
+    }
+}
+`))
+	require.NoError(t, err)
+
+	certificates, err := GetCertificateFiles(confFile, []string{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"/etc/nginx/certs/example.crt":         "ssl_certificate",
+		"/tmp/testdata/nginx/certs/server.crt": "ssl_certificate",
+		"/etc/nginx/certs/clients.crt":         "ssl_client_certificate",
+		"/etc/nginx/certs/upstream-ca.crt":     "proxy_ssl_trusted_certificate",
+		"/etc/nginx/certs/ca.crt":              "ssl_trusted_certificate",
+	}, certificates)
+
+	_, err = GetCertificateFiles("/tmp/testdata/nginx/missing.conf", []string{})
+	assert.Error(t, err)
+}
+
+func TestGetCertificateKeyPairs(t *testing.T) {
+	err := setUpDirectories()
+	require.NoError(t, err)
+	defer tearDownDirectories()
+
+	confFile := "/tmp/testdata/nginx/certificate-keys.conf"
+	err = setUpFile(confFile, []byte(`daemon            off;
+events {
+    worker_connections  1024;
+}
+http {
+    ssl_certificate /etc/nginx/certs/example.crt;
+    ssl_certificate_key /etc/nginx/certs/example.key;
+    server {
+        listen       443 ssl;
+        ssl_certificate certs/rsa.crt;
+        ssl_certificate_key certs/rsa.key;
+        ssl_certificate /etc/nginx/certs/ecdsa.crt;
+        ssl_certificate_key /etc/nginx/certs/ecdsa.key;
+    }
+    server {
+        listen       8443 ssl;
+        ssl_certificate $ssl_server_name.crt;
+        ssl_certificate_key $ssl_server_name.key;
+        ssl_certificate /etc/nginx/certs/without-key.crt;
+    }
+}
+`))
+	require.NoError(t, err)
+
+	pairs, err := GetCertificateKeyPairs(confFile, []string{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"/etc/nginx/certs/example.crt":      "/etc/nginx/certs/example.key",
+		"/tmp/testdata/nginx/certs/rsa.crt": "/tmp/testdata/nginx/certs/rsa.key",
+		"/etc/nginx/certs/ecdsa.crt":        "/etc/nginx/certs/ecdsa.key",
+	}, pairs)
+
+	_, err = GetCertificateKeyPairs("/tmp/testdata/nginx/missing.conf", []string{})
+	assert.Error(t, err)
+}
+
 func TestGetAccessLogs(t *testing.T) {
 	result := GetAccessLogs(accessLogs)
 	assert.Equal(t, []string{"/tmp/testdata/logs/access1.log", "/tmp/testdata/logs/access2.log", "/tmp/testdata/logs/access3.log"}, result)
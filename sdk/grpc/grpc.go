@@ -124,6 +124,17 @@ func GetCommandChannel(client proto.CommanderClient) (proto.Commander_CommandCha
 }
 
 func getTransportCredentials(certPath string, keyPath string, caPath string, serverName string, skipVerify bool) (credentials.TransportCredentials, error) {
+	tlsConfig, err := NewTLSConfig(certPath, keyPath, caPath, serverName, skipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// NewTLSConfig returns a tls Config with the CA and the cert and key pair found at the
+// provided filepaths, for clients that are not using gRPC transport credentials
+func NewTLSConfig(certPath string, keyPath string, caPath string, serverName string, skipVerify bool) (*tls.Config, error) {
 	tlsConfig := &tls.Config{
 		// note: ServerName is ignored if InsecureSkipVerify is true
 		ServerName:         serverName,
@@ -140,7 +151,7 @@ func getTransportCredentials(certPath string, keyPath string, caPath string, ser
 		return nil, err
 	}
 
-	return credentials.NewTLS(tlsConfig), nil
+	return tlsConfig, nil
 }
 
 // appendRootCAs will read, parse, and append any certificates found in the
@@ -33,7 +33,7 @@ const (
 	NginxConfigAction_APPLY NginxConfigAction = 1
 	// Test config action (This will be implemented in a future release)
 	NginxConfigAction_TEST NginxConfigAction = 2
-	// Rollback config action (This will be implemented in a future release)
+	// Rollback config action, applies the stored config revision identified by the config descriptor checksum
 	NginxConfigAction_ROLLBACK NginxConfigAction = 3
 	// Return config action (This will be implemented in a future release)
 	NginxConfigAction_RETURN NginxConfigAction = 4
@@ -0,0 +1,96 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+// Package cli implements the nginx-agent subcommands used for offline diagnostics. The
+// subcommands load the agent configuration and inspect the host directly, they never start the
+// message pipe or connect to the management server.
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+)
+
+const (
+	formatFlag = "format"
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// Commands returns the diagnostic subcommands of the agent
+func Commands(env core.Environment) []*cobra.Command {
+	return []*cobra.Command{
+		newNginxCommand(env),
+		newConfigCommand(env),
+		newMetricsCommand(env),
+		newSupportBundleCommand(env),
+	}
+}
+
+func addFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().String(formatFlag, formatText, "Output format, one of: text, json")
+}
+
+func getFormat(cmd *cobra.Command) (string, error) {
+	format, err := cmd.Flags().GetString(formatFlag)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case formatText, formatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported output format %q, must be one of: %s, %s", format, formatText, formatJSON)
+	}
+}
+
+// loadConfig reads the agent configuration files the same way the agent does on startup. The
+// agent logs are written to stderr so that they don't get mixed with the command output.
+func loadConfig(env core.Environment) (*config.Config, error) {
+	log.SetOutput(os.Stderr)
+
+	// the agent exits on invalid config files, so they are parsed first to report the error instead
+	cfgPath, dynamicCfgPath, err := config.ConfigFiles()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.ParseConfigFile(cfgPath); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", cfgPath, err)
+	}
+	if _, statErr := os.Stat(dynamicCfgPath); statErr == nil {
+		if err := config.ParseConfigFile(dynamicCfgPath); err != nil {
+			return nil, fmt.Errorf("invalid dynamic config file %s: %w", dynamicCfgPath, err)
+		}
+	}
+
+	config.InitConfigurationFiles()
+
+	conf, err := config.GetConfig(env.GetSystemUUID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	log.SetLevel(log.WarnLevel)
+	if level, err := log.ParseLevel(conf.Log.Level); err == nil && level > log.WarnLevel {
+		log.SetLevel(level)
+	}
+
+	return conf, nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,260 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	agent_config "github.com/nginx/agent/sdk/v2/agent/config"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+)
+
+const (
+	issueError   = "error"
+	issueWarning = "warning"
+)
+
+type validationIssue struct {
+	Level   string `json:"level"`
+	Setting string `json:"setting"`
+	Message string `json:"message"`
+}
+
+type validationResult struct {
+	Valid  bool              `json:"valid"`
+	Issues []validationIssue `json:"issues"`
+}
+
+func newConfigCommand(env core.Environment) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the agent configuration.",
+	}
+
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the agent configuration files, flags and environment variables.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			format, err := getFormat(cmd)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+
+			var issues []validationIssue
+			conf, err := loadConfig(env)
+			if err != nil {
+				issues = []validationIssue{{Level: issueError, Setting: "config_path", Message: err.Error()}}
+			} else {
+				issues = validateConfig(conf, config.Viper.GetStringSlice(agent_config.ExtensionsKey))
+			}
+
+			if err := writeValidationResult(cmd.OutOrStdout(), format, issues); err != nil {
+				return err
+			}
+			for _, issue := range issues {
+				if issue.Level == issueError {
+					return fmt.Errorf("the agent configuration is not valid")
+				}
+			}
+			return nil
+		},
+	}
+	addFormatFlag(validateCmd)
+	configCmd.AddCommand(validateCmd)
+
+	return configCmd
+}
+
+// validateConfig checks the loaded agent configuration for settings that would make the agent
+// fail at runtime. The configured extensions are passed separately, since unknown extensions are
+// dropped while loading the configuration.
+func validateConfig(conf *config.Config, extensions []string) []validationIssue {
+	issues := []validationIssue{}
+	addIssue := func(level, setting, format string, args ...interface{}) {
+		issues = append(issues, validationIssue{Level: level, Setting: setting, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, extension := range extensions {
+		if !agent_config.IsKnownExtension(extension) {
+			addIssue(issueWarning, agent_config.ExtensionsKey, "unknown extension %s will be ignored", extension)
+		}
+	}
+
+	if _, err := log.ParseLevel(conf.Log.Level); err != nil {
+		addIssue(issueError, config.LogLevel, "%v", err)
+	}
+
+	if conf.Server.Host != "" && conf.Server.GrpcPort == 0 {
+		addIssue(issueError, config.ServerGrpcPort, "server host %s is set without a gRPC port", conf.Server.Host)
+	}
+
+	if conf.TLS.Enable {
+		issues = append(issues, validateCertFiles(config.TlsCert, conf.TLS.Cert, config.TlsPrivateKey, conf.TLS.Key)...)
+		if conf.TLS.Ca != "" && !fileExists(conf.TLS.Ca) {
+			addIssue(issueError, config.TlsCa, "file %s does not exist", conf.TLS.Ca)
+		}
+	}
+
+	if conf.AgentAPI.Port != 0 {
+		issues = append(issues, validateCertFiles(config.AgentAPICert, conf.AgentAPI.Cert, config.AgentAPIKey, conf.AgentAPI.Key)...)
+		issues = append(issues, validateAgentAPIAuth(conf.AgentAPI)...)
+	}
+
+	if conf.AgentMetrics.CollectionInterval <= 0 {
+		addIssue(issueError, config.MetricsCollectionInterval, "collection interval must be greater than zero")
+	} else if conf.AgentMetrics.ReportInterval < conf.AgentMetrics.CollectionInterval {
+		addIssue(issueWarning, config.MetricsReportInterval, "report interval %v is shorter than the collection interval %v", conf.AgentMetrics.ReportInterval, conf.AgentMetrics.CollectionInterval)
+	}
+
+	for _, dir := range strings.Split(conf.ConfigDirs, ":") {
+		if dir != "" && !fileExists(dir) {
+			addIssue(issueWarning, config.ConfigDirsKey, "directory %s does not exist", dir)
+		}
+	}
+
+	if conf.OTLP.Endpoint != "" && conf.OTLP.Protocol != "grpc" && conf.OTLP.Protocol != "http" {
+		addIssue(issueError, config.OTLPProtocol, "unsupported protocol %q, must be one of: grpc, http", conf.OTLP.Protocol)
+	}
+
+	if conf.CertificateMonitor.Enable {
+		if conf.CertificateMonitor.CheckInterval <= 0 {
+			addIssue(issueError, config.CertificateMonitorCheckInterval, "check interval must be greater than zero")
+		}
+		if conf.CertificateMonitor.CriticalDays > conf.CertificateMonitor.WarningDays {
+			addIssue(issueWarning, config.CertificateMonitorCriticalDays, "critical days %d are more than the warning days %d, no warning events will be raised", conf.CertificateMonitor.CriticalDays, conf.CertificateMonitor.WarningDays)
+		}
+	}
+
+	if conf.ACME.Enable {
+		if directoryURL, err := url.Parse(conf.ACME.DirectoryURL); err != nil || directoryURL.Scheme != "https" || directoryURL.Host == "" {
+			addIssue(issueError, config.ACMEDirectoryURL, "directory URL %q must be an https URL", conf.ACME.DirectoryURL)
+		}
+		if conf.ACME.AccountKey == "" {
+			addIssue(issueError, config.ACMEAccountKey, "account key file must be set")
+		}
+		if conf.ACME.ChallengeDir == "" {
+			addIssue(issueError, config.ACMEChallengeDir, "challenge directory must be set")
+		}
+		if conf.ACME.SnippetPath == "" {
+			addIssue(issueError, config.ACMESnippetPath, "snippet path must be set")
+		}
+		if conf.ACME.CheckInterval <= 0 {
+			addIssue(issueError, config.ACMECheckInterval, "check interval must be greater than zero")
+		}
+		if conf.ACME.RenewBefore <= 0 {
+			addIssue(issueError, config.ACMERenewBefore, "renew before must be greater than zero")
+		}
+		if conf.ACME.Email == "" {
+			addIssue(issueWarning, config.ACMEEmail, "no contact email is set, the ACME server can't send expiry notices")
+		}
+		if len(conf.ACME.Certificates) == 0 {
+			addIssue(issueWarning, config.ACMECertificates, "no certificates are set, no certificates are renewed")
+		}
+		if conf.ACME.Ca != "" {
+			if _, err := os.Stat(conf.ACME.Ca); err != nil {
+				addIssue(issueError, config.ACMECa, "unable to read %s: %v", conf.ACME.Ca, err)
+			}
+		}
+	}
+
+	if conf.QueueSize <= 0 {
+		addIssue(issueError, config.QueueSizeKey, "queue size must be greater than zero")
+	}
+
+	return issues
+}
+
+func validateCertFiles(certSetting, cert, keySetting, key string) []validationIssue {
+	issues := []validationIssue{}
+	if (cert == "") != (key == "") {
+		issues = append(issues, validationIssue{
+			Level:   issueError,
+			Setting: certSetting,
+			Message: fmt.Sprintf("%s and %s must be set together", certSetting, keySetting),
+		})
+	}
+	if cert != "" && !fileExists(cert) {
+		issues = append(issues, validationIssue{Level: issueError, Setting: certSetting, Message: fmt.Sprintf("file %s does not exist", cert)})
+	}
+	if key != "" && !fileExists(key) {
+		issues = append(issues, validationIssue{Level: issueError, Setting: keySetting, Message: fmt.Sprintf("file %s does not exist", key)})
+	}
+	return issues
+}
+
+func validateAgentAPIAuth(api config.AgentAPI) []validationIssue {
+	issues := []validationIssue{}
+	addIssue := func(level, setting, format string, args ...interface{}) {
+		issues = append(issues, validationIssue{Level: level, Setting: setting, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if api.ClientCA != "" {
+		if !fileExists(api.ClientCA) {
+			addIssue(issueError, config.AgentAPIClientCA, "file %s does not exist", api.ClientCA)
+		}
+		if api.Cert == "" || api.Key == "" {
+			addIssue(issueError, config.AgentAPIClientCA, "%s and %s must be set to verify client certificates, the Agent API is not started", config.AgentAPICert, config.AgentAPIKey)
+		}
+	}
+
+	if !api.Auth.Enable {
+		addIssue(issueWarning, config.AgentAPIAuthEnable, "authentication is disabled, every client that can reach the Agent API can change the NGINX configuration")
+		return issues
+	}
+
+	subjects := len(api.Auth.ReadOnlySubjects) + len(api.Auth.ConfigWriteSubjects)
+	if len(api.Auth.ReadOnlyTokens)+len(api.Auth.ConfigWriteTokens)+subjects == 0 && api.Auth.JWKSFile == "" {
+		addIssue(issueError, config.AgentAPIAuthEnable, "authentication is enabled without tokens, client certificate subjects or a JWKS file, every request will be rejected")
+	}
+	if subjects > 0 && api.ClientCA == "" {
+		addIssue(issueError, config.AgentAPIClientCA, "client certificate subjects are set without a client CA to verify client certificates")
+	}
+	if api.Auth.JWKSFile != "" && !fileExists(api.Auth.JWKSFile) {
+		addIssue(issueError, config.AgentAPIAuthJWKSFile, "file %s does not exist", api.Auth.JWKSFile)
+	}
+
+	return issues
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func writeValidationResult(w io.Writer, format string, issues []validationIssue) error {
+	result := validationResult{Valid: true, Issues: issues}
+	for _, issue := range issues {
+		if issue.Level == issueError {
+			result.Valid = false
+		}
+	}
+
+	if format == formatJSON {
+		return writeJSON(w, result)
+	}
+
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s: %s: %s\n", strings.ToUpper(issue.Level), issue.Setting, issue.Message)
+	}
+	if result.Valid {
+		fmt.Fprintln(w, "Configuration is valid")
+	} else {
+		fmt.Fprintln(w, "Configuration is not valid")
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nginx/agent/v2/src/core/config"
+)
+
+func validConfig(t *testing.T) *config.Config {
+	return &config.Config{
+		ConfigDirs: t.TempDir(),
+		Log:        config.LogConfig{Level: "info"},
+		AgentMetrics: config.AgentMetrics{
+			CollectionInterval: 15 * time.Second,
+			ReportInterval:     time.Minute,
+		},
+		QueueSize: 100,
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	certFile := filepath.Join(t.TempDir(), "agent.crt")
+	require.NoError(t, os.WriteFile(certFile, []byte("cert"), 0o600))
+
+	tests := []struct {
+		name       string
+		update     func(*config.Config)
+		extensions []string
+		expected   []validationIssue
+	}{
+		{
+			name:     "valid config",
+			update:   func(*config.Config) {},
+			expected: []validationIssue{},
+		},
+		{
+			name:       "unknown extension",
+			update:     func(*config.Config) {},
+			extensions: []string{"nginx-app-protect", "unknown"},
+			expected: []validationIssue{
+				{Level: issueWarning, Setting: "extensions", Message: "unknown extension unknown will be ignored"},
+			},
+		},
+		{
+			name: "invalid log level",
+			update: func(c *config.Config) {
+				c.Log.Level = "verbose"
+			},
+			expected: []validationIssue{
+				{Level: issueError, Setting: "log_level", Message: "not a valid logrus Level: \"verbose\""},
+			},
+		},
+		{
+			name: "server host without grpc port",
+			update: func(c *config.Config) {
+				c.Server.Host = "127.0.0.1"
+			},
+			expected: []validationIssue{
+				{Level: issueError, Setting: "server_grpcport", Message: "server host 127.0.0.1 is set without a gRPC port"},
+			},
+		},
+		{
+			name: "tls key without cert",
+			update: func(c *config.Config) {
+				c.TLS = config.TLSConfig{Enable: true, Key: "/missing/agent.key", Ca: certFile}
+			},
+			expected: []validationIssue{
+				{Level: issueError, Setting: "tls_cert", Message: "tls_cert and tls_key must be set together"},
+				{Level: issueError, Setting: "tls_key", Message: "file /missing/agent.key does not exist"},
+			},
+		},
+		{
+			name: "api cert without key",
+			update: func(c *config.Config) {
+				c.AgentAPI = config.AgentAPI{Port: 8038, Cert: certFile}
+			},
+			expected: []validationIssue{
+				{Level: issueError, Setting: "api_cert", Message: "api_cert and api_key must be set together"},
+				{Level: issueWarning, Setting: "api_auth_enable", Message: "authentication is disabled, every client that can reach the Agent API can change the NGINX configuration"},
+			},
+		},
+		{
+			name: "api client ca without cert and key",
+			update: func(c *config.Config) {
+				c.AgentAPI = config.AgentAPI{Port: 8038, ClientCA: certFile, Auth: config.AgentAPIAuth{Enable: true, ReadOnlyTokens: []string{"token"}}}
+			},
+			expected: []validationIssue{
+				{Level: issueError, Setting: "api_client_ca", Message: "api_cert and api_key must be set to verify client certificates, the Agent API is not started"},
+			},
+		},
+		{
+			name: "api auth without credentials",
+			update: func(c *config.Config) {
+				c.AgentAPI = config.AgentAPI{Port: 8038, Auth: config.AgentAPIAuth{Enable: true}}
+			},
+			expected: []validationIssue{
+				{Level: issueError, Setting: "api_auth_enable", Message: "authentication is enabled without tokens, client certificate subjects or a JWKS file, every request will be rejected"},
+			},
+		},
+		{
+			name: "api auth client certificate subjects without client ca and missing jwks file",
+			update: func(c *config.Config) {
+				c.AgentAPI = config.AgentAPI{
+					Port: 8038,
+					Auth: config.AgentAPIAuth{
+						Enable:              true,
+						ConfigWriteSubjects: []string{"CN=ops"},
+						JWKSFile:            "/missing/jwks.json",
+					},
+				}
+			},
+			expected: []validationIssue{
+				{Level: issueError, Setting: "api_client_ca", Message: "client certificate subjects are set without a client CA to verify client certificates"},
+				{Level: issueError, Setting: "api_auth_jwks_file", Message: "file /missing/jwks.json does not exist"},
+			},
+		},
+		{
+			name: "report interval shorter than collection interval",
+			update: func(c *config.Config) {
+				c.AgentMetrics.ReportInterval = time.Second
+			},
+			expected: []validationIssue{
+				{Level: issueWarning, Setting: "metrics_report_interval", Message: "report interval 1s is shorter than the collection interval 15s"},
+			},
+		},
+		{
+			name: "certificate monitor without check interval and critical days more than warning days",
+			update: func(c *config.Config) {
+				c.CertificateMonitor = config.CertificateMonitor{Enable: true, WarningDays: 7, CriticalDays: 30}
+			},
+			expected: []validationIssue{
+				{Level: issueError, Setting: "certificate_monitor_check_interval", Message: "check interval must be greater than zero"},
+				{Level: issueWarning, Setting: "certificate_monitor_critical_days", Message: "critical days 30 are more than the warning days 7, no warning events will be raised"},
+			},
+		},
+		{
+			name: "acme without https directory url, email and check interval",
+			update: func(c *config.Config) {
+				c.ACME = config.ACME{
+					Enable:       true,
+					DirectoryURL: "http://acme.example.com/directory",
+					AccountKey:   "/var/lib/nginx-agent/acme/account.key",
+					ChallengeDir: "/var/lib/nginx-agent/acme/challenges",
+					SnippetPath:  "/etc/nginx/acme-challenge.conf",
+					RenewBefore:  time.Hour,
+				}
+			},
+			expected: []validationIssue{
+				{Level: issueError, Setting: "acme_directory_url", Message: "directory URL \"http://acme.example.com/directory\" must be an https URL"},
+				{Level: issueError, Setting: "acme_check_interval", Message: "check interval must be greater than zero"},
+				{Level: issueWarning, Setting: "acme_email", Message: "no contact email is set, the ACME server can't send expiry notices"},
+				{Level: issueWarning, Setting: "acme_certificates", Message: "no certificates are set, no certificates are renewed"},
+			},
+		},
+		{
+			name: "missing config dir and invalid otlp protocol and queue size",
+			update: func(c *config.Config) {
+				c.ConfigDirs = "/missing/nginx"
+				c.OTLP = config.OTLP{Endpoint: "localhost:4317", Protocol: "udp"}
+				c.QueueSize = 0
+			},
+			expected: []validationIssue{
+				{Level: issueWarning, Setting: "config_dirs", Message: "directory /missing/nginx does not exist"},
+				{Level: issueError, Setting: "otlp_protocol", Message: "unsupported protocol \"udp\", must be one of: grpc, http"},
+				{Level: issueError, Setting: "queue_size", Message: "queue size must be greater than zero"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := validConfig(t)
+			tt.update(conf)
+			assert.Equal(t, tt.expected, validateConfig(conf, tt.extensions))
+		})
+	}
+}
+
+func TestWriteValidationResult(t *testing.T) {
+	issues := []validationIssue{
+		{Level: issueWarning, Setting: "config_dirs", Message: "directory /missing/nginx does not exist"},
+		{Level: issueError, Setting: "queue_size", Message: "queue size must be greater than zero"},
+	}
+
+	out := &bytes.Buffer{}
+	require.NoError(t, writeValidationResult(out, formatText, issues))
+	assert.Equal(t, "WARNING: config_dirs: directory /missing/nginx does not exist\n"+
+		"ERROR: queue_size: queue size must be greater than zero\n"+
+		"Configuration is not valid\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writeValidationResult(out, formatJSON, issues[:1]))
+	result := validationResult{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.True(t, result.Valid)
+	assert.Equal(t, issues[:1], result.Issues)
+}
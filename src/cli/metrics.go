@@ -0,0 +1,165 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+	"github.com/nginx/agent/v2/src/core/metrics/collectors"
+	"github.com/nginx/agent/v2/src/plugins"
+)
+
+const (
+	intervalFlag    = "interval"
+	defaultInterval = time.Second
+)
+
+func newMetricsCommand(env core.Environment) *cobra.Command {
+	metricsCmd := &cobra.Command{
+		Use:   "metrics",
+		Short: "Inspect the metrics collected by the agent.",
+	}
+
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Collect the system and NGINX metrics once and print them.",
+		Long: `Collect the system and NGINX metrics once and print them.
+
+Counters are reported as the difference between two collections, so the collectors run
+twice, separated by the interval.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			format, err := getFormat(cmd)
+			if err != nil {
+				return err
+			}
+			interval, err := cmd.Flags().GetDuration(intervalFlag)
+			if err != nil {
+				return err
+			}
+			if interval <= 0 {
+				return fmt.Errorf("interval must be greater than zero")
+			}
+			cmd.SilenceUsage = true
+			conf, err := loadConfig(env)
+			if err != nil {
+				return err
+			}
+
+			binary := core.NewNginxBinary(env, conf)
+			stats := collectMetrics(cmd.Context(), newCollectors(env, conf, binary), interval)
+			return writeMetricsSnapshot(cmd.OutOrStdout(), format, stats)
+		},
+	}
+	addFormatFlag(snapshotCmd)
+	snapshotCmd.Flags().Duration(intervalFlag, defaultInterval, "Time between the two collections used to calculate the counters")
+	metricsCmd.AddCommand(snapshotCmd)
+
+	return metricsCmd
+}
+
+func newCollectors(env core.Environment, conf *config.Config, binary core.NginxBinary) []metrics.Collector {
+	metricsCollectors := []metrics.Collector{collectors.NewSystemCollector(env, conf)}
+	if env.IsContainer() {
+		metricsCollectors = append(metricsCollectors, collectors.NewContainerCollector(env, conf))
+	}
+
+	for _, collectorConfig := range plugins.NewNginxCollectorConfigs(conf, env, binary, env.Processes()) {
+		metricsCollectors = append(metricsCollectors, collectors.NewNginxCollector(conf, env, collectorConfig, binary))
+	}
+
+	return metricsCollectors
+}
+
+// collectMetrics runs the collectors twice, separated by the interval, and returns the stats of
+// the second collection. The first collection only sets the baseline of the counters.
+func collectMetrics(ctx context.Context, metricsCollectors []metrics.Collector, interval time.Duration) []*metrics.StatsEntityWrapper {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	collect := func() []*metrics.StatsEntityWrapper {
+		collectCtx, cancel := context.WithTimeout(ctx, interval)
+		defer cancel()
+
+		buf := make(chan *metrics.StatsEntityWrapper)
+		done := make(chan []*metrics.StatsEntityWrapper)
+		go func() {
+			stats := []*metrics.StatsEntityWrapper{}
+			for stat := range buf {
+				stats = append(stats, stat)
+			}
+			done <- stats
+		}()
+
+		wg := &sync.WaitGroup{}
+		for _, collector := range metricsCollectors {
+			wg.Add(1)
+			go collector.Collect(collectCtx, wg, buf)
+		}
+		wg.Wait()
+		close(buf)
+
+		return <-done
+	}
+
+	collect()
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-time.After(interval):
+	}
+	return collect()
+}
+
+func writeMetricsSnapshot(w io.Writer, format string, stats []*metrics.StatsEntityWrapper) error {
+	reports := []*proto.MetricsReport{}
+	if bundle, ok := metrics.GenerateMetricsReportBundle(stats).(*metrics.MetricsReportBundle); ok {
+		reports = bundle.Data
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].GetType() < reports[j].GetType()
+	})
+
+	if format == formatJSON {
+		return writeJSON(w, metrics.NewMetricsSnapshot(reports))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, report := range reports {
+		for _, entity := range report.GetData() {
+			dimensions := make([]string, 0, len(entity.GetDimensions()))
+			for _, dimension := range entity.GetDimensions() {
+				dimensions = append(dimensions, dimension.GetName()+"="+dimension.GetValue())
+			}
+			fmt.Fprintf(tw, "[%s] %s\n", report.GetType(), strings.Join(dimensions, " "))
+
+			simpleMetrics := entity.GetSimplemetrics()
+			sort.Slice(simpleMetrics, func(i, j int) bool {
+				return simpleMetrics[i].GetName() < simpleMetrics[j].GetName()
+			})
+			for _, metric := range simpleMetrics {
+				fmt.Fprintf(tw, "  %s\t%v\n", metric.GetName(), metric.GetValue())
+			}
+		}
+	}
+	return tw.Flush()
+}
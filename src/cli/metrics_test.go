@@ -0,0 +1,86 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+)
+
+type fakeCollector struct {
+	collections int
+}
+
+func (c *fakeCollector) Collect(_ context.Context, wg *sync.WaitGroup, m chan<- *metrics.StatsEntityWrapper) {
+	defer wg.Done()
+	c.collections++
+	m <- metrics.NewStatsEntityWrapper(
+		[]*proto.Dimension{{Name: "nginx_id", Value: "12345"}},
+		[]*proto.SimpleMetric{
+			{Name: "nginx.http.request.count", Value: float64(c.collections)},
+			{Name: "nginx.http.conn.active", Value: math.NaN()},
+		},
+		proto.MetricsReport_INSTANCE,
+	)
+}
+
+func (c *fakeCollector) UpdateConfig(*config.Config) {}
+
+func TestCollectMetrics(t *testing.T) {
+	collector := &fakeCollector{}
+
+	stats := collectMetrics(context.Background(), []metrics.Collector{collector}, 10*time.Millisecond)
+
+	assert.Equal(t, 2, collector.collections)
+	require.Len(t, stats, 1)
+	assert.Equal(t, float64(2), stats[0].Data.GetSimplemetrics()[0].GetValue())
+}
+
+func TestCollectMetrics_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	collector := &fakeCollector{}
+
+	assert.Nil(t, collectMetrics(ctx, []metrics.Collector{collector}, time.Minute))
+	assert.Equal(t, 1, collector.collections)
+}
+
+func TestWriteMetricsSnapshot(t *testing.T) {
+	collector := &fakeCollector{}
+	stats := collectMetrics(context.Background(), []metrics.Collector{collector}, time.Millisecond)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, writeMetricsSnapshot(out, formatText, stats))
+	assert.Equal(t, "[INSTANCE] nginx_id=12345\n"+
+		"  nginx.http.conn.active    NaN\n"+
+		"  nginx.http.request.count  2\n", out.String())
+
+	out.Reset()
+	require.NoError(t, writeMetricsSnapshot(out, formatJSON, stats))
+	entries := []metrics.MetricsSnapshotEntry{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entries))
+	assert.Equal(t, []metrics.MetricsSnapshotEntry{
+		{
+			Type:       "INSTANCE",
+			Dimensions: map[string]string{"nginx_id": "12345"},
+			Metrics:    map[string]float64{"nginx.http.request.count": 2},
+		},
+	}, entries)
+}
@@ -0,0 +1,80 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+)
+
+func newNginxCommand(env core.Environment) *cobra.Command {
+	nginxCmd := &cobra.Command{
+		Use:   "nginx",
+		Short: "Inspect the NGINX instances running on this host.",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the NGINX instances detected by the agent.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			format, err := getFormat(cmd)
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			conf, err := loadConfig(env)
+			if err != nil {
+				return err
+			}
+			binary := core.NewNginxBinary(env, conf)
+			return listNginxInstances(cmd.OutOrStdout(), format, binary, env.Processes())
+		},
+	}
+	addFormatFlag(listCmd)
+	nginxCmd.AddCommand(listCmd)
+
+	return nginxCmd
+}
+
+func listNginxInstances(w io.Writer, format string, binary core.NginxBinary, processes []*core.Process) error {
+	binary.UpdateNginxDetailsFromProcesses(processes)
+	detailsMap := binary.GetNginxDetailsMapFromProcesses(processes)
+	details := make([]*proto.NginxDetails, 0, len(detailsMap))
+	for _, detail := range detailsMap {
+		details = append(details, detail)
+	}
+	sort.Slice(details, func(i, j int) bool {
+		return details[i].GetNginxId() < details[j].GetNginxId()
+	})
+
+	if format == formatJSON {
+		return writeJSON(w, details)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NGINX ID\tVERSION\tPLUS\tPID\tCONF PATH\tSTATUS URL")
+	for _, detail := range details {
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%s\t%s\t%s\n",
+			detail.GetNginxId(),
+			detail.GetVersion(),
+			detail.GetPlus().GetEnabled(),
+			detail.GetProcessId(),
+			detail.GetConfPath(),
+			detail.GetStatusUrl(),
+		)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,48 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	tutils "github.com/nginx/agent/v2/test/utils"
+)
+
+func TestListNginxInstances(t *testing.T) {
+	binary := tutils.GetMockNginxBinary()
+	binary.On("UpdateNginxDetailsFromProcesses", mock.Anything)
+
+	out := &bytes.Buffer{}
+	err := listNginxInstances(out, formatText, binary, tutils.GetProcesses())
+	require.NoError(t, err)
+	binary.AssertCalled(t, "UpdateNginxDetailsFromProcesses", tutils.GetProcesses())
+	assert.Equal(t, "NGINX ID  VERSION  PLUS  PID  CONF PATH  STATUS URL\n"+
+		"12345     1.2.1    true  123  /var/conf  \n", out.String())
+}
+
+func TestListNginxInstances_JSON(t *testing.T) {
+	binary := tutils.GetMockNginxBinary()
+	binary.On("UpdateNginxDetailsFromProcesses", mock.Anything)
+
+	out := &bytes.Buffer{}
+	err := listNginxInstances(out, formatJSON, binary, tutils.GetProcesses())
+	require.NoError(t, err)
+
+	details := []*proto.NginxDetails{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &details))
+	require.Len(t, details, 1)
+	assert.Equal(t, "12345", details[0].GetNginxId())
+	assert.Equal(t, "/var/conf", details[0].GetConfPath())
+}
@@ -0,0 +1,125 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+	"github.com/nginx/agent/v2/src/core/supportbundle"
+)
+
+const (
+	outputFlag     = "output"
+	maxLogSizeFlag = "max-log-size"
+	stdoutOutput   = "-"
+)
+
+func newSupportBundleCommand(env core.Environment) *cobra.Command {
+	supportBundleCmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "Collect the NGINX configs, logs, host information and metrics into a tar.gz archive.",
+		Long: `Collect the NGINX configs, logs, host information and metrics into a tar.gz archive.
+
+The server token, the OTLP headers, private keys and key password files are left out of
+the archive.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			output, err := cmd.Flags().GetString(outputFlag)
+			if err != nil {
+				return err
+			}
+			interval, err := cmd.Flags().GetDuration(intervalFlag)
+			if err != nil {
+				return err
+			}
+			maxLogSize, err := cmd.Flags().GetInt64(maxLogSizeFlag)
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				output = fmt.Sprintf("nginx-agent-support-bundle-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+			}
+			cmd.SilenceUsage = true
+
+			conf, err := loadConfig(env)
+			if err != nil {
+				return err
+			}
+
+			binary := core.NewNginxBinary(env, conf)
+			processes := env.Processes()
+			binary.UpdateNginxDetailsFromProcesses(processes)
+
+			var reports []*proto.MetricsReport
+			if interval > 0 {
+				stats := collectMetrics(cmd.Context(), newCollectors(env, conf, binary), interval)
+				if bundle, ok := metrics.GenerateMetricsReportBundle(stats).(*metrics.MetricsReportBundle); ok {
+					reports = bundle.Data
+				}
+			}
+
+			builder := supportbundle.NewBuilder(conf, env, binary)
+			builder.SetMaxLogSize(maxLogSize)
+
+			if output == stdoutOutput {
+				_, err = builder.Write(cmd.OutOrStdout(), processes, reports, configuredPlugins(conf))
+				return err
+			}
+
+			file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+			if err != nil {
+				return fmt.Errorf("unable to create support bundle: %w", err)
+			}
+			defer file.Close()
+
+			manifest, err := builder.Write(file, processes, reports, configuredPlugins(conf))
+			if err != nil {
+				return err
+			}
+			writeSupportBundleSummary(cmd.OutOrStdout(), output, manifest)
+			return nil
+		},
+	}
+	supportBundleCmd.Flags().String(outputFlag, "", `Path of the archive, "-" writes the archive to stdout (default "nginx-agent-support-bundle-<time>.tar.gz")`)
+	supportBundleCmd.Flags().Duration(intervalFlag, defaultInterval, "Time between the two metrics collections used to calculate the counters, 0 leaves the metrics out")
+	supportBundleCmd.Flags().Int64(maxLogSizeFlag, supportbundle.DefaultMaxLogSize, "Number of bytes collected from the end of each log file")
+
+	return supportBundleCmd
+}
+
+// configuredPlugins lists the features and extensions enabled in the agent config, since the
+// plugins are not loaded by the command
+func configuredPlugins(conf *config.Config) []supportbundle.Plugin {
+	plugins := []supportbundle.Plugin{}
+	for _, feature := range conf.Features {
+		plugins = append(plugins, supportbundle.Plugin{Name: feature})
+	}
+	for _, extension := range conf.Extensions {
+		plugins = append(plugins, supportbundle.Plugin{Name: extension, Extension: true})
+	}
+	return plugins
+}
+
+func writeSupportBundleSummary(w io.Writer, output string, manifest *supportbundle.Manifest) {
+	fmt.Fprintf(w, "Support bundle written to %s with %d files\n", output, len(manifest.Files))
+	for _, name := range manifest.Redacted {
+		fmt.Fprintf(w, "Left out %s, it contains secrets\n", name)
+	}
+	for _, message := range manifest.Errors {
+		fmt.Fprintf(w, "Warning: %s\n", message)
+	}
+}
@@ -0,0 +1,46 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/supportbundle"
+)
+
+func TestConfiguredPlugins(t *testing.T) {
+	conf := &config.Config{
+		Features:   []string{"metrics", "agent-api"},
+		Extensions: []string{"nginx-app-protect"},
+	}
+
+	assert.Equal(t, []supportbundle.Plugin{
+		{Name: "metrics"},
+		{Name: "agent-api"},
+		{Name: "nginx-app-protect", Extension: true},
+	}, configuredPlugins(conf))
+}
+
+func TestWriteSupportBundleSummary(t *testing.T) {
+	manifest := &supportbundle.Manifest{
+		Files:    []string{"agent/config.json", "host/host_info.json"},
+		Redacted: []string{"nginx/12345/config/etc/nginx/server.key"},
+		Errors:   []string{"unable to read log file /var/log/nginx-agent/agent.log: permission denied"},
+	}
+
+	out := &bytes.Buffer{}
+	writeSupportBundleSummary(out, "bundle.tar.gz", manifest)
+
+	assert.Equal(t, "Support bundle written to bundle.tar.gz with 2 files\n"+
+		"Left out nginx/12345/config/etc/nginx/server.key, it contains secrets\n"+
+		"Warning: unable to read log file /var/log/nginx-agent/agent.log: permission denied\n", out.String())
+}
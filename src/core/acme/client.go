@@ -0,0 +1,263 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+// Package acme obtains certificates from an ACME (RFC 8555) server, e.g. Let's Encrypt, proving
+// the control of the domains with HTTP-01 challenges that are served by NGINX.
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme"
+
+	"github.com/nginx/agent/v2/src/core/config"
+)
+
+const (
+	userAgent = "nginx-agent"
+
+	accountKeyPermissions = 0o600
+	challengePermissions  = 0o644
+	directoryPermissions  = 0o755
+)
+
+// Client obtains certificates from an ACME server, the account is registered with the first order
+type Client struct {
+	client       *acme.Client
+	email        string
+	challengeDir string
+	mu           *sync.Mutex
+	registered   bool
+}
+
+// NewClient creates a client for the ACME server of the configuration, the account key is
+// created if the file doesn't exist
+func NewClient(conf config.ACME) (*Client, error) {
+	key, err := loadOrCreateAccountKey(conf.AccountKey)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if conf.Ca != "" {
+		ca, err := os.ReadFile(conf.Ca)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the ACME server CA %s: %w", conf.Ca, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in the ACME server CA %s", conf.Ca)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return &Client{
+		client: &acme.Client{
+			Key:          key,
+			DirectoryURL: conf.DirectoryURL,
+			HTTPClient:   &http.Client{Transport: transport, Timeout: time.Minute},
+			UserAgent:    userAgent,
+		},
+		email:        conf.Email,
+		challengeDir: conf.ChallengeDir,
+		mu:           &sync.Mutex{},
+	}, nil
+}
+
+// ObtainCertificate orders a certificate for the domains, the PEM encoded certificate chain and
+// the PEM encoded private key of the new certificate are returned
+func (c *Client) ObtainCertificate(ctx context.Context, domains []string) ([]byte, []byte, error) {
+	if len(domains) == 0 {
+		return nil, nil, errors.New("no domains to order a certificate for")
+	}
+
+	if err := c.register(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	order, err := c.client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create the order for %v: %w", domains, err)
+	}
+
+	for _, authzURL := range order.AuthzURLs {
+		if err := c.authorize(ctx, authzURL); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	order, err = c.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return nil, nil, fmt.Errorf("order for %v failed: %w", domains, err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate the certificate key: %w", err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domains[0]},
+		DNSNames: domains,
+	}, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create the certificate request: %w", err)
+	}
+
+	chain, _, err := c.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to finalize the order for %v: %w", domains, err)
+	}
+
+	certPEM := []byte{}
+	for _, cert := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})...)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to encode the certificate key: %w", err)
+	}
+
+	return certPEM, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+func (c *Client) register(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.registered {
+		return nil
+	}
+
+	account := &acme.Account{}
+	if c.email != "" {
+		account.Contact = []string{"mailto:" + c.email}
+	}
+	_, err := c.client.Register(ctx, account, acme.AcceptTOS)
+	if err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return fmt.Errorf("unable to register the ACME account: %w", err)
+	}
+	c.registered = true
+
+	return nil
+}
+
+// authorize solves the HTTP-01 challenge of a pending authorization, the challenge response is
+// written to the challenge directory for NGINX to serve and removed once the authorization is done
+func (c *Client) authorize(ctx context.Context, authzURL string) error {
+	authz, err := c.client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return fmt.Errorf("unable to get the authorization %s: %w", authzURL, err)
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+
+	var challenge *acme.Challenge
+	for _, chal := range authz.Challenges {
+		if chal.Type == "http-01" {
+			challenge = chal
+			break
+		}
+	}
+	if challenge == nil {
+		return fmt.Errorf("no http-01 challenge offered for %s", authz.Identifier.Value)
+	}
+
+	response, err := c.client.HTTP01ChallengeResponse(challenge.Token)
+	if err != nil {
+		return fmt.Errorf("unable to create the challenge response for %s: %w", authz.Identifier.Value, err)
+	}
+	challengeFile := filepath.Join(c.challengeDir, filepath.FromSlash(c.client.HTTP01ChallengePath(challenge.Token)))
+	if err := os.MkdirAll(filepath.Dir(challengeFile), directoryPermissions); err != nil {
+		return fmt.Errorf("unable to create the challenge directory: %w", err)
+	}
+	if err := os.WriteFile(challengeFile, []byte(response), challengePermissions); err != nil {
+		return fmt.Errorf("unable to write the challenge response: %w", err)
+	}
+	defer func() {
+		if err := os.Remove(challengeFile); err != nil {
+			log.Warnf("Unable to remove the challenge response %s: %v", challengeFile, err)
+		}
+	}()
+
+	if _, err := c.client.Accept(ctx, challenge); err != nil {
+		return fmt.Errorf("unable to accept the challenge for %s: %w", authz.Identifier.Value, err)
+	}
+	if _, err := c.client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return fmt.Errorf("authorization of %s failed: %w", authz.Identifier.Value, err)
+	}
+
+	return nil
+}
+
+// ChallengeSnippet returns the NGINX location that serves the HTTP-01 challenge responses from
+// the challenge directory, it's meant to be included in the server blocks listening on port 80
+func ChallengeSnippet(challengeDir string) []byte {
+	return []byte(fmt.Sprintf(`# Generated by nginx-agent, serves the ACME HTTP-01 challenge responses
+location ^~ /.well-known/acme-challenge/ {
+    root %s;
+    default_type text/plain;
+    try_files $uri =404;
+}
+`, challengeDir))
+}
+
+func loadOrCreateAccountKey(file string) (crypto.Signer, error) {
+	contents, err := os.ReadFile(file)
+	if err == nil {
+		block, _ := pem.Decode(contents)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM data found in the ACME account key %s", file)
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the ACME account key %s: %w", file, err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported ACME account key %s", file)
+		}
+		return signer, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unable to read the ACME account key %s: %w", file, err)
+	}
+
+	log.Infof("Creating the ACME account key %s", file)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate the ACME account key: %w", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode the ACME account key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), directoryPermissions); err != nil {
+		return nil, fmt.Errorf("unable to create the ACME account key directory: %w", err)
+	}
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), accountKeyPermissions); err != nil {
+		return nil, fmt.Errorf("unable to write the ACME account key %s: %w", file, err)
+	}
+
+	return key, nil
+}
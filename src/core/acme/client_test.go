@@ -0,0 +1,355 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nginx/agent/v2/src/core/config"
+)
+
+// testACMEServer is a minimal stand-in for an ACME server like Pebble, the JWS signatures aren't
+// verified but the HTTP-01 challenge responses are read from the challenge directory and checked
+// against the key authorization of the account
+type testACMEServer struct {
+	t            *testing.T
+	server       *httptest.Server
+	challengeDir string
+	caKey        *ecdsa.PrivateKey
+	caCert       *x509.Certificate
+	mu           sync.Mutex
+	thumbprint   string
+	accounts     int
+	domains      []string
+	tokens       map[string]string
+	statuses     map[string]string
+	certificate  []byte
+}
+
+func newTestACMEServer(t *testing.T, challengeDir string) *testACMEServer {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test ACME CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	s := &testACMEServer{
+		t:            t,
+		challengeDir: challengeDir,
+		caKey:        caKey,
+		caCert:       caCert,
+		tokens:       make(map[string]string),
+		statuses:     make(map[string]string),
+	}
+	s.server = httptest.NewTLSServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+func (s *testACMEServer) url(path string) string {
+	return s.server.URL + path
+}
+
+func (s *testACMEServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", time.Now().UnixNano()))
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Path == "/directory" {
+		s.writeJSON(w, http.StatusOK, map[string]string{
+			"newNonce":   s.url("/nonce"),
+			"newAccount": s.url("/account"),
+			"newOrder":   s.url("/order"),
+			"revokeCert": s.url("/revoke"),
+			"keyChange":  s.url("/key-change"),
+		})
+		return
+	}
+	if r.URL.Path == "/nonce" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var jws struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+	}
+	require.NoError(s.t, json.NewDecoder(r.Body).Decode(&jws))
+	payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	require.NoError(s.t, err)
+
+	switch {
+	case r.URL.Path == "/account":
+		protected, err := base64.RawURLEncoding.DecodeString(jws.Protected)
+		require.NoError(s.t, err)
+		var header struct {
+			JWK struct {
+				Crv string `json:"crv"`
+				Kty string `json:"kty"`
+				X   string `json:"x"`
+				Y   string `json:"y"`
+			} `json:"jwk"`
+		}
+		require.NoError(s.t, json.Unmarshal(protected, &header))
+		jwk := fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, header.JWK.Crv, header.JWK.Kty, header.JWK.X, header.JWK.Y)
+		sum := sha256.Sum256([]byte(jwk))
+		s.thumbprint = base64.RawURLEncoding.EncodeToString(sum[:])
+		s.accounts++
+
+		w.Header().Set("Location", s.url("/account/1"))
+		s.writeJSON(w, http.StatusCreated, map[string]interface{}{"status": "valid"})
+	case r.URL.Path == "/order":
+		var order struct {
+			Identifiers []struct {
+				Value string `json:"value"`
+			} `json:"identifiers"`
+		}
+		require.NoError(s.t, json.Unmarshal(payload, &order))
+		s.domains = []string{}
+		s.certificate = nil
+		for _, identifier := range order.Identifiers {
+			s.domains = append(s.domains, identifier.Value)
+			s.tokens[identifier.Value] = fmt.Sprintf("token-%d", time.Now().UnixNano())
+			s.statuses[identifier.Value] = "pending"
+		}
+
+		w.Header().Set("Location", s.url("/order/1"))
+		s.writeJSON(w, http.StatusCreated, s.order())
+	case r.URL.Path == "/order/1":
+		w.Header().Set("Location", s.url("/order/1"))
+		s.writeJSON(w, http.StatusOK, s.order())
+	case strings.HasPrefix(r.URL.Path, "/authz/"):
+		s.writeJSON(w, http.StatusOK, s.authorization(strings.TrimPrefix(r.URL.Path, "/authz/")))
+	case strings.HasPrefix(r.URL.Path, "/challenge/"):
+		domain := strings.TrimPrefix(r.URL.Path, "/challenge/")
+		token := s.tokens[domain]
+		// NGINX serves the challenge directory at /.well-known/acme-challenge/
+		response, err := os.ReadFile(filepath.Join(s.challengeDir, ".well-known", "acme-challenge", token))
+		s.statuses[domain] = "invalid"
+		if err == nil && string(response) == token+"."+s.thumbprint {
+			s.statuses[domain] = "valid"
+		}
+		s.writeJSON(w, http.StatusOK, s.challenge(domain))
+	case r.URL.Path == "/finalize/1":
+		var finalize struct {
+			CSR string `json:"csr"`
+		}
+		require.NoError(s.t, json.Unmarshal(payload, &finalize))
+		csrDER, err := base64.RawURLEncoding.DecodeString(finalize.CSR)
+		require.NoError(s.t, err)
+		csr, err := x509.ParseCertificateRequest(csrDER)
+		require.NoError(s.t, err)
+		s.issue(csr)
+
+		w.Header().Set("Location", s.url("/order/1"))
+		s.writeJSON(w, http.StatusOK, s.order())
+	case r.URL.Path == "/certificate/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		_, _ = w.Write(s.certificate)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *testACMEServer) order() map[string]interface{} {
+	status := "ready"
+	authorizations := []string{}
+	for _, domain := range s.domains {
+		authorizations = append(authorizations, s.url("/authz/"+domain))
+		if s.statuses[domain] != "valid" {
+			status = s.statuses[domain]
+		}
+	}
+
+	order := map[string]interface{}{
+		"status":         status,
+		"authorizations": authorizations,
+		"finalize":       s.url("/finalize/1"),
+	}
+	if s.certificate != nil {
+		order["status"] = "valid"
+		order["certificate"] = s.url("/certificate/1")
+	}
+	return order
+}
+
+func (s *testACMEServer) authorization(domain string) map[string]interface{} {
+	return map[string]interface{}{
+		"status":     s.statuses[domain],
+		"identifier": map[string]string{"type": "dns", "value": domain},
+		"challenges": []interface{}{s.challenge(domain)},
+	}
+}
+
+func (s *testACMEServer) challenge(domain string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":   "http-01",
+		"url":    s.url("/challenge/" + domain),
+		"token":  s.tokens[domain],
+		"status": s.statuses[domain],
+	}
+}
+
+func (s *testACMEServer) issue(csr *x509.CertificateRequest) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: csr.Subject.CommonName},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(0, 0, 90),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, s.caCert, csr.PublicKey, s.caKey)
+	require.NoError(s.t, err)
+
+	s.certificate = append(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})...,
+	)
+}
+
+func (s *testACMEServer) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.WriteHeader(status)
+	require.NoError(s.t, json.NewEncoder(w).Encode(body))
+}
+
+func (s *testACMEServer) writeServerCA(file string) {
+	require.NoError(s.t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.server.Certificate().Raw}), 0o600))
+}
+
+func TestClient_ObtainCertificate(t *testing.T) {
+	tmpDir := t.TempDir()
+	challengeDir := filepath.Join(tmpDir, "challenges")
+	server := newTestACMEServer(t, challengeDir)
+	server.writeServerCA(filepath.Join(tmpDir, "ca.crt"))
+
+	conf := config.ACME{
+		DirectoryURL: server.url("/directory"),
+		Email:        "admin@example.com",
+		AccountKey:   filepath.Join(tmpDir, "acme", "account.key"),
+		Ca:           filepath.Join(tmpDir, "ca.crt"),
+		ChallengeDir: challengeDir,
+	}
+	client, err := NewClient(conf)
+	require.NoError(t, err)
+
+	info, err := os.Stat(conf.AccountKey)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(accountKeyPermissions), info.Mode().Perm())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	certPEM, keyPEM, err := client.ObtainCertificate(ctx, []string{"example.com", "www.example.com"})
+	require.NoError(t, err)
+
+	block, rest := pem.Decode(certPEM)
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com", "www.example.com"}, cert.DNSNames)
+	assert.Equal(t, "example.com", cert.Subject.CommonName)
+
+	// the chain includes the issuer
+	block, _ = pem.Decode(rest)
+	require.NotNil(t, block)
+	issuer, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, "Test ACME CA", issuer.Subject.CommonName)
+
+	block, _ = pem.Decode(keyPEM)
+	require.NotNil(t, block)
+	assert.Equal(t, "PRIVATE KEY", block.Type)
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	require.NoError(t, err)
+	assert.Equal(t, cert.PublicKey, key.(*ecdsa.PrivateKey).Public())
+
+	// the challenge responses are removed once the authorizations are done
+	files, err := os.ReadDir(filepath.Join(challengeDir, ".well-known", "acme-challenge"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	// the account key is reused and the account is only registered once
+	client, err = NewClient(conf)
+	require.NoError(t, err)
+	_, _, err = client.ObtainCertificate(ctx, []string{"example.com"})
+	require.NoError(t, err)
+	_, _, err = client.ObtainCertificate(ctx, []string{"example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, server.accounts)
+}
+
+func TestClient_ObtainCertificateChallengeFailed(t *testing.T) {
+	tmpDir := t.TempDir()
+	// the server reads the responses from another directory than the client writes them to
+	server := newTestACMEServer(t, filepath.Join(tmpDir, "other"))
+	server.writeServerCA(filepath.Join(tmpDir, "ca.crt"))
+
+	client, err := NewClient(config.ACME{
+		DirectoryURL: server.url("/directory"),
+		AccountKey:   filepath.Join(tmpDir, "account.key"),
+		Ca:           filepath.Join(tmpDir, "ca.crt"),
+		ChallengeDir: filepath.Join(tmpDir, "challenges"),
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, _, err = client.ObtainCertificate(ctx, []string{"example.com"})
+	assert.ErrorContains(t, err, "authorization of example.com failed")
+}
+
+func TestNewClient_InvalidAccountKey(t *testing.T) {
+	accountKey := filepath.Join(t.TempDir(), "account.key")
+	require.NoError(t, os.WriteFile(accountKey, []byte("invalid"), 0o600))
+
+	_, err := NewClient(config.ACME{AccountKey: accountKey})
+	assert.ErrorContains(t, err, "no PEM data found")
+}
+
+func TestChallengeSnippet(t *testing.T) {
+	snippet := string(ChallengeSnippet("/var/lib/nginx-agent/acme/challenges"))
+
+	assert.Contains(t, snippet, "location ^~ /.well-known/acme-challenge/ {")
+	assert.Contains(t, snippet, "root /var/lib/nginx-agent/acme/challenges;")
+}
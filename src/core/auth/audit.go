@@ -0,0 +1,87 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AuditEntry is a mutating Agent API request written to the audit log
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	RemoteAddr string    `json:"remote_addr"`
+	AuthMethod string    `json:"auth_method"`
+	Subject    string    `json:"subject,omitempty"`
+	Roles      []string  `json:"roles,omitempty"`
+	StatusCode int       `json:"status_code"`
+	Duration   float64   `json:"duration"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// AuditLogger writes audit entries as JSON lines to a file, or to the agent log if no file is set
+type AuditLogger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewAuditLogger(path string) (*AuditLogger, error) {
+	if path == "" {
+		return &AuditLogger{}, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("unable to create audit log directory: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit log: %v", err)
+	}
+	return &AuditLogger{file: file}, nil
+}
+
+func (l *AuditLogger) Log(entry *AuditEntry) {
+	if l.file == nil {
+		log.WithFields(log.Fields{
+			"method":      entry.Method,
+			"path":        entry.Path,
+			"remote_addr": entry.RemoteAddr,
+			"auth_method": entry.AuthMethod,
+			"subject":     entry.Subject,
+			"status_code": entry.StatusCode,
+		}).Info("Agent API audit")
+		return
+	}
+
+	content, err := json.Marshal(entry)
+	if err != nil {
+		log.Warnf("Unable to encode Agent API audit entry: %v", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(content, '\n')); err != nil {
+		log.Warnf("Unable to write Agent API audit entry: %v", err)
+	}
+}
+
+func (l *AuditLogger) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
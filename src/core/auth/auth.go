@@ -0,0 +1,183 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nginx/agent/v2/src/core/config"
+)
+
+const (
+	// RoleReadOnly allows requests that only read the state of the agent and NGINX
+	RoleReadOnly = "read-only"
+	// RoleConfigWrite allows requests that change the NGINX config or control NGINX, it includes the read-only role
+	RoleConfigWrite = "config-write"
+
+	MethodAnonymous  = "anonymous"
+	MethodToken      = "token"
+	MethodJWT        = "jwt"
+	MethodClientCert = "client-certificate"
+
+	bearerPrefix = "bearer "
+)
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid credentials")
+
+	anonymous = &Identity{Method: MethodAnonymous}
+)
+
+type identityContextKey struct{}
+
+// Identity is the authenticated client of a request
+type Identity struct {
+	// Method the client authenticated with
+	Method string
+	// Subject identifying the client, e.g. the subject of a client certificate or the sub claim of a JWT
+	Subject string
+	// Roles granted to the client
+	Roles []string
+}
+
+// HasRole reports whether the identity was granted the role, the config-write role includes the read-only role
+func (i *Identity) HasRole(role string) bool {
+	for _, granted := range i.Roles {
+		if granted == role || (granted == RoleConfigWrite && role == RoleReadOnly) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator authenticates Agent API requests with static bearer tokens, JWTs validated against
+// a local JWKS file or verified client certificates
+type Authenticator struct {
+	enabled  bool
+	tokens   map[string]string
+	subjects map[string][]string
+	jwt      *jwtValidator
+}
+
+// NewAuthenticator returns an authenticator for the auth settings. If authentication is disabled
+// every request is authenticated as an anonymous client with all roles.
+func NewAuthenticator(conf config.AgentAPIAuth) (*Authenticator, error) {
+	a := &Authenticator{
+		enabled:  conf.Enable,
+		tokens:   make(map[string]string),
+		subjects: make(map[string][]string),
+	}
+	if !conf.Enable {
+		return a, nil
+	}
+
+	for _, token := range conf.ReadOnlyTokens {
+		a.tokens[token] = RoleReadOnly
+	}
+	for _, token := range conf.ConfigWriteTokens {
+		a.tokens[token] = RoleConfigWrite
+	}
+	for _, subject := range conf.ReadOnlySubjects {
+		a.subjects[subject] = append(a.subjects[subject], RoleReadOnly)
+	}
+	for _, subject := range conf.ConfigWriteSubjects {
+		a.subjects[subject] = append(a.subjects[subject], RoleConfigWrite)
+	}
+
+	if conf.JWKSFile != "" {
+		validator, err := newJWTValidator(conf.JWKSFile, conf.JWTIssuer, conf.JWTAudience, conf.JWTRolesClaim)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = validator
+	}
+
+	return a, nil
+}
+
+// Enabled reports whether requests have to be authenticated
+func (a *Authenticator) Enabled() bool {
+	return a.enabled
+}
+
+// Authenticate returns the identity of the client of a request. ErrUnauthenticated is returned if
+// the request has no credentials or the credentials are not valid.
+func (a *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	if !a.enabled {
+		return &Identity{Method: MethodAnonymous, Roles: []string{RoleConfigWrite}}, nil
+	}
+
+	header := r.Header.Get("Authorization")
+	if header != "" {
+		if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+			return nil, fmt.Errorf("%w: unsupported authorization scheme", ErrUnauthenticated)
+		}
+		return a.authenticateBearer(strings.TrimSpace(header[len(bearerPrefix):]))
+	}
+
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		return a.authenticateClientCert(r.TLS.VerifiedChains[0][0])
+	}
+
+	return nil, ErrUnauthenticated
+}
+
+func (a *Authenticator) authenticateBearer(token string) (*Identity, error) {
+	for configured, role := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(configured), []byte(token)) == 1 {
+			return &Identity{Method: MethodToken, Subject: tokenSubject(token), Roles: []string{role}}, nil
+		}
+	}
+
+	if a.jwt != nil && strings.Count(token, ".") == 2 {
+		claims, err := a.jwt.validate(token)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+		}
+		return &Identity{Method: MethodJWT, Subject: claims.subject, Roles: claims.roles}, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown bearer token", ErrUnauthenticated)
+}
+
+func (a *Authenticator) authenticateClientCert(cert *x509.Certificate) (*Identity, error) {
+	subject := cert.Subject.String()
+	roles := append(append([]string{}, a.subjects[subject]...), a.subjects[cert.Subject.CommonName]...)
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("%w: client certificate subject %s is not allowed", ErrUnauthenticated, subject)
+	}
+	return &Identity{Method: MethodClientCert, Subject: subject, Roles: roles}, nil
+}
+
+// tokenSubject identifies a static token in logs without revealing it
+func tokenSubject(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// WithIdentity returns a copy of the context with the identity of the client
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// IdentityFromContext returns the identity of the client stored in the context, an anonymous
+// identity without roles is returned if there is none
+func IdentityFromContext(ctx context.Context) *Identity {
+	if identity, ok := ctx.Value(identityContextKey{}).(*Identity); ok {
+		return identity
+	}
+	return anonymous
+}
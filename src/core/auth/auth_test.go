@@ -0,0 +1,214 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nginx/agent/v2/src/core/config"
+)
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	keys := newTestKeys(t)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	keys.writeJWKS(t, jwksFile)
+
+	jwt := signJWT(t, "RS256", "rsa", keys.rsa, map[string]interface{}{
+		"sub":   "ci-pipeline",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{RoleReadOnly},
+	})
+	expiredJWT := signJWT(t, "RS256", "rsa", keys.rsa, map[string]interface{}{
+		"sub": "ci-pipeline",
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	})
+
+	conf := config.AgentAPIAuth{
+		Enable:              true,
+		ReadOnlyTokens:      []string{"read-token"},
+		ConfigWriteTokens:   []string{"write-token"},
+		ReadOnlySubjects:    []string{"monitoring"},
+		ConfigWriteSubjects: []string{"CN=ops,O=Example"},
+		JWKSFile:            jwksFile,
+		JWTRolesClaim:       "roles",
+	}
+
+	tests := []struct {
+		name          string
+		conf          config.AgentAPIAuth
+		header        string
+		clientCert    *x509.Certificate
+		expected      *Identity
+		expectedError string
+	}{
+		{
+			name:     "authentication disabled",
+			conf:     config.AgentAPIAuth{},
+			expected: &Identity{Method: MethodAnonymous, Roles: []string{RoleConfigWrite}},
+		},
+		{
+			name:          "no credentials",
+			conf:          conf,
+			expectedError: "missing or invalid credentials",
+		},
+		{
+			name:     "read-only token",
+			conf:     conf,
+			header:   "Bearer read-token",
+			expected: &Identity{Method: MethodToken, Subject: tokenSubject("read-token"), Roles: []string{RoleReadOnly}},
+		},
+		{
+			name:     "config-write token",
+			conf:     conf,
+			header:   "bearer write-token",
+			expected: &Identity{Method: MethodToken, Subject: tokenSubject("write-token"), Roles: []string{RoleConfigWrite}},
+		},
+		{
+			name:          "unknown token",
+			conf:          conf,
+			header:        "Bearer other-token",
+			expectedError: "missing or invalid credentials: unknown bearer token",
+		},
+		{
+			name:          "basic authentication",
+			conf:          conf,
+			header:        "Basic dXNlcjpwYXNz",
+			expectedError: "missing or invalid credentials: unsupported authorization scheme",
+		},
+		{
+			name:     "jwt",
+			conf:     conf,
+			header:   "Bearer " + jwt,
+			expected: &Identity{Method: MethodJWT, Subject: "ci-pipeline", Roles: []string{RoleReadOnly}},
+		},
+		{
+			name:          "expired jwt",
+			conf:          conf,
+			header:        "Bearer " + expiredJWT,
+			expectedError: "missing or invalid credentials: JWT is expired",
+		},
+		{
+			name:       "client certificate subject",
+			conf:       conf,
+			clientCert: &x509.Certificate{Subject: pkix.Name{CommonName: "ops", Organization: []string{"Example"}}},
+			expected:   &Identity{Method: MethodClientCert, Subject: "CN=ops,O=Example", Roles: []string{RoleConfigWrite}},
+		},
+		{
+			name:       "client certificate common name",
+			conf:       conf,
+			clientCert: &x509.Certificate{Subject: pkix.Name{CommonName: "monitoring"}},
+			expected:   &Identity{Method: MethodClientCert, Subject: "CN=monitoring", Roles: []string{RoleReadOnly}},
+		},
+		{
+			name:          "unknown client certificate subject",
+			conf:          conf,
+			clientCert:    &x509.Certificate{Subject: pkix.Name{CommonName: "other"}},
+			expectedError: "missing or invalid credentials: client certificate subject CN=other is not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator, err := NewAuthenticator(tt.conf)
+			require.NoError(t, err)
+
+			r := httptest.NewRequest(http.MethodGet, "/nginx/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			if tt.clientCert != nil {
+				r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{tt.clientCert}}}
+			}
+
+			identity, err := authenticator.Authenticate(r)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				assert.True(t, errors.Is(err, ErrUnauthenticated))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, identity)
+		})
+	}
+}
+
+func TestNewAuthenticator_invalidJWKSFile(t *testing.T) {
+	_, err := NewAuthenticator(config.AgentAPIAuth{Enable: true, JWKSFile: "/missing/jwks.json"})
+	assert.ErrorContains(t, err, "unable to read JWKS file")
+}
+
+func TestIdentity_HasRole(t *testing.T) {
+	readOnly := &Identity{Roles: []string{RoleReadOnly}}
+	configWrite := &Identity{Roles: []string{RoleConfigWrite}}
+
+	assert.True(t, readOnly.HasRole(RoleReadOnly))
+	assert.False(t, readOnly.HasRole(RoleConfigWrite))
+	assert.True(t, configWrite.HasRole(RoleReadOnly))
+	assert.True(t, configWrite.HasRole(RoleConfigWrite))
+	assert.False(t, IdentityFromContext(context.Background()).HasRole(RoleReadOnly))
+
+	ctx := WithIdentity(context.Background(), configWrite)
+	assert.Equal(t, configWrite, IdentityFromContext(ctx))
+}
+
+func TestAuditLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "api-audit.log")
+	logger, err := NewAuditLogger(path)
+	require.NoError(t, err)
+
+	entry := &AuditEntry{
+		Time:       time.Unix(1700000000, 0).UTC(),
+		Method:     http.MethodPut,
+		Path:       "/nginx/config/",
+		RemoteAddr: "127.0.0.1:51234",
+		AuthMethod: MethodToken,
+		Subject:    "token:0123456789ab",
+		Roles:      []string{RoleConfigWrite},
+		StatusCode: http.StatusOK,
+	}
+	logger.Log(entry)
+	logger.Log(&AuditEntry{Method: http.MethodPost, Path: "/nginx/1/stop", StatusCode: http.StatusUnauthorized, Error: "missing or invalid credentials"})
+	require.NoError(t, logger.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := 0
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for decoder.More() {
+		var decoded AuditEntry
+		require.NoError(t, decoder.Decode(&decoded))
+		if lines == 0 {
+			assert.Equal(t, *entry, decoded)
+		} else {
+			assert.Equal(t, http.StatusUnauthorized, decoded.StatusCode)
+			assert.Equal(t, "missing or invalid credentials", decoded.Error)
+		}
+		lines++
+	}
+	assert.Equal(t, 2, lines)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
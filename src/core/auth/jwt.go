@@ -0,0 +1,361 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256" // hash functions of the supported JWT algorithms
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// clockSkew is the leeway given when validating the time claims of a JWT
+const clockSkew = 30 * time.Second
+
+// ecdsaAlgorithms are the JWT algorithms of the elliptic curves, by curve name
+var ecdsaAlgorithms = map[string]string{
+	"P-256": "ES256",
+	"P-384": "ES384",
+	"P-521": "ES512",
+}
+
+// jsonWebKey is a public key of a JWKS file, see RFC 7517
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type publicKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	subject string
+	roles   []string
+}
+
+// jwtValidator validates JWTs signed with the keys of a local JWKS file. The file is read
+// again when it changes so that keys can be rotated without restarting the agent.
+type jwtValidator struct {
+	mu          sync.Mutex
+	jwksFile    string
+	modTime     time.Time
+	keys        []publicKey
+	issuer      string
+	audience    string
+	rolesClaim  string
+	currentTime func() time.Time
+}
+
+func newJWTValidator(jwksFile, issuer, audience, rolesClaim string) (*jwtValidator, error) {
+	v := &jwtValidator{
+		jwksFile:    jwksFile,
+		issuer:      issuer,
+		audience:    audience,
+		rolesClaim:  rolesClaim,
+		currentTime: time.Now,
+	}
+	if err := v.loadKeys(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// loadKeys reads the JWKS file if it changed since it was last read, must be called with the lock held
+func (v *jwtValidator) loadKeys() error {
+	info, err := os.Stat(v.jwksFile)
+	if err != nil {
+		return fmt.Errorf("unable to read JWKS file: %v", err)
+	}
+	if v.keys != nil && info.ModTime().Equal(v.modTime) {
+		return nil
+	}
+
+	content, err := os.ReadFile(v.jwksFile)
+	if err != nil {
+		return fmt.Errorf("unable to read JWKS file: %v", err)
+	}
+	keys, err := parseJWKS(content)
+	if err != nil {
+		return fmt.Errorf("unable to parse JWKS file %s: %v", v.jwksFile, err)
+	}
+
+	log.Debugf("Loaded %d keys from JWKS file %s", len(keys), v.jwksFile)
+	v.keys = keys
+	v.modTime = info.ModTime()
+	return nil
+}
+
+func (v *jwtValidator) validate(token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed JWT")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed JWT header: %v", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed JWT signature: %v", err)
+	}
+
+	key, err := v.findKey(header)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed JWT claims: %v", err)
+	}
+	return v.validateClaims(claims)
+}
+
+func (v *jwtValidator) findKey(header jwtHeader) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := v.loadKeys(); err != nil {
+		log.Warnf("Using the previously loaded JWKS keys: %v", err)
+	}
+
+	var candidates []publicKey
+	for _, key := range v.keys {
+		if (header.Kid == "" || key.kid == header.Kid) && (key.alg == "" || key.alg == header.Alg) {
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) != 1 {
+		return nil, fmt.Errorf("no unique JWKS key found for JWT with kid %q and alg %q", header.Kid, header.Alg)
+	}
+	return candidates[0].key, nil
+}
+
+func (v *jwtValidator) validateClaims(claims map[string]interface{}) (*jwtClaims, error) {
+	now := v.currentTime()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.New("JWT has no exp claim")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, errors.New("JWT is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("JWT is not valid yet")
+	}
+
+	if v.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.issuer {
+			return nil, fmt.Errorf("JWT issuer %q is not accepted", iss)
+		}
+	}
+	if v.audience != "" && !containsString(claimStrings(claims["aud"], false), v.audience) {
+		return nil, fmt.Errorf("JWT audience does not contain %q", v.audience)
+	}
+
+	var roles []string
+	for _, role := range claimStrings(claims[v.rolesClaim], true) {
+		if role == RoleReadOnly || role == RoleConfigWrite {
+			roles = append(roles, role)
+		}
+	}
+
+	subject, _ := claims["sub"].(string)
+	return &jwtClaims{subject: subject, roles: roles}, nil
+}
+
+// claimStrings returns the values of a claim that is either a string or an array of strings,
+// a string is split on spaces if splitString is true, like the scope claim
+func claimStrings(claim interface{}, splitString bool) []string {
+	switch value := claim.(type) {
+	case string:
+		if splitString {
+			return strings.Fields(value)
+		}
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "PS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "PS512", "ES512":
+		hash = crypto.SHA512
+	case "EdDSA":
+	default:
+		return fmt.Errorf("JWT algorithm %q is not supported", alg)
+	}
+
+	var valid bool
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if alg[0] != 'R' && alg[0] != 'P' {
+			break
+		}
+		h := hash.New()
+		h.Write(signed)
+		if alg[0] == 'R' {
+			valid = rsa.VerifyPKCS1v15(k, hash, h.Sum(nil), signature) == nil
+		} else {
+			valid = rsa.VerifyPSS(k, hash, h.Sum(nil), signature, nil) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if ecdsaAlgorithms[k.Curve.Params().Name] != alg || len(signature) != 2*size {
+			break
+		}
+		h := hash.New()
+		h.Write(signed)
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		valid = ecdsa.Verify(k, h.Sum(nil), r, s)
+	case ed25519.PublicKey:
+		valid = alg == "EdDSA" && ed25519.Verify(k, signed, signature)
+	}
+
+	if !valid {
+		return errors.New("invalid JWT signature")
+	}
+	return nil
+}
+
+func parseJWKS(content []byte) ([]publicKey, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := []publicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %v", jwk.Kid, err)
+		}
+		keys = append(keys, publicKey{kid: jwk.Kid, alg: jwk.Alg, key: key})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys found")
+	}
+	return keys, nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %v", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+		size := (curve.Params().BitSize + 7) / 8
+		if errX != nil || errY != nil || len(x) != size || len(y) != size {
+			return nil, errors.New("invalid curve point")
+		}
+		// ecdh checks that the point is on the curve
+		if _, err := ecdhCurve.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("invalid curve point: %v", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if jwk.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
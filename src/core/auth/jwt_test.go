@@ -0,0 +1,283 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testKeys struct {
+	rsa     *rsa.PrivateKey
+	ecdsa   *ecdsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return &testKeys{rsa: rsaKey, ecdsa: ecdsaKey, ed25519: ed25519Key}
+}
+
+func (k *testKeys) writeJWKS(t *testing.T, path string) {
+	encode := base64.RawURLEncoding.EncodeToString
+	pub := k.ed25519.Public().(ed25519.PublicKey)
+	jwks := map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encode(k.rsa.N.Bytes()), "e": encode(big.NewInt(int64(k.rsa.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "alg": "ES256", "crv": "P-256", "x": encode(k.ecdsa.X.FillBytes(make([]byte, 32))), "y": encode(k.ecdsa.Y.FillBytes(make([]byte, 32)))},
+			{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": encode(pub)},
+			{"kty": "RSA", "kid": "enc", "use": "enc", "n": encode(k.rsa.N.Bytes()), "e": "AQAB"},
+		},
+	}
+	content, err := json.Marshal(jwks)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+}
+
+func signJWT(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		if alg == "PS256" {
+			signature, err = rsa.SignPSS(rand.Reader, k, crypto.SHA256, digest[:], nil)
+		} else {
+			signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		}
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, digest[:])
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, []byte(signed))
+	}
+	require.NoError(t, err)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTValidator(t *testing.T) {
+	keys := newTestKeys(t)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	keys.writeJWKS(t, jwksFile)
+
+	now := time.Unix(1700000000, 0)
+	validClaims := func(updates map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{
+			"sub":   "ci-pipeline",
+			"iss":   "https://idp.example.com",
+			"aud":   []string{"nginx-agent", "other"},
+			"exp":   now.Add(time.Hour).Unix(),
+			"roles": []string{RoleConfigWrite, "admin"},
+		}
+		for name, value := range updates {
+			if value == nil {
+				delete(claims, name)
+			} else {
+				claims[name] = value
+			}
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		expected      *jwtClaims
+		expectedError string
+	}{
+		{
+			name:     "RS256",
+			token:    signJWT(t, "RS256", "rsa", keys.rsa, validClaims(nil)),
+			expected: &jwtClaims{subject: "ci-pipeline", roles: []string{RoleConfigWrite}},
+		},
+		{
+			name:     "PS256",
+			token:    signJWT(t, "PS256", "rsa", keys.rsa, validClaims(nil)),
+			expected: &jwtClaims{subject: "ci-pipeline", roles: []string{RoleConfigWrite}},
+		},
+		{
+			name:     "ES256 with space separated roles",
+			token:    signJWT(t, "ES256", "ec", keys.ecdsa, validClaims(map[string]interface{}{"roles": "read-only openid"})),
+			expected: &jwtClaims{subject: "ci-pipeline", roles: []string{RoleReadOnly}},
+		},
+		{
+			name:     "EdDSA with audience string",
+			token:    signJWT(t, "EdDSA", "ed", keys.ed25519, validClaims(map[string]interface{}{"aud": "nginx-agent"})),
+			expected: &jwtClaims{subject: "ci-pipeline", roles: []string{RoleConfigWrite}},
+		},
+		{
+			name:          "expired",
+			token:         signJWT(t, "RS256", "rsa", keys.rsa, validClaims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()})),
+			expectedError: "JWT is expired",
+		},
+		{
+			name:     "expiry within clock skew",
+			token:    signJWT(t, "RS256", "rsa", keys.rsa, validClaims(map[string]interface{}{"exp": now.Add(-10 * time.Second).Unix()})),
+			expected: &jwtClaims{subject: "ci-pipeline", roles: []string{RoleConfigWrite}},
+		},
+		{
+			name:          "without expiry",
+			token:         signJWT(t, "RS256", "rsa", keys.rsa, validClaims(map[string]interface{}{"exp": nil})),
+			expectedError: "JWT has no exp claim",
+		},
+		{
+			name:          "not valid yet",
+			token:         signJWT(t, "RS256", "rsa", keys.rsa, validClaims(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()})),
+			expectedError: "JWT is not valid yet",
+		},
+		{
+			name:          "wrong issuer",
+			token:         signJWT(t, "RS256", "rsa", keys.rsa, validClaims(map[string]interface{}{"iss": "https://evil.example.com"})),
+			expectedError: "JWT issuer \"https://evil.example.com\" is not accepted",
+		},
+		{
+			name:          "wrong audience",
+			token:         signJWT(t, "RS256", "rsa", keys.rsa, validClaims(map[string]interface{}{"aud": "other"})),
+			expectedError: "JWT audience does not contain \"nginx-agent\"",
+		},
+		{
+			name:          "signed with another key",
+			token:         signJWT(t, "RS256", "rsa", newTestKeys(t).rsa, validClaims(nil)),
+			expectedError: "invalid JWT signature",
+		},
+		{
+			name:          "algorithm of another key type",
+			token:         signJWT(t, "ES256", "rsa", keys.ecdsa, validClaims(nil)),
+			expectedError: "invalid JWT signature",
+		},
+		{
+			name:          "algorithm not allowed by key",
+			token:         signJWT(t, "ES384", "ec", keys.ecdsa, validClaims(nil)),
+			expectedError: "no unique JWKS key found for JWT with kid \"ec\" and alg \"ES384\"",
+		},
+		{
+			name:          "unknown key",
+			token:         signJWT(t, "RS256", "unknown", keys.rsa, validClaims(nil)),
+			expectedError: "no unique JWKS key found for JWT with kid \"unknown\" and alg \"RS256\"",
+		},
+		{
+			name:          "encryption key",
+			token:         signJWT(t, "RS256", "enc", keys.rsa, validClaims(nil)),
+			expectedError: "no unique JWKS key found for JWT with kid \"enc\" and alg \"RS256\"",
+		},
+		{
+			name: "unsigned",
+			token: base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa"}`)) + "." +
+				base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"ci-pipeline","exp":1800000000,"roles":"config-write"}`)) + ".",
+			expectedError: "JWT algorithm \"none\" is not supported",
+		},
+		{
+			name:          "malformed",
+			token:         "a.b",
+			expectedError: "malformed JWT",
+		},
+	}
+
+	validator, err := newJWTValidator(jwksFile, "https://idp.example.com", "nginx-agent", "roles")
+	require.NoError(t, err)
+	validator.currentTime = func() time.Time { return now }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := validator.validate(tt.token)
+			if tt.expectedError != "" {
+				assert.EqualError(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, claims)
+		})
+	}
+}
+
+func TestJWTValidator_keyRotation(t *testing.T) {
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	oldKeys := newTestKeys(t)
+	oldKeys.writeJWKS(t, jwksFile)
+
+	validator, err := newJWTValidator(jwksFile, "", "", "roles")
+	require.NoError(t, err)
+
+	claims := map[string]interface{}{"sub": "ci-pipeline", "exp": time.Now().Add(time.Hour).Unix()}
+	_, err = validator.validate(signJWT(t, "RS256", "rsa", oldKeys.rsa, claims))
+	require.NoError(t, err)
+
+	newKeys := newTestKeys(t)
+	newKeys.writeJWKS(t, jwksFile)
+	modTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(jwksFile, modTime, modTime))
+
+	_, err = validator.validate(signJWT(t, "RS256", "rsa", oldKeys.rsa, claims))
+	assert.EqualError(t, err, "invalid JWT signature")
+	_, err = validator.validate(signJWT(t, "RS256", "rsa", newKeys.rsa, claims))
+	assert.NoError(t, err)
+}
+
+func TestParseJWKS(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		expectedError string
+	}{
+		{
+			name:          "invalid json",
+			content:       "keys",
+			expectedError: "invalid character 'k' looking for beginning of value",
+		},
+		{
+			name:          "no signing keys",
+			content:       `{"keys": []}`,
+			expectedError: "no signing keys found",
+		},
+		{
+			name:          "unsupported key type",
+			content:       `{"keys": [{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"}]}`,
+			expectedError: "key \"hmac\": unsupported key type \"oct\"",
+		},
+		{
+			name:          "point not on curve",
+			content:       `{"keys": [{"kty": "EC", "kid": "ec", "crv": "P-256", "x": "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", "y": "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAE"}]}`,
+			expectedError: "key \"ec\": invalid curve point",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseJWKS([]byte(tt.content))
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
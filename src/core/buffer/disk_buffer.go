@@ -0,0 +1,278 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package buffer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	models "github.com/nginx/agent/sdk/v2/proto/events"
+	"github.com/nginx/agent/v2/src/core"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	metricsReportExt = ".metrics"
+	eventReportExt   = ".events"
+	tmpExt           = ".tmp"
+	seqFormat        = "%020d"
+)
+
+var ErrUnsupportedPayload = errors.New("unsupported payload type")
+
+type entry struct {
+	seq     uint64
+	path    string
+	size    int64
+	created time.Time
+}
+
+// DiskBuffer is a FIFO queue of metrics and event reports persisted to a directory, one
+// file per report. The queue is bounded by the total size of the reports and by their
+// age, the oldest reports are dropped first when a limit is reached.
+type DiskBuffer struct {
+	dir     string
+	maxSize int64
+	maxAge  time.Duration
+	entries []*entry
+	size    int64
+	nextSeq uint64
+	mu      sync.Mutex
+}
+
+// NewDiskBuffer creates the buffer directory if needed and loads the reports that were
+// left in it, so reports buffered before a restart are not lost
+func NewDiskBuffer(dir string, maxSize int64, maxAge time.Duration) (*DiskBuffer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create buffer directory %s: %w", dir, err)
+	}
+
+	b := &DiskBuffer{
+		dir:     dir,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+		entries: []*entry{},
+	}
+
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (b *DiskBuffer) load() error {
+	files, err := os.ReadDir(b.dir)
+	if err != nil {
+		return fmt.Errorf("unable to read buffer directory %s: %w", b.dir, err)
+	}
+
+	for _, file := range files {
+		path := filepath.Join(b.dir, file.Name())
+		ext := filepath.Ext(file.Name())
+
+		if ext == tmpExt {
+			_ = os.Remove(path)
+			continue
+		}
+		if ext != metricsReportExt && ext != eventReportExt {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(file.Name(), ext), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+
+		b.entries = append(b.entries, &entry{seq: seq, path: path, size: info.Size(), created: info.ModTime()})
+		b.size += info.Size()
+		if seq >= b.nextSeq {
+			b.nextSeq = seq + 1
+		}
+	}
+
+	sort.Slice(b.entries, func(i, j int) bool {
+		return b.entries[i].seq < b.entries[j].seq
+	})
+
+	if len(b.entries) > 0 {
+		log.Infof("Loaded %d buffered reports from %s", len(b.entries), b.dir)
+	}
+
+	return nil
+}
+
+// Push appends a *proto.MetricsReport or *events.EventReport to the end of the buffer
+func (b *DiskBuffer) Push(payload core.Payload) error {
+	var data []byte
+	var ext string
+	var err error
+
+	switch report := payload.(type) {
+	case *proto.MetricsReport:
+		data, err = report.Marshal()
+		ext = metricsReportExt
+	case *models.EventReport:
+		data, err = report.Marshal()
+		ext = eventReportExt
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedPayload, payload)
+	}
+	if err != nil {
+		return err
+	}
+
+	size := int64(len(data))
+	if b.maxSize > 0 && size > b.maxSize {
+		return fmt.Errorf("report of %d bytes exceeds the buffer size of %d bytes", size, b.maxSize)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dropExpired()
+	for b.maxSize > 0 && b.size+size > b.maxSize && len(b.entries) > 0 {
+		log.Warnf("Buffer %s is full, dropping the oldest report", b.dir)
+		b.removeFirst()
+	}
+
+	seq := b.nextSeq
+	path := filepath.Join(b.dir, fmt.Sprintf(seqFormat, seq)+ext)
+	tmpPath := path + tmpExt
+
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	b.nextSeq++
+	b.entries = append(b.entries, &entry{seq: seq, path: path, size: size, created: time.Now()})
+	b.size += size
+
+	return nil
+}
+
+// Peek returns the oldest report in the buffer along with its sequence number without
+// removing it, or nil if the buffer is empty. Reports that can no longer be read are dropped.
+func (b *DiskBuffer) Peek() (uint64, core.Payload, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dropExpired()
+	for len(b.entries) > 0 {
+		payload, err := readEntry(b.entries[0])
+		if err == nil {
+			return b.entries[0].seq, payload, nil
+		}
+		log.Warnf("Dropping unreadable buffered report %s: %v", b.entries[0].path, err)
+		b.removeFirst()
+	}
+
+	return 0, nil, nil
+}
+
+// Remove removes the report with the sequence number returned by Peek. Nothing is removed
+// if the report was dropped in the meantime, so a report is never removed in its place.
+func (b *DiskBuffer) Remove(seq uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, e := range b.entries {
+		if e.seq == seq {
+			if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+				log.Warnf("Unable to remove buffered report %s: %v", e.path, err)
+			}
+			b.entries = append(b.entries[:i], b.entries[i+1:]...)
+			b.size -= e.size
+			return
+		}
+		if e.seq > seq {
+			return
+		}
+	}
+}
+
+// Len returns the number of reports in the buffer
+func (b *DiskBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.entries)
+}
+
+// Size returns the total size in bytes of the reports in the buffer
+func (b *DiskBuffer) Size() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.size
+}
+
+func (b *DiskBuffer) dropExpired() {
+	if b.maxAge <= 0 {
+		return
+	}
+
+	dropped := 0
+	for len(b.entries) > 0 && time.Since(b.entries[0].created) > b.maxAge {
+		b.removeFirst()
+		dropped++
+	}
+	if dropped > 0 {
+		log.Warnf("Dropped %d reports older than %s from buffer %s", dropped, b.maxAge, b.dir)
+	}
+}
+
+func (b *DiskBuffer) removeFirst() {
+	first := b.entries[0]
+	if err := os.Remove(first.path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Unable to remove buffered report %s: %v", first.path, err)
+	}
+	b.entries = b.entries[1:]
+	b.size -= first.size
+}
+
+func readEntry(e *entry) (core.Payload, error) {
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filepath.Ext(e.path) {
+	case metricsReportExt:
+		report := &proto.MetricsReport{}
+		if err := report.Unmarshal(data); err != nil {
+			return nil, err
+		}
+		return report, nil
+	case eventReportExt:
+		report := &models.EventReport{}
+		if err := report.Unmarshal(data); err != nil {
+			return nil, err
+		}
+		return report, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPayload, e.path)
+	}
+}
@@ -0,0 +1,163 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package buffer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	models "github.com/nginx/agent/sdk/v2/proto/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func metricsReport(value float64) *proto.MetricsReport {
+	return &proto.MetricsReport{
+		Type: proto.MetricsReport_SYSTEM,
+		Data: []*proto.StatsEntity{{Simplemetrics: []*proto.SimpleMetric{{Name: "system.cpu.idle", Value: value}}}},
+	}
+}
+
+func TestDiskBuffer(t *testing.T) {
+	dir := t.TempDir()
+	buffer, err := NewDiskBuffer(dir, 0, 0)
+	require.NoError(t, err)
+
+	seq, payload, err := buffer.Peek()
+	assert.NoError(t, err)
+	assert.Nil(t, payload)
+
+	require.NoError(t, buffer.Push(metricsReport(1)))
+	require.NoError(t, buffer.Push(&models.EventReport{Events: []*models.Event{{Metadata: &models.Metadata{UUID: "123"}}}}))
+	require.NoError(t, buffer.Push(metricsReport(3)))
+	assert.Error(t, buffer.Push("invalid"))
+	assert.Equal(t, 3, buffer.Len())
+
+	// reports are restored in order after a restart
+	buffer, err = NewDiskBuffer(dir, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, buffer.Len())
+
+	seq, payload, err = buffer.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), payload.(*proto.MetricsReport).Data[0].Simplemetrics[0].Value)
+	buffer.Remove(seq)
+
+	seq, payload, err = buffer.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, "123", payload.(*models.EventReport).Events[0].Metadata.UUID)
+	buffer.Remove(seq)
+
+	require.NoError(t, buffer.Push(metricsReport(4)))
+
+	seq, payload, err = buffer.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, float64(3), payload.(*proto.MetricsReport).Data[0].Simplemetrics[0].Value)
+	buffer.Remove(seq)
+
+	seq, payload, err = buffer.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, float64(4), payload.(*proto.MetricsReport).Data[0].Simplemetrics[0].Value)
+	buffer.Remove(seq)
+
+	assert.Equal(t, 0, buffer.Len())
+	assert.Equal(t, int64(0), buffer.Size())
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestDiskBuffer_RemoveDroppedReport(t *testing.T) {
+	data, err := metricsReport(1).Marshal()
+	require.NoError(t, err)
+
+	buffer, err := NewDiskBuffer(t.TempDir(), int64(len(data)), 0)
+	require.NoError(t, err)
+	require.NoError(t, buffer.Push(metricsReport(1)))
+
+	seq, _, err := buffer.Peek()
+	require.NoError(t, err)
+
+	// the peeked report is dropped for a newer one while it is being sent
+	require.NoError(t, buffer.Push(metricsReport(2)))
+	buffer.Remove(seq)
+
+	assert.Equal(t, 1, buffer.Len())
+	_, payload, err := buffer.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), payload.(*proto.MetricsReport).Data[0].Simplemetrics[0].Value)
+}
+
+func TestDiskBuffer_MaxSize(t *testing.T) {
+	data, err := metricsReport(1).Marshal()
+	require.NoError(t, err)
+	reportSize := int64(len(data))
+
+	buffer, err := NewDiskBuffer(t.TempDir(), 2*reportSize, 0)
+	require.NoError(t, err)
+
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, buffer.Push(metricsReport(float64(i))))
+	}
+
+	assert.Equal(t, 2, buffer.Len())
+	assert.Equal(t, 2*reportSize, buffer.Size())
+
+	_, payload, err := buffer.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), payload.(*proto.MetricsReport).Data[0].Simplemetrics[0].Value)
+
+	buffer, err = NewDiskBuffer(t.TempDir(), 1, 0)
+	require.NoError(t, err)
+	assert.Error(t, buffer.Push(metricsReport(1)))
+}
+
+func TestDiskBuffer_MaxAge(t *testing.T) {
+	dir := t.TempDir()
+	buffer, err := NewDiskBuffer(dir, 0, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, buffer.Push(metricsReport(1)))
+	require.NoError(t, buffer.Push(metricsReport(2)))
+
+	oldTime := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "00000000000000000000.metrics"), oldTime, oldTime))
+
+	buffer, err = NewDiskBuffer(dir, 0, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 2, buffer.Len())
+
+	_, payload, err := buffer.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), payload.(*proto.MetricsReport).Data[0].Simplemetrics[0].Value)
+	assert.Equal(t, 1, buffer.Len())
+}
+
+func TestDiskBuffer_CorruptReport(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000000.metrics"), []byte("invalid"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "00000000000000000001.metrics.tmp"), []byte("partial"), 0o600))
+
+	buffer, err := NewDiskBuffer(dir, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, buffer.Push(metricsReport(1)))
+	assert.Equal(t, 2, buffer.Len())
+
+	_, payload, err := buffer.Peek()
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), payload.(*proto.MetricsReport).Data[0].Simplemetrics[0].Value)
+	assert.Equal(t, 1, buffer.Len())
+
+	_, err = os.Stat(filepath.Join(dir, "00000000000000000001.metrics.tmp"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -109,6 +109,49 @@ func SetDefaults() {
 	Viper.SetDefault(MetricsReportInterval, Defaults.AgentMetrics.ReportInterval)
 	Viper.SetDefault(MetricsCollectionInterval, Defaults.AgentMetrics.CollectionInterval)
 
+	// AGENT API DEFAULTS
+	Viper.SetDefault(AgentAPIAuthJWTRolesClaim, Defaults.AgentAPI.Auth.JWTRolesClaim)
+
+	// OTLP DEFAULTS
+	Viper.SetDefault(OTLPProtocol, Defaults.OTLP.Protocol)
+	Viper.SetDefault(OTLPHeaders, Defaults.OTLP.Headers)
+	Viper.SetDefault(OTLPTimeout, Defaults.OTLP.Timeout)
+	Viper.SetDefault(OTLPBatchSize, Defaults.OTLP.BatchSize)
+	Viper.SetDefault(OTLPFlushInterval, Defaults.OTLP.FlushInterval)
+
+	// DISK BUFFER DEFAULTS
+	Viper.SetDefault(DiskBufferPath, Defaults.DiskBuffer.Path)
+	Viper.SetDefault(DiskBufferMaxSizeMB, Defaults.DiskBuffer.MaxSizeMB)
+	Viper.SetDefault(DiskBufferMaxAge, Defaults.DiskBuffer.MaxAge)
+
+	// CONFIG REVISIONS DEFAULTS
+	Viper.SetDefault(ConfigRevisionsPath, Defaults.ConfigRevisions.Path)
+	Viper.SetDefault(ConfigRevisionsMaxRevisions, Defaults.ConfigRevisions.MaxRevisions)
+
+	// ACCESS LOG METRICS DEFAULTS
+	Viper.SetDefault(AccessLogMetricsMaxDimensionValues, Defaults.AccessLogMetrics.MaxDimensionValues)
+	Viper.SetDefault(AccessLogMetricsHistogramSchema, Defaults.AccessLogMetrics.HistogramSchema)
+
+	// ERROR LOG EVENTS DEFAULTS
+	Viper.SetDefault(ErrorLogEventsLevels, Defaults.ErrorLogEvents.Levels)
+	Viper.SetDefault(ErrorLogEventsDedupWindow, Defaults.ErrorLogEvents.DedupWindow)
+	Viper.SetDefault(ErrorLogEventsRateLimit, Defaults.ErrorLogEvents.RateLimit)
+	Viper.SetDefault(CertificateMonitorEnable, Defaults.CertificateMonitor.Enable)
+	Viper.SetDefault(CertificateMonitorCheckInterval, Defaults.CertificateMonitor.CheckInterval)
+	Viper.SetDefault(CertificateMonitorWarningDays, Defaults.CertificateMonitor.WarningDays)
+	Viper.SetDefault(CertificateMonitorCriticalDays, Defaults.CertificateMonitor.CriticalDays)
+	Viper.SetDefault(ACMEEnable, Defaults.ACME.Enable)
+	Viper.SetDefault(ACMEDirectoryURL, Defaults.ACME.DirectoryURL)
+	Viper.SetDefault(ACMEAccountKey, Defaults.ACME.AccountKey)
+	Viper.SetDefault(ACMEChallengeDir, Defaults.ACME.ChallengeDir)
+	Viper.SetDefault(ACMESnippetPath, Defaults.ACME.SnippetPath)
+	Viper.SetDefault(ACMECertificates, Defaults.ACME.Certificates)
+	Viper.SetDefault(ACMERenewBefore, Defaults.ACME.RenewBefore)
+	Viper.SetDefault(ACMECheckInterval, Defaults.ACME.CheckInterval)
+
+	// MESSAGE PIPE DEFAULTS
+	Viper.SetDefault(MessagePipeSlowSubscriberDeadline, Defaults.MessagePipe.SlowSubscriberDeadline)
+
 	// NGINX DEFAULTS
 	Viper.SetDefault(NginxClientVersion, Defaults.Nginx.NginxClientVersion)
 	Viper.SetDefault(NginxConfigReloadMonitoringPeriod, Defaults.Nginx.ConfigReloadMonitoringPeriod)
@@ -119,7 +162,7 @@ func SetDefaults() {
 }
 
 func setFlagDeprecated(name string, usageMessage string) {
-	err := ROOT_COMMAND.Flags().MarkDeprecated(name, usageMessage)
+	err := ROOT_COMMAND.PersistentFlags().MarkDeprecated(name, usageMessage)
 	if err != nil {
 		log.Warnf("Error occurred deprecating flag %s: %v", name, err)
 	}
@@ -147,7 +190,7 @@ func RegisterFlags() {
 	Viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	Viper.AutomaticEnv()
 
-	fs := ROOT_COMMAND.Flags()
+	fs := ROOT_COMMAND.PersistentFlags()
 	for _, f := range append(agentFlags, deprecatedFlags...) {
 		f.register(fs)
 	}
@@ -195,6 +238,12 @@ func RegisterRunner(r func(cmd *cobra.Command, args []string)) {
 	ROOT_COMMAND.Run = r
 }
 
+// RegisterCommands adds subcommands to the root command. The agent flags are registered as
+// persistent flags, so the subcommands load the same configuration as the agent itself.
+func RegisterCommands(commands ...*cobra.Command) {
+	ROOT_COMMAND.AddCommand(commands...)
+}
+
 func GetConfig(clientId string) (*Config, error) {
 	extensions := []string{}
 
@@ -220,6 +269,14 @@ func GetConfig(clientId string) (*Config, error) {
 		Nginx:                 getNginx(),
 		Dataplane:             getDataplane(),
 		AgentMetrics:          getMetrics(),
+		OTLP:                  getOTLP(),
+		DiskBuffer:            getDiskBuffer(),
+		ConfigRevisions:       getConfigRevisions(),
+		AccessLogMetrics:      getAccessLogMetrics(),
+		ErrorLogEvents:        getErrorLogEvents(),
+		CertificateMonitor:    getCertificateMonitor(),
+		ACME:                  getACME(),
+		MessagePipe:           getMessagePipe(),
 		Features:              Viper.GetStringSlice(agent_config.FeaturesKey),
 		Extensions:            extensions,
 		Tags:                  Viper.GetStringSlice(TagsKey),
@@ -326,6 +383,90 @@ func getMetrics() AgentMetrics {
 	}
 }
 
+func getOTLP() OTLP {
+	return OTLP{
+		Endpoint:      Viper.GetString(OTLPEndpoint),
+		Protocol:      Viper.GetString(OTLPProtocol),
+		Headers:       Viper.GetStringMapString(OTLPHeaders),
+		Timeout:       Viper.GetDuration(OTLPTimeout),
+		BatchSize:     Viper.GetInt(OTLPBatchSize),
+		FlushInterval: Viper.GetDuration(OTLPFlushInterval),
+		TLS: TLSConfig{
+			Enable:     Viper.GetBool(OTLPTlsEnable),
+			Cert:       Viper.GetString(OTLPTlsCert),
+			Key:        Viper.GetString(OTLPTlsPrivateKey),
+			Ca:         Viper.GetString(OTLPTlsCa),
+			SkipVerify: Viper.GetBool(OTLPTlsSkipVerify),
+		},
+	}
+}
+
+func getDiskBuffer() DiskBuffer {
+	return DiskBuffer{
+		Enable:    Viper.GetBool(DiskBufferEnable),
+		Path:      Viper.GetString(DiskBufferPath),
+		MaxSizeMB: Viper.GetInt(DiskBufferMaxSizeMB),
+		MaxAge:    Viper.GetDuration(DiskBufferMaxAge),
+	}
+}
+
+func getConfigRevisions() ConfigRevisions {
+	return ConfigRevisions{
+		Enable:       Viper.GetBool(ConfigRevisionsEnable),
+		Path:         Viper.GetString(ConfigRevisionsPath),
+		MaxRevisions: Viper.GetInt(ConfigRevisionsMaxRevisions),
+	}
+}
+
+func getAccessLogMetrics() AccessLogMetrics {
+	return AccessLogMetrics{
+		Dimensions:         Viper.GetStringSlice(AccessLogMetricsDimensions),
+		URIPrefixes:        Viper.GetStringSlice(AccessLogMetricsURIPrefixes),
+		MaxDimensionValues: Viper.GetInt(AccessLogMetricsMaxDimensionValues),
+		Histograms:         Viper.GetBool(AccessLogMetricsHistograms),
+		HistogramSchema:    Viper.GetInt(AccessLogMetricsHistogramSchema),
+	}
+}
+
+func getErrorLogEvents() ErrorLogEvents {
+	return ErrorLogEvents{
+		Enable:      Viper.GetBool(ErrorLogEventsEnable),
+		Levels:      Viper.GetStringSlice(ErrorLogEventsLevels),
+		DedupWindow: Viper.GetDuration(ErrorLogEventsDedupWindow),
+		RateLimit:   Viper.GetInt(ErrorLogEventsRateLimit),
+	}
+}
+
+func getCertificateMonitor() CertificateMonitor {
+	return CertificateMonitor{
+		Enable:        Viper.GetBool(CertificateMonitorEnable),
+		CheckInterval: Viper.GetDuration(CertificateMonitorCheckInterval),
+		WarningDays:   Viper.GetInt(CertificateMonitorWarningDays),
+		CriticalDays:  Viper.GetInt(CertificateMonitorCriticalDays),
+	}
+}
+
+func getACME() ACME {
+	return ACME{
+		Enable:        Viper.GetBool(ACMEEnable),
+		DirectoryURL:  Viper.GetString(ACMEDirectoryURL),
+		Email:         Viper.GetString(ACMEEmail),
+		AccountKey:    Viper.GetString(ACMEAccountKey),
+		Ca:            Viper.GetString(ACMECa),
+		ChallengeDir:  Viper.GetString(ACMEChallengeDir),
+		SnippetPath:   Viper.GetString(ACMESnippetPath),
+		Certificates:  Viper.GetStringSlice(ACMECertificates),
+		RenewBefore:   Viper.GetDuration(ACMERenewBefore),
+		CheckInterval: Viper.GetDuration(ACMECheckInterval),
+	}
+}
+
+func getMessagePipe() MessagePipe {
+	return MessagePipe{
+		SlowSubscriberDeadline: Viper.GetDuration(MessagePipeSlowSubscriberDeadline),
+	}
+}
+
 func getLog() LogConfig {
 	return LogConfig{
 		Level: Viper.GetString(LogLevel),
@@ -386,10 +527,23 @@ func getMetricsBackOff() Backoff {
 
 func getAgentAPI() AgentAPI {
 	return AgentAPI{
-		Host: Viper.GetString(AgentAPIHost),
-		Port: Viper.GetInt(AgentAPIPort),
-		Cert: Viper.GetString(AgentAPICert),
-		Key:  Viper.GetString(AgentAPIKey),
+		Host:     Viper.GetString(AgentAPIHost),
+		Port:     Viper.GetInt(AgentAPIPort),
+		Cert:     Viper.GetString(AgentAPICert),
+		Key:      Viper.GetString(AgentAPIKey),
+		ClientCA: Viper.GetString(AgentAPIClientCA),
+		Auth: AgentAPIAuth{
+			Enable:              Viper.GetBool(AgentAPIAuthEnable),
+			ReadOnlyTokens:      Viper.GetStringSlice(AgentAPIAuthReadOnlyTokens),
+			ConfigWriteTokens:   Viper.GetStringSlice(AgentAPIAuthConfigWriteTokens),
+			ReadOnlySubjects:    Viper.GetStringSlice(AgentAPIAuthReadOnlySubjects),
+			ConfigWriteSubjects: Viper.GetStringSlice(AgentAPIAuthConfigWriteSubjects),
+			JWKSFile:            Viper.GetString(AgentAPIAuthJWKSFile),
+			JWTIssuer:           Viper.GetString(AgentAPIAuthJWTIssuer),
+			JWTAudience:         Viper.GetString(AgentAPIAuthJWTAudience),
+			JWTRolesClaim:       Viper.GetString(AgentAPIAuthJWTRolesClaim),
+			AuditLog:            Viper.GetString(AgentAPIAuthAuditLog),
+		},
 	}
 }
 
@@ -471,6 +625,86 @@ func LoadPropertiesFromFile(cfg string) error {
 	return nil
 }
 
+// ReloadConfigFiles reads the agent config file and the dynamic config file again, replacing the
+// values previously loaded from them. Flags, environment variables and values set at runtime,
+// like the tags and features received from the management plane, keep precedence over the files.
+func ReloadConfigFiles() error {
+	cfg := Viper.GetString(ConfigPathKey)
+	dynamicCfgPath := Viper.GetString(DynamicConfigPathKey)
+	if dynamicCfgPath == "" {
+		dynamicCfgPath = getDefaultDynamicConfPath()
+	}
+
+	// parse both files up front, so an invalid file keeps the previously loaded values in place
+	for _, path := range []string{cfg, dynamicCfgPath} {
+		err := ParseConfigFile(path)
+		if err != nil && !(path == dynamicCfgPath && errors.Is(err, fs.ErrNotExist)) {
+			return fmt.Errorf("error loading config file %s: %v", path, err)
+		}
+	}
+
+	Viper.SetConfigFile(cfg)
+	Viper.SetConfigType(ConfigFileType)
+	err := Viper.ReadInConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config file %s: %v", cfg, err)
+	}
+
+	Viper.SetConfigFile(dynamicCfgPath)
+	err = Viper.MergeInConfig()
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Debugf("Dynamic config %s does not exist, skipping it", dynamicCfgPath)
+	} else if err != nil {
+		return fmt.Errorf("error loading file %s: %v", dynamicCfgPath, err)
+	}
+
+	return nil
+}
+
+// ParseConfigFile parses a config file without loading its values
+func ParseConfigFile(path string) error {
+	v := viper.NewWithOptions(viper.KeyDelimiter(agent_config.KeyDelimiter))
+	v.SetConfigFile(path)
+	v.SetConfigType(ConfigFileType)
+	return v.ReadInConfig()
+}
+
+// ConfigFiles returns the path of the agent config file found in the config search paths and
+// the path of the dynamic config file
+func ConfigFiles() (string, string, error) {
+	dynamicCfgPath := Viper.GetString(DynamicConfigPathKey)
+	if dynamicCfgPath == "" {
+		dynamicCfgPath = getDefaultDynamicConfPath()
+	}
+
+	cfg, err := SeekConfigFileInPaths(ConfigFileName, ConfigFilePaths()...)
+	if err != nil {
+		return "", dynamicCfgPath, err
+	}
+
+	return cfg, dynamicCfgPath, nil
+}
+
+// DiffConfig returns the names of the top level settings, as used in the config file, whose
+// values differ between the running and the updated config
+func DiffConfig(running, updated *Config) []string {
+	changed := []string{}
+
+	runningValue := reflect.ValueOf(*running)
+	updatedValue := reflect.ValueOf(*updated)
+	for i := 0; i < runningValue.NumField(); i++ {
+		name, ok := runningValue.Type().Field(i).Tag.Lookup("mapstructure")
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(runningValue.Field(i).Interface(), updatedValue.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+
+	return changed
+}
+
 // removeFeatures removes enabled features from dynamic config content
 func removeFeatures(readFile io.Reader) (bool, []byte, error) {
 	fileScanner := bufio.NewScanner(readFile)
@@ -161,6 +161,48 @@ func TestGetConfig(t *testing.T) {
 		assert.Equal(t, Defaults.AgentMetrics.ReportInterval, config.AgentMetrics.ReportInterval)
 		assert.Equal(t, Defaults.AgentMetrics.CollectionInterval, config.AgentMetrics.CollectionInterval)
 
+		assert.Equal(t, Defaults.OTLP.Endpoint, config.OTLP.Endpoint)
+		assert.Equal(t, Defaults.OTLP.Protocol, config.OTLP.Protocol)
+		assert.Equal(t, Defaults.OTLP.Timeout, config.OTLP.Timeout)
+		assert.Equal(t, Defaults.OTLP.BatchSize, config.OTLP.BatchSize)
+		assert.Equal(t, Defaults.OTLP.FlushInterval, config.OTLP.FlushInterval)
+
+		assert.Equal(t, Defaults.DiskBuffer.Enable, config.DiskBuffer.Enable)
+		assert.Equal(t, Defaults.DiskBuffer.Path, config.DiskBuffer.Path)
+		assert.Equal(t, Defaults.DiskBuffer.MaxSizeMB, config.DiskBuffer.MaxSizeMB)
+		assert.Equal(t, Defaults.DiskBuffer.MaxAge, config.DiskBuffer.MaxAge)
+
+		assert.Equal(t, Defaults.ConfigRevisions.Enable, config.ConfigRevisions.Enable)
+		assert.Equal(t, Defaults.ConfigRevisions.Path, config.ConfigRevisions.Path)
+		assert.Equal(t, Defaults.ConfigRevisions.MaxRevisions, config.ConfigRevisions.MaxRevisions)
+
+		assert.Equal(t, Defaults.AccessLogMetrics.Dimensions, config.AccessLogMetrics.Dimensions)
+		assert.Equal(t, Defaults.AccessLogMetrics.URIPrefixes, config.AccessLogMetrics.URIPrefixes)
+		assert.Equal(t, Defaults.AccessLogMetrics.MaxDimensionValues, config.AccessLogMetrics.MaxDimensionValues)
+		assert.Equal(t, Defaults.AccessLogMetrics.Histograms, config.AccessLogMetrics.Histograms)
+		assert.Equal(t, Defaults.AccessLogMetrics.HistogramSchema, config.AccessLogMetrics.HistogramSchema)
+
+		assert.Equal(t, Defaults.ErrorLogEvents.Enable, config.ErrorLogEvents.Enable)
+		assert.Equal(t, Defaults.ErrorLogEvents.Levels, config.ErrorLogEvents.Levels)
+		assert.Equal(t, Defaults.ErrorLogEvents.DedupWindow, config.ErrorLogEvents.DedupWindow)
+		assert.Equal(t, Defaults.ErrorLogEvents.RateLimit, config.ErrorLogEvents.RateLimit)
+
+		assert.Equal(t, Defaults.CertificateMonitor.Enable, config.CertificateMonitor.Enable)
+		assert.Equal(t, Defaults.CertificateMonitor.CheckInterval, config.CertificateMonitor.CheckInterval)
+		assert.Equal(t, Defaults.CertificateMonitor.WarningDays, config.CertificateMonitor.WarningDays)
+		assert.Equal(t, Defaults.CertificateMonitor.CriticalDays, config.CertificateMonitor.CriticalDays)
+
+		assert.Equal(t, Defaults.ACME.Enable, config.ACME.Enable)
+		assert.Equal(t, Defaults.ACME.DirectoryURL, config.ACME.DirectoryURL)
+		assert.Equal(t, Defaults.ACME.AccountKey, config.ACME.AccountKey)
+		assert.Equal(t, Defaults.ACME.ChallengeDir, config.ACME.ChallengeDir)
+		assert.Equal(t, Defaults.ACME.SnippetPath, config.ACME.SnippetPath)
+		assert.Equal(t, Defaults.ACME.Certificates, config.ACME.Certificates)
+		assert.Equal(t, Defaults.ACME.RenewBefore, config.ACME.RenewBefore)
+		assert.Equal(t, Defaults.ACME.CheckInterval, config.ACME.CheckInterval)
+
+		assert.Equal(t, Defaults.MessagePipe.SlowSubscriberDeadline, config.MessagePipe.SlowSubscriberDeadline)
+
 		assert.Equal(t, []string{}, config.Tags)
 		assert.Equal(t, Defaults.Features, config.Features)
 		assert.Equal(t, []string{}, config.Extensions)
@@ -526,6 +568,83 @@ func setEnvVariable(t *testing.T, prefix, name, value string) {
 	require.NoError(t, err)
 }
 
+func TestReloadConfigFiles(t *testing.T) {
+	curDir, err := os.Getwd()
+	require.NoError(t, err)
+
+	tempConfDeleteFunc, err := sysutils.CopyFile(fmt.Sprintf("%s/%s", testCfgDir, emptyConfigFile), tempCfgFile)
+	defer func() {
+		err := tempConfDeleteFunc()
+		require.NoError(t, err, "deletion of temp config file failed")
+	}()
+	require.NoError(t, err)
+
+	tempDynamicDeleteFunc, err := sysutils.CopyFile(fmt.Sprintf("%s/%s", testCfgDir, emptyConfigFile), tempDynamicCfgFile)
+	defer func() {
+		err := tempDynamicDeleteFunc()
+		require.NoError(t, err, "deletion of temp dynamic config file failed")
+	}()
+	require.NoError(t, err)
+
+	cleanEnv(t, tempCfgFile, fmt.Sprintf("%s/%s", curDir, tempDynamicCfgFile), true)
+
+	config, err := GetConfig("12345")
+	require.NoError(t, err)
+	assert.Equal(t, Defaults.Log.Level, config.Log.Level)
+
+	err = os.WriteFile(tempCfgFile, []byte("log:\n  level: "+updatedLogLevel+"\n"), 0o640)
+	require.NoError(t, err)
+	err = os.WriteFile(tempDynamicCfgFile, []byte("tags:\n  - updated-locally-tagged\n  - updated-tagged-locally\n"), 0o640)
+	require.NoError(t, err)
+
+	require.NoError(t, ReloadConfigFiles())
+
+	config, err = GetConfig("12345")
+	require.NoError(t, err)
+	assert.Equal(t, updatedLogLevel, config.Log.Level)
+	assert.Equal(t, updatedConfTags, config.Tags)
+
+	// settings removed from the config file fall back to their defaults
+	err = os.WriteFile(tempCfgFile, []byte(""), 0o640)
+	require.NoError(t, err)
+
+	require.NoError(t, ReloadConfigFiles())
+
+	config, err = GetConfig("12345")
+	require.NoError(t, err)
+	assert.Equal(t, Defaults.Log.Level, config.Log.Level)
+	assert.Equal(t, updatedConfTags, config.Tags)
+
+	// an invalid config file keeps the previously loaded values
+	err = os.WriteFile(tempDynamicCfgFile, []byte("tags: [\n"), 0o640)
+	require.NoError(t, err)
+
+	assert.Error(t, ReloadConfigFiles())
+
+	config, err = GetConfig("12345")
+	require.NoError(t, err)
+	assert.Equal(t, updatedConfTags, config.Tags)
+}
+
+func TestDiffConfig(t *testing.T) {
+	running := &Config{
+		Log:                   LogConfig{Level: "info"},
+		Features:              []string{agent_config.FeatureMetrics},
+		AllowedDirectoriesMap: map[string]struct{}{"/etc/nginx": {}},
+		Updated:               time.Now(),
+	}
+	updated := &Config{
+		Log:                   LogConfig{Level: "debug"},
+		Features:              []string{agent_config.FeatureMetrics},
+		AllowedDirectoriesMap: map[string]struct{}{},
+		Updated:               time.Now().Add(time.Minute),
+		QueueSize:             10,
+	}
+
+	assert.Equal(t, []string{"log", "queue_size"}, DiffConfig(running, updated))
+	assert.Equal(t, []string{}, DiffConfig(running, running))
+}
+
 func cleanEnv(t *testing.T, confFileName, dynamicConfFileAbsPath string, register bool) {
 	os.Clearenv()
 	ROOT_COMMAND.ResetFlags()
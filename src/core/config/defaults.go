@@ -95,6 +95,59 @@ var (
 		},
 		AgentAPI: AgentAPI{
 			Host: "127.0.0.1",
+			Auth: AgentAPIAuth{
+				JWTRolesClaim: "roles",
+			},
+		},
+		OTLP: OTLP{
+			Protocol:      "grpc",
+			Headers:       map[string]string{},
+			Timeout:       10 * time.Second,
+			BatchSize:     20,
+			FlushInterval: 30 * time.Second,
+		},
+		DiskBuffer: DiskBuffer{
+			Enable:    false,
+			Path:      getDefaultDiskBufferPath(),
+			MaxSizeMB: 100,
+			MaxAge:    24 * time.Hour,
+		},
+		ConfigRevisions: ConfigRevisions{
+			Enable:       false,
+			Path:         getDefaultConfigRevisionsPath(),
+			MaxRevisions: 10,
+		},
+		AccessLogMetrics: AccessLogMetrics{
+			Dimensions:         []string{},
+			URIPrefixes:        []string{},
+			MaxDimensionValues: 100,
+			Histograms:         false,
+			HistogramSchema:    3,
+		},
+		ErrorLogEvents: ErrorLogEvents{
+			Enable:      false,
+			Levels:      []string{"error", "crit", "alert", "emerg"},
+			DedupWindow: 5 * time.Minute,
+			RateLimit:   10,
+		},
+		CertificateMonitor: CertificateMonitor{
+			Enable:        true,
+			CheckInterval: time.Hour,
+			WarningDays:   30,
+			CriticalDays:  7,
+		},
+		ACME: ACME{
+			Enable:        false,
+			DirectoryURL:  "https://acme-v02.api.letsencrypt.org/directory",
+			AccountKey:    "/var/lib/nginx-agent/acme/account.key",
+			ChallengeDir:  "/var/lib/nginx-agent/acme/challenges",
+			SnippetPath:   "/etc/nginx/acme-challenge.conf",
+			Certificates:  []string{},
+			RenewBefore:   30 * 24 * time.Hour,
+			CheckInterval: 12 * time.Hour,
+		},
+		MessagePipe: MessagePipe{
+			SlowSubscriberDeadline: 5 * time.Second,
 		},
 		Features:  agent_config.GetDefaultFeatures(),
 		QueueSize: 100,
@@ -108,6 +161,10 @@ const (
 	DynamicConfigFileName           = "agent-dynamic.conf"
 	DynamicConfigFileAbsPath        = "/var/lib/nginx-agent/agent-dynamic.conf"
 	DynamicConfigFileAbsFreeBsdPath = "/var/db/nginx-agent/agent-dynamic.conf"
+	DiskBufferAbsPath               = "/var/lib/nginx-agent/buffer"
+	DiskBufferAbsFreeBsdPath        = "/var/db/nginx-agent/buffer"
+	ConfigRevisionsAbsPath          = "/var/lib/nginx-agent/revisions"
+	ConfigRevisionsAbsFreeBsdPath   = "/var/db/nginx-agent/revisions"
 	ConfigFileName                  = "nginx-agent.conf"
 	ConfigFileType                  = "yaml"
 	LegacyEnvPrefix                 = "nms"
@@ -152,10 +209,23 @@ const (
 	// viper keys used in config
 	APIKey = "api"
 
-	AgentAPIHost = APIKey + agent_config.KeyDelimiter + "host"
-	AgentAPIPort = APIKey + agent_config.KeyDelimiter + "port"
-	AgentAPICert = APIKey + agent_config.KeyDelimiter + "cert"
-	AgentAPIKey  = APIKey + agent_config.KeyDelimiter + "key"
+	AgentAPIHost     = APIKey + agent_config.KeyDelimiter + "host"
+	AgentAPIPort     = APIKey + agent_config.KeyDelimiter + "port"
+	AgentAPICert     = APIKey + agent_config.KeyDelimiter + "cert"
+	AgentAPIKey      = APIKey + agent_config.KeyDelimiter + "key"
+	AgentAPIClientCA = APIKey + agent_config.KeyDelimiter + "client_ca"
+
+	AgentAPIAuthKey                 = APIKey + agent_config.KeyDelimiter + "auth"
+	AgentAPIAuthEnable              = AgentAPIAuthKey + agent_config.KeyDelimiter + "enable"
+	AgentAPIAuthReadOnlyTokens      = AgentAPIAuthKey + agent_config.KeyDelimiter + "read_only_tokens"
+	AgentAPIAuthConfigWriteTokens   = AgentAPIAuthKey + agent_config.KeyDelimiter + "config_write_tokens"
+	AgentAPIAuthReadOnlySubjects    = AgentAPIAuthKey + agent_config.KeyDelimiter + "read_only_subjects"
+	AgentAPIAuthConfigWriteSubjects = AgentAPIAuthKey + agent_config.KeyDelimiter + "config_write_subjects"
+	AgentAPIAuthJWKSFile            = AgentAPIAuthKey + agent_config.KeyDelimiter + "jwks_file"
+	AgentAPIAuthJWTIssuer           = AgentAPIAuthKey + agent_config.KeyDelimiter + "jwt_issuer"
+	AgentAPIAuthJWTAudience         = AgentAPIAuthKey + agent_config.KeyDelimiter + "jwt_audience"
+	AgentAPIAuthJWTRolesClaim       = AgentAPIAuthKey + agent_config.KeyDelimiter + "jwt_roles_claim"
+	AgentAPIAuthAuditLog            = AgentAPIAuthKey + agent_config.KeyDelimiter + "audit_log"
 
 	// viper keys used in config
 	TlsKey = "tls"
@@ -199,6 +269,75 @@ const (
 	BackoffMetricsMaxInterval         = MetricsKey + agent_config.KeyDelimiter + BackoffKey + agent_config.KeyDelimiter + "max_interval"
 	BackoffMetricsMaxElapsedTime      = MetricsKey + agent_config.KeyDelimiter + BackoffKey + agent_config.KeyDelimiter + "max_elapsed_time"
 
+	// viper keys used in config
+	OTLPKey = "otlp"
+
+	OTLPEndpoint      = OTLPKey + agent_config.KeyDelimiter + "endpoint"
+	OTLPProtocol      = OTLPKey + agent_config.KeyDelimiter + "protocol"
+	OTLPHeaders       = OTLPKey + agent_config.KeyDelimiter + "headers"
+	OTLPTimeout       = OTLPKey + agent_config.KeyDelimiter + "timeout"
+	OTLPBatchSize     = OTLPKey + agent_config.KeyDelimiter + "batch_size"
+	OTLPFlushInterval = OTLPKey + agent_config.KeyDelimiter + "flush_interval"
+	OTLPTlsEnable     = OTLPKey + agent_config.KeyDelimiter + TlsKey + agent_config.KeyDelimiter + "enable"
+	OTLPTlsCert       = OTLPKey + agent_config.KeyDelimiter + TlsKey + agent_config.KeyDelimiter + "cert"
+	OTLPTlsPrivateKey = OTLPKey + agent_config.KeyDelimiter + TlsKey + agent_config.KeyDelimiter + "key"
+	OTLPTlsCa         = OTLPKey + agent_config.KeyDelimiter + TlsKey + agent_config.KeyDelimiter + "ca"
+	OTLPTlsSkipVerify = OTLPKey + agent_config.KeyDelimiter + TlsKey + agent_config.KeyDelimiter + "skip_verify"
+
+	// viper keys used in config
+	DiskBufferKey = "disk_buffer"
+
+	DiskBufferEnable    = DiskBufferKey + agent_config.KeyDelimiter + "enable"
+	DiskBufferPath      = DiskBufferKey + agent_config.KeyDelimiter + "path"
+	DiskBufferMaxSizeMB = DiskBufferKey + agent_config.KeyDelimiter + "max_size_mb"
+	DiskBufferMaxAge    = DiskBufferKey + agent_config.KeyDelimiter + "max_age"
+
+	ConfigRevisionsKey = "config_revisions"
+
+	ConfigRevisionsEnable       = ConfigRevisionsKey + agent_config.KeyDelimiter + "enable"
+	ConfigRevisionsPath         = ConfigRevisionsKey + agent_config.KeyDelimiter + "path"
+	ConfigRevisionsMaxRevisions = ConfigRevisionsKey + agent_config.KeyDelimiter + "max_revisions"
+
+	// viper keys used in config
+	AccessLogMetricsKey = "access_log_metrics"
+
+	AccessLogMetricsDimensions         = AccessLogMetricsKey + agent_config.KeyDelimiter + "dimensions"
+	AccessLogMetricsURIPrefixes        = AccessLogMetricsKey + agent_config.KeyDelimiter + "uri_prefixes"
+	AccessLogMetricsMaxDimensionValues = AccessLogMetricsKey + agent_config.KeyDelimiter + "max_dimension_values"
+	AccessLogMetricsHistograms         = AccessLogMetricsKey + agent_config.KeyDelimiter + "histograms"
+	AccessLogMetricsHistogramSchema    = AccessLogMetricsKey + agent_config.KeyDelimiter + "histogram_schema"
+
+	ErrorLogEventsKey = "error_log_events"
+
+	ErrorLogEventsEnable      = ErrorLogEventsKey + agent_config.KeyDelimiter + "enable"
+	ErrorLogEventsLevels      = ErrorLogEventsKey + agent_config.KeyDelimiter + "levels"
+	ErrorLogEventsDedupWindow = ErrorLogEventsKey + agent_config.KeyDelimiter + "dedup_window"
+	ErrorLogEventsRateLimit   = ErrorLogEventsKey + agent_config.KeyDelimiter + "rate_limit"
+
+	CertificateMonitorKey = "certificate_monitor"
+
+	CertificateMonitorEnable        = CertificateMonitorKey + agent_config.KeyDelimiter + "enable"
+	CertificateMonitorCheckInterval = CertificateMonitorKey + agent_config.KeyDelimiter + "check_interval"
+	CertificateMonitorWarningDays   = CertificateMonitorKey + agent_config.KeyDelimiter + "warning_days"
+	CertificateMonitorCriticalDays  = CertificateMonitorKey + agent_config.KeyDelimiter + "critical_days"
+
+	ACMEKey = "acme"
+
+	ACMEEnable        = ACMEKey + agent_config.KeyDelimiter + "enable"
+	ACMEDirectoryURL  = ACMEKey + agent_config.KeyDelimiter + "directory_url"
+	ACMEEmail         = ACMEKey + agent_config.KeyDelimiter + "email"
+	ACMEAccountKey    = ACMEKey + agent_config.KeyDelimiter + "account_key"
+	ACMECa            = ACMEKey + agent_config.KeyDelimiter + "ca"
+	ACMEChallengeDir  = ACMEKey + agent_config.KeyDelimiter + "challenge_dir"
+	ACMESnippetPath   = ACMEKey + agent_config.KeyDelimiter + "snippet_path"
+	ACMECertificates  = ACMEKey + agent_config.KeyDelimiter + "certificates"
+	ACMERenewBefore   = ACMEKey + agent_config.KeyDelimiter + "renew_before"
+	ACMECheckInterval = ACMEKey + agent_config.KeyDelimiter + "check_interval"
+
+	MessagePipeKey = "message_pipe"
+
+	MessagePipeSlowSubscriberDeadline = MessagePipeKey + agent_config.KeyDelimiter + "slow_subscriber_deadline"
+
 	// DEPRECATED KEYS
 	AdvancedMetricsKey                  = "advanced_metrics"
 	AdvancedMetricsSocketPath           = AdvancedMetricsKey + agent_config.KeyDelimiter + "socket_path"
@@ -277,6 +416,52 @@ var (
 			Usage:        "The key used by the Agent API.",
 			DefaultValue: "",
 		},
+		&StringFlag{
+			Name:  AgentAPIClientCA,
+			Usage: "The CA used by the Agent API to verify client certificates.",
+		},
+		&BoolFlag{
+			Name:         AgentAPIAuthEnable,
+			Usage:        "Requires Agent API requests to be authenticated with a bearer token, a JWT or a client certificate.",
+			DefaultValue: Defaults.AgentAPI.Auth.Enable,
+		},
+		&StringSliceFlag{
+			Name:  AgentAPIAuthReadOnlyTokens,
+			Usage: "A comma-separated list of static bearer tokens granted the read-only role by the Agent API.",
+		},
+		&StringSliceFlag{
+			Name:  AgentAPIAuthConfigWriteTokens,
+			Usage: "A comma-separated list of static bearer tokens granted the config-write role by the Agent API.",
+		},
+		&StringSliceFlag{
+			Name:  AgentAPIAuthReadOnlySubjects,
+			Usage: "A comma-separated list of client certificate subjects granted the read-only role by the Agent API.",
+		},
+		&StringSliceFlag{
+			Name:  AgentAPIAuthConfigWriteSubjects,
+			Usage: "A comma-separated list of client certificate subjects granted the config-write role by the Agent API.",
+		},
+		&StringFlag{
+			Name:  AgentAPIAuthJWKSFile,
+			Usage: "The JWKS file with the public keys the Agent API validates JWT bearer tokens with.",
+		},
+		&StringFlag{
+			Name:  AgentAPIAuthJWTIssuer,
+			Usage: "The issuer JWT bearer tokens must have to be accepted by the Agent API.",
+		},
+		&StringFlag{
+			Name:  AgentAPIAuthJWTAudience,
+			Usage: "The audience JWT bearer tokens must have to be accepted by the Agent API.",
+		},
+		&StringFlag{
+			Name:         AgentAPIAuthJWTRolesClaim,
+			Usage:        "The JWT claim with the Agent API roles granted to the token.",
+			DefaultValue: Defaults.AgentAPI.Auth.JWTRolesClaim,
+		},
+		&StringFlag{
+			Name:  AgentAPIAuthAuditLog,
+			Usage: "The file mutating Agent API requests are logged to. If not set, they are logged to the agent log.",
+		},
 		&StringFlag{
 			Name:         DynamicConfigPathKey,
 			Usage:        "Defines the path of the Agent dynamic config file.",
@@ -365,6 +550,209 @@ var (
 			Usage:        "Only intended for demonstration, sets InsecureSkipVerify for gRPC TLS credentials",
 			DefaultValue: Defaults.TLS.SkipVerify,
 		},
+		// OTLP
+		&StringFlag{
+			Name:  OTLPEndpoint,
+			Usage: "The endpoint of the OpenTelemetry collector that metrics are exported to. Metrics are not exported over OTLP if no endpoint is set.",
+		},
+		&StringFlag{
+			Name:         OTLPProtocol,
+			Usage:        "The OTLP protocol used to export metrics: grpc or http.",
+			DefaultValue: Defaults.OTLP.Protocol,
+		},
+		&DurationFlag{
+			Name:         OTLPTimeout,
+			Usage:        "The timeout of a single OTLP export request.",
+			DefaultValue: Defaults.OTLP.Timeout,
+		},
+		&IntFlag{
+			Name:         OTLPBatchSize,
+			Usage:        "The amount of metrics reports buffered before they are exported over OTLP.",
+			DefaultValue: Defaults.OTLP.BatchSize,
+		},
+		&DurationFlag{
+			Name:         OTLPFlushInterval,
+			Usage:        "The maximum amount of time metrics reports are buffered before they are exported over OTLP.",
+			DefaultValue: Defaults.OTLP.FlushInterval,
+		},
+		&BoolFlag{
+			Name:  OTLPTlsEnable,
+			Usage: "Enables TLS for the connection to the OpenTelemetry collector.",
+		},
+		&StringFlag{
+			Name:  OTLPTlsCert,
+			Usage: "The path to the certificate file to use for the OTLP TLS connection.",
+		},
+		&StringFlag{
+			Name:  OTLPTlsPrivateKey,
+			Usage: "The path to the certificate key file to use for the OTLP TLS connection.",
+		},
+		&StringFlag{
+			Name:  OTLPTlsCa,
+			Usage: "The path to the CA certificate file to use for the OTLP TLS connection.",
+		},
+		&BoolFlag{
+			Name:  OTLPTlsSkipVerify,
+			Usage: "Only intended for demonstration, sets InsecureSkipVerify for the OTLP TLS connection.",
+		},
+		// Disk Buffer
+		&BoolFlag{
+			Name:         DiskBufferEnable,
+			Usage:        "Enables persisting metrics and event reports to disk when they can not be sent to the management plane.",
+			DefaultValue: Defaults.DiskBuffer.Enable,
+		},
+		&StringFlag{
+			Name:         DiskBufferPath,
+			Usage:        "The directory where undelivered metrics and event reports are stored.",
+			DefaultValue: Defaults.DiskBuffer.Path,
+		},
+		&IntFlag{
+			Name:         DiskBufferMaxSizeMB,
+			Usage:        "The maximum size, in megabytes, of the undelivered reports stored on disk. The oldest reports are dropped first.",
+			DefaultValue: Defaults.DiskBuffer.MaxSizeMB,
+		},
+		&DurationFlag{
+			Name:         DiskBufferMaxAge,
+			Usage:        "The maximum age of the undelivered reports stored on disk. Older reports are dropped.",
+			DefaultValue: Defaults.DiskBuffer.MaxAge,
+		},
+		// Config Revisions
+		&BoolFlag{
+			Name:         ConfigRevisionsEnable,
+			Usage:        "Enables keeping a history of applied NGINX configurations that can be reverted to.",
+			DefaultValue: Defaults.ConfigRevisions.Enable,
+		},
+		&StringFlag{
+			Name:         ConfigRevisionsPath,
+			Usage:        "The directory where applied NGINX configurations are stored.",
+			DefaultValue: Defaults.ConfigRevisions.Path,
+		},
+		&IntFlag{
+			Name:         ConfigRevisionsMaxRevisions,
+			Usage:        "The number of applied NGINX configurations to keep for each NGINX instance. The oldest configurations are removed first.",
+			DefaultValue: Defaults.ConfigRevisions.MaxRevisions,
+		},
+		// Access Log Metrics
+		&StringSliceFlag{
+			Name:  AccessLogMetricsDimensions,
+			Usage: "A comma-separated list of access log format variables, e.g. host or server_name, whose values are added as dimensions to the access log metrics.",
+		},
+		&StringSliceFlag{
+			Name:  AccessLogMetricsURIPrefixes,
+			Usage: "A comma-separated list of request URI prefixes the access log metrics are grouped by.",
+		},
+		&IntFlag{
+			Name:         AccessLogMetricsMaxDimensionValues,
+			Usage:        "The maximum number of distinct values of each access log metrics dimension per collection interval. Additional values are reported as \"other\".",
+			DefaultValue: Defaults.AccessLogMetrics.MaxDimensionValues,
+		},
+		&BoolFlag{
+			Name:         AccessLogMetricsHistograms,
+			Usage:        "Enables reporting request and upstream times collected from access logs as histograms together with their 50th, 90th, 99th and 99.9th percentiles.",
+			DefaultValue: Defaults.AccessLogMetrics.Histograms,
+		},
+		&IntFlag{
+			Name:         AccessLogMetricsHistogramSchema,
+			Usage:        "The resolution of the access log histograms, from -4 to 8. Higher values use more buckets with a smaller relative error.",
+			DefaultValue: Defaults.AccessLogMetrics.HistogramSchema,
+		},
+		// Error Log Events
+		&BoolFlag{
+			Name:         ErrorLogEventsEnable,
+			Usage:        "Enables forwarding NGINX error log lines as activity events.",
+			DefaultValue: Defaults.ErrorLogEvents.Enable,
+		},
+		&StringSliceFlag{
+			Name:         ErrorLogEventsLevels,
+			Usage:        "A comma-separated list of the error log levels whose lines are forwarded as activity events.",
+			DefaultValue: Defaults.ErrorLogEvents.Levels,
+		},
+		&DurationFlag{
+			Name:         ErrorLogEventsDedupWindow,
+			Usage:        "The period in which repeated error log lines with the same level and message are forwarded only once.",
+			DefaultValue: Defaults.ErrorLogEvents.DedupWindow,
+		},
+		&IntFlag{
+			Name:         ErrorLogEventsRateLimit,
+			Usage:        "The maximum number of error log events forwarded per minute for each NGINX instance.",
+			DefaultValue: Defaults.ErrorLogEvents.RateLimit,
+		},
+		// Certificate Monitor
+		&BoolFlag{
+			Name:         CertificateMonitorEnable,
+			Usage:        "Enables monitoring the expiry of the certificates referenced in the NGINX configuration and of the agent's own certificates.",
+			DefaultValue: Defaults.CertificateMonitor.Enable,
+		},
+		&DurationFlag{
+			Name:         CertificateMonitorCheckInterval,
+			Usage:        "The period in which the monitored certificate files are read again.",
+			DefaultValue: Defaults.CertificateMonitor.CheckInterval,
+		},
+		&IntFlag{
+			Name:         CertificateMonitorWarningDays,
+			Usage:        "The number of days before the expiry of a certificate a warning event is raised.",
+			DefaultValue: Defaults.CertificateMonitor.WarningDays,
+		},
+		&IntFlag{
+			Name:         CertificateMonitorCriticalDays,
+			Usage:        "The number of days before the expiry of a certificate a critical event is raised.",
+			DefaultValue: Defaults.CertificateMonitor.CriticalDays,
+		},
+		// ACME
+		&BoolFlag{
+			Name:         ACMEEnable,
+			Usage:        "Enables renewing the NGINX server certificates with an ACME server before they expire.",
+			DefaultValue: Defaults.ACME.Enable,
+		},
+		&StringFlag{
+			Name:         ACMEDirectoryURL,
+			Usage:        "The directory URL of the ACME server.",
+			DefaultValue: Defaults.ACME.DirectoryURL,
+		},
+		&StringFlag{
+			Name:  ACMEEmail,
+			Usage: "The contact email address of the ACME account.",
+		},
+		&StringFlag{
+			Name:         ACMEAccountKey,
+			Usage:        "The file the private key of the ACME account is kept in. The key is created if the file does not exist.",
+			DefaultValue: Defaults.ACME.AccountKey,
+		},
+		&StringFlag{
+			Name:  ACMECa,
+			Usage: "The CA used to verify the certificate of the ACME server. The system CAs are used if not set.",
+		},
+		&StringFlag{
+			Name:         ACMEChallengeDir,
+			Usage:        "The directory the HTTP-01 challenge responses are written to and served from by NGINX.",
+			DefaultValue: Defaults.ACME.ChallengeDir,
+		},
+		&StringFlag{
+			Name:         ACMESnippetPath,
+			Usage:        "The file the NGINX location snippet serving the HTTP-01 challenge responses is written to.",
+			DefaultValue: Defaults.ACME.SnippetPath,
+		},
+		&StringSliceFlag{
+			Name:         ACMECertificates,
+			Usage:        "A comma-separated list of the ssl_certificate files that are renewed. No certificates are renewed if not set.",
+			DefaultValue: Defaults.ACME.Certificates,
+		},
+		&DurationFlag{
+			Name:         ACMERenewBefore,
+			Usage:        "The time before the expiry of a certificate it is renewed.",
+			DefaultValue: Defaults.ACME.RenewBefore,
+		},
+		&DurationFlag{
+			Name:         ACMECheckInterval,
+			Usage:        "The period in which the certificates are checked for renewal.",
+			DefaultValue: Defaults.ACME.CheckInterval,
+		},
+		// Message Pipe
+		&DurationFlag{
+			Name:         MessagePipeSlowSubscriberDeadline,
+			Usage:        "The time a plugin can spend processing a single internal message before a warning is logged. Set to 0 to disable the warning.",
+			DefaultValue: Defaults.MessagePipe.SlowSubscriberDeadline,
+		},
 		// Dataplane
 		&DurationFlag{
 			Name:         DataplaneStatusPoll,
@@ -456,3 +844,17 @@ func getDefaultDynamicConfPath() string {
 		return DynamicConfigFileAbsPath
 	}
 }
+
+func getDefaultDiskBufferPath() string {
+	if runtime.GOOS == "freebsd" {
+		return DiskBufferAbsFreeBsdPath
+	}
+	return DiskBufferAbsPath
+}
+
+func getDefaultConfigRevisionsPath() string {
+	if runtime.GOOS == "freebsd" {
+		return ConfigRevisionsAbsFreeBsdPath
+	}
+	return ConfigRevisionsAbsPath
+}
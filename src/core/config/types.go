@@ -27,6 +27,14 @@ type Config struct {
 	Nginx                 Nginx               `mapstructure:"nginx" yaml:"-"`
 	Dataplane             Dataplane           `mapstructure:"dataplane" yaml:"-"`
 	AgentMetrics          AgentMetrics        `mapstructure:"metrics" yaml:"-"`
+	OTLP                  OTLP                `mapstructure:"otlp" yaml:"-"`
+	DiskBuffer            DiskBuffer          `mapstructure:"disk_buffer" yaml:"-"`
+	ConfigRevisions       ConfigRevisions     `mapstructure:"config_revisions" yaml:"-"`
+	AccessLogMetrics      AccessLogMetrics    `mapstructure:"access_log_metrics" yaml:"-"`
+	ErrorLogEvents        ErrorLogEvents      `mapstructure:"error_log_events" yaml:"-"`
+	CertificateMonitor    CertificateMonitor  `mapstructure:"certificate_monitor" yaml:"-"`
+	ACME                  ACME                `mapstructure:"acme" yaml:"-"`
+	MessagePipe           MessagePipe         `mapstructure:"message_pipe" yaml:"-"`
 	Tags                  []string            `mapstructure:"tags" yaml:"tags,omitempty"`
 	Features              []string            `mapstructure:"features" yaml:"features,omitempty"`
 	Extensions            []string            `mapstructure:"extensions" yaml:"extensions,omitempty"`
@@ -110,10 +118,124 @@ type Backoff struct {
 }
 
 type AgentAPI struct {
-	Host string `mapstructure:"host" yaml:"-"`
-	Port int    `mapstructure:"port" yaml:"-"`
-	Cert string `mapstructure:"cert" yaml:"-"`
-	Key  string `mapstructure:"key" yaml:"-"`
+	Host     string       `mapstructure:"host" yaml:"-"`
+	Port     int          `mapstructure:"port" yaml:"-"`
+	Cert     string       `mapstructure:"cert" yaml:"-"`
+	Key      string       `mapstructure:"key" yaml:"-"`
+	ClientCA string       `mapstructure:"client_ca" yaml:"-"`
+	Auth     AgentAPIAuth `mapstructure:"auth" yaml:"-"`
+}
+
+// AgentAPIAuth settings for authenticating Agent API requests and authorizing them by role
+type AgentAPIAuth struct {
+	Enable              bool     `mapstructure:"enable" yaml:"-"`
+	ReadOnlyTokens      []string `mapstructure:"read_only_tokens" yaml:"-"`
+	ConfigWriteTokens   []string `mapstructure:"config_write_tokens" yaml:"-"`
+	ReadOnlySubjects    []string `mapstructure:"read_only_subjects" yaml:"-"`
+	ConfigWriteSubjects []string `mapstructure:"config_write_subjects" yaml:"-"`
+	JWKSFile            string   `mapstructure:"jwks_file" yaml:"-"`
+	JWTIssuer           string   `mapstructure:"jwt_issuer" yaml:"-"`
+	JWTAudience         string   `mapstructure:"jwt_audience" yaml:"-"`
+	JWTRolesClaim       string   `mapstructure:"jwt_roles_claim" yaml:"-"`
+	AuditLog            string   `mapstructure:"audit_log" yaml:"-"`
+}
+
+// OTLP settings for exporting metrics to an OpenTelemetry collector
+type OTLP struct {
+	Endpoint      string            `mapstructure:"endpoint" yaml:"-"`
+	Protocol      string            `mapstructure:"protocol" yaml:"-"`
+	Headers       map[string]string `mapstructure:"headers" yaml:"-"`
+	Timeout       time.Duration     `mapstructure:"timeout" yaml:"-"`
+	BatchSize     int               `mapstructure:"batch_size" yaml:"-"`
+	FlushInterval time.Duration     `mapstructure:"flush_interval" yaml:"-"`
+	TLS           TLSConfig         `mapstructure:"tls" yaml:"-"`
+}
+
+// DiskBuffer settings for persisting metrics and event reports that could not be sent
+// to the management plane
+type DiskBuffer struct {
+	Enable    bool          `mapstructure:"enable" yaml:"-"`
+	Path      string        `mapstructure:"path" yaml:"-"`
+	MaxSizeMB int           `mapstructure:"max_size_mb" yaml:"-"`
+	MaxAge    time.Duration `mapstructure:"max_age" yaml:"-"`
+}
+
+// ConfigRevisions settings for keeping a history of applied NGINX configurations
+type ConfigRevisions struct {
+	Enable       bool   `mapstructure:"enable" yaml:"-"`
+	Path         string `mapstructure:"path" yaml:"-"`
+	MaxRevisions int    `mapstructure:"max_revisions" yaml:"-"`
+}
+
+// AccessLogMetrics settings for breaking down the metrics collected from NGINX access logs
+type AccessLogMetrics struct {
+	// Dimensions are the log format variables, e.g. host or server_name, whose values are added
+	// as dimensions to the access log metrics
+	Dimensions []string `mapstructure:"dimensions" yaml:"-"`
+	// URIPrefixes are the request URI prefixes the access log metrics are grouped by
+	URIPrefixes []string `mapstructure:"uri_prefixes" yaml:"-"`
+	// MaxDimensionValues limits the distinct values of each dimension per collection interval,
+	// additional values are reported as "other"
+	MaxDimensionValues int `mapstructure:"max_dimension_values" yaml:"-"`
+	// Histograms enables reporting request and upstream times as exponential histograms
+	// together with the percentiles calculated from them
+	Histograms bool `mapstructure:"histograms" yaml:"-"`
+	// HistogramSchema sets the resolution of the histograms, each bucket is 2^(2^-schema)
+	// times wider than the previous one
+	HistogramSchema int `mapstructure:"histogram_schema" yaml:"-"`
+}
+
+// ErrorLogEvents settings for forwarding NGINX error log lines as activity events
+type ErrorLogEvents struct {
+	Enable bool `mapstructure:"enable" yaml:"-"`
+	// Levels are the error log levels, e.g. error or crit, of the lines that are forwarded
+	Levels []string `mapstructure:"levels" yaml:"-"`
+	// DedupWindow is the period in which repeated lines with the same level and message
+	// are forwarded only once
+	DedupWindow time.Duration `mapstructure:"dedup_window" yaml:"-"`
+	// RateLimit is the maximum number of events forwarded per minute for each NGINX instance
+	RateLimit int `mapstructure:"rate_limit" yaml:"-"`
+}
+
+// CertificateMonitor settings for monitoring the expiry of the NGINX and agent certificates
+type CertificateMonitor struct {
+	Enable bool `mapstructure:"enable" yaml:"-"`
+	// CheckInterval is the period in which the certificate files are read again
+	CheckInterval time.Duration `mapstructure:"check_interval" yaml:"-"`
+	// WarningDays is the number of days before the expiry of a certificate a warning event is raised
+	WarningDays int `mapstructure:"warning_days" yaml:"-"`
+	// CriticalDays is the number of days before the expiry of a certificate a critical event is raised
+	CriticalDays int `mapstructure:"critical_days" yaml:"-"`
+}
+
+// ACME settings for renewing the NGINX server certificates with an ACME (RFC 8555) server
+type ACME struct {
+	Enable bool `mapstructure:"enable" yaml:"-"`
+	// DirectoryURL is the directory URL of the ACME server, e.g. the Let's Encrypt production directory
+	DirectoryURL string `mapstructure:"directory_url" yaml:"-"`
+	// Email is the contact address of the ACME account
+	Email string `mapstructure:"email" yaml:"-"`
+	// AccountKey is the file the private key of the ACME account is kept in, it's created if missing
+	AccountKey string `mapstructure:"account_key" yaml:"-"`
+	// Ca is the CA used to verify the certificate of the ACME server, the system CAs are used if empty
+	Ca string `mapstructure:"ca" yaml:"-"`
+	// ChallengeDir is the directory the HTTP-01 challenge responses are written to and served from
+	ChallengeDir string `mapstructure:"challenge_dir" yaml:"-"`
+	// SnippetPath is the file the location snippet that serves the challenge responses is written to
+	SnippetPath string `mapstructure:"snippet_path" yaml:"-"`
+	// Certificates are the ssl_certificate files that are renewed, none of them if empty
+	Certificates []string `mapstructure:"certificates" yaml:"-"`
+	// RenewBefore is the time before the expiry of a certificate it's renewed
+	RenewBefore time.Duration `mapstructure:"renew_before" yaml:"-"`
+	// CheckInterval is the period in which the certificates are checked for renewal
+	CheckInterval time.Duration `mapstructure:"check_interval" yaml:"-"`
+}
+
+// MessagePipe settings for the internal message pipe between the plugins
+type MessagePipe struct {
+	// SlowSubscriberDeadline is how long a plugin can process a single message before a
+	// warning is logged, zero disables the warning
+	SlowSubscriberDeadline time.Duration `mapstructure:"slow_subscriber_deadline" yaml:"-"`
 }
 
 // LogConfig for logging
@@ -51,6 +51,7 @@ type Environment interface {
 	WriteFile(backup ConfigApplyMarker, file *proto.File, confPath string) error
 	DeleteFile(backup ConfigApplyMarker, fileName string) error
 	Processes() (result []*Process)
+	ProcessesByName(namePrefix string) (result []*Process)
 	FileStat(path string) (os.FileInfo, error)
 	Disks() ([]*proto.DiskPartition, error)
 	DiskDevices() ([]string, error)
@@ -656,6 +657,56 @@ func (env *EnvironmentType) Processes() (result []*Process) {
 	return processList
 }
 
+// ProcessesByName returns the processes whose name starts with the prefix, a process is
+// a master process if its parent is not one of the returned processes
+func (env *EnvironmentType) ProcessesByName(namePrefix string) (result []*Process) {
+	ctx := context.Background()
+
+	pids, err := process.PidsWithContext(ctx)
+	if err != nil {
+		log.Errorf("failed to read pids for dataplane host: %v", err)
+		return result
+	}
+
+	matched := make(map[int32]*process.Process)
+	for _, pid := range pids {
+		p, err := process.NewProcessWithContext(ctx, pid)
+		if err != nil {
+			continue
+		}
+		name, _ := p.NameWithContext(ctx)
+		if strings.HasPrefix(name, namePrefix) {
+			matched[pid] = p
+		}
+	}
+
+	for pid, p := range matched {
+		name, _ := p.NameWithContext(ctx)
+		createTime, _ := p.CreateTimeWithContext(ctx)
+		status, _ := p.StatusWithContext(ctx)
+		running, _ := p.IsRunningWithContext(ctx)
+		user, _ := p.UsernameWithContext(ctx)
+		ppid, _ := p.PpidWithContext(ctx)
+		cmd, _ := p.CmdlineWithContext(ctx)
+		exe, _ := p.ExeWithContext(ctx)
+		_, hasMatchedParent := matched[ppid]
+
+		result = append(result, &Process{
+			Pid:        pid,
+			Name:       name,
+			CreateTime: createTime,
+			Status:     strings.Join(status, " "),
+			IsRunning:  running,
+			Path:       exe,
+			User:       user,
+			ParentPid:  ppid,
+			Command:    cmd,
+			IsMaster:   !hasMatchedParent,
+		})
+	}
+	return result
+}
+
 func (env *EnvironmentType) isNginxProcess(name string, cmd string) bool {
 	return name == "nginx" && !strings.Contains(cmd, "upgrade") && strings.HasPrefix(cmd, "nginx:")
 }
@@ -149,6 +149,17 @@ type FakeEnvironment struct {
 	processesReturnsOnCall map[int]struct {
 		result1 []*Process
 	}
+	ProcessesByNameStub        func(string) []*Process
+	processesByNameMutex       sync.RWMutex
+	processesByNameArgsForCall []struct {
+		arg1 string
+	}
+	processesByNameReturns struct {
+		result1 []*Process
+	}
+	processesByNameReturnsOnCall map[int]struct {
+		result1 []*Process
+	}
 	ReadDirectoryStub        func(string, string) ([]string, error)
 	readDirectoryMutex       sync.RWMutex
 	readDirectoryArgsForCall []struct {
@@ -896,6 +907,67 @@ func (fake *FakeEnvironment) ProcessesReturnsOnCall(i int, result1 []*Process) {
 	}{result1}
 }
 
+func (fake *FakeEnvironment) ProcessesByName(arg1 string) []*Process {
+	fake.processesByNameMutex.Lock()
+	ret, specificReturn := fake.processesByNameReturnsOnCall[len(fake.processesByNameArgsForCall)]
+	fake.processesByNameArgsForCall = append(fake.processesByNameArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.ProcessesByNameStub
+	fakeReturns := fake.processesByNameReturns
+	fake.recordInvocation("ProcessesByName", []interface{}{arg1})
+	fake.processesByNameMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeEnvironment) ProcessesByNameCallCount() int {
+	fake.processesByNameMutex.RLock()
+	defer fake.processesByNameMutex.RUnlock()
+	return len(fake.processesByNameArgsForCall)
+}
+
+func (fake *FakeEnvironment) ProcessesByNameCalls(stub func(string) []*Process) {
+	fake.processesByNameMutex.Lock()
+	defer fake.processesByNameMutex.Unlock()
+	fake.ProcessesByNameStub = stub
+}
+
+func (fake *FakeEnvironment) ProcessesByNameArgsForCall(i int) string {
+	fake.processesByNameMutex.RLock()
+	defer fake.processesByNameMutex.RUnlock()
+	argsForCall := fake.processesByNameArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEnvironment) ProcessesByNameReturns(result1 []*Process) {
+	fake.processesByNameMutex.Lock()
+	defer fake.processesByNameMutex.Unlock()
+	fake.ProcessesByNameStub = nil
+	fake.processesByNameReturns = struct {
+		result1 []*Process
+	}{result1}
+}
+
+func (fake *FakeEnvironment) ProcessesByNameReturnsOnCall(i int, result1 []*Process) {
+	fake.processesByNameMutex.Lock()
+	defer fake.processesByNameMutex.Unlock()
+	fake.ProcessesByNameStub = nil
+	if fake.processesByNameReturnsOnCall == nil {
+		fake.processesByNameReturnsOnCall = make(map[int]struct {
+			result1 []*Process
+		})
+	}
+	fake.processesByNameReturnsOnCall[i] = struct {
+		result1 []*Process
+	}{result1}
+}
+
 func (fake *FakeEnvironment) ReadDirectory(arg1 string, arg2 string) ([]string, error) {
 	fake.readDirectoryMutex.Lock()
 	ret, specificReturn := fake.readDirectoryReturnsOnCall[len(fake.readDirectoryArgsForCall)]
@@ -1176,6 +1248,8 @@ func (fake *FakeEnvironment) Invocations() map[string][][]interface{} {
 	defer fake.newHostInfoMutex.RUnlock()
 	fake.processesMutex.RLock()
 	defer fake.processesMutex.RUnlock()
+	fake.processesByNameMutex.RLock()
+	defer fake.processesByNameMutex.RUnlock()
 	fake.readDirectoryMutex.RLock()
 	defer fake.readDirectoryMutex.RUnlock()
 	fake.virtualizationMutex.RLock()
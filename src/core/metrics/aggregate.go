@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strings"
 
 	"github.com/nginx/agent/sdk/v2/checksum"
 	"github.com/nginx/agent/sdk/v2/proto"
@@ -21,6 +22,9 @@ var (
 	freeRegex  = regexp.MustCompile(`slab.slots.*.free`)
 	reqsRegex  = regexp.MustCompile(`slab.slots.*.reqs`)
 	usedRegex  = regexp.MustCompile(`slab.slots.*.used`)
+
+	histogramRegex  = regexp.MustCompile(`\.hist\.(count|sum|zero_count|schema_-?\d+\.bucket\.-?\d+)$`)
+	percentileRegex = regexp.MustCompile(`\.pctl(50|90|99|999)$`)
 )
 
 type PerDimension struct {
@@ -81,6 +85,7 @@ func GenerateMetrics(metricsCollections Collections) []*proto.StatsEntity {
 
 	for _, metricsPerDimension := range metricsCollections.Data {
 		simpleMetrics := getAggregatedSimpleMetric(metricsCollections.Count, metricsPerDimension.RunningSumMap)
+		updateHistogramPercentiles(simpleMetrics)
 		results = append(results, NewStatsEntity(
 			metricsPerDimension.Dimensions,
 			simpleMetrics,
@@ -96,6 +101,9 @@ func getAggregatedSimpleMetric(count int, internalMap map[string]float64) (simpl
 		freeRegex:  avg,
 		reqsRegex:  sum,
 		usedRegex:  avg,
+
+		histogramRegex:  sum,
+		percentileRegex: avg,
 	}
 
 	calMap := GetCalculationMap()
@@ -136,6 +144,29 @@ func getAggregatedSimpleMetric(count int, internalMap map[string]float64) (simpl
 	return simpleMetrics
 }
 
+// updateHistogramPercentiles replaces the averaged percentiles of the aggregated histograms
+// with the percentiles calculated from the merged buckets
+func updateHistogramPercentiles(simpleMetrics []*proto.SimpleMetric) {
+	histograms, _ := HistogramsFromSimpleMetrics(simpleMetrics)
+	if len(histograms) == 0 {
+		return
+	}
+
+	for _, metric := range simpleMetrics {
+		idx := strings.LastIndex(metric.Name, ".")
+		if idx == -1 {
+			continue
+		}
+		histogram, ok := histograms[metric.Name[:idx]]
+		if !ok {
+			continue
+		}
+		if q, ok := HistogramPercentiles[metric.Name[idx+1:]]; ok {
+			metric.Value = histogram.Quantile(q)
+		}
+	}
+}
+
 func sum(value float64, count int) float64 {
 	// the value is already summed in collection
 	return value
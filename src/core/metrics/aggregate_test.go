@@ -10,6 +10,7 @@ package metrics
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	log "github.com/sirupsen/logrus"
@@ -17,6 +18,7 @@ import (
 	"github.com/nginx/agent/sdk/v2/checksum"
 	"github.com/nginx/agent/sdk/v2/proto"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSaveCollections(t *testing.T) {
@@ -170,6 +172,43 @@ func TestGenerateAggregationReport(t *testing.T) {
 	}
 }
 
+func TestGenerateAggregationReportHistograms(t *testing.T) {
+	first, second := NewHistogram(3), NewHistogram(3)
+	for i := 1; i <= 100; i++ {
+		first.Observe(float64(i) / 1000)
+		second.Observe(float64(i))
+	}
+	merged := NewHistogram(3)
+	merged.Merge(first)
+	merged.Merge(second)
+
+	reports := []*proto.MetricsReport{}
+	for _, histogram := range []*Histogram{first, second} {
+		samples := map[string]float64{}
+		histogram.Samples("nginx.http.request.time", samples)
+
+		stats := &proto.StatsEntity{Dimensions: []*proto.Dimension{{Name: "hostname", Value: "test-host"}}}
+		for name, value := range samples {
+			stats.Simplemetrics = append(stats.Simplemetrics, &proto.SimpleMetric{Name: name, Value: value})
+		}
+		reports = append(reports, &proto.MetricsReport{Type: proto.MetricsReport_INSTANCE, Data: []*proto.StatsEntity{stats}})
+	}
+
+	metricsCollections := SaveCollections(Collections{Data: make(map[string]PerDimension)}, reports...)
+	results := GenerateMetrics(metricsCollections)
+	require.Len(t, results, 1)
+
+	histograms, others := HistogramsFromSimpleMetrics(results[0].GetSimplemetrics())
+	assert.Equal(t, merged, histograms["nginx.http.request.time"])
+
+	// percentiles are calculated from the merged histogram instead of being averaged
+	require.Len(t, others, len(HistogramPercentiles))
+	for _, metric := range others {
+		q := HistogramPercentiles[strings.TrimPrefix(metric.Name, "nginx.http.request.time.")]
+		assert.Equal(t, merged.Quantile(q), metric.Value, metric.Name)
+	}
+}
+
 func TestAvg(t *testing.T) {
 	result := avg(float64(2.12), 2)
 	assert.Equal(t, float64(1.06), result)
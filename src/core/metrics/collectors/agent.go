@@ -0,0 +1,82 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package collectors
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+	"github.com/nginx/agent/v2/src/core/metrics/sources"
+)
+
+var _ metrics.Collector = (*AgentCollector)(nil)
+
+// AgentCollector collects the self-metrics of the agent
+type AgentCollector struct {
+	sources      []metrics.Source
+	certificates *sources.AgentCertificates
+	buf          chan *metrics.StatsEntityWrapper
+	dim          *metrics.CommonDim
+	env          core.Environment
+}
+
+func NewAgentCollector(env core.Environment, conf *config.Config, pipeStats *core.PipeStats, certificateEvents chan<- *metrics.CertificateExpiryEvent) *AgentCollector {
+	certificates := sources.NewAgentCertificates(sources.AgentNamespace, conf, certificateEvents)
+	return &AgentCollector{
+		sources: []metrics.Source{
+			sources.NewMessagePipeSource(sources.AgentNamespace, pipeStats),
+			certificates,
+		},
+		certificates: certificates,
+		buf:          make(chan *metrics.StatsEntityWrapper, 65535),
+		dim:          metrics.NewCommonDim(env.NewHostInfo("agentVersion", &conf.Tags, conf.ConfigDirs, false), conf, ""),
+		env:          env,
+	}
+}
+
+func (c *AgentCollector) collectMetrics(ctx context.Context) {
+	wg := &sync.WaitGroup{}
+	for _, agentSource := range c.sources {
+		wg.Add(1)
+		go agentSource.Collect(ctx, wg, c.buf)
+	}
+	wg.Wait()
+}
+
+func (c *AgentCollector) Collect(ctx context.Context, wg *sync.WaitGroup, m chan<- *metrics.StatsEntityWrapper) {
+	defer wg.Done()
+	c.collectMetrics(ctx)
+
+	commonDims := c.dim.ToDimensions()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sample := <-c.buf:
+			sample.Data.Dimensions = append(commonDims, sample.Data.Dimensions...)
+
+			select {
+			case <-ctx.Done():
+				return
+			case m <- sample:
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (c *AgentCollector) UpdateConfig(config *config.Config) {
+	c.dim = metrics.NewCommonDim(c.env.NewHostInfo("agentVersion", &config.Tags, config.ConfigDirs, false), config, "")
+	if c.certificates != nil {
+		c.certificates.Update(config)
+	}
+}
@@ -0,0 +1,74 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package collectors
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+	tutils "github.com/nginx/agent/v2/test/utils"
+)
+
+func TestNewAgentCollector(t *testing.T) {
+	env := tutils.GetMockEnv()
+
+	agentCollector := NewAgentCollector(env, &config.Config{Tags: tutils.InitialConfTags}, core.NewPipeStats(10, nil), nil)
+
+	sourceTypes := []string{}
+	for _, agentSource := range agentCollector.sources {
+		sourceTypes = append(sourceTypes, reflect.TypeOf(agentSource).String())
+	}
+
+	assert.Equal(t, []string{"*sources.MessagePipe", "*sources.AgentCertificates"}, sourceTypes)
+	assert.Equal(t, &metrics.CommonDim{
+		Hostname:     "test-host",
+		InstanceTags: "locally-tagged,tagged-locally",
+	}, agentCollector.dim)
+}
+
+func TestAgentCollector_Collect(t *testing.T) {
+	mockSource := GetNginxSourceMock()
+
+	agentCollector := &AgentCollector{
+		sources: []metrics.Source{mockSource},
+		buf:     make(chan *metrics.StatsEntityWrapper),
+		dim:     &metrics.CommonDim{},
+	}
+
+	ctx := context.TODO()
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+
+	channel := make(chan *metrics.StatsEntityWrapper)
+	go agentCollector.Collect(ctx, wg, channel)
+
+	agentCollector.buf <- &metrics.StatsEntityWrapper{Type: proto.MetricsReport_AGENT, Data: &proto.StatsEntity{Dimensions: []*proto.Dimension{{Name: "plugin", Value: "metrics"}}}}
+	actual := <-channel
+
+	mockSource.AssertExpectations(t)
+
+	expectedDimensions := []*proto.Dimension{
+		{Name: "system_id", Value: ""},
+		{Name: "hostname", Value: ""},
+		{Name: "system.tags", Value: ""},
+		{Name: "instance_group", Value: ""},
+		{Name: "display_name", Value: ""},
+		{Name: "nginx_id", Value: ""},
+		{Name: "plugin", Value: "metrics"},
+	}
+	assert.Equal(t, expectedDimensions, actual.Data.Dimensions)
+	assert.Equal(t, proto.MetricsReport_AGENT, actual.Type)
+}
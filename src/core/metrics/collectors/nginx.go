@@ -57,17 +57,21 @@ func buildSources(dimensions *metrics.CommonDim, binary core.NginxBinary, collec
 
 		if collectorConf.StubStatus != "" {
 			nginxSources = append(nginxSources, sources.NewNginxOSS(dimensions, sources.OSSNamespace, collectorConf.StubStatus))
-			nginxSources = append(nginxSources, sources.NewNginxAccessLog(dimensions, sources.OSSNamespace, binary, sources.OSSNginxType, collectorConf.CollectionInterval))
-			nginxSources = append(nginxSources, sources.NewNginxErrorLog(dimensions, sources.OSSNamespace, binary, sources.OSSNginxType, collectorConf.CollectionInterval))
+			nginxSources = append(nginxSources, sources.NewNginxAccessLog(dimensions, sources.OSSNamespace, binary, sources.OSSNginxType, collectorConf.CollectionInterval, collectorConf.AccessLogMetrics, collectorConf.Upstreams))
+			nginxSources = append(nginxSources, sources.NewNginxErrorLog(dimensions, sources.OSSNamespace, binary, sources.OSSNginxType, collectorConf.CollectionInterval, collectorConf.ErrorLogEvents, collectorConf.ErrorLogEventChannel))
 		} else if collectorConf.PlusAPI != "" {
 			nginxSources = append(nginxSources, sources.NewNginxPlus(dimensions, sources.OSSNamespace, sources.PlusNamespace, collectorConf.PlusAPI, collectorConf.ClientVersion))
-			nginxSources = append(nginxSources, sources.NewNginxAccessLog(dimensions, sources.OSSNamespace, binary, sources.PlusNginxType, collectorConf.CollectionInterval))
-			nginxSources = append(nginxSources, sources.NewNginxErrorLog(dimensions, sources.OSSNamespace, binary, sources.PlusNginxType, collectorConf.CollectionInterval))
+			nginxSources = append(nginxSources, sources.NewNginxAccessLog(dimensions, sources.OSSNamespace, binary, sources.PlusNginxType, collectorConf.CollectionInterval, collectorConf.AccessLogMetrics, collectorConf.Upstreams))
+			nginxSources = append(nginxSources, sources.NewNginxErrorLog(dimensions, sources.OSSNamespace, binary, sources.PlusNginxType, collectorConf.CollectionInterval, collectorConf.ErrorLogEvents, collectorConf.ErrorLogEventChannel))
 		} else {
 			// if Plus API or stub_status are not setup, run the NGINX static collector and return nginx.status = 0
 			log.Warnf("The NGINX API is not configured. Please configure it to collect NGINX metrics.")
 			nginxSources = append(nginxSources, sources.NewNginxStatic(dimensions, sources.OSSNamespace))
 		}
+
+		if collectorConf.ConfPath != "" {
+			nginxSources = append(nginxSources, sources.NewNginxCertificates(dimensions, sources.OSSNamespace, collectorConf))
+		}
 	}
 	return nginxSources
 }
@@ -72,6 +72,16 @@ var (
 		ErrorLogs:          []string{},
 	}
 
+	collectorConfigWithConfPath = &metrics.NginxCollectorConfig{
+		BinPath:            "/path/to/nginx",
+		ConfPath:           "/etc/nginx/nginx.conf",
+		NginxId:            nginxPid,
+		CollectionInterval: 1,
+		StubStatus:         "http://localhost:80/stub_status",
+		AccessLogs:         []string{},
+		ErrorLogs:          []string{},
+	}
+
 	collectorConfigPlusApi = &metrics.NginxCollectorConfig{
 		BinPath:            "/path/to/nginx",
 		NginxId:            nginxPid,
@@ -130,6 +140,20 @@ func TestNewNginxCollector(t *testing.T) {
 				NginxAccessLogPaths: []string{},
 			},
 		},
+		{
+			testName:                "ConfPathConfigured",
+			config:                  configuration,
+			collectorConfig:         collectorConfigWithConfPath,
+			expectedSourceTypes:     []string{"*sources.NginxProcess", "*sources.NginxWorker", "*sources.NginxOSS", "*sources.NginxAccessLog", "*sources.NginxErrorLog", "*sources.NginxCertificates"},
+			expectedCollectorConfig: collectorConfigWithConfPath,
+			expectedDimensions: &metrics.CommonDim{
+				Hostname:            "test-host",
+				InstanceTags:        "locally-tagged,tagged-locally",
+				NginxId:             nginxPid,
+				NginxConfPath:       "/etc/nginx/nginx.conf",
+				NginxAccessLogPaths: []string{},
+			},
+		},
 	}
 
 	binary := tutils.NewMockNginxBinary()
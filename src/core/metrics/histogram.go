@@ -0,0 +1,309 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+)
+
+const (
+	MinHistogramSchema     = -4
+	MaxHistogramSchema     = 8
+	DefaultHistogramSchema = 3
+
+	histogramInfix           = ".hist."
+	histogramCountSuffix     = "count"
+	histogramSumSuffix       = "sum"
+	histogramZeroCountSuffix = "zero_count"
+	histogramSchemaPrefix    = "schema_"
+	histogramBucketInfix     = ".bucket."
+)
+
+// HistogramPercentiles are the percentiles calculated from histograms, keyed by the
+// suffix of the metric they are reported as
+var HistogramPercentiles = map[string]float64{
+	"pctl50":  0.5,
+	"pctl90":  0.9,
+	"pctl99":  0.99,
+	"pctl999": 0.999,
+}
+
+// Histogram is an exponential histogram using the bucket layout of Prometheus native
+// histograms and OpenTelemetry exponential histograms. The bucket with index i holds the
+// values in (base^(i-1), base^i] where base is 2^(2^-schema), so histograms with the same
+// schema are merged by adding up the counts of their buckets. Values less than or equal
+// to zero are counted in the zero bucket.
+type Histogram struct {
+	Schema    int32
+	Count     uint64
+	Sum       float64
+	ZeroCount uint64
+	Buckets   map[int32]uint64
+}
+
+// NewHistogram creates an empty histogram, the schema is clamped to the range supported
+// by Prometheus native histograms
+func NewHistogram(schema int32) *Histogram {
+	if schema < MinHistogramSchema {
+		schema = MinHistogramSchema
+	}
+	if schema > MaxHistogramSchema {
+		schema = MaxHistogramSchema
+	}
+	return &Histogram{
+		Schema:  schema,
+		Buckets: make(map[int32]uint64),
+	}
+}
+
+// Observe adds a value to the histogram
+func (h *Histogram) Observe(value float64) {
+	if math.IsNaN(value) {
+		return
+	}
+	h.Count++
+	h.Sum += value
+	if value <= 0 {
+		h.ZeroCount++
+		return
+	}
+	h.Buckets[h.bucketIndex(value)]++
+}
+
+func (h *Histogram) bucketIndex(value float64) int32 {
+	// frexp avoids rounding errors of log2 for exact powers of two
+	frac, exp := math.Frexp(value)
+	if h.Schema > 0 {
+		return int32(math.Ceil(math.Log2(frac)*math.Exp2(float64(h.Schema)))) + int32(exp)<<h.Schema
+	}
+	index := int32(exp)
+	if frac == 0.5 {
+		index--
+	}
+	offset := int32(1) << -h.Schema
+	return (index + offset - 1) >> -h.Schema
+}
+
+// UpperBound returns the upper bound of the bucket with the given index
+func (h *Histogram) UpperBound(index int32) float64 {
+	return math.Exp2(float64(index) * math.Exp2(-float64(h.Schema)))
+}
+
+// Merge adds the buckets of another histogram. If the schemas differ the histogram with
+// the higher resolution is reduced to the lower one.
+func (h *Histogram) Merge(other *Histogram) {
+	if other.Schema < h.Schema {
+		h.reduce(other.Schema)
+	}
+	shift := other.Schema - h.Schema
+
+	h.Count += other.Count
+	h.Sum += other.Sum
+	h.ZeroCount += other.ZeroCount
+	for index, count := range other.Buckets {
+		h.Buckets[reduceIndex(index, shift)] += count
+	}
+}
+
+func (h *Histogram) reduce(schema int32) {
+	buckets := make(map[int32]uint64, len(h.Buckets))
+	for index, count := range h.Buckets {
+		buckets[reduceIndex(index, h.Schema-schema)] += count
+	}
+	h.Buckets = buckets
+	h.Schema = schema
+}
+
+// reduceIndex maps a bucket index to the bucket containing it in a schema that is lower by
+// shift, every 2^shift buckets are merged into one
+func reduceIndex(index int32, shift int32) int32 {
+	if shift <= 0 {
+		return index
+	}
+	return (index + int32(1)<<shift - 1) >> shift
+}
+
+// Quantile estimates the value at the given quantile, interpolating exponentially within
+// the bucket the quantile falls into
+func (h *Histogram) Quantile(q float64) float64 {
+	if h.Count == 0 || math.IsNaN(q) {
+		return 0
+	}
+	if q < 0 {
+		q = 0
+	}
+	if q > 1 {
+		q = 1
+	}
+
+	rank := q * float64(h.Count)
+	cumulative := float64(h.ZeroCount)
+	if rank <= cumulative {
+		return 0
+	}
+
+	indexes := h.bucketIndexes()
+	for _, index := range indexes {
+		count := float64(h.Buckets[index])
+		if rank <= cumulative+count {
+			lower, upper := h.UpperBound(index-1), h.UpperBound(index)
+			fraction := (rank - cumulative) / count
+			return lower * math.Pow(upper/lower, fraction)
+		}
+		cumulative += count
+	}
+
+	if len(indexes) == 0 {
+		return 0
+	}
+	return h.UpperBound(indexes[len(indexes)-1])
+}
+
+func (h *Histogram) bucketIndexes() []int32 {
+	indexes := make([]int32, 0, len(h.Buckets))
+	for index, count := range h.Buckets {
+		if count > 0 {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i] < indexes[j]
+	})
+	return indexes
+}
+
+// Spans returns the non-empty buckets as spans of consecutive bucket indexes and the counts
+// of the buckets in order, gaps of up to two empty buckets are included in a span
+func (h *Histogram) Spans() (offsets []int32, lengths []uint32, counts []uint64) {
+	indexes := h.bucketIndexes()
+	for i, index := range indexes {
+		gap := int32(0)
+		if i > 0 {
+			gap = index - indexes[i-1] - 1
+		}
+		switch {
+		case i == 0:
+			offsets = append(offsets, index)
+			lengths = append(lengths, 1)
+		case gap <= 2:
+			for j := int32(0); j < gap; j++ {
+				counts = append(counts, 0)
+			}
+			lengths[len(lengths)-1] += uint32(gap) + 1
+		default:
+			offsets = append(offsets, gap)
+			lengths = append(lengths, 1)
+		}
+		counts = append(counts, h.Buckets[index])
+	}
+	return offsets, lengths, counts
+}
+
+// Samples adds the histogram and the percentiles calculated from it to the samples of a
+// metrics source. The histogram is reported as one sample per bucket, e.g.
+// request.time.hist.schema_3.bucket.-80, and the count, sum and zero_count samples so that
+// histograms are merged by adding up their samples.
+func (h *Histogram) Samples(metricName string, samples map[string]float64) {
+	prefix := metricName + histogramInfix
+	samples[prefix+histogramCountSuffix] = float64(h.Count)
+	samples[prefix+histogramSumSuffix] = h.Sum
+	samples[prefix+histogramZeroCountSuffix] = float64(h.ZeroCount)
+
+	bucketPrefix := prefix + histogramSchemaPrefix + strconv.Itoa(int(h.Schema)) + histogramBucketInfix
+	for index, count := range h.Buckets {
+		samples[bucketPrefix+strconv.Itoa(int(index))] = float64(count)
+	}
+
+	for suffix, q := range HistogramPercentiles {
+		samples[metricName+"."+suffix] = h.Quantile(q)
+	}
+}
+
+// IsHistogramMetric reports whether the metric is part of a histogram reported by Samples
+func IsHistogramMetric(metricName string) bool {
+	return strings.Contains(metricName, histogramInfix)
+}
+
+// HistogramsFromSimpleMetrics reassembles the histograms reported by Samples, keyed by the
+// name of the metric, and returns the remaining metrics. Buckets reported with different
+// schemas are merged into the lowest schema.
+func HistogramsFromSimpleMetrics(simpleMetrics []*proto.SimpleMetric) (map[string]*Histogram, []*proto.SimpleMetric) {
+	histograms := make(map[string]*Histogram)
+	buckets := make(map[string]map[int32]*Histogram)
+	others := make([]*proto.SimpleMetric, 0, len(simpleMetrics))
+
+	for _, metric := range simpleMetrics {
+		idx := strings.LastIndex(metric.GetName(), histogramInfix)
+		if idx == -1 {
+			others = append(others, metric)
+			continue
+		}
+		name, suffix := metric.GetName()[:idx], metric.GetName()[idx+len(histogramInfix):]
+
+		histogram, ok := histograms[name]
+		if !ok {
+			histogram = &Histogram{Schema: MaxHistogramSchema, Buckets: make(map[int32]uint64)}
+			histograms[name] = histogram
+			buckets[name] = make(map[int32]*Histogram)
+		}
+
+		switch suffix {
+		case histogramCountSuffix:
+			histogram.Count = uint64(metric.GetValue())
+		case histogramSumSuffix:
+			histogram.Sum = metric.GetValue()
+		case histogramZeroCountSuffix:
+			histogram.ZeroCount = uint64(metric.GetValue())
+		default:
+			schema, index, err := parseBucketSuffix(suffix)
+			if err != nil {
+				others = append(others, metric)
+				continue
+			}
+			if _, ok := buckets[name][schema]; !ok {
+				buckets[name][schema] = NewHistogram(schema)
+			}
+			buckets[name][schema].Buckets[index] = uint64(metric.GetValue())
+		}
+	}
+
+	for name, histogram := range histograms {
+		for _, schemaBuckets := range buckets[name] {
+			// only the buckets are merged, the count, sum and zero count are already totals
+			histogram.Merge(&Histogram{Schema: schemaBuckets.Schema, Buckets: schemaBuckets.Buckets})
+		}
+	}
+
+	return histograms, others
+}
+
+// parseBucketSuffix parses the schema and index of a bucket sample, e.g. schema_3.bucket.-80
+func parseBucketSuffix(suffix string) (int32, int32, error) {
+	if !strings.HasPrefix(suffix, histogramSchemaPrefix) {
+		return 0, 0, fmt.Errorf("invalid histogram sample %q", suffix)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(suffix, histogramSchemaPrefix), histogramBucketInfix, 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid histogram bucket %q", suffix)
+	}
+	schema, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	index, err := strconv.ParseInt(parts[1], 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int32(schema), int32(index), nil
+}
@@ -0,0 +1,163 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package metrics
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramBucketIndex(t *testing.T) {
+	tests := []struct {
+		schema   int32
+		value    float64
+		expected int32
+	}{
+		{0, 1, 0},
+		{0, 1.5, 1},
+		{0, 2, 1},
+		{0, 0.5, -1},
+		{0, 0.3, -1},
+		{3, 1, 0},
+		{3, 2, 8},
+		{3, 1.05, 1},
+		{3, 0.001, -79},
+		{-1, 2, 1},
+		{-1, 4, 1},
+		{-1, 5, 2},
+		{-2, 0.25, 0},
+		{-2, 0.0625, -1},
+	}
+
+	for _, test := range tests {
+		histogram := NewHistogram(test.schema)
+		index := histogram.bucketIndex(test.value)
+		assert.Equal(t, test.expected, index, "schema %d value %f", test.schema, test.value)
+		assert.LessOrEqual(t, test.value, histogram.UpperBound(index))
+		assert.Greater(t, test.value, histogram.UpperBound(index-1))
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	histogram := NewHistogram(DefaultHistogramSchema)
+	assert.Equal(t, float64(0), histogram.Quantile(0.5))
+
+	values := make([]float64, 0, 1000)
+	for i := 1; i <= 1000; i++ {
+		value := float64(i) / 1000
+		values = append(values, value)
+		histogram.Observe(value)
+	}
+	histogram.Observe(0)
+
+	assert.Equal(t, uint64(1001), histogram.Count)
+	assert.Equal(t, uint64(1), histogram.ZeroCount)
+	assert.InDelta(t, 500.5, histogram.Sum, 0.0001)
+
+	sort.Float64s(values)
+	for _, q := range []float64{0.5, 0.9, 0.99, 0.999} {
+		expected := values[int(q*float64(len(values)))-1]
+		// the relative error is bounded by the width of a bucket, about 9% for schema 3
+		assert.InEpsilon(t, expected, histogram.Quantile(q), 0.09, "quantile %f", q)
+	}
+	assert.Equal(t, float64(0), histogram.Quantile(0))
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a := NewHistogram(3)
+	b := NewHistogram(3)
+	all := NewHistogram(3)
+	for i := 1; i <= 100; i++ {
+		a.Observe(float64(i) / 100)
+		all.Observe(float64(i) / 100)
+	}
+	for i := 1; i <= 100; i++ {
+		b.Observe(float64(i))
+		all.Observe(float64(i))
+	}
+
+	a.Merge(b)
+	assert.Equal(t, all, a)
+
+	// histograms with a higher resolution are reduced
+	low := NewHistogram(1)
+	low.Observe(3)
+	high := NewHistogram(3)
+	high.Observe(3)
+	high.Observe(100)
+	high.Merge(low)
+
+	expected := NewHistogram(1)
+	expected.Observe(3)
+	expected.Observe(3)
+	expected.Observe(100)
+	assert.Equal(t, expected, high)
+}
+
+func TestHistogramSpans(t *testing.T) {
+	histogram := NewHistogram(0)
+	histogram.Buckets = map[int32]uint64{-3: 1, -2: 2, 0: 3, 4: 4, 5: 5}
+
+	offsets, lengths, counts := histogram.Spans()
+	assert.Equal(t, []int32{-3, 3}, offsets)
+	assert.Equal(t, []uint32{4, 2}, lengths)
+	assert.Equal(t, []uint64{1, 2, 0, 3, 4, 5}, counts)
+}
+
+func TestHistogramSamples(t *testing.T) {
+	histogram := NewHistogram(3)
+	histogram.Observe(0)
+	histogram.Observe(1)
+	histogram.Observe(2)
+	histogram.Observe(2)
+
+	samples := map[string]float64{}
+	histogram.Samples("request.time", samples)
+
+	assert.Equal(t, map[string]float64{
+		"request.time.hist.count":             4,
+		"request.time.hist.sum":               5,
+		"request.time.hist.zero_count":        1,
+		"request.time.hist.schema_3.bucket.0": 1,
+		"request.time.hist.schema_3.bucket.8": 2,
+		"request.time.pctl50":                 samples["request.time.pctl50"],
+		"request.time.pctl90":                 samples["request.time.pctl90"],
+		"request.time.pctl99":                 samples["request.time.pctl99"],
+		"request.time.pctl999":                samples["request.time.pctl999"],
+	}, samples)
+	assert.InEpsilon(t, 1, samples["request.time.pctl50"], 0.09)
+	assert.InEpsilon(t, 2, samples["request.time.pctl99"], 0.09)
+
+	simpleMetrics := []*proto.SimpleMetric{{Name: "nginx.http.request.count", Value: 4}}
+	for name, value := range samples {
+		simpleMetrics = append(simpleMetrics, &proto.SimpleMetric{Name: "nginx.http." + name, Value: value})
+	}
+	// buckets of another schema are merged into the lower schema
+	simpleMetrics = append(simpleMetrics, &proto.SimpleMetric{Name: "nginx.http.request.time.hist.schema_0.bucket.2", Value: 1})
+	simpleMetrics = append(simpleMetrics, &proto.SimpleMetric{Name: "nginx.http.request.time.hist.schema_x.bucket.2", Value: 1})
+
+	histograms, others := HistogramsFromSimpleMetrics(simpleMetrics)
+	require.Len(t, histograms, 1)
+	assert.Equal(t, &Histogram{
+		Schema:    0,
+		Count:     4,
+		Sum:       5,
+		ZeroCount: 1,
+		Buckets:   map[int32]uint64{0: 1, 1: 2, 2: 1},
+	}, histograms["nginx.http.request.time"])
+	assert.Len(t, others, 6)
+	assert.True(t, IsHistogramMetric("nginx.http.request.time.hist.count"))
+	assert.False(t, IsHistogramMetric("nginx.http.request.time.pctl99"))
+	assert.False(t, math.IsNaN(histograms["nginx.http.request.time"].Quantile(0.5)))
+}
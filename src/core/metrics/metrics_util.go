@@ -17,6 +17,7 @@ import (
 	"github.com/nginx/agent/sdk/v2/proto"
 	"github.com/nginx/agent/v2/src/core"
 	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/tailer"
 
 	"github.com/gogo/protobuf/types"
 )
@@ -55,6 +56,49 @@ type NginxCollectorConfig struct {
 	AccessLogs         []string
 	ErrorLogs          []string
 	ClientVersion      int
+	AccessLogMetrics   config.AccessLogMetrics
+	ErrorLogEvents     config.ErrorLogEvents
+	// Upstreams are the server addresses of each upstream in the NGINX configuration, keyed by
+	// the name of the upstream
+	Upstreams map[string][]string
+	// ErrorLogEventChannel receives the error log lines that are forwarded as activity events
+	ErrorLogEventChannel chan<- *NginxErrorLogEvent
+	CertificateMonitor   config.CertificateMonitor
+	// Certificates are the certificate files referenced in the NGINX configuration, keyed by file
+	// with the directive that references the file as value
+	Certificates map[string]string
+	// CertificateEventChannel receives the certificates that reach an expiry threshold
+	CertificateEventChannel chan<- *CertificateExpiryEvent
+}
+
+// NginxErrorLogEvent is an error log line of an NGINX instance that is forwarded as an
+// activity event
+type NginxErrorLogEvent struct {
+	NginxId string
+	LogFile string
+	Item    *tailer.NginxErrorItem
+}
+
+const (
+	CertificateExpiryWarning  = "warning"
+	CertificateExpiryCritical = "critical"
+	CertificateExpired        = "expired"
+)
+
+// CertificateExpiryEvent is raised when a monitored certificate reaches the warning or critical
+// threshold of the certificate monitor, or has expired
+type CertificateExpiryEvent struct {
+	// NginxId is empty for the certificates of the agent
+	NginxId string
+	File    string
+	// Source is the NGINX directive or agent setting that references the certificate file
+	Source          string
+	Subject         string
+	SerialNumber    string
+	NotAfter        time.Time
+	DaysUntilExpiry int
+	// Level is one of CertificateExpiryWarning, CertificateExpiryCritical or CertificateExpired
+	Level string
 }
 
 func NewStatsEntityWrapper(dims []*proto.Dimension, samples []*proto.SimpleMetric, seType proto.MetricsReport_Type) *StatsEntityWrapper {
@@ -117,6 +161,14 @@ func GetTimeMetrics(times []float64, metricType string) float64 {
 
 func GetCalculationMap() map[string]string {
 	return map[string]string{
+		"agent.pipe.queue.depth":                             "avg",
+		"agent.pipe.queue.usage":                             "avg",
+		"agent.pipe.queue.full":                              "sum",
+		"agent.pipe.messages.published":                      "sum",
+		"agent.pipe.plugin.processed":                        "sum",
+		"agent.pipe.plugin.slow":                             "sum",
+		"agent.pipe.plugin.pending":                          "avg",
+		"agent.pipe.plugin.processing_time":                  "avg",
 		"system.cpu.idle":                                    "avg",
 		"system.cpu.iowait":                                  "avg",
 		"system.cpu.stolen":                                  "avg",
@@ -190,6 +242,14 @@ func GetCalculationMap() map[string]string {
 		"nginx.http.request.count":                           "sum",
 		"nginx.http.request.current":                         "avg",
 		"nginx.http.request.buffered":                        "sum",
+		"nginx.error_log.debug":                              "sum",
+		"nginx.error_log.info":                               "sum",
+		"nginx.error_log.notice":                             "sum",
+		"nginx.error_log.warn":                               "sum",
+		"nginx.error_log.error":                              "sum",
+		"nginx.error_log.crit":                               "sum",
+		"nginx.error_log.alert":                              "sum",
+		"nginx.error_log.emerg":                              "sum",
 		"nginx.http.v0_9":                                    "sum",
 		"nginx.http.v1_0":                                    "sum",
 		"nginx.http.v1_1":                                    "sum",
@@ -215,6 +275,20 @@ func GetCalculationMap() map[string]string {
 		"nginx.upstream.status.3xx":                          "sum",
 		"nginx.upstream.status.4xx":                          "sum",
 		"nginx.upstream.status.5xx":                          "sum",
+		"nginx.upstream.peers.request.count":                 "sum",
+		"nginx.upstream.peers.fails":                         "sum",
+		"nginx.upstream.peers.status.1xx":                    "sum",
+		"nginx.upstream.peers.status.2xx":                    "sum",
+		"nginx.upstream.peers.status.3xx":                    "sum",
+		"nginx.upstream.peers.status.4xx":                    "sum",
+		"nginx.upstream.peers.status.5xx":                    "sum",
+		"nginx.upstream.peers.state.up":                      "avg",
+		"nginx.upstream.peers.state.down":                    "avg",
+		"nginx.upstream.peers.response.time":                 "avg",
+		"nginx.upstream.peers.response.time.count":           "sum",
+		"nginx.upstream.peers.response.time.max":             "avg",
+		"nginx.upstream.peers.response.time.median":          "avg",
+		"nginx.upstream.peers.response.time.pctl95":          "avg",
 		"nginx.http.conn.handled":                            "sum",
 		"nginx.http.conn.reading":                            "avg",
 		"nginx.http.conn.writing":                            "avg",
@@ -424,3 +498,37 @@ func GenerateMetricsReportBundle(entities []*StatsEntityWrapper) core.Payload {
 	}
 	return nil
 }
+
+// MetricsSnapshotEntry is a flattened stats entity, used to print the metrics reports as JSON
+type MetricsSnapshotEntry struct {
+	Type       string             `json:"type"`
+	Dimensions map[string]string  `json:"dimensions"`
+	Metrics    map[string]float64 `json:"metrics"`
+}
+
+// NewMetricsSnapshot flattens the stats entities of the metrics reports. Values that are not
+// finite, like the percentages calculated over an empty period, can not be encoded in JSON
+// and are left out.
+func NewMetricsSnapshot(reports []*proto.MetricsReport) []MetricsSnapshotEntry {
+	entries := []MetricsSnapshotEntry{}
+	for _, report := range reports {
+		for _, entity := range report.GetData() {
+			entry := MetricsSnapshotEntry{
+				Type:       report.GetType().String(),
+				Dimensions: make(map[string]string, len(entity.GetDimensions())),
+				Metrics:    make(map[string]float64, len(entity.GetSimplemetrics())),
+			}
+			for _, dimension := range entity.GetDimensions() {
+				entry.Dimensions[dimension.GetName()] = dimension.GetValue()
+			}
+			for _, metric := range entity.GetSimplemetrics() {
+				if math.IsNaN(metric.GetValue()) || math.IsInf(metric.GetValue(), 0) {
+					continue
+				}
+				entry.Metrics[metric.GetName()] = metric.GetValue()
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,310 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sources
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// CertificateExpiryDaysMetricName is the number of days until a certificate expires, negative
+	// once it has expired
+	CertificateExpiryDaysMetricName = "expiry_days"
+
+	CertificateFileDimension    = "certificate.file"
+	CertificateSourceDimension  = "certificate.source"
+	CertificateSubjectDimension = "certificate.subject"
+	CertificateSerialDimension  = "certificate.serial"
+)
+
+var certificateExpiryLevels = map[string]int{
+	"":                                1,
+	metrics.CertificateExpiryWarning:  2,
+	metrics.CertificateExpiryCritical: 3,
+	metrics.CertificateExpired:        4,
+}
+
+type monitoredCertificate struct {
+	file        string
+	source      string
+	certificate *x509.Certificate
+}
+
+// key identifies the certificate by its file and serial number
+func (m *monitoredCertificate) key() string {
+	return fmt.Sprintf("%s_%s", m.file, m.certificate.SerialNumber)
+}
+
+// certificateMonitor reads the monitored certificate files once per check interval, reports the
+// days until each certificate expires and raises an event every time a certificate reaches a
+// higher expiry level
+type certificateMonitor struct {
+	*namedMetric
+	mu           *sync.Mutex
+	conf         config.CertificateMonitor
+	files        map[string]string
+	certificates []*monitoredCertificate
+	lastCheck    time.Time
+	levels       map[string]string
+	eventChannel chan<- *metrics.CertificateExpiryEvent
+	currentTime  func() time.Time
+}
+
+func newCertificateMonitor(namespace string, conf config.CertificateMonitor, files map[string]string, eventChannel chan<- *metrics.CertificateExpiryEvent) *certificateMonitor {
+	return &certificateMonitor{
+		namedMetric:  &namedMetric{namespace: namespace, group: "ssl.certificate"},
+		mu:           &sync.Mutex{},
+		conf:         conf,
+		files:        files,
+		levels:       make(map[string]string),
+		eventChannel: eventChannel,
+		currentTime:  time.Now,
+	}
+}
+
+func (c *certificateMonitor) update(conf config.CertificateMonitor, files map[string]string, eventChannel chan<- *metrics.CertificateExpiryEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !reflect.DeepEqual(c.files, files) {
+		c.files = files
+		// read the new files at the next collection
+		c.lastCheck = time.Time{}
+	}
+	c.conf = conf
+	if eventChannel != nil {
+		c.eventChannel = eventChannel
+	}
+}
+
+// collect returns the days until expiry of every monitored certificate, the common dimensions
+// are added in front of the certificate dimensions
+func (c *certificateMonitor) collect(nginxId string, commonDimensions []*proto.Dimension) []*metrics.StatsEntityWrapper {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.conf.Enable {
+		return nil
+	}
+
+	now := c.currentTime()
+	if c.lastCheck.IsZero() || now.Sub(c.lastCheck) >= c.conf.CheckInterval {
+		c.certificates = readCertificates(c.files)
+		c.lastCheck = now
+		c.pruneLevels()
+	}
+
+	entities := make([]*metrics.StatsEntityWrapper, 0, len(c.certificates))
+	for _, monitored := range c.certificates {
+		cert := monitored.certificate
+		days := int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24))
+
+		dimensions := append([]*proto.Dimension{}, commonDimensions...)
+		dimensions = append(dimensions,
+			&proto.Dimension{Name: CertificateFileDimension, Value: monitored.file},
+			&proto.Dimension{Name: CertificateSourceDimension, Value: monitored.source},
+			&proto.Dimension{Name: CertificateSubjectDimension, Value: cert.Subject.String()},
+			&proto.Dimension{Name: CertificateSerialDimension, Value: cert.SerialNumber.String()},
+		)
+		entities = append(entities, metrics.NewStatsEntityWrapper(
+			dimensions,
+			c.convertSamplesToSimpleMetrics(map[string]float64{CertificateExpiryDaysMetricName: float64(days)}),
+			proto.MetricsReport_INSTANCE,
+		))
+
+		c.checkExpiry(nginxId, monitored, now, days)
+	}
+
+	return entities
+}
+
+func (c *certificateMonitor) checkExpiry(nginxId string, monitored *monitoredCertificate, now time.Time, days int) {
+	cert := monitored.certificate
+	level := ""
+	switch {
+	case !now.Before(cert.NotAfter):
+		level = metrics.CertificateExpired
+	case days <= c.conf.CriticalDays:
+		level = metrics.CertificateExpiryCritical
+	case days <= c.conf.WarningDays:
+		level = metrics.CertificateExpiryWarning
+	}
+
+	key := monitored.key()
+	previous, seen := c.levels[key]
+	c.levels[key] = level
+	if level == "" || (seen && certificateExpiryLevels[level] <= certificateExpiryLevels[previous]) {
+		return
+	}
+
+	if c.eventChannel == nil {
+		return
+	}
+
+	event := &metrics.CertificateExpiryEvent{
+		NginxId:         nginxId,
+		File:            monitored.file,
+		Source:          monitored.source,
+		Subject:         cert.Subject.String(),
+		SerialNumber:    cert.SerialNumber.String(),
+		NotAfter:        cert.NotAfter,
+		DaysUntilExpiry: days,
+		Level:           level,
+	}
+	select {
+	case c.eventChannel <- event:
+	default:
+		log.Debugf("Dropping certificate expiry event of %s, the event channel is full", monitored.file)
+	}
+}
+
+// pruneLevels removes the expiry levels of the certificates that are no longer monitored, like
+// renewed certificates and the certificates of files no longer referenced
+func (c *certificateMonitor) pruneLevels() {
+	monitored := make(map[string]struct{}, len(c.certificates))
+	for _, certificate := range c.certificates {
+		monitored[certificate.key()] = struct{}{}
+	}
+	for key := range c.levels {
+		if _, ok := monitored[key]; !ok {
+			delete(c.levels, key)
+		}
+	}
+}
+
+// readCertificates parses every certificate in the PEM files, a file can contain a chain or a
+// bundle of trusted CA certificates
+func readCertificates(files map[string]string) []*monitoredCertificate {
+	names := make([]string, 0, len(files))
+	for file := range files {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+
+	certificates := []*monitoredCertificate{}
+	for _, file := range names {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			log.Warnf("Unable to read certificate file %s: %v", file, err)
+			continue
+		}
+
+		for block, rest := pem.Decode(contents); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				log.Warnf("Unable to parse certificate in %s: %v", file, err)
+				continue
+			}
+			certificates = append(certificates, &monitoredCertificate{file: file, source: files[file], certificate: cert})
+		}
+	}
+	return certificates
+}
+
+// NginxCertificates reports the days until the certificates referenced in the NGINX
+// configuration expire
+type NginxCertificates struct {
+	baseDimensions *metrics.CommonDim
+	*certificateMonitor
+}
+
+func NewNginxCertificates(baseDimensions *metrics.CommonDim, namespace string, collectorConf *metrics.NginxCollectorConfig) *NginxCertificates {
+	return &NginxCertificates{
+		baseDimensions:     baseDimensions,
+		certificateMonitor: newCertificateMonitor(namespace, collectorConf.CertificateMonitor, collectorConf.Certificates, collectorConf.CertificateEventChannel),
+	}
+}
+
+func (c *NginxCertificates) Collect(ctx context.Context, wg *sync.WaitGroup, m chan<- *metrics.StatsEntityWrapper) {
+	defer wg.Done()
+
+	for _, entity := range c.collect(c.baseDimensions.NginxId, c.baseDimensions.ToDimensions()) {
+		select {
+		case <-ctx.Done():
+			return
+		case m <- entity:
+		}
+	}
+}
+
+func (c *NginxCertificates) Stop() {
+	log.Debugf("Stopping NginxCertificates source for nginx id: %v", c.baseDimensions.NginxId)
+}
+
+func (c *NginxCertificates) Update(dimensions *metrics.CommonDim, collectorConf *metrics.NginxCollectorConfig) {
+	c.baseDimensions = dimensions
+	c.update(collectorConf.CertificateMonitor, collectorConf.Certificates, collectorConf.CertificateEventChannel)
+}
+
+// AgentCertificates reports the days until the certificates the agent uses for the connection to
+// the management plane and for the Agent API expire
+type AgentCertificates struct {
+	*certificateMonitor
+}
+
+func NewAgentCertificates(namespace string, conf *config.Config, eventChannel chan<- *metrics.CertificateExpiryEvent) *AgentCertificates {
+	return &AgentCertificates{
+		certificateMonitor: newCertificateMonitor(namespace, conf.CertificateMonitor, agentCertificateFiles(conf), eventChannel),
+	}
+}
+
+func (c *AgentCertificates) Collect(ctx context.Context, wg *sync.WaitGroup, m chan<- *metrics.StatsEntityWrapper) {
+	defer wg.Done()
+
+	for _, entity := range c.collect("", []*proto.Dimension{}) {
+		entity.Type = proto.MetricsReport_AGENT
+		select {
+		case <-ctx.Done():
+			return
+		case m <- entity:
+		}
+	}
+}
+
+func (c *AgentCertificates) Update(conf *config.Config) {
+	c.update(conf.CertificateMonitor, agentCertificateFiles(conf), nil)
+}
+
+// agentCertificateFiles returns the certificate files of the agent, keyed by file with the
+// setting that references the file as value
+func agentCertificateFiles(conf *config.Config) map[string]string {
+	files := make(map[string]string)
+	add := func(setting, file string) {
+		if _, ok := files[file]; file != "" && !ok {
+			files[file] = setting
+		}
+	}
+
+	if conf.TLS.Enable {
+		add(config.TlsCert, conf.TLS.Cert)
+		add(config.TlsCa, conf.TLS.Ca)
+	}
+	add(config.AgentAPICert, conf.AgentAPI.Cert)
+	add(config.AgentAPIClientCA, conf.AgentAPI.ClientCA)
+
+	return files
+}
@@ -0,0 +1,206 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sources
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+)
+
+func createTestCertificate(t *testing.T, commonName string, serial int64, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+}
+
+func TestNginxCertificates_Collect(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	certFile := filepath.Join(t.TempDir(), "example.crt")
+	chain := append(
+		createTestCertificate(t, "example.com", 1, now.Add(20*24*time.Hour+time.Hour)),
+		createTestCertificate(t, "Example CA", 2, now.AddDate(2, 0, 0))...,
+	)
+	require.NoError(t, os.WriteFile(certFile, chain, 0o600))
+
+	eventChannel := make(chan *metrics.CertificateExpiryEvent, 10)
+	collectorConf := &metrics.NginxCollectorConfig{
+		CertificateMonitor:      config.CertificateMonitor{Enable: true, CheckInterval: time.Hour, WarningDays: 30, CriticalDays: 7},
+		Certificates:            map[string]string{certFile: "ssl_certificate", "/missing/example.crt": "ssl_trusted_certificate"},
+		CertificateEventChannel: eventChannel,
+	}
+	nginxCertificates := NewNginxCertificates(&metrics.CommonDim{NginxId: "12345"}, OSSNamespace, collectorConf)
+	nginxCertificates.currentTime = func() time.Time { return now }
+
+	collect := func() []*metrics.StatsEntityWrapper {
+		m := make(chan *metrics.StatsEntityWrapper, 10)
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		nginxCertificates.Collect(context.TODO(), wg, m)
+		close(m)
+
+		entities := []*metrics.StatsEntityWrapper{}
+		for entity := range m {
+			entities = append(entities, entity)
+		}
+		return entities
+	}
+
+	entities := collect()
+	require.Len(t, entities, 2)
+	assert.Equal(t, []*proto.SimpleMetric{{Name: "nginx.ssl.certificate.expiry_days", Value: 20}}, entities[0].Data.Simplemetrics)
+	assert.Equal(t, []*proto.SimpleMetric{{Name: "nginx.ssl.certificate.expiry_days", Value: 731}}, entities[1].Data.Simplemetrics)
+
+	dimensions := entities[0].Data.Dimensions
+	assert.Contains(t, dimensions, &proto.Dimension{Name: "nginx_id", Value: "12345"})
+	assert.Equal(t, []*proto.Dimension{
+		{Name: CertificateFileDimension, Value: certFile},
+		{Name: CertificateSourceDimension, Value: "ssl_certificate"},
+		{Name: CertificateSubjectDimension, Value: "CN=example.com"},
+		{Name: CertificateSerialDimension, Value: "1"},
+	}, dimensions[len(dimensions)-4:])
+
+	require.Len(t, eventChannel, 1)
+	assert.Equal(t, &metrics.CertificateExpiryEvent{
+		NginxId:         "12345",
+		File:            certFile,
+		Source:          "ssl_certificate",
+		Subject:         "CN=example.com",
+		SerialNumber:    "1",
+		NotAfter:        now.Add(20*24*time.Hour + time.Hour),
+		DaysUntilExpiry: 20,
+		Level:           metrics.CertificateExpiryWarning,
+	}, <-eventChannel)
+
+	// events are only raised when a certificate reaches a higher level
+	now = now.Add(24 * time.Hour)
+	collect()
+	assert.Empty(t, eventChannel)
+
+	now = now.Add(14 * 24 * time.Hour)
+	collect()
+	require.Len(t, eventChannel, 1)
+	event := <-eventChannel
+	assert.Equal(t, metrics.CertificateExpiryCritical, event.Level)
+	assert.Equal(t, 5, event.DaysUntilExpiry)
+
+	now = now.Add(6 * 24 * time.Hour)
+	entities = collect()
+	assert.Equal(t, float64(-1), entities[0].Data.Simplemetrics[0].Value)
+	require.Len(t, eventChannel, 1)
+	assert.Equal(t, metrics.CertificateExpired, (<-eventChannel).Level)
+
+	// the expiry levels of replaced certificates are removed when the files are read again
+	renewed := append(
+		createTestCertificate(t, "example.com", 3, now.AddDate(0, 3, 0)),
+		createTestCertificate(t, "Example CA", 2, now.AddDate(2, 0, 0))...,
+	)
+	require.NoError(t, os.WriteFile(certFile, renewed, 0o600))
+	now = now.Add(time.Hour)
+	collect()
+	assert.Empty(t, eventChannel)
+	assert.Equal(t, map[string]string{certFile + "_2": "", certFile + "_3": ""}, nginxCertificates.levels)
+
+	// a disabled monitor doesn't report the certificates
+	collectorConf.CertificateMonitor.Enable = false
+	nginxCertificates.Update(&metrics.CommonDim{NginxId: "12345"}, collectorConf)
+	assert.Empty(t, collect())
+}
+
+func TestAgentCertificates_Collect(t *testing.T) {
+	certFile := filepath.Join(t.TempDir(), "agent.crt")
+	require.NoError(t, os.WriteFile(certFile, createTestCertificate(t, "agent", 3, time.Now().AddDate(1, 0, 0)), 0o600))
+
+	conf := &config.Config{
+		AgentAPI:           config.AgentAPI{Cert: certFile},
+		CertificateMonitor: config.CertificateMonitor{Enable: true, CheckInterval: time.Hour, WarningDays: 30, CriticalDays: 7},
+	}
+	eventChannel := make(chan *metrics.CertificateExpiryEvent, 10)
+	agentCertificates := NewAgentCertificates(AgentNamespace, conf, eventChannel)
+
+	m := make(chan *metrics.StatsEntityWrapper, 10)
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	agentCertificates.Collect(context.TODO(), wg, m)
+
+	require.Len(t, m, 1)
+	entity := <-m
+	assert.Equal(t, proto.MetricsReport_AGENT, entity.Type)
+	assert.Equal(t, "agent.ssl.certificate.expiry_days", entity.Data.Simplemetrics[0].Name)
+	assert.Contains(t, entity.Data.Dimensions, &proto.Dimension{Name: CertificateSourceDimension, Value: config.AgentAPICert})
+	assert.Empty(t, eventChannel)
+}
+
+func TestAgentCertificateFiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     *config.Config
+		expected map[string]string
+	}{
+		{
+			name:     "no certificates",
+			conf:     &config.Config{},
+			expected: map[string]string{},
+		},
+		{
+			name: "tls disabled",
+			conf: &config.Config{
+				TLS:      config.TLSConfig{Cert: "/etc/nginx-agent/agent.crt", Ca: "/etc/nginx-agent/ca.crt"},
+				AgentAPI: config.AgentAPI{Cert: "/etc/nginx-agent/api.crt", ClientCA: "/etc/nginx-agent/ca.crt"},
+			},
+			expected: map[string]string{
+				"/etc/nginx-agent/api.crt": config.AgentAPICert,
+				"/etc/nginx-agent/ca.crt":  config.AgentAPIClientCA,
+			},
+		},
+		{
+			name: "tls enabled",
+			conf: &config.Config{
+				TLS:      config.TLSConfig{Enable: true, Cert: "/etc/nginx-agent/agent.crt", Ca: "/etc/nginx-agent/ca.crt"},
+				AgentAPI: config.AgentAPI{ClientCA: "/etc/nginx-agent/ca.crt"},
+			},
+			expected: map[string]string{
+				"/etc/nginx-agent/agent.crt": config.TlsCert,
+				"/etc/nginx-agent/ca.crt":    config.TlsCa,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, agentCertificateFiles(tt.conf))
+		})
+	}
+}
@@ -20,6 +20,7 @@ const (
 	PlusNamespace      = "plus"
 	SystemNamespace    = "system"
 	ContainerNamespace = "container"
+	AgentNamespace     = "agent"
 
 	OSSNginxType  = "oss"
 	PlusNginxType = "plus"
@@ -0,0 +1,98 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sources
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/metrics"
+)
+
+const (
+	TopicDimension  = "topic"
+	PluginDimension = "plugin"
+)
+
+// MessagePipe reports the throughput and saturation of the internal message pipe, and the
+// processing time of each plugin, as agent metrics
+type MessagePipe struct {
+	*namedMetric
+	stats    *core.PipeStats
+	previous *core.PipeStatsSnapshot
+}
+
+func NewMessagePipeSource(namespace string, stats *core.PipeStats) *MessagePipe {
+	return &MessagePipe{
+		namedMetric: &namedMetric{namespace, "pipe"},
+		stats:       stats,
+		previous:    &core.PipeStatsSnapshot{},
+	}
+}
+
+func (c *MessagePipe) Collect(ctx context.Context, wg *sync.WaitGroup, m chan<- *metrics.StatsEntityWrapper) {
+	defer wg.Done()
+
+	current := c.stats.Snapshot()
+	previous := c.previous
+	c.previous = current
+
+	queueUsage := 0.0
+	if current.QueueCapacity > 0 {
+		queueUsage = float64(current.QueueDepth) / float64(current.QueueCapacity) * 100
+	}
+
+	entities := []*metrics.StatsEntityWrapper{
+		metrics.NewStatsEntityWrapper([]*proto.Dimension{}, c.convertSamplesToSimpleMetrics(map[string]float64{
+			"queue.depth": float64(current.QueueDepth),
+			"queue.usage": queueUsage,
+			"queue.full":  float64(current.QueueFull - previous.QueueFull),
+		}), proto.MetricsReport_AGENT),
+	}
+
+	for topic, published := range current.Published {
+		entities = append(entities, metrics.NewStatsEntityWrapper(
+			[]*proto.Dimension{{Name: TopicDimension, Value: topic}},
+			c.convertSamplesToSimpleMetrics(map[string]float64{
+				"messages.published": float64(published - previous.Published[topic]),
+			}),
+			proto.MetricsReport_AGENT,
+		))
+	}
+
+	for plugin, stats := range current.Plugins {
+		previousStats := previous.Plugins[plugin]
+		processed := stats.Processed - previousStats.Processed
+
+		processingTime := 0.0
+		if processed > 0 {
+			processingTime = (stats.ProcessingTime - previousStats.ProcessingTime) / float64(processed)
+		}
+
+		entities = append(entities, metrics.NewStatsEntityWrapper(
+			[]*proto.Dimension{{Name: PluginDimension, Value: plugin}},
+			c.convertSamplesToSimpleMetrics(map[string]float64{
+				"plugin.processed":       float64(processed),
+				"plugin.slow":            float64(stats.Slow - previousStats.Slow),
+				"plugin.pending":         float64(stats.Pending),
+				"plugin.processing_time": processingTime,
+			}),
+			proto.MetricsReport_AGENT,
+		))
+	}
+
+	for _, entity := range entities {
+		select {
+		case <-ctx.Done():
+			return
+		case m <- entity:
+		}
+	}
+}
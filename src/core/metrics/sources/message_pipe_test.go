@@ -0,0 +1,62 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sources
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessagePipeCollect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pipeline := core.NewMessagePipe(ctx, 10)
+	source := NewMessagePipeSource(AgentNamespace, pipeline.GetStats())
+
+	pipeline.Process(core.NewMessage("test.message", 1))
+
+	collect := func() map[string]map[string]float64 {
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		channel := make(chan *metrics.StatsEntityWrapper, 100)
+		source.Collect(ctx, wg, channel)
+		wg.Wait()
+		close(channel)
+
+		actual := make(map[string]map[string]float64)
+		for entity := range channel {
+			key := ""
+			for _, dimension := range entity.Data.Dimensions {
+				key = dimension.Name + "=" + dimension.Value
+			}
+			actual[key] = make(map[string]float64)
+			for _, simpleMetric := range entity.Data.Simplemetrics {
+				actual[key][simpleMetric.Name] = simpleMetric.Value
+			}
+		}
+		return actual
+	}
+
+	assert.Equal(t, map[string]map[string]float64{
+		"": {
+			"agent.pipe.queue.depth": 1,
+			"agent.pipe.queue.usage": 10,
+			"agent.pipe.queue.full":  0,
+		},
+	}, collect())
+
+	pipeline.Process(core.NewMessage("test.message", 2))
+	assert.Equal(t, float64(2), collect()[""]["agent.pipe.queue.depth"])
+}
@@ -10,6 +10,7 @@ package sources
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"regexp"
 	"sort"
 	"strconv"
@@ -19,6 +20,7 @@ import (
 
 	"github.com/nginx/agent/sdk/v2/proto"
 	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
 	"github.com/nginx/agent/v2/src/core/metrics"
 	"github.com/nginx/agent/v2/src/core/tailer"
 
@@ -28,6 +30,11 @@ import (
 const (
 	spaceDelim = " "
 	pattern    = `[A-Z]+\s.+\s[A-Z]+/.+`
+
+	uriPrefixDimension    = "uri_prefix"
+	otherDimensionValue   = "other"
+	emptyDimensionValue   = "-"
+	dimensionKeySeparator = "\x00"
 )
 
 var logVarMap = map[string]string{
@@ -59,6 +66,12 @@ var logVarMap = map[string]string{
 // Pattern to match all the variables that are mentioned in the access log format
 var logVarRegex = regexp.MustCompile(`\$([a-zA-Z]+[_[a-zA-Z]+]*)`)
 
+// Pattern to match the "key": "$variable" pairs of a JSON access log format
+var jsonLogVarRegex = regexp.MustCompile(`"([^"]+)"\s*:\s*"?\$\{?([a-zA-Z0-9_]+)\}?"?`)
+
+// Escape parameters of the log_format directive, crossplane joins them with the format string
+var logFormatEscapePrefixes = []string{"escape=json", "escape=default", "escape=none"}
+
 // This metrics source is used to tail the NGINX access logs to retrieve metrics.
 
 type NginxAccessLog struct {
@@ -70,8 +83,10 @@ type NginxAccessLog struct {
 	binary             core.NginxBinary
 	nginxType          string
 	collectionInterval time.Duration
+	accessLogMetrics   config.AccessLogMetrics
 	buf                []*metrics.StatsEntityWrapper
 	logger             *MetricSourceLogger
+	upstreams          *upstreamPeerNames
 }
 
 func NewNginxAccessLog(
@@ -80,6 +95,8 @@ func NewNginxAccessLog(
 	binary core.NginxBinary,
 	nginxType string,
 	collectionInterval time.Duration,
+	accessLogMetrics config.AccessLogMetrics,
+	upstreams map[string][]string,
 ) *NginxAccessLog {
 	log.Trace("Creating NginxAccessLog")
 
@@ -92,8 +109,10 @@ func NewNginxAccessLog(
 		binary,
 		nginxType,
 		collectionInterval,
+		accessLogMetrics,
 		[]*metrics.StatsEntityWrapper{},
 		NewMetricSourceLogger(),
+		newUpstreamPeerNames(upstreams),
 	}
 
 	logs := binary.GetAccessLogs()
@@ -114,13 +133,17 @@ func (c *NginxAccessLog) Collect(ctx context.Context, wg *sync.WaitGroup, m chan
 }
 
 func (c *NginxAccessLog) Update(dimensions *metrics.CommonDim, collectorConf *metrics.NginxCollectorConfig) {
+	// resolving the upstream servers can block on DNS, so it is done before taking the lock
+	c.upstreams.update(collectorConf.Upstreams)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.baseDimensions = dimensions
 
-	if c.collectionInterval != collectorConf.CollectionInterval {
+	if c.collectionInterval != collectorConf.CollectionInterval || !reflect.DeepEqual(c.accessLogMetrics, collectorConf.AccessLogMetrics) {
 		c.collectionInterval = collectorConf.CollectionInterval
+		c.accessLogMetrics = collectorConf.AccessLogMetrics
 		// remove old access logs
 		// add new access logs
 		c.recreateLogs()
@@ -197,6 +220,7 @@ func (c *NginxAccessLog) collectLogStats(ctx context.Context, m chan<- *metrics.
 }
 
 func (c *NginxAccessLog) logStats(ctx context.Context, logFile, logFormat string) {
+	jsonKeys, isJSON := jsonLogFormatKeys(logFormat)
 	logPattern := logFormat
 
 	for key, value := range logVarMap {
@@ -208,12 +232,17 @@ func (c *NginxAccessLog) logStats(ctx context.Context, logFile, logFormat string
 	log.Debugf("Collecting from: %s using format: %s", logFile, logFormat)
 	log.Debugf("Pattern used for tailing logs: %s", logPattern)
 
-	httpCounters, upstreamCounters, upstreamCacheCounters := map[string]float64{}, map[string]float64{}, map[string]float64{}
-	gzipRatios, requestLengths, requestTimes, upstreamResponseLength, upstreamResponseTimes, upstreamConnectTimes, upstreamHeaderTimes := []float64{}, []float64{}, []float64{}, []float64{}, []float64{}, []float64{}, []float64{}
-
 	mu := sync.Mutex{}
 	data := make(chan map[string]string, 1024)
-	if logPattern == "ltsv" {
+	if isJSON {
+		log.Debugf("Tailing %s as JSON, keys: %v", logFile, jsonKeys)
+		t, err := tailer.NewJSONTailer(logFile, jsonKeys)
+		if err != nil {
+			log.Errorf("unable to tail %q: %v", logFile, err)
+			return
+		}
+		go t.Tail(ctx, data)
+	} else if logPattern == "ltsv" {
 		t, err := tailer.NewLTSVTailer(logFile)
 		if err != nil {
 			log.Errorf("unable to tail %q: %v", logFile, err)
@@ -229,13 +258,17 @@ func (c *NginxAccessLog) logStats(ctx context.Context, logFile, logFormat string
 		go t.Tail(ctx, data)
 	}
 
+	stats := newAccessLogStats(nil)
+	dimensionStats := make(map[string]*accessLogStats)
+	peerStats := make(map[string]*upstreamPeerStats)
+	limiter := newDimensionLimiter(c.accessLogMetrics.MaxDimensionValues)
+
 	tick := time.NewTicker(c.collectionInterval)
 	defer tick.Stop()
 	for {
 		select {
 		case d := <-data:
 			access, err := tailer.NewNginxAccessItem(d)
-			upstreamRequest := false
 			if err != nil {
 				c.logger.Log(fmt.Sprintf("Error decoding access log entry, %v", err))
 				continue
@@ -243,156 +276,319 @@ func (c *NginxAccessLog) logStats(ctx context.Context, logFile, logFormat string
 
 			mu.Lock()
 
-			httpCounters = c.parseAccessLogFloatCounters("request.body_bytes_sent", access.BodyBytesSent, httpCounters)
+			c.addAccessLogItem(stats, access)
 
-			httpCounters = c.parseAccessLogFloatCounters("request.bytes_sent", access.BytesSent, httpCounters)
+			if dimensions := c.accessLogDimensions(d, access, limiter); len(dimensions) > 0 {
+				key := dimensionsKey(dimensions)
+				if _, ok := dimensionStats[key]; !ok {
+					dimensionStats[key] = newAccessLogStats(dimensions)
+				}
+				c.addAccessLogItem(dimensionStats[key], access)
+			}
 
-			gzipRatios = c.parseAccessLogFloatTimes("gzip_ratio", access.GzipRatio, gzipRatios)
+			if c.nginxType == OSSNginxType {
+				c.addUpstreamPeers(peerStats, access, limiter)
+			}
 
-			requestLengths = c.parseAccessLogFloatTimes("request_length", access.RequestLength, requestLengths)
+			mu.Unlock()
 
-			requestTimes = c.parseAccessLogFloatTimes("request_time", access.RequestTime, requestTimes)
+		case <-tick.C:
+			c.baseDimensions.NginxType = c.nginxType
+			c.baseDimensions.PublishedAPI = logFile
 
-			upstreamConnectTimes = c.parseAccessLogUpstream("upstream_connect_time", access.UpstreamConnectTime, upstreamConnectTimes)
+			mu.Lock()
 
-			upstreamHeaderTimes = c.parseAccessLogUpstream("upstream_header_time", access.UpstreamHeaderTime, upstreamHeaderTimes)
+			simpleMetrics := c.accessLogSimpleMetrics(stats)
+			log.Tracef("Access log metrics collected: %v", simpleMetrics)
+			c.buf = append(c.buf, metrics.NewStatsEntityWrapper(c.baseDimensions.ToDimensions(), simpleMetrics, proto.MetricsReport_INSTANCE))
 
-			upstreamResponseLength = c.parseAccessLogUpstream("upstream_response_length", access.UpstreamResponseLength, upstreamResponseLength)
+			keys := make([]string, 0, len(dimensionStats))
+			for key := range dimensionStats {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				dims := append(c.baseDimensions.ToDimensions(), dimensionStats[key].dimensions...)
+				c.buf = append(c.buf, metrics.NewStatsEntityWrapper(dims, c.accessLogSimpleMetrics(dimensionStats[key]), proto.MetricsReport_INSTANCE))
+			}
 
-			upstreamResponseTimes = c.parseAccessLogUpstream("upstream_response_time", access.UpstreamResponseTime, upstreamResponseTimes)
+			c.buf = append(c.buf, c.upstreamPeerMetrics(peerStats)...)
 
-			if access.Request != "" {
-				method, _, protocol := getParsedRequest(access.Request)
-				n := fmt.Sprintf("method.%s", strings.ToLower(method))
-				if isOtherMethod(n) {
-					n = "method.others"
-				}
+			// reset the counters
+			stats = newAccessLogStats(nil)
+			dimensionStats = make(map[string]*accessLogStats)
+			peerStats = make(map[string]*upstreamPeerStats)
+			limiter.reset()
 
-				existingValue, ok := httpCounters[n]
-				if ok {
-					httpCounters[n] = existingValue + 1
-				} else {
-					httpCounters[n] = 1
-				}
+			mu.Unlock()
 
-				if access.ServerProtocol == "" {
-					calculateServerProtocol(protocol, httpCounters)
-				}
+		case <-ctx.Done():
+			err := ctx.Err()
+			if err != nil {
+				log.Tracef("NginxAccessLog: error in done context logStats %v", err)
 			}
+			log.Info("NginxAccessLog: logStats are done")
+			return
+		}
+	}
+}
 
-			if access.ServerProtocol != "" {
-				calculateServerProtocol(access.ServerProtocol, httpCounters)
-			}
+// accessLogStats holds the samples of the access log entries collected during one collection
+// interval, either for a whole access log or for one combination of dimension values
+type accessLogStats struct {
+	dimensions []*proto.Dimension
 
-			if access.UpstreamStatus != "" && access.UpstreamStatus != "-" {
-				upstreamRequest = true
-				statusValues := strings.Split(access.UpstreamStatus, ",")
-				for _, value := range statusValues {
-					if v, err := strconv.Atoi(value); err == nil {
-						n := fmt.Sprintf("upstream.status.%dxx", v/100)
-						existingValue, ok := upstreamCounters[n]
-						if ok {
-							upstreamCounters[n] = existingValue + 1
-						} else {
-							upstreamCounters[n] = 1
-						}
-					} else {
-						log.Debugf("Error getting upstream status value from access logs, %v", err)
-					}
-				}
+	httpCounters, upstreamCounters, upstreamCacheCounters map[string]float64
 
-			}
+	gzipRatios, requestLengths, requestTimes, upstreamResponseLength, upstreamResponseTimes, upstreamConnectTimes, upstreamHeaderTimes []float64
+}
 
-			if access.UpstreamCacheStatus != "" && access.UpstreamCacheStatus != "-" {
-				upstreamRequest = true
-				calculateUpstreamCacheStatus(access.UpstreamCacheStatus, upstreamCacheCounters)
-			}
+func newAccessLogStats(dimensions []*proto.Dimension) *accessLogStats {
+	return &accessLogStats{
+		dimensions:            dimensions,
+		httpCounters:          map[string]float64{},
+		upstreamCounters:      map[string]float64{},
+		upstreamCacheCounters: map[string]float64{},
+	}
+}
 
-			// don't need the http status for NGINX Plus
-			if c.nginxType == OSSNginxType {
-				c.calculateHttpStatus(access.Status, httpCounters)
-			}
+func (c *NginxAccessLog) addAccessLogItem(stats *accessLogStats, access *tailer.NginxAccessItem) {
+	upstreamRequest := false
 
-			if access.UpstreamConnectTime != "" || access.UpstreamHeaderTime != "" || access.UpstreamResponseTime != "" {
-				upstreamTimes := []string{access.UpstreamConnectTime, access.UpstreamHeaderTime, access.UpstreamResponseTime}
-				upstreamRequest, upstreamCounters = calculateUpstreamNextCount(upstreamTimes, upstreamCounters)
-			}
+	stats.httpCounters = c.parseAccessLogFloatCounters("request.body_bytes_sent", access.BodyBytesSent, stats.httpCounters)
+
+	stats.httpCounters = c.parseAccessLogFloatCounters("request.bytes_sent", access.BytesSent, stats.httpCounters)
+
+	stats.gzipRatios = c.parseAccessLogFloatTimes("gzip_ratio", access.GzipRatio, stats.gzipRatios)
+
+	stats.requestLengths = c.parseAccessLogFloatTimes("request_length", access.RequestLength, stats.requestLengths)
+
+	stats.requestTimes = c.parseAccessLogFloatTimes("request_time", access.RequestTime, stats.requestTimes)
+
+	stats.upstreamConnectTimes = c.parseAccessLogUpstream("upstream_connect_time", access.UpstreamConnectTime, stats.upstreamConnectTimes)
+
+	stats.upstreamHeaderTimes = c.parseAccessLogUpstream("upstream_header_time", access.UpstreamHeaderTime, stats.upstreamHeaderTimes)
 
-			if upstreamRequest {
-				existingValue, ok := upstreamCounters["upstream.request.count"]
+	stats.upstreamResponseLength = c.parseAccessLogUpstream("upstream_response_length", access.UpstreamResponseLength, stats.upstreamResponseLength)
+
+	stats.upstreamResponseTimes = c.parseAccessLogUpstream("upstream_response_time", access.UpstreamResponseTime, stats.upstreamResponseTimes)
+
+	if access.Request != "" {
+		method, _, protocol := getParsedRequest(access.Request)
+		n := fmt.Sprintf("method.%s", strings.ToLower(method))
+		if isOtherMethod(n) {
+			n = "method.others"
+		}
+
+		existingValue, ok := stats.httpCounters[n]
+		if ok {
+			stats.httpCounters[n] = existingValue + 1
+		} else {
+			stats.httpCounters[n] = 1
+		}
+
+		if access.ServerProtocol == "" {
+			calculateServerProtocol(protocol, stats.httpCounters)
+		}
+	}
+
+	if access.ServerProtocol != "" {
+		calculateServerProtocol(access.ServerProtocol, stats.httpCounters)
+	}
+
+	if access.UpstreamStatus != "" && access.UpstreamStatus != "-" {
+		upstreamRequest = true
+		statusValues := strings.Split(access.UpstreamStatus, ",")
+		for _, value := range statusValues {
+			if v, err := strconv.Atoi(value); err == nil {
+				n := fmt.Sprintf("upstream.status.%dxx", v/100)
+				existingValue, ok := stats.upstreamCounters[n]
 				if ok {
-					upstreamCounters["upstream.request.count"] = existingValue + 1
+					stats.upstreamCounters[n] = existingValue + 1
 				} else {
-					upstreamCounters["upstream.request.count"] = 1
+					stats.upstreamCounters[n] = 1
 				}
+			} else {
+				log.Debugf("Error getting upstream status value from access logs, %v", err)
 			}
+		}
 
-			mu.Unlock()
+	}
 
-		case <-tick.C:
-			c.baseDimensions.NginxType = c.nginxType
-			c.baseDimensions.PublishedAPI = logFile
+	if access.UpstreamCacheStatus != "" && access.UpstreamCacheStatus != "-" {
+		upstreamRequest = true
+		calculateUpstreamCacheStatus(access.UpstreamCacheStatus, stats.upstreamCacheCounters)
+	}
 
-			mu.Lock()
+	// don't need the http status for NGINX Plus
+	if c.nginxType == OSSNginxType {
+		c.calculateHttpStatus(access.Status, stats.httpCounters)
+	}
 
-			if len(requestLengths) > 0 {
-				httpCounters["request.length"] = getAverageMetricValue(requestLengths)
-			}
+	if access.UpstreamConnectTime != "" || access.UpstreamHeaderTime != "" || access.UpstreamResponseTime != "" {
+		upstreamTimes := []string{access.UpstreamConnectTime, access.UpstreamHeaderTime, access.UpstreamResponseTime}
+		upstreamRequest, stats.upstreamCounters = calculateUpstreamNextCount(upstreamTimes, stats.upstreamCounters)
+	}
 
-			if len(gzipRatios) > 0 {
-				httpCounters["gzip.ratio"] = getAverageMetricValue(gzipRatios)
-			}
+	if upstreamRequest {
+		existingValue, ok := stats.upstreamCounters["upstream.request.count"]
+		if ok {
+			stats.upstreamCounters["upstream.request.count"] = existingValue + 1
+		} else {
+			stats.upstreamCounters["upstream.request.count"] = 1
+		}
+	}
+}
 
-			if len(requestTimes) > 0 {
-				calculateTimeMetricsMap("request.time", requestTimes, httpCounters)
-			}
+func (c *NginxAccessLog) calculateHistogram(metricName string, times []float64, counter map[string]float64) {
+	if !c.accessLogMetrics.Histograms {
+		return
+	}
 
-			if len(upstreamConnectTimes) > 0 {
-				calculateTimeMetricsMap("upstream.connect.time", upstreamConnectTimes, upstreamCounters)
-			}
+	histogram := metrics.NewHistogram(int32(c.accessLogMetrics.HistogramSchema))
+	for _, t := range times {
+		histogram.Observe(t)
+	}
+	histogram.Samples(metricName, counter)
+}
 
-			if len(upstreamHeaderTimes) > 0 {
-				calculateTimeMetricsMap("upstream.header.time", upstreamHeaderTimes, upstreamCounters)
-			}
+func (c *NginxAccessLog) accessLogSimpleMetrics(stats *accessLogStats) []*proto.SimpleMetric {
+	if len(stats.requestLengths) > 0 {
+		stats.httpCounters["request.length"] = getAverageMetricValue(stats.requestLengths)
+	}
 
-			if len(upstreamResponseTimes) > 0 {
-				calculateTimeMetricsMap("upstream.response.time", upstreamResponseTimes, upstreamCounters)
-			}
+	if len(stats.gzipRatios) > 0 {
+		stats.httpCounters["gzip.ratio"] = getAverageMetricValue(stats.gzipRatios)
+	}
 
-			if len(upstreamResponseLength) > 0 {
-				upstreamCounters["upstream.response.length"] = getAverageMetricValue(upstreamResponseLength)
-			}
+	if len(stats.requestTimes) > 0 {
+		calculateTimeMetricsMap("request.time", stats.requestTimes, stats.httpCounters)
+		c.calculateHistogram("request.time", stats.requestTimes, stats.httpCounters)
+	}
 
-			c.group = "http"
-			simpleMetrics := c.convertSamplesToSimpleMetrics(httpCounters)
+	if len(stats.upstreamConnectTimes) > 0 {
+		calculateTimeMetricsMap("upstream.connect.time", stats.upstreamConnectTimes, stats.upstreamCounters)
+		c.calculateHistogram("upstream.connect.time", stats.upstreamConnectTimes, stats.upstreamCounters)
+	}
 
-			c.group = ""
-			simpleMetrics = append(simpleMetrics, c.convertSamplesToSimpleMetrics(upstreamCounters)...)
+	if len(stats.upstreamHeaderTimes) > 0 {
+		calculateTimeMetricsMap("upstream.header.time", stats.upstreamHeaderTimes, stats.upstreamCounters)
+		c.calculateHistogram("upstream.header.time", stats.upstreamHeaderTimes, stats.upstreamCounters)
+	}
 
-			c.group = ""
-			simpleMetrics = append(simpleMetrics, c.convertSamplesToSimpleMetrics(upstreamCacheCounters)...)
+	if len(stats.upstreamResponseTimes) > 0 {
+		calculateTimeMetricsMap("upstream.response.time", stats.upstreamResponseTimes, stats.upstreamCounters)
+		c.calculateHistogram("upstream.response.time", stats.upstreamResponseTimes, stats.upstreamCounters)
+	}
 
-			log.Tracef("Access log metrics collected: %v", simpleMetrics)
+	if len(stats.upstreamResponseLength) > 0 {
+		stats.upstreamCounters["upstream.response.length"] = getAverageMetricValue(stats.upstreamResponseLength)
+	}
 
-			// reset the counters
-			httpCounters, upstreamCounters, upstreamCacheCounters = map[string]float64{}, map[string]float64{}, map[string]float64{}
-			gzipRatios, requestLengths, requestTimes, upstreamResponseLength, upstreamResponseTimes, upstreamConnectTimes, upstreamHeaderTimes = []float64{}, []float64{}, []float64{}, []float64{}, []float64{}, []float64{}, []float64{}
+	c.group = "http"
+	simpleMetrics := c.convertSamplesToSimpleMetrics(stats.httpCounters)
 
-			c.buf = append(c.buf, metrics.NewStatsEntityWrapper(c.baseDimensions.ToDimensions(), simpleMetrics, proto.MetricsReport_INSTANCE))
+	c.group = ""
+	simpleMetrics = append(simpleMetrics, c.convertSamplesToSimpleMetrics(stats.upstreamCounters)...)
 
-			mu.Unlock()
+	c.group = ""
+	simpleMetrics = append(simpleMetrics, c.convertSamplesToSimpleMetrics(stats.upstreamCacheCounters)...)
 
-		case <-ctx.Done():
-			err := ctx.Err()
-			if err != nil {
-				log.Tracef("NginxAccessLog: error in done context logStats %v", err)
-			}
-			log.Info("NginxAccessLog: logStats are done")
-			return
+	return simpleMetrics
+}
+
+// accessLogDimensions returns the dimensions of an access log entry, the values of the
+// configured log format variables followed by the URI prefix bucket of the request
+func (c *NginxAccessLog) accessLogDimensions(values map[string]string, access *tailer.NginxAccessItem, limiter *dimensionLimiter) []*proto.Dimension {
+	dimensions := []*proto.Dimension{}
+
+	for _, name := range c.accessLogMetrics.Dimensions {
+		name = strings.TrimPrefix(name, "$")
+		value, ok := values[name]
+		if !ok {
+			// the variable is not part of the log format
+			continue
+		}
+		if value == "" {
+			value = emptyDimensionValue
+		}
+		dimensions = append(dimensions, &proto.Dimension{Name: name, Value: limiter.limit(name, value)})
+	}
+
+	if len(c.accessLogMetrics.URIPrefixes) > 0 {
+		uri := values["request_uri"]
+		if uri == "" {
+			uri = values["uri"]
+		}
+		if uri == "" {
+			_, uri, _ = getParsedRequest(access.Request)
 		}
+		dimensions = append(dimensions, &proto.Dimension{Name: uriPrefixDimension, Value: uriPrefixBucket(uri, c.accessLogMetrics.URIPrefixes)})
+	}
+
+	return dimensions
+}
+
+// uriPrefixBucket returns the longest prefix that matches the URI or "other" if none matches
+func uriPrefixBucket(uri string, prefixes []string) string {
+	bucket := ""
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(uri, prefix) && len(prefix) > len(bucket) {
+			bucket = prefix
+		}
+	}
+	if bucket == "" {
+		return otherDimensionValue
+	}
+	return bucket
+}
+
+func dimensionsKey(dimensions []*proto.Dimension) string {
+	parts := make([]string, 0, len(dimensions)*2)
+	for _, dimension := range dimensions {
+		parts = append(parts, dimension.Name, dimension.Value)
+	}
+	return strings.Join(parts, dimensionKeySeparator)
+}
+
+// dimensionLimiter bounds the number of distinct values of each dimension, values seen after
+// the limit is reached are replaced with "other"
+type dimensionLimiter struct {
+	maxValues int
+	values    map[string]map[string]struct{}
+}
+
+func newDimensionLimiter(maxValues int) *dimensionLimiter {
+	return &dimensionLimiter{
+		maxValues: maxValues,
+		values:    make(map[string]map[string]struct{}),
 	}
 }
 
+func (l *dimensionLimiter) limit(name, value string) string {
+	if l.maxValues <= 0 {
+		return value
+	}
+
+	seen, ok := l.values[name]
+	if !ok {
+		seen = make(map[string]struct{})
+		l.values[name] = seen
+	}
+	if _, ok := seen[value]; ok {
+		return value
+	}
+	if len(seen) >= l.maxValues {
+		return otherDimensionValue
+	}
+	seen[value] = struct{}{}
+	return value
+}
+
+func (l *dimensionLimiter) reset() {
+	l.values = make(map[string]map[string]struct{})
+}
+
 func calculateUpstreamNextCount(metricValues []string, upstreamCounters map[string]float64) (bool, map[string]float64) {
 	upstreamRequest := false
 	for _, upstreamTimes := range metricValues {
@@ -625,3 +821,21 @@ func replaceCustomLogVars(logPattern string) string {
 	}
 	return logPattern
 }
+
+// jsonLogFormatKeys reports whether the log format writes JSON objects, e.g. formats defined
+// with escape=json, and maps the JSON keys of the format to the NGINX variables they hold
+func jsonLogFormatKeys(logFormat string) (map[string]string, bool) {
+	format := strings.TrimSpace(logFormat)
+	for _, prefix := range logFormatEscapePrefixes {
+		format = strings.TrimSpace(strings.TrimPrefix(format, prefix))
+	}
+	if !strings.HasPrefix(format, "{") {
+		return nil, false
+	}
+
+	keys := make(map[string]string)
+	for _, match := range jsonLogVarRegex.FindAllStringSubmatch(format, -1) {
+		keys[match[1]] = match[2]
+	}
+	return keys, true
+}
@@ -31,7 +31,7 @@ func TestAccessLogUpdate(t *testing.T) {
 
 	collectionDuration := time.Millisecond * 300
 	newCollectionDuration := time.Millisecond * 500
-	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration)
+	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.AccessLogMetrics{}, nil)
 
 	assert.Equal(t, "", nginxAccessLog.baseDimensions.InstanceTags)
 	assert.Equal(t, collectionDuration, nginxAccessLog.collectionInterval)
@@ -58,7 +58,7 @@ func TestAccessLogStop(t *testing.T) {
 	binary.On("GetAccessLogs").Return(map[string]string{"/tmp/access.log": ""}).Once()
 
 	collectionDuration := time.Millisecond * 300
-	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration)
+	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.AccessLogMetrics{}, nil)
 
 	_, ok := nginxAccessLog.logs["/tmp/access.log"]
 	assert.True(t, ok)
@@ -174,7 +174,7 @@ func TestParseAccessLogFloatTimes(t *testing.T) {
 
 	binary := core.NewNginxBinary(tutils.NewMockEnvironment(), &config.Config{})
 	collectionDuration := time.Millisecond * 300
-	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration)
+	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.AccessLogMetrics{}, nil)
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -238,7 +238,7 @@ func TestParseAccessLogUpstream(t *testing.T) {
 
 	binary := core.NewNginxBinary(tutils.NewMockEnvironment(), &config.Config{})
 	collectionDuration := time.Millisecond * 300
-	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration)
+	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.AccessLogMetrics{}, nil)
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -304,7 +304,7 @@ func TestParseAccessLogFloatCounters(t *testing.T) {
 
 	binary := core.NewNginxBinary(tutils.NewMockEnvironment(), &config.Config{})
 	collectionDuration := time.Millisecond * 300
-	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration)
+	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.AccessLogMetrics{}, nil)
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -593,7 +593,7 @@ func TestCalculateHTTPStatus(t *testing.T) {
 
 	binary := core.NewNginxBinary(tutils.NewMockEnvironment(), &config.Config{})
 	collectionDuration := time.Millisecond * 300
-	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration)
+	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.AccessLogMetrics{}, nil)
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -1076,6 +1076,71 @@ func TestAccessLogStats(t *testing.T) {
 				},
 			},
 		},
+		{
+			"json_access_log_test",
+			`escape=json{"addr":"$remote_addr","request":"$request","status":$status,"bytes":$body_bytes_sent,"upstream":"$upstream_status","agent":"$http_user_agent"}`,
+			[]string{
+				`{"addr":"127.0.0.1","request":"GET /nginx_status HTTP/1.1","status":200,"bytes":98,"upstream":"200","agent":"Go-http-client/1.1"}` + "\n",
+				`{"addr":"127.0.0.1","request":"POST /login HTTP/2","status":502,"bytes":98,"upstream":"502","agent":"\"quoted\""}` + "\n",
+				`{"addr":"127.0.0.1","request":"GET / HTTP/1.1","status":404,"bytes":0,"upstream":"","agent":""}` + "\n",
+			},
+			&proto.StatsEntity{
+				Simplemetrics: []*proto.SimpleMetric{
+					{
+						Name:  "nginx.http.request.body_bytes_sent",
+						Value: 196,
+					},
+					{
+						Name:  "nginx.http.method.get",
+						Value: 2,
+					},
+					{
+						Name:  "nginx.http.method.post",
+						Value: 1,
+					},
+					{
+						Name:  "nginx.http.status.2xx",
+						Value: 1,
+					},
+					{
+						Name:  "nginx.http.status.404",
+						Value: 1,
+					},
+					{
+						Name:  "nginx.http.status.502",
+						Value: 1,
+					},
+					{
+						Name:  "nginx.http.status.4xx",
+						Value: 1,
+					},
+					{
+						Name:  "nginx.http.status.5xx",
+						Value: 1,
+					},
+					{
+						Name:  "nginx.http.v1_1",
+						Value: 2,
+					},
+					{
+						Name:  "nginx.http.v2",
+						Value: 1,
+					},
+					{
+						Name:  "nginx.upstream.status.2xx",
+						Value: 1,
+					},
+					{
+						Name:  "nginx.upstream.status.5xx",
+						Value: 1,
+					},
+					{
+						Name:  "nginx.upstream.request.count",
+						Value: 2,
+					},
+				},
+			},
+		},
 	}
 
 	binary := core.NewNginxBinary(tutils.NewMockEnvironment(), &config.Config{})
@@ -1086,7 +1151,7 @@ func TestAccessLogStats(t *testing.T) {
 		t.Run(test.name, func(tt *testing.T) {
 			accessLogFile, _ := os.CreateTemp(os.TempDir(), "access.log")
 
-			nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration)
+			nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.AccessLogMetrics{}, nil)
 			go nginxAccessLog.logStats(context.TODO(), accessLogFile.Name(), test.logFormat)
 
 			time.Sleep(sleepDuration)
@@ -1113,3 +1178,186 @@ func TestAccessLogStats(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONLogFormatKeys(t *testing.T) {
+	tests := []struct {
+		name         string
+		logFormat    string
+		expectedKeys map[string]string
+		expectedJSON bool
+	}{
+		{
+			"escape_json",
+			`escape=json{"remote_addr":"$remote_addr", "code": $status, "rt": "${request_time}"}`,
+			map[string]string{"remote_addr": "remote_addr", "code": "status", "rt": "request_time"},
+			true,
+		},
+		{
+			"no_escape",
+			` { "request": "$request" }`,
+			map[string]string{"request": "request"},
+			true,
+		},
+		{
+			"combined",
+			`$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`,
+			nil,
+			false,
+		},
+		{
+			"ltsv",
+			"ltsv",
+			nil,
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(tt *testing.T) {
+			keys, isJSON := jsonLogFormatKeys(test.logFormat)
+			assert.Equal(tt, test.expectedJSON, isJSON)
+			assert.Equal(tt, test.expectedKeys, keys)
+		})
+	}
+}
+
+func TestAccessLogStatsDimensions(t *testing.T) {
+	logFormat := `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$host"`
+	logLines := []string{
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"GET /api/v2/users HTTP/1.1\" 200 10 \"a.example.com\"\n",
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"GET /api/v1/users HTTP/1.1\" 404 20 \"a.example.com\"\n",
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"GET /static/app.js HTTP/1.1\" 200 30 \"b.example.com\"\n",
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"POST /login HTTP/1.1\" 500 40 \"c.example.com\"\n",
+	}
+
+	binary := core.NewNginxBinary(tutils.NewMockEnvironment(), &config.Config{})
+	collectionDuration := time.Millisecond * 300
+	accessLogFile, _ := os.CreateTemp(os.TempDir(), "access.log")
+
+	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.AccessLogMetrics{
+		Dimensions:         []string{"$host", "server_name"},
+		URIPrefixes:        []string{"/api", "/api/v2", "/static"},
+		MaxDimensionValues: 2,
+	}, nil)
+	go nginxAccessLog.logStats(context.TODO(), accessLogFile.Name(), logFormat)
+
+	time.Sleep(time.Millisecond * 100)
+
+	for _, logLine := range logLines {
+		_, err := accessLogFile.WriteString(logLine)
+		require.NoError(t, err, "Error writing data to access log")
+	}
+
+	time.Sleep(collectionDuration)
+
+	accessLogFile.Close()
+	os.Remove(accessLogFile.Name())
+
+	require.Len(t, nginxAccessLog.buf, 5)
+
+	// the first entity holds the metrics of the whole access log
+	assert.Len(t, nginxAccessLog.buf[0].Data.GetDimensions(), len((&metrics.CommonDim{}).ToDimensions()))
+	assert.Contains(t, nginxAccessLog.buf[0].Data.GetSimplemetrics(), &proto.SimpleMetric{Name: "nginx.http.request.body_bytes_sent", Value: 100})
+
+	expected := []struct {
+		host      string
+		uriPrefix string
+		bytesSent float64
+	}{
+		{"a.example.com", "/api", 20},
+		{"a.example.com", "/api/v2", 10},
+		{"b.example.com", "/static", 30},
+		{"other", "other", 40},
+	}
+
+	for i, e := range expected {
+		entity := nginxAccessLog.buf[i+1].Data
+		dimensions := entity.GetDimensions()
+		assert.Equal(t, &proto.Dimension{Name: "host", Value: e.host}, dimensions[len(dimensions)-2])
+		assert.Equal(t, &proto.Dimension{Name: "uri_prefix", Value: e.uriPrefix}, dimensions[len(dimensions)-1])
+		assert.Contains(t, entity.GetSimplemetrics(), &proto.SimpleMetric{Name: "nginx.http.request.body_bytes_sent", Value: e.bytesSent})
+	}
+}
+
+func TestUriPrefixBucket(t *testing.T) {
+	prefixes := []string{"/api", "/api/v2", "/static"}
+
+	assert.Equal(t, "/api", uriPrefixBucket("/api/v1/users", prefixes))
+	assert.Equal(t, "/api/v2", uriPrefixBucket("/api/v2/users", prefixes))
+	assert.Equal(t, "/static", uriPrefixBucket("/static/app.js", prefixes))
+	assert.Equal(t, "other", uriPrefixBucket("/login", prefixes))
+	assert.Equal(t, "other", uriPrefixBucket("", prefixes))
+}
+
+func TestDimensionLimiter(t *testing.T) {
+	limiter := newDimensionLimiter(2)
+
+	assert.Equal(t, "a", limiter.limit("host", "a"))
+	assert.Equal(t, "b", limiter.limit("host", "b"))
+	assert.Equal(t, "other", limiter.limit("host", "c"))
+	assert.Equal(t, "a", limiter.limit("host", "a"))
+	assert.Equal(t, "c", limiter.limit("server_name", "c"))
+
+	limiter.reset()
+	assert.Equal(t, "c", limiter.limit("host", "c"))
+
+	unlimited := newDimensionLimiter(0)
+	for _, value := range []string{"a", "b", "c"} {
+		assert.Equal(t, value, unlimited.limit("host", value))
+	}
+}
+
+func TestAccessLogStatsHistograms(t *testing.T) {
+	logFormat := `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$request_time"`
+	logLines := []string{
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"GET /a HTTP/1.1\" 200 10 \"0.000\"\n",
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"GET /b HTTP/1.1\" 200 10 \"0.010\"\n",
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"GET /c HTTP/1.1\" 200 10 \"0.100\"\n",
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"GET /d HTTP/1.1\" 200 10 \"1.000\"\n",
+	}
+
+	binary := core.NewNginxBinary(tutils.NewMockEnvironment(), &config.Config{})
+	collectionDuration := time.Millisecond * 300
+	accessLogFile, _ := os.CreateTemp(os.TempDir(), "access.log")
+
+	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.AccessLogMetrics{
+		Histograms:      true,
+		HistogramSchema: 3,
+	}, nil)
+	go nginxAccessLog.logStats(context.TODO(), accessLogFile.Name(), logFormat)
+
+	time.Sleep(time.Millisecond * 100)
+
+	for _, logLine := range logLines {
+		_, err := accessLogFile.WriteString(logLine)
+		require.NoError(t, err, "Error writing data to access log")
+	}
+
+	time.Sleep(collectionDuration)
+
+	accessLogFile.Close()
+	os.Remove(accessLogFile.Name())
+
+	require.NotEmpty(t, nginxAccessLog.buf)
+
+	histograms, simpleMetrics := metrics.HistogramsFromSimpleMetrics(nginxAccessLog.buf[0].Data.GetSimplemetrics())
+	require.Contains(t, histograms, "nginx.http.request.time")
+
+	histogram := histograms["nginx.http.request.time"]
+	assert.Equal(t, int32(3), histogram.Schema)
+	assert.Equal(t, uint64(4), histogram.Count)
+	assert.Equal(t, uint64(1), histogram.ZeroCount)
+	assert.InDelta(t, 1.11, histogram.Sum, 0.0001)
+	assert.Len(t, histogram.Buckets, 3)
+
+	percentiles := map[string]float64{}
+	for _, metric := range simpleMetrics {
+		percentiles[metric.Name] = metric.Value
+	}
+	assert.InEpsilon(t, 0.01, percentiles["nginx.http.request.time.pctl50"], 0.09)
+	assert.InEpsilon(t, 1, percentiles["nginx.http.request.time.pctl99"], 0.09)
+	assert.Contains(t, percentiles, "nginx.http.request.time.pctl90")
+	assert.Contains(t, percentiles, "nginx.http.request.time.pctl999")
+	// the existing request time metrics are still reported
+	assert.Contains(t, percentiles, "nginx.http.request.time.pctl95")
+}
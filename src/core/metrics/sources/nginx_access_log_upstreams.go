@@ -0,0 +1,220 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sources
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+
+	"github.com/nginx/agent/v2/src/core/metrics"
+	"github.com/nginx/agent/v2/src/core/tailer"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	upstreamDimension    = "upstream"
+	peerAddressDimension = "peer.address"
+
+	// the values of $upstream_addr, $upstream_status and $upstream_response_time are separated
+	// by commas for each server tried and by colons when the request is redirected to another
+	// upstream
+	upstreamValueSeparator    = ", "
+	upstreamRedirectSeparator = " : "
+
+	defaultUpstreamServerPort = "80"
+	unixSocketPrefix          = "unix:"
+)
+
+// upstreamPeerNames maps the peer addresses logged in $upstream_addr to the names of the
+// upstreams the peers belong to. Servers configured with a host name are matched by the
+// addresses the host name resolves to.
+type upstreamPeerNames struct {
+	upstreams map[string][]string
+	names     map[string]string
+	mu        sync.RWMutex
+}
+
+func newUpstreamPeerNames(upstreams map[string][]string) *upstreamPeerNames {
+	u := &upstreamPeerNames{}
+	u.update(upstreams)
+	return u
+}
+
+// update resolves the servers of the upstreams without holding the lock and then stores the
+// resulting peer names
+func (u *upstreamPeerNames) update(upstreams map[string][]string) {
+	u.mu.RLock()
+	unchanged := u.names != nil && reflect.DeepEqual(u.upstreams, upstreams)
+	u.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	names := make(map[string]string)
+	for name, servers := range upstreams {
+		for _, server := range servers {
+			for _, address := range upstreamServerAddresses(server) {
+				names[address] = name
+			}
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.upstreams = upstreams
+	u.names = names
+}
+
+// lookup returns the name of the upstream of a peer address. If no upstream servers are
+// available NGINX logs the name of the upstream instead of an address.
+func (u *upstreamPeerNames) lookup(address string) string {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	if name, ok := u.names[address]; ok {
+		return name
+	}
+	if _, ok := u.upstreams[address]; ok {
+		return address
+	}
+	return emptyDimensionValue
+}
+
+// upstreamServerAddresses returns the addresses a server of an upstream block is logged as
+func upstreamServerAddresses(server string) []string {
+	if strings.HasPrefix(server, unixSocketPrefix) {
+		return []string{server}
+	}
+
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		host, port = server, defaultUpstreamServerPort
+	}
+	if net.ParseIP(host) != nil {
+		return []string{net.JoinHostPort(host, port)}
+	}
+
+	addresses := []string{server}
+	ips, err := net.LookupHost(host)
+	if err != nil {
+		log.Debugf("Unable to resolve upstream server %s: %v", server, err)
+		return addresses
+	}
+	for _, ip := range ips {
+		addresses = append(addresses, net.JoinHostPort(ip, port))
+	}
+	return addresses
+}
+
+// upstreamPeerStats holds the samples of the requests sent to one upstream peer during one
+// collection interval
+type upstreamPeerStats struct {
+	dimensions    []*proto.Dimension
+	counters      map[string]float64
+	responseTimes []float64
+	successes     int
+}
+
+// addUpstreamPeers adds the samples of each upstream peer tried for a request. A peer fails
+// when it could not be reached, timed out or returned an invalid response, which NGINX
+// reports as status 502 or 504.
+func (c *NginxAccessLog) addUpstreamPeers(peerStats map[string]*upstreamPeerStats, access *tailer.NginxAccessItem, limiter *dimensionLimiter) {
+	addresses := splitUpstreamValues(access.UpstreamAddr)
+	statuses := splitUpstreamValues(access.UpstreamStatus)
+	times := splitUpstreamValues(access.UpstreamResponseTime)
+
+	for i, address := range addresses {
+		if address == "" || address == emptyDimensionValue {
+			continue
+		}
+
+		upstream := c.upstreams.lookup(address)
+		peer := limiter.limit(peerAddressDimension, address)
+		key := upstream + dimensionKeySeparator + peer
+
+		stats, ok := peerStats[key]
+		if !ok {
+			stats = &upstreamPeerStats{
+				dimensions: []*proto.Dimension{
+					{Name: upstreamDimension, Value: upstream},
+					{Name: peerAddressDimension, Value: peer},
+				},
+				counters: map[string]float64{
+					"upstream.peers.request.count": 0,
+					"upstream.peers.fails":         0,
+				},
+			}
+			peerStats[key] = stats
+		}
+
+		stats.counters["upstream.peers.request.count"]++
+
+		status := upstreamValue(statuses, i)
+		code, err := strconv.Atoi(status)
+		if err == nil && code >= 100 && code < 600 {
+			stats.counters[fmt.Sprintf("upstream.peers.status.%dxx", code/100)]++
+		}
+		if err != nil || code == 502 || code == 504 {
+			stats.counters["upstream.peers.fails"]++
+		} else {
+			stats.successes++
+		}
+
+		if responseTime, err := strconv.ParseFloat(upstreamValue(times, i), 64); err == nil {
+			stats.responseTimes = append(stats.responseTimes, responseTime)
+		}
+	}
+}
+
+// upstreamPeerMetrics reports the samples of each upstream peer. A peer is reported as up
+// when at least one request sent to it did not fail and as down when all of them failed.
+func (c *NginxAccessLog) upstreamPeerMetrics(peerStats map[string]*upstreamPeerStats) []*metrics.StatsEntityWrapper {
+	keys := make([]string, 0, len(peerStats))
+	for key := range peerStats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	statsEntities := make([]*metrics.StatsEntityWrapper, 0, len(keys))
+	for _, key := range keys {
+		stats := peerStats[key]
+
+		if len(stats.responseTimes) > 0 {
+			calculateTimeMetricsMap("upstream.peers.response.time", stats.responseTimes, stats.counters)
+		}
+		stats.counters["upstream.peers.state.up"] = boolToFloat64(stats.successes > 0)
+		stats.counters["upstream.peers.state.down"] = boolToFloat64(stats.successes == 0)
+
+		dims := append(c.baseDimensions.ToDimensions(), stats.dimensions...)
+		statsEntities = append(statsEntities, metrics.NewStatsEntityWrapper(dims, c.convertSamplesToSimpleMetrics(stats.counters), proto.MetricsReport_UPSTREAMS))
+	}
+
+	return statsEntities
+}
+
+func splitUpstreamValues(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(value, upstreamRedirectSeparator, upstreamValueSeparator), upstreamValueSeparator)
+}
+
+func upstreamValue(values []string, i int) string {
+	if i < len(values) {
+		return strings.TrimSpace(values[i])
+	}
+	return ""
+}
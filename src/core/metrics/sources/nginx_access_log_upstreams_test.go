@@ -0,0 +1,122 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sources
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+	tutils "github.com/nginx/agent/v2/test/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLogUpstreamPeers(t *testing.T) {
+	logFormat := `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$upstream_addr" "$upstream_status" "$upstream_response_time"`
+	logLines := []string{
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"GET /a HTTP/1.1\" 200 10 \"127.0.0.1:8080\" \"200\" \"0.010\"\n",
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"GET /b HTTP/1.1\" 200 10 \"127.0.0.1:8081, 127.0.0.1:8080\" \"502, 200\" \"0.001, 0.030\"\n",
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"GET /c HTTP/1.1\" 404 10 \"127.0.0.1:8080 : 10.0.0.1:9000\" \"404 : 504\" \"0.020 : 1.000\"\n",
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"GET /d HTTP/1.1\" 502 10 \"backend\" \"502\" \"0.000\"\n",
+		"127.0.0.1 - - [19/May/2022:09:30:39 +0000] \"GET /e HTTP/1.1\" 200 10 \"-\" \"-\" \"-\"\n",
+	}
+
+	binary := core.NewNginxBinary(tutils.NewMockEnvironment(), &config.Config{})
+	collectionDuration := time.Millisecond * 300
+	accessLogFile, _ := os.CreateTemp(os.TempDir(), "access.log")
+
+	upstreams := map[string][]string{
+		"backend": {"127.0.0.1:8080", "127.0.0.1:8081"},
+	}
+	nginxAccessLog := NewNginxAccessLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.AccessLogMetrics{}, upstreams)
+	go nginxAccessLog.logStats(context.TODO(), accessLogFile.Name(), logFormat)
+
+	time.Sleep(time.Millisecond * 100)
+
+	for _, logLine := range logLines {
+		_, err := accessLogFile.WriteString(logLine)
+		require.NoError(t, err, "Error writing data to access log")
+	}
+
+	time.Sleep(collectionDuration)
+
+	accessLogFile.Close()
+	os.Remove(accessLogFile.Name())
+
+	peers := map[string]map[string]float64{}
+	for _, stats := range nginxAccessLog.buf {
+		if stats.Type != proto.MetricsReport_UPSTREAMS {
+			continue
+		}
+		dims := map[string]string{}
+		for _, dim := range stats.Data.GetDimensions() {
+			dims[dim.Name] = dim.Value
+		}
+		values := map[string]float64{}
+		for _, metric := range stats.Data.GetSimplemetrics() {
+			values[metric.Name] = metric.Value
+		}
+		peers[dims["upstream"]+"/"+dims["peer.address"]] = values
+	}
+	require.Len(t, peers, 4)
+
+	peer := peers["backend/127.0.0.1:8080"]
+	assert.Equal(t, float64(3), peer["nginx.upstream.peers.request.count"])
+	assert.Equal(t, float64(2), peer["nginx.upstream.peers.status.2xx"])
+	assert.Equal(t, float64(1), peer["nginx.upstream.peers.status.4xx"])
+	assert.Equal(t, float64(0), peer["nginx.upstream.peers.fails"])
+	assert.Equal(t, float64(1), peer["nginx.upstream.peers.state.up"])
+	assert.Equal(t, float64(0), peer["nginx.upstream.peers.state.down"])
+	assert.Equal(t, float64(3), peer["nginx.upstream.peers.response.time.count"])
+	assert.Equal(t, 0.03, peer["nginx.upstream.peers.response.time.max"])
+
+	peer = peers["backend/127.0.0.1:8081"]
+	assert.Equal(t, float64(1), peer["nginx.upstream.peers.request.count"])
+	assert.Equal(t, float64(1), peer["nginx.upstream.peers.status.5xx"])
+	assert.Equal(t, float64(1), peer["nginx.upstream.peers.fails"])
+	assert.Equal(t, float64(0), peer["nginx.upstream.peers.state.up"])
+	assert.Equal(t, float64(1), peer["nginx.upstream.peers.state.down"])
+
+	// peers that are not part of an upstream block, e.g. proxy_pass to an address
+	peer = peers["-/10.0.0.1:9000"]
+	assert.Equal(t, float64(1), peer["nginx.upstream.peers.fails"])
+
+	// the upstream name is logged when no servers of the upstream are available
+	peer = peers["backend/backend"]
+	assert.Equal(t, float64(1), peer["nginx.upstream.peers.fails"])
+}
+
+func TestUpstreamPeerNames(t *testing.T) {
+	names := newUpstreamPeerNames(map[string][]string{
+		"backend": {"127.0.0.1:8080", "10.0.0.1", "unix:/tmp/backend.sock", "localhost:9000"},
+	})
+
+	assert.Equal(t, "backend", names.lookup("127.0.0.1:8080"))
+	assert.Equal(t, "backend", names.lookup("10.0.0.1:80"))
+	assert.Equal(t, "backend", names.lookup("unix:/tmp/backend.sock"))
+	assert.Equal(t, "backend", names.lookup("localhost:9000"))
+	assert.Equal(t, "backend", names.lookup("backend"))
+	assert.Equal(t, "-", names.lookup("127.0.0.1:8081"))
+
+	names.update(map[string][]string{"api": {"127.0.0.1:8081"}})
+	assert.Equal(t, "api", names.lookup("127.0.0.1:8081"))
+	assert.Equal(t, "-", names.lookup("127.0.0.1:8080"))
+}
+
+func TestSplitUpstreamValues(t *testing.T) {
+	assert.Nil(t, splitUpstreamValues(""))
+	assert.Equal(t, []string{"127.0.0.1:8080"}, splitUpstreamValues("127.0.0.1:8080"))
+	assert.Equal(t, []string{"127.0.0.1:8080", "127.0.0.1:8081", "unix:/tmp/a.sock"}, splitUpstreamValues("127.0.0.1:8080, 127.0.0.1:8081 : unix:/tmp/a.sock"))
+}
@@ -9,13 +9,17 @@ package sources
 
 import (
 	"context"
+	"net/url"
+	"reflect"
 	re "regexp"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/nginx/agent/sdk/v2/proto"
 
 	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
 	"github.com/nginx/agent/v2/src/core/metrics"
 	"github.com/nginx/agent/v2/src/core/tailer"
 
@@ -27,6 +31,15 @@ const (
 	UpstreamResponseBufferedMetricName = "upstream.response.buffered"
 	UpstreamRequestFailedMetricName    = "upstream.request.failed"
 	UpstreamResponseFailedMetricName   = "upstream.response.failed"
+
+	// ErrorLogLevelMetricPrefix is followed by the level of the error log lines it counts,
+	// e.g. error_log.crit
+	ErrorLogLevelMetricPrefix = "error_log."
+
+	errorLogPeerDimension = "peer.address"
+	// maxErrorLogPeers bounds the number of upstream peers error log metrics are reported for
+	// per collection interval
+	maxErrorLogPeers = 100
 )
 
 var regularExpressionErrorMap = map[string][]*re.Regexp{
@@ -66,6 +79,8 @@ type NginxErrorLog struct {
 	nginxType          string
 	collectionInterval time.Duration
 	buf                []*metrics.StatsEntityWrapper
+	errorLogEvents     config.ErrorLogEvents
+	eventChannel       chan<- *metrics.NginxErrorLogEvent
 }
 
 func NewNginxErrorLog(
@@ -74,6 +89,8 @@ func NewNginxErrorLog(
 	binary core.NginxBinary,
 	nginxType string,
 	collectionInterval time.Duration,
+	errorLogEvents config.ErrorLogEvents,
+	eventChannel chan<- *metrics.NginxErrorLogEvent,
 ) *NginxErrorLog {
 	log.Trace("Creating NewNginxErrorLog")
 	nginxErrorLog := &NginxErrorLog{
@@ -86,6 +103,8 @@ func NewNginxErrorLog(
 		nginxType,
 		collectionInterval,
 		[]*metrics.StatsEntityWrapper{},
+		errorLogEvents,
+		eventChannel,
 	}
 
 	logs := binary.GetErrorLogs()
@@ -121,9 +140,11 @@ func (c *NginxErrorLog) Update(dimensions *metrics.CommonDim, collectorConf *met
 	defer c.mu.Unlock()
 
 	c.baseDimensions = dimensions
+	c.eventChannel = collectorConf.ErrorLogEventChannel
 
-	if c.collectionInterval != collectorConf.CollectionInterval {
+	if c.collectionInterval != collectorConf.CollectionInterval || !reflect.DeepEqual(c.errorLogEvents, collectorConf.ErrorLogEvents) {
 		c.collectionInterval = collectorConf.CollectionInterval
+		c.errorLogEvents = collectorConf.ErrorLogEvents
 		// remove old error logs
 		// add new error logs
 		c.recreateLogs()
@@ -194,6 +215,11 @@ func (c *NginxErrorLog) logStats(ctx context.Context, logFile string) {
 	log.Debugf("Collecting from error log: %s", logFile)
 
 	counters := map[string]float64{}
+	peerCounters := map[string]map[string]float64{}
+	peerLimiter := newDimensionLimiter(maxErrorLogPeers)
+	c.mu.Lock()
+	eventLevels := c.eventLevels()
+	c.mu.Unlock()
 	mu := sync.Mutex{}
 
 	t, err := tailer.NewTailer(logFile)
@@ -226,20 +252,58 @@ func (c *NginxErrorLog) logStats(ctx context.Context, logFile string) {
 				}
 			}
 
+			item, err := tailer.NewNginxErrorItem(d)
+			if err != nil {
+				log.Tracef("Unable to parse error log line: %v", err)
+				mu.Unlock()
+				continue
+			}
+
+			metricName := ErrorLogLevelMetricPrefix + item.Level
+			counters[metricName]++
+
+			if item.Upstream != "" {
+				peer := peerLimiter.limit(errorLogPeerDimension, errorLogPeerAddress(item.Upstream))
+				if _, ok := peerCounters[peer]; !ok {
+					peerCounters[peer] = map[string]float64{}
+				}
+				peerCounters[peer][metricName]++
+			}
+
+			if _, ok := eventLevels[item.Level]; ok {
+				c.forwardEvent(logFile, item)
+			}
+
 			mu.Unlock()
 
 		case <-tick.C:
-			c.baseDimensions.NginxType = c.nginxType
-			c.baseDimensions.PublishedAPI = logFile
-
 			mu.Lock()
 			simpleMetrics := c.convertSamplesToSimpleMetrics(counters)
 			log.Tracef("Error log metrics collected: %v", simpleMetrics)
 
+			// the buffer and dimensions are shared with Collect and Update
+			c.mu.Lock()
+			c.baseDimensions.NginxType = c.nginxType
+			c.baseDimensions.PublishedAPI = logFile
+
+			baseDimensions := c.baseDimensions.ToDimensions()
+			c.buf = append(c.buf, metrics.NewStatsEntityWrapper(baseDimensions, simpleMetrics, proto.MetricsReport_INSTANCE))
+
+			peers := make([]string, 0, len(peerCounters))
+			for peer := range peerCounters {
+				peers = append(peers, peer)
+			}
+			sort.Strings(peers)
+			for _, peer := range peers {
+				dimensions := append(append([]*proto.Dimension{}, baseDimensions...), &proto.Dimension{Name: errorLogPeerDimension, Value: peer})
+				c.buf = append(c.buf, metrics.NewStatsEntityWrapper(dimensions, c.convertSamplesToSimpleMetrics(peerCounters[peer]), proto.MetricsReport_INSTANCE))
+			}
+			c.mu.Unlock()
+
 			// reset the counters
 			counters = map[string]float64{}
-
-			c.buf = append(c.buf, metrics.NewStatsEntityWrapper(c.baseDimensions.ToDimensions(), simpleMetrics, proto.MetricsReport_INSTANCE))
+			peerCounters = map[string]map[string]float64{}
+			peerLimiter.reset()
 
 			mu.Unlock()
 
@@ -253,3 +317,44 @@ func (c *NginxErrorLog) logStats(ctx context.Context, logFile string) {
 		}
 	}
 }
+
+func (c *NginxErrorLog) eventLevels() map[string]struct{} {
+	levels := map[string]struct{}{}
+	if !c.errorLogEvents.Enable {
+		return levels
+	}
+	for _, level := range c.errorLogEvents.Levels {
+		levels[level] = struct{}{}
+	}
+	return levels
+}
+
+// forwardEvent passes an error log line on to be reported as an activity event, lines are
+// dropped instead of blocking the tailer if events are not consumed fast enough
+func (c *NginxErrorLog) forwardEvent(logFile string, item *tailer.NginxErrorItem) {
+	// the event channel and dimensions are replaced by Update
+	c.mu.Lock()
+	eventChannel := c.eventChannel
+	nginxId := c.baseDimensions.NginxId
+	c.mu.Unlock()
+
+	if eventChannel == nil {
+		return
+	}
+
+	select {
+	case eventChannel <- &metrics.NginxErrorLogEvent{NginxId: nginxId, LogFile: logFile, Item: item}:
+	default:
+		log.Debugf("Dropping error log event of %s, the event channel is full", logFile)
+	}
+}
+
+// errorLogPeerAddress returns the address of the upstream peer in the upstream field of an
+// error log line, e.g. 127.0.0.1:8080 for http://127.0.0.1:8080/api
+func errorLogPeerAddress(upstream string) string {
+	u, err := url.Parse(upstream)
+	if err != nil || u.Host == "" {
+		return upstream
+	}
+	return u.Host
+}
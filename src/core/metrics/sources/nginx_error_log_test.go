@@ -31,7 +31,7 @@ func TestNginxErrorLogUpdate(t *testing.T) {
 
 	collectionDuration := time.Millisecond * 300
 	newCollectionDuration := time.Millisecond * 500
-	nginxErrorLog := NewNginxErrorLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration)
+	nginxErrorLog := NewNginxErrorLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.ErrorLogEvents{}, nil)
 
 	assert.Equal(t, "", nginxErrorLog.baseDimensions.InstanceTags)
 	assert.Equal(t, collectionDuration, nginxErrorLog.collectionInterval)
@@ -58,7 +58,7 @@ func TestNginxErrorLogStop(t *testing.T) {
 	binary.On("GetErrorLogs").Return(map[string]string{"/tmp/error.log": ""}).Once()
 
 	collectionDuration := time.Millisecond * 300
-	nginxErrorLog := NewNginxErrorLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration)
+	nginxErrorLog := NewNginxErrorLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.ErrorLogEvents{}, nil)
 
 	_, ok := nginxErrorLog.logs["/tmp/error.log"]
 	assert.True(t, ok)
@@ -101,6 +101,18 @@ func TestErrorLogStats(t *testing.T) {
 						Name:  "nginx.http.request.buffered",
 						Value: 1,
 					},
+					{
+						Name:  "nginx.error_log.error",
+						Value: 3,
+					},
+					{
+						Name:  "nginx.error_log.warn",
+						Value: 1,
+					},
+					{
+						Name:  "nginx.error_log.info",
+						Value: 1,
+					},
 				},
 			},
 		},
@@ -114,13 +126,13 @@ func TestErrorLogStats(t *testing.T) {
 		t.Run(test.name, func(tt *testing.T) {
 			errorLogFile, _ := os.CreateTemp(os.TempDir(), "error.log")
 
-			nginxErrorLog := NewNginxErrorLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration)
+			nginxErrorLog := NewNginxErrorLog(&metrics.CommonDim{}, OSSNamespace, binary, OSSNginxType, collectionDuration, config.ErrorLogEvents{}, nil)
 			go nginxErrorLog.logStats(context.TODO(), errorLogFile.Name())
 
 			time.Sleep(sleepDuration)
 
 			for _, logLine := range test.logLines {
-				_, err := errorLogFile.WriteString(logLine)
+				_, err := errorLogFile.WriteString(logLine + "\n")
 				require.NoError(t, err, "Error writing data to error log")
 			}
 
@@ -141,3 +153,120 @@ func TestErrorLogStats(t *testing.T) {
 		})
 	}
 }
+
+func TestErrorLogStatsPeersAndEvents(t *testing.T) {
+	logLines := []string{
+		`2022/05/24 13:18:37 [error] 21314#21314: *91 connect() failed (111: Connection refused) while connecting to upstream, client: 127.0.0.1, server: , request: "GET /frontend1 HTTP/1.1", upstream: "http://127.0.0.1:9091/frontend1", host: "127.0.0.1:8081"`,
+		`2022/05/24 13:18:38 [error] 21314#21314: *92 connect() failed (111: Connection refused) while connecting to upstream, client: 127.0.0.1, server: , request: "GET /frontend2 HTTP/1.1", upstream: "http://127.0.0.1:9091/frontend2", host: "127.0.0.1:8081"`,
+		`2022/05/24 13:18:39 [warn] 21314#21314: *93 an upstream response is buffered to a temporary file while reading upstream, client: 127.0.0.1, server: , request: "GET /frontend3 HTTP/1.1", upstream: "http://127.0.0.1:9092/frontend3", host: "127.0.0.1:8081"`,
+		`2022/05/24 13:18:40 [crit] 21314#21314: open() "/var/run/nginx.pid" failed (13: Permission denied)`,
+	}
+
+	binary := core.NewNginxBinary(tutils.NewMockEnvironment(), &config.Config{})
+	collectionDuration := time.Millisecond * 300
+	sleepDuration := time.Millisecond * 100
+
+	errorLogFile, err := os.CreateTemp(os.TempDir(), "error.log")
+	require.NoError(t, err)
+	defer os.Remove(errorLogFile.Name())
+
+	events := make(chan *metrics.NginxErrorLogEvent, 10)
+	errorLogEvents := config.ErrorLogEvents{Enable: true, Levels: []string{"crit"}}
+	nginxErrorLog := NewNginxErrorLog(&metrics.CommonDim{NginxId: "123"}, OSSNamespace, binary, OSSNginxType, collectionDuration, errorLogEvents, events)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go nginxErrorLog.logStats(ctx, errorLogFile.Name())
+
+	time.Sleep(sleepDuration)
+
+	for _, logLine := range logLines {
+		_, err := errorLogFile.WriteString(logLine + "\n")
+		require.NoError(t, err, "Error writing data to error log")
+	}
+
+	time.Sleep(collectionDuration)
+	errorLogFile.Close()
+
+	nginxErrorLog.mu.Lock()
+	defer nginxErrorLog.mu.Unlock()
+	require.Len(t, nginxErrorLog.buf, 3)
+
+	peers := map[string][]*proto.SimpleMetric{}
+	for _, stats := range nginxErrorLog.buf[1:] {
+		for _, dimension := range stats.Data.GetDimensions() {
+			if dimension.GetName() == "peer.address" {
+				peers[dimension.GetValue()] = stats.Data.GetSimplemetrics()
+			}
+		}
+	}
+	assert.Equal(t, map[string][]*proto.SimpleMetric{
+		"127.0.0.1:9091": {{Name: "nginx.error_log.error", Value: 2}},
+		"127.0.0.1:9092": {{Name: "nginx.error_log.warn", Value: 1}},
+	}, peers)
+
+	require.Len(t, events, 1)
+	event := <-events
+	assert.Equal(t, "123", event.NginxId)
+	assert.Equal(t, errorLogFile.Name(), event.LogFile)
+	assert.Equal(t, "crit", event.Item.Level)
+	assert.Equal(t, `open() "/var/run/nginx.pid" failed (13: Permission denied)`, event.Item.Message)
+}
+
+func TestErrorLogEventsUpdate(t *testing.T) {
+	logLine := `2022/05/24 13:18:40 [crit] 21314#21314: open() "/var/run/nginx.pid" failed (13: Permission denied)`
+
+	binary := core.NewNginxBinary(tutils.NewMockEnvironment(), &config.Config{})
+	collectionDuration := time.Minute
+
+	errorLogFile, err := os.CreateTemp(os.TempDir(), "error.log")
+	require.NoError(t, err)
+	defer os.Remove(errorLogFile.Name())
+	defer errorLogFile.Close()
+
+	errorLogEvents := config.ErrorLogEvents{Enable: true, Levels: []string{"crit"}}
+	nginxErrorLog := NewNginxErrorLog(&metrics.CommonDim{NginxId: "123"}, OSSNamespace, binary, OSSNginxType, collectionDuration, errorLogEvents, make(chan *metrics.NginxErrorLogEvent, 100))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go nginxErrorLog.logStats(ctx, errorLogFile.Name())
+
+	time.Sleep(100 * time.Millisecond)
+
+	// the event channel is replaced while the error log is tailed
+	var events chan *metrics.NginxErrorLogEvent
+	for i := 0; i < 20; i++ {
+		_, err := errorLogFile.WriteString(logLine + "\n")
+		require.NoError(t, err)
+
+		events = make(chan *metrics.NginxErrorLogEvent, 100)
+		nginxErrorLog.Update(&metrics.CommonDim{NginxId: "456"}, &metrics.NginxCollectorConfig{
+			CollectionInterval:   collectionDuration,
+			ErrorLogEvents:       errorLogEvents,
+			ErrorLogEventChannel: events,
+		})
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	for len(events) > 0 {
+		<-events
+	}
+
+	_, err = errorLogFile.WriteString(logLine + "\n")
+	require.NoError(t, err)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "456", event.NginxId)
+		assert.Equal(t, "crit", event.Item.Level)
+	case <-time.After(time.Second):
+		t.Fatal("error log event was not sent to the updated event channel")
+	}
+}
+
+func TestErrorLogPeerAddress(t *testing.T) {
+	assert.Equal(t, "127.0.0.1:8080", errorLogPeerAddress("http://127.0.0.1:8080/api"))
+	assert.Equal(t, "unix:/tmp/backend.sock", errorLogPeerAddress("unix:/tmp/backend.sock"))
+	assert.Equal(t, "backend", errorLogPeerAddress("backend"))
+}
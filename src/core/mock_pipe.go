@@ -19,6 +19,7 @@ type MockMessagePipe struct {
 	messages          []*Message
 	processedMessages []*Message
 	ctx               context.Context
+	stats             *PipeStats
 }
 
 var _ MessagePipeInterface = &MockMessagePipe{}
@@ -48,7 +49,8 @@ func ValidateMessages(t *testing.T, messagePipe *MockMessagePipe, msgTopics []st
 
 func NewMockMessagePipe(ctx context.Context) *MockMessagePipe {
 	return &MockMessagePipe{
-		ctx: ctx,
+		ctx:   ctx,
+		stats: NewPipeStats(0, nil),
 	}
 }
 
@@ -79,6 +81,17 @@ func (p *MockMessagePipe) DeRegister(pluginNames []string) error {
 
 	}
 
+	for _, name := range pluginNames {
+		index := getExtensionIndex(name, p.extensionPlugins)
+
+		if index != -1 {
+			plugin := p.extensionPlugins[index]
+			p.extensionPlugins = append(p.extensionPlugins[:index], p.extensionPlugins[index+1:]...)
+
+			plugin.Close()
+		}
+	}
+
 	return nil
 }
 
@@ -137,6 +150,10 @@ func (p *MockMessagePipe) GetExtensionPlugins() []ExtensionPlugin {
 	return p.extensionPlugins
 }
 
+func (p *MockMessagePipe) GetStats() *PipeStats {
+	return p.stats
+}
+
 func (p *MockMessagePipe) IsPluginAlreadyRegistered(pluginName string) bool {
 	pluginAlreadyRegistered := false
 	for _, plugin := range p.GetPlugins() {
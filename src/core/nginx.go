@@ -44,9 +44,10 @@ var (
 )
 
 type NginxBinary interface {
-	Start(nginxId, bin string) error
+	Start(nginxId, bin, confPath string) error
 	Stop(processId, bin string) error
 	Reload(processId, bin string) error
+	Signal(processId string, signal syscall.Signal) error
 	ValidateConfig(processId, bin, configLocation string, config *proto.NginxConfig, configApply *sdk.ConfigApply) error
 	GetNginxDetailsFromProcess(nginxProcess *Process) *proto.NginxDetails
 	GetNginxDetailsByID(nginxID string) *proto.NginxDetails
@@ -257,11 +258,19 @@ func defaultToNginxCommandForProcessPath() string {
 	return path
 }
 
-// Start starts NGINX.
-func (n *NginxBinaryType) Start(nginxId, bin string) error {
+// Start starts NGINX. If confPath is set NGINX is started with nginx -c confPath.
+func (n *NginxBinaryType) Start(nginxId, bin, confPath string) error {
 	log.Infof("Starting NGINX Id: %s Bin: %s", nginxId, bin)
 
-	_, err := runCmd(bin)
+	var args []string
+	if confPath != "" {
+		args = append(args, "-c", confPath)
+	}
+
+	response, err := runCmd(bin, args...)
+	if err != nil {
+		err = fmt.Errorf("%v: %s", err, bytes.TrimSpace(response.Bytes()))
+	}
 	if err != nil {
 		log.Errorf("Starting NGINX caused error: %v", err)
 	} else {
@@ -289,6 +298,22 @@ func (n *NginxBinaryType) Reload(processId, bin string) error {
 	return err
 }
 
+// Signal sends a signal to an NGINX process.
+func (n *NginxBinaryType) Signal(processId string, signal syscall.Signal) error {
+	log.Infof("Sending signal %s to NGINX PID: %s", signal, processId)
+	intProcess, err := strconv.Atoi(processId)
+	if err != nil {
+		log.Errorf("Signaling NGINX caused error when trying to determine process id: %v", err)
+		return err
+	}
+
+	err = syscall.Kill(intProcess, signal)
+	if err != nil {
+		log.Errorf("Sending signal %s to NGINX caused error: %v", signal, err)
+	}
+	return err
+}
+
 // ValidateConfig tests the config with nginx -t -c configLocation.
 func (n *NginxBinaryType) ValidateConfig(processId, bin, configLocation string, config *proto.NginxConfig, configApply *sdk.ConfigApply) error {
 	log.Debugf("Validating config, %s for nginx process, %s", configLocation, processId)
@@ -29,6 +29,7 @@ type MessagePipeInterface interface {
 	GetPlugins() []Plugin
 	GetExtensionPlugins() []ExtensionPlugin
 	IsPluginAlreadyRegistered(string) bool
+	GetStats() *PipeStats
 }
 
 type MessagePipe struct {
@@ -39,17 +40,31 @@ type MessagePipe struct {
 	cancel           context.CancelFunc
 	mu               sync.RWMutex
 	bus              message_bus.MessageBus
+	stats            *PipeStats
+	subscriptions    map[string][]pipeSubscription
+	topicSubscribers map[string][]string
+}
+
+// pipeSubscription keeps the handler subscribed to the message bus on behalf of a plugin, so the
+// same handler can be unsubscribed when the plugin is deregistered
+type pipeSubscription struct {
+	topic   string
+	handler func(*Message)
 }
 
 func NewMessagePipe(ctx context.Context, size int) *MessagePipe {
 	pipeContext, pipeCancel := context.WithCancel(ctx)
+	messageChannel := make(chan *Message, size)
 	return &MessagePipe{
-		messageChannel:   make(chan *Message, size),
+		messageChannel:   messageChannel,
 		plugins:          make([]Plugin, 0, MaxPlugins),
 		extensionPlugins: make([]ExtensionPlugin, 0, MaxExtensionPlugins),
 		ctx:              pipeContext,
 		cancel:           pipeCancel,
 		mu:               sync.RWMutex{},
+		stats:            NewPipeStats(size, func() int { return len(messageChannel) }),
+		subscriptions:    make(map[string][]pipeSubscription),
+		topicSubscribers: make(map[string][]string),
 	}
 }
 
@@ -68,26 +83,24 @@ func (p *MessagePipe) Register(size int, plugins []Plugin, extensionPlugins []Ex
 	p.plugins = append(p.plugins, plugins...)
 	p.extensionPlugins = append(p.extensionPlugins, extensionPlugins...)
 	p.bus = message_bus.New(size)
+	p.subscriptions = make(map[string][]pipeSubscription)
+	p.topicSubscribers = make(map[string][]string)
 
 	pluginsRegistered := []string{}
 	extensionPluginsRegistered := []string{}
 
 	for _, plugin := range p.plugins {
-		for _, subscription := range plugin.Subscriptions() {
-			err := p.bus.Subscribe(subscription, plugin.Process)
-			if err != nil {
-				return err
-			}
+		err := p.subscribe(plugin.Info().Name(), plugin.Subscriptions(), plugin.Process)
+		if err != nil {
+			return err
 		}
 		pluginsRegistered = append(pluginsRegistered, *plugin.Info().name)
 	}
 
 	for _, plugin := range p.extensionPlugins {
-		for _, subscription := range plugin.Subscriptions() {
-			err := p.bus.Subscribe(subscription, plugin.Process)
-			if err != nil {
-				return err
-			}
+		err := p.subscribe(plugin.Info().Name(), plugin.Subscriptions(), plugin.Process)
+		if err != nil {
+			return err
 		}
 		extensionPluginsRegistered = append(extensionPluginsRegistered, *plugin.Info().name)
 	}
@@ -99,6 +112,7 @@ func (p *MessagePipe) Register(size int, plugins []Plugin, extensionPlugins []Ex
 
 func (p *MessagePipe) DeRegister(pluginNames []string) error {
 	p.mu.Lock()
+	defer p.mu.Unlock()
 
 	var plugins []Plugin
 	for _, name := range pluginNames {
@@ -117,17 +131,64 @@ func (p *MessagePipe) DeRegister(pluginNames []string) error {
 
 			plugin.Close()
 
-			for _, subscription := range plugin.Subscriptions() {
-				err := p.bus.Unsubscribe(subscription, plugin.Process)
-				if err != nil {
-					return err
-				}
+			err := p.unsubscribe(plugin.Info().Name())
+			if err != nil {
+				return err
 			}
 		}
 
 	}
 
-	p.mu.Unlock()
+	for _, name := range pluginNames {
+		index := getExtensionIndex(name, p.extensionPlugins)
+
+		if index != -1 {
+			plugin := p.extensionPlugins[index]
+			p.extensionPlugins = append(p.extensionPlugins[:index], p.extensionPlugins[index+1:]...)
+
+			plugin.Close()
+
+			err := p.unsubscribe(name)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// subscribe must be called with the lock held
+func (p *MessagePipe) subscribe(pluginName string, topics []string, process func(*Message)) error {
+	for _, topic := range topics {
+		handler := p.stats.track(pluginName, process)
+		err := p.bus.Subscribe(topic, handler)
+		if err != nil {
+			return err
+		}
+		p.subscriptions[pluginName] = append(p.subscriptions[pluginName], pipeSubscription{topic: topic, handler: handler})
+		p.topicSubscribers[topic] = append(p.topicSubscribers[topic], pluginName)
+	}
+	return nil
+}
+
+// unsubscribe must be called with the lock held
+func (p *MessagePipe) unsubscribe(pluginName string) error {
+	for _, subscription := range p.subscriptions[pluginName] {
+		err := p.bus.Unsubscribe(subscription.topic, subscription.handler)
+		if err != nil {
+			return err
+		}
+
+		subscribers := p.topicSubscribers[subscription.topic]
+		for index, subscriber := range subscribers {
+			if subscriber == pluginName {
+				p.topicSubscribers[subscription.topic] = append(subscribers[:index], subscribers[index+1:]...)
+				break
+			}
+		}
+	}
+	delete(p.subscriptions, pluginName)
 	return nil
 }
 
@@ -140,8 +201,25 @@ func getIndex(pluginName string, plugins []Plugin) int {
 	return -1
 }
 
+func getExtensionIndex(pluginName string, plugins []ExtensionPlugin) int {
+	for index, plugin := range plugins {
+		if pluginName == plugin.Info().Name() {
+			return index
+		}
+	}
+	return -1
+}
+
 func (p *MessagePipe) Process(messages ...*Message) {
 	for _, m := range messages {
+		select {
+		case p.messageChannel <- m:
+			continue
+		default:
+			// the message channel is full, so the caller is blocked until the pipe catches up
+			p.stats.recordQueueFull()
+		}
+
 		select {
 		case p.messageChannel <- m:
 		case <-p.ctx.Done():
@@ -169,6 +247,7 @@ func (p *MessagePipe) Run() {
 			return
 		case m := <-p.messageChannel:
 			p.mu.Lock()
+			p.stats.recordPublish(m.Topic(), p.topicSubscribers[m.Topic()])
 			p.bus.Publish(m.Topic(), m)
 			p.mu.Unlock()
 		}
@@ -191,6 +270,10 @@ func (p *MessagePipe) GetExtensionPlugins() []ExtensionPlugin {
 	return p.extensionPlugins
 }
 
+func (p *MessagePipe) GetStats() *PipeStats {
+	return p.stats
+}
+
 func (p *MessagePipe) initPlugins() {
 	for _, r := range p.plugins {
 		r.Init(p)
@@ -0,0 +1,198 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package core
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultSlowSubscriberDeadline = 5 * time.Second
+)
+
+// PipeStats tracks the messages published through the message pipe, the saturation of the
+// message channel and how long each plugin takes to process the messages it is subscribed to
+type PipeStats struct {
+	mu                     sync.RWMutex
+	queueCapacity          int
+	queueDepth             func() int
+	maxQueueDepth          int
+	queueFull              uint64
+	published              map[string]uint64
+	plugins                map[string]*PluginPipeStats
+	slowSubscriberDeadline time.Duration
+}
+
+// PluginPipeStats are the message processing statistics of a single plugin
+// swagger:model PluginPipeStats
+type PluginPipeStats struct {
+	// Number of messages processed
+	// example: 120
+	Processed uint64 `json:"processed"`
+	// Number of messages that took longer than the slow subscriber deadline to process
+	// example: 0
+	Slow uint64 `json:"slow"`
+	// Number of messages published to the plugin that have not been processed yet
+	// example: 1
+	Pending int64 `json:"pending"`
+	// Total time spent processing messages, in seconds
+	// example: 0.35
+	ProcessingTime float64 `json:"processing_time"`
+	// Longest time spent processing a single message, in seconds
+	// example: 0.02
+	MaxProcessingTime float64 `json:"max_processing_time"`
+}
+
+// PipeStatsSnapshot is a point in time copy of the message pipe statistics
+// swagger:model PipeStatsSnapshot
+type PipeStatsSnapshot struct {
+	// Size of the message queue
+	// example: 100
+	QueueCapacity int `json:"queue_capacity"`
+	// Number of messages waiting in the message queue
+	// example: 0
+	QueueDepth int `json:"queue_depth"`
+	// Highest number of messages waiting in the message queue since the agent started
+	// example: 12
+	MaxQueueDepth int `json:"max_queue_depth"`
+	// Number of times a message was sent while the message queue was full
+	// example: 0
+	QueueFull uint64 `json:"queue_full"`
+	// Number of messages published, by topic
+	Published map[string]uint64 `json:"published"`
+	// Message processing statistics, by plugin name
+	Plugins map[string]PluginPipeStats `json:"plugins"`
+}
+
+func NewPipeStats(queueCapacity int, queueDepth func() int) *PipeStats {
+	return &PipeStats{
+		queueCapacity:          queueCapacity,
+		queueDepth:             queueDepth,
+		published:              make(map[string]uint64),
+		plugins:                make(map[string]*PluginPipeStats),
+		slowSubscriberDeadline: DefaultSlowSubscriberDeadline,
+	}
+}
+
+// SetSlowSubscriberDeadline sets how long a plugin can spend processing a single message
+// before a warning is logged. A deadline of zero disables the warning.
+func (s *PipeStats) SetSlowSubscriberDeadline(deadline time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slowSubscriberDeadline = deadline
+}
+
+func (s *PipeStats) SlowSubscriberDeadline() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.slowSubscriberDeadline
+}
+
+func (s *PipeStats) recordQueueFull() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queueFull++
+}
+
+func (s *PipeStats) recordPublish(topic string, subscribers []string) {
+	depth := s.currentQueueDepth()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.published[topic]++
+	if depth > s.maxQueueDepth {
+		s.maxQueueDepth = depth
+	}
+	for _, subscriber := range subscribers {
+		s.pluginStats(subscriber).Pending++
+	}
+}
+
+func (s *PipeStats) recordProcessed(plugin string, duration time.Duration, slow bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.pluginStats(plugin)
+	stats.Processed++
+	stats.Pending--
+	stats.ProcessingTime += duration.Seconds()
+	if duration.Seconds() > stats.MaxProcessingTime {
+		stats.MaxProcessingTime = duration.Seconds()
+	}
+	if slow {
+		stats.Slow++
+	}
+}
+
+// pluginStats must be called with the lock held
+func (s *PipeStats) pluginStats(plugin string) *PluginPipeStats {
+	stats, ok := s.plugins[plugin]
+	if !ok {
+		stats = &PluginPipeStats{}
+		s.plugins[plugin] = stats
+	}
+	return stats
+}
+
+func (s *PipeStats) currentQueueDepth() int {
+	if s.queueDepth == nil {
+		return 0
+	}
+	return s.queueDepth()
+}
+
+// track wraps the process function of a plugin so that the time spent processing each message
+// is recorded, and a warning is logged as soon as a message takes longer than the slow
+// subscriber deadline, even if the plugin never returns
+func (s *PipeStats) track(plugin string, process func(*Message)) func(*Message) {
+	return func(msg *Message) {
+		var timer *time.Timer
+		deadline := s.SlowSubscriberDeadline()
+		if deadline > 0 {
+			timer = time.AfterFunc(deadline, func() {
+				log.Warnf("Plugin %s has been processing message with topic %s for more than %v", plugin, msg.Topic(), deadline)
+			})
+		}
+
+		start := time.Now()
+		process(msg)
+		duration := time.Since(start)
+
+		slow := false
+		if timer != nil {
+			slow = !timer.Stop()
+		}
+		s.recordProcessed(plugin, duration, slow)
+	}
+}
+
+// Snapshot returns a copy of the current message pipe statistics
+func (s *PipeStats) Snapshot() *PipeStatsSnapshot {
+	depth := s.currentQueueDepth()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := &PipeStatsSnapshot{
+		QueueCapacity: s.queueCapacity,
+		QueueDepth:    depth,
+		MaxQueueDepth: s.maxQueueDepth,
+		QueueFull:     s.queueFull,
+		Published:     make(map[string]uint64, len(s.published)),
+		Plugins:       make(map[string]PluginPipeStats, len(s.plugins)),
+	}
+	for topic, count := range s.published {
+		snapshot.Published[topic] = count
+	}
+	for plugin, stats := range s.plugins {
+		snapshot.Plugins[plugin] = *stats
+	}
+	return snapshot
+}
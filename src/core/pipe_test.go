@@ -76,6 +76,89 @@ func TestMessagePipe(t *testing.T) {
 	plugin.AssertExpectations(t)
 }
 
+func TestMessagePipe_Stats(t *testing.T) {
+	messages := []*Message{
+		NewMessage("test.message", 1),
+		NewMessage("test.message", 2),
+		NewMessage("other.message", 3),
+	}
+
+	plugin := new(testPlugin)
+	plugin.On("Init").Times(1)
+	plugin.On("Process").Times(2)
+	plugin.On("Close").Times(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pipelineDone := make(chan bool)
+
+	messagePipe := NewMessagePipe(ctx, 100)
+	err := messagePipe.Register(10, []Plugin{plugin}, nil)
+
+	assert.NoError(t, err)
+
+	go func() {
+		messagePipe.Run()
+		pipelineDone <- true
+	}()
+
+	messagePipe.Process(messages...)
+	assert.Eventually(t, func() bool {
+		return messagePipe.GetStats().Snapshot().Plugins["test"].Processed == 2
+	}, time.Second, 5*time.Millisecond)
+
+	err = messagePipe.DeRegister([]string{"test"})
+	assert.NoError(t, err)
+
+	messagePipe.Process(NewMessage("test.message", 4))
+	assert.Eventually(t, func() bool {
+		return messagePipe.GetStats().Snapshot().Published["test.message"] == 3
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-pipelineDone
+
+	snapshot := messagePipe.GetStats().Snapshot()
+	assert.Equal(t, 100, snapshot.QueueCapacity)
+	assert.Equal(t, map[string]uint64{"test.message": 3, "other.message": 1}, snapshot.Published)
+	assert.Equal(t, uint64(2), snapshot.Plugins["test"].Processed)
+	assert.Equal(t, int64(0), snapshot.Plugins["test"].Pending)
+
+	plugin.AssertExpectations(t)
+}
+
+func TestPipeStats_SlowSubscriber(t *testing.T) {
+	stats := NewPipeStats(10, nil)
+	stats.SetSlowSubscriberDeadline(time.Millisecond)
+
+	process := stats.track("slow", func(*Message) { time.Sleep(20 * time.Millisecond) })
+	process(NewMessage("test.message", 1))
+
+	stats.SetSlowSubscriberDeadline(0)
+	process(NewMessage("test.message", 2))
+
+	snapshot := stats.Snapshot()
+	assert.Equal(t, uint64(2), snapshot.Plugins["slow"].Processed)
+	assert.Equal(t, uint64(1), snapshot.Plugins["slow"].Slow)
+	assert.GreaterOrEqual(t, snapshot.Plugins["slow"].MaxProcessingTime, 0.02)
+	assert.GreaterOrEqual(t, snapshot.Plugins["slow"].ProcessingTime, 0.04)
+}
+
+func TestMessagePipe_QueueFull(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messagePipe := NewMessagePipe(ctx, 1)
+	messagePipe.Process(NewMessage("test.message", 1))
+	assert.Equal(t, 1, messagePipe.GetStats().Snapshot().QueueDepth)
+
+	go messagePipe.Process(NewMessage("test.message", 2))
+	assert.Eventually(t, func() bool {
+		return messagePipe.GetStats().Snapshot().QueueFull == 1
+	}, time.Second, 5*time.Millisecond)
+
+	<-messagePipe.messageChannel
+}
+
 func TestPipe_DeRegister(t *testing.T) {
 	plugin := new(testPlugin)
 	plugin.On("Init").Times(1)
@@ -93,6 +176,24 @@ func TestPipe_DeRegister(t *testing.T) {
 	assert.Equal(t, 0, len(messagePipe.GetPlugins()))
 }
 
+func TestPipe_DeRegisterExtensionPlugin(t *testing.T) {
+	plugin := new(testPlugin)
+	plugin.On("Close").Times(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messagePipe := NewMessagePipe(ctx, 100)
+	err := messagePipe.Register(10, nil, []ExtensionPlugin{plugin})
+	assert.NoError(t, err)
+
+	err = messagePipe.DeRegister([]string{*plugin.Info().name})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, len(messagePipe.GetExtensionPlugins()))
+	plugin.AssertExpectations(t)
+}
+
 func TestPipe_IsPluginAlreadyRegistered(t *testing.T) {
 	plugin := new(testPlugin)
 	plugin.On("Init").Times(1)
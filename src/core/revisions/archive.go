@@ -0,0 +1,245 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+// Package revisions stages, validates and tracks sets of NGINX configuration files.
+package revisions
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nginx/agent/sdk/v2/checksum"
+	"github.com/nginx/agent/sdk/v2/files"
+	"github.com/nginx/agent/sdk/v2/proto"
+	sdkZip "github.com/nginx/agent/sdk/v2/zip"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+
+	ErrEmptyArchive    = errors.New("archive does not contain any files")
+	ErrArchiveTooLarge = errors.New("archive files exceed the maximum size")
+)
+
+// sizeLimit keeps track of the bytes that can still be extracted from an archive
+type sizeLimit struct {
+	remaining int64
+}
+
+// read reads an archive member, failing with ErrArchiveTooLarge once the files
+// read so far exceed the limit
+func (l *sizeLimit) read(r io.Reader) ([]byte, error) {
+	contents, err := io.ReadAll(io.LimitReader(r, l.remaining+1))
+	if err != nil {
+		return nil, err
+	}
+	l.remaining -= int64(len(contents))
+	if l.remaining < 0 {
+		return nil, ErrArchiveTooLarge
+	}
+	return contents, nil
+}
+
+// ReadArchive extracts the files of a tar, tar.gz or zip archive. The names of the
+// returned files are the cleaned paths found in the archive, directories are skipped.
+// ErrArchiveTooLarge is returned if the uncompressed files exceed maxSize bytes.
+func ReadArchive(data []byte, maxSize int64) ([]*proto.File, error) {
+	var archiveFiles []*proto.File
+	var err error
+
+	limit := &sizeLimit{remaining: maxSize}
+
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		archiveFiles, err = readTarGz(data, limit)
+	case bytes.HasPrefix(data, zipMagic):
+		archiveFiles, err = readZip(data, limit)
+	default:
+		archiveFiles, err = readTar(bytes.NewReader(data), limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read archive: %w", err)
+	}
+
+	if len(archiveFiles) == 0 {
+		return nil, ErrEmptyArchive
+	}
+
+	for _, file := range archiveFiles {
+		name, err := cleanName(file.GetName())
+		if err != nil {
+			return nil, err
+		}
+		file.Name = name
+	}
+
+	return archiveFiles, nil
+}
+
+func readTarGz(data []byte, limit *sizeLimit) ([]*proto.File, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return readTar(reader, limit)
+}
+
+func readTar(r io.Reader, limit *sizeLimit) ([]*proto.File, error) {
+	archiveFiles := []*proto.File{}
+	reader := tar.NewReader(r)
+
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return archiveFiles, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		contents, err := limit.read(reader)
+		if err != nil {
+			return nil, err
+		}
+		archiveFiles = append(archiveFiles, &proto.File{
+			Name:        header.Name,
+			Permissions: files.GetPermissions(os.FileMode(header.Mode)),
+			Contents:    contents,
+		})
+	}
+}
+
+func readZip(data []byte, limit *sizeLimit) ([]*proto.File, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	archiveFiles := []*proto.File{}
+	for _, zipFile := range reader.File {
+		if zipFile.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := zipFile.Open()
+		if err != nil {
+			return nil, err
+		}
+		contents, err := limit.read(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		archiveFiles = append(archiveFiles, &proto.File{
+			Name:        zipFile.Name,
+			Permissions: files.GetPermissions(zipFile.Mode()),
+			Contents:    contents,
+		})
+	}
+
+	return archiveFiles, nil
+}
+
+// cleanName rejects names that would escape the directory the archive is extracted to
+func cleanName(name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return filepath.Clean(name), nil
+	}
+
+	cleaned := filepath.Clean(name)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid file name %q in archive", name)
+	}
+
+	return cleaned, nil
+}
+
+// ResolveFiles returns copies of the files with their names made absolute, relative names
+// are resolved against the directory of the main NGINX configuration file
+func ResolveFiles(confPath string, configFiles []*proto.File) []*proto.File {
+	confDir := filepath.Dir(confPath)
+	resolved := make([]*proto.File, 0, len(configFiles))
+
+	for _, file := range configFiles {
+		name := file.GetName()
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(confDir, name)
+		}
+		permissions := file.GetPermissions()
+		if permissions == "" {
+			permissions = files.GetPermissions(sdkZip.DefaultFileMode)
+		}
+		resolved = append(resolved, &proto.File{
+			Name:        name,
+			Permissions: permissions,
+			Contents:    file.GetContents(),
+		})
+	}
+
+	sort.Slice(resolved, func(i, j int) bool {
+		return resolved[i].GetName() < resolved[j].GetName()
+	})
+
+	return resolved
+}
+
+// ID returns the content address of a set of files
+func ID(configFiles []*proto.File) string {
+	sorted := make([]*proto.File, len(configFiles))
+	copy(sorted, configFiles)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].GetName() < sorted[j].GetName()
+	})
+
+	var buf bytes.Buffer
+	for _, file := range sorted {
+		buf.WriteString(file.GetName())
+		buf.WriteByte(0)
+		buf.WriteString(checksum.HexChecksum(file.GetContents()))
+		buf.WriteByte(0)
+	}
+
+	return checksum.HexChecksum(buf.Bytes())
+}
+
+// StagedID returns the ID of a set of files staged on top of the current configs of NGINX
+// instances, currentIds maps the NGINX IDs to the IDs of their current configs
+func StagedID(configFiles []*proto.File, currentIds map[string]string) string {
+	nginxIds := make([]string, 0, len(currentIds))
+	for nginxId := range currentIds {
+		nginxIds = append(nginxIds, nginxId)
+	}
+	sort.Strings(nginxIds)
+
+	var buf bytes.Buffer
+	buf.WriteString(ID(configFiles))
+	buf.WriteByte(0)
+	for _, nginxId := range nginxIds {
+		buf.WriteString(nginxId)
+		buf.WriteByte(0)
+		buf.WriteString(currentIds[nginxId])
+		buf.WriteByte(0)
+	}
+
+	return checksum.HexChecksum(buf.Bytes())
+}
@@ -0,0 +1,160 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package revisions
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tarArchive(t *testing.T, archiveFiles map[string]string) []byte {
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	for name, contents := range archiveFiles {
+		require.NoError(t, writer.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		_, err := writer.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestReadArchive(t *testing.T) {
+	archiveFiles := map[string]string{
+		"nginx.conf":                 "http {}",
+		"conf.d/default.conf":        "server {}",
+		"/etc/nginx/ssl/example.crt": "cert",
+	}
+
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, err := gzipWriter.Write(tarArchive(t, archiveFiles))
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	var zipped bytes.Buffer
+	zipWriter := zip.NewWriter(&zipped)
+	for name, contents := range archiveFiles {
+		fileWriter, err := zipWriter.Create(name)
+		require.NoError(t, err)
+		_, err = fileWriter.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	_, err = zipWriter.Create("conf.d/")
+	require.NoError(t, err)
+	require.NoError(t, zipWriter.Close())
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "tar", data: tarArchive(t, archiveFiles)},
+		{name: "tar.gz", data: gzipped.Bytes()},
+		{name: "zip", data: zipped.Bytes()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ReadArchive(tt.data, 1024)
+			require.NoError(t, err)
+
+			contents := make(map[string]string)
+			for _, file := range result {
+				contents[file.GetName()] = string(file.GetContents())
+			}
+			assert.Equal(t, archiveFiles, contents)
+		})
+	}
+}
+
+func TestReadArchive_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "invalid archive", data: []byte("not an archive")},
+		{name: "invalid gzip", data: []byte{0x1f, 0x8b, 0x00}},
+		{name: "empty archive", data: tarArchive(t, map[string]string{})},
+		{name: "path escape", data: tarArchive(t, map[string]string{"../nginx.conf": "http {}"})},
+		{name: "too large", data: tarArchive(t, map[string]string{"nginx.conf": strings.Repeat("#", 1025)})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadArchive(tt.data, 1024)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReadArchive_MaxSize(t *testing.T) {
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, err := gzipWriter.Write(tarArchive(t, map[string]string{
+		"nginx.conf":          strings.Repeat("#", 600),
+		"conf.d/default.conf": strings.Repeat("#", 600),
+	}))
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	_, err = ReadArchive(gzipped.Bytes(), 1024)
+	assert.ErrorIs(t, err, ErrArchiveTooLarge)
+
+	result, err := ReadArchive(gzipped.Bytes(), 1200)
+	require.NoError(t, err)
+	assert.Len(t, result, 2)
+}
+
+func TestResolveFiles(t *testing.T) {
+	resolved := ResolveFiles("/etc/nginx/nginx.conf", []*proto.File{
+		{Name: "nginx.conf", Contents: []byte("http {}")},
+		{Name: "/usr/share/nginx/html/index.html", Permissions: "0600"},
+	})
+
+	require.Len(t, resolved, 2)
+	assert.Equal(t, "/etc/nginx/nginx.conf", resolved[0].GetName())
+	assert.Equal(t, "0644", resolved[0].GetPermissions())
+	assert.Equal(t, "/usr/share/nginx/html/index.html", resolved[1].GetName())
+	assert.Equal(t, "0600", resolved[1].GetPermissions())
+}
+
+func TestID(t *testing.T) {
+	configFiles := []*proto.File{
+		{Name: "/etc/nginx/nginx.conf", Contents: []byte("http {}")},
+		{Name: "/etc/nginx/mime.types", Contents: []byte("types {}")},
+	}
+
+	id := ID(configFiles)
+	assert.Len(t, id, 64)
+	assert.Equal(t, id, ID([]*proto.File{configFiles[1], configFiles[0]}))
+	assert.NotEqual(t, id, ID([]*proto.File{configFiles[0]}))
+	assert.NotEqual(t, id, ID([]*proto.File{
+		{Name: "/etc/nginx/nginx.conf", Contents: []byte("http { }")},
+		configFiles[1],
+	}))
+}
+
+func TestStagedID(t *testing.T) {
+	configFiles := []*proto.File{{Name: "/etc/nginx/nginx.conf", Contents: []byte("http {}")}}
+
+	id := StagedID(configFiles, map[string]string{"1": "a", "2": "b"})
+	assert.Len(t, id, 64)
+	assert.Equal(t, id, StagedID(configFiles, map[string]string{"2": "b", "1": "a"}))
+	assert.NotEqual(t, id, StagedID(configFiles, map[string]string{"1": "a"}))
+	assert.NotEqual(t, id, StagedID(configFiles, map[string]string{"1": "a", "2": "c"}))
+	assert.NotEqual(t, id, StagedID([]*proto.File{{Name: "/etc/nginx/nginx.conf", Contents: []byte("http { }")}}, map[string]string{"1": "a", "2": "b"}))
+}
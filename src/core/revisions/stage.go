@@ -0,0 +1,147 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package revisions
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nginx/agent/sdk/v2/files"
+	"github.com/nginx/agent/sdk/v2/proto"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	scratchDirPattern = "nginx-agent-staging-"
+	diffContextLines  = 3
+)
+
+// Stage is a copy of the current NGINX configuration with a set of changed files applied,
+// written to a scratch directory so the result can be validated without touching the
+// live configuration
+type Stage struct {
+	// Dir is the scratch directory, absolute paths are mirrored under it
+	Dir string
+	// ConfPath is the path of the main NGINX configuration file in the scratch directory
+	ConfPath string
+}
+
+// NewStage writes the current configuration and aux files followed by the changed files
+// to a scratch directory. References to the directory of the main configuration file are
+// rewritten in the configuration files so includes resolve to the staged copies.
+func NewStage(confPath string, current []*proto.File, changed []*proto.File) (*Stage, error) {
+	dir, err := os.MkdirTemp("", scratchDirPattern)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create scratch directory: %w", err)
+	}
+
+	stage := &Stage{
+		Dir:      dir,
+		ConfPath: filepath.Join(dir, confPath),
+	}
+
+	confDir := filepath.Dir(confPath) + string(filepath.Separator)
+	stagedConfDir := filepath.Join(dir, confDir) + string(filepath.Separator)
+
+	staged := make(map[string]*proto.File)
+	for _, file := range current {
+		staged[file.GetName()] = file
+	}
+	for _, file := range changed {
+		staged[file.GetName()] = file
+	}
+
+	for name, file := range staged {
+		contents := file.GetContents()
+		if isConfigFile(name) {
+			contents = bytes.ReplaceAll(contents, []byte(confDir), []byte(stagedConfDir))
+		}
+
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			stage.Cleanup()
+			return nil, err
+		}
+		if err := os.WriteFile(path, contents, files.GetFileMode(file.GetPermissions())); err != nil {
+			stage.Cleanup()
+			return nil, err
+		}
+	}
+
+	return stage, nil
+}
+
+// Cleanup removes the scratch directory
+func (s *Stage) Cleanup() {
+	_ = os.RemoveAll(s.Dir)
+}
+
+func isConfigFile(name string) bool {
+	return strings.HasSuffix(name, ".conf") || !strings.Contains(filepath.Base(name), ".")
+}
+
+// Diff returns a unified diff of the changed files against the current files. Files
+// that do not exist yet are diffed against /dev/null.
+func Diff(current []*proto.File, changed []*proto.File) (string, error) {
+	currentContents := make(map[string][]byte)
+	for _, file := range current {
+		currentContents[file.GetName()] = file.GetContents()
+	}
+
+	sorted := make([]*proto.File, len(changed))
+	copy(sorted, changed)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].GetName() < sorted[j].GetName()
+	})
+
+	var diff strings.Builder
+	for _, file := range sorted {
+		fromFile := "a" + file.GetName()
+		contents, ok := currentContents[file.GetName()]
+		if !ok {
+			fromFile = "/dev/null"
+		} else if bytes.Equal(contents, file.GetContents()) {
+			continue
+		}
+
+		fileDiff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        splitLines(contents),
+			B:        splitLines(file.GetContents()),
+			FromFile: fromFile,
+			ToFile:   "b" + file.GetName(),
+			Context:  diffContextLines,
+		})
+		if err != nil {
+			return "", err
+		}
+		diff.WriteString(fileDiff)
+	}
+
+	return diff.String(), nil
+}
+
+// splitLines splits contents into lines that keep their line endings, a missing
+// final line ending is added so the last line is terminated in the diff
+func splitLines(contents []byte) []string {
+	if len(contents) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(contents), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+
+	return lines
+}
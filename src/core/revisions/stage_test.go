@@ -0,0 +1,82 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package revisions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStage(t *testing.T) {
+	current := []*proto.File{
+		{Name: "/etc/nginx/nginx.conf", Permissions: "0644", Contents: []byte("include /etc/nginx/conf.d/*.conf;\n")},
+		{Name: "/etc/nginx/conf.d/default.conf", Permissions: "0644", Contents: []byte("server { listen 80; }\n")},
+		{Name: "/usr/share/nginx/html/index.html", Permissions: "0644", Contents: []byte("/etc/nginx/")},
+	}
+	changed := []*proto.File{
+		{Name: "/etc/nginx/conf.d/default.conf", Permissions: "0644", Contents: []byte("server { listen 8080; }\n")},
+	}
+
+	stage, err := NewStage("/etc/nginx/nginx.conf", current, changed)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(stage.Dir, "/etc/nginx/nginx.conf"), stage.ConfPath)
+
+	contents, err := os.ReadFile(stage.ConfPath)
+	require.NoError(t, err)
+	assert.Equal(t, "include "+filepath.Join(stage.Dir, "/etc/nginx/conf.d")+"/*.conf;\n", string(contents))
+
+	contents, err = os.ReadFile(filepath.Join(stage.Dir, "/etc/nginx/conf.d/default.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "server { listen 8080; }\n", string(contents))
+
+	contents, err = os.ReadFile(filepath.Join(stage.Dir, "/usr/share/nginx/html/index.html"))
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/nginx/", string(contents))
+
+	stage.Cleanup()
+	_, err = os.Stat(stage.Dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDiff(t *testing.T) {
+	current := []*proto.File{
+		{Name: "/etc/nginx/nginx.conf", Contents: []byte("worker_processes 1;\nevents {}\n")},
+		{Name: "/etc/nginx/mime.types", Contents: []byte("types {}\n")},
+	}
+	changed := []*proto.File{
+		{Name: "/etc/nginx/nginx.conf", Contents: []byte("worker_processes 2;\nevents {}\n")},
+		{Name: "/etc/nginx/mime.types", Contents: []byte("types {}\n")},
+		{Name: "/etc/nginx/conf.d/default.conf", Contents: []byte("server {}\n")},
+	}
+
+	diff, err := Diff(current, changed)
+	require.NoError(t, err)
+
+	expected := "--- /dev/null\n" +
+		"+++ b/etc/nginx/conf.d/default.conf\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+server {}\n" +
+		"--- a/etc/nginx/nginx.conf\n" +
+		"+++ b/etc/nginx/nginx.conf\n" +
+		"@@ -1,2 +1,2 @@\n" +
+		"-worker_processes 1;\n" +
+		"+worker_processes 2;\n" +
+		" events {}\n"
+	assert.Equal(t, expected, diff)
+
+	diff, err = Diff(current, current)
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+}
@@ -0,0 +1,242 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package revisions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nginx/agent/sdk/v2"
+	"github.com/nginx/agent/sdk/v2/proto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	revisionFileName = "revision.json"
+	configFileName   = "config.pb"
+)
+
+var ErrRevisionNotFound = errors.New("config revision not found")
+
+// Revision describes a set of NGINX configuration files that was applied to an NGINX instance
+type Revision struct {
+	// ID is the content address of the configuration files
+	ID            string    `json:"id"`
+	NginxId       string    `json:"nginx_id"`
+	CorrelationId string    `json:"correlation_id"`
+	AppliedBy     string    `json:"applied_by"`
+	AppliedAt     time.Time `json:"applied_at"`
+	Files         []string  `json:"files"`
+}
+
+// Store persists the most recently applied NGINX configurations of each NGINX instance to
+// a directory, one directory per instance and revision. Applying the same files again
+// updates the existing revision instead of adding a new one.
+type Store struct {
+	dir          string
+	maxRevisions int
+	mu           sync.Mutex
+}
+
+// NewStore creates the store directory if needed
+func NewStore(dir string, maxRevisions int) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create config revisions directory %s: %w", dir, err)
+	}
+
+	return &Store{
+		dir:          dir,
+		maxRevisions: maxRevisions,
+	}, nil
+}
+
+// Add stores the NGINX configuration as the latest revision of the NGINX instance and removes
+// the oldest revisions that exceed the maximum number of revisions
+func (s *Store) Add(revision Revision, config *proto.NginxConfig) (*Revision, error) {
+	if revision.NginxId == "" {
+		return nil, errors.New("config revision has no NGINX ID")
+	}
+	if revision.AppliedAt.IsZero() {
+		revision.AppliedAt = time.Now()
+	}
+
+	confFiles, auxFiles, err := sdk.GetNginxConfigFiles(config)
+	if err != nil {
+		return nil, err
+	}
+	configFiles := append(confFiles, auxFiles...)
+
+	revision.ID = ID(configFiles)
+	revision.Files = make([]string, 0, len(configFiles))
+	for _, file := range configFiles {
+		revision.Files = append(revision.Files, file.GetName())
+	}
+	sort.Strings(revision.Files)
+
+	data, err := config.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := json.Marshal(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revisionDir := filepath.Join(s.dir, revision.NginxId, revision.ID)
+	if err := os.MkdirAll(revisionDir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(revisionDir, configFileName), data); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(revisionDir, revisionFileName), metadata); err != nil {
+		return nil, err
+	}
+
+	s.prune(revision.NginxId)
+
+	return &revision, nil
+}
+
+// List returns the stored revisions of an NGINX instance, newest first
+func (s *Store) List(nginxId string) ([]*Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list(nginxId)
+}
+
+// NginxIds returns the IDs of the NGINX instances that have stored revisions
+func (s *Store) NginxIds() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	nginxIds := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			nginxIds = append(nginxIds, entry.Name())
+		}
+	}
+
+	return nginxIds, nil
+}
+
+// Get returns a stored revision of an NGINX instance together with its NGINX configuration
+func (s *Store) Get(nginxId, id string) (*Revision, *proto.NginxConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revisionDir := filepath.Join(s.dir, filepath.Base(nginxId), filepath.Base(id))
+
+	revision, err := readRevision(revisionDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrRevisionNotFound, id)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(revisionDir, configFileName))
+	if err != nil {
+		return nil, nil, err
+	}
+	config := &proto.NginxConfig{}
+	if err := config.Unmarshal(data); err != nil {
+		return nil, nil, err
+	}
+
+	return revision, config, nil
+}
+
+func (s *Store) list(nginxId string) ([]*Revision, error) {
+	nginxDir := filepath.Join(s.dir, filepath.Base(nginxId))
+
+	entries, err := os.ReadDir(nginxDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Revision{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]*Revision, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		revision, err := readRevision(filepath.Join(nginxDir, entry.Name()))
+		if err != nil {
+			log.Warnf("Unable to read config revision %s: %v", entry.Name(), err)
+			continue
+		}
+		revisions = append(revisions, revision)
+	}
+
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].AppliedAt.After(revisions[j].AppliedAt)
+	})
+
+	return revisions, nil
+}
+
+func (s *Store) prune(nginxId string) {
+	if s.maxRevisions <= 0 {
+		return
+	}
+
+	revisions, err := s.list(nginxId)
+	if err != nil {
+		log.Warnf("Unable to list config revisions of NGINX instance %s: %v", nginxId, err)
+		return
+	}
+
+	for i := s.maxRevisions; i < len(revisions); i++ {
+		log.Debugf("Removing config revision %s of NGINX instance %s", revisions[i].ID, nginxId)
+		if err := os.RemoveAll(filepath.Join(s.dir, nginxId, revisions[i].ID)); err != nil {
+			log.Warnf("Unable to remove config revision %s: %v", revisions[i].ID, err)
+		}
+	}
+}
+
+func readRevision(revisionDir string) (*Revision, error) {
+	data, err := os.ReadFile(filepath.Join(revisionDir, revisionFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	revision := &Revision{}
+	if err := json.Unmarshal(data, revision); err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+func writeFile(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package revisions
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/sdk/v2/zip"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nginxConfig(t *testing.T, contents string) *proto.NginxConfig {
+	writer, err := zip.NewWriter("/etc/nginx")
+	require.NoError(t, err)
+	require.NoError(t, writer.Add("/etc/nginx/nginx.conf", zip.DefaultFileMode, bytes.NewBufferString(contents)))
+	zconfig, err := writer.Proto()
+	require.NoError(t, err)
+
+	return &proto.NginxConfig{ConfigData: &proto.ConfigDescriptor{NginxId: "1"}, Zconfig: zconfig}
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir, 2)
+	require.NoError(t, err)
+
+	now := time.Now()
+	first, err := store.Add(Revision{NginxId: "1", CorrelationId: "a", AppliedBy: "api", AppliedAt: now.Add(-3 * time.Minute)}, nginxConfig(t, "worker_processes 1;"))
+	require.NoError(t, err)
+	assert.Len(t, first.ID, 64)
+	assert.Equal(t, []string{"/etc/nginx/nginx.conf"}, first.Files)
+
+	second, err := store.Add(Revision{NginxId: "1", CorrelationId: "b", AppliedAt: now.Add(-2 * time.Minute)}, nginxConfig(t, "worker_processes 2;"))
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	revisions, err := store.List("1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, second.ID, revisions[0].ID)
+	assert.Equal(t, first.ID, revisions[1].ID)
+
+	// applying the same files again updates the existing revision
+	reapplied, err := store.Add(Revision{NginxId: "1", CorrelationId: "c", AppliedAt: now.Add(-time.Minute)}, nginxConfig(t, "worker_processes 1;"))
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, reapplied.ID)
+
+	revisions, err = store.List("1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, first.ID, revisions[0].ID)
+	assert.Equal(t, "c", revisions[0].CorrelationId)
+
+	// the oldest revision is removed once the maximum is exceeded
+	third, err := store.Add(Revision{NginxId: "1", CorrelationId: "d", AppliedAt: now}, nginxConfig(t, "worker_processes 3;"))
+	require.NoError(t, err)
+
+	store, err = NewStore(dir, 2)
+	require.NoError(t, err)
+	revisions, err = store.List("1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, third.ID, revisions[0].ID)
+	assert.Equal(t, first.ID, revisions[1].ID)
+
+	revision, config, err := store.Get("1", first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "c", revision.CorrelationId)
+	assert.Equal(t, "1", config.GetConfigData().GetNginxId())
+	files, err := zip.UnPack(config.GetZconfig())
+	require.NoError(t, err)
+	assert.Equal(t, "worker_processes 1;", string(files[0].GetContents()))
+
+	_, _, err = store.Get("1", second.ID)
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+
+	nginxIds, err := store.NginxIds()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, nginxIds)
+
+	revisions, err = store.List("2")
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	_, err = store.Add(Revision{}, nginxConfig(t, "worker_processes 1;"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,265 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+// Package supportbundle collects the NGINX configuration, logs, host information and agent state
+// needed to troubleshoot an agent into a single tar.gz archive, with secrets redacted.
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nginx/agent/sdk/v2"
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+)
+
+const (
+	DefaultMaxLogSize = 1024 * 1024
+
+	defaultAgentLogFile = "agent.log"
+	manifestFile        = "manifest.json"
+	fileMode            = 0o600
+)
+
+// Plugin is a plugin loaded by the agent, or enabled in the agent configuration when the bundle
+// is created while the agent is not running
+type Plugin struct {
+	Name      string `json:"name"`
+	Version   string `json:"version,omitempty"`
+	Extension bool   `json:"extension"`
+}
+
+// Manifest describes the content of a support bundle. It is added to the bundle as manifest.json.
+type Manifest struct {
+	CreatedAt    time.Time `json:"created_at"`
+	AgentVersion string    `json:"agent_version"`
+	Files        []string  `json:"files"`
+	// Redacted lists the files left out of the bundle because they contain secrets
+	Redacted []string `json:"redacted"`
+	// Errors lists the content that could not be collected
+	Errors []string `json:"errors"`
+}
+
+// Builder writes support bundles. The content that can't be collected is listed in the errors of
+// the bundle manifest instead of failing the whole bundle.
+type Builder struct {
+	config     *config.Config
+	env        core.Environment
+	binary     core.NginxBinary
+	maxLogSize int64
+}
+
+func NewBuilder(conf *config.Config, env core.Environment, binary core.NginxBinary) *Builder {
+	return &Builder{
+		config:     conf,
+		env:        env,
+		binary:     binary,
+		maxLogSize: DefaultMaxLogSize,
+	}
+}
+
+// SetMaxLogSize sets the number of bytes collected from the end of each log file
+func (b *Builder) SetMaxLogSize(maxLogSize int64) {
+	b.maxLogSize = maxLogSize
+}
+
+// Write writes a support bundle to w. The processes are used to find the NGINX instances, the
+// metrics reports and plugins are added as they are.
+func (b *Builder) Write(w io.Writer, processes []*core.Process, reports []*proto.MetricsReport, plugins []Plugin) (*Manifest, error) {
+	gzipWriter := gzip.NewWriter(w)
+	bundle := &bundleWriter{
+		writer:  tar.NewWriter(gzipWriter),
+		created: time.Now(),
+		manifest: &Manifest{
+			AgentVersion: b.config.Version,
+			Files:        []string{},
+			Redacted:     []string{},
+			Errors:       []string{},
+		},
+	}
+	bundle.manifest.CreatedAt = bundle.created
+
+	b.addAgent(bundle, plugins)
+	b.addHost(bundle, processes)
+	b.addNginxInstances(bundle, processes)
+	bundle.addJSON("metrics/metrics.json", metrics.NewMetricsSnapshot(reports))
+
+	bundle.addJSON(manifestFile, bundle.manifest)
+	if err := bundle.writer.Close(); err != nil {
+		return nil, fmt.Errorf("unable to write support bundle: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("unable to write support bundle: %w", err)
+	}
+
+	return bundle.manifest, nil
+}
+
+func (b *Builder) addAgent(bundle *bundleWriter, plugins []Plugin) {
+	bundle.addJSON("agent/config.json", redactConfig(b.config))
+	bundle.addJSON("agent/plugins.json", plugins)
+
+	for _, path := range []string{b.config.Path, b.config.DynamicConfigPath} {
+		if path == "" {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			bundle.addError("unable to read agent config file %s: %v", path, err)
+			continue
+		}
+		bundle.add(filepath.Join("agent", filepath.Base(path)), redactAgentConfigFile(content))
+	}
+
+	if b.config.Log.Path != "" {
+		logPath := b.config.Log.Path
+		if info, err := os.Stat(logPath); err == nil && info.IsDir() {
+			logPath = filepath.Join(logPath, defaultAgentLogFile)
+		}
+		b.addLog(bundle, "agent/"+defaultAgentLogFile, logPath)
+	}
+}
+
+func (b *Builder) addHost(bundle *bundleWriter, processes []*core.Process) {
+	tags := b.config.Tags
+	bundle.addJSON("host/host_info.json", b.env.NewHostInfo(b.config.Version, &tags, b.config.ConfigDirs, false))
+	bundle.addJSON("host/processes.json", processes)
+}
+
+func (b *Builder) addNginxInstances(bundle *bundleWriter, processes []*core.Process) {
+	detailsMap := b.binary.GetNginxDetailsMapFromProcesses(processes)
+	nginxIds := make([]string, 0, len(detailsMap))
+	for nginxId := range detailsMap {
+		nginxIds = append(nginxIds, nginxId)
+	}
+	sort.Strings(nginxIds)
+
+	for _, nginxId := range nginxIds {
+		detail := detailsMap[nginxId]
+		dir := filepath.Join("nginx", nginxId)
+		bundle.addJSON(filepath.Join(dir, "details.json"), detail)
+
+		nginxConfig, err := b.binary.ReadConfig(detail.GetConfPath(), nginxId, b.env.GetSystemUUID())
+		if err != nil {
+			bundle.addError("unable to read config of NGINX instance %s: %v", nginxId, err)
+			continue
+		}
+
+		confFiles, auxFiles, err := sdk.GetNginxConfigFiles(nginxConfig)
+		if err != nil {
+			bundle.addError("unable to read config files of NGINX instance %s: %v", nginxId, err)
+		}
+		files, redacted := redactNginxFiles(append(confFiles, auxFiles...), filepath.Dir(detail.GetConfPath()))
+		for _, file := range files {
+			bundle.add(filepath.Join(dir, "config", file.GetName()), file.GetContents())
+		}
+		for _, name := range redacted {
+			bundle.manifest.Redacted = append(bundle.manifest.Redacted, filepath.Join(dir, "config", name))
+		}
+
+		for _, errorLog := range nginxConfig.GetErrorLogs().GetErrorLog() {
+			// error logs sent to syslog, stderr or memory can't be collected
+			if !filepath.IsAbs(errorLog.GetName()) {
+				continue
+			}
+			b.addLog(bundle, filepath.Join(dir, "logs", errorLog.GetName()), errorLog.GetName())
+		}
+	}
+}
+
+// addLog adds the end of a log file, starting at the first complete line
+func (b *Builder) addLog(bundle *bundleWriter, name, path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		bundle.addError("unable to read log file %s: %v", path, err)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		bundle.addError("unable to read log file %s: %v", path, err)
+		return
+	}
+
+	offset := info.Size() - b.maxLogSize
+	if offset > 0 {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			bundle.addError("unable to read log file %s: %v", path, err)
+			return
+		}
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		bundle.addError("unable to read log file %s: %v", path, err)
+		return
+	}
+	if offset > 0 {
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			content = content[i+1:]
+		}
+	}
+
+	bundle.add(name, content)
+}
+
+type bundleWriter struct {
+	writer   *tar.Writer
+	created  time.Time
+	manifest *Manifest
+}
+
+func (w *bundleWriter) add(name string, content []byte) {
+	name = filepath.ToSlash(filepath.Clean(strings.TrimPrefix(name, "/")))
+	err := w.writer.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    fileMode,
+		Size:    int64(len(content)),
+		ModTime: w.created,
+	})
+	if err == nil {
+		_, err = w.writer.Write(content)
+	}
+	if err != nil {
+		w.addError("unable to add %s to support bundle: %v", name, err)
+		return
+	}
+
+	if name != manifestFile {
+		w.manifest.Files = append(w.manifest.Files, name)
+	}
+}
+
+func (w *bundleWriter) addJSON(name string, v interface{}) {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		w.addError("unable to encode %s: %v", name, err)
+		return
+	}
+	w.add(name, content)
+}
+
+func (w *bundleWriter) addError(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	log.Warnf("Support bundle: %s", message)
+	w.manifest.Errors = append(w.manifest.Errors, message)
+}
@@ -0,0 +1,149 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package supportbundle
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core/config"
+)
+
+const redacted = "REDACTED"
+
+var (
+	// matches the directives that reference private keys and key passwords, like ssl_certificate_key,
+	// proxy_ssl_certificate_key and ssl_password_file, together with their first argument
+	secretDirectiveRegex = regexp.MustCompile(`\b([a-z_]*ssl_certificate_key|[a-z_]*ssl_password_file)(\s+)("[^"]*"|'[^']*'|[^\s;]+)`)
+	privateKeyRegex      = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+	agentTokenRegex      = regexp.MustCompile(`(?m)^(\s*token\s*:).*$`)
+
+	// the values of these agent config keys are replaced, the values of the maps and lists under them too
+	secretAgentConfigKeys = map[string]bool{
+		"token":               true,
+		"headers":             true,
+		"read_only_tokens":    true,
+		"config_write_tokens": true,
+	}
+)
+
+// redactConfig returns a copy of the agent config with the server token, the Agent API tokens and
+// the OTLP headers, which usually carry credentials, redacted
+func redactConfig(conf *config.Config) config.Config {
+	redactedConf := *conf
+	if redactedConf.Server.Token != "" {
+		redactedConf.Server.Token = redacted
+	}
+	if len(conf.OTLP.Headers) > 0 {
+		redactedConf.OTLP.Headers = make(map[string]string, len(conf.OTLP.Headers))
+		for name := range conf.OTLP.Headers {
+			redactedConf.OTLP.Headers[name] = redacted
+		}
+	}
+	redactedConf.AgentAPI.Auth.ReadOnlyTokens = redactValues(conf.AgentAPI.Auth.ReadOnlyTokens)
+	redactedConf.AgentAPI.Auth.ConfigWriteTokens = redactValues(conf.AgentAPI.Auth.ConfigWriteTokens)
+	return redactedConf
+}
+
+// redactValues returns a list with each of the values redacted
+func redactValues(values []string) []string {
+	if len(values) == 0 {
+		return values
+	}
+	redactedValues := make([]string, len(values))
+	for i := range values {
+		redactedValues[i] = redacted
+	}
+	return redactedValues
+}
+
+// redactAgentConfigFile redacts the token and headers values of an agent config file, keeping
+// the rest of the file, including the comments, as it is
+func redactAgentConfigFile(content []byte) []byte {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil || len(document.Content) == 0 {
+		return agentTokenRegex.ReplaceAll(content, []byte("$1 "+redacted))
+	}
+
+	redactYAMLNode(&document, false)
+
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return agentTokenRegex.ReplaceAll(content, []byte("$1 "+redacted))
+	}
+	return buf.Bytes()
+}
+
+func redactYAMLNode(node *yaml.Node, secret bool) {
+	if node.Kind == yaml.ScalarNode {
+		if secret && node.Value != "" {
+			node.Value = redacted
+			node.Style = 0
+		}
+		return
+	}
+
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			redactYAMLNode(node.Content[i+1], secret || secretAgentConfigKeys[node.Content[i].Value])
+		}
+		return
+	}
+
+	for _, child := range node.Content {
+		redactYAMLNode(child, secret)
+	}
+}
+
+// redactNginxFiles leaves out the private keys and key password files referenced by the NGINX
+// config, and any other file containing a private key, and replaces the inline private keys of
+// the config. It returns the files to keep and the names of the files left out.
+func redactNginxFiles(files []*proto.File, confDir string) ([]*proto.File, []string) {
+	secretFiles := map[string]bool{}
+
+	redactedFiles := make([]*proto.File, 0, len(files))
+	for _, file := range files {
+		contents := secretDirectiveRegex.ReplaceAllFunc(file.GetContents(), func(match []byte) []byte {
+			groups := secretDirectiveRegex.FindSubmatch(match)
+			value := strings.Trim(string(groups[3]), `"'`)
+
+			switch {
+			case strings.HasPrefix(value, "data:"):
+				return []byte(string(groups[1]) + string(groups[2]) + "data:" + redacted)
+			case strings.HasPrefix(value, "engine:"), strings.Contains(value, "$"):
+			default:
+				if !filepath.IsAbs(value) {
+					value = filepath.Join(confDir, value)
+				}
+				secretFiles[filepath.Clean(value)] = true
+			}
+			return match
+		})
+
+		redactedFiles = append(redactedFiles, &proto.File{Name: file.GetName(), Contents: contents})
+	}
+
+	kept := make([]*proto.File, 0, len(redactedFiles))
+	names := []string{}
+	for _, file := range redactedFiles {
+		if secretFiles[filepath.Clean(file.GetName())] || privateKeyRegex.Match(file.GetContents()) {
+			names = append(names, file.GetName())
+			continue
+		}
+		kept = append(kept, file)
+	}
+
+	return kept, names
+}
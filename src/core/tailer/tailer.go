@@ -9,8 +9,12 @@ package tailer
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/nxadm/tail"
@@ -46,6 +50,7 @@ type NginxAccessItem struct {
 	UpstreamResponseLength string `mapstructure:"upstream_response_length"`
 	UpstreamStatus         string `mapstructure:"upstream_status"`
 	UpstreamCacheStatus    string `mapstructure:"upstream_cache_status"`
+	UpstreamAddr           string `mapstructure:"upstream_addr"`
 }
 
 func NewNginxAccessItem(v map[string]string) (*NginxAccessItem, error) {
@@ -56,6 +61,115 @@ func NewNginxAccessItem(v map[string]string) (*NginxAccessItem, error) {
 	return res, nil
 }
 
+// NginxErrorItem represents a decoded error log line, e.g.
+// 2022/05/24 13:18:37 [error] 21314#21314: *91 connect() failed while connecting to upstream, client: 127.0.0.1, server: localhost, request: "GET / HTTP/1.1", upstream: "http://127.0.0.1:9091/", host: "localhost"
+type NginxErrorItem struct {
+	Time         time.Time
+	Level        string
+	Pid          string
+	Tid          string
+	ConnectionId string
+	Message      string
+	Client       string
+	Server       string
+	Request      string
+	Upstream     string
+	Host         string
+}
+
+const (
+	errorLogTimeLayout    = "2006/01/02 15:04:05"
+	errorLogContextPrefix = ", client: "
+)
+
+var errorLogLineRegex = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}) \[(\w+)\] (\d+)#(\d+): (?:\*(\d+) )?(.*)$`)
+
+// NewNginxErrorItem decodes an error log line. The context NGINX appends to the message,
+// starting with the client address, is split into the client, server, request, upstream
+// and host fields.
+func NewNginxErrorItem(line string) (*NginxErrorItem, error) {
+	matches := errorLogLineRegex.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+	if matches == nil {
+		return nil, fmt.Errorf("invalid error log line %q", line)
+	}
+
+	timestamp, err := time.ParseInLocation(errorLogTimeLayout, matches[1], time.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &NginxErrorItem{
+		Time:         timestamp,
+		Level:        matches[2],
+		Pid:          matches[3],
+		Tid:          matches[4],
+		ConnectionId: matches[5],
+		Message:      matches[6],
+	}
+
+	idx := strings.Index(item.Message, errorLogContextPrefix)
+	if idx == -1 {
+		return item, nil
+	}
+
+	for key, value := range parseErrorLogContext(item.Message[idx+2:]) {
+		switch key {
+		case "client":
+			item.Client = value
+		case "server":
+			item.Server = value
+		case "request":
+			item.Request = value
+		case "upstream":
+			item.Upstream = value
+		case "host":
+			item.Host = value
+		}
+	}
+	item.Message = item.Message[:idx]
+
+	return item, nil
+}
+
+// parseErrorLogContext parses the comma separated key: value pairs of an error log line,
+// values may be quoted and contain escaped quotes
+func parseErrorLogContext(text string) map[string]string {
+	values := make(map[string]string)
+	for text != "" {
+		idx := strings.Index(text, ": ")
+		if idx == -1 {
+			break
+		}
+		key := text[:idx]
+		text = text[idx+2:]
+
+		var value string
+		if strings.HasPrefix(text, `"`) {
+			end := 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				value, text = text[1:], ""
+			} else {
+				value, text = text[1:end], text[end+1:]
+			}
+			value = strings.ReplaceAll(value, `\"`, `"`)
+		} else if end := strings.Index(text, ", "); end != -1 {
+			value, text = text[:end], text[end:]
+		} else {
+			value, text = text, ""
+		}
+		values[key] = value
+
+		text = strings.TrimPrefix(text, ", ")
+	}
+	return values
+}
+
 type Tailer struct {
 	handle *tail.Tail
 }
@@ -69,6 +183,14 @@ type LTSVTailer struct {
 	handle *tail.Tail
 }
 
+// JSONTailer tails logs written as one JSON object per line, e.g. access logs using a
+// log_format with escape=json
+type JSONTailer struct {
+	handle *tail.Tail
+	// keys maps the JSON keys of a log line to the names of the NGINX variables they hold
+	keys map[string]string
+}
+
 func NewTailer(file string) (*Tailer, error) {
 	t, err := tail.TailFile(file, tailConfig)
 	if err != nil {
@@ -106,6 +228,16 @@ func NewLTSVTailer(file string) (*LTSVTailer, error) {
 	return &LTSVTailer{t}, nil
 }
 
+// NewJSONTailer creates a tailer that decodes each line as a JSON object. JSON keys found in
+// keys are renamed to the NGINX variable they map to, other keys are used as is.
+func NewJSONTailer(file string, keys map[string]string) (*JSONTailer, error) {
+	t, err := tail.TailFile(file, tailConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONTailer{t, keys}, nil
+}
+
 func (t *Tailer) Tail(ctx context.Context, data chan<- string) {
 	for {
 		select {
@@ -203,3 +335,68 @@ func (t *LTSVTailer) parse(line string) map[string]string {
 	}
 	return lineMap
 }
+
+func (t *JSONTailer) Tail(ctx context.Context, data chan<- map[string]string) {
+	for {
+		select {
+		case line := <-t.handle.Lines:
+			if line == nil {
+				return
+			}
+			if line.Err != nil {
+				continue
+			}
+			l, err := t.parse(line.Text)
+			if err != nil {
+				log.Debugf("Unable to parse JSON log line: %v", err)
+				continue
+			}
+			data <- l
+		case <-ctx.Done():
+			ctxErr := ctx.Err()
+			switch ctxErr {
+			case context.DeadlineExceeded:
+				log.Tracef("Tailer cancelled because deadline was exceeded, %v", ctxErr)
+			case context.Canceled:
+				log.Tracef("Tailer forcibly cancelled, %v", ctxErr)
+			}
+			log.Tracef("Tailer is done")
+			return
+		}
+	}
+}
+
+func (t *JSONTailer) parse(line string) (map[string]string, error) {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+
+	fields := make(map[string]interface{})
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+
+	lineMap := make(map[string]string, len(fields))
+	for key, value := range fields {
+		if name, ok := t.keys[key]; ok {
+			key = name
+		}
+		switch v := value.(type) {
+		case nil:
+			lineMap[key] = ""
+		case string:
+			lineMap[key] = v
+		case json.Number:
+			lineMap[key] = v.String()
+		case map[string]interface{}, []interface{}:
+			// nested values are kept as JSON so they can still be matched by name
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			lineMap[key] = string(b)
+		default:
+			lineMap[key] = fmt.Sprint(v)
+		}
+	}
+	return lineMap, nil
+}
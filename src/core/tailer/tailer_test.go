@@ -32,6 +32,70 @@ func TestNewNginxAccessItem(t *testing.T) {
 	assert.Equal(t, "456", actual.BytesSent)
 }
 
+func TestNewNginxErrorItem(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected *NginxErrorItem
+	}{
+		{
+			name: "upstream error",
+			line: `2022/05/24 13:18:37 [error] 21314#21314: *91 connect() failed (111: Connection refused) while connecting to upstream, client: 127.0.0.1, server: , request: "GET /frontend1 HTTP/1.1", upstream: "http://127.0.0.1:9091/frontend1", host: "127.0.0.1:8081"`,
+			expected: &NginxErrorItem{
+				Time:         time.Date(2022, 5, 24, 13, 18, 37, 0, time.Local),
+				Level:        "error",
+				Pid:          "21314",
+				Tid:          "21314",
+				ConnectionId: "91",
+				Message:      "connect() failed (111: Connection refused) while connecting to upstream",
+				Client:       "127.0.0.1",
+				Request:      "GET /frontend1 HTTP/1.1",
+				Upstream:     "http://127.0.0.1:9091/frontend1",
+				Host:         "127.0.0.1:8081",
+			},
+		},
+		{
+			name: "quoted values",
+			line: `2015/07/15 05:56:33 [warn] 28386#28386: *94149 an upstream response is buffered, client: 85.141.232.177, server: *.compute.amazonaws.com, request: "GET /\"quoted\" HTTP/1.1", upstream: "http://127.0.0.1:3000/", host: "example.com", referrer: "http://example.com/"`,
+			expected: &NginxErrorItem{
+				Time:         time.Date(2015, 7, 15, 5, 56, 33, 0, time.Local),
+				Level:        "warn",
+				Pid:          "28386",
+				Tid:          "28386",
+				ConnectionId: "94149",
+				Message:      "an upstream response is buffered",
+				Client:       "85.141.232.177",
+				Server:       "*.compute.amazonaws.com",
+				Request:      `GET /"quoted" HTTP/1.1`,
+				Upstream:     "http://127.0.0.1:3000/",
+				Host:         "example.com",
+			},
+		},
+		{
+			name: "no connection",
+			line: `2022/05/24 13:18:37 [emerg] 1#1: bind() to 0.0.0.0:80 failed (98: Address already in use)`,
+			expected: &NginxErrorItem{
+				Time:    time.Date(2022, 5, 24, 13, 18, 37, 0, time.Local),
+				Level:   "emerg",
+				Pid:     "1",
+				Tid:     "1",
+				Message: "bind() to 0.0.0.0:80 failed (98: Address already in use)",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(tt *testing.T) {
+			item, err := NewNginxErrorItem(test.line)
+			require.NoError(tt, err)
+			assert.Equal(tt, test.expected, item)
+		})
+	}
+
+	_, err := NewNginxErrorItem("not an error log line")
+	assert.Error(t, err)
+}
+
 func TestGrok(t *testing.T) {
 	g, err := grok.New(grok.Config{
 		NamedCapturesOnly: true,
@@ -191,3 +255,56 @@ T:
 		res,
 	)
 }
+
+func TestJSONTailer(t *testing.T) {
+	accessLogFile, _ := os.CreateTemp(os.TempDir(), "access.log")
+	logLines := "{\"addr\":\"127.0.0.1\",\"request\":\"GET /500 HTTP/1.1\",\"status\":500,\"body_bytes_sent\":\"4\",\"upstream\":null,\"tags\":[\"a\"]}\n" +
+		"not json\n"
+
+	tailer, err := NewJSONTailer(accessLogFile.Name(), map[string]string{"addr": "remote_addr", "upstream": "upstream_status"})
+	require.Nil(t, err)
+
+	timeoutDuration := time.Millisecond * 300
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutDuration)
+	defer cancel()
+
+	data := make(chan map[string]string, 100)
+	go tailer.Tail(ctx, data)
+
+	time.Sleep(time.Millisecond * 100)
+	_, err = accessLogFile.WriteString(logLines)
+	if err != nil {
+		t.Fatalf("Error writing data to access log")
+	}
+	accessLogFile.Close()
+
+	var count int
+	var res map[string]string
+T:
+	for {
+		select {
+		case r := <-data:
+			res = r
+			count++
+		case <-time.After(timeoutDuration):
+			break T
+		case <-ctx.Done():
+			break T
+		}
+	}
+
+	os.Remove(accessLogFile.Name())
+	assert.Equal(t, 1, count)
+	assert.Equal(
+		t,
+		map[string]string{
+			"body_bytes_sent": "4",
+			"remote_addr":     "127.0.0.1",
+			"request":         "GET /500 HTTP/1.1",
+			"status":          "500",
+			"tags":            `["a"]`,
+			"upstream_status": "",
+		},
+		res,
+	)
+}
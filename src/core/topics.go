@@ -16,6 +16,9 @@ const (
 	NginxReloadComplete             = "nginx.reload.complete"
 	NginxStart                      = "nginx.start"
 	NginxStop                       = "nginx.stop"
+	NginxReopenLogs                 = "nginx.reopen.logs"
+	NginxUpgrade                    = "nginx.upgrade"
+	NginxLifecycleComplete          = "nginx.lifecycle.complete"
 	NginxPluginConfigured           = "nginx.plugin.config"
 	NginxInstancesFound             = "nginx.instances.found"
 	NginxMasterProcCreated          = "nginx.master.created"
@@ -28,6 +31,9 @@ const (
 	NginxConfigValidationSucceeded  = "nginx.config.validation.succeeded"
 	NginxConfigApplyFailed          = "nginx.config.apply.failed"
 	NginxConfigApplySucceeded       = "nginx.config.apply.succeeded"
+	NginxErrorLogEvent              = "nginx.error_log.event"
+	CertificateExpiry               = "certificate.expiry"
+	CertificateRenewal              = "certificate.renewal"
 	CommPrefix                      = "comms."
 	CommStatus                      = CommPrefix + "status"
 	CommMetrics                     = CommPrefix + "metrics"
@@ -37,8 +43,10 @@ const (
 	AgentConnected                  = "agent.connected"
 	AgentConfig                     = "agent.config"
 	AgentConfigChanged              = "agent.config.changed"
+	AgentConfigFilesChanged         = "agent.config.files.changed"
 	AgentCollectorsUpdate           = "agent.collectors.update"
 	MetricReport                    = "metrics.report"
+	SecurityMetricReport            = "metrics.security.report"
 	DataplaneChanged                = "dataplane.changed"
 	DataplaneFilesChanged           = "dataplane.fileschanged"
 	Events                          = "events"
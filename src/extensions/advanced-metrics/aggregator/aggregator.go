@@ -27,6 +27,7 @@ type ReadTable interface {
 
 type PriorityTable interface {
 	Samples() map[string]*sample.Sample
+	HeavyHitters() map[schema.FieldIndex][]priority_table.HeavyHitter
 }
 
 type Publisher interface {
@@ -21,7 +21,9 @@ import (
 
 	aggregator "github.com/nginx/agent/v2/src/extensions/advanced-metrics/aggregator"
 	tables "github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables"
+	priority_table "github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/priority_table"
 	sample "github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/sample"
+	schema "github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/schema"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -86,6 +88,20 @@ func (m *MockPriorityTable) EXPECT() *MockPriorityTableMockRecorder {
 	return m.recorder
 }
 
+// HeavyHitters mocks base method.
+func (m *MockPriorityTable) HeavyHitters() map[schema.FieldIndex][]priority_table.HeavyHitter {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HeavyHitters")
+	ret0, _ := ret[0].(map[schema.FieldIndex][]priority_table.HeavyHitter)
+	return ret0
+}
+
+// HeavyHitters indicates an expected call of HeavyHitters.
+func (mr *MockPriorityTableMockRecorder) HeavyHitters() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HeavyHitters", reflect.TypeOf((*MockPriorityTable)(nil).HeavyHitters))
+}
+
 // Samples mocks base method.
 func (m *MockPriorityTable) Samples() map[string]*sample.Sample {
 	m.ctrl.T.Helper()
@@ -61,7 +61,7 @@ func (i *Ingester) parseFrames(ctx context.Context) {
 				return
 			}
 			for _, msg := range frame.Messages() {
-				err := i.stagingTable.Add(newMessageFieldIterator(msg))
+				err := i.stagingTable.Add(newFieldIterator(msg, frame.Source()))
 				if err != nil {
 					log.Warnf("Fail to process incoming metric '%s': %s", string(msg), err.Error())
 				}
@@ -30,6 +30,7 @@ func TestIngesterRunCanProcessIncomingData(t *testing.T) {
 	frameMock := readerMock.NewMockFrame(ctrl)
 
 	frameMock.EXPECT().Messages().Return([][]byte{message1, message2})
+	frameMock.EXPECT().Source().Return("").Times(2)
 	frameMock.EXPECT().Release()
 	stagingTableMock.EXPECT().Add(newMessageFieldIterator(message1)).Return(nil)
 	stagingTableMock.EXPECT().Add(newMessageFieldIterator(message2)).Return(nil)
@@ -60,6 +61,7 @@ func TestIngesterRunIsNotStopingOnTableAddError(t *testing.T) {
 	frameMock := readerMock.NewMockFrame(ctrl)
 
 	frameMock.EXPECT().Messages().Return([][]byte{message1, message2})
+	frameMock.EXPECT().Source().Return("").Times(2)
 	frameMock.EXPECT().Release()
 	stagingTableMock.EXPECT().Add(newMessageFieldIterator(message1)).Return(errors.New("dummy error"))
 	stagingTableMock.EXPECT().Add(newMessageFieldIterator(message2)).Return(nil)
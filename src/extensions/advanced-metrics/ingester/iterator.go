@@ -9,6 +9,8 @@ package ingester
 
 import (
 	"bytes"
+
+	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables"
 )
 
 const (
@@ -62,3 +64,38 @@ func (i *messageFieldIterator) Next() []byte {
 func isNextFieldStringField(data []byte) bool {
 	return len(data) > 0 && data[0] == stringEscapingCharacter
 }
+
+// sourceFieldIterator returns the fields of a message followed by the source label of the frame
+// the message was received in, the label is the last dimension of the schema.
+type sourceFieldIterator struct {
+	*messageFieldIterator
+	source   []byte
+	consumed bool
+}
+
+// newFieldIterator returns an iterator over the fields of a message, the source label is added
+// as the last field if it's set.
+func newFieldIterator(data []byte, source string) tables.FieldIterator {
+	if source == "" {
+		return newMessageFieldIterator(data)
+	}
+	return &sourceFieldIterator{
+		messageFieldIterator: newMessageFieldIterator(data),
+		source:               []byte(source),
+	}
+}
+
+func (i *sourceFieldIterator) HasNext() bool {
+	return i.messageFieldIterator.HasNext() || !i.consumed
+}
+
+func (i *sourceFieldIterator) Next() []byte {
+	if i.messageFieldIterator.HasNext() {
+		return i.messageFieldIterator.Next()
+	}
+	if i.consumed {
+		return nil
+	}
+	i.consumed = true
+	return i.source
+}
@@ -134,3 +134,43 @@ func TestIterator(t *testing.T) {
 		})
 	}
 }
+
+func TestIteratorWithSource(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           string
+		source         string
+		expectedFields []string
+	}{
+		{
+			name:           "no source",
+			data:           "field1 field2",
+			expectedFields: []string{"field1", "field2"},
+		},
+		{
+			name:           "source added as last field",
+			data:           "field1 field2",
+			source:         "sidecar",
+			expectedFields: []string{"field1", "field2", "sidecar"},
+		},
+		{
+			name:           "source of empty message",
+			data:           "",
+			source:         "sidecar",
+			expectedFields: []string{"sidecar"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			it := newFieldIterator([]byte(test.data), test.source)
+			for _, expectedField := range test.expectedFields {
+				assert.True(t, it.HasNext())
+				field := it.Next()
+				assert.Equal(t, []byte(expectedField), field)
+			}
+			assert.False(t, it.HasNext())
+			assert.Nil(t, it.Next())
+		})
+	}
+}
@@ -10,6 +10,7 @@ package advanced_metrics
 import (
 	"context"
 	"fmt"
+	"net"
 	"time"
 
 	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/aggregator"
@@ -58,10 +59,29 @@ type TableSizesLimits struct {
 	PriorityTableThreshold int `mapstructure:"priority_table_threshold" yaml:"-"`
 }
 
+// SourceDimension is the dimension of the source label of the listener on which a sample was received.
+// It's added as the last dimension of the schema if listeners are configured.
+const SourceDimension = "source"
+
+// ListenerConfig specifies an address on which AdvancedMetrics listens for incoming metrics.
+type ListenerConfig struct {
+	// Network is one of "unix", "tcp" or "udp". TCP and UDP listeners are only allowed on loopback addresses.
+	Network string `mapstructure:"network" yaml:"-"`
+	// Address is the unix socket path, or the host:port of TCP and UDP listeners.
+	Address string `mapstructure:"address" yaml:"-"`
+	// Source is the value of the source dimension of metrics received on the listener.
+	// The address is used if it's not set.
+	Source string `mapstructure:"source" yaml:"-"`
+}
+
 // Config keeps configuration for app centric metric server
 type Config struct {
 	// Unix socket address on which AppCentricMetrics should listen for incoming metrics
 	Address string
+	// Listeners on which AppCentricMetrics should listen for incoming metrics instead of Address.
+	// Samples are labeled with the source of the listener in the SourceDimension, which has to be
+	// the last dimension of the schema.
+	Listeners []ListenerConfig
 
 	AggregatorConfig
 	TableSizesLimits
@@ -73,7 +93,7 @@ type Config struct {
 type AdvancedMetrics struct {
 	config Config
 
-	metricsChannel chan []*publisher.MetricSet
+	metricsChannel chan *publisher.MetricsReport
 	publisher      *publisher.Publisher
 	reader         *reader.Reader
 	ingester       *ingester.Ingester
@@ -86,9 +106,18 @@ func NewAdvancedMetrics(config Config, schema *schema.Schema) (*AdvancedMetrics,
 		return nil, fmt.Errorf("failed to create staging table limits: %w", err)
 	}
 	stagingTable := tables.NewStagingTable(schema, l)
-	metricsChannel := make(chan []*publisher.MetricSet)
+	metricsChannel := make(chan *publisher.MetricsReport)
 	publisher := publisher.New(metricsChannel, schema)
-	reader := reader.NewReader(config.Address)
+	if err := ValidateListeners(config.Listeners); err != nil {
+		return nil, err
+	}
+	if len(config.Listeners) > 0 {
+		fields := schema.Fields()
+		if len(fields) == 0 || fields[len(fields)-1].Name != SourceDimension {
+			return nil, fmt.Errorf("last field of the schema must be the %s dimension if listeners are configured", SourceDimension)
+		}
+	}
+	reader := reader.NewReader(endpoints(config))
 	ingester := ingester.NewIngester(reader.OutChannel(), stagingTable)
 	l, err = limits.NewLimits(config.PriorityTableMaxSize, config.PriorityTableThreshold)
 	if err != nil {
@@ -106,9 +135,9 @@ func NewAdvancedMetrics(config Config, schema *schema.Schema) (*AdvancedMetrics,
 	}, nil
 }
 
-// OutChannel returns publisher channel which will publish metrics sets
-// in configured intervals(config.PublishingPeriod)
-func (m *AdvancedMetrics) OutChannel() chan []*publisher.MetricSet {
+// OutChannel returns publisher channel which will publish metrics sets, with the values tracked
+// for the dimensions in the heavy hitters mode (see schema.WithTopK), in configured intervals(config.PublishingPeriod)
+func (m *AdvancedMetrics) OutChannel() chan *publisher.MetricsReport {
 	return m.metricsChannel
 }
 
@@ -141,3 +170,67 @@ func (m *AdvancedMetrics) Run(ctx context.Context) error {
 
 	return group.Wait()
 }
+
+// ValidateListeners checks that the listeners have a valid network and address and that their
+// source labels are unique.
+func ValidateListeners(listeners []ListenerConfig) error {
+	sources := make(map[string]struct{}, len(listeners))
+	for _, listener := range listeners {
+		switch listener.Network {
+		case reader.UnixNetwork:
+			if listener.Address == "" {
+				return fmt.Errorf("unix listener has no socket path")
+			}
+		case reader.TCPNetwork, reader.UDPNetwork:
+			host, _, err := net.SplitHostPort(listener.Address)
+			if err != nil {
+				return fmt.Errorf("%s listener has invalid address '%s': %w", listener.Network, listener.Address, err)
+			}
+			if !isLoopback(host) {
+				return fmt.Errorf("%s listener address '%s' is not a loopback address", listener.Network, listener.Address)
+			}
+		default:
+			return fmt.Errorf("listener '%s' has invalid network '%s', must be one of unix, tcp or udp", listener.Address, listener.Network)
+		}
+
+		source := listenerSource(listener)
+		if _, ok := sources[source]; ok {
+			return fmt.Errorf("listener source '%s' is defined more than once", source)
+		}
+		sources[source] = struct{}{}
+	}
+	return nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func listenerSource(listener ListenerConfig) string {
+	if listener.Source != "" {
+		return listener.Source
+	}
+	return listener.Address
+}
+
+// endpoints returns the reader endpoints of the listeners, or of the unix socket address if no
+// listeners are configured. Frames of the unix socket address have no source label.
+func endpoints(config Config) []reader.Endpoint {
+	if len(config.Listeners) == 0 {
+		return []reader.Endpoint{{Network: reader.UnixNetwork, Address: config.Address}}
+	}
+
+	endpoints := make([]reader.Endpoint, 0, len(config.Listeners))
+	for _, listener := range config.Listeners {
+		endpoints = append(endpoints, reader.Endpoint{
+			Network: listener.Network,
+			Address: listener.Address,
+			Source:  listenerSource(listener),
+		})
+	}
+	return endpoints
+}
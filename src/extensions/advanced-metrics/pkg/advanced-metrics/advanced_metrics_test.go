@@ -0,0 +1,90 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package advanced_metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/reader"
+)
+
+func TestValidateListeners(t *testing.T) {
+	tests := []struct {
+		name          string
+		listeners     []ListenerConfig
+		expectedError string
+	}{
+		{
+			name: "valid listeners",
+			listeners: []ListenerConfig{
+				{Network: "unix", Address: "/var/run/nginx-agent/advanced-metrics.sock", Source: "nginx"},
+				{Network: "unix", Address: "/var/run/nginx-agent/tenant-a.sock"},
+				{Network: "tcp", Address: "localhost:9100", Source: "sidecar"},
+				{Network: "udp", Address: "[::1]:9100"},
+			},
+		},
+		{
+			name:          "invalid network",
+			listeners:     []ListenerConfig{{Network: "http", Address: "127.0.0.1:9100"}},
+			expectedError: "listener '127.0.0.1:9100' has invalid network 'http', must be one of unix, tcp or udp",
+		},
+		{
+			name:          "unix listener without path",
+			listeners:     []ListenerConfig{{Network: "unix"}},
+			expectedError: "unix listener has no socket path",
+		},
+		{
+			name:          "tcp listener without port",
+			listeners:     []ListenerConfig{{Network: "tcp", Address: "127.0.0.1"}},
+			expectedError: "tcp listener has invalid address '127.0.0.1'",
+		},
+		{
+			name:          "udp listener on public address",
+			listeners:     []ListenerConfig{{Network: "udp", Address: "0.0.0.0:9100"}},
+			expectedError: "udp listener address '0.0.0.0:9100' is not a loopback address",
+		},
+		{
+			name: "duplicate source",
+			listeners: []ListenerConfig{
+				{Network: "unix", Address: "/var/run/nginx-agent/tenant-a.sock", Source: "tenant"},
+				{Network: "tcp", Address: "127.0.0.1:9100", Source: "tenant"},
+			},
+			expectedError: "listener source 'tenant' is defined more than once",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateListeners(tt.listeners)
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	assert.Equal(t, []reader.Endpoint{
+		{Network: "unix", Address: "/var/run/nginx-agent/advanced-metrics.sock"},
+	}, endpoints(Config{Address: "/var/run/nginx-agent/advanced-metrics.sock"}))
+
+	assert.Equal(t, []reader.Endpoint{
+		{Network: "unix", Address: "/var/run/nginx-agent/tenant-a.sock", Source: "/var/run/nginx-agent/tenant-a.sock"},
+		{Network: "tcp", Address: "127.0.0.1:9100", Source: "sidecar"},
+	}, endpoints(Config{
+		Address: "/var/run/nginx-agent/advanced-metrics.sock",
+		Listeners: []ListenerConfig{
+			{Network: "unix", Address: "/var/run/nginx-agent/tenant-a.sock"},
+			{Network: "tcp", Address: "127.0.0.1:9100", Source: "sidecar"},
+		},
+	}))
+}
@@ -7,7 +7,10 @@
 
 package publisher
 
-import "github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/sample"
+import (
+	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/sample"
+	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/schema"
+)
 
 // Dimension holds dimension name and value.
 type Dimension struct {
@@ -18,10 +21,14 @@ type Dimension struct {
 // MetricValues holds metric min, max, count, total values.
 type MetricValues = sample.Metric
 
-// Metric defines metric name and values.
+// MetricAggregation defines which of the metric values is published.
+type MetricAggregation = schema.MetricAggregation
+
+// Metric defines metric name, aggregation and values.
 type Metric struct {
-	Name   string
-	Values MetricValues
+	Name        string
+	Aggregation MetricAggregation
+	Values      MetricValues
 }
 
 // MetricSet defines metrics and dimensions associated for metrics.
@@ -29,3 +36,25 @@ type MetricSet struct {
 	Dimensions []Dimension
 	Metrics    []Metric
 }
+
+// MetricsReport holds the metric sets published for a publishing period and the heavy hitters
+// tracked in the same period.
+type MetricsReport struct {
+	MetricSets   []*MetricSet
+	HeavyHitters []HeavyHitters
+}
+
+// HeavyHitter holds a tracked dimension value and its estimated hit count within the publishing period.
+// Count is overestimated by at most Error.
+type HeavyHitter struct {
+	Value string
+	Count int
+	Error int
+}
+
+// HeavyHitters holds the values of a dimension in the heavy hitters mode, ordered by the estimated hit count.
+// These values are not collapsed into "AGGR" value in published metric sets.
+type HeavyHitters struct {
+	Dimension string
+	Values    []HeavyHitter
+}
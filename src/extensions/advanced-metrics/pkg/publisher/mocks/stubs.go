@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/priority_table"
 	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/sample"
 	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/schema"
 )
@@ -37,9 +38,14 @@ func (l *LookupSetStub) LookupCode(index int, code int) (string, error) {
 }
 
 type PriorityTableStub struct {
-	SamplesMap map[string]*sample.Sample
+	SamplesMap      map[string]*sample.Sample
+	HeavyHittersMap map[schema.FieldIndex][]priority_table.HeavyHitter
 }
 
 func (p *PriorityTableStub) Samples() map[string]*sample.Sample {
 	return p.SamplesMap
 }
+
+func (p *PriorityTableStub) HeavyHitters() map[schema.FieldIndex][]priority_table.HeavyHitter {
+	return p.HeavyHittersMap
+}
@@ -23,10 +23,10 @@ import (
 // Publisher is responsible for translation of internal tables to public structures and publishing process of metrics.
 type Publisher struct {
 	schema         *schema.Schema
-	metricsChannel chan<- []*MetricSet
+	metricsChannel chan<- *MetricsReport
 }
 
-func New(metricsChannel chan []*MetricSet, schema *schema.Schema) *Publisher {
+func New(metricsChannel chan *MetricsReport, schema *schema.Schema) *Publisher {
 	return &Publisher{
 		schema:         schema,
 		metricsChannel: metricsChannel,
@@ -46,17 +46,56 @@ func (p *Publisher) Publish(ctx context.Context, lookups tables.LookupSet, prior
 		metrics = append(metrics, metric)
 	}
 
+	// the heavy hitters are published with the metric sets, so that they are from the same period
+	report := &MetricsReport{
+		MetricSets:   metrics,
+		HeavyHitters: p.buildHeavyHitters(lookups, priorityTable),
+	}
+
 	select {
 	case <-time.After(time.Second):
 		return errors.New("timed out while publishing metrics report")
 	case <-ctx.Done():
 		return ctx.Err()
-	case p.metricsChannel <- metrics:
+	case p.metricsChannel <- report:
 
 	}
 	return nil
 }
 
+func (p *Publisher) buildHeavyHitters(lookups tables.LookupSet, priorityTable aggregator.PriorityTable) []HeavyHitters {
+	tracked := priorityTable.HeavyHitters()
+	heavyHitters := make([]HeavyHitters, 0, len(tracked))
+	for _, dimensionSchema := range p.schema.Dimensions() {
+		codes, ok := tracked[dimensionSchema.Index()]
+		if !ok {
+			continue
+		}
+
+		values := make([]HeavyHitter, 0, len(codes))
+		for _, code := range codes {
+			if code.Code == lookup.LookupNACode {
+				continue
+			}
+			value, err := p.dimensionValue(dimensionSchema, lookups, code.Code)
+			if err != nil {
+				logrus.Warnf("Heavy hitter lookup for dimension named '%s' failed with error '%v", dimensionSchema.Name, err)
+				continue
+			}
+			values = append(values, HeavyHitter{Value: value, Count: code.Count, Error: code.Error})
+		}
+		heavyHitters = append(heavyHitters, HeavyHitters{Dimension: dimensionSchema.Name, Values: values})
+	}
+	return heavyHitters
+}
+
+func (p *Publisher) dimensionValue(dimensionSchema *schema.Field, lookups tables.LookupSet, lookupCode int) (string, error) {
+	if dimensionSchema.Transform != nil {
+		return dimensionSchema.Transform.FromLookupCodeToValue(lookupCode)
+	}
+	return lookups.LookupCode(dimensionSchema.Index(), lookupCode)
+}
+
 func (p *Publisher) buildMetrics(s *sample.Sample) []Metric {
 	metrics := make([]Metric, 0, len(s.Metrics()))
 	for i, metric := range s.Metrics() {
@@ -64,8 +103,9 @@ func (p *Publisher) buildMetrics(s *sample.Sample) []Metric {
 			continue
 		}
 		metrics = append(metrics, Metric{
-			Name:   p.schema.Metric(i).Name,
-			Values: metric,
+			Name:        p.schema.Metric(i).Name,
+			Aggregation: p.schema.Metric(i).Aggregation,
+			Values:      metric,
 		})
 	}
 
@@ -14,6 +14,7 @@ import (
 
 	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/pkg/publisher/mocks"
 	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/lookup"
+	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/priority_table"
 	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/sample"
 	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/schema"
 	"github.com/stretchr/testify/assert"
@@ -136,7 +137,7 @@ func TestPublisher(t *testing.T) {
 				schema.NewDimensionField("dim1", 0, schema.WithKeyBitSize(8)),
 				schema.NewMetricField("metric1"),
 				schema.NewDimensionField("dim2", 0, schema.WithKeyBitSize(8)),
-				schema.NewMetricField("metric2"),
+				schema.NewMetricField("metric2", schema.WithAggregation(schema.AggregationMax)),
 			}...),
 			samples: map[string]*sample.Sample{
 				"s1": testSample(t, []float64{1, 11}, []int{1, 11}),
@@ -177,7 +178,8 @@ func TestPublisher(t *testing.T) {
 							},
 						},
 						{
-							Name: "metric2",
+							Name:        "metric2",
+							Aggregation: schema.AggregationMax,
 							Values: sample.Metric{
 								Count: 1,
 								Last:  11,
@@ -211,7 +213,8 @@ func TestPublisher(t *testing.T) {
 							},
 						},
 						{
-							Name: "metric2",
+							Name:        "metric2",
+							Aggregation: schema.AggregationMax,
 							Values: sample.Metric{
 								Count: 1,
 								Last:  12,
@@ -245,7 +248,8 @@ func TestPublisher(t *testing.T) {
 							},
 						},
 						{
-							Name: "metric2",
+							Name:        "metric2",
+							Aggregation: schema.AggregationMax,
 							Values: sample.Metric{
 								Count: 1,
 								Last:  13,
@@ -264,7 +268,7 @@ func TestPublisher(t *testing.T) {
 				schema.NewDimensionField("dim1", 0, schema.WithKeyBitSize(8)),
 				schema.NewMetricField("metric1"),
 				schema.NewDimensionField("dim2", 0, schema.WithKeyBitSize(8)),
-				schema.NewMetricField("metric2"),
+				schema.NewMetricField("metric2", schema.WithAggregation(schema.AggregationMax)),
 			}...),
 			samples: map[string]*sample.Sample{
 				"s1": testSample(t, []float64{1}, []int{1, 11}),
@@ -353,7 +357,8 @@ func TestPublisher(t *testing.T) {
 							},
 						},
 						{
-							Name: "metric2",
+							Name:        "metric2",
+							Aggregation: schema.AggregationMax,
 							Values: sample.Metric{
 								Count: 1,
 								Last:  13,
@@ -372,7 +377,7 @@ func TestPublisher(t *testing.T) {
 				schema.NewDimensionField("dim1", 0, schema.WithKeyBitSize(8)),
 				schema.NewMetricField("metric1"),
 				schema.NewDimensionField("dim2", 0, schema.WithKeyBitSize(8)),
-				schema.NewMetricField("metric2"),
+				schema.NewMetricField("metric2", schema.WithAggregation(schema.AggregationMax)),
 			}...),
 			samples: map[string]*sample.Sample{
 				"s1": testSample(t, []float64{1}, []int{1, 11}),
@@ -439,19 +444,62 @@ func TestPublisher(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			outChannel := make(chan []*MetricSet, 1)
+			outChannel := make(chan *MetricsReport, 1)
 			publisher := New(outChannel, test.schema)
 			err := publisher.Publish(
 				context.Background(),
 				&mocks.LookupSetStub{Lookups: test.dimensionsLookups},
 				&mocks.PriorityTableStub{SamplesMap: test.samples})
 			assert.NoError(t, err)
-			metrics := <-outChannel
-			assert.ElementsMatch(t, test.expectedMetrics, metrics)
+			report := <-outChannel
+			assert.ElementsMatch(t, test.expectedMetrics, report.MetricSets)
 		})
 	}
 }
 
+func TestPublisherHeavyHitters(t *testing.T) {
+	testSchema := schema.NewSchema(
+		schema.NewDimensionField("dim1", 0xff, schema.WithTopK(2)),
+		schema.NewDimensionField("dim2", 0xff),
+		schema.NewDimensionField("dim3", 0xff, schema.WithTopK(1)),
+		schema.NewMetricField("metric1"),
+	)
+
+	outChannel := make(chan *MetricsReport, 1)
+	publisher := New(outChannel, testSchema)
+
+	err := publisher.Publish(
+		context.Background(),
+		&mocks.LookupSetStub{Lookups: map[int]map[int]string{
+			0: {2: "/api", 3: "/login"},
+			2: {},
+		}},
+		&mocks.PriorityTableStub{
+			SamplesMap: map[string]*sample.Sample{},
+			HeavyHittersMap: map[schema.FieldIndex][]priority_table.HeavyHitter{
+				0: {{Code: 2, Count: 100}, {Code: 3, Count: 20, Error: 5}},
+				2: {{Code: lookup.LookupNACode, Count: 10}},
+			},
+		})
+	assert.NoError(t, err)
+	report := <-outChannel
+
+	assert.Empty(t, report.MetricSets)
+	assert.Equal(t, []HeavyHitters{
+		{
+			Dimension: "dim1",
+			Values: []HeavyHitter{
+				{Value: "/api", Count: 100},
+				{Value: "/login", Count: 20, Error: 5},
+			},
+		},
+		{
+			Dimension: "dim3",
+			Values:    []HeavyHitter{},
+		},
+	}, report.HeavyHitters)
+}
+
 func testSample(t *testing.T, metrics []float64, dimensionsCodes []int) *sample.Sample {
 	s := sample.NewSample(len(dimensionsCodes)*8, len(metrics))
 	for _, c := range dimensionsCodes {
@@ -19,14 +19,50 @@ import (
 type (
 	FieldOption                = schema.FieldOption
 	DimensionTransformFunction = schema.DimensionTransformFunction
+	MetricAggregation          = schema.MetricAggregation
 )
 
+const (
+	FieldTypeDimension        = "dimension"
+	FieldTypeIntegerDimension = "integer_dimension"
+	FieldTypeMetric           = "metric"
+
+	AggregationNone  = schema.AggregationNone
+	AggregationCount = schema.AggregationCount
+	AggregationSum   = schema.AggregationSum
+	AggregationMin   = schema.AggregationMin
+	AggregationMax   = schema.AggregationMax
+
+	// minDimensionSetSize is the minimal cardinality of a dimension, two values are reserved for internal use
+	minDimensionSetSize = 4
+)
+
+// FieldConfig defines a single schema field in the advanced metrics configuration.
+// Fields have to be listed in the order of the columns sent by the NGINX metrics module.
+type FieldConfig struct {
+	// Name of the dimension or metric
+	Name string `mapstructure:"name" yaml:"name"`
+	// Type is one of dimension, integer_dimension or metric
+	Type string `mapstructure:"type" yaml:"type"`
+	// Cardinality of a dimension, the maximum value for an integer dimension
+	Cardinality uint32 `mapstructure:"cardinality" yaml:"cardinality,omitempty"`
+	// CollapsingLevel of a dimension, see WithCollapsingLevel
+	CollapsingLevel *uint32 `mapstructure:"collapsing_level" yaml:"collapsing_level,omitempty"`
+	// Aggregation of a metric, one of none, count, sum, min or max
+	Aggregation MetricAggregation `mapstructure:"aggregation" yaml:"aggregation,omitempty"`
+	// TopK of a dimension, see WithTopK
+	TopK uint32 `mapstructure:"top_k" yaml:"top_k,omitempty"`
+}
+
 // WithTransformFunction defines pair of function which transform dimension raw value
 // from []byte to LookupCode and from LookupCode to string when dimension value will be published
 // Presence of this pair of functions assumes that dimension will be not stored in LookupTable
 // and converted value will be directly encoded in tables key.
 var WithTransformFunction = schema.WithTransformFunction
 
+// WithAggregation defines which aggregated value of a metric is published.
+var WithAggregation = schema.WithAggregation
+
 // WithCollapsingLevel defines CollapsingLevel for a dimension.
 // CollapsingLevel determines if specific dimension value should be aggregated into "AGGR" value.
 // CollapsingLevel is specified as a percent of elements above threshold value for both staging and priority tables
@@ -35,6 +71,12 @@ var WithTransformFunction = schema.WithTransformFunction
 // More about collapsing algorithm in TableSizesLimits doc string.
 var WithCollapsingLevel = schema.WithLevel
 
+// WithTopK enables the heavy hitters mode for a dimension.
+// The K values of the dimension with the highest hit count within the publishing period are tracked
+// and are never collapsed into "AGGR" value by the priority table, only the remaining values are collapsed.
+// The tracked values are published in the HeavyHitters of the MetricsReport.
+var WithTopK = schema.WithTopK
+
 type SchemaBuilder struct {
 	fields []*schema.Field
 }
@@ -56,12 +98,12 @@ func (b *SchemaBuilder) NewDimension(name string, maxDimensionSetSize uint32, op
 	return b
 }
 
-func (b *SchemaBuilder) NewIntegerDimension(name string, maxDimensionValue uint32) *SchemaBuilder {
-	b.fields = append(b.fields, schema.NewDimensionField(name,
-		uint32(maxDimensionValue),
+func (b *SchemaBuilder) NewIntegerDimension(name string, maxDimensionValue uint32, opts ...FieldOption) *SchemaBuilder {
+	opts = append([]FieldOption{
 		schema.WithTransformFunction(&integerDimensionTransformFunction),
 		schema.WithKeyBitSize(bits.UintSize),
-	))
+	}, opts...)
+	b.fields = append(b.fields, schema.NewDimensionField(name, uint32(maxDimensionValue), opts...))
 
 	return b
 }
@@ -80,8 +122,8 @@ func integerDimensionTransformFromLookupCode(code int) (string, error) {
 	return strconv.Itoa(code), nil
 }
 
-func (b *SchemaBuilder) NewMetric(name string) *SchemaBuilder {
-	b.fields = append(b.fields, schema.NewMetricField(name))
+func (b *SchemaBuilder) NewMetric(name string, opts ...FieldOption) *SchemaBuilder {
+	b.fields = append(b.fields, schema.NewMetricField(name, opts...))
 	return b
 }
 
@@ -94,3 +136,91 @@ func (b *SchemaBuilder) Build() (*schema.Schema, error) {
 	}
 	return schema, nil
 }
+
+// NewSchemaBuilderFromConfig validates the configured schema fields and returns a builder with
+// the fields in the configured order
+func NewSchemaBuilderFromConfig(fields []FieldConfig) (*SchemaBuilder, error) {
+	if err := ValidateFieldConfigs(fields); err != nil {
+		return nil, err
+	}
+
+	b := NewSchemaBuilder()
+	for _, field := range fields {
+		switch field.Type {
+		case FieldTypeDimension:
+			opts := []FieldOption{}
+			if field.CollapsingLevel != nil {
+				opts = append(opts, WithCollapsingLevel(*field.CollapsingLevel))
+			}
+			if field.TopK != 0 {
+				opts = append(opts, WithTopK(field.TopK))
+			}
+			b.NewDimension(field.Name, field.Cardinality, opts...)
+		case FieldTypeIntegerDimension:
+			opts := []FieldOption{}
+			if field.TopK != 0 {
+				opts = append(opts, WithTopK(field.TopK))
+			}
+			b.NewIntegerDimension(field.Name, field.Cardinality, opts...)
+		case FieldTypeMetric:
+			b.NewMetric(field.Name, WithAggregation(field.Aggregation))
+		}
+	}
+	return b, nil
+}
+
+// ValidateFieldConfigs checks that the schema has at least one dimension and one metric, that
+// the field names are unique and that every field has the settings of its type
+func ValidateFieldConfigs(fields []FieldConfig) error {
+	names := make(map[string]struct{}, len(fields))
+	dimensions, metrics := 0, 0
+	for i, field := range fields {
+		if field.Name == "" {
+			return fmt.Errorf("schema field %d has no name", i)
+		}
+		if _, ok := names[field.Name]; ok {
+			return fmt.Errorf("schema field '%s' is defined more than once", field.Name)
+		}
+		names[field.Name] = struct{}{}
+
+		switch field.Type {
+		case FieldTypeDimension, FieldTypeIntegerDimension:
+			dimensions++
+			if field.Aggregation != "" {
+				return fmt.Errorf("dimension: '%s' can't have an aggregation", field.Name)
+			}
+			if field.Type == FieldTypeDimension && field.Cardinality < minDimensionSetSize {
+				return fmt.Errorf("dimension: '%s' has cardinality=%d lower than minimum allowed value=%d", field.Name, field.Cardinality, minDimensionSetSize)
+			}
+			if field.Type == FieldTypeIntegerDimension && field.Cardinality == 0 {
+				return fmt.Errorf("dimension: '%s' has no cardinality", field.Name)
+			}
+			if field.Type == FieldTypeIntegerDimension && field.CollapsingLevel != nil {
+				return fmt.Errorf("integer dimension: '%s' can't have a collapsing level", field.Name)
+			}
+			if field.CollapsingLevel != nil && *field.CollapsingLevel > limits.MaxCollapseLevel {
+				return fmt.Errorf("dimension: '%s' contains CollapsingLevel=%d greater than maximum allowed value=%d", field.Name, *field.CollapsingLevel, limits.MaxCollapseLevel)
+			}
+			if field.Type == FieldTypeDimension && field.TopK > field.Cardinality {
+				return fmt.Errorf("dimension: '%s' has top_k=%d greater than its cardinality=%d", field.Name, field.TopK, field.Cardinality)
+			}
+		case FieldTypeMetric:
+			metrics++
+			if field.Cardinality != 0 || field.CollapsingLevel != nil || field.TopK != 0 {
+				return fmt.Errorf("metric: '%s' can't have a cardinality, collapsing level or top_k", field.Name)
+			}
+			switch field.Aggregation {
+			case AggregationNone, AggregationCount, AggregationSum, AggregationMin, AggregationMax:
+			default:
+				return fmt.Errorf("metric: '%s' has invalid aggregation '%s', must be one of none, count, sum, min or max", field.Name, field.Aggregation)
+			}
+		default:
+			return fmt.Errorf("schema field '%s' has invalid type '%s', must be one of dimension, integer_dimension or metric", field.Name, field.Type)
+		}
+	}
+
+	if dimensions == 0 || metrics == 0 {
+		return fmt.Errorf("schema must have at least one dimension and one metric")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/tables/schema"
+)
+
+func TestNewSchemaBuilderFromConfig(t *testing.T) {
+	collapsingLevel := uint32(50)
+	builder, err := NewSchemaBuilderFromConfig([]FieldConfig{
+		{Name: "dim1", Type: FieldTypeDimension, Cardinality: 64, CollapsingLevel: &collapsingLevel},
+		{Name: "metric1", Type: FieldTypeMetric, Aggregation: AggregationSum},
+		{Name: "dim2", Type: FieldTypeIntegerDimension, Cardinality: 600, TopK: 10},
+		{Name: "metric2", Type: FieldTypeMetric, Aggregation: AggregationNone},
+	})
+	require.NoError(t, err)
+
+	s, err := builder.Build()
+	require.NoError(t, err)
+
+	require.Len(t, s.Fields(), 4)
+	assert.Equal(t, 2, s.NumDimensions())
+	assert.Equal(t, 2, s.NumMetrics())
+
+	assert.Equal(t, "dim1", s.Dimension(0).Name)
+	assert.Equal(t, uint32(64), s.Dimension(0).MaxDimensionSetSize)
+	assert.Equal(t, &collapsingLevel, s.Dimension(0).CollapsingLevel)
+	assert.Nil(t, s.Dimension(0).Transform)
+	assert.NotNil(t, s.Dimension(1).Transform)
+	assert.Equal(t, uint32(0), s.Dimension(0).TopK)
+	assert.Equal(t, uint32(10), s.Dimension(1).TopK)
+
+	assert.Equal(t, schema.MetricField{Aggregation: AggregationSum}, s.Metric(0).MetricField)
+	assert.Equal(t, schema.MetricField{Aggregation: AggregationNone}, s.Metric(1).MetricField)
+}
+
+func TestValidateFieldConfigs(t *testing.T) {
+	collapsingLevel := uint32(120)
+	metric := FieldConfig{Name: "hitcount", Type: FieldTypeMetric, Aggregation: AggregationCount}
+	dimension := FieldConfig{Name: "http.uri", Type: FieldTypeDimension, Cardinality: 16000}
+
+	tests := []struct {
+		name          string
+		fields        []FieldConfig
+		expectedError string
+	}{
+		{
+			name:   "valid schema",
+			fields: []FieldConfig{dimension, metric},
+		},
+		{
+			name:          "no metrics",
+			fields:        []FieldConfig{dimension},
+			expectedError: "schema must have at least one dimension and one metric",
+		},
+		{
+			name:          "field without name",
+			fields:        []FieldConfig{dimension, {Type: FieldTypeMetric, Aggregation: AggregationSum}},
+			expectedError: "schema field 1 has no name",
+		},
+		{
+			name:          "duplicate name",
+			fields:        []FieldConfig{dimension, metric, metric},
+			expectedError: "schema field 'hitcount' is defined more than once",
+		},
+		{
+			name:          "invalid type",
+			fields:        []FieldConfig{dimension, {Name: "bytes", Type: "counter"}},
+			expectedError: "schema field 'bytes' has invalid type 'counter'",
+		},
+		{
+			name:          "dimension cardinality too low",
+			fields:        []FieldConfig{{Name: "family", Type: FieldTypeDimension, Cardinality: 2}, metric},
+			expectedError: "dimension: 'family' has cardinality=2 lower than minimum allowed value=4",
+		},
+		{
+			name:          "integer dimension without cardinality",
+			fields:        []FieldConfig{{Name: "status", Type: FieldTypeIntegerDimension}, metric},
+			expectedError: "dimension: 'status' has no cardinality",
+		},
+		{
+			name:          "collapsing level too high",
+			fields:        []FieldConfig{{Name: "http.uri", Type: FieldTypeDimension, Cardinality: 16000, CollapsingLevel: &collapsingLevel}, metric},
+			expectedError: "dimension: 'http.uri' contains CollapsingLevel=120 greater than maximum allowed value=100",
+		},
+		{
+			name:          "dimension with aggregation",
+			fields:        []FieldConfig{{Name: "http.uri", Type: FieldTypeDimension, Cardinality: 16000, Aggregation: AggregationMax}, metric},
+			expectedError: "dimension: 'http.uri' can't have an aggregation",
+		},
+		{
+			name:          "metric with cardinality",
+			fields:        []FieldConfig{dimension, {Name: "bytes", Type: FieldTypeMetric, Cardinality: 10, Aggregation: AggregationSum}},
+			expectedError: "metric: 'bytes' can't have a cardinality, collapsing level or top_k",
+		},
+		{
+			name:   "dimension with top_k",
+			fields: []FieldConfig{{Name: "http.uri", Type: FieldTypeDimension, Cardinality: 16000, TopK: 100}, metric},
+		},
+		{
+			name:          "top_k greater than cardinality",
+			fields:        []FieldConfig{{Name: "http.uri", Type: FieldTypeDimension, Cardinality: 64, TopK: 100}, metric},
+			expectedError: "dimension: 'http.uri' has top_k=100 greater than its cardinality=64",
+		},
+		{
+			name:          "metric with top_k",
+			fields:        []FieldConfig{dimension, {Name: "bytes", Type: FieldTypeMetric, TopK: 10, Aggregation: AggregationSum}},
+			expectedError: "metric: 'bytes' can't have a cardinality, collapsing level or top_k",
+		},
+		{
+			name:          "metric without aggregation",
+			fields:        []FieldConfig{dimension, {Name: "bytes", Type: FieldTypeMetric}},
+			expectedError: "metric: 'bytes' has invalid aggregation ''",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFieldConfigs(tt.fields)
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.expectedError)
+		})
+	}
+}
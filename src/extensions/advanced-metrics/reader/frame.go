@@ -23,6 +23,7 @@ var (
 type frame struct {
 	buffer    *fixedSizeBuffer
 	frameSize int
+	source    string
 
 	release func(*fixedSizeBuffer)
 }
@@ -43,6 +44,11 @@ func (f *frame) Messages() [][]byte {
 	return messages[:len(messages)-trailingSlices]
 }
 
+// Source returns the source label of the endpoint on which the frame was received.
+func (f *frame) Source() string {
+	return f.source
+}
+
 // Release clears Frame internal data and returns buffer to the pool.
 // This function should be always called after Frame handling is done.
 // This is safe to call any other methods of Frame after Release call.
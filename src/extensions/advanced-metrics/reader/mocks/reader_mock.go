@@ -61,6 +61,21 @@ func (mr *MockListenerConfigMockRecorder) Listen(ctx, network, address any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Listen", reflect.TypeOf((*MockListenerConfig)(nil).Listen), ctx, network, address)
 }
 
+// ListenPacket mocks base method.
+func (m *MockListenerConfig) ListenPacket(ctx context.Context, network, address string) (net.PacketConn, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListenPacket", ctx, network, address)
+	ret0, _ := ret[0].(net.PacketConn)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListenPacket indicates an expected call of ListenPacket.
+func (mr *MockListenerConfigMockRecorder) ListenPacket(ctx, network, address any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListenPacket", reflect.TypeOf((*MockListenerConfig)(nil).ListenPacket), ctx, network, address)
+}
+
 // MockWorker is a mock of Worker interface.
 type MockWorker struct {
 	ctrl     *gomock.Controller
@@ -146,3 +161,17 @@ func (mr *MockFrameMockRecorder) Release() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockFrame)(nil).Release))
 }
+
+// Source mocks base method.
+func (m *MockFrame) Source() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Source")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Source indicates an expected call of Source.
+func (mr *MockFrameMockRecorder) Source() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Source", reflect.TypeOf((*MockFrame)(nil).Source))
+}
@@ -0,0 +1,109 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package reader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+// packetWorker reads the datagrams received on a UDP endpoint. Unlike the stream worker every
+// datagram is a frame of its own, so data of different datagrams or clients is never joined.
+type packetWorker struct {
+	conn         net.PacketConn
+	source       string
+	frameChannel chan Frame
+
+	maxBufferSize int
+}
+
+func newPacketWorker(conn net.PacketConn, source string, frameChannel chan Frame) *packetWorker {
+	return &packetWorker{
+		conn:         conn,
+		source:       source,
+		frameChannel: frameChannel,
+
+		maxBufferSize: maxWorkerBufferSize,
+	}
+}
+
+func (w *packetWorker) Run(ctx context.Context) error {
+	readLoopGroup, ctx := errgroup.WithContext(ctx)
+	readLoopGroup.Go(func() error {
+		return w.readLoop(ctx)
+	})
+	readLoopGroup.Go(func() error {
+		return w.closeConnection(ctx)
+	})
+
+	return readLoopGroup.Wait()
+}
+
+func (w *packetWorker) closeConnection(ctx context.Context) error {
+	<-ctx.Done()
+	err := w.conn.Close()
+	if err != nil {
+		return fmt.Errorf("fail to close connection: %w", err)
+	}
+
+	return nil
+}
+
+// readLoop sends the complete messages of every datagram as a frame, an unterminated message at the
+// end of a datagram is dropped. Read errors of single datagrams are logged and reading continues.
+func (w *packetWorker) readLoop(ctx context.Context) error {
+	bufferPool := sync.Pool{}
+	bufferPool.New = func() interface{} {
+		return NewFixedSizeBuffer(w.maxBufferSize)
+	}
+	releaseFunction := func(buffer *fixedSizeBuffer) {
+		bufferPool.Put(buffer)
+	}
+
+	buffer := bufferPool.Get().(*fixedSizeBuffer)
+	for {
+		bytesReceived, addr, err := w.conn.ReadFrom(buffer.buffer)
+		if errors.Is(err, net.ErrClosed) {
+			log.Info("Connection was gracefully closed")
+			return nil
+		}
+		if err != nil {
+			log.Warnf("Failed to read advanced metrics datagram: %v", err)
+			continue
+		}
+		buffer.size = bytesReceived
+
+		frameSize := frameSize(buffer)
+		if frameSize == -1 {
+			log.Debugf("Dropping unterminated advanced metrics datagram of %d bytes received from %v", bytesReceived, addr)
+			buffer.clear()
+			continue
+		}
+		if frameSize < bytesReceived {
+			log.Debugf("Dropping unterminated advanced metrics message of %d bytes received from %v", bytesReceived-frameSize, addr)
+		}
+
+		frame := &frame{
+			buffer:    buffer,
+			frameSize: frameSize,
+			source:    w.source,
+			release:   releaseFunction,
+		}
+		select {
+		case w.frameChannel <- frame:
+		case <-ctx.Done():
+		}
+		buffer = bufferPool.Get().(*fixedSizeBuffer)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"sync"
@@ -22,14 +23,17 @@ import (
 //go:generate go run go.uber.org/mock/mockgen -source reader.go -destination mocks/reader_mock.go -package mocks -copyright_file=../../../../COPYRIGHT
 //go:generate go run go.uber.org/mock/mockgen -destination mocks/net_mocks.go -build_flags=--mod=mod -package mocks net Listener,Conn
 const (
-	networkType = "unix"
+	UnixNetwork = "unix"
+	TCPNetwork  = "tcp"
+	UDPNetwork  = "udp"
 )
 
 type ListenerConfig interface {
 	Listen(ctx context.Context, network, address string) (net.Listener, error)
+	ListenPacket(ctx context.Context, network, address string) (net.PacketConn, error)
 }
 
-type NewWorkerConstructor = func(connection net.Conn, frameChannel chan Frame) Worker
+type NewWorkerConstructor = func(connection io.ReadCloser, source string, frameChannel chan Frame) Worker
 
 type Worker interface {
 	Run(ctx context.Context) error
@@ -39,10 +43,21 @@ type Worker interface {
 // For more details read `frame` documentations.
 type Frame interface {
 	Messages() [][]byte
+	// Source returns the source label of the endpoint on which the frame was received
+	Source() string
 	Release()
 }
 
-// Reader exposes unix socket and reads the messages send by clients and forwards it further to the 'Frame' channel.
+// Endpoint is an address on which Reader receives messages.
+type Endpoint struct {
+	// Network is one of "unix", "tcp" or "udp"
+	Network string
+	Address string
+	// Source is the label of the frames received on the endpoint
+	Source string
+}
+
+// Reader exposes unix sockets, or TCP and UDP addresses, and reads the messages send by clients and forwards it further to the 'Frame' channel.
 //
 // Reader implements very simple separator based protocol in order to receive multiple messages on single UNIX stream connection.
 // Protocol is specified as series of following:
@@ -52,11 +67,13 @@ type Frame interface {
 //   - <separator> is message separator character: `;`
 //   - <message_data> is any arbitrary data forming single message with following restrictions: it could not contain <separator> and
 //     maximal message size is 64kB - 1(for separator)
+//
+// UDP endpoints use the same protocol, every datagram is a frame of its own and should contain only complete
+// messages. An unterminated message at the end of a datagram is dropped.
 type Reader struct {
 	listenerConfig ListenerConfig
-	listener       net.Listener
 
-	address string
+	endpoints []Endpoint
 
 	workersWaitGroup sync.WaitGroup
 	newWorker        NewWorkerConstructor
@@ -64,44 +81,41 @@ type Reader struct {
 	frameChannel chan Frame
 }
 
-func NewReader(address string) *Reader {
+func NewReader(endpoints []Endpoint) *Reader {
 	frameChannel := make(chan Frame)
-	return newReader(address, &net.ListenConfig{}, frameChannel, func(connection net.Conn, frameChannel chan Frame) Worker { return newWorker(connection, frameChannel) })
+	return newReader(endpoints, &net.ListenConfig{}, frameChannel, func(connection io.ReadCloser, source string, frameChannel chan Frame) Worker {
+		return newWorker(connection, source, frameChannel)
+	})
 }
 
-func newReader(address string, listenerConfig ListenerConfig, frameChannel chan Frame, newWorker NewWorkerConstructor) *Reader {
+func newReader(endpoints []Endpoint, listenerConfig ListenerConfig, frameChannel chan Frame, newWorker NewWorkerConstructor) *Reader {
 	return &Reader{
 		listenerConfig: listenerConfig,
-		address:        address,
+		endpoints:      endpoints,
 		newWorker:      newWorker,
 		frameChannel:   frameChannel,
 	}
 }
 
 func (r *Reader) Run(ctx context.Context) error {
-	if networkType == "unix" {
-		err := r.checkSocketAndCleanup()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	acceptLoopGroup, ctx := errgroup.WithContext(ctx)
+	var err error
+	for _, endpoint := range r.endpoints {
+		err = r.listen(ctx, acceptLoopGroup, endpoint)
 		if err != nil {
-			log.Warnf("Unable to cleanup orphaned unix socket, please remove manually before restarting. \nDetails:\n%v", err)
-			return err
+			// stop the endpoints which are already listening
+			cancel()
+			break
 		}
 	}
-	listener, err := r.listenerConfig.Listen(ctx, networkType, r.address)
-	if err != nil {
-		return fmt.Errorf("failed to start advanced metrics listener")
-	}
-	r.listener = listener
-	log.Debug("advanced metrics reader started listening")
 
-	acceptLoopGroup, ctx := errgroup.WithContext(ctx)
-	acceptLoopGroup.Go(func() error {
-		return r.acceptLoop(ctx)
-	})
-	acceptLoopGroup.Go(func() error {
-		return r.closeListener(ctx)
-	})
-
-	err = acceptLoopGroup.Wait()
+	groupErr := acceptLoopGroup.Wait()
+	if err == nil {
+		err = groupErr
+	}
 	r.workersWaitGroup.Wait()
 	close(r.frameChannel)
 
@@ -112,34 +126,76 @@ func (r *Reader) OutChannel() chan Frame {
 	return r.frameChannel
 }
 
-func (r *Reader) closeListener(ctx context.Context) error {
+func (r *Reader) listen(ctx context.Context, acceptLoopGroup *errgroup.Group, endpoint Endpoint) error {
+	if endpoint.Network == UnixNetwork {
+		err := checkSocketAndCleanup(endpoint.Address)
+		if err != nil {
+			log.Warnf("Unable to cleanup orphaned unix socket, please remove manually before restarting. \nDetails:\n%v", err)
+			return err
+		}
+	}
+
+	if endpoint.Network == UDPNetwork {
+		conn, err := r.listenerConfig.ListenPacket(ctx, endpoint.Network, endpoint.Address)
+		if err != nil {
+			return fmt.Errorf("failed to start advanced metrics listener on %s %s: %w", endpoint.Network, endpoint.Address, err)
+		}
+		log.Debugf("advanced metrics reader started listening on %s %s", endpoint.Network, endpoint.Address)
+		worker := newPacketWorker(conn, endpoint.Source, r.frameChannel)
+		acceptLoopGroup.Go(func() error {
+			// the error is not returned to the group, so that it doesn't stop the other endpoints
+			err := worker.Run(ctx)
+			if err != nil {
+				log.Errorf("Advanced metrics reader on %s %s failed: %v", endpoint.Network, endpoint.Address, err)
+			}
+			return nil
+		})
+		return nil
+	}
+
+	listener, err := r.listenerConfig.Listen(ctx, endpoint.Network, endpoint.Address)
+	if err != nil {
+		return fmt.Errorf("failed to start advanced metrics listener on %s %s: %w", endpoint.Network, endpoint.Address, err)
+	}
+	log.Debugf("advanced metrics reader started listening on %s %s", endpoint.Network, endpoint.Address)
+
+	acceptLoopGroup.Go(func() error {
+		return r.acceptLoop(ctx, listener, endpoint.Source)
+	})
+	acceptLoopGroup.Go(func() error {
+		return closeListener(ctx, listener)
+	})
+	return nil
+}
+
+func closeListener(ctx context.Context, listener net.Listener) error {
 	<-ctx.Done()
-	err := r.listener.Close()
+	err := listener.Close()
 	if err != nil {
 		return fmt.Errorf("fail to close listener: %w", err)
 	}
 	return nil
 }
 
-func (r *Reader) acceptLoop(ctx context.Context) error {
+func (r *Reader) acceptLoop(ctx context.Context, listener net.Listener, source string) error {
 	id := 0
 	for {
-		connection, err := r.listener.Accept()
+		connection, err := listener.Accept()
 		if errors.Is(err, net.ErrClosed) {
 			return nil
 		}
 		if err != nil {
 			return fmt.Errorf("fail to accept new connection: %w", err)
 		}
-		r.runWorker(ctx, connection, id)
+		r.runWorker(ctx, connection, source, id)
 		id++
 	}
 }
 
-func (r *Reader) runWorker(ctx context.Context, connection net.Conn, id int) {
+func (r *Reader) runWorker(ctx context.Context, connection io.ReadCloser, source string, id int) {
 	log.Debugf("New connection accepted, starting new reader worker ID: %d", id)
 
-	worker := r.newWorker(connection, r.frameChannel)
+	worker := r.newWorker(connection, source, r.frameChannel)
 	r.workersWaitGroup.Add(1)
 	go func() {
 		err := worker.Run(ctx)
@@ -151,11 +207,11 @@ func (r *Reader) runWorker(ctx context.Context, connection net.Conn, id int) {
 	}()
 }
 
-func (r *Reader) checkSocketAndCleanup() error {
+func checkSocketAndCleanup(address string) error {
 	log.Info("Checking availability of unix socket")
 
-	if _, err := os.Stat(r.address); err == nil {
-		err = os.Remove(r.address)
+	if _, err := os.Stat(address); err == nil {
+		err = os.Remove(address)
 		if err != nil {
 			return err
 		}
@@ -11,11 +11,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/reader/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
@@ -29,12 +32,12 @@ func TestReaderShouldAcceptNewConnectionAndStartWorker(t *testing.T) {
 	listenerMock := mocks.NewMockListener(ctrl)
 	workerMock := mocks.NewMockWorker(ctrl)
 	conn := mocks.NewMockConn(ctrl)
-	newWorker := func(net.Conn, chan Frame) Worker {
+	newWorker := func(io.ReadCloser, string, chan Frame) Worker {
 		return workerMock
 	}
 
 	frameChannel := make(chan Frame)
-	reader := newReader(address, configMock, frameChannel, newWorker)
+	reader := newReader([]Endpoint{{Network: UnixNetwork, Address: address}}, configMock, frameChannel, newWorker)
 	ctx, cancel := context.WithCancel(context.Background())
 
 	configMock.EXPECT().Listen(gomock.Any(), "unix", address).Return(listenerMock, nil)
@@ -65,12 +68,12 @@ func TestReaderShouldStopWorkersOnAcceptError(t *testing.T) {
 	listenerMock := mocks.NewMockListener(ctrl)
 	conn := mocks.NewMockConn(ctrl)
 	workerMock := mocks.NewMockWorker(ctrl)
-	newWorker := func(net.Conn, chan Frame) Worker {
+	newWorker := func(io.ReadCloser, string, chan Frame) Worker {
 		return workerMock
 	}
 
 	frameChannel := make(chan Frame)
-	reader := newReader(address, configMock, frameChannel, newWorker)
+	reader := newReader([]Endpoint{{Network: UnixNetwork, Address: address}}, configMock, frameChannel, newWorker)
 	ctx := context.Background()
 
 	configMock.EXPECT().Listen(gomock.Any(), "unix", address).Return(listenerMock, nil)
@@ -88,3 +91,139 @@ func TestReaderShouldStopWorkersOnAcceptError(t *testing.T) {
 	_, ok := <-frameChannel
 	assert.False(t, ok)
 }
+
+func TestReaderShouldStopListeningEndpointsOnListenError(t *testing.T) {
+	const dummyError = "address already in use"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	configMock := mocks.NewMockListenerConfig(ctrl)
+	listenerMock := mocks.NewMockListener(ctrl)
+	newWorker := func(io.ReadCloser, string, chan Frame) Worker {
+		return mocks.NewMockWorker(ctrl)
+	}
+
+	endpoints := []Endpoint{
+		{Network: UnixNetwork, Address: address, Source: "nginx"},
+		{Network: TCPNetwork, Address: "127.0.0.1:9000", Source: "sidecar"},
+	}
+	frameChannel := make(chan Frame)
+	reader := newReader(endpoints, configMock, frameChannel, newWorker)
+
+	configMock.EXPECT().Listen(gomock.Any(), "unix", address).Return(listenerMock, nil)
+	configMock.EXPECT().Listen(gomock.Any(), "tcp", "127.0.0.1:9000").Return(nil, errors.New(dummyError))
+
+	accepting := make(chan struct{})
+	listenerMock.EXPECT().Accept().Return(nil, net.ErrClosed).Do(func() {
+		<-accepting
+	})
+	listenerMock.EXPECT().Close().Do(func() {
+		close(accepting)
+	}).Return(nil)
+
+	assert.EqualError(t, reader.Run(context.Background()), fmt.Sprintf("failed to start advanced metrics listener on tcp 127.0.0.1:9000: %s", dummyError))
+
+	_, ok := <-frameChannel
+	assert.False(t, ok)
+}
+
+func TestReaderShouldReceiveFramesOnUDPEndpoint(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	configMock := mocks.NewMockListenerConfig(ctrl)
+	configMock.EXPECT().ListenPacket(gomock.Any(), "udp", "127.0.0.1:9000").Return(conn, nil)
+
+	frameChannel := make(chan Frame)
+	reader := newReader([]Endpoint{{Network: UDPNetwork, Address: "127.0.0.1:9000", Source: "sidecar"}}, configMock, frameChannel, func(connection io.ReadCloser, source string, frameChannel chan Frame) Worker {
+		return newWorker(connection, source, frameChannel)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- reader.Run(ctx)
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	_, err = client.Write([]byte("data1;data2;"))
+	require.NoError(t, err)
+
+	select {
+	case frame := <-frameChannel:
+		assert.Equal(t, [][]byte{[]byte("data1"), []byte("data2")}, frame.Messages())
+		assert.Equal(t, "sidecar", frame.Source())
+		frame.Release()
+	case <-time.After(time.Second):
+		assert.Fail(t, "no frame received")
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	_, ok := <-frameChannel
+	assert.False(t, ok)
+}
+
+func TestReaderShouldDropUnterminatedUDPMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	configMock := mocks.NewMockListenerConfig(ctrl)
+	configMock.EXPECT().ListenPacket(gomock.Any(), "udp", "127.0.0.1:9000").Return(conn, nil)
+
+	frameChannel := make(chan Frame)
+	reader := newReader([]Endpoint{{Network: UDPNetwork, Address: "127.0.0.1:9000", Source: "sidecar"}}, configMock, frameChannel, func(connection io.ReadCloser, source string, frameChannel chan Frame) Worker {
+		return newWorker(connection, source, frameChannel)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- reader.Run(ctx)
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	// the unterminated datagram and message are not joined with the data of the next datagrams
+	for _, datagram := range []string{"unterminated", "data1;data2;partial", "data3;"} {
+		_, err = client.Write([]byte(datagram))
+		require.NoError(t, err)
+	}
+
+	for _, expected := range [][][]byte{
+		{[]byte("data1"), []byte("data2")},
+		{[]byte("data3")},
+	} {
+		select {
+		case frame := <-frameChannel:
+			assert.Equal(t, expected, frame.Messages())
+			frame.Release()
+		case <-time.After(time.Second):
+			assert.Fail(t, "no frame received")
+		}
+	}
+
+	select {
+	case err := <-done:
+		assert.Fail(t, "reader stopped", "%v", err)
+	default:
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	_, ok := <-frameChannel
+	assert.False(t, ok)
+}
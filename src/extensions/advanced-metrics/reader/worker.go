@@ -25,15 +25,17 @@ import (
 const maxWorkerBufferSize = 1024 * 64
 
 type worker struct {
-	conn         net.Conn
+	conn         io.ReadCloser
+	source       string
 	frameChannel chan Frame
 
 	maxBufferSize int
 }
 
-func newWorker(conn net.Conn, frameChannel chan Frame) *worker {
+func newWorker(conn io.ReadCloser, source string, frameChannel chan Frame) *worker {
 	return &worker{
 		conn:         conn,
+		source:       source,
 		frameChannel: frameChannel,
 
 		maxBufferSize: maxWorkerBufferSize,
@@ -108,6 +110,7 @@ func (w *worker) readLoop(ctx context.Context) error {
 		frame := &frame{
 			buffer:    buffer,
 			frameSize: frameSize,
+			source:    w.source,
 			release:   releaseFunction,
 		}
 		select {
@@ -34,7 +34,7 @@ func TestWorkerStopAndCloseConnectionOnContexCancelation(t *testing.T) {
 	}).Return(0, net.ErrClosed)
 
 	outChannel := make(chan Frame)
-	worker := newWorker(connMock, outChannel)
+	worker := newWorker(connMock, "", outChannel)
 
 	err := worker.Run(ctx)
 	assert.NoError(t, err)
@@ -52,7 +52,7 @@ func TestWorkerStopAndCloseConnectionOnReadError(t *testing.T) {
 	connMock.EXPECT().Read(gomock.Any()).Return(0, errors.New(error))
 
 	outChannel := make(chan Frame)
-	worker := newWorker(connMock, outChannel)
+	worker := newWorker(connMock, "", outChannel)
 
 	err := worker.Run(ctx)
 	assert.EqualError(t, err, fmt.Sprintf("fail to read data: %s", error))
@@ -206,7 +206,7 @@ func TestWorkerFrameProcessing(t *testing.T) {
 			})
 
 			outChannel := make(chan Frame)
-			worker := newWorker(connMock, outChannel)
+			worker := newWorker(connMock, "", outChannel)
 			if test.receiverBufferSize != 0 {
 				worker.maxBufferSize = test.receiverBufferSize
 			}
@@ -226,6 +226,38 @@ func TestWorkerFrameProcessing(t *testing.T) {
 	}
 }
 
+func TestWorkerFrameSource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	connMock := mocks.NewMockConn(ctrl)
+	connMock.EXPECT().Close().Return(nil)
+	connMock.EXPECT().Read(gomock.Any()).DoAndReturn(func(buf []byte) (int, error) {
+		return copy(buf, "data;"), nil
+	})
+	connMock.EXPECT().Read(gomock.Any()).Return(0, net.ErrClosed).Do(func(buf []byte) {
+		<-ctx.Done()
+	})
+
+	outChannel := make(chan Frame)
+	worker := newWorker(connMock, "tenant-a", outChannel)
+
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, worker.Run(ctx))
+		done <- struct{}{}
+	}()
+
+	frame := <-outChannel
+	assert.Equal(t, "tenant-a", frame.Source())
+	frame.Release()
+
+	cancel()
+	<-done
+}
+
 func TestWorkerFrameProcessingErrorWhenMessageExceededBufferSize(t *testing.T) {
 	const maxBufferSize = 12
 
@@ -241,7 +273,7 @@ func TestWorkerFrameProcessingErrorWhenMessageExceededBufferSize(t *testing.T) {
 	connMock.EXPECT().Read(gomock.Any()).Return(maxBufferSize/2, nil)
 
 	outChannel := make(chan Frame)
-	worker := newWorker(connMock, outChannel)
+	worker := newWorker(connMock, "", outChannel)
 	worker.maxBufferSize = maxBufferSize
 
 	assert.EqualError(t, worker.Run(ctx), fmt.Sprintf("fail to process frames, data exceeded buffer size: %d", maxBufferSize))
@@ -308,7 +340,7 @@ func BenchmarkWorkerFrameProcessing(b *testing.B) {
 				FramePosition: test.framePosition,
 				Separator:     frameSeparatorByte,
 			}
-			worker := newWorker(stub, outChannel)
+			worker := newWorker(stub, "", outChannel)
 
 			ctx := context.Background()
 			go func() {
@@ -0,0 +1,123 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package priority_table
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// countersPerHeavyHitter is the number of counters of the sketch per tracked value.
+// More counters lower the estimation error of the hit counts of the tracked values.
+const countersPerHeavyHitter = 4
+
+// HeavyHitter is a dimension value tracked in the heavy hitters mode.
+// Count is the estimated hit count of the value, which is overestimated by at most Error.
+type HeavyHitter struct {
+	Code  int
+	Count int
+	Error int
+}
+
+type counter struct {
+	HeavyHitter
+	heapIndex int
+}
+
+// spaceSaving implements the Space-Saving algorithm which finds the most frequent dimension values
+// with a fixed number of counters. A value which is not tracked replaces the value with the
+// lowest count and inherits its count as the estimation error.
+type spaceSaving struct {
+	k        int
+	counters counterQueue
+	codes    map[int]*counter
+}
+
+func newSpaceSaving(k int) *spaceSaving {
+	return &spaceSaving{
+		k:        k,
+		counters: make(counterQueue, 0, k*countersPerHeavyHitter),
+		codes:    make(map[int]*counter, k*countersPerHeavyHitter),
+	}
+}
+
+// Add adds hit count to the counter of the dimension value
+func (s *spaceSaving) Add(code int, hitCount int) {
+	if c, ok := s.codes[code]; ok {
+		c.Count += hitCount
+		heap.Fix(&s.counters, c.heapIndex)
+		return
+	}
+
+	if s.counters.Len() < cap(s.counters) {
+		c := &counter{HeavyHitter: HeavyHitter{Code: code, Count: hitCount}}
+		heap.Push(&s.counters, c)
+		s.codes[code] = c
+		return
+	}
+
+	c := s.counters[0]
+	delete(s.codes, c.Code)
+	c.Code = code
+	c.Error = c.Count
+	c.Count += hitCount
+	s.codes[code] = c
+	heap.Fix(&s.counters, 0)
+}
+
+// TopK returns the k values with the highest estimated hit count, ordered by the hit count
+func (s *spaceSaving) TopK() []HeavyHitter {
+	heavyHitters := make([]HeavyHitter, 0, s.counters.Len())
+	for _, c := range s.counters {
+		heavyHitters = append(heavyHitters, c.HeavyHitter)
+	}
+	sort.Slice(heavyHitters, func(i, j int) bool {
+		if heavyHitters[i].Count == heavyHitters[j].Count {
+			return heavyHitters[i].Code < heavyHitters[j].Code
+		}
+		return heavyHitters[i].Count > heavyHitters[j].Count
+	})
+
+	if len(heavyHitters) > s.k {
+		heavyHitters = heavyHitters[:s.k]
+	}
+	return heavyHitters
+}
+
+// counterQueue is a min heap of the counters ordered by the hit count
+type counterQueue []*counter
+
+var _ heap.Interface = &counterQueue{}
+
+func (q counterQueue) Len() int {
+	return len(q)
+}
+
+func (q counterQueue) Less(i, j int) bool {
+	return q[i].Count < q[j].Count
+}
+
+func (q counterQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].heapIndex = i
+	q[j].heapIndex = j
+}
+
+func (q *counterQueue) Pop() interface{} {
+	o := *q
+	n := len(o)
+	x := o[n-1]
+	*q = o[0 : n-1]
+	return x
+}
+
+func (q *counterQueue) Push(c interface{}) {
+	counter := c.(*counter)
+	counter.heapIndex = len(*q)
+	*q = append(*q, counter)
+}
@@ -0,0 +1,64 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package priority_table
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpaceSaving(t *testing.T) {
+	tests := []struct {
+		name     string
+		k        int
+		hits     [][2]int
+		expected []HeavyHitter
+	}{
+		{
+			name:     "no values",
+			k:        2,
+			expected: []HeavyHitter{},
+		},
+		{
+			name: "exact counts below capacity",
+			k:    2,
+			hits: [][2]int{{10, 5}, {11, 1}, {10, 5}, {12, 3}},
+			expected: []HeavyHitter{
+				{Code: 10, Count: 10},
+				{Code: 12, Count: 3},
+			},
+		},
+		{
+			name: "long tail replaces the lowest counter",
+			k:    1,
+			hits: [][2]int{{10, 100}, {11, 1}, {12, 1}, {13, 1}, {14, 1}, {15, 2}},
+			expected: []HeavyHitter{
+				{Code: 10, Count: 100},
+			},
+		},
+		{
+			name: "heavy hitter arriving late is tracked",
+			k:    1,
+			hits: [][2]int{{11, 1}, {12, 1}, {13, 1}, {14, 1}, {15, 1}, {16, 1}, {10, 50}},
+			expected: []HeavyHitter{
+				{Code: 10, Count: 51, Error: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sketch := newSpaceSaving(tt.k)
+			for _, hit := range tt.hits {
+				sketch.Add(hit[0], hit[1])
+			}
+			assert.Equal(t, tt.expected, sketch.TopK())
+		})
+	}
+}
@@ -20,22 +20,44 @@ import (
 // PriorityTable represents set of samples with limited size.
 // Samples added to the table are ordered by the hitcount of the sample.
 // Priority determines which samples dimensions will be aggregated.
+//
+// Dimensions in the heavy hitters mode track the hit count of their values in a sketch. When the table
+// is collapsed, values outside of the TopK values of the dimension are collapsed first, and the TopK
+// values are never collapsed.
 type PriorityTable struct {
-	samples map[string]*sample.Sample
+	samples      map[string]*sample.Sample
+	heavyHitters map[schema.FieldIndex]*spaceSaving
 
 	schema *schema.Schema
 	limits limits.Limits
 }
 
-func NewPriorityTable(schema *schema.Schema, limits limits.Limits) *PriorityTable {
+func NewPriorityTable(s *schema.Schema, limits limits.Limits) *PriorityTable {
+	heavyHitters := map[schema.FieldIndex]*spaceSaving{}
+	for _, dim := range s.Dimensions() {
+		if dim.IsHeavyHitter() {
+			heavyHitters[dim.Index()] = newSpaceSaving(int(dim.TopK))
+		}
+	}
+
 	return &PriorityTable{
-		samples: map[string]*sample.Sample{},
-		schema:  schema,
-		limits:  limits,
+		samples:      map[string]*sample.Sample{},
+		heavyHitters: heavyHitters,
+		schema:       s,
+		limits:       limits,
 	}
 }
 
 func (p *PriorityTable) Add(s *sample.Sample) error {
+	if len(p.heavyHitters) > 0 {
+		codes := s.Key().GetKeyParts(p.schema.DimensionKeyPartSizes())
+		for index, sketch := range p.heavyHitters {
+			// values collapsed by the staging table can't be tracked
+			if codes[index] != lookup.LookupAggrCode {
+				sketch.Add(codes[index], s.HitCount())
+			}
+		}
+	}
 	return addSampleToTable(s, p.samples)
 }
 
@@ -56,6 +78,17 @@ func (p *PriorityTable) CollapseSamples() error {
 
 	log.Debugf("Collapsing priority table. Size of table before collapsing: %d", len(p.samples))
 
+	if len(p.heavyHitters) > 0 {
+		err := p.collapseLongTail()
+		if err != nil {
+			return err
+		}
+		if !p.shouldCollapseSamples() {
+			log.Debugf("Collapsing priority table. Size of table after collapsing long tail: %d", len(p.samples))
+			return nil
+		}
+	}
+
 	collapseLevel := p.limits.GetCurrentCollapsingLevel(len(p.samples))
 	newSamples := make(map[string]*sample.Sample, len(p.samples))
 	priorityQueue := sampleQueue{}
@@ -96,12 +129,49 @@ func (p *PriorityTable) CollapseSamples() error {
 	return nil
 }
 
+// collapseLongTail collapses the values of the heavy hitters dimensions which are not in the TopK values
+func (p *PriorityTable) collapseLongTail() error {
+	topK := p.topKCodes()
+	newSamples := make(map[string]*sample.Sample, len(p.samples))
+	for _, s := range p.samples {
+		codes := s.Key().GetKeyParts(p.schema.DimensionKeyPartSizes())
+		for index, tracked := range topK {
+			if _, ok := tracked[codes[index]]; !ok {
+				dim := p.schema.Dimension(index)
+				s.Key().SetKeyPart(lookup.LookupAggrCode, dim.KeyBitSize, dim.KeyBitPositionInCompoundKey)
+			}
+		}
+		err := addSampleToTable(s, newSamples)
+		if err != nil {
+			return err
+		}
+	}
+	p.samples = newSamples
+	return nil
+}
+
+func (p *PriorityTable) topKCodes() map[schema.FieldIndex]map[int]struct{} {
+	topK := make(map[schema.FieldIndex]map[int]struct{}, len(p.heavyHitters))
+	for index, sketch := range p.heavyHitters {
+		codes := map[int]struct{}{}
+		for _, heavyHitter := range sketch.TopK() {
+			codes[heavyHitter.Code] = struct{}{}
+		}
+		topK[index] = codes
+	}
+	return topK
+}
+
 func (p *PriorityTable) shouldCollapseSamples() bool {
 	return len(p.samples) > p.limits.Threshold()
 }
 
 func (p *PriorityTable) collapseSample(sample *sample.Sample, currentCollapseLevel limits.CollapsingLevel) {
 	for _, dim := range p.schema.Dimensions() {
+		// the long tail of heavy hitters dimensions is already collapsed
+		if dim.IsHeavyHitter() {
+			continue
+		}
 		if dim.ShouldCollapse(currentCollapseLevel) {
 			sample.Key().SetKeyPart(lookup.LookupAggrCode, dim.KeyBitSize, dim.KeyBitPositionInCompoundKey)
 		}
@@ -111,3 +181,12 @@ func (p *PriorityTable) collapseSample(sample *sample.Sample, currentCollapseLev
 func (p *PriorityTable) Samples() map[string]*sample.Sample {
 	return p.samples
 }
+
+// HeavyHitters returns the TopK values tracked for each dimension in the heavy hitters mode
+func (p *PriorityTable) HeavyHitters() map[schema.FieldIndex][]HeavyHitter {
+	heavyHitters := make(map[schema.FieldIndex][]HeavyHitter, len(p.heavyHitters))
+	for index, sketch := range p.heavyHitters {
+		heavyHitters[index] = sketch.TopK()
+	}
+	return heavyHitters
+}
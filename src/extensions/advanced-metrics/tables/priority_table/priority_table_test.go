@@ -175,6 +175,107 @@ func TestPriorityTableWithCollapsing(t *testing.T) {
 	}
 }
 
+func TestPriorityTableWithHeavyHitters(t *testing.T) {
+	testSchema := schema.NewSchema([]*schema.Field{
+		schema.NewDimensionField("dim1", 0xff, schema.WithTopK(2)),
+		schema.NewDimensionField("dim2", 0xff, schema.WithLevel(1)),
+		schema.NewMetricField("m1"),
+	}...)
+	testSingleMetric := []sample.Metric{{Count: 1, Last: 1, Min: 1, Max: 1, Sum: 1}}
+
+	tests := []struct {
+		name              string
+		inputSamples      []*sample.Sample
+		limits            limits.Limits
+		expectedHitCounts map[[2]int]int
+	}{
+		{
+			name: "should not collapse samples below threshold",
+			inputSamples: []*sample.Sample{
+				testSample(t, testSingleMetric, []int{10, 1}, 50),
+				testSample(t, testSingleMetric, []int{12, 1}, 1),
+			},
+			limits: testLimits(t, 10, 3),
+			expectedHitCounts: map[[2]int]int{
+				{10, 1}: 50,
+				{12, 1}: 1,
+			},
+		},
+		{
+			name: "should collapse only long tail of heavy hitters dimension",
+			inputSamples: []*sample.Sample{
+				testSample(t, testSingleMetric, []int{10, 1}, 50),
+				testSample(t, testSingleMetric, []int{10, 2}, 40),
+				testSample(t, testSingleMetric, []int{11, 1}, 30),
+				testSample(t, testSingleMetric, []int{12, 1}, 1),
+				testSample(t, testSingleMetric, []int{13, 1}, 1),
+			},
+			limits: testLimits(t, 10, 4),
+			expectedHitCounts: map[[2]int]int{
+				{10, 1}:                    50,
+				{10, 2}:                    40,
+				{11, 1}:                    30,
+				{lookup.LookupAggrCode, 1}: 2,
+			},
+		},
+		{
+			name: "should collapse other dimensions of long tail above threshold",
+			inputSamples: []*sample.Sample{
+				testSample(t, testSingleMetric, []int{10, 1}, 50),
+				testSample(t, testSingleMetric, []int{10, 2}, 40),
+				testSample(t, testSingleMetric, []int{11, 1}, 30),
+				testSample(t, testSingleMetric, []int{12, 1}, 1),
+				testSample(t, testSingleMetric, []int{13, 1}, 1),
+				testSample(t, testSingleMetric, []int{14, 2}, 1),
+			},
+			limits: testLimits(t, 10, 3),
+			expectedHitCounts: map[[2]int]int{
+				{10, 1}: 50,
+				{10, 2}: 40,
+				{11, 1}: 30,
+				{lookup.LookupAggrCode, lookup.LookupAggrCode}: 3,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			table := NewPriorityTable(testSchema, test.limits)
+			for _, s := range test.inputSamples {
+				assert.NoError(t, table.Add(s))
+			}
+			assert.NoError(t, table.CollapseSamples())
+
+			hitCounts := map[[2]int]int{}
+			for _, s := range table.Samples() {
+				codes := s.Key().GetKeyParts(testSchema.DimensionKeyPartSizes())
+				hitCounts[[2]int{codes[0], codes[1]}] = s.HitCount()
+			}
+			assert.Equal(t, test.expectedHitCounts, hitCounts)
+		})
+	}
+}
+
+func TestPriorityTableHeavyHitters(t *testing.T) {
+	testSchema := schema.NewSchema([]*schema.Field{
+		schema.NewDimensionField("dim1", 0xff),
+		schema.NewDimensionField("dim2", 0xff, schema.WithTopK(2)),
+		schema.NewMetricField("m1"),
+	}...)
+	testSingleMetric := []sample.Metric{{Count: 1, Last: 1, Min: 1, Max: 1, Sum: 1}}
+
+	table := NewPriorityTable(testSchema, testLimits(t, 10, 5))
+	assert.NoError(t, table.Add(testSample(t, testSingleMetric, []int{10, 20}, 5)))
+	assert.NoError(t, table.Add(testSample(t, testSingleMetric, []int{11, 20}, 5)))
+	assert.NoError(t, table.Add(testSample(t, testSingleMetric, []int{10, 21}, 3)))
+	assert.NoError(t, table.Add(testSample(t, testSingleMetric, []int{10, 22}, 1)))
+	assert.NoError(t, table.Add(testSample(t, testSingleMetric, []int{10, lookup.LookupAggrCode}, 100)))
+
+	assert.Equal(t, map[schema.FieldIndex][]HeavyHitter{
+		1: {{Code: 20, Count: 10}, {Code: 21, Count: 3}},
+	}, table.HeavyHitters())
+}
+
 func testSample(t *testing.T, metrics []sample.Metric, dimensionsCodes []int, hitcount int) *sample.Sample {
 	s := sample.NewSample(len(dimensionsCodes)*8, len(metrics))
 	s.AddHitCount(hitcount - 1)
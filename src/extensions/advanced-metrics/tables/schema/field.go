@@ -22,12 +22,24 @@ const (
 
 type FieldIndex = int
 
+// MetricAggregation defines which of the aggregated values of a metric is published
+type MetricAggregation string
+
+const (
+	AggregationNone  MetricAggregation = "none"
+	AggregationCount MetricAggregation = "count"
+	AggregationSum   MetricAggregation = "sum"
+	AggregationMin   MetricAggregation = "min"
+	AggregationMax   MetricAggregation = "max"
+)
+
 // Field defines attributes of input field for StagingTable
 type Field struct {
 	Name string
 	Type FieldType
 
 	DimensionField
+	MetricField
 
 	index FieldIndex
 }
@@ -37,12 +49,22 @@ type Field struct {
 // MaxDimensionSetSize specifies max unique dimension which will be stored in staging table
 //
 //	if max size will be reaches all new unique dimensions will be transformed to AGGR value
+//
+// TopK enables the heavy hitters mode of the dimension, the TopK values with the highest hit count
+// are not collapsed by the priority table
 type DimensionField struct {
 	KeyBitSize                  int
 	KeyBitPositionInCompoundKey int
 	MaxDimensionSetSize         uint32
 	Transform                   *DimensionTransformFunction
 	CollapsingLevel             *limits.CollapsingLevel
+	TopK                        uint32
+}
+
+// MetricField defines metric field specific information
+// Aggregation specifies the aggregated value of the metric which is published
+type MetricField struct {
+	Aggregation MetricAggregation
 }
 
 type FieldOption func(f *Field)
@@ -64,6 +86,14 @@ func WithLevel(level limits.CollapsingLevel) FieldOption {
 	return func(f *Field) { f.CollapsingLevel = &level }
 }
 
+func WithTopK(k uint32) FieldOption {
+	return func(f *Field) { f.TopK = k }
+}
+
+func WithAggregation(aggregation MetricAggregation) FieldOption {
+	return func(f *Field) { f.Aggregation = aggregation }
+}
+
 func NewDimensionField(name string, maxDimensionSetSize uint32, opts ...FieldOption) *Field {
 	f := &Field{
 		Name: name,
@@ -82,11 +112,15 @@ func NewDimensionField(name string, maxDimensionSetSize uint32, opts ...FieldOpt
 	return f
 }
 
-func NewMetricField(name string) *Field {
-	return &Field{
+func NewMetricField(name string, opts ...FieldOption) *Field {
+	f := &Field{
 		Name: name,
 		Type: FieldTypeMetric,
 	}
+	for _, o := range opts {
+		o(f)
+	}
+	return f
 }
 
 func (f *Field) Index() FieldIndex {
@@ -96,3 +130,7 @@ func (f *Field) Index() FieldIndex {
 func (f *Field) ShouldCollapse(level limits.CollapsingLevel) bool {
 	return f.CollapsingLevel != nil && level > *f.CollapsingLevel
 }
+
+func (f *Field) IsHeavyHitter() bool {
+	return f.TopK > 0
+}
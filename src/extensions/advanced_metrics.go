@@ -9,6 +9,7 @@ package extensions
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"time"
 
@@ -82,22 +83,66 @@ const (
 	gatewayNameDimension               = "gateway_name"
 	siteDimension                      = "site"
 	siteNameDimension                  = "site_name"
+	// dimensions and metrics of the values tracked for the dimensions in the heavy hitters mode
+	heavyHitterDimensionDimension = "heavy_hitter.dimension"
+	heavyHitterValueDimension     = "heavy_hitter.value"
+	heavyHitterCountMetric        = "heavy_hitter.count"
+	heavyHitterErrorMetric        = "heavy_hitter.error"
 )
 
-var maxOnlyMetrics = map[string]struct{}{
-	clientNetworkLatencyMetric:    {},
-	clientTtfbLatencyMetric:       {},
-	clientRequestLatencyMetric:    {},
-	clientResponseLatencyMetric:   {},
-	upstreamNetworkLatencyMetric:  {},
-	upstreamHeaderLatencyMetric:   {},
-	upstreamResponseLatencyMetric: {},
-}
-
-var totalOnlyMetrics = map[string]struct{}{
-	bytesRcvdMetric:          {},
-	bytesSentMetric:          {},
-	connectionDurationMetric: {},
+// defaultSchema is the schema of the samples sent by the NGINX metrics module, it's used if
+// no schema is configured
+var defaultSchema = []schema.FieldConfig{
+	{Name: httpUriDimension, Type: schema.FieldTypeDimension, Cardinality: 16000},
+	{Name: httpResponseCodeDimension, Type: schema.FieldTypeIntegerDimension, Cardinality: 600},
+	{Name: httpRequestMethodDimension, Type: schema.FieldTypeDimension, Cardinality: 16},
+	{Name: hitcountMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationCount},
+	{Name: httpRequestBytesRcvdMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationNone},
+	{Name: httpRequestBytesSentMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationNone},
+	{Name: environmentDimension, Type: schema.FieldTypeDimension, Cardinality: 32},
+	{Name: appDimension, Type: schema.FieldTypeDimension, Cardinality: 32},
+	{Name: componentDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: acmInfraWorkspacesNameDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: acmServiceWorkspacesNameDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: acmEnvironmentsNameDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: acmEnvironmentsTypeDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: acmApiProxyNameDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: acmApiProxyHostnameDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: acmProxyApiVersionDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: countryCodeDimension, Type: schema.FieldTypeDimension, Cardinality: 256}, // TODO should be implemented as GeoIP
+	{Name: httpVersionSchemaDimension, Type: schema.FieldTypeDimension, Cardinality: 16},
+	{Name: httpUpstreamAddrDimension, Type: schema.FieldTypeDimension, Cardinality: 1024},
+	{Name: upstreamResponseCodeDimension, Type: schema.FieldTypeIntegerDimension, Cardinality: 600},
+	{Name: httpHostnameDimension, Type: schema.FieldTypeDimension, Cardinality: 16000},
+	{Name: clientNetworkLatencyMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationMax},
+	{Name: clientTtfbLatencyMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationMax},
+	{Name: clientRequestLatencyMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationMax},
+	{Name: clientResponseLatencyMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationMax},
+	{Name: upstreamNetworkLatencyMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationMax},
+	{Name: upstreamHeaderLatencyMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationMax},
+	{Name: upstreamResponseLatencyMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationMax},
+	{Name: publishedApiDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: requestOutcomeDimension, Type: schema.FieldTypeDimension, Cardinality: 8},
+	{Name: requestOutcomeReasonDimension, Type: schema.FieldTypeDimension, Cardinality: 32},
+	{Name: gatewayDimension, Type: schema.FieldTypeDimension, Cardinality: 32},
+	{Name: wafSignatureIdsDimension, Type: schema.FieldTypeDimension, Cardinality: 16000},
+	{Name: wafAttackTypesDimension, Type: schema.FieldTypeDimension, Cardinality: 8},
+	{Name: wafViolationRatingDimension, Type: schema.FieldTypeDimension, Cardinality: 8},
+	{Name: wafViolationsDimension, Type: schema.FieldTypeDimension, Cardinality: 128},
+	{Name: wafViolationSubviolationsDimension, Type: schema.FieldTypeDimension, Cardinality: 16},
+	{Name: clientLatencyMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationNone},
+	{Name: upstreamLatencyMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationNone},
+	{Name: connectionDurationMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationSum},
+	{Name: familyDimension, Type: schema.FieldTypeDimension, Cardinality: 4},
+	{Name: proxiedProtocolDimension, Type: schema.FieldTypeDimension, Cardinality: 4},
+	{Name: bytesRcvdMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationSum},
+	{Name: bytesSentMetric, Type: schema.FieldTypeMetric, Aggregation: schema.AggregationSum},
+	{Name: environmentNameDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: appNameDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: componentNameDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: gatewayNameDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
+	{Name: siteDimension, Type: schema.FieldTypeDimension, Cardinality: 32},
+	{Name: siteNameDimension, Type: schema.FieldTypeDimension, Cardinality: 256},
 }
 
 var advancedMetricsDefaults = &AdvancedMetricsConfig{
@@ -110,11 +155,15 @@ var advancedMetricsDefaults = &AdvancedMetricsConfig{
 		PriorityTableThreshold: 1000,
 		PriorityTableMaxSize:   1000,
 	},
+	Schema: defaultSchema,
 }
 
 const (
 	httpMetricPrefix   = "http.request"
 	streamMetricPrefix = "stream"
+
+	// minSourceCardinality is the minimum cardinality of the source dimension allowed by the schema
+	minSourceCardinality = 4
 )
 
 type AdvancedMetricsConfig struct {
@@ -122,6 +171,10 @@ type AdvancedMetricsConfig struct {
 	AggregationPeriod time.Duration                     `mapstructure:"aggregation_period"`
 	PublishingPeriod  time.Duration                     `mapstructure:"publishing_period"`
 	TableSizesLimits  advanced_metrics.TableSizesLimits `mapstructure:"table_sizes_limits"`
+	// Schema lists the dimensions and metrics in the order of the columns sent by the NGINX metrics module
+	Schema []schema.FieldConfig `mapstructure:"schema"`
+	// Listeners replace the socket_path, the samples they receive are labeled with the source dimension
+	Listeners []advanced_metrics.ListenerConfig `mapstructure:"listeners"`
 }
 
 type AdvancedMetrics struct {
@@ -133,72 +186,36 @@ type AdvancedMetrics struct {
 	commonDims       *metrics.CommonDim
 }
 
-func NewAdvancedMetrics(env core.Environment, conf *config.Config, advancedMetricsConf interface{}) *AdvancedMetrics {
-	builder := schema.NewSchemaBuilder()
-	builder.NewDimension(httpUriDimension, 16000).
-		NewIntegerDimension(httpResponseCodeDimension, 600).
-		NewDimension(httpRequestMethodDimension, 16).
-		NewMetric(hitcountMetric).
-		NewMetric(httpRequestBytesRcvdMetric).
-		NewMetric(httpRequestBytesSentMetric).
-		NewDimension(environmentDimension, 32).
-		NewDimension(appDimension, 32).
-		NewDimension(componentDimension, 256).
-		NewDimension(acmInfraWorkspacesNameDimension, 256).
-		NewDimension(acmServiceWorkspacesNameDimension, 256).
-		NewDimension(acmEnvironmentsNameDimension, 256).
-		NewDimension(acmEnvironmentsTypeDimension, 256).
-		NewDimension(acmApiProxyNameDimension, 256).
-		NewDimension(acmApiProxyHostnameDimension, 256).
-		NewDimension(acmProxyApiVersionDimension, 256).
-		NewDimension(countryCodeDimension, 256). // TODO should be implemented as GeoIP
-		NewDimension(httpVersionSchemaDimension, 16).
-		NewDimension(httpUpstreamAddrDimension, 1024).
-		NewIntegerDimension(upstreamResponseCodeDimension, 600).
-		NewDimension(httpHostnameDimension, 16000).
-		NewMetric(clientNetworkLatencyMetric).
-		NewMetric(clientTtfbLatencyMetric).
-		NewMetric(clientRequestLatencyMetric).
-		NewMetric(clientResponseLatencyMetric).
-		NewMetric(upstreamNetworkLatencyMetric).
-		NewMetric(upstreamHeaderLatencyMetric).
-		NewMetric(upstreamResponseLatencyMetric).
-		NewDimension(publishedApiDimension, 256).
-		NewDimension(requestOutcomeDimension, 8).
-		NewDimension(requestOutcomeReasonDimension, 32).
-		NewDimension(gatewayDimension, 32).
-		NewDimension(wafSignatureIdsDimension, 16000).
-		NewDimension(wafAttackTypesDimension, 8).
-		NewDimension(wafViolationRatingDimension, 8).
-		NewDimension(wafViolationsDimension, 128).
-		NewDimension(wafViolationSubviolationsDimension, 16).
-		NewMetric(clientLatencyMetric).
-		NewMetric(upstreamLatencyMetric).
-		NewMetric(connectionDurationMetric).
-		NewDimension(familyDimension, 4).
-		NewDimension(proxiedProtocolDimension, 4).
-		NewMetric(bytesRcvdMetric).
-		NewMetric(bytesSentMetric).
-		NewDimension(environmentNameDimension, 256).
-		NewDimension(appNameDimension, 256).
-		NewDimension(componentNameDimension, 256).
-		NewDimension(gatewayNameDimension, 256).
-		NewDimension(siteDimension, 32).
-		NewDimension(siteNameDimension, 256)
-
+func NewAdvancedMetrics(env core.Environment, conf *config.Config, advancedMetricsConf interface{}) (*AdvancedMetrics, error) {
 	advancedMetricsConfig := advancedMetricsDefaults
 
 	if advancedMetricsConf != nil {
 		var err error
 		advancedMetricsConfig, err = agent_config.DecodeConfig[*AdvancedMetricsConfig](advancedMetricsConf)
 		if err != nil {
-			log.Errorf("Error decoding configuration for extension plugin %s, %v", AdvancedMetricsPluginName, err)
-			return nil
+			return nil, fmt.Errorf("error decoding configuration for extension plugin %s, %v", AdvancedMetricsPluginName, err)
 		}
 	}
 
+	fields := advancedMetricsConfig.Schema
+	if len(fields) == 0 {
+		fields = defaultSchema
+	}
+	if len(advancedMetricsConfig.Listeners) > 0 {
+		fields = append(fields[:len(fields):len(fields)], schema.FieldConfig{
+			Name:        advanced_metrics.SourceDimension,
+			Type:        schema.FieldTypeDimension,
+			Cardinality: uint32(max(len(advancedMetricsConfig.Listeners), minSourceCardinality)),
+		})
+	}
+	builder, err := schema.NewSchemaBuilderFromConfig(fields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema for extension plugin %s, %v", AdvancedMetricsPluginName, err)
+	}
+
 	cfg := advanced_metrics.Config{
-		Address: advancedMetricsConfig.SocketPath,
+		Address:   advancedMetricsConfig.SocketPath,
+		Listeners: advancedMetricsConfig.Listeners,
 		AggregatorConfig: advanced_metrics.AggregatorConfig{
 			AggregationPeriod: advancedMetricsConfig.AggregationPeriod,
 			PublishingPeriod:  advancedMetricsConfig.PublishingPeriod,
@@ -210,18 +227,18 @@ func NewAdvancedMetrics(env core.Environment, conf *config.Config, advancedMetri
 
 	schema, err := builder.Build()
 	if err != nil {
-		log.Warnf("Unable to build schema for Advanced Metrics %v", err)
+		return nil, fmt.Errorf("unable to build schema for extension plugin %s, %v", AdvancedMetricsPluginName, err)
 	}
 	app, err := advanced_metrics.NewAdvancedMetrics(cfg, schema)
 	if err != nil {
-		log.Warnf("Unable to initiate advanced metrics module %v", err)
+		return nil, fmt.Errorf("unable to initiate extension plugin %s, %v", AdvancedMetricsPluginName, err)
 	}
 
 	return &AdvancedMetrics{
 		cfg:              cfg,
 		advanced_metrics: app,
 		commonDims:       metrics.NewCommonDim(env.NewHostInfo("agentVersion", &conf.Tags, conf.ConfigDirs, false), conf, ""),
-	}
+	}, nil
 }
 
 func (m *AdvancedMetrics) Init(pipeline core.MessagePipeInterface) {
@@ -259,9 +276,11 @@ func (m *AdvancedMetrics) run() {
 		}
 	}()
 	defer m.ctxCancel()
-	err := core.EnableWritePermissionForSocket(m.cfg.Address)
-	if err != nil {
-		log.Error("App centric metric plugin failed to change socket permissions")
+	for _, socket := range unixSockets(m.cfg) {
+		err := core.EnableWritePermissionForSocket(socket)
+		if err != nil {
+			log.Errorf("App centric metric plugin failed to change socket permissions of %s", socket)
+		}
 	}
 	commonDimensions := append(m.commonDims.ToDimensions(), &proto.Dimension{
 		Name:  aggregationDurationDimension,
@@ -275,7 +294,8 @@ func (m *AdvancedMetrics) run() {
 				return
 			}
 			now := types.TimestampNow()
-			report := toMetricReport(mr, now, commonDimensions)
+			report := toMetricReport(mr.MetricSets, now, commonDimensions)
+			report.Data = append(report.Data, toHeavyHitterStatsEntities(mr.HeavyHitters, now, commonDimensions)...)
 			if len(report.Data) != 0 {
 				m.pipeline.Process(core.NewMessage(core.CommMetrics, []core.Payload{report}))
 			}
@@ -319,30 +339,8 @@ func toMetricReport(set []*publisher.MetricSet, now *types.Timestamp, commonDime
 		}
 
 		for i := range s.Metrics {
-			metricName := s.Metrics[i].Name
-			if _, ok := maxOnlyMetrics[metricName]; ok {
-				statsEntity.Simplemetrics = append(statsEntity.Simplemetrics, &proto.SimpleMetric{
-					Name:  metricName + ".max",
-					Value: s.Metrics[i].Values.Max,
-				})
-			}
-			if metricName == hitcountMetric {
-				name := metricNamePrefix
-				if isStreamMetric {
-					name += ".connections"
-				} else {
-					name += ".count"
-				}
-				statsEntity.Simplemetrics = append(statsEntity.Simplemetrics, &proto.SimpleMetric{
-					Name:  name,
-					Value: s.Metrics[i].Values.Count,
-				})
-			}
-			if _, ok := totalOnlyMetrics[metricName]; ok {
-				statsEntity.Simplemetrics = append(statsEntity.Simplemetrics, &proto.SimpleMetric{
-					Name:  metricNamePrefix + "." + metricName,
-					Value: s.Metrics[i].Values.Sum,
-				})
+			if simpleMetric := toSimpleMetric(s.Metrics[i], metricNamePrefix, isStreamMetric); simpleMetric != nil {
+				statsEntity.Simplemetrics = append(statsEntity.Simplemetrics, simpleMetric)
 			}
 		}
 		mr.Data = append(mr.Data, &statsEntity)
@@ -350,6 +348,70 @@ func toMetricReport(set []*publisher.MetricSet, now *types.Timestamp, commonDime
 	return mr
 }
 
+// toHeavyHitterStatsEntities returns a stats entity for each value tracked for the dimensions in the
+// heavy hitters mode, with the estimated hit count and its maximum error
+func toHeavyHitterStatsEntities(heavyHitters []publisher.HeavyHitters, now *types.Timestamp, commonDimensions []*proto.Dimension) []*proto.StatsEntity {
+	entities := []*proto.StatsEntity{}
+	for _, dimension := range heavyHitters {
+		for _, heavyHitter := range dimension.Values {
+			dimensions := make([]*proto.Dimension, 0, len(commonDimensions)+2)
+			dimensions = append(dimensions, commonDimensions...)
+			dimensions = append(dimensions,
+				&proto.Dimension{Name: heavyHitterDimensionDimension, Value: dimension.Dimension},
+				&proto.Dimension{Name: heavyHitterValueDimension, Value: heavyHitter.Value},
+			)
+			entities = append(entities, &proto.StatsEntity{
+				Timestamp:  now,
+				Dimensions: dimensions,
+				Simplemetrics: []*proto.SimpleMetric{
+					{Name: heavyHitterCountMetric, Value: float64(heavyHitter.Count)},
+					{Name: heavyHitterErrorMetric, Value: float64(heavyHitter.Error)},
+				},
+			})
+		}
+	}
+	return entities
+}
+
+// toSimpleMetric returns the aggregated value of a metric which is published, nil if the metric
+// isn't published
+func toSimpleMetric(metric publisher.Metric, metricNamePrefix string, isStreamMetric bool) *proto.SimpleMetric {
+	switch metric.Aggregation {
+	case schema.AggregationCount:
+		name := metricNamePrefix + "." + metric.Name + ".count"
+		if metric.Name == hitcountMetric {
+			name = metricNamePrefix + ".count"
+			if isStreamMetric {
+				name = metricNamePrefix + ".connections"
+			}
+		}
+		return &proto.SimpleMetric{Name: name, Value: metric.Values.Count}
+	case schema.AggregationSum:
+		return &proto.SimpleMetric{Name: metricNamePrefix + "." + metric.Name, Value: metric.Values.Sum}
+	case schema.AggregationMax:
+		return &proto.SimpleMetric{Name: metric.Name + ".max", Value: metric.Values.Max}
+	case schema.AggregationMin:
+		return &proto.SimpleMetric{Name: metric.Name + ".min", Value: metric.Values.Min}
+	default:
+		return nil
+	}
+}
+
+// unixSockets returns the paths of the unix sockets on which the metrics are received
+func unixSockets(cfg advanced_metrics.Config) []string {
+	if len(cfg.Listeners) == 0 {
+		return []string{cfg.Address}
+	}
+
+	sockets := []string{}
+	for _, listener := range cfg.Listeners {
+		if listener.Network == "unix" {
+			sockets = append(sockets, listener.Address)
+		}
+	}
+	return sockets
+}
+
 func (m *AdvancedMetrics) Info() *core.Info {
 	return core.NewInfo(AdvancedMetricsPluginName, advancedMetricsPluginVersion)
 }
@@ -14,7 +14,9 @@ import (
 	"github.com/nginx/agent/sdk/v2/proto"
 	"github.com/nginx/agent/v2/src/core"
 	"github.com/nginx/agent/v2/src/core/config"
+	advanced_metrics "github.com/nginx/agent/v2/src/extensions/advanced-metrics/pkg/advanced-metrics"
 	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/pkg/publisher"
+	"github.com/nginx/agent/v2/src/extensions/advanced-metrics/pkg/schema"
 	tutils "github.com/nginx/agent/v2/test/utils"
 
 	"github.com/gogo/protobuf/types"
@@ -46,7 +48,8 @@ func TestAppCentricMetric_toMetricReport(t *testing.T) {
 			},
 			Metrics: []publisher.Metric{
 				{
-					Name: hitcountMetric,
+					Name:        hitcountMetric,
+					Aggregation: schema.AggregationCount,
 					Values: publisher.MetricValues{
 						Count: 1,
 						Last:  0,
@@ -56,7 +59,8 @@ func TestAppCentricMetric_toMetricReport(t *testing.T) {
 					},
 				},
 				{
-					Name: bytesRcvdMetric,
+					Name:        bytesRcvdMetric,
+					Aggregation: schema.AggregationSum,
 					Values: publisher.MetricValues{
 						Count: 1,
 						Last:  0,
@@ -84,7 +88,8 @@ func TestAppCentricMetric_toMetricReport(t *testing.T) {
 			},
 			Metrics: []publisher.Metric{
 				{
-					Name: hitcountMetric,
+					Name:        hitcountMetric,
+					Aggregation: schema.AggregationCount,
 					Values: publisher.MetricValues{
 						Count: 11,
 						Last:  0,
@@ -94,7 +99,17 @@ func TestAppCentricMetric_toMetricReport(t *testing.T) {
 					},
 				},
 				{
-					Name: clientNetworkLatencyMetric,
+					Name:        clientLatencyMetric,
+					Aggregation: schema.AggregationNone,
+					Values: publisher.MetricValues{
+						Count: 1,
+						Max:   50,
+						Sum:   50,
+					},
+				},
+				{
+					Name:        clientNetworkLatencyMetric,
+					Aggregation: schema.AggregationMax,
 					Values: publisher.MetricValues{
 						Count: 1,
 						Last:  0,
@@ -104,7 +119,8 @@ func TestAppCentricMetric_toMetricReport(t *testing.T) {
 					},
 				},
 				{
-					Name: bytesRcvdMetric,
+					Name:        bytesRcvdMetric,
+					Aggregation: schema.AggregationSum,
 					Values: publisher.MetricValues{
 						Count: 1,
 						Last:  0,
@@ -194,9 +210,53 @@ func TestAppCentricMetric_toMetricReport(t *testing.T) {
 	}, report)
 }
 
+func TestAppCentricMetric_toHeavyHitterStatsEntities(t *testing.T) {
+	now := types.TimestampNow()
+	commonDimensions := []*proto.Dimension{{Name: "hostname", Value: "example.com"}}
+
+	entities := toHeavyHitterStatsEntities([]publisher.HeavyHitters{
+		{
+			Dimension: "http.uri",
+			Values: []publisher.HeavyHitter{
+				{Value: "/api", Count: 100},
+				{Value: "/login", Count: 20, Error: 5},
+			},
+		},
+		{Dimension: "country_code", Values: []publisher.HeavyHitter{}},
+	}, now, commonDimensions)
+
+	assert.Equal(t, []*proto.StatsEntity{
+		{
+			Timestamp: now,
+			Dimensions: []*proto.Dimension{
+				{Name: "hostname", Value: "example.com"},
+				{Name: "heavy_hitter.dimension", Value: "http.uri"},
+				{Name: "heavy_hitter.value", Value: "/api"},
+			},
+			Simplemetrics: []*proto.SimpleMetric{
+				{Name: "heavy_hitter.count", Value: 100},
+				{Name: "heavy_hitter.error", Value: 0},
+			},
+		},
+		{
+			Timestamp: now,
+			Dimensions: []*proto.Dimension{
+				{Name: "hostname", Value: "example.com"},
+				{Name: "heavy_hitter.dimension", Value: "http.uri"},
+				{Name: "heavy_hitter.value", Value: "/login"},
+			},
+			Simplemetrics: []*proto.SimpleMetric{
+				{Name: "heavy_hitter.count", Value: 20},
+				{Name: "heavy_hitter.error", Value: 5},
+			},
+		},
+	}, entities)
+}
+
 func TestAppCentricMetricClose(t *testing.T) {
 	env := tutils.GetMockEnv()
-	pluginUnderTest := NewAdvancedMetrics(env, &config.Config{}, nil)
+	pluginUnderTest, err := NewAdvancedMetrics(env, &config.Config{}, nil)
+	assert.NoError(t, err)
 
 	ctx, cancelCTX := context.WithCancel(context.Background())
 	defer cancelCTX()
@@ -210,6 +270,116 @@ func TestAppCentricMetricClose(t *testing.T) {
 }
 
 func TestAppCentricMetricSubscriptions(t *testing.T) {
-	pluginUnderTest := NewAdvancedMetrics(tutils.GetMockEnv(), &config.Config{}, nil)
+	pluginUnderTest, err := NewAdvancedMetrics(tutils.GetMockEnv(), &config.Config{}, nil)
+	assert.NoError(t, err)
 	assert.Equal(t, []string{}, pluginUnderTest.Subscriptions())
 }
+
+func TestUnixSockets(t *testing.T) {
+	assert.Equal(t, []string{"/tmp/advanced-metrics.sock"}, unixSockets(advanced_metrics.Config{Address: "/tmp/advanced-metrics.sock"}))
+	assert.Equal(t, []string{"/tmp/tenant-a.sock"}, unixSockets(advanced_metrics.Config{
+		Address: "/tmp/advanced-metrics.sock",
+		Listeners: []advanced_metrics.ListenerConfig{
+			{Network: "unix", Address: "/tmp/tenant-a.sock"},
+			{Network: "udp", Address: "127.0.0.1:9100"},
+		},
+	}))
+}
+
+func TestNewAdvancedMetrics_Schema(t *testing.T) {
+	tests := []struct {
+		name          string
+		conf          interface{}
+		expectedError string
+	}{
+		{
+			name: "default schema",
+			conf: map[string]interface{}{"socket_path": "/tmp/advanced-metrics.sock"},
+		},
+		{
+			name: "stream schema",
+			conf: map[string]interface{}{
+				"schema": []interface{}{
+					map[string]interface{}{"name": "upstream_addr", "type": "dimension", "cardinality": 1024, "collapsing_level": 50},
+					map[string]interface{}{"name": "status", "type": "integer_dimension", "cardinality": 600},
+					map[string]interface{}{"name": "hitcount", "type": "metric", "aggregation": "count"},
+					map[string]interface{}{"name": "session_time", "type": "metric", "aggregation": "max"},
+				},
+			},
+		},
+		{
+			name: "invalid aggregation",
+			conf: map[string]interface{}{
+				"schema": []interface{}{
+					map[string]interface{}{"name": "server_name", "type": "dimension", "cardinality": 64},
+					map[string]interface{}{"name": "hitcount", "type": "metric", "aggregation": "avg"},
+				},
+			},
+			expectedError: "metric: 'hitcount' has invalid aggregation 'avg'",
+		},
+		{
+			name: "duplicate field",
+			conf: map[string]interface{}{
+				"schema": []interface{}{
+					map[string]interface{}{"name": "hitcount", "type": "dimension", "cardinality": 64},
+					map[string]interface{}{"name": "hitcount", "type": "metric", "aggregation": "count"},
+				},
+			},
+			expectedError: "schema field 'hitcount' is defined more than once",
+		},
+		{
+			name: "heavy hitters",
+			conf: map[string]interface{}{
+				"schema": []interface{}{
+					map[string]interface{}{"name": "http.uri", "type": "dimension", "cardinality": 16000, "top_k": 100},
+					map[string]interface{}{"name": "hitcount", "type": "metric", "aggregation": "count"},
+				},
+			},
+		},
+		{
+			name: "listeners",
+			conf: map[string]interface{}{
+				"listeners": []interface{}{
+					map[string]interface{}{"network": "unix", "address": "/tmp/tenant-a.sock", "source": "tenant-a"},
+					map[string]interface{}{"network": "tcp", "address": "127.0.0.1:9100", "source": "sidecar"},
+				},
+			},
+		},
+		{
+			name: "listener on public address",
+			conf: map[string]interface{}{
+				"listeners": []interface{}{
+					map[string]interface{}{"network": "udp", "address": "0.0.0.0:9100"},
+				},
+			},
+			expectedError: "udp listener address '0.0.0.0:9100' is not a loopback address",
+		},
+		{
+			name: "schema with source dimension",
+			conf: map[string]interface{}{
+				"listeners": []interface{}{
+					map[string]interface{}{"network": "unix", "address": "/tmp/tenant-a.sock"},
+				},
+				"schema": []interface{}{
+					map[string]interface{}{"name": "source", "type": "dimension", "cardinality": 64},
+					map[string]interface{}{"name": "hitcount", "type": "metric", "aggregation": "count"},
+				},
+			},
+			expectedError: "schema field 'source' is defined more than once",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tutils.GetMockEnv()
+			pluginUnderTest, err := NewAdvancedMetrics(env, &config.Config{}, tt.conf)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				assert.Nil(t, pluginUnderTest)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, pluginUnderTest)
+		})
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/gogo/protobuf/types"
+
 	agent_config "github.com/nginx/agent/sdk/v2/agent/config"
 	"github.com/nginx/agent/sdk/v2/proto"
 	models "github.com/nginx/agent/sdk/v2/proto/events"
@@ -19,6 +21,8 @@ import (
 	"github.com/nginx/agent/v2/src/core/metrics"
 	"github.com/nginx/agent/v2/src/core/payloads"
 	"github.com/nginx/agent/v2/src/extensions/nginx-app-protect/monitoring/manager"
+	"github.com/nginx/agent/v2/src/extensions/nginx-app-protect/monitoring/processor"
+	"github.com/nginx/agent/v2/src/extensions/nginx-app-protect/monitoring/sink"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -38,13 +42,20 @@ var nginxAppProtectMonitoringDefault = &manager.NginxAppProtectMonitoringConfig{
 	SyslogPort:          514,
 	ReportInterval:      time.Minute,
 	ReportCount:         400,
+	Metrics: manager.MetricsConfig{
+		Interval: time.Minute,
+	},
 }
 
 type NAPMonitoring struct {
 	monitorMgr      *manager.Manager
+	forwarder       *sink.Forwarder
+	aggregator      *processor.Aggregator
+	commonDims      *metrics.CommonDim
 	messagePipeline core.MessagePipeInterface
 	reportInterval  time.Duration
 	reportCount     int
+	metricsInterval time.Duration
 	ctx             context.Context
 	ctxCancel       context.CancelFunc
 }
@@ -82,10 +93,44 @@ func NewNAPMonitoring(env core.Environment, cfg *config.Config, nginxAppProtectM
 		nginxAppProtectMonitoringConfig.ReportCount = nginxAppProtectMonitoringDefault.ReportCount
 	}
 
+	var forwarder *sink.Forwarder
+	if nginxAppProtectMonitoringConfig.Sinks.Enabled() {
+		forwarder, err = sink.NewForwarder(nginxAppProtectMonitoringConfig.Sinks)
+		if err != nil {
+			log.Errorf("Error creating security violation event sinks for extension plugin %s, %v", napMonitoringPluginName, err)
+			return nil, err
+		}
+	}
+
+	var aggregator *processor.Aggregator
+	metricsConfig := nginxAppProtectMonitoringConfig.Metrics
+	if metricsConfig.Enable {
+		if metricsConfig.Interval <= 0 {
+			metricsConfig.Interval = nginxAppProtectMonitoringDefault.Metrics.Interval
+		}
+		ipv4PrefixLength := processor.DefaultIPv4PrefixLength
+		if metricsConfig.IPv4PrefixLength != nil {
+			ipv4PrefixLength = *metricsConfig.IPv4PrefixLength
+		}
+		ipv6PrefixLength := processor.DefaultIPv6PrefixLength
+		if metricsConfig.IPv6PrefixLength != nil {
+			ipv6PrefixLength = *metricsConfig.IPv6PrefixLength
+		}
+		aggregator, err = processor.NewAggregator(ipv4PrefixLength, ipv6PrefixLength)
+		if err != nil {
+			log.Errorf("Error creating security violation event metrics for extension plugin %s, %v", napMonitoringPluginName, err)
+			return nil, err
+		}
+	}
+
 	return &NAPMonitoring{
-		monitorMgr:     m,
-		reportInterval: nginxAppProtectMonitoringConfig.ReportInterval,
-		reportCount:    nginxAppProtectMonitoringConfig.ReportCount,
+		monitorMgr:      m,
+		forwarder:       forwarder,
+		aggregator:      aggregator,
+		commonDims:      commonDims,
+		reportInterval:  nginxAppProtectMonitoringConfig.ReportInterval,
+		reportCount:     nginxAppProtectMonitoringConfig.ReportCount,
+		metricsInterval: metricsConfig.Interval,
 	}, nil
 }
 
@@ -111,6 +156,9 @@ func (n *NAPMonitoring) Init(pipeline core.MessagePipeInterface) {
 	)
 
 	go n.monitorMgr.Run(ctx)
+	if n.forwarder != nil {
+		go n.forwarder.Run(ctx)
+	}
 	go n.run()
 }
 
@@ -135,6 +183,14 @@ func (n *NAPMonitoring) run() {
 		Events: []*models.Event{},
 	}
 
+	// the metrics ticker channel is nil when the aggregation of events into metrics is disabled
+	var metricsTick <-chan time.Time
+	if n.aggregator != nil {
+		metricsTicker := time.NewTicker(n.metricsInterval)
+		defer metricsTicker.Stop()
+		metricsTick = metricsTicker.C
+	}
+
 	for {
 		select {
 		case event, ok := <-n.monitorMgr.OutChannel():
@@ -142,6 +198,12 @@ func (n *NAPMonitoring) run() {
 				log.Errorf("NAP Monitoring processing channel closed unexpectedly")
 				return
 			}
+			if n.forwarder != nil {
+				n.forwarder.Forward(event)
+			}
+			if n.aggregator != nil {
+				n.aggregator.Add(event)
+			}
 			report.Events = append(report.Events, event)
 			if len(report.Events) == n.reportCount {
 				log.Infof("collected %d Security Violation Events, sending report", n.reportCount)
@@ -152,12 +214,29 @@ func (n *NAPMonitoring) run() {
 				log.Infof("reached a report interval of %vs, sending %d Security Violation Events as a report", n.reportInterval.Seconds(), len(report.Events))
 				n.send(report)
 			}
+		case <-metricsTick:
+			n.sendMetrics()
 		case <-n.ctx.Done():
+			// send the metrics of the last, partial interval
+			if n.aggregator != nil {
+				n.sendMetrics()
+			}
 			return
 		}
 	}
 }
 
+// sendMetrics sends the metrics aggregated from the events of the interval to the management
+// plane and to the Prometheus exporter of the Agent API. The exporter also gets the reports
+// without metrics, so that it doesn't expose the metrics of a previous interval.
+func (n *NAPMonitoring) sendMetrics() {
+	report := n.aggregator.Report(types.TimestampNow(), n.commonDims.ToDimensions())
+	if len(report.Data) > 0 {
+		n.messagePipeline.Process(core.NewMessage(core.CommMetrics, []core.Payload{report}))
+	}
+	n.messagePipeline.Process(core.NewMessage(core.SecurityMetricReport, report))
+}
+
 func (n *NAPMonitoring) send(report *models.EventReport) {
 	reportToSend := &models.EventReport{
 		Events: make([]*models.Event, len(report.Events)),
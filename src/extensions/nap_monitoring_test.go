@@ -8,13 +8,20 @@
 package extensions
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/nginx/agent/sdk/v2/proto"
+	models "github.com/nginx/agent/sdk/v2/proto/events"
+	"github.com/nginx/agent/v2/src/core"
 	"github.com/nginx/agent/v2/src/core/config"
 	"github.com/nginx/agent/v2/src/extensions/nginx-app-protect/monitoring/manager"
+	"github.com/nginx/agent/v2/src/extensions/nginx-app-protect/monitoring/sink"
 	tutils "github.com/nginx/agent/v2/test/utils"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNAPMonitoring(t *testing.T) {
@@ -69,6 +76,56 @@ func TestNAPMonitoring(t *testing.T) {
 			error:         true,
 			errorContains: "invalid port",
 		},
+		{
+			name: "syslog sink",
+			conf: manager.NginxAppProtectMonitoringConfig{
+				CollectorBufferSize: 1,
+				ProcessorBufferSize: 1,
+				SyslogIP:            "127.0.0.1",
+				SyslogPort:          1237,
+				Sinks: sink.Config{
+					Syslog: sink.SyslogConfig{Network: "tcp", Address: "127.0.0.1:6514"},
+				},
+			},
+			error: false,
+		},
+		{
+			name: "invalid syslog sink network",
+			conf: manager.NginxAppProtectMonitoringConfig{
+				CollectorBufferSize: 1,
+				ProcessorBufferSize: 1,
+				SyslogIP:            "127.0.0.1",
+				SyslogPort:          1238,
+				Sinks: sink.Config{
+					Syslog: sink.SyslogConfig{Network: "http", Address: "127.0.0.1:6514"},
+				},
+			},
+			error:         true,
+			errorContains: "syslog sink network 'http'",
+		},
+		{
+			name: "metrics",
+			conf: manager.NginxAppProtectMonitoringConfig{
+				CollectorBufferSize: 1,
+				ProcessorBufferSize: 1,
+				SyslogIP:            "127.0.0.1",
+				SyslogPort:          1239,
+				Metrics:             manager.MetricsConfig{Enable: true},
+			},
+			error: false,
+		},
+		{
+			name: "invalid metrics client IP prefix length",
+			conf: manager.NginxAppProtectMonitoringConfig{
+				CollectorBufferSize: 1,
+				ProcessorBufferSize: 1,
+				SyslogIP:            "127.0.0.1",
+				SyslogPort:          1240,
+				Metrics:             manager.MetricsConfig{Enable: true, IPv4PrefixLength: intPtr(48)},
+			},
+			error:         true,
+			errorContains: "IPv4 prefix length 48 is not between 0 and 32",
+		},
 	}
 
 	env := tutils.GetMockEnv()
@@ -86,9 +143,137 @@ func TestNAPMonitoring(t *testing.T) {
 	}
 }
 
+func intPtr(value int) *int {
+	return &value
+}
+
+func TestNAPMonitoring_ClientIPPrefixLength(t *testing.T) {
+	tests := []struct {
+		name             string
+		syslogPort       int
+		ipv4PrefixLength *int
+		expectedBucket   string
+	}{
+		{
+			name:           "default prefix length",
+			syslogPort:     1242,
+			expectedBucket: "192.0.2.0/24",
+		},
+		{
+			name:             "zero prefix length",
+			syslogPort:       1244,
+			ipv4PrefixLength: intPtr(0),
+			expectedBucket:   "0.0.0.0/0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pluginUnderTest, err := NewNAPMonitoring(tutils.GetMockEnv(), tutils.GetMockAgentConfig(), manager.NginxAppProtectMonitoringConfig{
+				CollectorBufferSize: 1,
+				ProcessorBufferSize: 1,
+				SyslogIP:            "127.0.0.1",
+				SyslogPort:          tt.syslogPort,
+				Metrics:             manager.MetricsConfig{Enable: true, IPv4PrefixLength: tt.ipv4PrefixLength},
+			})
+			require.NoError(t, err)
+
+			pluginUnderTest.aggregator.Add(&models.Event{
+				Metadata: &models.Metadata{UUID: "1"},
+				Data: &models.Event_SecurityViolationEvent{SecurityViolationEvent: &models.SecurityViolationEvent{
+					PolicyName: "app_protect_default_policy",
+					RemoteAddr: "192.0.2.10",
+				}},
+			})
+
+			buckets := []string{}
+			for _, entity := range pluginUnderTest.aggregator.Report(nil, nil).Data {
+				for _, dimension := range entity.Dimensions {
+					if dimension.Name == "client_ip_bucket" {
+						buckets = append(buckets, dimension.Value)
+					}
+				}
+			}
+			assert.Equal(t, []string{tt.expectedBucket}, buckets)
+		})
+	}
+}
+
 func TestNAPMonitoring_Info(t *testing.T) {
 	pluginUnderTest, err := NewNAPMonitoring(tutils.GetMockEnv(), tutils.GetMockAgentConfig(), manager.NginxAppProtectMonitoringConfig{})
 
 	assert.NoError(t, err)
 	assert.Equal(t, "nap-monitoring", pluginUnderTest.Info().Name())
 }
+
+func TestNAPMonitoring_sendMetrics(t *testing.T) {
+	pluginUnderTest, err := NewNAPMonitoring(tutils.GetMockEnv(), tutils.GetMockAgentConfig(), manager.NginxAppProtectMonitoringConfig{
+		CollectorBufferSize: 1,
+		ProcessorBufferSize: 1,
+		SyslogIP:            "127.0.0.1",
+		SyslogPort:          1241,
+		Metrics:             manager.MetricsConfig{Enable: true},
+	})
+	require.NoError(t, err)
+
+	messagePipe := core.SetupMockMessagePipe(t, context.Background(), []core.Plugin{}, []core.ExtensionPlugin{})
+	pluginUnderTest.messagePipeline = messagePipe
+
+	// without events, only the Prometheus exporter gets the empty report
+	pluginUnderTest.sendMetrics()
+	messages := messagePipe.GetMessages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, core.SecurityMetricReport, messages[0].Topic())
+	assert.Empty(t, messages[0].Data().(*proto.MetricsReport).Data)
+	messagePipe.ClearMessages()
+
+	pluginUnderTest.aggregator.Add(&models.Event{
+		Metadata: &models.Metadata{UUID: "1"},
+		Data: &models.Event_SecurityViolationEvent{SecurityViolationEvent: &models.SecurityViolationEvent{
+			PolicyName:    "app_protect_default_policy",
+			RequestStatus: "blocked",
+		}},
+	})
+	pluginUnderTest.sendMetrics()
+	messages = messagePipe.GetMessages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, core.CommMetrics, messages[0].Topic())
+	assert.Equal(t, core.SecurityMetricReport, messages[1].Topic())
+
+	report := messages[1].Data().(*proto.MetricsReport)
+	assert.Equal(t, []core.Payload{report}, messages[0].Data())
+	require.Len(t, report.Data, 1)
+	assert.Contains(t, report.Data[0].Simplemetrics, &proto.SimpleMetric{Name: "nap.request.blocked", Value: 1})
+}
+
+func TestNAPMonitoring_run_SendsMetricsOnClose(t *testing.T) {
+	pluginUnderTest, err := NewNAPMonitoring(tutils.GetMockEnv(), tutils.GetMockAgentConfig(), manager.NginxAppProtectMonitoringConfig{
+		CollectorBufferSize: 1,
+		ProcessorBufferSize: 1,
+		SyslogIP:            "127.0.0.1",
+		SyslogPort:          1243,
+		Metrics:             manager.MetricsConfig{Enable: true, Interval: time.Hour},
+	})
+	require.NoError(t, err)
+
+	messagePipe := core.SetupMockMessagePipe(t, context.Background(), []core.Plugin{}, []core.ExtensionPlugin{})
+	pluginUnderTest.messagePipeline = messagePipe
+	pluginUnderTest.ctx, pluginUnderTest.ctxCancel = context.WithCancel(context.Background())
+
+	pluginUnderTest.aggregator.Add(&models.Event{
+		Metadata: &models.Metadata{UUID: "1"},
+		Data: &models.Event_SecurityViolationEvent{SecurityViolationEvent: &models.SecurityViolationEvent{
+			PolicyName:    "app_protect_default_policy",
+			RequestStatus: "blocked",
+		}},
+	})
+
+	// the metrics of the partial interval are sent when the plugin stops
+	pluginUnderTest.ctxCancel()
+	pluginUnderTest.run()
+
+	messages := messagePipe.GetMessages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, core.CommMetrics, messages[0].Topic())
+	assert.Equal(t, core.SecurityMetricReport, messages[1].Topic())
+}
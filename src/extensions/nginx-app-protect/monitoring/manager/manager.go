@@ -20,6 +20,7 @@ import (
 	"github.com/nginx/agent/v2/src/extensions/nginx-app-protect/monitoring"
 	"github.com/nginx/agent/v2/src/extensions/nginx-app-protect/monitoring/collector"
 	"github.com/nginx/agent/v2/src/extensions/nginx-app-protect/monitoring/processor"
+	"github.com/nginx/agent/v2/src/extensions/nginx-app-protect/monitoring/sink"
 )
 
 const (
@@ -35,6 +36,18 @@ type NginxAppProtectMonitoringConfig struct {
 	SyslogPort          int           `mapstructure:"syslog_port" yaml:"-"`
 	ReportInterval      time.Duration `mapstructure:"report_interval" yaml:"-"`
 	ReportCount         int           `mapstructure:"report_count" yaml:"-"`
+	Sinks               sink.Config   `mapstructure:"sinks" yaml:"-"`
+	Metrics             MetricsConfig `mapstructure:"metrics" yaml:"-"`
+}
+
+// MetricsConfig holds the config of the aggregation of security violation events into metrics,
+// client IPs are bucketed by the prefix lengths. The prefix lengths are pointers so that a prefix
+// length of 0, which puts all clients in one bucket, can be told apart from an unset one.
+type MetricsConfig struct {
+	Enable           bool          `mapstructure:"enable" yaml:"-"`
+	Interval         time.Duration `mapstructure:"interval" yaml:"-"`
+	IPv4PrefixLength *int          `mapstructure:"ipv4_prefix_length" yaml:"-"`
+	IPv6PrefixLength *int          `mapstructure:"ipv6_prefix_length" yaml:"-"`
 }
 
 type Manager struct {
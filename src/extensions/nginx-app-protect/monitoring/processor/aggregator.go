@@ -0,0 +1,223 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package processor
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/gogo/protobuf/types"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	pb "github.com/nginx/agent/sdk/v2/proto/events"
+)
+
+const (
+	RequestCountMetric   = "nap.request.count"
+	RequestBlockedMetric = "nap.request.blocked"
+	RequestAlertedMetric = "nap.request.alerted"
+
+	PolicyNameDimension      = "policy_name"
+	ViolationNameDimension   = "violation_name"
+	SignatureIDDimension     = "signature_id"
+	BotCategoryDimension     = "bot_category"
+	ClientIPBucketDimension  = "client_ip_bucket"
+	ViolationRatingDimension = "violation_rating"
+
+	DefaultIPv4PrefixLength = 24
+	DefaultIPv6PrefixLength = 64
+
+	requestStatusBlocked = "blocked"
+	requestStatusAlerted = "alerted"
+	notApplicable        = "N/A"
+	ipv4Bits             = 32
+	ipv6Bits             = 128
+)
+
+// aggregationKey identifies the counters of a policy, or of a value of a dimension
+// within a policy when dimension is set.
+type aggregationKey struct {
+	policy    string
+	dimension string
+	value     string
+}
+
+type requestCounters struct {
+	count   float64
+	blocked float64
+	alerted float64
+}
+
+// Aggregator aggregates security violation events into request counters per policy,
+// and per violation name, signature ID, bot category, client IP bucket and violation
+// rating within the policy. Client IPs are bucketed by their network prefix so that
+// the number of reported values stays bounded.
+type Aggregator struct {
+	ipv4Mask net.IPMask
+	ipv6Mask net.IPMask
+	counters map[aggregationKey]*requestCounters
+}
+
+// NewAggregator creates an aggregator which buckets client IPs by the prefix lengths
+func NewAggregator(ipv4PrefixLength, ipv6PrefixLength int) (*Aggregator, error) {
+	if ipv4PrefixLength < 0 || ipv4PrefixLength > ipv4Bits {
+		return nil, fmt.Errorf("IPv4 prefix length %d is not between 0 and %d", ipv4PrefixLength, ipv4Bits)
+	}
+	if ipv6PrefixLength < 0 || ipv6PrefixLength > ipv6Bits {
+		return nil, fmt.Errorf("IPv6 prefix length %d is not between 0 and %d", ipv6PrefixLength, ipv6Bits)
+	}
+
+	return &Aggregator{
+		ipv4Mask: net.CIDRMask(ipv4PrefixLength, ipv4Bits),
+		ipv6Mask: net.CIDRMask(ipv6PrefixLength, ipv6Bits),
+		counters: make(map[aggregationKey]*requestCounters),
+	}, nil
+}
+
+// Add counts the request of the security violation event
+func (a *Aggregator) Add(event *pb.Event) {
+	violation := event.GetSecurityViolationEvent()
+	if violation == nil {
+		return
+	}
+
+	policy := violation.GetPolicyName()
+	status := violation.GetRequestStatus()
+
+	a.count(aggregationKey{policy: policy}, status)
+	for _, name := range splitValues(violation.GetViolations()) {
+		a.count(aggregationKey{policy, ViolationNameDimension, name}, status)
+	}
+	for _, id := range signatureIDs(violation) {
+		a.count(aggregationKey{policy, SignatureIDDimension, id}, status)
+	}
+	if isSet(violation.GetBotCategory()) {
+		a.count(aggregationKey{policy, BotCategoryDimension, violation.GetBotCategory()}, status)
+	}
+	if bucket := a.clientIPBucket(violation.GetRemoteAddr()); bucket != "" {
+		a.count(aggregationKey{policy, ClientIPBucketDimension, bucket}, status)
+	}
+	if isSet(violation.GetViolationRating()) {
+		a.count(aggregationKey{policy, ViolationRatingDimension, violation.GetViolationRating()}, status)
+	}
+}
+
+// Report returns the metrics aggregated since the previous report and resets the counters
+func (a *Aggregator) Report(now *types.Timestamp, commonDimensions []*proto.Dimension) *proto.MetricsReport {
+	keys := make([]aggregationKey, 0, len(a.counters))
+	for key := range a.counters {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].policy != keys[j].policy {
+			return keys[i].policy < keys[j].policy
+		}
+		if keys[i].dimension != keys[j].dimension {
+			return keys[i].dimension < keys[j].dimension
+		}
+		return keys[i].value < keys[j].value
+	})
+
+	report := &proto.MetricsReport{
+		Meta: &proto.Metadata{Timestamp: now},
+		Type: proto.MetricsReport_INSTANCE,
+		Data: make([]*proto.StatsEntity, 0, len(keys)),
+	}
+	for _, key := range keys {
+		dimensions := make([]*proto.Dimension, 0, len(commonDimensions)+2)
+		dimensions = append(dimensions, commonDimensions...)
+		dimensions = append(dimensions, &proto.Dimension{Name: PolicyNameDimension, Value: key.policy})
+		if key.dimension != "" {
+			dimensions = append(dimensions, &proto.Dimension{Name: key.dimension, Value: key.value})
+		}
+
+		counters := a.counters[key]
+		report.Data = append(report.Data, &proto.StatsEntity{
+			Timestamp:  now,
+			Dimensions: dimensions,
+			Simplemetrics: []*proto.SimpleMetric{
+				{Name: RequestCountMetric, Value: counters.count},
+				{Name: RequestBlockedMetric, Value: counters.blocked},
+				{Name: RequestAlertedMetric, Value: counters.alerted},
+			},
+		})
+	}
+
+	a.counters = make(map[aggregationKey]*requestCounters)
+	return report
+}
+
+func (a *Aggregator) count(key aggregationKey, status string) {
+	counters, ok := a.counters[key]
+	if !ok {
+		counters = &requestCounters{}
+		a.counters[key] = counters
+	}
+
+	counters.count++
+	switch status {
+	case requestStatusBlocked:
+		counters.blocked++
+	case requestStatusAlerted:
+		counters.alerted++
+	}
+}
+
+// clientIPBucket returns the network of the client IP in CIDR notation
+func (a *Aggregator) clientIPBucket(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return ""
+	}
+
+	mask := a.ipv6Mask
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		mask = a.ipv4Mask
+	}
+	ones, _ := mask.Size()
+	return fmt.Sprintf("%s/%d", ip.Mask(mask), ones)
+}
+
+// signatureIDs returns the distinct IDs of the signatures that matched the request
+func signatureIDs(violation *pb.SecurityViolationEvent) []string {
+	ids := []string{}
+	seen := make(map[string]struct{})
+	for _, data := range violation.GetViolationsData() {
+		for _, signature := range data.GetSignatures() {
+			id := signature.GetID()
+			if _, ok := seen[id]; ok || !isSet(id) {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// splitValues returns the distinct values of a comma separated list
+func splitValues(list string) []string {
+	values := []string{}
+	seen := make(map[string]struct{})
+	for _, value := range strings.Split(list, ",") {
+		value = strings.TrimSpace(value)
+		if _, ok := seen[value]; ok || !isSet(value) {
+			continue
+		}
+		seen[value] = struct{}{}
+		values = append(values, value)
+	}
+	return values
+}
+
+func isSet(value string) bool {
+	return value != "" && value != notApplicable
+}
@@ -0,0 +1,127 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	pb "github.com/nginx/agent/sdk/v2/proto/events"
+)
+
+func securityViolationEvent(violation *pb.SecurityViolationEvent) *pb.Event {
+	return &pb.Event{
+		Metadata: &pb.Metadata{UUID: violation.GetSupportID()},
+		Data:     &pb.Event_SecurityViolationEvent{SecurityViolationEvent: violation},
+	}
+}
+
+func TestNewAggregator(t *testing.T) {
+	_, err := NewAggregator(DefaultIPv4PrefixLength, DefaultIPv6PrefixLength)
+	assert.NoError(t, err)
+
+	_, err = NewAggregator(33, DefaultIPv6PrefixLength)
+	assert.EqualError(t, err, "IPv4 prefix length 33 is not between 0 and 32")
+
+	_, err = NewAggregator(DefaultIPv4PrefixLength, -1)
+	assert.EqualError(t, err, "IPv6 prefix length -1 is not between 0 and 128")
+}
+
+func TestAggregator(t *testing.T) {
+	aggregator, err := NewAggregator(DefaultIPv4PrefixLength, DefaultIPv6PrefixLength)
+	require.NoError(t, err)
+
+	aggregator.Add(securityViolationEvent(&pb.SecurityViolationEvent{
+		PolicyName:      "default",
+		SupportID:       "1",
+		RequestStatus:   "blocked",
+		RemoteAddr:      "192.0.2.10",
+		Violations:      "Attack signature detected,Bot Client Detected",
+		ViolationRating: "5",
+		BotCategory:     "HTTP Library",
+		ViolationsData: []*pb.ViolationData{
+			{Name: "VIOL_ATTACK_SIGNATURE", Signatures: []*pb.SignatureData{{ID: "200001475"}, {ID: "200000098"}}},
+			{Name: "VIOL_ATTACK_SIGNATURE", Signatures: []*pb.SignatureData{{ID: "200001475"}}},
+		},
+	}))
+	aggregator.Add(securityViolationEvent(&pb.SecurityViolationEvent{
+		PolicyName:      "default",
+		SupportID:       "2",
+		RequestStatus:   "alerted",
+		RemoteAddr:      "192.0.2.200",
+		Violations:      "Attack signature detected",
+		ViolationRating: "3",
+		BotCategory:     "N/A",
+		ViolationsData: []*pb.ViolationData{
+			{Name: "VIOL_ATTACK_SIGNATURE", Signatures: []*pb.SignatureData{{ID: "200001475"}}},
+		},
+	}))
+	aggregator.Add(securityViolationEvent(&pb.SecurityViolationEvent{
+		PolicyName:    "strict",
+		SupportID:     "3",
+		RequestStatus: "passed",
+		RemoteAddr:    "2001:db8::1",
+		Violations:    "",
+	}))
+	// events without a security violation are ignored
+	aggregator.Add(&pb.Event{Metadata: &pb.Metadata{UUID: "4"}})
+
+	now := types.TimestampNow()
+	commonDimensions := []*proto.Dimension{{Name: "hostname", Value: "example.com"}}
+	report := aggregator.Report(now, commonDimensions)
+
+	assert.Equal(t, &proto.Metadata{Timestamp: now}, report.Meta)
+	assert.Equal(t, proto.MetricsReport_INSTANCE, report.Type)
+
+	type counters struct {
+		count, blocked, alerted float64
+	}
+	expected := []struct {
+		policy, dimension, value string
+		counters
+	}{
+		{"default", "", "", counters{2, 1, 1}},
+		{"default", BotCategoryDimension, "HTTP Library", counters{1, 1, 0}},
+		{"default", ClientIPBucketDimension, "192.0.2.0/24", counters{2, 1, 1}},
+		{"default", SignatureIDDimension, "200000098", counters{1, 1, 0}},
+		{"default", SignatureIDDimension, "200001475", counters{2, 1, 1}},
+		{"default", ViolationNameDimension, "Attack signature detected", counters{2, 1, 1}},
+		{"default", ViolationNameDimension, "Bot Client Detected", counters{1, 1, 0}},
+		{"default", ViolationRatingDimension, "3", counters{1, 0, 1}},
+		{"default", ViolationRatingDimension, "5", counters{1, 1, 0}},
+		{"strict", "", "", counters{1, 0, 0}},
+		{"strict", ClientIPBucketDimension, "2001:db8::/64", counters{1, 0, 0}},
+	}
+	require.Len(t, report.Data, len(expected))
+
+	for i, e := range expected {
+		dimensions := []*proto.Dimension{
+			{Name: "hostname", Value: "example.com"},
+			{Name: PolicyNameDimension, Value: e.policy},
+		}
+		if e.dimension != "" {
+			dimensions = append(dimensions, &proto.Dimension{Name: e.dimension, Value: e.value})
+		}
+		assert.Equal(t, &proto.StatsEntity{
+			Timestamp:  now,
+			Dimensions: dimensions,
+			Simplemetrics: []*proto.SimpleMetric{
+				{Name: RequestCountMetric, Value: e.count},
+				{Name: RequestBlockedMetric, Value: e.blocked},
+				{Name: RequestAlertedMetric, Value: e.alerted},
+			},
+		}, report.Data[i])
+	}
+
+	// the counters are reset by the report
+	assert.Empty(t, aggregator.Report(now, commonDimensions).Data)
+}
@@ -0,0 +1,127 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	models "github.com/nginx/agent/sdk/v2/proto/events"
+)
+
+const (
+	defaultFileMaxSize    = 100
+	defaultFileMaxBackups = 5
+	fileMode              = 0o640
+	megabyte              = 1024 * 1024
+)
+
+// FileConfig holds the config of the file sink, max size is in megabytes.
+type FileConfig struct {
+	Path       string `mapstructure:"path" yaml:"-"`
+	MaxSize    int    `mapstructure:"max_size" yaml:"-"`
+	MaxBackups int    `mapstructure:"max_backups" yaml:"-"`
+}
+
+// FileSink writes security violation events as JSON lines. The file is rotated when it
+// reaches the max size, the rotated files are renamed to path.1 up to path.<max backups>.
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewFileSink creates a file sink which appends to the file of the path
+func NewFileSink(conf FileConfig) (*FileSink, error) {
+	s := &FileSink{
+		path:       conf.Path,
+		maxSize:    int64(conf.MaxSize) * megabyte,
+		maxBackups: conf.MaxBackups,
+	}
+	if conf.MaxSize <= 0 {
+		s.maxSize = defaultFileMaxSize * megabyte
+	}
+	if conf.MaxBackups <= 0 {
+		s.maxBackups = defaultFileMaxBackups
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) Write(_ context.Context, event *models.Event) error {
+	data, err := marshalEvent(event)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if s.size > 0 && s.size+int64(len(data)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(data)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("unable to write to file sink %s: %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *FileSink) Flush(context.Context) error {
+	return s.file.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, fileMode)
+	if err != nil {
+		return fmt.Errorf("unable to open file sink %s: %w", s.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("unable to stat file sink %s: %w", s.path, err)
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("unable to close file sink %s: %w", s.path, err)
+	}
+
+	for i := s.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(backupPath(s.path, i), backupPath(s.path, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("unable to rotate file sink %s: %w", s.path, err)
+		}
+	}
+	if err := os.Rename(s.path, backupPath(s.path, 1)); err != nil {
+		return fmt.Errorf("unable to rotate file sink %s: %w", s.path, err)
+	}
+
+	return s.open()
+}
+
+func backupPath(path string, index int) string {
+	return fmt.Sprintf("%s.%d", path, index)
+}
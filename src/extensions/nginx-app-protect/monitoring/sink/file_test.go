@@ -0,0 +1,68 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sink
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	s, err := NewFileSink(FileConfig{Path: path})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(context.Background(), testEvent("first", requestStatusBlocked)))
+	require.NoError(t, s.Write(context.Background(), testEvent("second", "passed")))
+	require.NoError(t, s.Flush(context.Background()))
+	require.NoError(t, s.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"uuid":"first"`)
+	assert.Contains(t, lines[1], `"uuid":"second"`)
+}
+
+func TestFileSink_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	s, err := NewFileSink(FileConfig{Path: path, MaxBackups: 2})
+	require.NoError(t, err)
+	defer s.Close()
+
+	// a max size below the size of an event rotates the file on every write
+	s.maxSize = 1
+
+	for _, uuid := range []string{"first", "second", "third", "fourth"} {
+		require.NoError(t, s.Write(context.Background(), testEvent(uuid, requestStatusBlocked)))
+	}
+
+	for file, uuid := range map[string]string{
+		path:        "fourth",
+		path + ".1": "third",
+		path + ".2": "second",
+	} {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(data), "\n"), file)
+		assert.Contains(t, string(data), `"uuid":"`+uuid+`"`, file)
+	}
+
+	_, err = os.Stat(path + ".3")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
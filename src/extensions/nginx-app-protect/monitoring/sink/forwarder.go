@@ -0,0 +1,105 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sink
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	models "github.com/nginx/agent/sdk/v2/proto/events"
+)
+
+// Forwarder forwards security violation events to the local sinks. Events are buffered
+// so that slow sinks don't block the reports sent to the management plane, events are
+// dropped when the buffer is full.
+type Forwarder struct {
+	sinks         []Sink
+	events        chan *models.Event
+	flushInterval time.Duration
+}
+
+// NewForwarder creates a forwarder for the sinks enabled in the config
+func NewForwarder(conf Config) (*Forwarder, error) {
+	sinks, err := NewSinks(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	bufferSize := conf.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	flushInterval := conf.Webhook.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultWebhookFlushInterval
+	}
+
+	return newForwarder(sinks, bufferSize, flushInterval), nil
+}
+
+func newForwarder(sinks []Sink, bufferSize int, flushInterval time.Duration) *Forwarder {
+	return &Forwarder{
+		sinks:         sinks,
+		events:        make(chan *models.Event, bufferSize),
+		flushInterval: flushInterval,
+	}
+}
+
+// Forward queues the event for the sinks without blocking
+func (f *Forwarder) Forward(event *models.Event) {
+	if event.GetSecurityViolationEvent() == nil {
+		return
+	}
+
+	select {
+	case f.events <- event:
+	default:
+		log.Warnf("Security violation event sink buffer is full, dropping event %s", event.GetMetadata().GetUUID())
+	}
+}
+
+// Run writes the queued events to the sinks until the context is done, the sinks are
+// flushed periodically and closed when Run returns
+func (f *Forwarder) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.flushInterval)
+	defer ticker.Stop()
+	defer f.close()
+
+	for {
+		select {
+		case event := <-f.events:
+			for _, s := range f.sinks {
+				if err := s.Write(ctx, event); err != nil {
+					log.Warnf("Unable to forward security violation event: %v", err)
+				}
+			}
+		case <-ticker.C:
+			f.flush(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (f *Forwarder) flush(ctx context.Context) {
+	for _, s := range f.sinks {
+		if err := s.Flush(ctx); err != nil {
+			log.Warnf("Unable to flush security violation events: %v", err)
+		}
+	}
+}
+
+func (f *Forwarder) close() {
+	for _, s := range f.sinks {
+		if err := s.Close(); err != nil {
+			log.Warnf("Unable to close security violation event sink: %v", err)
+		}
+	}
+}
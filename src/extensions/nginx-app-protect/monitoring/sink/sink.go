@@ -0,0 +1,142 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sink
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	models "github.com/nginx/agent/sdk/v2/proto/events"
+)
+
+const (
+	defaultBufferSize = 10000
+)
+
+// Sink is the interface implemented by the local destinations of security violation events.
+type Sink interface {
+	// Write sends the event to the sink, sinks which batch events may buffer it until Flush
+	Write(ctx context.Context, event *models.Event) error
+	// Flush sends the buffered events of the sink
+	Flush(ctx context.Context) error
+	// Close flushes the buffered events and releases the resources of the sink
+	Close() error
+}
+
+// Config holds the config of the local sinks of security violation events,
+// a sink is enabled when its address, path or url is set.
+type Config struct {
+	BufferSize int           `mapstructure:"buffer_size" yaml:"-"`
+	Syslog     SyslogConfig  `mapstructure:"syslog" yaml:"-"`
+	File       FileConfig    `mapstructure:"file" yaml:"-"`
+	Webhook    WebhookConfig `mapstructure:"webhook" yaml:"-"`
+}
+
+// Enabled reports whether any sink is configured
+func (c Config) Enabled() bool {
+	return c.Syslog.Address != "" || c.File.Path != "" || c.Webhook.URL != ""
+}
+
+// NewSinks creates the sinks enabled in the config
+func NewSinks(conf Config) ([]Sink, error) {
+	sinks := []Sink{}
+
+	if conf.Syslog.Address != "" {
+		s, err := NewSyslogSink(conf.Syslog)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+
+	if conf.File.Path != "" {
+		s, err := NewFileSink(conf.File)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+
+	if conf.Webhook.URL != "" {
+		s, err := NewWebhookSink(conf.Webhook)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+
+	return sinks, nil
+}
+
+func closeSinks(sinks []Sink) {
+	for _, s := range sinks {
+		_ = s.Close()
+	}
+}
+
+// record is the JSON representation of a security violation event in the sinks,
+// the fields of the event metadata are flattened next to the fields of the violation.
+type record struct {
+	Timestamp     string `json:"timestamp"`
+	UUID          string `json:"uuid"`
+	CorrelationID string `json:"correlation_id"`
+	Module        string `json:"module"`
+	Type          string `json:"type"`
+	Category      string `json:"category"`
+	EventLevel    string `json:"event_level"`
+	*models.SecurityViolationEvent
+}
+
+func newRecord(event *models.Event) record {
+	r := record{SecurityViolationEvent: event.GetSecurityViolationEvent()}
+	if metadata := event.GetMetadata(); metadata != nil {
+		r.UUID = metadata.GetUUID()
+		r.CorrelationID = metadata.GetCorrelationID()
+		r.Module = metadata.GetModule()
+		r.Type = metadata.GetType()
+		r.Category = metadata.GetCategory()
+		r.EventLevel = metadata.GetEventLevel()
+		if ts := metadata.GetTimestamp(); ts != nil {
+			r.Timestamp = time.Unix(ts.GetSeconds(), int64(ts.GetNanos())).UTC().Format(time.RFC3339Nano)
+		}
+	}
+	return r
+}
+
+func marshalEvent(event *models.Event) ([]byte, error) {
+	data, err := json.Marshal(newRecord(event))
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal security violation event: %w", err)
+	}
+	return data, nil
+}
+
+func tlsConfig(ca string) (*tls.Config, error) {
+	conf := &tls.Config{MinVersion: tls.VersionTLS12}
+	if ca == "" {
+		return conf, nil
+	}
+
+	pem, err := os.ReadFile(ca)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the sink CA %s: %w", ca, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in the sink CA %s", ca)
+	}
+	conf.RootCAs = pool
+	return conf, nil
+}
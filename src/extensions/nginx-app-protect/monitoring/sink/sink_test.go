@@ -0,0 +1,164 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	models "github.com/nginx/agent/sdk/v2/proto/events"
+)
+
+func testEvent(uuid string, requestStatus string) *models.Event {
+	return &models.Event{
+		Metadata: &models.Metadata{
+			Module:     "Agent",
+			UUID:       uuid,
+			Timestamp:  &types.Timestamp{Seconds: 1700000000},
+			EventLevel: "ERROR",
+			Type:       "Nginx",
+			Category:   "AppProtect",
+		},
+		Data: &models.Event_SecurityViolationEvent{
+			SecurityViolationEvent: &models.SecurityViolationEvent{
+				PolicyName:     "app_protect_default_policy",
+				SupportID:      "4355056874564592513",
+				RequestStatus:  requestStatus,
+				RemoteAddr:     "127.0.0.1",
+				ParentHostname: "nginx-host",
+			},
+		},
+	}
+}
+
+func TestMarshalEvent(t *testing.T) {
+	data, err := marshalEvent(testEvent("a1b2", requestStatusBlocked))
+	require.NoError(t, err)
+
+	fields := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, "2023-11-14T22:13:20Z", fields["timestamp"])
+	assert.Equal(t, "a1b2", fields["uuid"])
+	assert.Equal(t, "AppProtect", fields["category"])
+	assert.Equal(t, "app_protect_default_policy", fields["policy_name"])
+	assert.Equal(t, "4355056874564592513", fields["support_id"])
+	assert.Equal(t, "blocked", fields["request_status"])
+}
+
+func TestNewSinks(t *testing.T) {
+	tests := []struct {
+		name          string
+		conf          Config
+		expectedSinks int
+		expectedError string
+	}{
+		{
+			name: "no sinks",
+			conf: Config{},
+		},
+		{
+			name: "all sinks",
+			conf: Config{
+				Syslog:  SyslogConfig{Address: "127.0.0.1:514"},
+				File:    FileConfig{Path: filepath.Join(t.TempDir(), "events.log")},
+				Webhook: WebhookConfig{URL: "https://siem.example.com/events"},
+			},
+			expectedSinks: 3,
+		},
+		{
+			name:          "invalid syslog address",
+			conf:          Config{Syslog: SyslogConfig{Network: "tcp", Address: "siem.example.com"}},
+			expectedError: "invalid syslog sink address 'siem.example.com'",
+		},
+		{
+			name:          "missing file directory",
+			conf:          Config{File: FileConfig{Path: filepath.Join(t.TempDir(), "missing", "events.log")}},
+			expectedError: "unable to open file sink",
+		},
+		{
+			name:          "invalid webhook url",
+			conf:          Config{Webhook: WebhookConfig{URL: "siem.example.com/events"}},
+			expectedError: "webhook sink url 'siem.example.com/events' is not a valid http or https url",
+		},
+		{
+			name: "missing webhook ca",
+			conf: Config{Webhook: WebhookConfig{
+				URL: "https://siem.example.com/events",
+				Ca:  filepath.Join(t.TempDir(), "ca.pem"),
+			}},
+			expectedError: "unable to read the sink CA",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sinks, err := NewSinks(tt.conf)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, sinks, tt.expectedSinks)
+			assert.Equal(t, tt.expectedSinks > 0, tt.conf.Enabled())
+			closeSinks(sinks)
+		})
+	}
+}
+
+type sinkStub struct {
+	events chan *models.Event
+	closed chan struct{}
+}
+
+func (s *sinkStub) Write(_ context.Context, event *models.Event) error {
+	s.events <- event
+	return nil
+}
+
+func (s *sinkStub) Flush(context.Context) error {
+	return nil
+}
+
+func (s *sinkStub) Close() error {
+	close(s.closed)
+	return nil
+}
+
+func TestForwarder(t *testing.T) {
+	stub := &sinkStub{events: make(chan *models.Event, 2), closed: make(chan struct{})}
+	forwarder := newForwarder([]Sink{stub}, 1, time.Hour)
+
+	forwarder.Forward(testEvent("first", requestStatusBlocked))
+	// the buffer is full, so the event is dropped
+	forwarder.Forward(testEvent("dropped", requestStatusBlocked))
+	// only security violation events are forwarded
+	forwarder.Forward(&models.Event{Metadata: &models.Metadata{UUID: "other"}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go forwarder.Run(ctx)
+
+	assert.Equal(t, "first", (<-stub.events).GetMetadata().GetUUID())
+
+	forwarder.Forward(testEvent("second", "passed"))
+	assert.Equal(t, "second", (<-stub.events).GetMetadata().GetUUID())
+
+	cancel()
+	select {
+	case <-stub.closed:
+	case <-time.After(time.Second):
+		t.Fatal("sink was not closed")
+	}
+	assert.Empty(t, stub.events)
+}
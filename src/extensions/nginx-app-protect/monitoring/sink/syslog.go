@@ -0,0 +1,200 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sink
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	models "github.com/nginx/agent/sdk/v2/proto/events"
+)
+
+const (
+	SyslogUDP = "udp"
+	SyslogTCP = "tcp"
+	SyslogTLS = "tls"
+
+	defaultSyslogAppName = "nginx-app-protect"
+	syslogFacilityLocal0 = 16
+	syslogSeverityWarn   = 4
+	syslogSeverityNotice = 5
+	syslogMsgID          = "security-violation"
+	syslogNilValue       = "-"
+	syslogDialTimeout    = 10 * time.Second
+	syslogWriteTimeout   = 10 * time.Second
+	requestStatusBlocked = "blocked"
+)
+
+// SyslogConfig holds the config of the syslog sink, network is one of udp, tcp or tls.
+type SyslogConfig struct {
+	Network string `mapstructure:"network" yaml:"-"`
+	Address string `mapstructure:"address" yaml:"-"`
+	AppName string `mapstructure:"app_name" yaml:"-"`
+	Ca      string `mapstructure:"ca" yaml:"-"`
+}
+
+// SyslogSink sends security violation events as RFC 5424 messages with the JSON
+// representation of the event as the message. Messages sent over TCP and TLS are
+// framed with octet counting as described in RFC 6587 and RFC 5425.
+type SyslogSink struct {
+	network      string
+	address      string
+	appName      string
+	procID       string
+	tlsConfig    *tls.Config
+	writeTimeout time.Duration
+	conn         net.Conn
+}
+
+// NewSyslogSink creates a syslog sink, the connection is established on the first write
+func NewSyslogSink(conf SyslogConfig) (*SyslogSink, error) {
+	network := strings.ToLower(conf.Network)
+	if network == "" {
+		network = SyslogUDP
+	}
+
+	s := &SyslogSink{
+		network:      network,
+		address:      conf.Address,
+		appName:      conf.AppName,
+		procID:       fmt.Sprint(os.Getpid()),
+		writeTimeout: syslogWriteTimeout,
+	}
+	if s.appName == "" {
+		s.appName = defaultSyslogAppName
+	}
+
+	switch network {
+	case SyslogUDP, SyslogTCP:
+	case SyslogTLS:
+		tlsConf, err := tlsConfig(conf.Ca)
+		if err != nil {
+			return nil, err
+		}
+		s.tlsConfig = tlsConf
+	default:
+		return nil, fmt.Errorf("syslog sink network '%s' is not one of %s, %s or %s", conf.Network, SyslogUDP, SyslogTCP, SyslogTLS)
+	}
+
+	if _, _, err := net.SplitHostPort(conf.Address); err != nil {
+		return nil, fmt.Errorf("invalid syslog sink address '%s': %w", conf.Address, err)
+	}
+
+	return s, nil
+}
+
+func (s *SyslogSink) Write(ctx context.Context, event *models.Event) error {
+	msg, err := s.format(event)
+	if err != nil {
+		return err
+	}
+
+	// the connection is dialed again once if the syslog server closed it or stopped reading
+	// from it, a message that was partly written before the write timed out would corrupt the
+	// framing of the following messages, so the connection is never reused after an error
+	for attempt := 0; ; attempt++ {
+		if s.conn == nil {
+			if err := s.dial(ctx); err != nil {
+				return err
+			}
+		}
+		err = s.write(msg)
+		if err == nil {
+			return nil
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+		if attempt > 0 {
+			return fmt.Errorf("unable to write to syslog sink %s: %w", s.address, err)
+		}
+	}
+}
+
+// write writes the message with a deadline, so a syslog server that stops reading from a TCP
+// or TLS connection doesn't block the sink
+func (s *SyslogSink) write(msg []byte) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.writeTimeout)); err != nil {
+		return err
+	}
+	_, err := s.conn.Write(msg)
+	return err
+}
+
+func (s *SyslogSink) Flush(context.Context) error {
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *SyslogSink) dial(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: syslogDialTimeout}
+
+	var err error
+	switch s.network {
+	case SyslogTLS:
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.tlsConfig}
+		s.conn, err = tlsDialer.DialContext(ctx, "tcp", s.address)
+	default:
+		s.conn, err = dialer.DialContext(ctx, s.network, s.address)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to connect to syslog sink %s: %w", s.address, err)
+	}
+	return nil
+}
+
+func (s *SyslogSink) format(event *models.Event) ([]byte, error) {
+	data, err := marshalEvent(event)
+	if err != nil {
+		return nil, err
+	}
+
+	severity := syslogSeverityNotice
+	hostname := syslogNilValue
+	if violation := event.GetSecurityViolationEvent(); violation != nil {
+		if violation.GetRequestStatus() == requestStatusBlocked {
+			severity = syslogSeverityWarn
+		}
+		if violation.GetParentHostname() != "" {
+			hostname = violation.GetParentHostname()
+		}
+	}
+
+	timestamp := syslogNilValue
+	if ts := event.GetMetadata().GetTimestamp(); ts != nil {
+		timestamp = time.Unix(ts.GetSeconds(), int64(ts.GetNanos())).UTC().Format(time.RFC3339Nano)
+	}
+
+	msg := fmt.Sprintf("<%d>1 %s %s %s %s %s %s %s",
+		syslogFacilityLocal0*8+severity,
+		timestamp,
+		hostname,
+		s.appName,
+		s.procID,
+		syslogMsgID,
+		syslogNilValue,
+		data,
+	)
+
+	if s.network == SyslogUDP {
+		return []byte(msg), nil
+	}
+	return []byte(fmt.Sprintf("%d %s", len(msg), msg)), nil
+}
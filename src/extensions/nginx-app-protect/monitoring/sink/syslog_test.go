@@ -0,0 +1,155 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sink
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSyslogSink(t *testing.T) {
+	tests := []struct {
+		name          string
+		conf          SyslogConfig
+		expectedError string
+	}{
+		{
+			name: "default network",
+			conf: SyslogConfig{Address: "127.0.0.1:514"},
+		},
+		{
+			name: "tls",
+			conf: SyslogConfig{Network: "TLS", Address: "siem.example.com:6514"},
+		},
+		{
+			name:          "invalid network",
+			conf:          SyslogConfig{Network: "unix", Address: "/dev/log"},
+			expectedError: "syslog sink network 'unix' is not one of udp, tcp or tls",
+		},
+		{
+			name:          "missing port",
+			conf:          SyslogConfig{Network: "udp", Address: "127.0.0.1"},
+			expectedError: "invalid syslog sink address '127.0.0.1'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSyslogSink(tt.conf)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestSyslogSink_UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	s, err := NewSyslogSink(SyslogConfig{Network: "udp", Address: conn.LocalAddr().String()})
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Write(context.Background(), testEvent("a1b2", requestStatusBlocked)))
+
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	msg := string(buf[:n])
+	assert.True(t, strings.HasPrefix(msg, fmt.Sprintf("<132>1 2023-11-14T22:13:20Z nginx-host nginx-app-protect %d security-violation - {", os.Getpid())), msg)
+	assert.Contains(t, msg, `"uuid":"a1b2"`)
+	assert.Contains(t, msg, `"support_id":"4355056874564592513"`)
+}
+
+// readSyslogMessages reads the octet counted messages of the first connection to the listener
+func readSyslogMessages(listener net.Listener, messages chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		length, err := reader.ReadString(' ')
+		if err != nil {
+			return
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(length))
+		if err != nil {
+			return
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(reader, msg); err != nil {
+			return
+		}
+		messages <- string(msg)
+	}
+}
+
+func TestSyslogSink_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	messages := make(chan string, 2)
+	go readSyslogMessages(listener, messages)
+
+	s, err := NewSyslogSink(SyslogConfig{Network: "tcp", Address: listener.Addr().String(), AppName: "waf"})
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.Write(context.Background(), testEvent("first", requestStatusBlocked)))
+	require.NoError(t, s.Write(context.Background(), testEvent("second", "passed")))
+
+	first := <-messages
+	assert.True(t, strings.HasPrefix(first, "<132>1 2023-11-14T22:13:20Z nginx-host waf "), first)
+	assert.Contains(t, first, `"uuid":"first"`)
+
+	second := <-messages
+	assert.True(t, strings.HasPrefix(second, "<133>1 "), second)
+	assert.Contains(t, second, `"uuid":"second"`)
+}
+
+func TestSyslogSink_WriteTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	messages := make(chan string, 1)
+	go readSyslogMessages(listener, messages)
+
+	s, err := NewSyslogSink(SyslogConfig{Network: "tcp", Address: listener.Addr().String()})
+	require.NoError(t, err)
+	defer s.Close()
+
+	// a connection that is never read from blocks the write until the deadline
+	stalled, peer := net.Pipe()
+	defer peer.Close()
+	s.conn = stalled
+	s.writeTimeout = 50 * time.Millisecond
+
+	require.NoError(t, s.Write(context.Background(), testEvent("first", requestStatusBlocked)))
+	assert.Contains(t, <-messages, `"uuid":"first"`)
+	assert.NotEqual(t, stalled, s.conn)
+}
@@ -0,0 +1,132 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	models "github.com/nginx/agent/sdk/v2/proto/events"
+)
+
+const (
+	defaultWebhookBatchSize     = 100
+	defaultWebhookFlushInterval = 10 * time.Second
+	defaultWebhookTimeout       = 10 * time.Second
+)
+
+// WebhookConfig holds the config of the webhook sink. Events are posted once the batch
+// size is reached or the flush interval elapsed.
+type WebhookConfig struct {
+	URL           string            `mapstructure:"url" yaml:"-"`
+	Headers       map[string]string `mapstructure:"headers" yaml:"-"`
+	BatchSize     int               `mapstructure:"batch_size" yaml:"-"`
+	FlushInterval time.Duration     `mapstructure:"flush_interval" yaml:"-"`
+	Timeout       time.Duration     `mapstructure:"timeout" yaml:"-"`
+	Ca            string            `mapstructure:"ca" yaml:"-"`
+}
+
+// WebhookSink posts batches of security violation events as a JSON array
+type WebhookSink struct {
+	url       string
+	headers   map[string]string
+	batchSize int
+	client    *http.Client
+	batch     []record
+}
+
+// NewWebhookSink creates a webhook sink for the url of the config
+func NewWebhookSink(conf WebhookConfig) (*WebhookSink, error) {
+	u, err := url.Parse(conf.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("webhook sink url '%s' is not a valid http or https url", conf.URL)
+	}
+
+	s := &WebhookSink{
+		url:       conf.URL,
+		headers:   conf.Headers,
+		batchSize: conf.BatchSize,
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = defaultWebhookBatchSize
+	}
+
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if conf.Ca != "" {
+		tlsConf, err := tlsConfig(conf.Ca)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConf
+	}
+	s.client = &http.Client{Transport: transport, Timeout: timeout}
+	s.batch = make([]record, 0, s.batchSize)
+
+	return s, nil
+}
+
+func (s *WebhookSink) Write(ctx context.Context, event *models.Event) error {
+	s.batch = append(s.batch, newRecord(event))
+	if len(s.batch) < s.batchSize {
+		return nil
+	}
+	return s.Flush(ctx)
+}
+
+func (s *WebhookSink) Flush(ctx context.Context) error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+	// the batch is dropped on failure so that an unavailable webhook doesn't grow it without bounds
+	defer func() {
+		s.batch = s.batch[:0]
+	}()
+
+	body, err := json.Marshal(s.batch)
+	if err != nil {
+		return fmt.Errorf("unable to marshal security violation events: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create webhook sink request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to post %d events to webhook sink: %w", len(s.batch), err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook sink responded with status %d to %d events", resp.StatusCode, len(s.batch))
+	}
+	return nil
+}
+
+func (s *WebhookSink) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.client.Timeout)
+	defer cancel()
+	return s.Flush(ctx)
+}
@@ -0,0 +1,77 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSink(t *testing.T) {
+	batches := make(chan []map[string]interface{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		batch := []map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		batches <- batch
+	}))
+	defer server.Close()
+
+	s, err := NewWebhookSink(WebhookConfig{
+		URL:       server.URL,
+		Headers:   map[string]string{"Authorization": "Bearer token"},
+		BatchSize: 2,
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, s.Write(ctx, testEvent("first", requestStatusBlocked)))
+	assert.Empty(t, batches)
+
+	// the batch is posted once it is full
+	require.NoError(t, s.Write(ctx, testEvent("second", requestStatusBlocked)))
+	batch := <-batches
+	require.Len(t, batch, 2)
+	assert.Equal(t, "first", batch[0]["uuid"])
+	assert.Equal(t, "second", batch[1]["uuid"])
+
+	// the remaining events are posted on close
+	require.NoError(t, s.Write(ctx, testEvent("third", "passed")))
+	require.NoError(t, s.Close())
+	batch = <-batches
+	require.Len(t, batch, 1)
+	assert.Equal(t, "third", batch[0]["uuid"])
+
+	// nothing is posted without events
+	require.NoError(t, s.Flush(ctx))
+	assert.Empty(t, batches)
+}
+
+func TestWebhookSink_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s, err := NewWebhookSink(WebhookConfig{URL: server.URL})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Write(context.Background(), testEvent("first", requestStatusBlocked)))
+	assert.EqualError(t, s.Flush(context.Background()), "webhook sink responded with status 503 to 1 events")
+	// the failed batch is dropped
+	assert.Empty(t, s.batch)
+}
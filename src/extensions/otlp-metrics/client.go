@@ -0,0 +1,193 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package otlp_metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	sdkGRPC "github.com/nginx/agent/sdk/v2/grpc"
+	"github.com/nginx/agent/v2/src/core/config"
+
+	"github.com/cenkalti/backoff/v4"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+
+	httpMetricsPath   = "/v1/metrics"
+	httpContentType   = "application/x-protobuf"
+	maxErrorBodyBytes = 1024
+)
+
+// Client exports metrics to an OpenTelemetry collector. Errors that will not succeed on
+// retry are wrapped with backoff.Permanent.
+type Client interface {
+	Export(ctx context.Context, request *collectormetrics.ExportMetricsServiceRequest) error
+	Close() error
+}
+
+// NewClient returns a client for the protocol set in the OTLP config
+func NewClient(conf config.OTLP) (Client, error) {
+	switch strings.ToLower(conf.Protocol) {
+	case ProtocolGRPC, "":
+		return newGrpcClient(conf)
+	case ProtocolHTTP:
+		return newHttpClient(conf)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q", conf.Protocol)
+	}
+}
+
+type grpcClient struct {
+	conn    *grpc.ClientConn
+	client  collectormetrics.MetricsServiceClient
+	headers metadata.MD
+}
+
+func newGrpcClient(conf config.OTLP) (*grpcClient, error) {
+	secureDialOption, err := sdkGRPC.SecureDialOptions(
+		conf.TLS.Enable,
+		conf.TLS.Cert,
+		conf.TLS.Key,
+		conf.TLS.Ca,
+		"",
+		conf.TLS.SkipVerify,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := sdkGRPC.NewGrpcConnectionWithContext(context.Background(), conf.Endpoint, []grpc.DialOption{secureDialOption})
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to OTLP endpoint %s: %w", conf.Endpoint, err)
+	}
+
+	return &grpcClient{
+		conn:    conn,
+		client:  collectormetrics.NewMetricsServiceClient(conn),
+		headers: metadata.New(conf.Headers),
+	}, nil
+}
+
+func (c *grpcClient) Export(ctx context.Context, request *collectormetrics.ExportMetricsServiceRequest) error {
+	if len(c.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, c.headers)
+	}
+
+	response, err := c.client.Export(ctx, request)
+	if err != nil {
+		switch status.Code(err) {
+		case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied, codes.Unimplemented:
+			return backoff.Permanent(err)
+		default:
+			return err
+		}
+	}
+
+	if rejected := response.GetPartialSuccess().GetRejectedDataPoints(); rejected > 0 {
+		return backoff.Permanent(fmt.Errorf("OTLP collector rejected %d data points: %s", rejected, response.GetPartialSuccess().GetErrorMessage()))
+	}
+
+	return nil
+}
+
+func (c *grpcClient) Close() error {
+	return c.conn.Close()
+}
+
+type httpClient struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newHttpClient(conf config.OTLP) (*httpClient, error) {
+	url := strings.TrimSuffix(conf.Endpoint, "/")
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		if conf.TLS.Enable {
+			url = "https://" + url
+		} else {
+			url = "http://" + url
+		}
+	}
+	if !strings.HasSuffix(url, httpMetricsPath) {
+		url += httpMetricsPath
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if conf.TLS.Enable {
+		tlsConfig, err := sdkGRPC.NewTLSConfig(conf.TLS.Cert, conf.TLS.Key, conf.TLS.Ca, "", conf.TLS.SkipVerify)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure tls: %w", err)
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &httpClient{
+		url:     url,
+		headers: conf.Headers,
+		client:  &http.Client{Transport: transport},
+	}, nil
+}
+
+func (c *httpClient) Export(ctx context.Context, request *collectormetrics.ExportMetricsServiceRequest) error {
+	body, err := protobuf.Marshal(request)
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return backoff.Permanent(err)
+	}
+	req.Header.Set("Content-Type", httpContentType)
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	err = fmt.Errorf("OTLP collector responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return err
+	default:
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return backoff.Permanent(err)
+		}
+		return err
+	}
+}
+
+func (c *httpClient) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
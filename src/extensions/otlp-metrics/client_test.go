@@ -0,0 +1,157 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package otlp_metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nginx/agent/v2/src/core/config"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+type mockMetricsService struct {
+	collectormetrics.UnimplementedMetricsServiceServer
+	requests chan *collectormetrics.ExportMetricsServiceRequest
+	headers  chan metadata.MD
+}
+
+func (m *mockMetricsService) Export(ctx context.Context, request *collectormetrics.ExportMetricsServiceRequest) (*collectormetrics.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	m.headers <- md
+	m.requests <- request
+	return &collectormetrics.ExportMetricsServiceResponse{}, nil
+}
+
+func testRequest() *collectormetrics.ExportMetricsServiceRequest {
+	return &collectormetrics.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			{ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: []*metricspb.Metric{{Name: "nginx.http.request.count"}}}}},
+		},
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	client, err := NewClient(config.OTLP{Endpoint: "127.0.0.1:4317", Protocol: "grpc"})
+	require.NoError(t, err)
+	assert.IsType(t, &grpcClient{}, client)
+	assert.NoError(t, client.Close())
+
+	client, err = NewClient(config.OTLP{Endpoint: "127.0.0.1:4318", Protocol: "http"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:4318/v1/metrics", client.(*httpClient).url)
+
+	client, err = NewClient(config.OTLP{Endpoint: "127.0.0.1:4318", Protocol: "http", TLS: config.TLSConfig{Enable: true}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://127.0.0.1:4318/v1/metrics", client.(*httpClient).url)
+
+	_, err = NewClient(config.OTLP{Endpoint: "127.0.0.1:4318", Protocol: "udp"})
+	assert.Error(t, err)
+}
+
+func TestGrpcClient_Export(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	service := &mockMetricsService{
+		requests: make(chan *collectormetrics.ExportMetricsServiceRequest, 1),
+		headers:  make(chan metadata.MD, 1),
+	}
+	server := grpc.NewServer()
+	collectormetrics.RegisterMetricsServiceServer(server, service)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	client, err := NewClient(config.OTLP{
+		Endpoint: listener.Addr().String(),
+		Protocol: "grpc",
+		Headers:  map[string]string{"api-key": "secret"},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.NoError(t, client.Export(context.Background(), testRequest()))
+
+	request := <-service.requests
+	assert.Equal(t, "nginx.http.request.count", request.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name)
+	assert.Equal(t, []string{"secret"}, (<-service.headers).Get("api-key"))
+}
+
+func TestHttpClient_Export(t *testing.T) {
+	requests := make(chan *collectormetrics.ExportMetricsServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, httpMetricsPath, r.URL.Path)
+		assert.Equal(t, httpContentType, r.Header.Get("Content-Type"))
+		assert.Equal(t, "secret", r.Header.Get("api-key"))
+
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		request := &collectormetrics.ExportMetricsServiceRequest{}
+		assert.NoError(t, protobuf.Unmarshal(body, request))
+		requests <- request
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.OTLP{
+		Endpoint: server.URL,
+		Protocol: "http",
+		Headers:  map[string]string{"api-key": "secret"},
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.NoError(t, client.Export(context.Background(), testRequest()))
+
+	request := <-requests
+	assert.Equal(t, "nginx.http.request.count", request.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].Name)
+}
+
+func TestHttpClient_ExportErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		permanent  bool
+	}{
+		{name: "bad request", statusCode: http.StatusBadRequest, permanent: true},
+		{name: "too many requests", statusCode: http.StatusTooManyRequests, permanent: false},
+		{name: "service unavailable", statusCode: http.StatusServiceUnavailable, permanent: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+
+			client, err := NewClient(config.OTLP{Endpoint: server.URL, Protocol: "http"})
+			require.NoError(t, err)
+
+			err = client.Export(context.Background(), testRequest())
+			require.Error(t, err)
+
+			var permanent *backoff.PermanentError
+			assert.Equal(t, tt.permanent, errors.As(err, &permanent))
+		})
+	}
+}
@@ -0,0 +1,222 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package otlp_metrics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core/metrics"
+
+	"github.com/gogo/protobuf/types"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+const (
+	scopeName       = "nginx-agent"
+	histogramSuffix = ".histogram"
+)
+
+// Resource describes the entity producing the exported metrics
+type Resource struct {
+	ServiceName    string
+	ServiceVersion string
+	HostName       string
+	HostID         string
+}
+
+// ToExportRequest converts metrics reports into an OTLP export request. Metrics that are
+// summed up over the collection interval are exported as delta counters, histograms are
+// exported as delta exponential histograms and every other metric is exported as a gauge.
+func ToExportRequest(resource *Resource, reports []*proto.MetricsReport, collectionInterval time.Duration) *collectormetrics.ExportMetricsServiceRequest {
+	otlpMetrics := make(map[string]*metricspb.Metric)
+
+	for _, report := range reports {
+		for _, statsEntity := range report.GetData() {
+			timestamp := getTimestamp(statsEntity.GetTimestamp(), report.GetMeta().GetTimestamp())
+			attributes := convertDimensionsToAttributes(statsEntity.GetDimensions())
+
+			histograms, simpleMetrics := metrics.HistogramsFromSimpleMetrics(statsEntity.GetSimplemetrics())
+			for _, metric := range simpleMetrics {
+				otlpMetric, ok := otlpMetrics[metric.GetName()]
+				if !ok {
+					otlpMetric = newMetric(metric.GetName())
+					otlpMetrics[metric.GetName()] = otlpMetric
+				}
+				addDataPoint(otlpMetric, metric.GetValue(), attributes, timestamp, collectionInterval)
+			}
+
+			for name, histogram := range histograms {
+				name += histogramSuffix
+				otlpMetric, ok := otlpMetrics[name]
+				if !ok {
+					otlpMetric = newHistogramMetric(name)
+					otlpMetrics[name] = otlpMetric
+				}
+				addHistogramDataPoint(otlpMetric, histogram, attributes, timestamp, collectionInterval)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(otlpMetrics))
+	for name := range otlpMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	scopeMetrics := &metricspb.ScopeMetrics{
+		Scope:   &commonpb.InstrumentationScope{Name: scopeName, Version: resource.ServiceVersion},
+		Metrics: make([]*metricspb.Metric, 0, len(names)),
+	}
+	for _, name := range names {
+		scopeMetrics.Metrics = append(scopeMetrics.Metrics, otlpMetrics[name])
+	}
+
+	return &collectormetrics.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			{
+				Resource:     convertResource(resource),
+				ScopeMetrics: []*metricspb.ScopeMetrics{scopeMetrics},
+			},
+		},
+	}
+}
+
+func newMetric(name string) *metricspb.Metric {
+	if isCounter(name) {
+		return &metricspb.Metric{
+			Name: name,
+			Data: &metricspb.Metric_Sum{
+				Sum: &metricspb.Sum{
+					AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+					IsMonotonic:            true,
+				},
+			},
+		}
+	}
+
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_Gauge{
+			Gauge: &metricspb.Gauge{},
+		},
+	}
+}
+
+func addDataPoint(metric *metricspb.Metric, value float64, attributes []*commonpb.KeyValue, timestamp time.Time, collectionInterval time.Duration) {
+	dataPoint := &metricspb.NumberDataPoint{
+		Attributes:   attributes,
+		TimeUnixNano: uint64(timestamp.UnixNano()),
+		Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+	}
+
+	switch data := metric.Data.(type) {
+	case *metricspb.Metric_Sum:
+		dataPoint.StartTimeUnixNano = uint64(timestamp.Add(-collectionInterval).UnixNano())
+		data.Sum.DataPoints = append(data.Sum.DataPoints, dataPoint)
+	case *metricspb.Metric_Gauge:
+		data.Gauge.DataPoints = append(data.Gauge.DataPoints, dataPoint)
+	}
+}
+
+func newHistogramMetric(name string) *metricspb.Metric {
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_ExponentialHistogram{
+			ExponentialHistogram: &metricspb.ExponentialHistogram{
+				AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+			},
+		},
+	}
+}
+
+func addHistogramDataPoint(metric *metricspb.Metric, histogram *metrics.Histogram, attributes []*commonpb.KeyValue, timestamp time.Time, collectionInterval time.Duration) {
+	sum := histogram.Sum
+	dataPoint := &metricspb.ExponentialHistogramDataPoint{
+		Attributes:        attributes,
+		StartTimeUnixNano: uint64(timestamp.Add(-collectionInterval).UnixNano()),
+		TimeUnixNano:      uint64(timestamp.UnixNano()),
+		Count:             histogram.Count,
+		Sum:               &sum,
+		Scale:             histogram.Schema,
+		ZeroCount:         histogram.ZeroCount,
+		Positive:          &metricspb.ExponentialHistogramDataPoint_Buckets{},
+	}
+
+	offsets, lengths, counts := histogram.Spans()
+	if len(offsets) > 0 {
+		// OTLP bucket i holds the values in (base^i, base^(i+1)], one below the
+		// index of the same bucket in the agent histogram
+		dataPoint.Positive.Offset = offsets[0] - 1
+		for i := range offsets {
+			if i > 0 {
+				for j := int32(0); j < offsets[i]; j++ {
+					dataPoint.Positive.BucketCounts = append(dataPoint.Positive.BucketCounts, 0)
+				}
+			}
+			dataPoint.Positive.BucketCounts = append(dataPoint.Positive.BucketCounts, counts[:lengths[i]]...)
+			counts = counts[lengths[i]:]
+		}
+	}
+
+	if data, ok := metric.Data.(*metricspb.Metric_ExponentialHistogram); ok {
+		data.ExponentialHistogram.DataPoints = append(data.ExponentialHistogram.DataPoints, dataPoint)
+	}
+}
+
+func isCounter(metricName string) bool {
+	calMap := metrics.GetCalculationMap()
+
+	if value, ok := calMap[metricName]; ok {
+		return value == "sum"
+	}
+
+	return false
+}
+
+func getTimestamp(timestamps ...*types.Timestamp) time.Time {
+	for _, timestamp := range timestamps {
+		if timestamp == nil {
+			continue
+		}
+		if t, err := types.TimestampFromProto(timestamp); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
+
+func convertDimensionsToAttributes(dimensions []*proto.Dimension) []*commonpb.KeyValue {
+	attributes := make([]*commonpb.KeyValue, 0, len(dimensions))
+	for _, dimension := range dimensions {
+		attributes = append(attributes, stringAttribute(dimension.GetName(), dimension.GetValue()))
+	}
+	return attributes
+}
+
+func convertResource(resource *Resource) *resourcepb.Resource {
+	return &resourcepb.Resource{
+		Attributes: []*commonpb.KeyValue{
+			stringAttribute("service.name", resource.ServiceName),
+			stringAttribute("service.version", resource.ServiceVersion),
+			stringAttribute("host.name", resource.HostName),
+			stringAttribute("host.id", resource.HostID),
+		},
+	}
+}
+
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
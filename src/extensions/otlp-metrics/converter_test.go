@@ -0,0 +1,134 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package otlp_metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core/metrics"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+func TestToExportRequest(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	timestamp, err := types.TimestampProto(now)
+	require.NoError(t, err)
+
+	reports := []*proto.MetricsReport{
+		{
+			Meta: &proto.Metadata{Timestamp: timestamp},
+			Type: proto.MetricsReport_SYSTEM,
+			Data: []*proto.StatsEntity{
+				{
+					Dimensions: []*proto.Dimension{{Name: "hostname", Value: "example.com"}},
+					Simplemetrics: []*proto.SimpleMetric{
+						{Name: "system.cpu.idle", Value: 90},
+						{Name: "system.io.iops_r", Value: 5},
+					},
+				},
+			},
+		},
+		{
+			Type: proto.MetricsReport_INSTANCE,
+			Data: []*proto.StatsEntity{
+				{
+					Timestamp:     timestamp,
+					Dimensions:    []*proto.Dimension{{Name: "nginx_id", Value: "123"}},
+					Simplemetrics: []*proto.SimpleMetric{{Name: "system.cpu.idle", Value: 80}},
+				},
+			},
+		},
+	}
+
+	resource := &Resource{ServiceName: "nginx-agent", ServiceVersion: "v2.0.0", HostName: "example.com", HostID: "456"}
+	request := ToExportRequest(resource, reports, 15*time.Second)
+
+	require.Len(t, request.ResourceMetrics, 1)
+	resourceMetrics := request.ResourceMetrics[0]
+	assert.Len(t, resourceMetrics.Resource.Attributes, 4)
+	assert.Equal(t, "service.name", resourceMetrics.Resource.Attributes[0].Key)
+	assert.Equal(t, "nginx-agent", resourceMetrics.Resource.Attributes[0].Value.GetStringValue())
+
+	require.Len(t, resourceMetrics.ScopeMetrics, 1)
+	otlpMetrics := resourceMetrics.ScopeMetrics[0].Metrics
+	require.Len(t, otlpMetrics, 2)
+
+	gauge := otlpMetrics[0]
+	assert.Equal(t, "system.cpu.idle", gauge.Name)
+	require.NotNil(t, gauge.GetGauge())
+	require.Len(t, gauge.GetGauge().DataPoints, 2)
+	assert.Equal(t, float64(90), gauge.GetGauge().DataPoints[0].GetAsDouble())
+	assert.Equal(t, uint64(now.UnixNano()), gauge.GetGauge().DataPoints[0].TimeUnixNano)
+	assert.Equal(t, "hostname", gauge.GetGauge().DataPoints[0].Attributes[0].Key)
+	assert.Equal(t, "123", gauge.GetGauge().DataPoints[1].Attributes[0].Value.GetStringValue())
+
+	sum := otlpMetrics[1]
+	assert.Equal(t, "system.io.iops_r", sum.Name)
+	require.NotNil(t, sum.GetSum())
+	assert.True(t, sum.GetSum().IsMonotonic)
+	assert.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, sum.GetSum().AggregationTemporality)
+	require.Len(t, sum.GetSum().DataPoints, 1)
+	assert.Equal(t, float64(5), sum.GetSum().DataPoints[0].GetAsDouble())
+	assert.Equal(t, uint64(now.Add(-15*time.Second).UnixNano()), sum.GetSum().DataPoints[0].StartTimeUnixNano)
+}
+
+func TestToExportRequest_NoReports(t *testing.T) {
+	request := ToExportRequest(&Resource{}, []*proto.MetricsReport{}, time.Second)
+
+	require.Len(t, request.ResourceMetrics, 1)
+	assert.Empty(t, request.ResourceMetrics[0].ScopeMetrics[0].Metrics)
+}
+
+func TestToExportRequest_Histograms(t *testing.T) {
+	histogram := metrics.NewHistogram(0)
+	for _, value := range []float64{0, 0.3, 1, 3, 100} {
+		histogram.Observe(value)
+	}
+	samples := map[string]float64{}
+	histogram.Samples("nginx.http.request.time", samples)
+
+	statsEntity := &proto.StatsEntity{Dimensions: []*proto.Dimension{{Name: "nginx_id", Value: "123"}}}
+	for name, value := range samples {
+		statsEntity.Simplemetrics = append(statsEntity.Simplemetrics, &proto.SimpleMetric{Name: name, Value: value})
+	}
+
+	request := ToExportRequest(&Resource{}, []*proto.MetricsReport{{Data: []*proto.StatsEntity{statsEntity}}}, 15*time.Second)
+
+	otlpMetrics := request.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	names := []string{}
+	for _, metric := range otlpMetrics {
+		names = append(names, metric.Name)
+	}
+	assert.Equal(t, []string{
+		"nginx.http.request.time.histogram",
+		"nginx.http.request.time.pctl50",
+		"nginx.http.request.time.pctl90",
+		"nginx.http.request.time.pctl99",
+		"nginx.http.request.time.pctl999",
+	}, names)
+
+	exponentialHistogram := otlpMetrics[0].GetExponentialHistogram()
+	require.NotNil(t, exponentialHistogram)
+	assert.Equal(t, metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, exponentialHistogram.AggregationTemporality)
+	require.Len(t, exponentialHistogram.DataPoints, 1)
+
+	dataPoint := exponentialHistogram.DataPoints[0]
+	assert.Equal(t, "123", dataPoint.Attributes[0].Value.GetStringValue())
+	assert.Equal(t, uint64(5), dataPoint.Count)
+	assert.InDelta(t, 104.3, dataPoint.GetSum(), 0.0001)
+	assert.Equal(t, int32(0), dataPoint.Scale)
+	assert.Equal(t, uint64(1), dataPoint.ZeroCount)
+	assert.Equal(t, int32(-2), dataPoint.Positive.Offset)
+	assert.Equal(t, []uint64{1, 1, 0, 1, 0, 0, 0, 0, 1}, dataPoint.Positive.BucketCounts)
+}
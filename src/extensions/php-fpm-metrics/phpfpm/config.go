@@ -0,0 +1,176 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package phpfpm
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	globalSection       = "global"
+	includeDirective    = "include"
+	listenDirective     = "listen"
+	statusPathDirective = "pm.status_path"
+	poolVariable        = "$pool"
+	maxIncludeDepth     = 8
+)
+
+// ParsePools reads the php-fpm configuration file at confPath, following include
+// directives, and returns every pool that is defined in it.
+func ParsePools(confPath string) ([]*Pool, error) {
+	pools := make(map[string]*Pool)
+	order := []string{}
+
+	err := parseFile(confPath, filepath.Dir(confPath), pools, &order, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Pool, 0, len(order))
+	for _, name := range order {
+		pool := pools[name]
+		pool.Listen = strings.ReplaceAll(pool.Listen, poolVariable, pool.Name)
+		pool.StatusPath = strings.ReplaceAll(pool.StatusPath, poolVariable, pool.Name)
+		result = append(result, pool)
+	}
+
+	return result, nil
+}
+
+func parseFile(path, prefix string, pools map[string]*Pool, order *[]string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("php-fpm config include depth exceeded at %s", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section != globalSection {
+				if _, ok := pools[section]; !ok {
+					pools[section] = &Pool{Name: section}
+					*order = append(*order, section)
+				}
+			}
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = unquote(strings.TrimSpace(value))
+
+		switch {
+		case key == includeDirective:
+			if err := parseInclude(value, prefix, pools, order, depth); err != nil {
+				return err
+			}
+		case section == "" || section == globalSection:
+			continue
+		case key == listenDirective:
+			pools[section].Listen = value
+		case key == statusPathDirective:
+			pools[section].StatusPath = value
+		}
+	}
+
+	return scanner.Err()
+}
+
+func parseInclude(pattern, prefix string, pools map[string]*Pool, order *[]string, depth int) error {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(prefix, pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	sort.Strings(matches)
+
+	for _, match := range matches {
+		if err := parseFile(match, prefix, pools, order, depth+1); err != nil {
+			log.Warnf("Unable to parse included php-fpm config %s: %v", match, err)
+		}
+	}
+
+	return nil
+}
+
+func unquote(value string) string {
+	if i := strings.Index(value, " ;"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	if len(value) >= 2 {
+		if (value[0] == '"' && value[len(value)-1] == '"') || (value[0] == '\'' && value[len(value)-1] == '\'') {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+// ListenAddress converts the listen directive of a pool into a network and address
+// that can be dialed, e.g. ("unix", "/run/php/php-fpm.sock") or ("tcp", "127.0.0.1:9000").
+func ListenAddress(listen string) (network, address string) {
+	listen = strings.TrimSpace(listen)
+	switch {
+	case listen == "":
+		return "", ""
+	case strings.HasPrefix(listen, "/"):
+		return "unix", listen
+	case !strings.Contains(listen, ":"):
+		// only a port was provided, php-fpm listens on all addresses
+		return "tcp", net.JoinHostPort("127.0.0.1", listen)
+	}
+
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return "tcp", listen
+	}
+
+	switch host {
+	case "", "*", "0.0.0.0":
+		host = "127.0.0.1"
+	case "[::]", "::":
+		host = "::1"
+	}
+
+	return "tcp", net.JoinHostPort(host, port)
+}
+
+// FormattedListen returns the listen address of a pool in the form used by the
+// dataplane software details, e.g. unix:/run/php/php-fpm.sock or 127.0.0.1:9000
+func FormattedListen(listen string) string {
+	network, address := ListenAddress(listen)
+	if network == "unix" {
+		return "unix:" + address
+	}
+	return address
+}
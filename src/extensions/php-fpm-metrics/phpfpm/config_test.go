@@ -0,0 +1,82 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package phpfpm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testMainConf = `[global]
+pid = /run/php/php7.4-fpm.pid
+error_log = /var/log/php7.4-fpm.log
+; pool definitions
+include=pool.d/*.conf
+`
+	testWwwPoolConf = `[www]
+user = www-data
+listen = /run/php/php7.4-fpm.sock
+;pm.status_path = /ignored
+pm.status_path = /status
+`
+	testApiPoolConf = `[api]
+listen = "127.0.0.1:9001" ; tcp pool
+pm.status_path = /$pool-status
+`
+)
+
+func TestParsePools(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "pool.d"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "php-fpm.conf"), []byte(testMainConf), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pool.d", "www.conf"), []byte(testWwwPoolConf), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pool.d", "api.conf"), []byte(testApiPoolConf), 0o644))
+
+	pools, err := ParsePools(filepath.Join(dir, "php-fpm.conf"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []*Pool{
+		{Name: "api", Listen: "127.0.0.1:9001", StatusPath: "/api-status"},
+		{Name: "www", Listen: "/run/php/php7.4-fpm.sock", StatusPath: "/status"},
+	}, pools)
+}
+
+func TestParsePools_MissingFile(t *testing.T) {
+	_, err := ParsePools(filepath.Join(t.TempDir(), "php-fpm.conf"))
+	assert.Error(t, err)
+}
+
+func TestListenAddress(t *testing.T) {
+	tests := []struct {
+		listen          string
+		expectedNetwork string
+		expectedAddress string
+		expectedFormat  string
+	}{
+		{"/run/php/php-fpm.sock", "unix", "/run/php/php-fpm.sock", "unix:/run/php/php-fpm.sock"},
+		{"9000", "tcp", "127.0.0.1:9000", "127.0.0.1:9000"},
+		{"127.0.0.1:9000", "tcp", "127.0.0.1:9000", "127.0.0.1:9000"},
+		{"0.0.0.0:9000", "tcp", "127.0.0.1:9000", "127.0.0.1:9000"},
+		{"[::]:9000", "tcp", "[::1]:9000", "[::1]:9000"},
+		{"", "", "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.listen, func(t *testing.T) {
+			network, address := ListenAddress(test.listen)
+			assert.Equal(t, test.expectedNetwork, network)
+			assert.Equal(t, test.expectedAddress, address)
+			assert.Equal(t, test.expectedFormat, FormattedListen(test.listen))
+		})
+	}
+}
@@ -0,0 +1,197 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package phpfpm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FastCGI record types and roles as defined in the FastCGI specification
+const (
+	fcgiVersion       uint8  = 1
+	fcgiBeginRequest  uint8  = 1
+	fcgiEndRequest    uint8  = 3
+	fcgiParams        uint8  = 4
+	fcgiStdin         uint8  = 5
+	fcgiStdout        uint8  = 6
+	fcgiStderr        uint8  = 7
+	fcgiResponder     uint16 = 1
+	fcgiRequestID     uint16 = 1
+	fcgiHeaderLen            = 8
+	fcgiMaxContentLen        = 65535
+)
+
+type fcgiHeader struct {
+	Version       uint8
+	Type          uint8
+	RequestID     uint16
+	ContentLength uint16
+	PaddingLength uint8
+	Reserved      uint8
+}
+
+// fastCGIGet performs a single GET request against a FastCGI responder listening on
+// the given network address and returns the response body.
+func fastCGIGet(ctx context.Context, network, address, path, query string, timeout time.Duration) ([]byte, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		"GATEWAY_INTERFACE": "CGI/1.1",
+		"REQUEST_METHOD":    "GET",
+		"SCRIPT_NAME":       path,
+		"SCRIPT_FILENAME":   path,
+		"REQUEST_URI":       path + "?" + query,
+		"QUERY_STRING":      query,
+		"SERVER_PROTOCOL":   "HTTP/1.1",
+		"SERVER_SOFTWARE":   "nginx-agent",
+		"REMOTE_ADDR":       "127.0.0.1",
+	}
+
+	if err := writeRequest(conn, params); err != nil {
+		return nil, err
+	}
+
+	stdout, stderr, err := readResponse(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(stdout) == 0 && len(stderr) > 0 {
+		return nil, fmt.Errorf("fastcgi error: %s", strings.TrimSpace(string(stderr)))
+	}
+
+	return parseCGIResponse(stdout)
+}
+
+func writeRequest(w io.Writer, params map[string]string) error {
+	begin := make([]byte, 8)
+	binary.BigEndian.PutUint16(begin, fcgiResponder)
+	if err := writeRecord(w, fcgiBeginRequest, begin); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for name, value := range params {
+		writeLength(&buf, len(name))
+		writeLength(&buf, len(value))
+		buf.WriteString(name)
+		buf.WriteString(value)
+	}
+
+	content := buf.Bytes()
+	for len(content) > 0 {
+		n := len(content)
+		if n > fcgiMaxContentLen {
+			n = fcgiMaxContentLen
+		}
+		if err := writeRecord(w, fcgiParams, content[:n]); err != nil {
+			return err
+		}
+		content = content[n:]
+	}
+
+	if err := writeRecord(w, fcgiParams, nil); err != nil {
+		return err
+	}
+
+	return writeRecord(w, fcgiStdin, nil)
+}
+
+func writeLength(buf *bytes.Buffer, length int) {
+	if length < 128 {
+		buf.WriteByte(byte(length))
+		return
+	}
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(length)|1<<31)
+	buf.Write(b)
+}
+
+func writeRecord(w io.Writer, recordType uint8, content []byte) error {
+	padding := uint8(-len(content) & 7)
+	header := fcgiHeader{
+		Version:       fcgiVersion,
+		Type:          recordType,
+		RequestID:     fcgiRequestID,
+		ContentLength: uint16(len(content)),
+		PaddingLength: padding,
+	}
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.BigEndian, header); err != nil {
+		return err
+	}
+	buf.Write(content)
+	buf.Write(make([]byte, padding))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func readResponse(r io.Reader) (stdout, stderr []byte, err error) {
+	reader := bufio.NewReader(r)
+	for {
+		var header fcgiHeader
+		if err := binary.Read(reader, binary.BigEndian, &header); err != nil {
+			return nil, nil, err
+		}
+
+		content := make([]byte, int(header.ContentLength)+int(header.PaddingLength))
+		if _, err := io.ReadFull(reader, content); err != nil {
+			return nil, nil, err
+		}
+		content = content[:header.ContentLength]
+
+		switch header.Type {
+		case fcgiStdout:
+			stdout = append(stdout, content...)
+		case fcgiStderr:
+			stderr = append(stderr, content...)
+		case fcgiEndRequest:
+			return stdout, stderr, nil
+		}
+	}
+}
+
+// parseCGIResponse strips the CGI headers from a FastCGI response and returns the body,
+// returning an error if the responder set a non 2xx status.
+func parseCGIResponse(response []byte) ([]byte, error) {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(response)))
+	headers, err := reader.ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if status := headers.Get("Status"); status != "" {
+		code, convErr := strconv.Atoi(strings.Fields(status)[0])
+		if convErr != nil || code < 200 || code > 299 {
+			return nil, fmt.Errorf("unexpected status %q", status)
+		}
+	}
+
+	return io.ReadAll(reader.R)
+}
@@ -0,0 +1,41 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package phpfpm
+
+const (
+	MetricPrefix = "php.fpm."
+	StatusMetric = MetricPrefix + "status"
+)
+
+// Metrics converts the status of a pool into metric samples. Counters are reported as
+// the delta since the previous status, or as the raw value if php-fpm was restarted.
+func (s *Status) Metrics(prev *Status) map[string]float64 {
+	if prev == nil {
+		prev = s
+	}
+
+	return map[string]float64{
+		MetricPrefix + "conn.accepted":   delta(s.AcceptedConn, prev.AcceptedConn),
+		MetricPrefix + "queue.current":   float64(s.ListenQueue),
+		MetricPrefix + "queue.max":       float64(s.MaxListenQueue),
+		MetricPrefix + "queue.len":       float64(s.ListenQueueLen),
+		MetricPrefix + "proc.idle":       float64(s.IdleProcesses),
+		MetricPrefix + "proc.active":     float64(s.ActiveProcesses),
+		MetricPrefix + "proc.total":      float64(s.TotalProcesses),
+		MetricPrefix + "proc.max_active": float64(s.MaxActiveProcesses),
+		MetricPrefix + "proc.max_child":  delta(s.MaxChildrenReached, prev.MaxChildrenReached),
+		MetricPrefix + "slow_req":        delta(s.SlowRequests, prev.SlowRequests),
+	}
+}
+
+func delta(current, previous uint64) float64 {
+	if current < previous {
+		return float64(current)
+	}
+	return float64(current - previous)
+}
@@ -0,0 +1,114 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package phpfpm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nginx/agent/v2/src/core"
+)
+
+const (
+	// ProcessName is the name prefix of the php-fpm processes
+	ProcessName = "php-fpm"
+	// versionTimeout bounds the run of the php-fpm binary to get its version
+	versionTimeout = 5 * time.Second
+)
+
+var (
+	masterCmdRegex = regexp.MustCompile(`^php-fpm[\d.]*: master process \((.+)\)`)
+	versionRegex   = regexp.MustCompile(`^PHP ([\d.]+\S*)`)
+	versions       = sync.Map{}
+)
+
+// GetMasters groups the php-fpm processes into their master processes, parses the
+// configuration of each master and returns the masters with their pools.
+func GetMasters(procs []*core.Process) []*Master {
+	masters := []*Master{}
+	workers := make(map[int32]int32)
+
+	for _, proc := range procs {
+		workers[proc.ParentPid]++
+	}
+
+	for _, proc := range procs {
+		if !proc.IsMaster {
+			continue
+		}
+		matches := masterCmdRegex.FindStringSubmatch(proc.Command)
+		if len(matches) < 2 {
+			continue
+		}
+
+		master := &Master{
+			Pid:      proc.Pid,
+			Name:     proc.Name,
+			Command:  proc.Command,
+			ConfPath: matches[1],
+			BinPath:  proc.Path,
+			Workers:  workers[proc.Pid],
+		}
+		master.Version, master.VersionLine = getVersion(proc.Path)
+
+		pools, err := ParsePools(master.ConfPath)
+		if err != nil {
+			log.Warnf("Unable to parse php-fpm config %s: %v", master.ConfPath, err)
+		}
+		master.Pools = pools
+
+		masters = append(masters, master)
+	}
+
+	return masters
+}
+
+// getVersion runs the php-fpm binary to get its version, caching the result per binary
+func getVersion(binPath string) (version, versionLine string) {
+	if binPath == "" {
+		return "", ""
+	}
+	if cached, ok := versions.Load(binPath); ok {
+		lines := cached.([]string)
+		return lines[0], lines[1]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, binPath, "-v").CombinedOutput()
+	if err != nil {
+		log.Debugf("Unable to get php-fpm version from %s: %v", binPath, err)
+		return "", ""
+	}
+
+	version, versionLine = parseVersion(out)
+	versions.Store(binPath, []string{version, versionLine})
+
+	return version, versionLine
+}
+
+func parseVersion(out []byte) (version, versionLine string) {
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	if !scanner.Scan() {
+		return "", ""
+	}
+	versionLine = strings.TrimSpace(scanner.Text())
+	if matches := versionRegex.FindStringSubmatch(versionLine); len(matches) > 1 {
+		version = matches[1]
+	}
+	return version, versionLine
+}
@@ -0,0 +1,50 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package phpfpm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nginx/agent/v2/src/core"
+)
+
+func TestGetMasters(t *testing.T) {
+	dir := t.TempDir()
+	confPath := filepath.Join(dir, "php-fpm.conf")
+	require.NoError(t, os.WriteFile(confPath, []byte(testWwwPoolConf), 0o644))
+
+	procs := []*core.Process{
+		{Pid: 10, ParentPid: 1, Name: "php-fpm7.4", Command: "php-fpm: master process (" + confPath + ")", IsMaster: true},
+		{Pid: 11, ParentPid: 10, Name: "php-fpm7.4", Command: "php-fpm: pool www"},
+		{Pid: 12, ParentPid: 10, Name: "php-fpm7.4", Command: "php-fpm: pool www"},
+	}
+
+	masters := GetMasters(procs)
+	require.Len(t, masters, 1)
+	assert.Equal(t, int32(10), masters[0].Pid)
+	assert.Equal(t, confPath, masters[0].ConfPath)
+	assert.Equal(t, int32(2), masters[0].Workers)
+	require.Len(t, masters[0].Pools, 1)
+	assert.Equal(t, "www", masters[0].Pools[0].Name)
+}
+
+func TestParseVersion(t *testing.T) {
+	out := []byte("PHP 7.4.33 (fpm-fcgi) (built: Feb 14 2023 18:31:23)\nCopyright (c) The PHP Group\n")
+	version, versionLine := parseVersion(out)
+	assert.Equal(t, "7.4.33", version)
+	assert.Equal(t, "PHP 7.4.33 (fpm-fcgi) (built: Feb 14 2023 18:31:23)", versionLine)
+
+	version, versionLine = parseVersion([]byte{})
+	assert.Empty(t, version)
+	assert.Empty(t, versionLine)
+}
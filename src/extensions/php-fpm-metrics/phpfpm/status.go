@@ -0,0 +1,77 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package phpfpm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const statusQuery = "json"
+
+// GetPoolStatus scrapes the status page of a pool. If statusURL is set the status page
+// is requested over HTTP, otherwise the pool's listen address is queried over FastCGI
+// using the pool's pm.status_path.
+func GetPoolStatus(ctx context.Context, pool *Pool, statusURL string, timeout time.Duration) (*Status, error) {
+	var body []byte
+	var err error
+
+	if statusURL != "" {
+		body, err = httpGet(ctx, statusURL, timeout)
+	} else {
+		if pool.StatusPath == "" {
+			return nil, fmt.Errorf("pm.status_path is not configured for pool %s", pool.Name)
+		}
+		network, address := ListenAddress(pool.Listen)
+		if address == "" {
+			return nil, fmt.Errorf("listen is not configured for pool %s", pool.Name)
+		}
+		body, err = fastCGIGet(ctx, network, address, pool.StatusPath, statusQuery, timeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get status for pool %s: %w", pool.Name, err)
+	}
+
+	status := &Status{}
+	if err := json.Unmarshal(body, status); err != nil {
+		return nil, fmt.Errorf("unable to parse status for pool %s: %w", pool.Name, err)
+	}
+
+	return status, nil
+}
+
+func httpGet(ctx context.Context, statusURL string, timeout time.Duration) ([]byte, error) {
+	if !strings.Contains(statusURL, "?") {
+		statusURL += "?" + statusQuery
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
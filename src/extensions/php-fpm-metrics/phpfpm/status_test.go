@@ -0,0 +1,88 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package phpfpm
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/fcgi"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStatus = `{"pool":"www","process manager":"dynamic","start time":1700000000,"start since":60,` +
+	`"accepted conn":12,"listen queue":1,"max listen queue":2,"listen queue len":128,"idle processes":3,` +
+	`"active processes":1,"total processes":4,"max active processes":2,"max children reached":0,"slow requests":5}`
+
+func statusHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/status" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "json", r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, testStatus)
+	})
+}
+
+func TestGetPoolStatus_FastCGI(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "php-fpm.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		_ = fcgi.Serve(listener, statusHandler(t))
+	}()
+
+	status, err := GetPoolStatus(context.Background(), &Pool{Name: "www", Listen: socket, StatusPath: "/status"}, "", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "www", status.Pool)
+	assert.Equal(t, uint64(12), status.AcceptedConn)
+	assert.Equal(t, uint64(4), status.TotalProcesses)
+
+	_, err = GetPoolStatus(context.Background(), &Pool{Name: "www", Listen: socket, StatusPath: "/missing"}, "", time.Second)
+	assert.Error(t, err)
+}
+
+func TestGetPoolStatus_HTTP(t *testing.T) {
+	server := httptest.NewServer(statusHandler(t))
+	defer server.Close()
+
+	status, err := GetPoolStatus(context.Background(), &Pool{Name: "www"}, server.URL+"/status", time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), status.SlowRequests)
+}
+
+func TestGetPoolStatus_NoStatusPath(t *testing.T) {
+	_, err := GetPoolStatus(context.Background(), &Pool{Name: "www", Listen: "9000"}, "", time.Second)
+	assert.ErrorContains(t, err, "pm.status_path is not configured for pool www")
+}
+
+func TestStatusMetrics(t *testing.T) {
+	prev := &Status{AcceptedConn: 10, MaxChildrenReached: 1, SlowRequests: 7}
+	current := &Status{AcceptedConn: 15, MaxChildrenReached: 1, SlowRequests: 2, TotalProcesses: 4}
+
+	samples := current.Metrics(prev)
+	assert.Equal(t, float64(5), samples["php.fpm.conn.accepted"])
+	assert.Equal(t, float64(0), samples["php.fpm.proc.max_child"])
+	// counter was reset, e.g. php-fpm was restarted
+	assert.Equal(t, float64(2), samples["php.fpm.slow_req"])
+	assert.Equal(t, float64(4), samples["php.fpm.proc.total"])
+
+	samples = current.Metrics(nil)
+	assert.Equal(t, float64(0), samples["php.fpm.conn.accepted"])
+}
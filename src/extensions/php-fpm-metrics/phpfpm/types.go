@@ -0,0 +1,46 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package phpfpm
+
+// Master represents a running php-fpm master process and the pools it manages
+type Master struct {
+	Pid         int32
+	Name        string
+	Command     string
+	ConfPath    string
+	BinPath     string
+	Version     string
+	VersionLine string
+	Workers     int32
+	Pools       []*Pool
+}
+
+// Pool represents a worker pool defined in the php-fpm configuration
+type Pool struct {
+	Name       string
+	Listen     string
+	StatusPath string
+}
+
+// Status is the JSON document returned by the php-fpm status page of a pool
+type Status struct {
+	Pool               string `json:"pool"`
+	ProcessManager     string `json:"process manager"`
+	StartTime          int64  `json:"start time"`
+	StartSince         int64  `json:"start since"`
+	AcceptedConn       uint64 `json:"accepted conn"`
+	ListenQueue        uint64 `json:"listen queue"`
+	MaxListenQueue     uint64 `json:"max listen queue"`
+	ListenQueueLen     uint64 `json:"listen queue len"`
+	IdleProcesses      uint64 `json:"idle processes"`
+	ActiveProcesses    uint64 `json:"active processes"`
+	TotalProcesses     uint64 `json:"total processes"`
+	MaxActiveProcesses uint64 `json:"max active processes"`
+	MaxChildrenReached uint64 `json:"max children reached"`
+	SlowRequests       uint64 `json:"slow requests"`
+}
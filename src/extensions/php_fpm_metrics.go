@@ -9,41 +9,105 @@ package extensions
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	agent_config "github.com/nginx/agent/sdk/v2/agent/config"
+	"github.com/nginx/agent/sdk/v2/proto"
 	"github.com/nginx/agent/v2/src/core"
 	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+	"github.com/nginx/agent/v2/src/core/payloads"
+	"github.com/nginx/agent/v2/src/extensions/php-fpm-metrics/phpfpm"
 
+	gogo "github.com/gogo/protobuf/proto"
+	"github.com/gogo/protobuf/types"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
-	phpFpmMetricsExtensionPluginVersion = "v0.1.0"
+	phpFpmMetricsExtensionPluginVersion = "v0.2.0"
+	phpFpmMetricsPluginName             = agent_config.PhpFpmMetricsExtensionPlugin
+
+	phpIdDimension       = "php_id"
+	phpPoolDimension     = "php_pool"
+	phpFpmMinInterval    = 5 * time.Second
+	phpFpmDegradedReason = "unable to collect status of pool %s: %v"
 )
 
+var phpFpmMetricsDefaults = &PhpFpmMetricsConfig{
+	CollectionInterval: 30 * time.Second,
+	StatusTimeout:      5 * time.Second,
+	StatusURLs:         map[string]string{},
+}
+
+type PhpFpmMetricsConfig struct {
+	CollectionInterval time.Duration     `mapstructure:"collection_interval" yaml:"-"`
+	StatusTimeout      time.Duration     `mapstructure:"status_timeout" yaml:"-"`
+	StatusURLs         map[string]string `mapstructure:"status_urls" yaml:"-"`
+}
+
+// PhpFpmMetrics discovers php-fpm master processes, scrapes the status page of every
+// pool and reports the results as metrics and dataplane software details
 type PhpFpmMetrics struct {
-	ctx       context.Context
-	ctxCancel context.CancelFunc
-	pipeline  core.MessagePipeInterface
-	env       core.Environment
+	ctx                context.Context
+	ctxCancel          context.CancelFunc
+	pipeline           core.MessagePipeInterface
+	env                core.Environment
+	agentVersion       string
+	commonDims         *metrics.CommonDim
+	collectionInterval time.Duration
+	statusTimeout      time.Duration
+	statusURLs         map[string]string
+	prevStats          map[string]*phpfpm.Status
+	softwareDetails    map[string]*proto.PhpFpmDetails
 }
 
-func NewPhpFpmMetrics(env core.Environment, conf *config.Config) (*PhpFpmMetrics, error) {
+func NewPhpFpmMetrics(env core.Environment, conf *config.Config, phpFpmMetricsConf interface{}) (*PhpFpmMetrics, error) {
+	phpFpmMetricsConfig := phpFpmMetricsDefaults
+
+	if phpFpmMetricsConf != nil {
+		var err error
+		phpFpmMetricsConfig, err = agent_config.DecodeConfig[*PhpFpmMetricsConfig](phpFpmMetricsConf)
+		if err != nil {
+			log.Errorf("Error decoding configuration for extension plugin %s, %v", phpFpmMetricsPluginName, err)
+			return nil, err
+		}
+	}
+
+	config.CheckAndSetDefault(&phpFpmMetricsConfig.CollectionInterval, phpFpmMetricsDefaults.CollectionInterval)
+	config.CheckAndSetDefault(&phpFpmMetricsConfig.StatusTimeout, phpFpmMetricsDefaults.StatusTimeout)
+
+	if phpFpmMetricsConfig.CollectionInterval < phpFpmMinInterval {
+		log.Warnf("The provided php-fpm collection interval (%s) is less than the allowed minimum, updating php-fpm collection interval to %s",
+			phpFpmMetricsConfig.CollectionInterval, phpFpmMinInterval)
+		phpFpmMetricsConfig.CollectionInterval = phpFpmMinInterval
+	}
+
 	return &PhpFpmMetrics{
-		env: env,
+		env:                env,
+		agentVersion:       conf.Version,
+		commonDims:         metrics.NewCommonDim(env.NewHostInfo("agentVersion", &conf.Tags, conf.ConfigDirs, false), conf, ""),
+		collectionInterval: phpFpmMetricsConfig.CollectionInterval,
+		statusTimeout:      phpFpmMetricsConfig.StatusTimeout,
+		statusURLs:         phpFpmMetricsConfig.StatusURLs,
+		prevStats:          make(map[string]*phpfpm.Status),
+		softwareDetails:    make(map[string]*proto.PhpFpmDetails),
 	}, nil
 }
 
 func (pfm *PhpFpmMetrics) Init(pipeline core.MessagePipeInterface) {
-	log.Infof("%s initializing", agent_config.PhpFpmMetricsExtensionPlugin)
+	log.Infof("%s initializing", phpFpmMetricsPluginName)
 	pfm.pipeline = pipeline
 	ctx, cancel := context.WithCancel(pfm.pipeline.Context())
 	pfm.ctx = ctx
 	pfm.ctxCancel = cancel
+
+	go pfm.run()
 }
 
-func (pf *PhpFpmMetrics) Info() *core.Info {
-	return core.NewInfo(agent_config.PhpFpmMetricsExtensionPlugin, phpFpmMetricsExtensionPluginVersion)
+func (pfm *PhpFpmMetrics) Info() *core.Info {
+	return core.NewInfo(phpFpmMetricsPluginName, phpFpmMetricsExtensionPluginVersion)
 }
 
 func (pfm *PhpFpmMetrics) Process(msg *core.Message) {
@@ -55,5 +119,163 @@ func (pfm *PhpFpmMetrics) Subscriptions() []string {
 }
 
 func (pfm *PhpFpmMetrics) Close() {
+	log.Infof("%s is wrapping up", phpFpmMetricsPluginName)
 	pfm.ctxCancel()
 }
+
+func (pfm *PhpFpmMetrics) run() {
+	ticker := time.NewTicker(pfm.collectionInterval)
+	defer ticker.Stop()
+
+	pfm.collect()
+
+	for {
+		select {
+		case <-ticker.C:
+			pfm.collect()
+		case <-pfm.ctx.Done():
+			return
+		}
+	}
+}
+
+func (pfm *PhpFpmMetrics) collect() {
+	masters := phpfpm.GetMasters(pfm.env.ProcessesByName(phpfpm.ProcessName))
+	report, details := pfm.buildReport(pfm.ctx, masters)
+
+	if len(report.Data) > 0 {
+		pfm.pipeline.Process(core.NewMessage(core.CommMetrics, []core.Payload{report}))
+	}
+
+	pfm.updateSoftwareDetails(details)
+}
+
+// buildReport scrapes the status of every pool of the masters and returns the metrics
+// report along with the software details of each master, keyed by php id
+func (pfm *PhpFpmMetrics) buildReport(ctx context.Context, masters []*phpfpm.Master) (*proto.MetricsReport, map[string]*proto.PhpFpmDetails) {
+	now := types.TimestampNow()
+	report := &proto.MetricsReport{
+		Meta: &proto.Metadata{Timestamp: now},
+		Type: proto.MetricsReport_INSTANCE,
+		Data: []*proto.StatsEntity{},
+	}
+	details := make(map[string]*proto.PhpFpmDetails)
+	currentStats := make(map[string]*phpfpm.Status)
+
+	for _, master := range masters {
+		phpId := core.GenerateNginxID("%s_%s", master.BinPath, master.ConfPath)
+		masterDetails := pfm.masterDetails(phpId, master)
+
+		for _, pool := range master.Pools {
+			masterDetails.Children = append(masterDetails.Children, pfm.poolDetails(phpId, pool))
+
+			dimensions := append(pfm.commonDims.ToDimensions(),
+				&proto.Dimension{Name: phpIdDimension, Value: phpId},
+				&proto.Dimension{Name: phpPoolDimension, Value: pool.Name},
+			)
+			poolId := core.GenerateNginxID("%s_%s", phpId, pool.Name)
+
+			status, err := phpfpm.GetPoolStatus(ctx, pool, pfm.statusURLs[pool.Name], pfm.statusTimeout)
+			if err != nil {
+				log.Debugf("php-fpm status collection failed: %v", err)
+				masterDetails.Health.PhpfpmHealthStatus = proto.PhpFpmHealth_DEGRADED
+				masterDetails.Health.DegradedReason = fmt.Sprintf(phpFpmDegradedReason, pool.Name, err)
+				report.Data = append(report.Data, &proto.StatsEntity{
+					Timestamp:     now,
+					Dimensions:    dimensions,
+					Simplemetrics: []*proto.SimpleMetric{{Name: phpfpm.StatusMetric, Value: 0}},
+				})
+				continue
+			}
+
+			simpleMetrics := []*proto.SimpleMetric{{Name: phpfpm.StatusMetric, Value: 1}}
+			for name, value := range status.Metrics(pfm.prevStats[poolId]) {
+				simpleMetrics = append(simpleMetrics, &proto.SimpleMetric{Name: name, Value: value})
+			}
+			currentStats[poolId] = status
+
+			report.Data = append(report.Data, &proto.StatsEntity{
+				Timestamp:     now,
+				Dimensions:    dimensions,
+				Simplemetrics: simpleMetrics,
+			})
+		}
+
+		details[phpId] = masterDetails
+	}
+
+	pfm.prevStats = currentStats
+
+	return report, details
+}
+
+func (pfm *PhpFpmMetrics) masterDetails(phpId string, master *phpfpm.Master) *proto.PhpFpmDetails {
+	return &proto.PhpFpmDetails{
+		Type:        proto.PhpFpmProcessType_PHPFPM,
+		Uuid:        pfm.commonDims.SystemId,
+		PhpId:       phpId,
+		Name:        master.Name,
+		Cmd:         master.Command,
+		ConfPath:    master.ConfPath,
+		ProcessPath: master.BinPath,
+		Version:     master.Version,
+		VersionLine: master.VersionLine,
+		Pid:         master.Pid,
+		Agent:       pfm.agentVersion,
+		Children:    []*proto.PhpFpmPool{},
+		Workers:     master.Workers,
+		Health: &proto.PhpFpmHealth{
+			SystemId:           pfm.commonDims.SystemId,
+			PhpfpmHealthStatus: proto.PhpFpmHealth_ACTIVE,
+		},
+	}
+}
+
+func (pfm *PhpFpmMetrics) poolDetails(phpId string, pool *phpfpm.Pool) *proto.PhpFpmPool {
+	return &proto.PhpFpmPool{
+		Type:            proto.PhpFpmProcessType_PHPFPM_POOL,
+		Uuid:            pfm.commonDims.SystemId,
+		PhpId:           core.GenerateNginxID("%s_%s", phpId, pool.Name),
+		Name:            pool.Name,
+		DisplayName:     fmt.Sprintf("phpfpm %s @ %s", pool.Name, pfm.commonDims.Hostname),
+		ParentPhpId:     phpId,
+		Listen:          pool.Listen,
+		Flisten:         phpfpm.FormattedListen(pool.Listen),
+		StatusPath:      pool.StatusPath,
+		CanHaveChildren: false,
+		Agent:           pfm.agentVersion,
+	}
+}
+
+// updateSoftwareDetails publishes the software details of every master that is new or
+// has changed, and clears the details of masters that are no longer running
+func (pfm *PhpFpmMetrics) updateSoftwareDetails(details map[string]*proto.PhpFpmDetails) {
+	for phpId, masterDetails := range details {
+		if previous, ok := pfm.softwareDetails[phpId]; ok && gogo.Equal(previous, masterDetails) {
+			continue
+		}
+		pfm.publishSoftwareDetails(phpId, &proto.DataplaneSoftwareDetails{
+			Data: &proto.DataplaneSoftwareDetails_PhpFpmDetails{PhpFpmDetails: masterDetails},
+		})
+	}
+
+	for phpId := range pfm.softwareDetails {
+		if _, ok := details[phpId]; !ok {
+			pfm.publishSoftwareDetails(phpId, &proto.DataplaneSoftwareDetails{})
+		}
+	}
+
+	pfm.softwareDetails = details
+}
+
+func (pfm *PhpFpmMetrics) publishSoftwareDetails(phpId string, details *proto.DataplaneSoftwareDetails) {
+	pfm.pipeline.Process(
+		core.NewMessage(
+			core.DataplaneSoftwareDetailsUpdated,
+			payloads.NewDataplaneSoftwareDetailsUpdate(
+				fmt.Sprintf("%s:%s", phpFpmMetricsPluginName, phpId),
+				details,
+			),
+		),
+	)
+}
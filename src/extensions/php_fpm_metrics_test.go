@@ -5,25 +5,160 @@
  * LICENSE file in the root directory of this source tree.
  */
 
-package extensions_test
+package extensions
 
 import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/fcgi"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
 	"github.com/nginx/agent/v2/src/core/config"
-	"github.com/nginx/agent/v2/src/extensions"
+	"github.com/nginx/agent/v2/src/core/payloads"
+	"github.com/nginx/agent/v2/src/extensions/php-fpm-metrics/phpfpm"
 	tutils "github.com/nginx/agent/v2/test/utils"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewPhpFpmMetrics(t *testing.T) {
-	_, err := extensions.NewPhpFpmMetrics(tutils.GetMockEnv(), &config.Config{})
+	plugin, err := NewPhpFpmMetrics(tutils.GetMockEnv(), &config.Config{}, nil)
 	assert.NoError(t, err)
+	assert.Equal(t, phpFpmMetricsDefaults.CollectionInterval, plugin.collectionInterval)
+
+	plugin, err = NewPhpFpmMetrics(tutils.GetMockEnv(), &config.Config{}, map[string]interface{}{
+		"collection_interval": "1s",
+		"status_urls":         map[string]string{"www": "http://127.0.0.1/status"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, phpFpmMinInterval, plugin.collectionInterval)
+	assert.Equal(t, "http://127.0.0.1/status", plugin.statusURLs["www"])
+
+	_, err = NewPhpFpmMetrics(tutils.GetMockEnv(), &config.Config{}, "invalid")
+	assert.Error(t, err)
 }
 
 func TestPhpFpmMetrics_Info(t *testing.T) {
-	plugin, err := extensions.NewPhpFpmMetrics(tutils.GetMockEnv(), tutils.GetMockAgentConfig())
+	plugin, err := NewPhpFpmMetrics(tutils.GetMockEnv(), tutils.GetMockAgentConfig(), nil)
 	assert.NoError(t, err)
 	assert.Equal(t, "php-fpm-metrics", plugin.Info().Name())
 }
+
+func TestPhpFpmMetrics_BuildReport(t *testing.T) {
+	accepted := atomic.Int64{}
+	accepted.Store(10)
+	socket := filepath.Join(t.TempDir(), "php-fpm.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		_ = fcgi.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"pool":"www","accepted conn":%d,"total processes":3}`, accepted.Load())
+		}))
+	}()
+
+	plugin, err := NewPhpFpmMetrics(tutils.GetMockEnv(), tutils.GetMockAgentConfig(), nil)
+	require.NoError(t, err)
+
+	masters := []*phpfpm.Master{
+		{
+			Pid:      10,
+			Name:     "php-fpm",
+			BinPath:  "/usr/sbin/php-fpm7.4",
+			ConfPath: "/etc/php/7.4/fpm/php-fpm.conf",
+			Workers:  3,
+			Pools: []*phpfpm.Pool{
+				{Name: "www", Listen: socket, StatusPath: "/status"},
+				{Name: "api", Listen: "127.0.0.1:9001"},
+			},
+		},
+	}
+
+	report, details := plugin.buildReport(context.Background(), masters)
+	assert.Equal(t, proto.MetricsReport_INSTANCE, report.Type)
+	require.Len(t, report.Data, 2)
+	assert.Equal(t, float64(1), metricValue(report.Data[0], phpfpm.StatusMetric))
+	assert.Equal(t, float64(0), metricValue(report.Data[0], "php.fpm.conn.accepted"))
+	assert.Equal(t, float64(3), metricValue(report.Data[0], "php.fpm.proc.total"))
+	assert.Equal(t, float64(0), metricValue(report.Data[1], phpfpm.StatusMetric))
+
+	require.Len(t, details, 1)
+	for _, masterDetails := range details {
+		assert.Equal(t, proto.PhpFpmProcessType_PHPFPM, masterDetails.Type)
+		assert.Equal(t, int32(3), masterDetails.Workers)
+		assert.Len(t, masterDetails.Children, 2)
+		assert.Equal(t, "unix:"+socket, masterDetails.Children[0].Flisten)
+		assert.Equal(t, proto.PhpFpmHealth_DEGRADED, masterDetails.Health.PhpfpmHealthStatus)
+		assert.Contains(t, masterDetails.Health.DegradedReason, "api")
+	}
+
+	accepted.Store(25)
+	report, _ = plugin.buildReport(context.Background(), masters)
+	assert.Equal(t, float64(15), metricValue(report.Data[0], "php.fpm.conn.accepted"))
+}
+
+func TestPhpFpmMetrics_UpdateSoftwareDetails(t *testing.T) {
+	plugin, err := NewPhpFpmMetrics(tutils.GetMockEnv(), tutils.GetMockAgentConfig(), nil)
+	require.NoError(t, err)
+
+	pipe := core.NewMockMessagePipe(context.Background())
+	plugin.pipeline = pipe
+
+	details := map[string]*proto.PhpFpmDetails{"123": {PhpId: "123", Pid: 10}}
+	plugin.updateSoftwareDetails(details)
+	// unchanged details are not published again
+	plugin.updateSoftwareDetails(map[string]*proto.PhpFpmDetails{"123": {PhpId: "123", Pid: 10}})
+	plugin.updateSoftwareDetails(map[string]*proto.PhpFpmDetails{})
+
+	messages := pipe.GetMessages()
+	require.Len(t, messages, 2)
+	for _, msg := range messages {
+		assert.Equal(t, core.DataplaneSoftwareDetailsUpdated, msg.Topic())
+		assert.Equal(t, "php-fpm-metrics:123", msg.Data().(*payloads.DataplaneSoftwareDetailsUpdate).GetPluginName())
+	}
+	assert.Equal(t, int32(10), messages[0].Data().(*payloads.DataplaneSoftwareDetailsUpdate).GetDataplaneSoftwareDetails().GetPhpFpmDetails().GetPid())
+	assert.Nil(t, messages[1].Data().(*payloads.DataplaneSoftwareDetailsUpdate).GetDataplaneSoftwareDetails().GetData())
+}
+
+func TestPhpFpmMetrics_Collect(t *testing.T) {
+	confPath := filepath.Join(t.TempDir(), "php-fpm.conf")
+	require.NoError(t, os.WriteFile(confPath, []byte("[www]\nlisten = 127.0.0.1:1\n"), 0o644))
+
+	env := tutils.GetMockEnv()
+	env.On("ProcessesByName", phpfpm.ProcessName).Return([]*core.Process{
+		{Pid: 10, ParentPid: 1, Name: "php-fpm7.4", Command: "php-fpm: master process (" + confPath + ")", IsMaster: true},
+		{Pid: 11, ParentPid: 10, Name: "php-fpm7.4", Command: "php-fpm: pool www"},
+	})
+
+	plugin, err := NewPhpFpmMetrics(env, tutils.GetMockAgentConfig(), nil)
+	require.NoError(t, err)
+	pipe := core.NewMockMessagePipe(context.Background())
+	plugin.pipeline = pipe
+	plugin.ctx = context.Background()
+
+	plugin.collect()
+
+	env.AssertCalled(t, "ProcessesByName", phpfpm.ProcessName)
+	messages := pipe.GetMessages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, core.CommMetrics, messages[0].Topic())
+	assert.Equal(t, core.DataplaneSoftwareDetailsUpdated, messages[1].Topic())
+}
+
+func metricValue(entity *proto.StatsEntity, name string) float64 {
+	for _, metric := range entity.Simplemetrics {
+		if metric.Name == name {
+			return metric.Value
+		}
+	}
+	return -1
+}
@@ -7,10 +7,15 @@ import (
 	"github.com/nginx/agent/v2/src/core/metrics"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
+const histogramSuffix = "_histogram"
+
 type Exporter struct {
-	latestMetricReports *metrics.MetricsReportBundle
+	latestMetricReports        *metrics.MetricsReportBundle
+	latestSecurityMetricReport *proto.MetricsReport
 }
 
 func NewExporter(report *proto.MetricsReport) *Exporter {
@@ -21,10 +26,19 @@ func (e *Exporter) SetLatestMetricReport(latest *metrics.MetricsReportBundle) {
 	e.latestMetricReports = latest
 }
 
+// SetLatestSecurityMetricReport sets the latest report of the metrics aggregated from
+// NGINX App Protect security events, which is exposed next to the latest metric reports
+func (e *Exporter) SetLatestSecurityMetricReport(latest *proto.MetricsReport) {
+	e.latestSecurityMetricReport = latest
+}
+
 func (e *Exporter) GetLatestMetricReports() (reports []*proto.MetricsReport) {
 	for _, report := range e.latestMetricReports.Data {
 		reports = append(reports, report)
 	}
+	if e.latestSecurityMetricReport != nil {
+		reports = append(reports, e.latestSecurityMetricReport)
+	}
 	return
 }
 
@@ -43,11 +57,15 @@ func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
 }
 
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
-	for _, report := range e.latestMetricReports.Data {
+	for _, report := range e.GetLatestMetricReports() {
 		for _, statsEntity := range report.Data {
-			for _, metric := range statsEntity.Simplemetrics {
+			histograms, simpleMetrics := metrics.HistogramsFromSimpleMetrics(statsEntity.Simplemetrics)
+			for _, metric := range simpleMetrics {
 				ch <- createPrometheusMetric(metric, statsEntity.GetDimensions())
 			}
+			for name, histogram := range histograms {
+				ch <- createPrometheusHistogram(name, histogram, statsEntity.GetDimensions())
+			}
 		}
 	}
 }
@@ -64,6 +82,83 @@ func createPrometheusMetric(metric *proto.SimpleMetric, Dimensions []*proto.Dime
 	)
 }
 
+// histogramMetric exposes a histogram as a Prometheus native histogram. The buckets are
+// also exposed as classic buckets for scrapers that do not support native histograms.
+type histogramMetric struct {
+	desc      *prometheus.Desc
+	histogram *metrics.Histogram
+}
+
+func createPrometheusHistogram(name string, histogram *metrics.Histogram, Dimensions []*proto.Dimension) prometheus.Metric {
+	return &histogramMetric{
+		desc: prometheus.NewDesc(
+			convertMetricNameToPrometheusFormat(name)+histogramSuffix,
+			"",
+			nil,
+			convertDimensionsToLabels(Dimensions),
+		),
+		histogram: histogram,
+	}
+}
+
+func (m *histogramMetric) Desc() *prometheus.Desc {
+	return m.desc
+}
+
+func (m *histogramMetric) Write(out *dto.Metric) error {
+	h := &dto.Histogram{
+		SampleCount:   protobuf.Uint64(m.histogram.Count),
+		SampleSum:     protobuf.Float64(m.histogram.Sum),
+		Schema:        protobuf.Int32(m.histogram.Schema),
+		ZeroThreshold: protobuf.Float64(0),
+		ZeroCount:     protobuf.Uint64(m.histogram.ZeroCount),
+	}
+
+	offsets, lengths, counts := m.histogram.Spans()
+	for i := range offsets {
+		h.PositiveSpan = append(h.PositiveSpan, &dto.BucketSpan{
+			Offset: protobuf.Int32(offsets[i]),
+			Length: protobuf.Uint32(lengths[i]),
+		})
+	}
+	previous := int64(0)
+	for _, count := range counts {
+		h.PositiveDelta = append(h.PositiveDelta, int64(count)-previous)
+		previous = int64(count)
+	}
+
+	cumulative := m.histogram.ZeroCount
+	if cumulative > 0 {
+		h.Bucket = append(h.Bucket, &dto.Bucket{
+			CumulativeCount: protobuf.Uint64(cumulative),
+			UpperBound:      protobuf.Float64(0),
+		})
+	}
+	index := int32(0)
+	for i, offset := range offsets {
+		if i == 0 {
+			index = offset
+		} else {
+			index += offset
+		}
+		for j := uint32(0); j < lengths[i]; j++ {
+			count := m.histogram.Buckets[index]
+			if count > 0 {
+				cumulative += count
+				h.Bucket = append(h.Bucket, &dto.Bucket{
+					CumulativeCount: protobuf.Uint64(cumulative),
+					UpperBound:      protobuf.Float64(m.histogram.UpperBound(index)),
+				})
+			}
+			index++
+		}
+	}
+
+	out.Histogram = h
+	out.Label = prometheus.MakeLabelPairs(m.desc, nil)
+	return nil
+}
+
 func convertMetricNameToPrometheusFormat(metricName string) string {
 	return strings.Replace(metricName, ".", "_", -1)
 }
@@ -7,7 +7,9 @@ import (
 	"github.com/nginx/agent/v2/src/core/metrics"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExporter(t *testing.T) {
@@ -29,6 +31,36 @@ func TestExporter(t *testing.T) {
 	assert.Equal(t, metricReport3, exporter.GetLatestMetricReports()[1])
 }
 
+func TestExporter_securityMetricReport(t *testing.T) {
+	metricReport := &proto.MetricsReport{Meta: &proto.Metadata{MessageId: "123"}}
+	securityMetricReport := &proto.MetricsReport{
+		Meta: &proto.Metadata{MessageId: "456"},
+		Data: []*proto.StatsEntity{
+			{
+				Dimensions:    []*proto.Dimension{{Name: "policy_name", Value: "app_protect_default_policy"}},
+				Simplemetrics: []*proto.SimpleMetric{{Name: "nap.request.blocked", Value: 3}},
+			},
+		},
+	}
+
+	exporter := NewExporter(metricReport)
+	exporter.SetLatestSecurityMetricReport(securityMetricReport)
+
+	assert.Equal(t, []*proto.MetricsReport{metricReport, securityMetricReport}, exporter.GetLatestMetricReports())
+
+	// the security metric report is kept when the latest metric report is replaced
+	exporter.SetLatestMetricReport(&metrics.MetricsReportBundle{Data: []*proto.MetricsReport{}})
+	assert.Equal(t, []*proto.MetricsReport{securityMetricReport}, exporter.GetLatestMetricReports())
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(exporter))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	assert.Equal(t, "nap_request_blocked", families[0].GetName())
+	assert.Equal(t, 3.0, families[0].GetMetric()[0].GetGauge().GetValue())
+}
+
 func TestExporter_convertMetricNameToPrometheusFormat(t *testing.T) {
 	expected := "test_metric_name"
 	actual := convertMetricNameToPrometheusFormat("test.metric.name")
@@ -95,3 +127,73 @@ func TestExporter_createPrometheusMetric(t *testing.T) {
 
 	assert.Equal(t, expected, actual.Desc().String())
 }
+
+func TestExporter_histograms(t *testing.T) {
+	histogram := metrics.NewHistogram(0)
+	for _, value := range []float64{0, 0.3, 0.4, 1, 3, 100} {
+		histogram.Observe(value)
+	}
+	samples := map[string]float64{}
+	histogram.Samples("nginx.http.request.time", samples)
+
+	statsEntity := &proto.StatsEntity{
+		Dimensions:    []*proto.Dimension{{Name: "server_name", Value: "example.com"}},
+		Simplemetrics: []*proto.SimpleMetric{{Name: "nginx.http.request.count", Value: 6}},
+	}
+	for name, value := range samples {
+		statsEntity.Simplemetrics = append(statsEntity.Simplemetrics, &proto.SimpleMetric{Name: name, Value: value})
+	}
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(NewExporter(&proto.MetricsReport{Data: []*proto.StatsEntity{statsEntity}})))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	names := []string{}
+	var histogramFamily *dto.MetricFamily
+	for _, family := range families {
+		names = append(names, family.GetName())
+		if family.GetName() == "nginx_http_request_time_histogram" {
+			histogramFamily = family
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"nginx_http_request_count",
+		"nginx_http_request_time_pctl50",
+		"nginx_http_request_time_pctl90",
+		"nginx_http_request_time_pctl99",
+		"nginx_http_request_time_pctl999",
+		"nginx_http_request_time_histogram",
+	}, names)
+
+	require.NotNil(t, histogramFamily)
+	assert.Equal(t, dto.MetricType_HISTOGRAM, histogramFamily.GetType())
+	require.Len(t, histogramFamily.GetMetric(), 1)
+
+	metric := histogramFamily.GetMetric()[0]
+	assert.Equal(t, "server_name", metric.GetLabel()[0].GetName())
+	assert.Equal(t, "example.com", metric.GetLabel()[0].GetValue())
+
+	h := metric.GetHistogram()
+	assert.Equal(t, uint64(6), h.GetSampleCount())
+	assert.InDelta(t, 104.7, h.GetSampleSum(), 0.0001)
+	assert.Equal(t, int32(0), h.GetSchema())
+	assert.Equal(t, uint64(1), h.GetZeroCount())
+
+	// buckets -1, 0 and 2 are in one span, bucket 7 starts a new span
+	require.Len(t, h.GetPositiveSpan(), 2)
+	assert.Equal(t, int32(-1), h.GetPositiveSpan()[0].GetOffset())
+	assert.Equal(t, uint32(4), h.GetPositiveSpan()[0].GetLength())
+	assert.Equal(t, int32(4), h.GetPositiveSpan()[1].GetOffset())
+	assert.Equal(t, uint32(1), h.GetPositiveSpan()[1].GetLength())
+	assert.Equal(t, []int64{2, -1, -1, 1, 0}, h.GetPositiveDelta())
+
+	upperBounds, cumulativeCounts := []float64{}, []uint64{}
+	for _, bucket := range h.GetBucket() {
+		upperBounds = append(upperBounds, bucket.GetUpperBound())
+		cumulativeCounts = append(cumulativeCounts, bucket.GetCumulativeCount())
+	}
+	assert.Equal(t, []float64{0, 0.5, 1, 4, 128}, upperBounds)
+	assert.Equal(t, []uint64{1, 3, 4, 5, 6}, cumulativeCounts)
+}
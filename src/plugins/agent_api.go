@@ -12,7 +12,10 @@ package plugins
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -22,13 +25,18 @@ import (
 	"time"
 
 	"github.com/nginx/agent/v2/src/core/metrics"
+	"github.com/nginx/agent/v2/src/core/supportbundle"
 
 	"github.com/google/uuid"
 	"github.com/nginx/agent/sdk/v2"
 	agent_config "github.com/nginx/agent/sdk/v2/agent/config"
+	sdk_files "github.com/nginx/agent/sdk/v2/files"
 	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/sdk/v2/zip"
 	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/auth"
 	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/revisions"
 	prometheus_metrics "github.com/nginx/agent/v2/src/extensions/prometheus-metrics"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -40,6 +48,10 @@ import (
 // in: body
 type _ string
 
+// swagger:response SupportBundleResponse
+// in: body
+type _ []byte
+
 const (
 	okStatus      = "OK"
 	pendingStatus = "PENDING"
@@ -52,10 +64,27 @@ const (
 )
 
 var (
-	healthRegex       = regexp.MustCompile(`^\/health[\/]*$`)
-	instancesRegex    = regexp.MustCompile(`^\/nginx[\/]*$`)
-	configRegex       = regexp.MustCompile(`^\/nginx/config[\/]*$`)
-	configStatusRegex = regexp.MustCompile(`^\/nginx/config/status[\/]*$`)
+	healthRegex          = regexp.MustCompile(`^\/health[\/]*$`)
+	instancesRegex       = regexp.MustCompile(`^\/nginx[\/]*$`)
+	configRegex          = regexp.MustCompile(`^\/nginx/config[\/]*$`)
+	configStatusRegex    = regexp.MustCompile(`^\/nginx/config/status[\/]*$`)
+	configDryRunRegex    = regexp.MustCompile(`^\/nginx/config/dry-run[\/]*$`)
+	configConfirmRegex   = regexp.MustCompile(`^\/nginx/config/confirm[\/]*$`)
+	configRevisionsRegex = regexp.MustCompile(`^\/nginx/config/revisions[\/]*$`)
+	configRevertRegex    = regexp.MustCompile(`^\/nginx/config/revisions/revert[\/]*$`)
+	lifecycleStatusRegex = regexp.MustCompile(`^\/nginx/lifecycle/status[\/]*$`)
+	lifecycleRegex       = regexp.MustCompile(`^\/nginx/([^/]+)/(reload|stop|start|reopen-logs|upgrade)[\/]*$`)
+	pipeStatsRegex       = regexp.MustCompile(`^\/debug/pipe[\/]*$`)
+	supportBundleRegex   = regexp.MustCompile(`^\/support-bundle[\/]*$`)
+
+	stagedRevisionTTL  = 15 * time.Minute
+	lifecycleStatusTTL = 15 * time.Minute
+
+	// the maximum size of a config upload request and of the files extracted from a config archive
+	maxConfigUploadSize  int64 = 32 << 20
+	maxConfigArchiveSize int64 = 128 << 20
+
+	errConfigChanged = errors.New("config changed since the dry run")
 )
 
 type AgentAPI struct {
@@ -68,10 +97,30 @@ type AgentAPI struct {
 	rootHandler  *RootHandler
 	exporter     *prometheus_metrics.Exporter
 	processes    []*core.Process
+	auditLogger  *auth.AuditLogger
+	events       *eventStream
+}
+
+// authHandler authenticates Agent API requests, checks that the client was granted the role required
+// by the route and writes every mutating request to the audit log
+type authHandler struct {
+	next          http.Handler
+	authenticator *auth.Authenticator
+	auditLogger   *auth.AuditLogger
+}
+
+// statusRecorder records the status code of a response for the audit log
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
 }
 
 type RootHandler struct {
 	config               *config.Config
+	env                  core.Environment
+	pipeline             core.MessagePipeInterface
+	nginxBinary          core.NginxBinary
+	exporter             *prometheus_metrics.Exporter
 	isGrpcRegistered     bool
 	lastCommandSent      time.Time
 	lastMetricReportSent time.Time
@@ -87,9 +136,25 @@ type NginxHandler struct {
 	configResponseStatuses map[string]*proto.NginxConfigStatus
 	processesMutex         sync.RWMutex
 	processes              []*core.Process
+	stagedRevisions        map[string]*stagedRevision
+	stagedRevisionsMutex   sync.Mutex
+	revisionStore          *revisions.Store
+	lifecycleStatuses      map[string]*NginxLifecycleStatus
+	lifecycleStatusesMutex sync.Mutex
+	knownInstances         map[string]*proto.NginxDetails
+	knownInstancesMutex    sync.Mutex
+}
+
+// stagedRevision is a validated set of config files waiting to be confirmed
+type stagedRevision struct {
+	// files to write, by NGINX ID
+	files map[string][]*proto.File
+	// content address of the config files at the time of the dry run, by NGINX ID
+	currentIds map[string]string
+	expiresAt  time.Time
 }
 
-// swagger:parameters apply-nginx-config
+// swagger:parameters apply-nginx-config dry-run-nginx-config
 type ParameterRequest struct {
 	// in: formData
 	// swagger:file
@@ -98,6 +163,7 @@ type ParameterRequest struct {
 
 type AgentAPIConfigApplyRequest struct {
 	correlationId string
+	appliedBy     string
 	config        *proto.NginxConfig
 }
 
@@ -123,6 +189,62 @@ type AgentAPIConfigApplyResponse struct {
 	NginxInstances []NginxInstanceResponse `json:"nginx_instances"`
 }
 
+// swagger:model NginxInstanceDryRunResponse
+type NginxInstanceDryRunResponse struct {
+	// NGINX ID
+	// example: b636d4376dea15405589692d3c5d3869ff3a9b26b0e7bb4bb1aa7e658ace1437
+	NginxId string `json:"nginx_id"`
+	// Message
+	// example: config validated successfully
+	Message string `json:"message"`
+	// Status
+	// example: OK
+	Status string `json:"status"`
+	// Unified diff of the uploaded files against the current files
+	// example: --- a/etc/nginx/nginx.conf\n+++ b/etc/nginx/nginx.conf\n@@ -1 +1 @@\n-worker_processes 1;\n+worker_processes 2;\n
+	Diff string `json:"diff"`
+}
+
+// swagger:model AgentAPIConfigDryRunResponse
+type AgentAPIConfigDryRunResponse struct {
+	// Revision ID, used to confirm the config apply
+	// example: 2ab6e2b1d0f0e3c5a6e4a2c9d1c8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0
+	RevisionId string `json:"revision_id"`
+	// NGINX Instances
+	NginxInstances []NginxInstanceDryRunResponse `json:"nginx_instances"`
+	// Time after which the revision can no longer be confirmed
+	// example: 2023-01-01T12:15:00Z
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// swagger:model ConfigRevision
+type ConfigRevision struct {
+	// Revision ID, the checksum of the config files
+	// example: 2ab6e2b1d0f0e3c5a6e4a2c9d1c8b7a6f5e4d3c2b1a0f9e8d7c6b5a4f3e2d1c0
+	RevisionId string `json:"revision_id"`
+	// NGINX ID
+	// example: b636d4376dea15405589692d3c5d3869ff3a9b26b0e7bb4bb1aa7e658ace1437
+	NginxId string `json:"nginx_id"`
+	// Correlation ID of the config apply request
+	// example: 6204037c-30e6-408b-8aaa-dd8219860b4b
+	CorrelationId string `json:"correlation_id"`
+	// Client that applied the config
+	// example: command-channel
+	AppliedBy string `json:"applied_by"`
+	// Time the config was applied
+	// example: 2023-01-01T12:00:00Z
+	AppliedAt time.Time `json:"applied_at"`
+	// Config files
+	// example: ["/etc/nginx/nginx.conf"]
+	Files []string `json:"files"`
+}
+
+// swagger:model AgentAPIConfigRevisionsResponse
+type AgentAPIConfigRevisionsResponse struct {
+	// Config revisions, newest first for each NGINX instance
+	Revisions []ConfigRevision `json:"revisions"`
+}
+
 // swagger:model AgentAPICommonResponse
 type AgentAPICommonResponse struct {
 	// Correlation ID
@@ -168,6 +290,7 @@ type HealthResponse struct {
 const (
 	contentTypeHeader = "Content-Type"
 	jsonMimeType      = "application/json"
+	gzipMimeType      = "application/gzip"
 )
 
 func NewAgentAPI(config *config.Config, env core.Environment, nginxBinary core.NginxBinary, processes []*core.Process) *AgentAPI {
@@ -177,6 +300,7 @@ func NewAgentAPI(config *config.Config, env core.Environment, nginxBinary core.N
 		nginxBinary: nginxBinary,
 		exporter:    prometheus_metrics.NewExporter(&proto.MetricsReport{}),
 		processes:   processes,
+		events:      newEventStream(),
 	}
 }
 
@@ -191,11 +315,20 @@ func (a *AgentAPI) Close() {
 	if err := a.server.Shutdown(context.Background()); err != nil {
 		log.Errorf("Agent API HTTP Server Shutdown Error: %v", err)
 	}
+	if a.auditLogger != nil {
+		if err := a.auditLogger.Close(); err != nil {
+			log.Errorf("Unable to close Agent API audit log: %v", err)
+		}
+	}
 }
 
 func (a *AgentAPI) Process(message *core.Message) {
 	log.Tracef("Process function in the agent_api.go, %s %v", message.Topic(), message.Data())
 
+	if a.events != nil {
+		a.events.publishMessage(message)
+	}
+
 	switch message.Topic() {
 	case core.AgentAPIConfigApplyResponse:
 		switch response := message.Data().(type) {
@@ -211,6 +344,13 @@ func (a *AgentAPI) Process(message *core.Message) {
 		default:
 			log.Warnf("Unknown MetricReportBundle type: %T(%v)", message.Data(), message.Data())
 		}
+	case core.SecurityMetricReport:
+		switch response := message.Data().(type) {
+		case *proto.MetricsReport:
+			a.exporter.SetLatestSecurityMetricReport(response)
+		default:
+			log.Warnf("Unknown security MetricsReport type: %T(%v)", message.Data(), message.Data())
+		}
 	case core.NginxConfigValidationPending, core.NginxConfigApplyFailed, core.NginxConfigApplySucceeded:
 		switch response := message.Data().(type) {
 		case *proto.AgentActivityStatus:
@@ -219,6 +359,15 @@ func (a *AgentAPI) Process(message *core.Message) {
 		default:
 			log.Errorf("Expected the type %T but got %T", &proto.AgentActivityStatus{}, response)
 		}
+	case core.NginxLifecycleComplete:
+		switch status := message.Data().(type) {
+		case *NginxLifecycleStatus:
+			if a.nginxHandler != nil {
+				a.nginxHandler.updateLifecycleStatus(status)
+			}
+		default:
+			log.Errorf("Expected the type %T but got %T", &NginxLifecycleStatus{}, status)
+		}
 	case core.NginxDetailProcUpdate:
 		a.processes = message.Data().([]*core.Process)
 		if a.nginxHandler != nil {
@@ -241,19 +390,32 @@ func (a *AgentAPI) Subscriptions() []string {
 	return []string{
 		core.AgentAPIConfigApplyResponse,
 		core.MetricReport,
+		core.SecurityMetricReport,
 		core.NginxConfigValidationPending,
 		core.NginxConfigApplyFailed,
 		core.NginxConfigApplySucceeded,
+		core.NginxLifecycleComplete,
 		core.NginxDetailProcUpdate,
 		core.AgentConnected,
 		core.CommandSent,
 		core.MetricReportSent,
+		core.Events,
+		core.CommMetrics,
+		core.NginxReloadComplete,
+		core.NginxMasterProcCreated,
+		core.NginxMasterProcKilled,
+		core.NginxWorkerProcCreated,
+		core.NginxWorkerProcKilled,
 	}
 }
 
 func (a *AgentAPI) createHttpServer() {
 	a.rootHandler = &RootHandler{
 		config:           a.config,
+		env:              a.env,
+		pipeline:         a.pipeline,
+		nginxBinary:      a.nginxBinary,
+		exporter:         a.exporter,
 		isGrpcRegistered: false,
 		startTime:        time.Now(),
 	}
@@ -266,20 +428,63 @@ func (a *AgentAPI) createHttpServer() {
 		responseChannel:        make(chan *proto.Command_NginxConfigResponse),
 		configResponseStatuses: make(map[string]*proto.NginxConfigStatus),
 		processes:              a.processes,
+		stagedRevisions:        make(map[string]*stagedRevision),
+		revisionStore:          newConfigRevisionStore(a.config),
+		lifecycleStatuses:      make(map[string]*NginxLifecycleStatus),
+		knownInstances:         make(map[string]*proto.NginxDetails),
 	}
 
 	mux := http.NewServeMux()
 
 	mux.Handle("/metrics/", a.getPrometheusHandler())
 	mux.Handle("/nginx/", a.nginxHandler)
+	mux.Handle(apiV2Prefix+"/", &APIV2Handler{
+		config:       a.config,
+		env:          a.env,
+		nginxBinary:  a.nginxBinary,
+		nginxHandler: a.nginxHandler,
+		exporter:     a.exporter,
+		events:       a.events,
+	})
 	mux.Handle("/", a.rootHandler)
 
-	handler := cors.New(cors.Options{AllowedMethods: []string{"OPTIONS", "GET", "PUT"}}).Handler(mux)
+	authenticator, err := auth.NewAuthenticator(a.config.AgentAPI.Auth)
+	if err != nil {
+		log.Errorf("Agent API not started, unable to configure authentication: %v", err)
+		return
+	}
+	a.auditLogger, err = auth.NewAuditLogger(a.config.AgentAPI.Auth.AuditLog)
+	if err != nil {
+		log.Errorf("Agent API not started: %v", err)
+		return
+	}
+	if !authenticator.Enabled() && a.config.AgentAPI.Port != 0 {
+		log.Warn("Agent API authentication is disabled, every client that can reach the Agent API can change the NGINX configuration")
+	}
+
+	handler := cors.New(cors.Options{
+		AllowedMethods: []string{"OPTIONS", "GET", "PUT", "POST"},
+		AllowedHeaders: []string{"Origin", "Accept", "Content-Type", "X-Requested-With", "Authorization"},
+	}).Handler(&authHandler{next: mux, authenticator: authenticator, auditLogger: a.auditLogger})
 	a.server = http.Server{
 		Addr:    fmt.Sprintf("%s:%d", a.config.AgentAPI.Host, a.config.AgentAPI.Port),
 		Handler: handler,
 	}
 
+	if a.config.AgentAPI.ClientCA != "" {
+		// the client CA is only used by the TLS server, without a cert and key client certificates would not be verified
+		if a.config.AgentAPI.Cert == "" || a.config.AgentAPI.Key == "" {
+			log.Errorf("Agent API not started, %s requires %s and %s to be set", config.AgentAPIClientCA, config.AgentAPICert, config.AgentAPIKey)
+			return
+		}
+		tlsConfig, err := clientCATLSConfig(a.config.AgentAPI.ClientCA)
+		if err != nil {
+			log.Errorf("Agent API not started: %v", err)
+			return
+		}
+		a.server.TLSConfig = tlsConfig
+	}
+
 	if a.config.AgentAPI.Cert != "" && a.config.AgentAPI.Key != "" && a.config.AgentAPI.Port != 0 {
 		log.Info("Starting Agent API HTTP server with cert and key and port from config")
 		if err := a.server.ListenAndServeTLS(a.config.AgentAPI.Cert, a.config.AgentAPI.Key); err != http.ErrServerClosed {
@@ -295,6 +500,117 @@ func (a *AgentAPI) createHttpServer() {
 	}
 }
 
+// clientCATLSConfig returns a TLS config that verifies the client certificates that are presented against the CA,
+// clients without a certificate can still authenticate with a bearer token
+func clientCATLSConfig(clientCA string) (*tls.Config, error) {
+	caCert, err := os.ReadFile(clientCA)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read client CA: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no certificates found in client CA %s", clientCA)
+	}
+
+	return &tls.Config{
+		ClientCAs:  pool,
+		ClientAuth: tls.VerifyClientCertIfGiven,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+
+	var identity *auth.Identity
+	var authErr error
+	if isMutatingRequest(r) {
+		defer func() {
+			entry := &auth.AuditEntry{
+				Time:       start,
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				RemoteAddr: r.RemoteAddr,
+				StatusCode: recorder.statusCode,
+				Duration:   time.Since(start).Seconds(),
+			}
+			if identity != nil {
+				entry.AuthMethod = identity.Method
+				entry.Subject = identity.Subject
+				entry.Roles = identity.Roles
+			}
+			if authErr != nil {
+				entry.Error = authErr.Error()
+			}
+			h.auditLogger.Log(entry)
+		}()
+	}
+
+	role := requiredRole(r)
+	if role == "" {
+		h.next.ServeHTTP(recorder, r)
+		return
+	}
+
+	identity, authErr = h.authenticator.Authenticate(r)
+	if authErr != nil {
+		log.Debugf("Agent API request %s %s from %s not authenticated: %v", r.Method, r.URL.Path, r.RemoteAddr, authErr)
+		recorder.Header().Set("WWW-Authenticate", "Bearer")
+		writeAuthErrorResponse(recorder, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	if !identity.HasRole(role) {
+		authErr = fmt.Errorf("the %s role is required", role)
+		log.Debugf("Agent API request %s %s from %s not authorized: %v", r.Method, r.URL.Path, r.RemoteAddr, authErr)
+		writeAuthErrorResponse(recorder, http.StatusForbidden, fmt.Sprintf("The %s role is required", role))
+		return
+	}
+
+	h.next.ServeHTTP(recorder, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+}
+
+// requiredRole returns the role required by an Agent API route, no role is required by the health check.
+// Requests that change the NGINX config or control NGINX, and support bundle downloads, which include the
+// NGINX and agent config, require the config-write role, every other request requires the read-only role.
+func requiredRole(r *http.Request) string {
+	switch {
+	case healthRegex.MatchString(r.URL.Path):
+		return ""
+	case isMutatingRequest(r), supportBundleRegex.MatchString(r.URL.Path):
+		return auth.RoleConfigWrite
+	default:
+		return auth.RoleReadOnly
+	}
+}
+
+func isMutatingRequest(r *http.Request) bool {
+	return r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions
+}
+
+func writeAuthErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set(contentTypeHeader, jsonMimeType)
+	w.WriteHeader(statusCode)
+	err := writeObjectToResponseBody(w, AgentAPICommonResponse{Message: message})
+	if err != nil {
+		log.Warn(err)
+	}
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Flush sends any buffered data to the client, if the wrapped response writer supports it
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // swagger:route GET /metrics/ nginx-agent get-prometheus-metrics
 //
 // # Get Prometheus Metrics
@@ -354,6 +670,52 @@ func (h *NginxHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			log.Warn("Config Apply Feature Disabled")
 		}
 
+	case configDryRunRegex.MatchString(r.URL.Path), configConfirmRegex.MatchString(r.URL.Path):
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !h.isConfigApplyEnabled(w) {
+			return
+		}
+
+		var err error
+		if configDryRunRegex.MatchString(r.URL.Path) {
+			err = h.dryRunConfig(w, r)
+		} else {
+			err = h.confirmConfig(w, r)
+		}
+		if err != nil {
+			log.Warnf("Failed to process config request: %v", err)
+		}
+
+	case configRevisionsRegex.MatchString(r.URL.Path):
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		err := h.getConfigRevisions(w, r)
+		if err != nil {
+			log.Warnf("Failed to get config revisions: %v", err)
+		}
+
+	case configRevertRegex.MatchString(r.URL.Path):
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !h.isConfigApplyEnabled(w) {
+			return
+		}
+
+		err := h.revertConfig(w, r)
+		if err != nil {
+			log.Warnf("Failed to revert config: %v", err)
+		}
+
 	case configStatusRegex.MatchString(r.URL.Path):
 		if r.Method != http.MethodGet {
 			w.WriteHeader(http.StatusMethodNotAllowed)
@@ -364,6 +726,33 @@ func (h *NginxHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			log.Warnf("Failed to get config status: %v", err)
 		}
+
+	case lifecycleStatusRegex.MatchString(r.URL.Path):
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		err := h.getLifecycleStatus(w, r)
+		if err != nil {
+			log.Warnf("Failed to get NGINX lifecycle status: %v", err)
+		}
+
+	case lifecycleRegex.MatchString(r.URL.Path):
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !h.isLifecycleEnabled(w) {
+			return
+		}
+
+		matches := lifecycleRegex.FindStringSubmatch(r.URL.Path)
+		err := h.runLifecycleAction(w, r, matches[1], matches[2])
+		if err != nil {
+			log.Warnf("Failed to run NGINX %s: %v", matches[2], err)
+		}
 	default:
 		w.WriteHeader(http.StatusNotFound)
 		_, err := fmt.Fprint(w, []byte("not found"))
@@ -373,6 +762,61 @@ func (h *NginxHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// isConfigApplyEnabled writes an error response if the config apply features are disabled
+func (h *NginxHandler) isConfigApplyEnabled(w http.ResponseWriter) bool {
+	if h.config.IsFeatureEnabled(agent_config.FeatureNginxConfig) || h.config.IsFeatureEnabled(agent_config.FeatureNginxConfigAsync) {
+		return true
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+	response := AgentAPIConfigApplyStatusResponse{
+		CorrelationId: uuid.New().String(),
+		Message:       "unable to process NGINX config apply request as the nginx-config-async feature is disabled",
+		Status:        errorStatus,
+	}
+	err := writeObjectToResponseBody(w, response)
+	if err != nil {
+		log.Warn(err)
+	}
+	log.Warn("Config Apply Feature Disabled")
+
+	return false
+}
+
+// isLifecycleEnabled writes an error response if the NGINX lifecycle feature is disabled. The lifecycle
+// actions are also refused if Agent API authentication is disabled, as every client would be allowed to run them.
+func (h *NginxHandler) isLifecycleEnabled(w http.ResponseWriter) bool {
+	if !h.config.IsFeatureEnabled(agent_config.FeatureNginxLifecycle) {
+		w.WriteHeader(http.StatusNotFound)
+		response := AgentAPICommonResponse{
+			CorrelationId: uuid.New().String(),
+			Message:       "unable to process NGINX lifecycle request as the nginx-lifecycle feature is disabled",
+		}
+		err := writeObjectToResponseBody(w, response)
+		if err != nil {
+			log.Warn(err)
+		}
+		log.Warn("NGINX Lifecycle Feature Disabled")
+		return false
+	}
+
+	if !h.config.AgentAPI.Auth.Enable {
+		w.WriteHeader(http.StatusForbidden)
+		response := AgentAPICommonResponse{
+			CorrelationId: uuid.New().String(),
+			Message:       "unable to process NGINX lifecycle request as Agent API authentication is disabled",
+		}
+		err := writeObjectToResponseBody(w, response)
+		if err != nil {
+			log.Warn(err)
+		}
+		log.Warn("NGINX lifecycle request refused, Agent API authentication is disabled")
+		return false
+	}
+
+	return true
+}
+
 // swagger:route GET /nginx/ nginx-agent get-nginx-instances
 //
 // # Get NGINX Instances
@@ -416,13 +860,14 @@ func (h *NginxHandler) sendInstanceDetailsPayload(w http.ResponseWriter, r *http
 //	200: AgentAPIConfigApplyResponse
 //	400: AgentAPICommonResponse
 //	408: AgentAPIConfigApplyStatusResponse
+//	413: AgentAPICommonResponse
 //	500: AgentAPICommonResponse
 func (h *NginxHandler) updateConfig(w http.ResponseWriter, r *http.Request) error {
 	correlationId := uuid.New().String()
 
-	buf, err := readFileFromRequest(r)
+	buf, err := readFileFromRequest(w, r)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(requestErrorStatus(err))
 		response := AgentAPICommonResponse{
 			CorrelationId: correlationId,
 			Message:       err.Error(),
@@ -433,7 +878,7 @@ func (h *NginxHandler) updateConfig(w http.ResponseWriter, r *http.Request) erro
 	nginxDetails := h.getNginxDetails()
 
 	for _, nginxDetail := range nginxDetails {
-		err := h.applyNginxConfig(nginxDetail, buf, correlationId)
+		err := h.applyNginxConfig(nginxDetail, buf, correlationId, agentAPIAppliedBy(r))
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			response := AgentAPICommonResponse{
@@ -444,68 +889,87 @@ func (h *NginxHandler) updateConfig(w http.ResponseWriter, r *http.Request) erro
 		}
 	}
 
-	if len(nginxDetails) > 0 {
-		agentAPIConfigApplyResponse := &AgentAPIConfigApplyResponse{CorrelationId: correlationId, NginxInstances: make([]NginxInstanceResponse, 0)}
+	if len(nginxDetails) == 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+		response := AgentAPICommonResponse{
+			CorrelationId: correlationId,
+			Message:       "No NGINX instances found",
+		}
+		return writeObjectToResponseBody(w, response)
+	}
 
-		select {
-		case response := <-h.responseChannel:
-			nginxResponse := NginxInstanceResponse{
-				NginxId: response.NginxConfigResponse.GetConfigData().GetNginxId(),
-				Message: response.NginxConfigResponse.GetStatus().GetMessage(),
-				Status:  okStatus,
-			}
+	return h.waitForConfigApplyResponse(w, correlationId, len(nginxDetails))
+}
 
-			if response.NginxConfigResponse.GetStatus().GetStatus() != proto.CommandStatusResponse_CMD_OK {
-				if response.NginxConfigResponse.Status.Error == nginxConfigAsyncFeatureDisabled {
-					w.WriteHeader(http.StatusForbidden)
-				} else {
-					w.WriteHeader(http.StatusBadRequest)
-				}
-				nginxResponse.Status = errorStatus
-			} else {
-				if response.NginxConfigResponse.GetStatus().GetMessage() == configAppliedProcessedResponse {
-					w.WriteHeader(http.StatusRequestTimeout)
-					nginxResponse.Status = pendingStatus
-				} else {
-					w.WriteHeader(http.StatusOK)
-				}
-			}
+// waitForConfigApplyResponse writes the outcome of the config apply requests sent to the nginx plugin
+func (h *NginxHandler) waitForConfigApplyResponse(w http.ResponseWriter, correlationId string, nginxInstanceCount int) error {
+	agentAPIConfigApplyResponse := &AgentAPIConfigApplyResponse{CorrelationId: correlationId, NginxInstances: make([]NginxInstanceResponse, 0)}
 
-			agentAPIConfigApplyResponse.NginxInstances = append(agentAPIConfigApplyResponse.NginxInstances, nginxResponse)
+	select {
+	case response := <-h.responseChannel:
+		nginxResponse := NginxInstanceResponse{
+			NginxId: response.NginxConfigResponse.GetConfigData().GetNginxId(),
+			Message: response.NginxConfigResponse.GetStatus().GetMessage(),
+			Status:  okStatus,
+		}
 
-			// If the number of responses match the number of NGINX instances then return a response.
-			// Otherwise wait until all config apply requests are complete for all NGINX instances.
-			if len(agentAPIConfigApplyResponse.NginxInstances) == len(nginxDetails) {
-				return writeObjectToResponseBody(w, agentAPIConfigApplyResponse)
+		if response.NginxConfigResponse.GetStatus().GetStatus() != proto.CommandStatusResponse_CMD_OK {
+			if response.NginxConfigResponse.Status.Error == nginxConfigAsyncFeatureDisabled {
+				w.WriteHeader(http.StatusForbidden)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
 			}
-
-		case <-time.After(validationTimeout):
-			w.WriteHeader(http.StatusRequestTimeout)
-			agentAPIConfigApplyStatusResponse := AgentAPIConfigApplyStatusResponse{
-				CorrelationId: correlationId,
-				Message:       "pending config apply",
-				Status:        pendingStatus,
+			nginxResponse.Status = errorStatus
+		} else {
+			if response.NginxConfigResponse.GetStatus().GetMessage() == configAppliedProcessedResponse {
+				w.WriteHeader(http.StatusRequestTimeout)
+				nginxResponse.Status = pendingStatus
+			} else {
+				w.WriteHeader(http.StatusOK)
 			}
+		}
 
-			return writeObjectToResponseBody(w, agentAPIConfigApplyStatusResponse)
+		agentAPIConfigApplyResponse.NginxInstances = append(agentAPIConfigApplyResponse.NginxInstances, nginxResponse)
+
+		// If the number of responses match the number of NGINX instances then return a response.
+		// Otherwise wait until all config apply requests are complete for all NGINX instances.
+		if len(agentAPIConfigApplyResponse.NginxInstances) == nginxInstanceCount {
+			return writeObjectToResponseBody(w, agentAPIConfigApplyResponse)
 		}
-	} else {
-		w.WriteHeader(http.StatusInternalServerError)
-		response := AgentAPICommonResponse{
+
+	case <-time.After(validationTimeout):
+		w.WriteHeader(http.StatusRequestTimeout)
+		agentAPIConfigApplyStatusResponse := AgentAPIConfigApplyStatusResponse{
 			CorrelationId: correlationId,
-			Message:       "No NGINX instances found",
+			Message:       "pending config apply",
+			Status:        pendingStatus,
 		}
-		return writeObjectToResponseBody(w, response)
+
+		return writeObjectToResponseBody(w, agentAPIConfigApplyStatusResponse)
 	}
 
 	w.WriteHeader(http.StatusInternalServerError)
 	return nil
 }
 
-func readFileFromRequest(r *http.Request) (*bytes.Buffer, error) {
-	err := r.ParseMultipartForm(32 << 20)
+// agentAPIAppliedBy identifies the client of a config apply request
+func agentAPIAppliedBy(r *http.Request) string {
+	identity := auth.IdentityFromContext(r.Context())
+	if identity.Subject != "" {
+		return fmt.Sprintf("agent-api %s (%s)", identity.Subject, r.RemoteAddr)
+	}
+	return "agent-api " + r.RemoteAddr
+}
+
+func readFileFromRequest(w http.ResponseWriter, r *http.Request) (*bytes.Buffer, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxConfigUploadSize)
+	err := r.ParseMultipartForm(maxConfigUploadSize)
 	if err != nil {
 		log.Errorf("unable to parse config apply request, %v", err)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, fmt.Errorf("request exceeds the maximum size of %d bytes: %w", maxConfigUploadSize, err)
+		}
 	}
 	file, _, err := r.FormFile("file")
 	if err != nil {
@@ -520,6 +984,17 @@ func readFileFromRequest(r *http.Request) (*bytes.Buffer, error) {
 	return buf, nil
 }
 
+// requestErrorStatus returns the status code of an error reading a config upload
+func requestErrorStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || errors.Is(err, revisions.ErrArchiveTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// getNginxDetails returns the details of the running NGINX instances, the instances are remembered
+// so that they can be started through the API after they are stopped
 func (h *NginxHandler) getNginxDetails() []*proto.NginxDetails {
 	var nginxDetails []*proto.NginxDetails
 
@@ -528,10 +1003,20 @@ func (h *NginxHandler) getNginxDetails() []*proto.NginxDetails {
 			nginxDetails = append(nginxDetails, h.nginxBinary.GetNginxDetailsFromProcess(proc))
 		}
 	}
+
+	h.knownInstancesMutex.Lock()
+	defer h.knownInstancesMutex.Unlock()
+	if h.knownInstances == nil {
+		h.knownInstances = make(map[string]*proto.NginxDetails)
+	}
+	for _, nginxDetail := range nginxDetails {
+		h.knownInstances[nginxDetail.GetNginxId()] = nginxDetail
+	}
+
 	return nginxDetails
 }
 
-func (h *NginxHandler) applyNginxConfig(nginxDetail *proto.NginxDetails, buf *bytes.Buffer, correlationId string) error {
+func (h *NginxHandler) applyNginxConfig(nginxDetail *proto.NginxDetails, buf *bytes.Buffer, correlationId, appliedBy string) error {
 	fullFilePath := nginxDetail.ConfPath
 
 	// Create backup of nginx.conf file on host
@@ -573,47 +1058,523 @@ func (h *NginxHandler) applyNginxConfig(nginxDetail *proto.NginxDetails, buf *by
 	}
 
 	// Send a config apply request to the nginx.go plugin
-	h.pipeline.Process(core.NewMessage(core.CommNginxConfig, &AgentAPIConfigApplyRequest{correlationId: correlationId, config: conf}))
+	h.pipeline.Process(core.NewMessage(core.CommNginxConfig, &AgentAPIConfigApplyRequest{correlationId: correlationId, appliedBy: appliedBy, config: conf}))
 	return nil
 }
 
-// swagger:route GET /nginx/config/status nginx-agent get-nginx-config-status
+// swagger:route PUT /nginx/config/dry-run nginx-agent dry-run-nginx-config
 //
-// # Get status NGINX config apply
+// # Validate NGINX configuration for all NGINX instances
 //
-// # Returns status NGINX config apply
+// # Validates a tar, tar.gz or zip archive of NGINX configuration files against the current configuration of each NGINX instance and returns a diff and a revision ID to confirm the config apply with
+// Consumes:
+//   - multipart/form-data
 //
-//	Parameters:
-//	     + name: correlation_id
-//	       in: query
-//	       description: Correlation ID of a NGINX config apply request
-//	       required: true
-//	       type: string
+// Produces:
+//   - application/json
 //
 // responses:
 //
-//	200: AgentAPIConfigApplyResponse
-//	400: AgentAPIConfigApplyStatusResponse
-//	404: AgentAPIConfigApplyStatusResponse
+//	200: AgentAPIConfigDryRunResponse
+//	400: AgentAPIConfigDryRunResponse
+//	413: AgentAPICommonResponse
 //	500: AgentAPICommonResponse
-func (h *NginxHandler) getConfigStatus(w http.ResponseWriter, r *http.Request) error {
-	correlationId := r.URL.Query().Get("correlation_id")
+func (h *NginxHandler) dryRunConfig(w http.ResponseWriter, r *http.Request) error {
+	buf, err := readFileFromRequest(w, r)
+	if err != nil {
+		w.WriteHeader(requestErrorStatus(err))
+		return writeObjectToResponseBody(w, AgentAPICommonResponse{Message: err.Error()})
+	}
 
-	if correlationId == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	archiveFiles, err := revisions.ReadArchive(buf.Bytes(), maxConfigArchiveSize)
+	if err != nil {
+		w.WriteHeader(requestErrorStatus(err))
+		return writeObjectToResponseBody(w, AgentAPICommonResponse{Message: err.Error()})
+	}
 
-		agentAPIConfigApplyStatusResponse := AgentAPIConfigApplyStatusResponse{
-			CorrelationId: correlationId,
-			Message:       "Missing required query parameter correlation_id",
-			Status:        unknownStatus,
+	nginxDetails := h.getNginxDetails()
+	if len(nginxDetails) == 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+		return writeObjectToResponseBody(w, AgentAPICommonResponse{Message: "No NGINX instances found"})
+	}
+
+	revision := &stagedRevision{
+		files:      make(map[string][]*proto.File),
+		currentIds: make(map[string]string),
+		expiresAt:  time.Now().Add(stagedRevisionTTL),
+	}
+	response := AgentAPIConfigDryRunResponse{
+		NginxInstances: make([]NginxInstanceDryRunResponse, 0, len(nginxDetails)),
+	}
+	valid := true
+
+	for _, nginxDetail := range nginxDetails {
+		nginxResponse, stagedFiles, currentId, err := h.dryRunNginxConfig(nginxDetail, archiveFiles)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return writeObjectToResponseBody(w, AgentAPICommonResponse{Message: err.Error()})
 		}
 
-		return writeObjectToResponseBody(w, agentAPIConfigApplyStatusResponse)
+		response.NginxInstances = append(response.NginxInstances, nginxResponse)
+		if nginxResponse.Status != okStatus {
+			valid = false
+			continue
+		}
+		revision.files[nginxDetail.GetNginxId()] = stagedFiles
+		revision.currentIds[nginxDetail.GetNginxId()] = currentId
 	}
 
-	agentAPIConfigApplyStatusResponse := AgentAPIConfigApplyResponse{
-		CorrelationId:  correlationId,
-		NginxInstances: []NginxInstanceResponse{},
+	if !valid {
+		w.WriteHeader(http.StatusBadRequest)
+		return writeObjectToResponseBody(w, response)
+	}
+
+	// the current configs are part of the ID so that dry runs of the same archive against
+	// other instances or a changed config don't replace each other's staged revision
+	response.RevisionId = revisions.StagedID(archiveFiles, revision.currentIds)
+	response.ExpiresAt = revision.expiresAt
+	h.storeStagedRevision(response.RevisionId, revision)
+
+	w.WriteHeader(http.StatusOK)
+	return writeObjectToResponseBody(w, response)
+}
+
+// dryRunNginxConfig validates the archive files on top of the current config of an NGINX instance
+// in a scratch directory. Validation failures are reported in the response, the returned error is
+// only set if the dry run could not be performed.
+func (h *NginxHandler) dryRunNginxConfig(nginxDetail *proto.NginxDetails, archiveFiles []*proto.File) (NginxInstanceDryRunResponse, []*proto.File, string, error) {
+	nginxResponse := NginxInstanceDryRunResponse{NginxId: nginxDetail.GetNginxId()}
+
+	currentConfig, err := h.nginxBinary.ReadConfig(nginxDetail.GetConfPath(), nginxDetail.GetNginxId(), h.env.GetSystemUUID())
+	if err != nil {
+		return nginxResponse, nil, "", fmt.Errorf("unable to read config: %v", err)
+	}
+
+	currentFiles, err := currentNginxConfigFiles(currentConfig)
+	if err != nil {
+		return nginxResponse, nil, "", err
+	}
+
+	stagedFiles := revisions.ResolveFiles(nginxDetail.GetConfPath(), archiveFiles)
+
+	stage, err := revisions.NewStage(nginxDetail.GetConfPath(), currentFiles, stagedFiles)
+	if err != nil {
+		return nginxResponse, nil, "", fmt.Errorf("unable to stage config: %v", err)
+	}
+	defer stage.Cleanup()
+
+	diff, err := revisions.Diff(currentFiles, stagedFiles)
+	if err != nil {
+		return nginxResponse, nil, "", fmt.Errorf("unable to diff config: %v", err)
+	}
+	nginxResponse.Diff = diff
+
+	err = h.nginxBinary.ValidateConfig(nginxDetail.GetProcessId(), nginxDetail.GetProcessPath(), stage.ConfPath, currentConfig, nil)
+	if err != nil {
+		nginxResponse.Status = errorStatus
+		nginxResponse.Message = err.Error()
+		return nginxResponse, nil, "", nil
+	}
+
+	nginxResponse.Status = okStatus
+	nginxResponse.Message = "config validated successfully"
+	return nginxResponse, stagedFiles, revisions.ID(currentFiles), nil
+}
+
+// swagger:route PUT /nginx/config/confirm nginx-agent confirm-nginx-config
+//
+// # Apply a validated NGINX configuration to all NGINX instances
+//
+// # Applies the configuration of a successful dry run and returns a config apply status
+//
+//	Parameters:
+//	     + name: revision_id
+//	       in: query
+//	       description: Revision ID returned by a NGINX config dry run
+//	       required: true
+//	       type: string
+//
+// Produces:
+//   - application/json
+//
+// responses:
+//
+//	200: AgentAPIConfigApplyResponse
+//	400: AgentAPICommonResponse
+//	404: AgentAPICommonResponse
+//	408: AgentAPIConfigApplyStatusResponse
+//	409: AgentAPICommonResponse
+//	500: AgentAPICommonResponse
+func (h *NginxHandler) confirmConfig(w http.ResponseWriter, r *http.Request) error {
+	correlationId := uuid.New().String()
+	revisionId := r.URL.Query().Get("revision_id")
+
+	if revisionId == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		response := AgentAPICommonResponse{
+			CorrelationId: correlationId,
+			Message:       "Missing required query parameter revision_id",
+		}
+		return writeObjectToResponseBody(w, response)
+	}
+
+	revision := h.takeStagedRevision(revisionId)
+	if revision == nil {
+		w.WriteHeader(http.StatusNotFound)
+		response := AgentAPICommonResponse{
+			CorrelationId: correlationId,
+			Message:       fmt.Sprintf("Unable to find a validated config with the revision_id %s", revisionId),
+		}
+		return writeObjectToResponseBody(w, response)
+	}
+
+	nginxDetails := h.getNginxDetails()
+	if len(nginxDetails) == 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+		response := AgentAPICommonResponse{
+			CorrelationId: correlationId,
+			Message:       "No NGINX instances found",
+		}
+		return writeObjectToResponseBody(w, response)
+	}
+
+	appliedBy := agentAPIAppliedBy(r)
+	configs := make([]*proto.NginxConfig, 0, len(nginxDetails))
+	for _, nginxDetail := range nginxDetails {
+		conf, err := h.readStagedNginxConfig(nginxDetail, revision)
+		if errors.Is(err, errConfigChanged) {
+			w.WriteHeader(http.StatusConflict)
+			response := AgentAPICommonResponse{
+				CorrelationId: correlationId,
+				Message:       err.Error(),
+			}
+			return writeObjectToResponseBody(w, response)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			response := AgentAPICommonResponse{
+				CorrelationId: correlationId,
+				Message:       err.Error(),
+			}
+			return writeObjectToResponseBody(w, response)
+		}
+		configs = append(configs, conf)
+	}
+
+	for _, conf := range configs {
+		// Send a config apply request to the nginx.go plugin
+		h.pipeline.Process(core.NewMessage(core.CommNginxConfig, &AgentAPIConfigApplyRequest{correlationId: correlationId, appliedBy: appliedBy, config: conf}))
+	}
+
+	return h.waitForConfigApplyResponse(w, correlationId, len(nginxDetails))
+}
+
+// readStagedNginxConfig builds the NginxConfig of a staged revision for an NGINX instance from its
+// current config, with the staged files replacing or added to the zipped config files. The live
+// config directory is not written to, the nginx plugin writes the staged files when applying the config.
+func (h *NginxHandler) readStagedNginxConfig(nginxDetail *proto.NginxDetails, revision *stagedRevision) (*proto.NginxConfig, error) {
+	nginxId := nginxDetail.GetNginxId()
+	stagedFiles, ok := revision.files[nginxId]
+	if !ok {
+		return nil, fmt.Errorf("%w, NGINX instance %s was not validated", errConfigChanged, nginxId)
+	}
+
+	conf, err := h.nginxBinary.ReadConfig(nginxDetail.GetConfPath(), nginxId, h.env.GetSystemUUID())
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config: %v", err)
+	}
+	confFiles, auxFiles, err := sdk.GetNginxConfigFiles(conf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config files: %v", err)
+	}
+	if revisions.ID(append(confFiles, auxFiles...)) != revision.currentIds[nginxId] {
+		return nil, fmt.Errorf("%w for NGINX instance %s", errConfigChanged, nginxId)
+	}
+
+	auxNames := make(map[string]struct{}, len(auxFiles))
+	for _, file := range auxFiles {
+		auxNames[file.GetName()] = struct{}{}
+	}
+	var stagedConfFiles, stagedAuxFiles []*proto.File
+	for _, file := range stagedFiles {
+		if _, ok := auxNames[file.GetName()]; ok {
+			stagedAuxFiles = append(stagedAuxFiles, file)
+		} else {
+			stagedConfFiles = append(stagedConfFiles, file)
+		}
+	}
+
+	conf.Zconfig, err = zipStagedFiles(conf.GetZconfig().GetRootDirectory(), confFiles, stagedConfFiles)
+	if err != nil {
+		return nil, fmt.Errorf("unable to zip staged config: %v", err)
+	}
+	if len(stagedAuxFiles) > 0 {
+		conf.Zaux, err = zipStagedFiles(conf.GetZaux().GetRootDirectory(), auxFiles, stagedAuxFiles)
+		if err != nil {
+			return nil, fmt.Errorf("unable to zip staged auxiliary files: %v", err)
+		}
+	}
+
+	return conf, nil
+}
+
+// zipStagedFiles zips the current files with the staged files replacing the current files of the same
+// name, staged files which do not exist yet are added after the current files
+func zipStagedFiles(prefix string, current []*proto.File, staged []*proto.File) (*proto.ZippedFile, error) {
+	stagedByName := make(map[string]*proto.File, len(staged))
+	for _, file := range staged {
+		stagedByName[file.GetName()] = file
+	}
+
+	writer, err := zip.NewWriter(prefix)
+	if err != nil {
+		return nil, err
+	}
+	add := func(file *proto.File) error {
+		return writer.Add(file.GetName(), sdk_files.GetFileMode(file.GetPermissions()), bytes.NewReader(file.GetContents()))
+	}
+
+	for _, file := range current {
+		if stagedFile, ok := stagedByName[file.GetName()]; ok {
+			file = stagedFile
+			delete(stagedByName, file.GetName())
+		}
+		if err := add(file); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range staged {
+		if _, ok := stagedByName[file.GetName()]; !ok {
+			continue
+		}
+		if err := add(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return writer.Proto()
+}
+
+func currentNginxConfigFiles(conf *proto.NginxConfig) ([]*proto.File, error) {
+	confFiles, auxFiles, err := sdk.GetNginxConfigFiles(conf)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config files: %v", err)
+	}
+	return append(confFiles, auxFiles...), nil
+}
+
+func (h *NginxHandler) storeStagedRevision(revisionId string, revision *stagedRevision) {
+	h.stagedRevisionsMutex.Lock()
+	defer h.stagedRevisionsMutex.Unlock()
+
+	if h.stagedRevisions == nil {
+		h.stagedRevisions = make(map[string]*stagedRevision)
+	}
+	for id, staged := range h.stagedRevisions {
+		if time.Now().After(staged.expiresAt) {
+			delete(h.stagedRevisions, id)
+		}
+	}
+	h.stagedRevisions[revisionId] = revision
+}
+
+// takeStagedRevision removes and returns a staged revision, nil is returned if the revision does not exist or expired
+func (h *NginxHandler) takeStagedRevision(revisionId string) *stagedRevision {
+	h.stagedRevisionsMutex.Lock()
+	defer h.stagedRevisionsMutex.Unlock()
+
+	revision, ok := h.stagedRevisions[revisionId]
+	if !ok {
+		return nil
+	}
+	delete(h.stagedRevisions, revisionId)
+	if time.Now().After(revision.expiresAt) {
+		return nil
+	}
+	return revision
+}
+
+// swagger:route GET /nginx/config/revisions nginx-agent get-nginx-config-revisions
+//
+// # Get applied NGINX configurations
+//
+// # Returns the stored revisions of the NGINX configurations that were applied
+//
+//	Parameters:
+//	     + name: nginx_id
+//	       in: query
+//	       description: Only return the revisions of this NGINX instance
+//	       required: false
+//	       type: string
+//
+// responses:
+//
+//	200: AgentAPIConfigRevisionsResponse
+//	404: AgentAPICommonResponse
+//	500: AgentAPICommonResponse
+func (h *NginxHandler) getConfigRevisions(w http.ResponseWriter, r *http.Request) error {
+	if h.revisionStore == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return writeObjectToResponseBody(w, AgentAPICommonResponse{Message: configRevisionsDisabled})
+	}
+
+	nginxIds := []string{r.URL.Query().Get("nginx_id")}
+	if nginxIds[0] == "" {
+		var err error
+		nginxIds, err = h.revisionStore.NginxIds()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return writeObjectToResponseBody(w, AgentAPICommonResponse{Message: err.Error()})
+		}
+	}
+
+	response := AgentAPIConfigRevisionsResponse{Revisions: []ConfigRevision{}}
+	for _, nginxId := range nginxIds {
+		storedRevisions, err := h.revisionStore.List(nginxId)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return writeObjectToResponseBody(w, AgentAPICommonResponse{Message: err.Error()})
+		}
+
+		for _, revision := range storedRevisions {
+			response.Revisions = append(response.Revisions, ConfigRevision{
+				RevisionId:    revision.ID,
+				NginxId:       revision.NginxId,
+				CorrelationId: revision.CorrelationId,
+				AppliedBy:     revision.AppliedBy,
+				AppliedAt:     revision.AppliedAt,
+				Files:         revision.Files,
+			})
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	return writeObjectToResponseBody(w, response)
+}
+
+// swagger:route PUT /nginx/config/revisions/revert nginx-agent revert-nginx-config
+//
+// # Revert NGINX configuration to a stored revision
+//
+// # Applies a stored NGINX configuration revision and returns a config apply status
+//
+//	Parameters:
+//	     + name: nginx_id
+//	       in: query
+//	       description: NGINX ID of the NGINX instance to revert
+//	       required: true
+//	       type: string
+//	     + name: revision_id
+//	       in: query
+//	       description: Revision ID of the stored NGINX configuration
+//	       required: true
+//	       type: string
+//
+// Produces:
+//   - application/json
+//
+// responses:
+//
+//	200: AgentAPIConfigApplyResponse
+//	400: AgentAPICommonResponse
+//	404: AgentAPICommonResponse
+//	408: AgentAPIConfigApplyStatusResponse
+//	500: AgentAPICommonResponse
+func (h *NginxHandler) revertConfig(w http.ResponseWriter, r *http.Request) error {
+	correlationId := uuid.New().String()
+	nginxId := r.URL.Query().Get("nginx_id")
+	revisionId := r.URL.Query().Get("revision_id")
+
+	if nginxId == "" || revisionId == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		response := AgentAPICommonResponse{
+			CorrelationId: correlationId,
+			Message:       "Missing required query parameters nginx_id and revision_id",
+		}
+		return writeObjectToResponseBody(w, response)
+	}
+
+	if h.revisionStore == nil {
+		w.WriteHeader(http.StatusNotFound)
+		response := AgentAPICommonResponse{
+			CorrelationId: correlationId,
+			Message:       configRevisionsDisabled,
+		}
+		return writeObjectToResponseBody(w, response)
+	}
+
+	_, conf, err := h.revisionStore.Get(nginxId, revisionId)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, revisions.ErrRevisionNotFound) {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+		response := AgentAPICommonResponse{
+			CorrelationId: correlationId,
+			Message:       err.Error(),
+		}
+		return writeObjectToResponseBody(w, response)
+	}
+
+	found := false
+	for _, nginxDetail := range h.getNginxDetails() {
+		if nginxDetail.GetNginxId() == nginxId {
+			found = true
+		}
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		response := AgentAPICommonResponse{
+			CorrelationId: correlationId,
+			Message:       fmt.Sprintf("Unable to find NGINX instance %s", nginxId),
+		}
+		return writeObjectToResponseBody(w, response)
+	}
+
+	// Send a config apply request to the nginx.go plugin
+	h.pipeline.Process(core.NewMessage(core.CommNginxConfig, &AgentAPIConfigApplyRequest{correlationId: correlationId, appliedBy: agentAPIAppliedBy(r), config: conf}))
+
+	return h.waitForConfigApplyResponse(w, correlationId, 1)
+}
+
+// swagger:route GET /nginx/config/status nginx-agent get-nginx-config-status
+//
+// # Get status NGINX config apply
+//
+// # Returns status NGINX config apply
+//
+//	Parameters:
+//	     + name: correlation_id
+//	       in: query
+//	       description: Correlation ID of a NGINX config apply request
+//	       required: true
+//	       type: string
+//
+// responses:
+//
+//	200: AgentAPIConfigApplyResponse
+//	400: AgentAPIConfigApplyStatusResponse
+//	404: AgentAPIConfigApplyStatusResponse
+//	500: AgentAPICommonResponse
+func (h *NginxHandler) getConfigStatus(w http.ResponseWriter, r *http.Request) error {
+	correlationId := r.URL.Query().Get("correlation_id")
+
+	if correlationId == "" {
+		w.WriteHeader(http.StatusBadRequest)
+
+		agentAPIConfigApplyStatusResponse := AgentAPIConfigApplyStatusResponse{
+			CorrelationId: correlationId,
+			Message:       "Missing required query parameter correlation_id",
+			Status:        unknownStatus,
+		}
+
+		return writeObjectToResponseBody(w, agentAPIConfigApplyStatusResponse)
+	}
+
+	agentAPIConfigApplyStatusResponse := AgentAPIConfigApplyResponse{
+		CorrelationId:  correlationId,
+		NginxInstances: []NginxInstanceResponse{},
 	}
 
 	for _, nginxConfigStatus := range h.configResponseStatuses {
@@ -642,6 +1603,184 @@ func (h *NginxHandler) getConfigStatus(w http.ResponseWriter, r *http.Request) e
 	return writeObjectToResponseBody(w, agentAPIConfigApplyStatusResponse)
 }
 
+// swagger:route POST /nginx/{nginx_id}/{action} nginx-agent run-nginx-lifecycle-action
+//
+// # Run a lifecycle action on an NGINX instance
+//
+// # Reloads, gracefully stops, starts, reopens the logs of or upgrades the binary of an NGINX instance and returns the pending status of the action
+//
+//	Parameters:
+//	     + name: nginx_id
+//	       in: path
+//	       description: NGINX ID of the NGINX instance
+//	       required: true
+//	       type: string
+//	     + name: action
+//	       in: path
+//	       description: Lifecycle action
+//	       required: true
+//	       type: string
+//	       enum: reload,stop,start,reopen-logs,upgrade
+//
+// Produces:
+//   - application/json
+//
+// responses:
+//
+//	202: NginxLifecycleStatus
+//	403: AgentAPICommonResponse
+//	404: AgentAPICommonResponse
+//	409: AgentAPICommonResponse
+func (h *NginxHandler) runLifecycleAction(w http.ResponseWriter, r *http.Request, nginxId, action string) error {
+	correlationId := uuid.New().String()
+
+	var nginxDetails *proto.NginxDetails
+	for _, nginxDetail := range h.getNginxDetails() {
+		if nginxDetail.GetNginxId() == nginxId {
+			nginxDetails = nginxDetail
+		}
+	}
+	isRunning := nginxDetails != nil
+	if !isRunning {
+		nginxDetails = h.getKnownInstance(nginxId)
+	}
+
+	var conflict string
+	switch {
+	case nginxDetails == nil:
+		w.WriteHeader(http.StatusNotFound)
+		response := AgentAPICommonResponse{
+			CorrelationId: correlationId,
+			Message:       fmt.Sprintf("Unable to find NGINX instance %s", nginxId),
+		}
+		return writeObjectToResponseBody(w, response)
+	case action == NginxLifecycleStart && isRunning:
+		conflict = fmt.Sprintf("NGINX instance %s is already running", nginxId)
+	case action != NginxLifecycleStart && !isRunning:
+		conflict = fmt.Sprintf("NGINX instance %s is not running", nginxId)
+	}
+
+	status := &NginxLifecycleStatus{
+		CorrelationId: correlationId,
+		NginxId:       nginxId,
+		Action:        action,
+		Status:        pendingStatus,
+		Message:       fmt.Sprintf("pending nginx %s", action),
+		StartedAt:     time.Now(),
+		nginxDetails:  nginxDetails,
+	}
+	if conflict == "" && !h.storeLifecycleStatus(status) {
+		conflict = fmt.Sprintf("A lifecycle action is already in progress for NGINX instance %s", nginxId)
+	}
+
+	if conflict != "" {
+		w.WriteHeader(http.StatusConflict)
+		response := AgentAPICommonResponse{
+			CorrelationId: correlationId,
+			Message:       conflict,
+		}
+		return writeObjectToResponseBody(w, response)
+	}
+
+	// Send the lifecycle request to the nginx.go plugin
+	h.pipeline.Process(core.NewMessage(nginxLifecycleTopics[action], &NginxLifecycleRequest{
+		correlationId: correlationId,
+		action:        action,
+		requestedBy:   agentAPIAppliedBy(r),
+		nginxDetails:  nginxDetails,
+	}))
+
+	w.WriteHeader(http.StatusAccepted)
+	return writeObjectToResponseBody(w, status)
+}
+
+// swagger:route GET /nginx/lifecycle/status nginx-agent get-nginx-lifecycle-status
+//
+// # Get status of an NGINX lifecycle action
+//
+// # Returns the status of a lifecycle action run on an NGINX instance
+//
+//	Parameters:
+//	     + name: correlation_id
+//	       in: query
+//	       description: Correlation ID of a NGINX lifecycle request
+//	       required: true
+//	       type: string
+//
+// responses:
+//
+//	200: NginxLifecycleStatus
+//	400: AgentAPICommonResponse
+//	404: AgentAPICommonResponse
+func (h *NginxHandler) getLifecycleStatus(w http.ResponseWriter, r *http.Request) error {
+	correlationId := r.URL.Query().Get("correlation_id")
+
+	if correlationId == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		response := AgentAPICommonResponse{
+			CorrelationId: correlationId,
+			Message:       "Missing required query parameter correlation_id",
+		}
+		return writeObjectToResponseBody(w, response)
+	}
+
+	h.lifecycleStatusesMutex.Lock()
+	status, ok := h.lifecycleStatuses[correlationId]
+	var response NginxLifecycleStatus
+	if ok {
+		response = *status
+	}
+	h.lifecycleStatusesMutex.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		response := AgentAPICommonResponse{
+			CorrelationId: correlationId,
+			Message:       fmt.Sprintf("Unable to find a lifecycle request with the correlation_id %s", correlationId),
+		}
+		return writeObjectToResponseBody(w, response)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	return writeObjectToResponseBody(w, response)
+}
+
+func (h *NginxHandler) getKnownInstance(nginxId string) *proto.NginxDetails {
+	h.knownInstancesMutex.Lock()
+	defer h.knownInstancesMutex.Unlock()
+	return h.knownInstances[nginxId]
+}
+
+// storeLifecycleStatus stores the status of a new lifecycle request, false is returned if a lifecycle
+// action is already pending for the NGINX instance
+func (h *NginxHandler) storeLifecycleStatus(status *NginxLifecycleStatus) bool {
+	h.lifecycleStatusesMutex.Lock()
+	defer h.lifecycleStatusesMutex.Unlock()
+
+	if h.lifecycleStatuses == nil {
+		h.lifecycleStatuses = make(map[string]*NginxLifecycleStatus)
+	}
+	for id, stored := range h.lifecycleStatuses {
+		if stored.Status == pendingStatus && stored.NginxId == status.NginxId {
+			return false
+		}
+		if stored.CompletedAt != nil && time.Since(*stored.CompletedAt) > lifecycleStatusTTL {
+			delete(h.lifecycleStatuses, id)
+		}
+	}
+	h.lifecycleStatuses[status.CorrelationId] = status
+	return true
+}
+
+func (h *NginxHandler) updateLifecycleStatus(status *NginxLifecycleStatus) {
+	h.lifecycleStatusesMutex.Lock()
+	defer h.lifecycleStatusesMutex.Unlock()
+
+	if _, ok := h.lifecycleStatuses[status.CorrelationId]; ok {
+		h.lifecycleStatuses[status.CorrelationId] = status
+	}
+}
+
 func (h *NginxHandler) getNginxProccessInfo() []*core.Process {
 	h.processesMutex.RLock()
 	defer h.processesMutex.RUnlock()
@@ -668,6 +1807,26 @@ func (rh *RootHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			log.Warnf("Failed to get agent health: %v", err)
 		}
+	case pipeStatsRegex.MatchString(r.URL.Path):
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		err := rh.getPipeStats(w)
+		if err != nil {
+			log.Warnf("Failed to get message pipe stats: %v", err)
+		}
+	case supportBundleRegex.MatchString(r.URL.Path):
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		err := rh.getSupportBundle(w)
+		if err != nil {
+			log.Warnf("Failed to create support bundle: %v", err)
+		}
 	default:
 		w.WriteHeader(http.StatusNotFound)
 		_, err := fmt.Fprint(w, []byte("not found"))
@@ -763,6 +1922,57 @@ func (rh *RootHandler) healthCheck(w http.ResponseWriter) error {
 	return writeObjectToResponseBody(w, healthResponse)
 }
 
+// swagger:route GET /debug/pipe nginx-agent get-pipe-stats
+//
+// # Get the internal message pipe statistics
+//
+// # Returns the number of messages published per topic, the saturation of the message queue and the processing time of each plugin
+//
+// responses:
+//
+//	200: PipeStatsSnapshot
+func (rh *RootHandler) getPipeStats(w http.ResponseWriter) error {
+	w.WriteHeader(http.StatusOK)
+	return writeObjectToResponseBody(w, rh.pipeline.GetStats().Snapshot())
+}
+
+// swagger:route GET /support-bundle nginx-agent get-support-bundle
+//
+// # Download a support bundle
+//
+// # Returns a tar.gz archive with the NGINX configs and error logs, the agent config and log, the host information, the latest metrics and the loaded plugins. Secrets are left out of the archive.
+//
+// Produces:
+//   - application/gzip
+//
+// responses:
+//
+//	200: SupportBundleResponse
+//	500: AgentAPICommonResponse
+func (rh *RootHandler) getSupportBundle(w http.ResponseWriter) error {
+	plugins := []supportbundle.Plugin{}
+	for _, plugin := range rh.pipeline.GetPlugins() {
+		plugins = append(plugins, supportbundle.Plugin{Name: plugin.Info().Name(), Version: plugin.Info().Version()})
+	}
+	for _, plugin := range rh.pipeline.GetExtensionPlugins() {
+		plugins = append(plugins, supportbundle.Plugin{Name: plugin.Info().Name(), Version: plugin.Info().Version(), Extension: true})
+	}
+
+	bundle := new(bytes.Buffer)
+	builder := supportbundle.NewBuilder(rh.config, rh.env, rh.nginxBinary)
+	_, err := builder.Write(bundle, rh.env.Processes(), rh.exporter.GetLatestMetricReports(), plugins)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return writeObjectToResponseBody(w, AgentAPICommonResponse{Message: err.Error()})
+	}
+
+	w.Header().Set(contentTypeHeader, gzipMimeType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("nginx-agent-support-bundle-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))))
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(bundle.Bytes())
+	return err
+}
+
 func writeObjectToResponseBody(w http.ResponseWriter, response any) error {
 	respBody := new(bytes.Buffer)
 	err := json.NewEncoder(respBody).Encode(response)
@@ -0,0 +1,280 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	eventsProto "github.com/nginx/agent/sdk/v2/proto/events"
+	"github.com/nginx/agent/v2/src/core"
+)
+
+const (
+	// StreamTopicActivity is the topic of the activity events reported to the management plane
+	StreamTopicActivity = "activity"
+	// StreamTopicSecurityViolation is the topic of the NGINX App Protect security violation events
+	StreamTopicSecurityViolation = "security-violation"
+
+	eventStreamMimeType       = "text/event-stream"
+	eventStreamHistorySize    = 256
+	eventStreamSubscriberSize = 64
+)
+
+var (
+	eventStreamKeepAliveInterval = 15 * time.Second
+
+	// streamedTopics are the message pipe topics streamed with their own name as the stream topic
+	streamedTopics = map[string]bool{
+		core.NginxConfigValidationPending: true,
+		core.NginxConfigApplySucceeded:    true,
+		core.NginxConfigApplyFailed:       true,
+		core.NginxReloadComplete:          true,
+		core.NginxLifecycleComplete:       true,
+		core.NginxMasterProcCreated:       true,
+		core.NginxMasterProcKilled:        true,
+		core.NginxWorkerProcCreated:       true,
+		core.NginxWorkerProcKilled:        true,
+	}
+)
+
+// StreamEvent is an agent event sent to the clients of the event stream
+type StreamEvent struct {
+	// ID of the event, increasing with every event published by the agent
+	ID uint64 `json:"id"`
+	// Topic of the event, e.g. activity or nginx.config.apply.succeeded
+	Topic string `json:"topic"`
+	// Time the event was published
+	Time time.Time `json:"time"`
+	// Data of the event, depends on the topic
+	Data interface{} `json:"data"`
+}
+
+// AgentEvent is the data of an activity or security violation event
+type AgentEvent struct {
+	Metadata *eventsProto.Metadata `json:"metadata"`
+	Event    interface{}           `json:"event"`
+}
+
+// NginxReloadEvent is the data of an nginx.reload.complete event
+type NginxReloadEvent struct {
+	NginxId       string `json:"nginx_id"`
+	CorrelationId string `json:"correlation_id"`
+	Succeeded     bool   `json:"succeeded"`
+}
+
+// eventStream keeps the latest events and fans them out to the subscribed clients. Publishing never
+// blocks the message pipe, events are dropped for subscribers that don't keep up.
+type eventStream struct {
+	mu          sync.Mutex
+	lastId      uint64
+	history     []*StreamEvent
+	subscribers map[*eventSubscriber]struct{}
+}
+
+type eventSubscriber struct {
+	topics  []string
+	events  chan *StreamEvent
+	dropped int
+}
+
+func newEventStream() *eventStream {
+	return &eventStream{
+		history:     make([]*StreamEvent, 0, eventStreamHistorySize),
+		subscribers: make(map[*eventSubscriber]struct{}),
+	}
+}
+
+// publishMessage publishes the events of a message pipe message, messages of other topics are ignored
+func (s *eventStream) publishMessage(message *core.Message) {
+	switch {
+	case message.Exact(core.Events):
+		if command, ok := message.Data().(*proto.Command); ok {
+			for _, event := range command.GetEventReport().GetEvents() {
+				s.publishAgentEvent(event)
+			}
+		}
+	case message.Exact(core.CommMetrics):
+		if payloads, ok := message.Data().([]core.Payload); ok {
+			for _, payload := range payloads {
+				if report, ok := payload.(*eventsProto.EventReport); ok {
+					for _, event := range report.GetEvents() {
+						s.publishAgentEvent(event)
+					}
+				}
+			}
+		}
+	case streamedTopics[message.Topic()]:
+		switch data := message.Data().(type) {
+		case *proto.AgentActivityStatus:
+			s.publish(message.Topic(), data.GetNginxConfigStatus())
+		case NginxReloadResponse:
+			s.publish(message.Topic(), &NginxReloadEvent{
+				NginxId:       data.nginxDetails.GetNginxId(),
+				CorrelationId: data.correlationId,
+				Succeeded:     data.succeeded,
+			})
+		default:
+			s.publish(message.Topic(), data)
+		}
+	}
+}
+
+func (s *eventStream) publishAgentEvent(event *eventsProto.Event) {
+	switch {
+	case event.GetActivityEvent() != nil:
+		s.publish(StreamTopicActivity, &AgentEvent{Metadata: event.GetMetadata(), Event: event.GetActivityEvent()})
+	case event.GetSecurityViolationEvent() != nil:
+		s.publish(StreamTopicSecurityViolation, &AgentEvent{Metadata: event.GetMetadata(), Event: event.GetSecurityViolationEvent()})
+	}
+}
+
+func (s *eventStream) publish(topic string, data interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastId++
+	event := &StreamEvent{ID: s.lastId, Topic: topic, Time: time.Now().UTC(), Data: data}
+
+	if len(s.history) == eventStreamHistorySize {
+		copy(s.history, s.history[1:])
+		s.history = s.history[:eventStreamHistorySize-1]
+	}
+	s.history = append(s.history, event)
+
+	for subscriber := range s.subscribers {
+		if !matchesTopics(topic, subscriber.topics) {
+			continue
+		}
+		select {
+		case subscriber.events <- event:
+		default:
+			subscriber.dropped++
+		}
+	}
+}
+
+// subscribe returns a subscriber for the events of the topics, the events published after the
+// event with the lastEventId that are still in the history are sent first
+func (s *eventStream) subscribe(topics []string, lastEventId uint64) *eventSubscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscriber := &eventSubscriber{
+		topics: topics,
+		events: make(chan *StreamEvent, eventStreamSubscriberSize+eventStreamHistorySize),
+	}
+	if lastEventId > 0 {
+		for _, event := range s.history {
+			if event.ID > lastEventId && matchesTopics(event.Topic, topics) {
+				subscriber.events <- event
+			}
+		}
+	}
+	s.subscribers[subscriber] = struct{}{}
+
+	return subscriber
+}
+
+func (s *eventStream) unsubscribe(subscriber *eventSubscriber) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.subscribers, subscriber)
+	if subscriber.dropped > 0 {
+		log.Warnf("Dropped %d events for a slow Agent API event stream client", subscriber.dropped)
+	}
+}
+
+// matchesTopics reports whether a topic matches one of the topic filters, a filter matches the
+// topic itself and its sub topics, e.g. nginx.config matches nginx.config.apply.failed
+func matchesTopics(topic string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if topic == filter || strings.HasPrefix(topic, filter+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// streamEvents sends the agent events to the client as Server-Sent Events until it disconnects
+func (h *APIV2Handler) streamEvents(w http.ResponseWriter, r *http.Request) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return writeAPIError(w, http.StatusInternalServerError, errorCodeInternal, "streaming is not supported")
+	}
+
+	var topics []string
+	for _, value := range r.URL.Query()["topic"] {
+		for _, topic := range strings.Split(value, ",") {
+			if topic = strings.TrimSpace(topic); topic != "" {
+				topics = append(topics, topic)
+			}
+		}
+	}
+
+	var lastEventId uint64
+	if value := r.Header.Get("Last-Event-ID"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return writeAPIError(w, http.StatusBadRequest, errorCodeInvalidParameter, fmt.Sprintf("invalid Last-Event-ID header %q", value))
+		}
+		lastEventId = parsed
+	}
+
+	subscriber := h.events.subscribe(topics, lastEventId)
+	defer h.events.unsubscribe(subscriber)
+
+	w.Header().Set(contentTypeHeader, eventStreamMimeType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case event := <-subscriber.events:
+			if err := writeStreamEvent(w, event); err != nil {
+				return err
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, event *StreamEvent) error {
+	data := new(bytes.Buffer)
+	if err := json.NewEncoder(data).Encode(event); err != nil {
+		return fmt.Errorf("failed to encode event: %v", err)
+	}
+
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n", event.ID, event.Topic, data.Bytes())
+	return err
+}
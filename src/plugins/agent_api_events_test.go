@@ -0,0 +1,196 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	eventsProto "github.com/nginx/agent/sdk/v2/proto/events"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/metrics"
+)
+
+func TestEventStream_publishMessage(t *testing.T) {
+	nginxDetails := &proto.NginxDetails{NginxId: "12345", ProcessId: "1"}
+	activityEvent := &eventsProto.Event{
+		Metadata: &eventsProto.Metadata{Module: "NGINX-AGENT", Category: "STATUS"},
+		Data:     &eventsProto.Event_ActivityEvent{ActivityEvent: &eventsProto.ActivityEvent{Message: "nginx-v1.25.3 master process (pid: 1) stopped"}},
+	}
+	securityViolationEvent := &eventsProto.Event{
+		Metadata: &eventsProto.Metadata{Module: "Agent"},
+		Data:     &eventsProto.Event_SecurityViolationEvent{SecurityViolationEvent: &eventsProto.SecurityViolationEvent{SupportID: "123"}},
+	}
+
+	tests := []struct {
+		name           string
+		message        *core.Message
+		expectedTopics []string
+		expectedData   []interface{}
+	}{
+		{
+			name: "activity events",
+			message: core.NewMessage(core.Events, &proto.Command{Data: &proto.Command_EventReport{
+				EventReport: &eventsProto.EventReport{Events: []*eventsProto.Event{activityEvent, activityEvent}},
+			}}),
+			expectedTopics: []string{StreamTopicActivity, StreamTopicActivity},
+			expectedData: []interface{}{
+				&AgentEvent{Metadata: activityEvent.GetMetadata(), Event: activityEvent.GetActivityEvent()},
+				&AgentEvent{Metadata: activityEvent.GetMetadata(), Event: activityEvent.GetActivityEvent()},
+			},
+		},
+		{
+			name: "security violation events",
+			message: core.NewMessage(core.CommMetrics, []core.Payload{
+				&metrics.MetricsReportBundle{},
+				&eventsProto.EventReport{Events: []*eventsProto.Event{securityViolationEvent}},
+			}),
+			expectedTopics: []string{StreamTopicSecurityViolation},
+			expectedData:   []interface{}{&AgentEvent{Metadata: securityViolationEvent.GetMetadata(), Event: securityViolationEvent.GetSecurityViolationEvent()}},
+		},
+		{
+			name: "config apply result",
+			message: core.NewMessage(core.NginxConfigApplySucceeded, &proto.AgentActivityStatus{
+				Status: &proto.AgentActivityStatus_NginxConfigStatus{NginxConfigStatus: &proto.NginxConfigStatus{NginxId: "12345", Status: proto.NginxConfigStatus_OK}},
+			}),
+			expectedTopics: []string{core.NginxConfigApplySucceeded},
+			expectedData:   []interface{}{&proto.NginxConfigStatus{NginxId: "12345", Status: proto.NginxConfigStatus_OK}},
+		},
+		{
+			name:           "reload",
+			message:        core.NewMessage(core.NginxReloadComplete, NginxReloadResponse{succeeded: true, correlationId: "123", nginxDetails: nginxDetails}),
+			expectedTopics: []string{core.NginxReloadComplete},
+			expectedData:   []interface{}{&NginxReloadEvent{NginxId: "12345", CorrelationId: "123", Succeeded: true}},
+		},
+		{
+			name:           "master process created",
+			message:        core.NewMessage(core.NginxMasterProcCreated, nginxDetails),
+			expectedTopics: []string{core.NginxMasterProcCreated},
+			expectedData:   []interface{}{nginxDetails},
+		},
+		{
+			name:    "not streamed",
+			message: core.NewMessage(core.MetricReport, &metrics.MetricsReportBundle{}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream := newEventStream()
+			subscriber := stream.subscribe(nil, 0)
+
+			stream.publishMessage(tt.message)
+
+			assert.Len(t, subscriber.events, len(tt.expectedTopics))
+			for i, topic := range tt.expectedTopics {
+				event := <-subscriber.events
+				assert.Equal(t, uint64(i+1), event.ID)
+				assert.Equal(t, topic, event.Topic)
+				assert.Equal(t, tt.expectedData[i], event.Data)
+			}
+		})
+	}
+}
+
+func TestEventStream_subscribe(t *testing.T) {
+	stream := newEventStream()
+	stream.publish(core.NginxConfigApplyFailed, "first")
+	stream.publish(StreamTopicActivity, "second")
+	stream.publish(core.NginxConfigValidationPending, "third")
+
+	// without a last event ID only new events are sent
+	subscriber := stream.subscribe([]string{"nginx.config"}, 0)
+	assert.Empty(t, subscriber.events)
+	stream.unsubscribe(subscriber)
+
+	// missed events are replayed, filtered by topic
+	subscriber = stream.subscribe([]string{"nginx.config"}, 1)
+	require.Len(t, subscriber.events, 1)
+	assert.Equal(t, "third", (<-subscriber.events).Data)
+
+	stream.publish(StreamTopicActivity, "fourth")
+	stream.publish(core.NginxConfigApplySucceeded, "fifth")
+	stream.publish("nginx.configured", "sixth")
+	require.Len(t, subscriber.events, 1)
+	assert.Equal(t, "fifth", (<-subscriber.events).Data)
+
+	// events are dropped for subscribers that don't keep up
+	for i := 0; i < cap(subscriber.events)+5; i++ {
+		stream.publish(core.NginxConfigApplySucceeded, i)
+	}
+	assert.Len(t, subscriber.events, cap(subscriber.events))
+	assert.Equal(t, 5, subscriber.dropped)
+	assert.Len(t, stream.history, eventStreamHistorySize)
+
+	stream.unsubscribe(subscriber)
+	assert.Empty(t, stream.subscribers)
+}
+
+func TestAPIV2Handler_streamEvents(t *testing.T) {
+	stream := newEventStream()
+	stream.publish(core.NginxMasterProcCreated, &proto.NginxDetails{NginxId: "missed"})
+
+	server := httptest.NewServer(&APIV2Handler{events: stream})
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v2/events?topic=nginx.master,activity", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "0")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, eventStreamMimeType, resp.Header.Get(contentTypeHeader))
+
+	stream.publish(core.NginxWorkerProcCreated, &proto.NginxDetails{NginxId: "worker"})
+	stream.publish(core.NginxMasterProcKilled, &proto.NginxDetails{NginxId: "12345"})
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+
+	assert.Equal(t, "id: 3", lines[0])
+	assert.Equal(t, "event: "+core.NginxMasterProcKilled, lines[1])
+	require.True(t, strings.HasPrefix(lines[2], "data: "))
+
+	var event struct {
+		ID    uint64              `json:"id"`
+		Topic string              `json:"topic"`
+		Data  *proto.NginxDetails `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &event))
+	assert.Equal(t, uint64(3), event.ID)
+	assert.Equal(t, core.NginxMasterProcKilled, event.Topic)
+	assert.Equal(t, "12345", event.Data.GetNginxId())
+}
+
+func TestAPIV2Handler_streamEventsInvalidLastEventID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	w := httptest.NewRecorder()
+
+	(&APIV2Handler{events: newEventStream()}).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
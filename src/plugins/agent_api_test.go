@@ -8,11 +8,14 @@
 package plugins
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -22,16 +25,23 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/nginx/agent/v2/src/core/metrics"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/nginx/agent/sdk/v2"
+	agent_config "github.com/nginx/agent/sdk/v2/agent/config"
 	"github.com/nginx/agent/sdk/v2/backoff"
 	"github.com/nginx/agent/sdk/v2/proto"
+	sdk_zip "github.com/nginx/agent/sdk/v2/zip"
 	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/auth"
 	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/revisions"
+	prometheus_metrics "github.com/nginx/agent/v2/src/extensions/prometheus-metrics"
 	tutils "github.com/nginx/agent/v2/test/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -52,13 +62,22 @@ func TestAgentAPI_Subscriptions(t *testing.T) {
 	expectedSubscriptions := []string{
 		core.AgentAPIConfigApplyResponse,
 		core.MetricReport,
+		core.SecurityMetricReport,
 		core.NginxConfigValidationPending,
 		core.NginxConfigApplyFailed,
 		core.NginxConfigApplySucceeded,
+		core.NginxLifecycleComplete,
 		core.NginxDetailProcUpdate,
 		core.AgentConnected,
 		core.CommandSent,
 		core.MetricReportSent,
+		core.Events,
+		core.CommMetrics,
+		core.NginxReloadComplete,
+		core.NginxMasterProcCreated,
+		core.NginxMasterProcKilled,
+		core.NginxWorkerProcCreated,
+		core.NginxWorkerProcKilled,
 	}
 
 	agentAPI := AgentAPI{}
@@ -426,6 +445,55 @@ func TestProcess_metricReport(t *testing.T) {
 	assert.Equal(t, metricReport, agentAPI.exporter.GetLatestMetricReports()[0])
 }
 
+func TestProcess_securityMetricReport(t *testing.T) {
+	conf := &config.Config{
+		AgentAPI: config.AgentAPI{
+			Port: 9090,
+		},
+	}
+
+	metricReport := &proto.MetricsReport{Meta: &proto.Metadata{MessageId: "123"}}
+	securityMetricReport := &proto.MetricsReport{Meta: &proto.Metadata{MessageId: "456"}}
+
+	agentAPI := NewAgentAPI(conf, tutils.NewMockEnvironment(), tutils.NewMockNginxBinary(), []*core.Process{})
+
+	agentAPI.Process(core.NewMessage(core.MetricReport, &metrics.MetricsReportBundle{Data: []*proto.MetricsReport{metricReport}}))
+	agentAPI.Process(core.NewMessage(core.SecurityMetricReport, securityMetricReport))
+
+	// Check that the security metric report is exposed next to the latest metric report
+	assert.Equal(t, []*proto.MetricsReport{metricReport, securityMetricReport}, agentAPI.exporter.GetLatestMetricReports())
+}
+
+func TestAgentAPI_ClientCAWithoutCert(t *testing.T) {
+	caCert, _, err := createRenewalTestCertificate([]string{"ca.example.com"}, time.Now().AddDate(0, 0, 1))
+	require.NoError(t, err)
+	clientCA := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(clientCA, caCert, 0o644))
+
+	conf := &config.Config{
+		AgentAPI: config.AgentAPI{
+			Port:     2346,
+			ClientCA: clientCA,
+		},
+	}
+
+	agentAPI := NewAgentAPI(conf, tutils.GetMockEnvWithProcess(), tutils.GetMockNginxBinary(), tutils.GetProcesses())
+	agentAPI.pipeline = core.NewMockMessagePipe(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		agentAPI.createHttpServer()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.NoError(t, agentAPI.server.Close())
+		t.Fatal("Agent API was started without TLS while a client CA is set")
+	}
+}
+
 func TestMtls_forApi(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -766,6 +834,81 @@ func TestRootHandler_healthCheck(t *testing.T) {
 	}
 }
 
+func TestRootHandler_getPipeStats(t *testing.T) {
+	pipeline := core.NewMockMessagePipe(context.TODO())
+	rootHandler := &RootHandler{config: &config.Config{}, pipeline: pipeline}
+
+	responseRecorder := httptest.NewRecorder()
+	rootHandler.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/debug/pipe", nil))
+
+	assert.Equal(t, http.StatusOK, responseRecorder.Result().StatusCode)
+
+	actualBody := &core.PipeStatsSnapshot{}
+	err := json.NewDecoder(responseRecorder.Result().Body).Decode(actualBody)
+	require.NoError(t, err)
+
+	assert.Equal(t, pipeline.GetStats().Snapshot(), actualBody)
+
+	responseRecorder = httptest.NewRecorder()
+	rootHandler.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodPut, "/debug/pipe", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, responseRecorder.Result().StatusCode)
+}
+
+func TestRootHandler_getSupportBundle(t *testing.T) {
+	conf := &config.Config{Version: "v2.36.0", Server: config.Server{Token: "secret-token"}}
+	env := tutils.NewMockEnvironment()
+	env.On("Processes").Return(tutils.GetProcesses())
+	env.On("NewHostInfo", mock.Anything, mock.Anything).Return(&proto.HostInfo{Hostname: "test-host"})
+	binary := tutils.NewMockNginxBinary()
+	binary.On("GetNginxDetailsMapFromProcesses", mock.Anything).Return(map[string]*proto.NginxDetails{})
+
+	pipeline := core.NewMockMessagePipe(context.TODO())
+	err := pipeline.Register(10, []core.Plugin{NewAgentAPI(conf, env, binary, nil)}, nil)
+	require.NoError(t, err)
+
+	rootHandler := &RootHandler{
+		config:      conf,
+		env:         env,
+		pipeline:    pipeline,
+		nginxBinary: binary,
+		exporter:    prometheus_metrics.NewExporter(&proto.MetricsReport{}),
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	rootHandler.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodGet, "/support-bundle", nil))
+
+	response := responseRecorder.Result()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "application/gzip", response.Header.Get("Content-Type"))
+	assert.Contains(t, response.Header.Get("Content-Disposition"), "attachment; filename=\"nginx-agent-support-bundle-")
+
+	gzipReader, err := gzip.NewReader(response.Body)
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+	files := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(tarReader)
+		require.NoError(t, err)
+		files[header.Name] = string(content)
+	}
+
+	assert.Contains(t, files["agent/plugins.json"], "\"name\": \"agent-api\"")
+	assert.Contains(t, files["host/host_info.json"], "test-host")
+	assert.NotContains(t, files["agent/config.json"], "secret-token")
+	assert.Contains(t, files, "manifest.json")
+
+	responseRecorder = httptest.NewRecorder()
+	rootHandler.ServeHTTP(responseRecorder, httptest.NewRequest(http.MethodPost, "/support-bundle", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, responseRecorder.Result().StatusCode)
+}
+
 func getConfig(t *testing.T) *tls.Config {
 	crt, err := os.ReadFile("../../build/certs/client.crt")
 	assert.NoError(t, err)
@@ -808,3 +951,849 @@ func printResult(resp *resty.Response, err error) *resty.Response {
 	fmt.Println()
 	return resp
 }
+
+func nginxConfigFromFiles(t *testing.T, configFiles map[string]string) *proto.NginxConfig {
+	writer, err := sdk_zip.NewWriter("/")
+	require.NoError(t, err)
+	for name, contents := range configFiles {
+		require.NoError(t, writer.Add(name, sdk_zip.DefaultFileMode, bytes.NewBufferString(contents)))
+	}
+	zconfig, err := writer.Proto()
+	require.NoError(t, err)
+
+	return &proto.NginxConfig{ConfigData: &proto.ConfigDescriptor{NginxId: "1"}, Zconfig: zconfig}
+}
+
+func configArchiveRequest(t *testing.T, path string, configFiles map[string]string) *http.Request {
+	var archive bytes.Buffer
+	tarWriter := tar.NewWriter(&archive)
+	for name, contents := range configFiles {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		_, err := tarWriter.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "config.tar")
+	require.NoError(t, err)
+	_, err = io.Copy(part, &archive)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	r := httptest.NewRequest(http.MethodPut, path, body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return r
+}
+
+func TestNginxHandler_dryRunConfig(t *testing.T) {
+	confPath := filepath.Join(t.TempDir(), "nginx.conf")
+	require.NoError(t, os.WriteFile(confPath, []byte("worker_processes 1;\n"), 0o644))
+	currentConfig := nginxConfigFromFiles(t, map[string]string{confPath: "worker_processes 1;\n"})
+
+	tests := []struct {
+		name               string
+		archive            map[string]string
+		validationError    error
+		expectedStatusCode int
+		expectedStatus     string
+		expectedDiff       string
+	}{
+		{
+			name:               "valid config",
+			archive:            map[string]string{"nginx.conf": "worker_processes 2;\n"},
+			expectedStatusCode: 200,
+			expectedStatus:     "OK",
+			expectedDiff: "--- a" + confPath + "\n+++ b" + confPath + "\n@@ -1 +1 @@\n" +
+				"-worker_processes 1;\n+worker_processes 2;\n",
+		},
+		{
+			name:               "invalid config",
+			archive:            map[string]string{"nginx.conf": "worker_processes;\n"},
+			validationError:    fmt.Errorf("invalid number of arguments in \"worker_processes\" directive"),
+			expectedStatusCode: 400,
+			expectedStatus:     "ERROR",
+			expectedDiff: "--- a" + confPath + "\n+++ b" + confPath + "\n@@ -1 +1 @@\n" +
+				"-worker_processes 1;\n+worker_processes;\n",
+		},
+		{
+			name:               "empty archive",
+			archive:            map[string]string{},
+			expectedStatusCode: 400,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nginxDetail := &proto.NginxDetails{NginxId: "1", ConfPath: confPath, ProcessId: "123", ProcessPath: "/usr/sbin/nginx"}
+
+			mockNginxBinary := tutils.NewMockNginxBinary()
+			mockNginxBinary.On("GetNginxDetailsFromProcess", mock.Anything).Return(nginxDetail)
+			mockNginxBinary.On("ReadConfig", confPath, "1", mock.Anything).Return(currentConfig, nil)
+			mockNginxBinary.On("ValidateConfig", "123", "/usr/sbin/nginx", mock.Anything, currentConfig, mock.Anything).Return(tt.validationError)
+
+			h := &NginxHandler{
+				config:      config.Defaults,
+				env:         tutils.GetMockEnv(),
+				nginxBinary: mockNginxBinary,
+				processes:   tutils.GetProcesses(),
+			}
+
+			w := httptest.NewRecorder()
+			err := h.dryRunConfig(w, configArchiveRequest(t, "/nginx/config/dry-run", tt.archive))
+			require.NoError(t, err)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode)
+
+			if tt.expectedStatus == "" {
+				mockNginxBinary.AssertNotCalled(t, "ValidateConfig", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			result := &AgentAPIConfigDryRunResponse{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(result))
+			require.Len(t, result.NginxInstances, 1)
+			assert.Equal(t, tt.expectedStatus, result.NginxInstances[0].Status)
+			assert.Equal(t, tt.expectedDiff, result.NginxInstances[0].Diff)
+
+			if tt.validationError == nil {
+				assert.NotEmpty(t, result.RevisionId)
+				assert.Contains(t, h.stagedRevisions, result.RevisionId)
+			} else {
+				assert.Empty(t, result.RevisionId)
+				assert.Empty(t, h.stagedRevisions)
+			}
+
+			// the scratch directory is removed after validation
+			stagedConfPath := mockNginxBinary.Calls[len(mockNginxBinary.Calls)-1].Arguments.String(2)
+			_, err = os.Stat(stagedConfPath)
+			assert.True(t, os.IsNotExist(err))
+		})
+	}
+}
+
+func TestNginxHandler_dryRunConfig_TooLarge(t *testing.T) {
+	defaultUploadSize, defaultArchiveSize := maxConfigUploadSize, maxConfigArchiveSize
+	defer func() {
+		maxConfigUploadSize, maxConfigArchiveSize = defaultUploadSize, defaultArchiveSize
+	}()
+
+	tests := []struct {
+		name        string
+		uploadSize  int64
+		archiveSize int64
+	}{
+		{name: "request too large", uploadSize: 1024, archiveSize: defaultArchiveSize},
+		{name: "archive files too large", uploadSize: defaultUploadSize, archiveSize: 1024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxConfigUploadSize, maxConfigArchiveSize = tt.uploadSize, tt.archiveSize
+
+			mockNginxBinary := tutils.NewMockNginxBinary()
+			h := &NginxHandler{
+				config:      config.Defaults,
+				env:         tutils.GetMockEnv(),
+				nginxBinary: mockNginxBinary,
+				processes:   tutils.GetProcesses(),
+			}
+
+			w := httptest.NewRecorder()
+			err := h.dryRunConfig(w, configArchiveRequest(t, "/nginx/config/dry-run", map[string]string{"nginx.conf": strings.Repeat("#", 4096)}))
+			require.NoError(t, err)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+			mockNginxBinary.AssertNotCalled(t, "ReadConfig", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestNginxHandler_dryRunConfig_RevisionId(t *testing.T) {
+	confPath := filepath.Join(t.TempDir(), "nginx.conf")
+	archive := map[string]string{"nginx.conf": "worker_processes 2;\n"}
+
+	dryRun := func(nginxId, currentContents string) string {
+		nginxDetail := &proto.NginxDetails{NginxId: nginxId, ConfPath: confPath, ProcessId: "123", ProcessPath: "/usr/sbin/nginx"}
+		currentConfig := nginxConfigFromFiles(t, map[string]string{confPath: currentContents})
+
+		mockNginxBinary := tutils.NewMockNginxBinary()
+		mockNginxBinary.On("GetNginxDetailsFromProcess", mock.Anything).Return(nginxDetail)
+		mockNginxBinary.On("ReadConfig", confPath, nginxId, mock.Anything).Return(currentConfig, nil)
+		mockNginxBinary.On("ValidateConfig", "123", "/usr/sbin/nginx", mock.Anything, currentConfig, mock.Anything).Return(nil)
+
+		h := &NginxHandler{
+			config:      config.Defaults,
+			env:         tutils.GetMockEnv(),
+			nginxBinary: mockNginxBinary,
+			processes:   tutils.GetProcesses(),
+		}
+
+		w := httptest.NewRecorder()
+		require.NoError(t, h.dryRunConfig(w, configArchiveRequest(t, "/nginx/config/dry-run", archive)))
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		result := &AgentAPIConfigDryRunResponse{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(result))
+		return result.RevisionId
+	}
+
+	revisionId := dryRun("1", "worker_processes 1;\n")
+	assert.Equal(t, revisionId, dryRun("1", "worker_processes 1;\n"))
+	assert.NotEqual(t, revisionId, dryRun("2", "worker_processes 1;\n"))
+	assert.NotEqual(t, revisionId, dryRun("1", "worker_processes 4;\n"))
+}
+
+func TestNginxHandler_confirmConfig(t *testing.T) {
+	confPath := filepath.Join(t.TempDir(), "nginx.conf")
+	require.NoError(t, os.WriteFile(confPath, []byte("worker_processes 1;\n"), 0o644))
+	currentId := revisions.ID([]*proto.File{{Name: confPath, Permissions: "0644", Contents: []byte("worker_processes 1;\n")}})
+	stagedFiles := []*proto.File{{Name: confPath, Permissions: "0644", Contents: []byte("worker_processes 2;\n")}}
+
+	tests := []struct {
+		name               string
+		revisionId         string
+		revision           *stagedRevision
+		expectedStatusCode int
+	}{
+		{
+			name:               "missing revision id",
+			revisionId:         "",
+			expectedStatusCode: 400,
+		},
+		{
+			name:               "unknown revision id",
+			revisionId:         "123",
+			expectedStatusCode: 404,
+		},
+		{
+			name:       "expired revision",
+			revisionId: "123",
+			revision: &stagedRevision{
+				files:      map[string][]*proto.File{"1": stagedFiles},
+				currentIds: map[string]string{"1": currentId},
+				expiresAt:  time.Now().Add(-time.Minute),
+			},
+			expectedStatusCode: 404,
+		},
+		{
+			name:       "config changed since dry run",
+			revisionId: "123",
+			revision: &stagedRevision{
+				files:      map[string][]*proto.File{"1": stagedFiles},
+				currentIds: map[string]string{"1": "456"},
+				expiresAt:  time.Now().Add(time.Minute),
+			},
+			expectedStatusCode: 409,
+		},
+		{
+			name:       "confirmed revision",
+			revisionId: "123",
+			revision: &stagedRevision{
+				files:      map[string][]*proto.File{"1": stagedFiles},
+				currentIds: map[string]string{"1": currentId},
+				expiresAt:  time.Now().Add(time.Minute),
+			},
+			expectedStatusCode: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validationTimeout = 15 * time.Second
+			nginxDetail := &proto.NginxDetails{NginxId: "1", ConfPath: confPath, ProcessId: "123", ProcessPath: "/usr/sbin/nginx"}
+
+			env := tutils.GetMockEnv()
+
+			currentConfig := nginxConfigFromFiles(t, map[string]string{confPath: "worker_processes 1;\n"})
+			mockNginxBinary := tutils.NewMockNginxBinary()
+			mockNginxBinary.On("GetNginxDetailsFromProcess", mock.Anything).Return(nginxDetail)
+			mockNginxBinary.On("ReadConfig", confPath, "1", mock.Anything).Return(currentConfig, nil)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			pipeline := core.NewMockMessagePipe(ctx)
+
+			h := &NginxHandler{
+				config:          config.Defaults,
+				env:             env,
+				pipeline:        pipeline,
+				nginxBinary:     mockNginxBinary,
+				responseChannel: make(chan *proto.Command_NginxConfigResponse),
+				processes:       tutils.GetProcesses(),
+				stagedRevisions: make(map[string]*stagedRevision),
+			}
+			if tt.revision != nil {
+				h.stagedRevisions[tt.revisionId] = tt.revision
+			}
+
+			if tt.expectedStatusCode == 200 {
+				go func() {
+					h.responseChannel <- &proto.Command_NginxConfigResponse{
+						NginxConfigResponse: &proto.NginxConfigResponse{
+							Status:     &proto.CommandStatusResponse{Status: proto.CommandStatusResponse_CMD_OK, Message: configAppliedResponse},
+							Action:     proto.NginxConfigAction_APPLY,
+							ConfigData: &proto.ConfigDescriptor{NginxId: "1"},
+						},
+					}
+				}()
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPut, "/nginx/config/confirm?revision_id="+tt.revisionId, nil)
+			require.NoError(t, h.confirmConfig(w, r))
+
+			resp := w.Result()
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode)
+			assert.Empty(t, h.stagedRevisions)
+
+			// the live config is left to the nginx plugin to write
+			env.AssertNotCalled(t, "WriteFiles", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			liveContents, err := os.ReadFile(confPath)
+			require.NoError(t, err)
+			assert.Equal(t, "worker_processes 1;\n", string(liveContents))
+
+			messages := pipeline.GetMessages()
+			if tt.expectedStatusCode == 200 {
+				require.Len(t, messages, 1)
+				assert.Equal(t, core.CommNginxConfig, messages[0].Topic())
+
+				confFiles, _, err := sdk.GetNginxConfigFiles(messages[0].Data().(*AgentAPIConfigApplyRequest).config)
+				require.NoError(t, err)
+				require.Len(t, confFiles, 1)
+				assert.Equal(t, confPath, confFiles[0].GetName())
+				assert.Equal(t, "worker_processes 2;\n", string(confFiles[0].GetContents()))
+			} else {
+				assert.Empty(t, messages)
+			}
+		})
+	}
+}
+
+func TestNginxHandler_getConfigRevisions(t *testing.T) {
+	store, err := revisions.NewStore(t.TempDir(), 10)
+	require.NoError(t, err)
+	revision, err := store.Add(revisions.Revision{NginxId: "1", CorrelationId: "123", AppliedBy: commandChannelAppliedBy}, nginxConfigFromFiles(t, map[string]string{"/etc/nginx/nginx.conf": "worker_processes 2;"}))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name               string
+		store              *revisions.Store
+		query              string
+		expectedStatusCode int
+		expectedRevisions  []string
+	}{
+		{
+			name:               "config revisions disabled",
+			store:              nil,
+			expectedStatusCode: 404,
+		},
+		{
+			name:               "all nginx instances",
+			store:              store,
+			expectedStatusCode: 200,
+			expectedRevisions:  []string{revision.ID},
+		},
+		{
+			name:               "nginx instance with revisions",
+			store:              store,
+			query:              "?nginx_id=1",
+			expectedStatusCode: 200,
+			expectedRevisions:  []string{revision.ID},
+		},
+		{
+			name:               "nginx instance without revisions",
+			store:              store,
+			query:              "?nginx_id=2",
+			expectedStatusCode: 200,
+			expectedRevisions:  []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &NginxHandler{config: config.Defaults, revisionStore: tt.store}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/nginx/config/revisions"+tt.query, nil)
+			require.NoError(t, h.getConfigRevisions(w, r))
+
+			resp := w.Result()
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode)
+
+			if tt.expectedRevisions == nil {
+				return
+			}
+
+			result := &AgentAPIConfigRevisionsResponse{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(result))
+			revisionIds := []string{}
+			for _, configRevision := range result.Revisions {
+				revisionIds = append(revisionIds, configRevision.RevisionId)
+				assert.Equal(t, "123", configRevision.CorrelationId)
+				assert.Equal(t, commandChannelAppliedBy, configRevision.AppliedBy)
+				assert.Equal(t, []string{"/etc/nginx/nginx.conf"}, configRevision.Files)
+			}
+			assert.Equal(t, tt.expectedRevisions, revisionIds)
+		})
+	}
+}
+
+func TestNginxHandler_revertConfig(t *testing.T) {
+	store, err := revisions.NewStore(t.TempDir(), 10)
+	require.NoError(t, err)
+	revision, err := store.Add(revisions.Revision{NginxId: "1"}, nginxConfigFromFiles(t, map[string]string{"/etc/nginx/nginx.conf": "worker_processes 2;"}))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name               string
+		store              *revisions.Store
+		query              string
+		expectedStatusCode int
+	}{
+		{
+			name:               "missing query parameters",
+			store:              store,
+			query:              "?nginx_id=1",
+			expectedStatusCode: 400,
+		},
+		{
+			name:               "config revisions disabled",
+			store:              nil,
+			query:              "?nginx_id=1&revision_id=" + revision.ID,
+			expectedStatusCode: 404,
+		},
+		{
+			name:               "unknown revision",
+			store:              store,
+			query:              "?nginx_id=1&revision_id=123",
+			expectedStatusCode: 404,
+		},
+		{
+			name:               "unknown nginx instance",
+			store:              store,
+			query:              "?nginx_id=2&revision_id=" + revision.ID,
+			expectedStatusCode: 404,
+		},
+		{
+			name:               "stored revision",
+			store:              store,
+			query:              "?nginx_id=1&revision_id=" + revision.ID,
+			expectedStatusCode: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validationTimeout = 15 * time.Second
+
+			mockNginxBinary := tutils.NewMockNginxBinary()
+			mockNginxBinary.On("GetNginxDetailsFromProcess", mock.Anything).Return(&proto.NginxDetails{NginxId: "1"})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			pipeline := core.NewMockMessagePipe(ctx)
+
+			h := &NginxHandler{
+				config:          config.Defaults,
+				pipeline:        pipeline,
+				nginxBinary:     mockNginxBinary,
+				responseChannel: make(chan *proto.Command_NginxConfigResponse),
+				processes:       tutils.GetProcesses(),
+				revisionStore:   tt.store,
+			}
+
+			if tt.expectedStatusCode == 200 {
+				go func() {
+					h.responseChannel <- &proto.Command_NginxConfigResponse{
+						NginxConfigResponse: &proto.NginxConfigResponse{
+							Status:     &proto.CommandStatusResponse{Status: proto.CommandStatusResponse_CMD_OK, Message: configAppliedResponse},
+							Action:     proto.NginxConfigAction_APPLY,
+							ConfigData: &proto.ConfigDescriptor{NginxId: "1"},
+						},
+					}
+				}()
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPut, "/nginx/config/revisions/revert"+tt.query, nil)
+			require.NoError(t, h.revertConfig(w, r))
+
+			resp := w.Result()
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode)
+
+			messages := pipeline.GetMessages()
+			if tt.expectedStatusCode == 200 {
+				require.Len(t, messages, 1)
+				request := messages[0].Data().(*AgentAPIConfigApplyRequest)
+				assert.Equal(t, "1", request.config.GetConfigData().GetNginxId())
+				assert.Contains(t, request.appliedBy, "agent-api")
+			} else {
+				assert.Empty(t, messages)
+			}
+		})
+	}
+}
+
+func TestNginxHandler_runLifecycleAction(t *testing.T) {
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		knownInstances     map[string]*proto.NginxDetails
+		pendingNginxId     string
+		expectedStatusCode int
+		expectedTopic      string
+	}{
+		{
+			name:               "reload running instance",
+			method:             http.MethodPost,
+			path:               "/nginx/1/reload",
+			expectedStatusCode: 202,
+			expectedTopic:      core.NginxReload,
+		},
+		{
+			name:               "upgrade running instance",
+			method:             http.MethodPost,
+			path:               "/nginx/1/upgrade/",
+			expectedStatusCode: 202,
+			expectedTopic:      core.NginxUpgrade,
+		},
+		{
+			name:               "start stopped instance",
+			method:             http.MethodPost,
+			path:               "/nginx/2/start",
+			knownInstances:     map[string]*proto.NginxDetails{"2": {NginxId: "2"}},
+			expectedStatusCode: 202,
+			expectedTopic:      core.NginxStart,
+		},
+		{
+			name:               "unknown instance",
+			method:             http.MethodPost,
+			path:               "/nginx/2/stop",
+			expectedStatusCode: 404,
+		},
+		{
+			name:               "start running instance",
+			method:             http.MethodPost,
+			path:               "/nginx/1/start",
+			expectedStatusCode: 409,
+		},
+		{
+			name:               "stop stopped instance",
+			method:             http.MethodPost,
+			path:               "/nginx/2/stop",
+			knownInstances:     map[string]*proto.NginxDetails{"2": {NginxId: "2"}},
+			expectedStatusCode: 409,
+		},
+		{
+			name:               "action already pending",
+			method:             http.MethodPost,
+			path:               "/nginx/1/reopen-logs",
+			pendingNginxId:     "1",
+			expectedStatusCode: 409,
+		},
+		{
+			name:               "method not allowed",
+			method:             http.MethodGet,
+			path:               "/nginx/1/reload",
+			expectedStatusCode: 405,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockNginxBinary := tutils.NewMockNginxBinary()
+			mockNginxBinary.On("GetNginxDetailsFromProcess", mock.Anything).Return(&proto.NginxDetails{NginxId: "1", ProcessId: "1"})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			pipeline := core.NewMockMessagePipe(ctx)
+
+			conf := *config.Defaults
+			conf.Features = append([]string{agent_config.FeatureNginxLifecycle}, conf.Features...)
+			conf.AgentAPI.Auth.Enable = true
+
+			h := &NginxHandler{
+				config:            &conf,
+				pipeline:          pipeline,
+				nginxBinary:       mockNginxBinary,
+				processes:         tutils.GetProcesses(),
+				knownInstances:    tt.knownInstances,
+				lifecycleStatuses: make(map[string]*NginxLifecycleStatus),
+			}
+			if tt.pendingNginxId != "" {
+				h.lifecycleStatuses["pending"] = &NginxLifecycleStatus{CorrelationId: "pending", NginxId: tt.pendingNginxId, Status: pendingStatus}
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			resp := w.Result()
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode)
+
+			messages := pipeline.GetMessages()
+			if tt.expectedTopic == "" {
+				assert.Empty(t, messages)
+				return
+			}
+
+			require.Len(t, messages, 1)
+			assert.Equal(t, tt.expectedTopic, messages[0].Topic())
+			request := messages[0].Data().(*NginxLifecycleRequest)
+			assert.Contains(t, request.requestedBy, "agent-api")
+
+			var status NginxLifecycleStatus
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+			assert.Equal(t, request.correlationId, status.CorrelationId)
+			assert.Equal(t, request.action, status.Action)
+			assert.Equal(t, pendingStatus, status.Status)
+			assert.Contains(t, h.lifecycleStatuses, status.CorrelationId)
+		})
+	}
+}
+
+func TestNginxHandler_runLifecycleAction_Disabled(t *testing.T) {
+	tests := []struct {
+		name               string
+		feature            bool
+		authEnabled        bool
+		expectedStatusCode int
+	}{
+		{
+			name:               "feature disabled",
+			authEnabled:        true,
+			expectedStatusCode: 404,
+		},
+		{
+			name:               "auth disabled",
+			feature:            true,
+			expectedStatusCode: 403,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockNginxBinary := tutils.NewMockNginxBinary()
+			mockNginxBinary.On("GetNginxDetailsFromProcess", mock.Anything).Return(&proto.NginxDetails{NginxId: "1", ProcessId: "1"})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			pipeline := core.NewMockMessagePipe(ctx)
+
+			conf := *config.Defaults
+			if tt.feature {
+				conf.Features = append([]string{agent_config.FeatureNginxLifecycle}, conf.Features...)
+			}
+			conf.AgentAPI.Auth.Enable = tt.authEnabled
+
+			h := &NginxHandler{
+				config:            &conf,
+				pipeline:          pipeline,
+				nginxBinary:       mockNginxBinary,
+				processes:         tutils.GetProcesses(),
+				lifecycleStatuses: make(map[string]*NginxLifecycleStatus),
+			}
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/nginx/1/stop", nil))
+
+			resp := w.Result()
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode)
+			assert.Empty(t, pipeline.GetMessages())
+			assert.Empty(t, h.lifecycleStatuses)
+		})
+	}
+}
+
+func TestNginxHandler_getLifecycleStatus(t *testing.T) {
+	completedAt := time.Now()
+	h := &NginxHandler{
+		lifecycleStatuses: map[string]*NginxLifecycleStatus{
+			"123": {CorrelationId: "123", NginxId: "1", Action: NginxLifecycleReload, Status: pendingStatus},
+		},
+	}
+	h.updateLifecycleStatus(&NginxLifecycleStatus{CorrelationId: "123", NginxId: "1", Action: NginxLifecycleReload, Status: okStatus, CompletedAt: &completedAt})
+	h.updateLifecycleStatus(&NginxLifecycleStatus{CorrelationId: "456", NginxId: "1", Action: NginxLifecycleStop, Status: okStatus, CompletedAt: &completedAt})
+
+	tests := []struct {
+		name               string
+		query              string
+		expectedStatusCode int
+		expectedStatus     string
+	}{
+		{
+			name:               "missing correlation id",
+			expectedStatusCode: 400,
+		},
+		{
+			name:               "unknown correlation id",
+			query:              "?correlation_id=456",
+			expectedStatusCode: 404,
+		},
+		{
+			name:               "completed lifecycle action",
+			query:              "?correlation_id=123",
+			expectedStatusCode: 200,
+			expectedStatus:     okStatus,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/nginx/lifecycle/status"+tt.query, nil))
+
+			resp := w.Result()
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode)
+
+			if tt.expectedStatus != "" {
+				var status NginxLifecycleStatus
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+				assert.Equal(t, tt.expectedStatus, status.Status)
+				assert.NotNil(t, status.CompletedAt)
+			}
+		})
+	}
+}
+
+func TestAuthHandler(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator(config.AgentAPIAuth{
+		Enable:            true,
+		ReadOnlyTokens:    []string{"read-token"},
+		ConfigWriteTokens: []string{"write-token"},
+	})
+	require.NoError(t, err)
+
+	auditLog := filepath.Join(t.TempDir(), "audit.log")
+	auditLogger, err := auth.NewAuditLogger(auditLog)
+	require.NoError(t, err)
+
+	var identity *auth.Identity
+	h := &authHandler{
+		next: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity = auth.IdentityFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}),
+		authenticator: authenticator,
+		auditLogger:   auditLogger,
+	}
+
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		token              string
+		expectedStatusCode int
+		expectedRoles      []string
+	}{
+		{
+			name:               "health check without credentials",
+			method:             http.MethodGet,
+			path:               "/health",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "read without credentials",
+			method:             http.MethodGet,
+			path:               "/nginx/",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "read with read-only token",
+			method:             http.MethodGet,
+			path:               "/nginx/",
+			token:              "read-token",
+			expectedStatusCode: http.StatusOK,
+			expectedRoles:      []string{auth.RoleReadOnly},
+		},
+		{
+			name:               "config update with read-only token",
+			method:             http.MethodPut,
+			path:               "/nginx/config/",
+			token:              "read-token",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "config update with unknown token",
+			method:             http.MethodPut,
+			path:               "/nginx/config/",
+			token:              "other-token",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "config update with config-write token",
+			method:             http.MethodPut,
+			path:               "/nginx/config/",
+			token:              "write-token",
+			expectedStatusCode: http.StatusOK,
+			expectedRoles:      []string{auth.RoleConfigWrite},
+		},
+		{
+			name:               "support bundle with read-only token",
+			method:             http.MethodGet,
+			path:               "/support-bundle",
+			token:              "read-token",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "support bundle with config-write token",
+			method:             http.MethodGet,
+			path:               "/support-bundle",
+			token:              "write-token",
+			expectedStatusCode: http.StatusOK,
+			expectedRoles:      []string{auth.RoleConfigWrite},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity = nil
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode)
+			if tt.expectedStatusCode == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+			}
+			if tt.expectedRoles != nil {
+				require.NotNil(t, identity)
+				assert.Equal(t, tt.expectedRoles, identity.Roles)
+			}
+		})
+	}
+
+	require.NoError(t, auditLogger.Close())
+	content, err := os.ReadFile(auditLog)
+	require.NoError(t, err)
+
+	var statusCodes []int
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for decoder.More() {
+		var entry auth.AuditEntry
+		require.NoError(t, decoder.Decode(&entry))
+		assert.Equal(t, http.MethodPut, entry.Method)
+		statusCodes = append(statusCodes, entry.StatusCode)
+	}
+	assert.Equal(t, []int{http.StatusForbidden, http.StatusUnauthorized, http.StatusOK}, statusCodes)
+}
@@ -0,0 +1,394 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package plugins
+
+import (
+	_ "embed"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+	prometheus_metrics "github.com/nginx/agent/v2/src/extensions/prometheus-metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	apiV2Prefix = "/api/v2"
+
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+
+	errorCodeNotFound         = "NOT_FOUND"
+	errorCodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	errorCodeInvalidParameter = "INVALID_PARAMETER"
+	errorCodeInternal         = "INTERNAL_ERROR"
+
+	instanceResourceCertificates = "certificates"
+	instanceResourceAccessLogs   = "access-logs"
+	instanceResourceMetrics      = "metrics"
+
+	nginxIdDimension = "nginx_id"
+)
+
+// openAPIV2Document describes the v2 Agent API
+//
+//go:embed openapi/agent-api-v2.json
+var openAPIV2Document []byte
+
+var (
+	v2OpenAPIRegex          = regexp.MustCompile(`^\/api\/v2\/openapi\.json$`)
+	v2EventsRegex           = regexp.MustCompile(`^\/api\/v2\/events[\/]*$`)
+	v2HostRegex             = regexp.MustCompile(`^\/api\/v2\/host[\/]*$`)
+	v2InstancesRegex        = regexp.MustCompile(`^\/api\/v2\/instances[\/]*$`)
+	v2InstanceRegex         = regexp.MustCompile(`^\/api\/v2\/instances\/([^/]+)[\/]*$`)
+	v2InstanceResourceRegex = regexp.MustCompile(`^\/api\/v2\/instances\/([^/]+)\/(certificates|access-logs|error-logs|metrics)[\/]*$`)
+)
+
+// APIV2Handler serves the read endpoints of the v2 Agent API. Every response is a JSON object, errors
+// are returned as an APIErrorResponse and lists can be paginated with the limit and offset parameters.
+type APIV2Handler struct {
+	config       *config.Config
+	env          core.Environment
+	nginxBinary  core.NginxBinary
+	nginxHandler *NginxHandler
+	exporter     *prometheus_metrics.Exporter
+	events       *eventStream
+}
+
+// APIError is the error returned by the v2 Agent API
+type APIError struct {
+	// Error code, one of NOT_FOUND, METHOD_NOT_ALLOWED, INVALID_PARAMETER or INTERNAL_ERROR
+	Code string `json:"code"`
+	// Error message
+	Message string `json:"message"`
+}
+
+// APIErrorResponse is the body of every v2 Agent API response with an error status code
+type APIErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// Pagination of a list returned by the v2 Agent API
+type Pagination struct {
+	// Total number of items matching the filters
+	Total int `json:"total"`
+	// Maximum number of items returned
+	Limit int `json:"limit"`
+	// Number of items skipped
+	Offset int `json:"offset"`
+}
+
+// InstanceList is a page of NGINX instances
+type InstanceList struct {
+	Items []*proto.NginxDetails `json:"items"`
+	Pagination
+}
+
+// CertificateList is a page of the SSL certificates referenced by the config of an NGINX instance
+type CertificateList struct {
+	Items []*proto.SslCertificate `json:"items"`
+	Pagination
+}
+
+// AccessLogList is a page of the access logs of an NGINX instance
+type AccessLogList struct {
+	Items []*proto.AccessLog `json:"items"`
+	Pagination
+}
+
+// ErrorLogList is a page of the error logs of an NGINX instance
+type ErrorLogList struct {
+	Items []*proto.ErrorLog `json:"items"`
+	Pagination
+}
+
+// InstanceMetric is a metric value of the latest metrics report of an NGINX instance
+type InstanceMetric struct {
+	Name       string            `json:"name"`
+	Value      float64           `json:"value"`
+	Dimensions map[string]string `json:"dimensions"`
+	Timestamp  *time.Time        `json:"timestamp,omitempty"`
+}
+
+// InstanceMetricList is a page of the latest metrics of an NGINX instance
+type InstanceMetricList struct {
+	Items []*InstanceMetric `json:"items"`
+	Pagination
+}
+
+// instanceFilter selects the NGINX instances returned by the instances endpoint
+type instanceFilter struct {
+	tag     string
+	version string
+	plus    *bool
+}
+
+func (h *APIV2Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var err error
+	switch {
+	case r.Method != http.MethodGet:
+		err = writeAPIError(w, http.StatusMethodNotAllowed, errorCodeMethodNotAllowed, fmt.Sprintf("method %s is not allowed", r.Method))
+	case v2OpenAPIRegex.MatchString(r.URL.Path):
+		w.Header().Set(contentTypeHeader, jsonMimeType)
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(openAPIV2Document)
+	case v2EventsRegex.MatchString(r.URL.Path):
+		err = h.streamEvents(w, r)
+	case v2HostRegex.MatchString(r.URL.Path):
+		err = h.getHost(w)
+	case v2InstancesRegex.MatchString(r.URL.Path):
+		err = h.getInstances(w, r)
+	case v2InstanceRegex.MatchString(r.URL.Path):
+		matches := v2InstanceRegex.FindStringSubmatch(r.URL.Path)
+		err = h.getInstance(w, matches[1])
+	case v2InstanceResourceRegex.MatchString(r.URL.Path):
+		matches := v2InstanceResourceRegex.FindStringSubmatch(r.URL.Path)
+		err = h.getInstanceResource(w, r, matches[1], matches[2])
+	default:
+		err = writeAPIError(w, http.StatusNotFound, errorCodeNotFound, fmt.Sprintf("%s not found", r.URL.Path))
+	}
+
+	if err != nil {
+		log.Warnf("Failed to send v2 Agent API response for %s: %v", r.URL.Path, err)
+	}
+}
+
+func (h *APIV2Handler) getHost(w http.ResponseWriter) error {
+	hostInfo := h.env.NewHostInfo(h.config.Version, &h.config.Tags, h.config.ConfigDirs, false)
+	return writeAPIResponse(w, hostInfo)
+}
+
+func (h *APIV2Handler) getInstances(w http.ResponseWriter, r *http.Request) error {
+	filter, err := parseInstanceFilter(r)
+	if err != nil {
+		return writeAPIError(w, http.StatusBadRequest, errorCodeInvalidParameter, err.Error())
+	}
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		return writeAPIError(w, http.StatusBadRequest, errorCodeInvalidParameter, err.Error())
+	}
+
+	instances := []*proto.NginxDetails{}
+	for _, nginxDetails := range h.nginxHandler.getNginxDetails() {
+		if h.matchesFilter(nginxDetails, filter) {
+			instances = append(instances, nginxDetails)
+		}
+	}
+
+	start, end := pageBounds(len(instances), limit, offset)
+	return writeAPIResponse(w, &InstanceList{
+		Items:      instances[start:end],
+		Pagination: Pagination{Total: len(instances), Limit: limit, Offset: offset},
+	})
+}
+
+func (h *APIV2Handler) getInstance(w http.ResponseWriter, nginxId string) error {
+	nginxDetails := h.findInstance(nginxId)
+	if nginxDetails == nil {
+		return writeAPIError(w, http.StatusNotFound, errorCodeNotFound, fmt.Sprintf("NGINX instance %s not found", nginxId))
+	}
+	return writeAPIResponse(w, nginxDetails)
+}
+
+func (h *APIV2Handler) getInstanceResource(w http.ResponseWriter, r *http.Request, nginxId, resource string) error {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		return writeAPIError(w, http.StatusBadRequest, errorCodeInvalidParameter, err.Error())
+	}
+
+	nginxDetails := h.findInstance(nginxId)
+	if nginxDetails == nil {
+		return writeAPIError(w, http.StatusNotFound, errorCodeNotFound, fmt.Sprintf("NGINX instance %s not found", nginxId))
+	}
+
+	if resource == instanceResourceMetrics {
+		metrics := h.instanceMetrics(nginxId)
+		start, end := pageBounds(len(metrics), limit, offset)
+		return writeAPIResponse(w, &InstanceMetricList{
+			Items:      metrics[start:end],
+			Pagination: Pagination{Total: len(metrics), Limit: limit, Offset: offset},
+		})
+	}
+
+	nginxConfig, err := h.nginxBinary.ReadConfig(nginxDetails.GetConfPath(), nginxId, h.env.GetSystemUUID())
+	if err != nil {
+		log.Warnf("Unable to read the config of NGINX instance %s: %v", nginxId, err)
+		return writeAPIError(w, http.StatusInternalServerError, errorCodeInternal, fmt.Sprintf("unable to read the config of NGINX instance %s: %v", nginxId, err))
+	}
+
+	switch resource {
+	case instanceResourceCertificates:
+		certificates := nginxConfig.GetSsl().GetSslCerts()
+		if certificates == nil {
+			certificates = []*proto.SslCertificate{}
+		}
+		start, end := pageBounds(len(certificates), limit, offset)
+		return writeAPIResponse(w, &CertificateList{
+			Items:      certificates[start:end],
+			Pagination: Pagination{Total: len(certificates), Limit: limit, Offset: offset},
+		})
+	case instanceResourceAccessLogs:
+		accessLogs := nginxConfig.GetAccessLogs().GetAccessLog()
+		if accessLogs == nil {
+			accessLogs = []*proto.AccessLog{}
+		}
+		start, end := pageBounds(len(accessLogs), limit, offset)
+		return writeAPIResponse(w, &AccessLogList{
+			Items:      accessLogs[start:end],
+			Pagination: Pagination{Total: len(accessLogs), Limit: limit, Offset: offset},
+		})
+	default:
+		errorLogs := nginxConfig.GetErrorLogs().GetErrorLog()
+		if errorLogs == nil {
+			errorLogs = []*proto.ErrorLog{}
+		}
+		start, end := pageBounds(len(errorLogs), limit, offset)
+		return writeAPIResponse(w, &ErrorLogList{
+			Items:      errorLogs[start:end],
+			Pagination: Pagination{Total: len(errorLogs), Limit: limit, Offset: offset},
+		})
+	}
+}
+
+// findInstance returns the details of a running NGINX instance, nil is returned if it is not running
+func (h *APIV2Handler) findInstance(nginxId string) *proto.NginxDetails {
+	for _, nginxDetails := range h.nginxHandler.getNginxDetails() {
+		if nginxDetails.GetNginxId() == nginxId {
+			return nginxDetails
+		}
+	}
+	return nil
+}
+
+// instanceMetrics returns the metrics of the latest metrics reports that have the nginx_id dimension of the instance.
+// Values that are not finite can not be encoded in JSON and are left out.
+func (h *APIV2Handler) instanceMetrics(nginxId string) []*InstanceMetric {
+	metrics := []*InstanceMetric{}
+	for _, report := range h.exporter.GetLatestMetricReports() {
+		for _, entity := range report.GetData() {
+			dimensions := make(map[string]string, len(entity.GetDimensions()))
+			for _, dimension := range entity.GetDimensions() {
+				dimensions[dimension.GetName()] = dimension.GetValue()
+			}
+			if dimensions[nginxIdDimension] != nginxId {
+				continue
+			}
+
+			var timestamp *time.Time
+			if entity.GetTimestamp() != nil {
+				t := time.Unix(entity.GetTimestamp().GetSeconds(), int64(entity.GetTimestamp().GetNanos())).UTC()
+				timestamp = &t
+			}
+			for _, metric := range entity.GetSimplemetrics() {
+				if math.IsNaN(metric.GetValue()) || math.IsInf(metric.GetValue(), 0) {
+					continue
+				}
+				metrics = append(metrics, &InstanceMetric{
+					Name:       metric.GetName(),
+					Value:      metric.GetValue(),
+					Dimensions: dimensions,
+					Timestamp:  timestamp,
+				})
+			}
+		}
+	}
+	return metrics
+}
+
+// matchesFilter reports whether an NGINX instance matches the filter. NGINX instances have no tags of
+// their own, the tags configured for the agent apply to every instance of the host.
+func (h *APIV2Handler) matchesFilter(nginxDetails *proto.NginxDetails, filter *instanceFilter) bool {
+	if filter.tag != "" {
+		tagged := false
+		for _, tag := range h.config.Tags {
+			if tag == filter.tag {
+				tagged = true
+				break
+			}
+		}
+		if !tagged {
+			return false
+		}
+	}
+	if filter.version != "" && nginxDetails.GetVersion() != filter.version {
+		return false
+	}
+	if filter.plus != nil && nginxDetails.GetPlus().GetEnabled() != *filter.plus {
+		return false
+	}
+	return true
+}
+
+func parseInstanceFilter(r *http.Request) (*instanceFilter, error) {
+	query := r.URL.Query()
+	filter := &instanceFilter{
+		tag:     query.Get("tag"),
+		version: query.Get("version"),
+	}
+	if value := query.Get("plus"); value != "" {
+		plus, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid plus parameter %q, expected true or false", value)
+		}
+		filter.plus = &plus
+	}
+	return filter, nil
+}
+
+func parsePagination(r *http.Request) (int, int, error) {
+	query := r.URL.Query()
+	limit := defaultPageLimit
+	offset := 0
+
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageLimit {
+			return 0, 0, fmt.Errorf("invalid limit parameter %q, expected a number from 1 to %d", value, maxPageLimit)
+		}
+		limit = parsed
+	}
+	if value := query.Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("invalid offset parameter %q, expected a positive number", value)
+		}
+		offset = parsed
+	}
+
+	return limit, offset, nil
+}
+
+// pageBounds returns the slice bounds of a page of a list with total items
+func pageBounds(total, limit, offset int) (int, int) {
+	if offset > total {
+		return total, total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return offset, end
+}
+
+func writeAPIResponse(w http.ResponseWriter, response any) error {
+	w.Header().Set(contentTypeHeader, jsonMimeType)
+	w.WriteHeader(http.StatusOK)
+	return writeObjectToResponseBody(w, response)
+}
+
+func writeAPIError(w http.ResponseWriter, statusCode int, code, message string) error {
+	w.Header().Set(contentTypeHeader, jsonMimeType)
+	w.WriteHeader(statusCode)
+	return writeObjectToResponseBody(w, APIErrorResponse{Error: APIError{Code: code, Message: message}})
+}
@@ -0,0 +1,279 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package plugins
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+	prometheus_metrics "github.com/nginx/agent/v2/src/extensions/prometheus-metrics"
+	tutils "github.com/nginx/agent/v2/test/utils"
+)
+
+func TestAPIV2Handler(t *testing.T) {
+	ossProcess := &core.Process{Pid: 1, Name: "oss", IsMaster: true}
+	plusProcess := &core.Process{Pid: 2, Name: "plus", IsMaster: true}
+	ossDetails := &proto.NginxDetails{NginxId: "oss", Version: "1.25.3", ConfPath: "/etc/nginx/nginx.conf", Plus: &proto.NginxPlusMetaData{}}
+	plusDetails := &proto.NginxDetails{NginxId: "plus", Version: "1.25.1", ConfPath: "/etc/nginx-plus/nginx.conf", Plus: &proto.NginxPlusMetaData{Enabled: true, Release: "R30"}}
+
+	mockNginxBinary := tutils.NewMockNginxBinary()
+	mockNginxBinary.On("GetNginxDetailsFromProcess", ossProcess).Return(ossDetails)
+	mockNginxBinary.On("GetNginxDetailsFromProcess", plusProcess).Return(plusDetails)
+	mockNginxBinary.On("ReadConfig", "/etc/nginx/nginx.conf", "oss", "12345678").Return(&proto.NginxConfig{
+		AccessLogs: &proto.AccessLogs{AccessLog: []*proto.AccessLog{
+			{Name: "/var/log/nginx/access.log", Format: "combined", Readable: true},
+			{Name: "/var/log/nginx/api.log", Format: "json", Readable: true},
+		}},
+		ErrorLogs: &proto.ErrorLogs{ErrorLog: []*proto.ErrorLog{{Name: "/var/log/nginx/error.log", LogLevel: "warn", Readable: true}}},
+		Ssl:       &proto.SslCertificates{SslCerts: []*proto.SslCertificate{{FileName: "/etc/nginx/example.crt"}}},
+	}, nil)
+
+	exporter := prometheus_metrics.NewExporter(&proto.MetricsReport{})
+	exporter.SetLatestMetricReport(&metrics.MetricsReportBundle{Data: []*proto.MetricsReport{{
+		Data: []*proto.StatsEntity{
+			{
+				Timestamp:  &types.Timestamp{Seconds: 1700000000},
+				Dimensions: []*proto.Dimension{{Name: "nginx_id", Value: "oss"}},
+				Simplemetrics: []*proto.SimpleMetric{
+					{Name: "nginx.http.request.count", Value: 12},
+					{Name: "nginx.http.conn.active", Value: 3},
+					// values that are not finite are left out
+					{Name: "nginx.http.request.time", Value: math.NaN()},
+					{Name: "nginx.http.request.time.max", Value: math.Inf(1)},
+				},
+			},
+			{
+				Dimensions:    []*proto.Dimension{{Name: "nginx_id", Value: "plus"}},
+				Simplemetrics: []*proto.SimpleMetric{{Name: "nginx.http.request.count", Value: 7}},
+			},
+		},
+	}}})
+
+	h := &APIV2Handler{
+		config:       &config.Config{Version: "v2.31.0", Tags: []string{"production"}},
+		env:          tutils.GetMockEnv(),
+		nginxBinary:  mockNginxBinary,
+		nginxHandler: &NginxHandler{nginxBinary: mockNginxBinary, processes: []*core.Process{ossProcess, plusProcess}},
+		exporter:     exporter,
+	}
+
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		expectedStatusCode int
+		expectedErrorCode  string
+		expectedItems      []string
+		expectedTotal      int
+	}{
+		{
+			name:               "all instances",
+			path:               "/api/v2/instances",
+			expectedStatusCode: http.StatusOK,
+			expectedItems:      []string{"oss", "plus"},
+			expectedTotal:      2,
+		},
+		{
+			name:               "instances filtered by plus and tag",
+			path:               "/api/v2/instances?plus=true&tag=production",
+			expectedStatusCode: http.StatusOK,
+			expectedItems:      []string{"plus"},
+			expectedTotal:      1,
+		},
+		{
+			name:               "instances filtered by version",
+			path:               "/api/v2/instances/?version=1.25.3",
+			expectedStatusCode: http.StatusOK,
+			expectedItems:      []string{"oss"},
+			expectedTotal:      1,
+		},
+		{
+			name:               "instances filtered by unknown tag",
+			path:               "/api/v2/instances?tag=staging",
+			expectedStatusCode: http.StatusOK,
+			expectedItems:      []string{},
+		},
+		{
+			name:               "second page of instances",
+			path:               "/api/v2/instances?limit=1&offset=1",
+			expectedStatusCode: http.StatusOK,
+			expectedItems:      []string{"plus"},
+			expectedTotal:      2,
+		},
+		{
+			name:               "offset after the last instance",
+			path:               "/api/v2/instances?offset=5",
+			expectedStatusCode: http.StatusOK,
+			expectedItems:      []string{},
+			expectedTotal:      2,
+		},
+		{
+			name:               "invalid limit",
+			path:               "/api/v2/instances?limit=0",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrorCode:  errorCodeInvalidParameter,
+		},
+		{
+			name:               "invalid plus filter",
+			path:               "/api/v2/instances?plus=maybe",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedErrorCode:  errorCodeInvalidParameter,
+		},
+		{
+			name:               "unknown instance",
+			path:               "/api/v2/instances/unknown",
+			expectedStatusCode: http.StatusNotFound,
+			expectedErrorCode:  errorCodeNotFound,
+		},
+		{
+			name:               "certificates",
+			path:               "/api/v2/instances/oss/certificates",
+			expectedStatusCode: http.StatusOK,
+			expectedItems:      []string{"/etc/nginx/example.crt"},
+			expectedTotal:      1,
+		},
+		{
+			name:               "access logs",
+			path:               "/api/v2/instances/oss/access-logs?limit=1",
+			expectedStatusCode: http.StatusOK,
+			expectedItems:      []string{"/var/log/nginx/access.log"},
+			expectedTotal:      2,
+		},
+		{
+			name:               "error logs",
+			path:               "/api/v2/instances/oss/error-logs",
+			expectedStatusCode: http.StatusOK,
+			expectedItems:      []string{"/var/log/nginx/error.log"},
+			expectedTotal:      1,
+		},
+		{
+			name:               "metrics",
+			path:               "/api/v2/instances/oss/metrics",
+			expectedStatusCode: http.StatusOK,
+			expectedItems:      []string{"nginx.http.request.count", "nginx.http.conn.active"},
+			expectedTotal:      2,
+		},
+		{
+			name:               "logs of unknown instance",
+			path:               "/api/v2/instances/unknown/access-logs",
+			expectedStatusCode: http.StatusNotFound,
+			expectedErrorCode:  errorCodeNotFound,
+		},
+		{
+			name:               "unknown path",
+			path:               "/api/v2/upstreams",
+			expectedStatusCode: http.StatusNotFound,
+			expectedErrorCode:  errorCodeNotFound,
+		},
+		{
+			name:               "method not allowed",
+			method:             http.MethodPost,
+			path:               "/api/v2/instances",
+			expectedStatusCode: http.StatusMethodNotAllowed,
+			expectedErrorCode:  errorCodeMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(method, tt.path, nil))
+
+			resp := w.Result()
+			defer resp.Body.Close()
+			assert.Equal(t, tt.expectedStatusCode, resp.StatusCode)
+			assert.Equal(t, jsonMimeType, resp.Header.Get(contentTypeHeader))
+
+			if tt.expectedErrorCode != "" {
+				var errorResponse APIErrorResponse
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&errorResponse))
+				assert.Equal(t, tt.expectedErrorCode, errorResponse.Error.Code)
+				assert.NotEmpty(t, errorResponse.Error.Message)
+				return
+			}
+
+			var list struct {
+				Items []map[string]interface{} `json:"items"`
+				Pagination
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+			items := []string{}
+			for _, item := range list.Items {
+				for _, key := range []string{"nginx_id", "fileName", "name"} {
+					if value, ok := item[key].(string); ok {
+						items = append(items, value)
+						break
+					}
+				}
+			}
+			assert.Equal(t, tt.expectedItems, items)
+			assert.Equal(t, tt.expectedTotal, list.Total)
+		})
+	}
+}
+
+func TestAPIV2Handler_getHost(t *testing.T) {
+	h := &APIV2Handler{config: &config.Config{}, env: tutils.GetMockEnv()}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/host", nil))
+
+	resp := w.Result()
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var hostInfo proto.HostInfo
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&hostInfo))
+	assert.Equal(t, "test-host", hostInfo.Hostname)
+}
+
+func TestAPIV2Handler_openAPIDocument(t *testing.T) {
+	h := &APIV2Handler{}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/openapi.json", nil))
+
+	resp := w.Result()
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var document struct {
+		OpenAPI string                 `json:"openapi"`
+		Paths   map[string]interface{} `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&document))
+	assert.Equal(t, "3.0.3", document.OpenAPI)
+
+	// every documented path is served by the handler
+	routes := []*regexp.Regexp{v2OpenAPIRegex, v2EventsRegex, v2HostRegex, v2InstancesRegex, v2InstanceRegex, v2InstanceResourceRegex}
+	for path := range document.Paths {
+		path = apiV2Prefix + regexp.MustCompile(`\{[^}]+\}`).ReplaceAllString(path, "1")
+		matched := false
+		for _, route := range routes {
+			matched = matched || route.MatchString(path)
+		}
+		assert.True(t, matched, "no route for %s", path)
+	}
+	assert.Len(t, document.Paths, 9)
+}
@@ -0,0 +1,384 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package plugins
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nginx/agent/sdk/v2"
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/acme"
+	"github.com/nginx/agent/v2/src/core/config"
+)
+
+const (
+	certificateRenewalPluginName    = "certificate-renewal"
+	certificateRenewalPluginVersion = "v0.0.1"
+
+	renewedCertificatePermissions = "0644"
+	renewedKeyPermissions         = "0600"
+
+	// certificateOrderTimeout is the time an ACME order may take, including the challenge validation
+	certificateOrderTimeout = 5 * time.Minute
+)
+
+// certificateIssuer obtains a new certificate and private key for a set of domains
+type certificateIssuer interface {
+	ObtainCertificate(ctx context.Context, domains []string) ([]byte, []byte, error)
+}
+
+// CertificateRenewalResult is the result of renewing a certificate referenced by an NGINX instance
+type CertificateRenewalResult struct {
+	NginxId  string
+	File     string
+	Domains  []string
+	NotAfter time.Time
+	Err      error
+}
+
+// CertificateRenewal renews the ssl_certificate files of the NGINX instances with an ACME server
+// before they expire. The new certificate and key are written with the config apply backup, and
+// the previous files are restored if NGINX rejects them.
+type CertificateRenewal struct {
+	pipeline       core.MessagePipeInterface
+	config         *config.Config
+	env            core.Environment
+	nginxBinary    core.NginxBinary
+	issuer         certificateIssuer
+	processes      []*core.Process
+	processesMutex sync.RWMutex
+	renewMutex     sync.Mutex
+	currentTime    func() time.Time
+}
+
+func NewCertificateRenewal(conf *config.Config, env core.Environment, binary core.NginxBinary, processes []*core.Process) (*CertificateRenewal, error) {
+	client, err := acme.NewClient(conf.ACME)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateRenewal{
+		config:      conf,
+		env:         env,
+		nginxBinary: binary,
+		issuer:      client,
+		processes:   processes,
+		currentTime: time.Now,
+	}, nil
+}
+
+func (c *CertificateRenewal) Init(pipeline core.MessagePipeInterface) {
+	log.Info("CertificateRenewal initializing")
+	c.pipeline = pipeline
+
+	if len(c.config.ACME.Certificates) == 0 {
+		log.Warnf("No certificates are renewed, %s is not set", config.ACMECertificates)
+	}
+
+	if err := writeChallengeSnippet(c.config.ACME); err != nil {
+		log.Errorf("Unable to write the ACME challenge snippet %s: %v", c.config.ACME.SnippetPath, err)
+	}
+
+	go c.renewalLoop(pipeline.Context())
+}
+
+func (c *CertificateRenewal) Close() {
+	log.Info("CertificateRenewal is wrapping up")
+}
+
+func (c *CertificateRenewal) Process(msg *core.Message) {
+	switch {
+	case msg.Exact(core.NginxDetailProcUpdate):
+		processes, ok := msg.Data().([]*core.Process)
+		if !ok {
+			log.Warnf("Invalid message received, %T, for topic, %s", msg.Data(), msg.Topic())
+			return
+		}
+		c.processesMutex.Lock()
+		c.processes = processes
+		c.processesMutex.Unlock()
+	}
+}
+
+func (c *CertificateRenewal) Info() *core.Info {
+	return core.NewInfo(certificateRenewalPluginName, certificateRenewalPluginVersion)
+}
+
+func (c *CertificateRenewal) Subscriptions() []string {
+	return []string{core.NginxDetailProcUpdate}
+}
+
+func (c *CertificateRenewal) renewalLoop(ctx context.Context) {
+	ticker := time.NewTicker(c.config.ACME.CheckInterval)
+	defer ticker.Stop()
+
+	c.renewCertificates(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.renewCertificates(ctx)
+		}
+	}
+}
+
+// expiringCertificate is a certificate that expires within the renewal window, with the NGINX
+// instances that reference it
+type expiringCertificate struct {
+	certFile     string
+	keyFile      string
+	certificate  *x509.Certificate
+	nginxDetails []*proto.NginxDetails
+}
+
+// renewCertificates renews the certificates of every NGINX instance that expire within the
+// renewal window
+func (c *CertificateRenewal) renewCertificates(ctx context.Context) {
+	c.renewMutex.Lock()
+	defer c.renewMutex.Unlock()
+
+	c.processesMutex.RLock()
+	processes := c.processes
+	c.processesMutex.RUnlock()
+
+	// a certificate shared by several NGINX instances is renewed once, and all of them are reloaded
+	expiring := make(map[string]*expiringCertificate)
+	expiringFiles := []string{}
+	for _, process := range processes {
+		if !process.IsMaster {
+			continue
+		}
+		nginxDetails := c.nginxBinary.GetNginxDetailsFromProcess(process)
+		if nginxDetails.GetConfPath() == "" {
+			continue
+		}
+
+		pairs, err := sdk.GetCertificateKeyPairs(nginxDetails.GetConfPath(), c.config.IgnoreDirectives)
+		if err != nil {
+			log.Warnf("Unable to read the certificates of nginx instance %s: %v", nginxDetails.GetNginxId(), err)
+			continue
+		}
+
+		certFiles := make([]string, 0, len(pairs))
+		for certFile := range pairs {
+			certFiles = append(certFiles, certFile)
+		}
+		sort.Strings(certFiles)
+
+		for _, certFile := range certFiles {
+			if expiringCert, ok := expiring[certFile]; ok {
+				expiringCert.nginxDetails = append(expiringCert.nginxDetails, nginxDetails)
+				continue
+			}
+			if !c.isRenewable(certFile) {
+				continue
+			}
+			cert, err := sdk.LoadCertificate(certFile)
+			if err != nil {
+				log.Warnf("Unable to read certificate %s: %v", certFile, err)
+				continue
+			}
+			if cert.NotAfter.Sub(c.currentTime()) > c.config.ACME.RenewBefore {
+				continue
+			}
+
+			expiring[certFile] = &expiringCertificate{
+				certFile:     certFile,
+				keyFile:      pairs[certFile],
+				certificate:  cert,
+				nginxDetails: []*proto.NginxDetails{nginxDetails},
+			}
+			expiringFiles = append(expiringFiles, certFile)
+		}
+	}
+
+	for _, certFile := range expiringFiles {
+		c.renewCertificate(ctx, expiring[certFile])
+	}
+}
+
+// isRenewable reports whether the certificate file is renewed, only the configured certificates are
+// so that certificates of other CAs are not moved to the ACME server
+func (c *CertificateRenewal) isRenewable(certFile string) bool {
+	for _, file := range c.config.ACME.Certificates {
+		if filepath.Clean(file) == certFile {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CertificateRenewal) renewCertificate(ctx context.Context, expiring *expiringCertificate) {
+	certFile := expiring.certFile
+	domains, err := certificateDomains(expiring.certificate)
+	if err != nil {
+		log.Warnf("Not renewing certificate %s: %v", certFile, err)
+		return
+	}
+
+	log.Infof("Renewing certificate %s for %v, it expires on %s", certFile, domains, expiring.certificate.NotAfter.Format(time.RFC3339))
+	notAfter, err := c.obtainAndInstallCertificate(ctx, expiring, domains)
+	if err != nil {
+		log.Errorf("Unable to renew certificate %s: %v", certFile, err)
+	} else {
+		log.Infof("Renewed certificate %s, it expires on %s", certFile, notAfter.Format(time.RFC3339))
+	}
+
+	for _, nginxDetails := range expiring.nginxDetails {
+		c.pipeline.Process(core.NewMessage(core.CertificateRenewal, &CertificateRenewalResult{
+			NginxId:  nginxDetails.GetNginxId(),
+			File:     certFile,
+			Domains:  domains,
+			NotAfter: notAfter,
+			Err:      err,
+		}))
+	}
+}
+
+func (c *CertificateRenewal) obtainAndInstallCertificate(ctx context.Context, expiring *expiringCertificate, domains []string) (time.Time, error) {
+	orderCtx, cancel := context.WithTimeout(ctx, certificateOrderTimeout)
+	defer cancel()
+
+	certPEM, keyPEM, err := c.issuer.ObtainCertificate(orderCtx, domains)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// make sure the issued certificate can be read before NGINX is pointed at it
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return time.Time{}, fmt.Errorf("issued certificate for %v is not in PEM format", domains)
+	}
+	renewedCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to parse the issued certificate for %v: %v", domains, err)
+	}
+
+	files := []*proto.File{
+		{Name: expiring.certFile, Contents: certPEM, Permissions: renewedCertificatePermissions},
+		{Name: expiring.keyFile, Contents: keyPEM, Permissions: renewedKeyPermissions},
+	}
+	if filepath.Clean(expiring.keyFile) == filepath.Clean(expiring.certFile) {
+		// the certificate and key are kept in the same file
+		files = []*proto.File{
+			{Name: expiring.certFile, Contents: append(append([]byte{}, certPEM...), keyPEM...), Permissions: renewedKeyPermissions},
+		}
+	}
+
+	err = c.installCertificate(expiring.nginxDetails, files)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return renewedCert.NotAfter, nil
+}
+
+// installCertificate writes the certificate and key files, validates the config of every NGINX
+// instance that references them and reloads the instances. The previous files are restored if any
+// of the steps fail.
+func (c *CertificateRenewal) installCertificate(nginxDetails []*proto.NginxDetails, files []*proto.File) error {
+	c.pipeline.Process(core.NewMessage(core.FileWatcherEnabled, false))
+	defer c.pipeline.Process(core.NewMessage(core.FileWatcherEnabled, true))
+
+	configApply, err := sdk.NewConfigApply("", c.config.AllowedDirectoriesMap)
+	if err != nil {
+		return fmt.Errorf("unable to back up the certificate files: %v", err)
+	}
+
+	if err := c.env.WriteFiles(configApply, files, filepath.Dir(nginxDetails[0].GetConfPath()), c.config.AllowedDirectoriesMap); err != nil {
+		return c.rollbackCertificate(nil, configApply, fmt.Errorf("unable to write the certificate files: %v", err))
+	}
+
+	for _, details := range nginxDetails {
+		if err := c.nginxBinary.ValidateConfig(details.GetProcessId(), details.GetProcessPath(), details.GetConfPath(), nil, configApply); err != nil {
+			return c.rollbackCertificate(nil, configApply, err)
+		}
+	}
+
+	for i, details := range nginxDetails {
+		if err := c.nginxBinary.Reload(details.GetProcessId(), details.GetProcessPath()); err != nil {
+			return c.rollbackCertificate(nginxDetails[:i+1], configApply, fmt.Errorf("unable to reload NGINX instance %s: %v", details.GetNginxId(), err))
+		}
+	}
+
+	if err := configApply.Complete(); err != nil {
+		log.Warnf("Unable to complete the certificate renewal: %v", err)
+	}
+
+	return nil
+}
+
+// rollbackCertificate restores the previous certificate files and reloads the NGINX instances
+// that were already reloaded with the renewed files
+func (c *CertificateRenewal) rollbackCertificate(reloaded []*proto.NginxDetails, configApply *sdk.ConfigApply, cause error) error {
+	if err := configApply.Rollback(cause); err != nil {
+		log.Errorf("Unable to restore the previous certificate files: %v", err)
+		return cause
+	}
+	for _, details := range reloaded {
+		if err := c.nginxBinary.Reload(details.GetProcessId(), details.GetProcessPath()); err != nil {
+			log.Errorf("Unable to reload NGINX instance %s after restoring the previous certificate files: %v", details.GetNginxId(), err)
+		}
+	}
+	return cause
+}
+
+// certificateDomains returns the DNS names of a certificate, or its common name if it has none.
+// An error is returned if the certificate has names that can't be validated with an HTTP-01
+// challenge, like wildcard names and IP addresses, since the renewed certificate would not
+// cover them.
+func certificateDomains(cert *x509.Certificate) ([]string, error) {
+	names := cert.DNSNames
+	if len(names) == 0 && cert.Subject.CommonName != "" {
+		names = []string{cert.Subject.CommonName}
+	}
+
+	unsupported := []string{}
+	for _, name := range names {
+		if strings.HasPrefix(name, "*.") {
+			unsupported = append(unsupported, name)
+		}
+	}
+	for _, ip := range cert.IPAddresses {
+		unsupported = append(unsupported, ip.String())
+	}
+	if len(unsupported) > 0 {
+		return nil, fmt.Errorf("the names %v can't be validated with an HTTP-01 challenge", unsupported)
+	}
+	if len(names) == 0 {
+		return nil, errors.New("it has no names to validate with an HTTP-01 challenge")
+	}
+
+	return names, nil
+}
+
+// writeChallengeSnippet writes the location snippet that serves the HTTP-01 challenge responses
+// and creates the challenge directory, the snippet has to be included in the server blocks that
+// listen on port 80
+func writeChallengeSnippet(conf config.ACME) error {
+	if err := os.MkdirAll(conf.ChallengeDir, 0o755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(conf.SnippetPath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(conf.SnippetPath, acme.ChallengeSnippet(conf.ChallengeDir), 0o644)
+}
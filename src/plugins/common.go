@@ -36,7 +36,7 @@ func LoadPlugins(commander client.Commander, binary core.NginxBinary, env core.E
 
 	if (loadedConfig.IsFeatureEnabled(agent_config.FeatureMetrics) || loadedConfig.IsFeatureEnabled(agent_config.FeatureMetricsSender)) && reporter != nil {
 		corePlugins = append(corePlugins,
-			NewMetricsSender(reporter),
+			NewMetricsSender(reporter, loadedConfig),
 		)
 	}
 
@@ -78,6 +78,24 @@ func LoadPlugins(commander client.Commander, binary core.NginxBinary, env core.E
 		log.Info("Agent API not configured")
 	}
 
+	if loadedConfig.OTLP.Endpoint != "" {
+		otlpExporter, err := NewOTLPExporter(loadedConfig, env)
+		if err != nil {
+			log.Errorf("Unable to load the OTLP exporter plugin due to the following error: %v", err)
+		} else {
+			corePlugins = append(corePlugins, otlpExporter)
+		}
+	}
+
+	if loadedConfig.ACME.Enable {
+		certificateRenewal, err := NewCertificateRenewal(loadedConfig, env, binary, processes)
+		if err != nil {
+			log.Errorf("Unable to load the certificate renewal plugin due to the following error: %v", err)
+		} else {
+			corePlugins = append(corePlugins, certificateRenewal)
+		}
+	}
+
 	if len(loadedConfig.Nginx.NginxCountingSocket) > 0 && loadedConfig.IsFeatureEnabled(agent_config.FeatureNginxCounting) {
 		corePlugins = append(corePlugins, NewNginxCounter(loadedConfig, binary, env))
 	}
@@ -86,8 +104,12 @@ func LoadPlugins(commander client.Commander, binary core.NginxBinary, env core.E
 		for _, extension := range loadedConfig.Extensions {
 			switch {
 			case extension == agent_config.AdvancedMetricsExtensionPlugin:
-				advancedMetricsExtensionPlugin := extensions.NewAdvancedMetrics(env, loadedConfig, config.Viper.Get(agent_config.AdvancedMetricsExtensionPluginConfigKey))
-				extensionPlugins = append(extensionPlugins, advancedMetricsExtensionPlugin)
+				advancedMetricsExtensionPlugin, err := extensions.NewAdvancedMetrics(env, loadedConfig, config.Viper.Get(agent_config.AdvancedMetricsExtensionPluginConfigKey))
+				if err != nil {
+					log.Errorf("Unable to load the Advanced Metrics plugin due to the following error: %v", err)
+				} else {
+					extensionPlugins = append(extensionPlugins, advancedMetricsExtensionPlugin)
+				}
 			case extension == agent_config.NginxAppProtectExtensionPlugin:
 				nginxAppProtectExtensionPlugin, err := extensions.NewNginxAppProtect(loadedConfig, env, config.Viper.Get(agent_config.NginxAppProtectExtensionPluginConfigKey))
 				if err != nil {
@@ -103,7 +125,7 @@ func LoadPlugins(commander client.Commander, binary core.NginxBinary, env core.E
 					extensionPlugins = append(extensionPlugins, nginxAppProtectMonitoringExtensionPlugin)
 				}
 			case extension == agent_config.PhpFpmMetricsExtensionPlugin:
-				phpFpmMetricstExtensionPlugin, err := extensions.NewPhpFpmMetrics(env, loadedConfig)
+				phpFpmMetricstExtensionPlugin, err := extensions.NewPhpFpmMetrics(env, loadedConfig, config.Viper.Get(agent_config.PhpFpmMetricsExtensionPluginConfigKey))
 				if err != nil {
 					log.Errorf("Unable to load the PhpFpm Metrics plugin due to the following error: %v", err)
 				} else {
@@ -37,6 +37,17 @@ func TestLoadPlugins(t *testing.T) {
 			expectedPluginSize:    5,
 			expectedExtensionSize: 0,
 		},
+		{
+			name: "otlp exporter",
+			loadedConfig: &config.Config{
+				OTLP: config.OTLP{
+					Endpoint: "127.0.0.1:4317",
+					Protocol: "grpc",
+				},
+			},
+			expectedPluginSize:    6,
+			expectedExtensionSize: 0,
+		},
 		{
 			name: "all plugins and extensions",
 			loadedConfig: &config.Config{
@@ -8,11 +8,15 @@
 package plugins
 
 import (
+	"context"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
@@ -20,6 +24,13 @@ import (
 	"github.com/nginx/agent/sdk/v2/proto"
 	"github.com/nginx/agent/v2/src/core"
 	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/logger"
+)
+
+const (
+	// agentConfigReloadDelay is how long the agent config files must stay unchanged before they
+	// are reloaded, so a file that is written in several steps is only reloaded once
+	agentConfigReloadDelay = time.Second
 )
 
 // ConfigReader reads in configuration from the messagePipe
@@ -37,6 +48,9 @@ func NewConfigReader(config *config.Config) *ConfigReader {
 
 func (r *ConfigReader) Init(pipeline core.MessagePipeInterface) {
 	r.messagePipeline = pipeline
+	if r.config != nil && r.config.Path != "" {
+		go r.watchAgentConfigFiles(pipeline.Context())
+	}
 }
 
 func (r *ConfigReader) Info() *core.Info {
@@ -65,6 +79,11 @@ func (r *ConfigReader) Process(msg *core.Message) {
 		return
 	}
 
+	if msg.Exact(core.AgentConfigFilesChanged) {
+		r.reloadAgentConfig()
+		return
+	}
+
 	switch cmd := msg.Data().(type) {
 	case *proto.Command:
 		switch msg.Topic() {
@@ -81,7 +100,7 @@ func (r *ConfigReader) Process(msg *core.Message) {
 }
 
 func (r *ConfigReader) Subscriptions() []string {
-	return []string{core.CommMetrics, core.AgentConfig, core.AgentConfigChanged, core.AgentConnected}
+	return []string{core.CommMetrics, core.AgentConfig, core.AgentConfigChanged, core.AgentConnected, core.AgentConfigFilesChanged}
 }
 
 func (r *ConfigReader) updateAgentConfig(payloadAgentConfig *proto.AgentConfig) {
@@ -141,6 +160,15 @@ func (r *ConfigReader) updateAgentConfig(payloadAgentConfig *proto.AgentConfig)
 			r.synchronizeFeatures(payloadAgentConfig)
 		}
 
+		// keep track of the updated config, so the dynamic config file written above is not
+		// applied a second time when it is reloaded
+		updatedAgentConfig, err := r.loadAgentConfig()
+		if err != nil {
+			log.Warnf("Failed to load the updated agent config: %v", err)
+		} else {
+			r.config = updatedAgentConfig
+		}
+
 		r.messagePipeline.Process(core.NewMessage(core.AgentConfigChanged, payloadAgentConfig))
 
 	}
@@ -179,3 +207,160 @@ func (r *ConfigReader) deRegisterPlugin(data string) {
 		}
 	}
 }
+
+// watchAgentConfigFiles notifies the config reader through the message pipe when the agent config
+// file or the dynamic config file changes, until the context is done
+func (r *ConfigReader) watchAgentConfigFiles(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Warnf("Unable to watch the agent config files for changes: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	configFiles := make(map[string]struct{})
+	for _, path := range []string{r.config.Path, r.config.DynamicConfigPath} {
+		if path == "" {
+			continue
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			log.Warnf("Unable to watch %s for changes: %v", path, err)
+			continue
+		}
+		configFiles[absPath] = struct{}{}
+
+		// the directory is watched, so files replaced by editors or config management tools
+		// are still picked up
+		err = watcher.Add(filepath.Dir(absPath))
+		if err != nil {
+			log.Warnf("Unable to watch %s for changes: %v", absPath, err)
+		}
+	}
+
+	reload := time.NewTimer(agentConfigReloadDelay)
+	reload.Stop()
+	defer reload.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			path, err := filepath.Abs(event.Name)
+			if err != nil {
+				continue
+			}
+			if _, ok := configFiles[path]; !ok || event.Op == fsnotify.Chmod {
+				continue
+			}
+			log.Debugf("Agent config file %s changed: %v", event.Name, event.Op)
+			reload.Reset(agentConfigReloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warnf("Error watching the agent config files: %v", err)
+		case <-reload.C:
+			r.messagePipeline.Process(core.NewMessage(core.AgentConfigFilesChanged, nil))
+		}
+	}
+}
+
+// reloadAgentConfig reloads the agent config files and applies the settings that changed. Features
+// and extensions are enabled or disabled, and the other plugins are notified with an
+// AgentConfigChanged message so they can pick up their updated settings.
+func (r *ConfigReader) reloadAgentConfig() {
+	err := config.ReloadConfigFiles()
+	if err != nil {
+		log.Errorf("Failed to reload the agent config files: %v", err)
+		return
+	}
+
+	updated, err := r.loadAgentConfig()
+	if err != nil {
+		log.Errorf("Failed to load the reloaded agent config: %v", err)
+		return
+	}
+
+	changes := config.DiffConfig(r.config, updated)
+	if len(changes) == 0 {
+		log.Debug("Agent config files reloaded without changes")
+		return
+	}
+	log.Infof("Agent config files reloaded, applying changes to %v", changes)
+
+	running := r.config
+	r.config = updated
+
+	if running.Log.Level != updated.Log.Level {
+		logger.SetLogLevel(updated.Log.Level)
+	}
+	r.messagePipeline.GetStats().SetSlowSubscriberDeadline(updated.MessagePipe.SlowSubscriberDeadline)
+
+	for _, extension := range missingFrom(updated.Extensions, running.Extensions) {
+		err := r.messagePipeline.DeRegister([]string{extension})
+		if err != nil {
+			log.Warnf("Error De-registering %v Extension: %v", extension, err)
+		}
+	}
+	for _, extension := range missingFrom(running.Extensions, updated.Extensions) {
+		r.messagePipeline.Process(core.NewMessage(core.EnableExtension, extension))
+	}
+
+	for _, feature := range missingFrom(updated.Features, running.Features) {
+		if feature != agent_config.FeatureRegistration && feature != agent_config.FeatureNginxConfigAsync {
+			r.mu.Lock()
+			r.deRegisterPlugin(feature)
+			r.mu.Unlock()
+		}
+	}
+	if enabledFeatures := missingFrom(running.Features, updated.Features); len(enabledFeatures) > 0 {
+		r.messagePipeline.Process(core.NewMessage(core.EnableFeature, enabledFeatures))
+	}
+
+	r.messagePipeline.Process(core.NewMessage(core.AgentConfigChanged, &proto.AgentConfig{
+		Details: &proto.AgentDetails{
+			Features:   updated.Features,
+			Extensions: updated.Extensions,
+			Tags:       updated.Tags,
+		},
+	}))
+}
+
+// loadAgentConfig gets the agent config from the currently loaded config files, flags and
+// environment variables
+func (r *ConfigReader) loadAgentConfig() (*config.Config, error) {
+	conf, err := config.GetConfig(r.config.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the display name defaults to the hostname, which is resolved once at startup
+	if conf.DisplayName == "" {
+		conf.DisplayName = r.config.DisplayName
+	}
+
+	return conf, nil
+}
+
+// missingFrom returns the values that are in values but not in existing
+func missingFrom(existing, values []string) []string {
+	missing := []string{}
+	for _, value := range values {
+		found := false
+		for _, existingValue := range existing {
+			if value == existingValue {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
@@ -9,11 +9,14 @@ package plugins
 
 import (
 	"context"
+	"os"
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/nginx/agent/sdk/v2/proto"
 	"github.com/nginx/agent/v2/src/core"
@@ -302,3 +305,88 @@ func TestUpdateAgentConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigReader_reloadAgentConfig(t *testing.T) {
+	agentConfFileName, _, cleanupFunc, err := tutils.CreateTestAgentConfigEnv()
+	require.NoError(t, err)
+	defer cleanupFunc()
+
+	conf, err := config.GetConfig("12345")
+	require.NoError(t, err)
+
+	configReader := NewConfigReader(conf)
+	messagePipe := core.SetupMockMessagePipe(t, context.Background(), []core.Plugin{configReader}, []core.ExtensionPlugin{})
+	configReader.messagePipeline = messagePipe
+
+	// reloading unchanged config files does not notify the other plugins
+	configReader.Process(core.NewMessage(core.AgentConfigFilesChanged, nil))
+	assert.Empty(t, messagePipe.GetMessages())
+
+	updatedConf := `server:
+    host: 127.0.0.1
+    grpcPort: 443
+log:
+    level: info
+features:
+    - registration
+    - nginx-config-async
+    - metrics
+    - metrics-throttle
+extensions:
+    - advanced-metrics
+message_pipe:
+    slow_subscriber_deadline: 1s
+`
+	require.NoError(t, os.WriteFile(agentConfFileName, []byte(updatedConf), 0o640))
+
+	configReader.Process(core.NewMessage(core.AgentConfigFilesChanged, nil))
+
+	messages := messagePipe.GetMessages()
+	require.Len(t, messages, 3)
+
+	assert.Equal(t, core.EnableExtension, messages[0].Topic())
+	assert.Equal(t, "advanced-metrics", messages[0].Data())
+	assert.Equal(t, core.EnableFeature, messages[1].Topic())
+	assert.Equal(t, []string{"metrics-throttle"}, messages[1].Data())
+	assert.Equal(t, core.AgentConfigChanged, messages[2].Topic())
+	assert.Equal(t, []string{"registration", "nginx-config-async", "metrics", "metrics-throttle"}, messages[2].Data().(*proto.AgentConfig).GetDetails().GetFeatures())
+
+	assert.Equal(t, "127.0.0.1:443", configReader.config.Server.Target)
+	assert.Equal(t, time.Second, messagePipe.GetStats().SlowSubscriberDeadline())
+
+	// an invalid config file keeps the running config
+	messagePipe.ClearMessages()
+	require.NoError(t, os.WriteFile(agentConfFileName, []byte("features: [\n"), 0o640))
+
+	configReader.Process(core.NewMessage(core.AgentConfigFilesChanged, nil))
+	assert.Empty(t, messagePipe.GetMessages())
+	assert.Equal(t, []string{"registration", "nginx-config-async", "metrics", "metrics-throttle"}, configReader.config.Features)
+}
+
+func TestConfigReader_watchAgentConfigFiles(t *testing.T) {
+	agentConfFileName, _, cleanupFunc, err := tutils.CreateTestAgentConfigEnv()
+	require.NoError(t, err)
+	defer cleanupFunc()
+
+	conf, err := config.GetConfig("12345")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messagePipe := core.NewMessagePipe(ctx, 10)
+	configReader := NewConfigReader(conf)
+	configReader.messagePipeline = messagePipe
+	go configReader.watchAgentConfigFiles(ctx)
+
+	// wait for the watcher to be set up before changing the file
+	time.Sleep(100 * time.Millisecond)
+
+	agentConf, err := os.ReadFile(agentConfFileName)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(agentConfFileName, append(agentConf, []byte("\nqueue_size: 50\n")...), 0o640))
+
+	assert.Eventually(t, func() bool {
+		return messagePipe.GetStats().Snapshot().QueueDepth == 1
+	}, 5*time.Second, 50*time.Millisecond)
+}
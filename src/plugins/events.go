@@ -10,6 +10,9 @@ package plugins
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gogo/protobuf/types"
 	"github.com/google/uuid"
@@ -21,19 +24,30 @@ import (
 	eventsProto "github.com/nginx/agent/sdk/v2/proto/events"
 	"github.com/nginx/agent/v2/src/core"
 	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
 )
 
 const (
-	NGINX_FOUND_MESSAGE             = "nginx-v%s master process was found with a pid %s"
-	NGINX_STOP_MESSAGE              = "nginx-v%s master process (pid: %s) stopped"
-	NGINX_RELOAD_SUCCESS_MESSAGE    = "nginx-v%s master process (pid: %s) reloaded successfully"
-	NGINX_RELOAD_FAILED_MESSAGE     = "nginx-v%s master process (pid: %s) failed to reload"
-	NGINX_WORKER_START_MESSAGE      = "new worker process started with pid %s for nginx-v%s process (pid: %s)"
-	NGINX_WORKER_STOP_MESSAGE       = "worker process with pid %s is shutting down for nginx-v%s process (pid: %s)"
-	CONFIG_APPLY_SUCCESS_MESSAGE    = "successfully applied config on %s"
-	CONFIG_APPLY_FAILURE_MESSAGE    = "failed to apply nginx config on %s"
-	CONFIG_ROLLBACK_SUCCESS_MESSAGE = "nginx config was rolled back on %s"
-	CONFIG_ROLLBACK_FAILURE_MESSAGE = "failed to rollback nginx config on %s"
+	NGINX_FOUND_MESSAGE                = "nginx-v%s master process was found with a pid %s"
+	NGINX_STOP_MESSAGE                 = "nginx-v%s master process (pid: %s) stopped"
+	NGINX_RELOAD_SUCCESS_MESSAGE       = "nginx-v%s master process (pid: %s) reloaded successfully"
+	NGINX_RELOAD_FAILED_MESSAGE        = "nginx-v%s master process (pid: %s) failed to reload"
+	NGINX_WORKER_START_MESSAGE         = "new worker process started with pid %s for nginx-v%s process (pid: %s)"
+	NGINX_WORKER_STOP_MESSAGE          = "worker process with pid %s is shutting down for nginx-v%s process (pid: %s)"
+	CONFIG_APPLY_SUCCESS_MESSAGE       = "successfully applied config on %s"
+	CONFIG_APPLY_FAILURE_MESSAGE       = "failed to apply nginx config on %s"
+	CONFIG_ROLLBACK_SUCCESS_MESSAGE    = "nginx config was rolled back on %s"
+	CONFIG_ROLLBACK_FAILURE_MESSAGE    = "failed to rollback nginx config on %s"
+	NGINX_ERROR_LOG_MESSAGE            = "[%s] %s (error log: %s)"
+	NGINX_LIFECYCLE_SUCCESS_MESSAGE    = "nginx-v%s %s succeeded: %s"
+	NGINX_LIFECYCLE_FAILED_MESSAGE     = "nginx-v%s %s failed: %s"
+	CERTIFICATE_EXPIRY_MESSAGE         = "certificate %s (%s, serial %s) in %s referenced by %s expires in %d days on %s"
+	CERTIFICATE_EXPIRED_MESSAGE        = "certificate %s (serial %s) in %s referenced by %s expired on %s"
+	CERTIFICATE_RENEWED_MESSAGE        = "certificate %s for %s renewed, it expires on %s"
+	CERTIFICATE_RENEWAL_FAILED_MESSAGE = "renewal of certificate %s for %s failed: %v"
+
+	errorLogEventRateWindow = time.Minute
+	maxErrorLogDedupEntries = 1000
 )
 
 type Events struct {
@@ -44,6 +58,7 @@ type Events struct {
 	meta            *proto.Metadata
 	nginxBinary     core.NginxBinary
 	agentEventsMeta *events.AgentEventMeta
+	errorLogLimiter *errorLogEventLimiter
 }
 
 func NewEvents(conf *config.Config, env core.Environment, meta *proto.Metadata, nginxBinary core.NginxBinary, agentEventsMeta *events.AgentEventMeta) *Events {
@@ -53,6 +68,7 @@ func NewEvents(conf *config.Config, env core.Environment, meta *proto.Metadata,
 		meta:            meta,
 		nginxBinary:     nginxBinary,
 		agentEventsMeta: agentEventsMeta,
+		errorLogLimiter: newErrorLogEventLimiter(conf.ErrorLogEvents.DedupWindow, conf.ErrorLogEvents.RateLimit),
 	}
 }
 
@@ -88,6 +104,14 @@ func (a *Events) Process(msg *core.Message) {
 		a.sendNginxWorkerStartEvent(msg)
 	case msg.Exact(core.NginxWorkerProcKilled):
 		a.sendNginxWorkerStopEvent(msg)
+	case msg.Exact(core.NginxErrorLogEvent):
+		a.sendNginxErrorLogEvent(msg)
+	case msg.Exact(core.NginxLifecycleComplete):
+		a.sendNginxLifecycleEvent(msg)
+	case msg.Exact(core.CertificateExpiry):
+		a.sendCertificateExpiryEvent(msg)
+	case msg.Exact(core.CertificateRenewal):
+		a.sendCertificateRenewalEvent(msg)
 	}
 }
 
@@ -106,6 +130,10 @@ func (a *Events) Subscriptions() []string {
 		core.NginxMasterProcKilled,
 		core.NginxWorkerProcCreated,
 		core.NginxWorkerProcKilled,
+		core.NginxErrorLogEvent,
+		core.NginxLifecycleComplete,
+		core.CertificateExpiry,
+		core.CertificateRenewal,
 	}
 }
 
@@ -319,6 +347,8 @@ func (a *Events) sendNginxStopEvent(msg *core.Message) {
 		return
 	}
 
+	a.errorLogLimiter.forget(nginxDetails.GetNginxId())
+
 	event := a.createNginxEvent(
 		nginxDetails.GetNginxId(),
 		types.TimestampNow(),
@@ -393,6 +423,245 @@ func (a *Events) sendNginxWorkerStopEvent(msg *core.Message) {
 	}))
 }
 
+func (a *Events) sendNginxErrorLogEvent(msg *core.Message) {
+	errorLogEvent, ok := msg.Data().(*metrics.NginxErrorLogEvent)
+	if !ok || errorLogEvent.Item == nil {
+		log.Warnf("Invalid message received, %T, for topic, %s", msg.Data(), msg.Topic())
+		return
+	}
+
+	if !a.errorLogLimiter.allow(errorLogEvent, time.Now()) {
+		log.Tracef("Suppressing error log event of NGINX instance %s: %s", errorLogEvent.NginxId, errorLogEvent.Item.Message)
+		return
+	}
+
+	timestamp, err := types.TimestampProto(errorLogEvent.Item.Time)
+	if err != nil {
+		timestamp = types.TimestampNow()
+	}
+
+	event := a.createNginxEvent(
+		errorLogEvent.NginxId,
+		timestamp,
+		errorLogEventLevel(errorLogEvent.Item.Level),
+		fmt.Sprintf(NGINX_ERROR_LOG_MESSAGE, errorLogEvent.Item.Level, errorLogEvent.Item.Message, errorLogEvent.LogFile),
+		uuid.NewString(),
+	)
+
+	log.Debugf("Created event: %v", event)
+	a.pipeline.Process(core.NewMessage(core.Events, &proto.Command{
+		Meta: a.meta,
+		Type: proto.Command_NORMAL,
+		Data: &proto.Command_EventReport{
+			EventReport: &eventsProto.EventReport{
+				Events: []*eventsProto.Event{event},
+			},
+		},
+	}))
+}
+
+func (a *Events) sendNginxLifecycleEvent(msg *core.Message) {
+	status, ok := msg.Data().(*NginxLifecycleStatus)
+	if !ok {
+		log.Warnf("Invalid message received, %T, for topic, %s", msg.Data(), msg.Topic())
+		return
+	}
+
+	timestamp := types.TimestampNow()
+	if status.CompletedAt != nil {
+		if completedAt, err := types.TimestampProto(*status.CompletedAt); err == nil {
+			timestamp = completedAt
+		}
+	}
+
+	level := events.INFO_EVENT_LEVEL
+	message := fmt.Sprintf(NGINX_LIFECYCLE_SUCCESS_MESSAGE, status.nginxDetails.GetVersion(), status.Action, status.Message)
+	if status.Status != okStatus {
+		level = events.ERROR_EVENT_LEVEL
+		message = fmt.Sprintf(NGINX_LIFECYCLE_FAILED_MESSAGE, status.nginxDetails.GetVersion(), status.Action, status.Message)
+	}
+
+	event := a.createNginxEvent(status.NginxId, timestamp, level, message, status.CorrelationId)
+
+	log.Debugf("Created event: %v", event)
+	a.pipeline.Process(core.NewMessage(core.Events, &proto.Command{
+		Meta: a.meta,
+		Type: proto.Command_NORMAL,
+		Data: &proto.Command_EventReport{
+			EventReport: &eventsProto.EventReport{
+				Events: []*eventsProto.Event{event},
+			},
+		},
+	}))
+}
+
+func (a *Events) sendCertificateExpiryEvent(msg *core.Message) {
+	expiryEvent, ok := msg.Data().(*metrics.CertificateExpiryEvent)
+	if !ok {
+		log.Warnf("Invalid message received, %T, for topic, %s", msg.Data(), msg.Topic())
+		return
+	}
+
+	notAfter := expiryEvent.NotAfter.UTC().Format(time.RFC3339)
+	level := events.CRITICAL_EVENT_LEVEL
+	message := fmt.Sprintf(CERTIFICATE_EXPIRED_MESSAGE, expiryEvent.Subject, expiryEvent.SerialNumber, expiryEvent.File, expiryEvent.Source, notAfter)
+	if expiryEvent.Level != metrics.CertificateExpired {
+		if expiryEvent.Level == metrics.CertificateExpiryWarning {
+			level = events.WARN_EVENT_LEVEL
+		}
+		message = fmt.Sprintf(CERTIFICATE_EXPIRY_MESSAGE, expiryEvent.Subject, expiryEvent.Level, expiryEvent.SerialNumber, expiryEvent.File, expiryEvent.Source, expiryEvent.DaysUntilExpiry, notAfter)
+	}
+
+	var event *eventsProto.Event
+	if expiryEvent.NginxId != "" {
+		event = a.createNginxEvent(expiryEvent.NginxId, types.TimestampNow(), level, message, uuid.NewString())
+	} else {
+		event = a.agentEventsMeta.CreateAgentEvent(types.TimestampNow(), level, message, uuid.NewString(), config.MODULE)
+	}
+
+	log.Debugf("Created event: %v", event)
+	a.pipeline.Process(core.NewMessage(core.Events, &proto.Command{
+		Meta: a.meta,
+		Type: proto.Command_NORMAL,
+		Data: &proto.Command_EventReport{
+			EventReport: &eventsProto.EventReport{
+				Events: []*eventsProto.Event{event},
+			},
+		},
+	}))
+}
+
+func (a *Events) sendCertificateRenewalEvent(msg *core.Message) {
+	result, ok := msg.Data().(*CertificateRenewalResult)
+	if !ok {
+		log.Warnf("Invalid message received, %T, for topic, %s", msg.Data(), msg.Topic())
+		return
+	}
+
+	domains := strings.Join(result.Domains, ", ")
+	level := events.INFO_EVENT_LEVEL
+	message := fmt.Sprintf(CERTIFICATE_RENEWED_MESSAGE, result.File, domains, result.NotAfter.UTC().Format(time.RFC3339))
+	if result.Err != nil {
+		level = events.ERROR_EVENT_LEVEL
+		message = fmt.Sprintf(CERTIFICATE_RENEWAL_FAILED_MESSAGE, result.File, domains, result.Err)
+	}
+
+	event := a.createNginxEvent(result.NginxId, types.TimestampNow(), level, message, uuid.NewString())
+
+	log.Debugf("Created event: %v", event)
+	a.pipeline.Process(core.NewMessage(core.Events, &proto.Command{
+		Meta: a.meta,
+		Type: proto.Command_NORMAL,
+		Data: &proto.Command_EventReport{
+			EventReport: &eventsProto.EventReport{
+				Events: []*eventsProto.Event{event},
+			},
+		},
+	}))
+}
+
+// errorLogEventLevel maps the level of an NGINX error log line to an event level
+func errorLogEventLevel(level string) string {
+	switch level {
+	case "debug":
+		return events.DEBUG_EVENT_LEVEL
+	case "warn":
+		return events.WARN_EVENT_LEVEL
+	case "error":
+		return events.ERROR_EVENT_LEVEL
+	case "crit", "alert", "emerg":
+		return events.CRITICAL_EVENT_LEVEL
+	default:
+		return events.INFO_EVENT_LEVEL
+	}
+}
+
+// errorLogEventLimiter suppresses error log events that repeat the level and message of an
+// event within the dedup window and limits the number of events per minute of each NGINX
+// instance. A zero dedup window or rate limit disables the respective check.
+type errorLogEventLimiter struct {
+	dedupWindow time.Duration
+	rateLimit   int
+	lastSeen    map[errorLogEventKey]time.Time
+	rateWindows map[string]*errorLogRateWindow
+	mu          sync.Mutex
+}
+
+type errorLogEventKey struct {
+	nginxId string
+	level   string
+	message string
+}
+
+type errorLogRateWindow struct {
+	start time.Time
+	count int
+}
+
+func newErrorLogEventLimiter(dedupWindow time.Duration, rateLimit int) *errorLogEventLimiter {
+	return &errorLogEventLimiter{
+		dedupWindow: dedupWindow,
+		rateLimit:   rateLimit,
+		lastSeen:    make(map[errorLogEventKey]time.Time),
+		rateWindows: make(map[string]*errorLogRateWindow),
+	}
+}
+
+func (l *errorLogEventLimiter) allow(event *metrics.NginxErrorLogEvent, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := errorLogEventKey{nginxId: event.NginxId, level: event.Item.Level, message: event.Item.Message}
+	if l.dedupWindow > 0 {
+		if seen, ok := l.lastSeen[key]; ok && now.Sub(seen) < l.dedupWindow {
+			return false
+		}
+	}
+
+	if l.rateLimit > 0 {
+		window, ok := l.rateWindows[event.NginxId]
+		if !ok || now.Sub(window.start) >= errorLogEventRateWindow {
+			window = &errorLogRateWindow{start: now}
+			l.rateWindows[event.NginxId] = window
+		}
+		if window.count >= l.rateLimit {
+			return false
+		}
+		window.count++
+	}
+
+	if l.dedupWindow > 0 {
+		if len(l.lastSeen) >= maxErrorLogDedupEntries {
+			l.expire(now)
+		}
+		l.lastSeen[key] = now
+	}
+
+	return true
+}
+
+// forget removes the state of an NGINX instance that has stopped
+func (l *errorLogEventLimiter) forget(nginxId string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.rateWindows, nginxId)
+	for key := range l.lastSeen {
+		if key.nginxId == nginxId {
+			delete(l.lastSeen, key)
+		}
+	}
+}
+
+// expire removes the messages whose dedup window has passed
+func (l *errorLogEventLimiter) expire(now time.Time) {
+	for key, seen := range l.lastSeen {
+		if now.Sub(seen) >= l.dedupWindow {
+			delete(l.lastSeen, key)
+		}
+	}
+}
+
 func (e *Events) createNginxEvent(nginxId string, timestamp *types.Timestamp, level string, message string, correlationId string) *eventsProto.Event {
 	activityEvent := e.agentEventsMeta.CreateActivityEvent(message, nginxId)
 
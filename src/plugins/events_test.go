@@ -9,6 +9,7 @@ package plugins
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -21,6 +22,8 @@ import (
 	eventsProto "github.com/nginx/agent/sdk/v2/proto/events"
 	"github.com/nginx/agent/v2/src/core"
 	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+	"github.com/nginx/agent/v2/src/core/tailer"
 	tutils "github.com/nginx/agent/v2/test/utils"
 	"github.com/stretchr/testify/assert"
 )
@@ -52,6 +55,7 @@ func TestActivityEvents_Process(t *testing.T) {
 		},
 	}
 	expectedNginxDimensions := append(nginxDim, expectedCommonDimensions...)
+	lifecycleCompletedAt := time.Unix(1564894, 0)
 
 	tests := []struct {
 		name                string
@@ -498,6 +502,223 @@ func TestActivityEvents_Process(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "test NginxErrorLogEvent message",
+			message: core.NewMessage(core.NginxErrorLogEvent, &metrics.NginxErrorLogEvent{
+				NginxId: "12345",
+				LogFile: "/var/log/nginx/error.log",
+				Item: &tailer.NginxErrorItem{
+					Time:    time.Unix(1564894, 0),
+					Level:   "crit",
+					Message: "SSL_do_handshake() failed",
+				},
+			}),
+			msgTopics: []string{
+				core.AgentStarted,
+				core.NginxErrorLogEvent,
+				core.Events,
+				core.Events,
+			},
+			expectedEventReport: &eventsProto.EventReport{
+				Events: []*eventsProto.Event{
+					{
+						Metadata: &eventsProto.Metadata{
+							Module:     "NGINX-AGENT",
+							Type:       "Nginx",
+							Category:   "Status",
+							EventLevel: "CRITICAL",
+							Timestamp:  &types.Timestamp{Seconds: 1564894},
+						},
+						Data: &eventsProto.Event_ActivityEvent{
+							ActivityEvent: &eventsProto.ActivityEvent{
+								Message:    "[crit] SSL_do_handshake() failed (error log: /var/log/nginx/error.log)",
+								Dimensions: expectedNginxDimensions,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "test NGINX lifecycle succeeded message",
+			message: core.NewMessage(core.NginxLifecycleComplete, &NginxLifecycleStatus{
+				CorrelationId: "123",
+				NginxId:       "12345",
+				Action:        NginxLifecycleReopenLogs,
+				Status:        okStatus,
+				Message:       "nginx master process (pid: 75231) reopened its log files",
+				CompletedAt:   &lifecycleCompletedAt,
+				nginxDetails:  &proto.NginxDetails{NginxId: "12345", Version: "1.23.3", ProcessId: "75231"},
+			}),
+			msgTopics: []string{
+				core.AgentStarted,
+				core.NginxLifecycleComplete,
+				core.Events,
+				core.Events,
+			},
+			expectedEventReport: &eventsProto.EventReport{
+				Events: []*eventsProto.Event{
+					{
+						Metadata: &eventsProto.Metadata{
+							Module:     "NGINX-AGENT",
+							Type:       "Nginx",
+							Category:   "Status",
+							EventLevel: "INFO",
+							Timestamp:  &types.Timestamp{Seconds: 1564894},
+						},
+						Data: &eventsProto.Event_ActivityEvent{
+							ActivityEvent: &eventsProto.ActivityEvent{
+								Message:    "nginx-v1.23.3 reopen-logs succeeded: nginx master process (pid: 75231) reopened its log files",
+								Dimensions: expectedNginxDimensions,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "test NGINX lifecycle failed message",
+			message: core.NewMessage(core.NginxLifecycleComplete, &NginxLifecycleStatus{
+				CorrelationId: "123",
+				NginxId:       "12345",
+				Action:        NginxLifecycleStop,
+				Status:        errorStatus,
+				Message:       "operation not permitted",
+				CompletedAt:   &lifecycleCompletedAt,
+				nginxDetails:  &proto.NginxDetails{NginxId: "12345", Version: "1.23.3", ProcessId: "75231"},
+			}),
+			msgTopics: []string{
+				core.AgentStarted,
+				core.NginxLifecycleComplete,
+				core.Events,
+				core.Events,
+			},
+			expectedEventReport: &eventsProto.EventReport{
+				Events: []*eventsProto.Event{
+					{
+						Metadata: &eventsProto.Metadata{
+							Module:     "NGINX-AGENT",
+							Type:       "Nginx",
+							Category:   "Status",
+							EventLevel: "ERROR",
+							Timestamp:  &types.Timestamp{Seconds: 1564894},
+						},
+						Data: &eventsProto.Event_ActivityEvent{
+							ActivityEvent: &eventsProto.ActivityEvent{
+								Message:    "nginx-v1.23.3 stop failed: operation not permitted",
+								Dimensions: expectedNginxDimensions,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "test NGINX certificate expiry message",
+			message: core.NewMessage(core.CertificateExpiry, &metrics.CertificateExpiryEvent{
+				NginxId:         "12345",
+				File:            "/etc/nginx/example.crt",
+				Source:          "ssl_certificate",
+				Subject:         "CN=example.com",
+				SerialNumber:    "42",
+				NotAfter:        time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC),
+				DaysUntilExpiry: 20,
+				Level:           metrics.CertificateExpiryWarning,
+			}),
+			msgTopics: []string{
+				core.AgentStarted,
+				core.CertificateExpiry,
+				core.Events,
+				core.Events,
+			},
+			expectedEventReport: &eventsProto.EventReport{
+				Events: []*eventsProto.Event{
+					{
+						Metadata: &eventsProto.Metadata{
+							Module:     "NGINX-AGENT",
+							Type:       "Nginx",
+							Category:   "Status",
+							EventLevel: "WARN",
+						},
+						Data: &eventsProto.Event_ActivityEvent{
+							ActivityEvent: &eventsProto.ActivityEvent{
+								Message:    "certificate CN=example.com (warning, serial 42) in /etc/nginx/example.crt referenced by ssl_certificate expires in 20 days on 2023-08-10T12:00:00Z",
+								Dimensions: expectedNginxDimensions,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "test agent certificate expired message",
+			message: core.NewMessage(core.CertificateExpiry, &metrics.CertificateExpiryEvent{
+				File:            "/etc/nginx-agent/agent.crt",
+				Source:          "tls_cert",
+				Subject:         "CN=agent",
+				SerialNumber:    "7",
+				NotAfter:        time.Date(2023, 8, 10, 12, 0, 0, 0, time.UTC),
+				DaysUntilExpiry: -1,
+				Level:           metrics.CertificateExpired,
+			}),
+			msgTopics: []string{
+				core.AgentStarted,
+				core.CertificateExpiry,
+				core.Events,
+				core.Events,
+			},
+			expectedEventReport: &eventsProto.EventReport{
+				Events: []*eventsProto.Event{
+					{
+						Metadata: &eventsProto.Metadata{
+							Module:     "NGINX-AGENT",
+							Type:       "Agent",
+							Category:   "Status",
+							EventLevel: "CRITICAL",
+						},
+						Data: &eventsProto.Event_ActivityEvent{
+							ActivityEvent: &eventsProto.ActivityEvent{
+								Message:    "certificate CN=agent (serial 7) in /etc/nginx-agent/agent.crt referenced by tls_cert expired on 2023-08-10T12:00:00Z",
+								Dimensions: expectedCommonDimensions,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "test certificate renewal failed message",
+			message: core.NewMessage(core.CertificateRenewal, &CertificateRenewalResult{
+				NginxId: "12345",
+				File:    "/etc/nginx/example.crt",
+				Domains: []string{"example.com", "www.example.com"},
+				Err:     errors.New("authorization of example.com failed"),
+			}),
+			msgTopics: []string{
+				core.AgentStarted,
+				core.CertificateRenewal,
+				core.Events,
+				core.Events,
+			},
+			expectedEventReport: &eventsProto.EventReport{
+				Events: []*eventsProto.Event{
+					{
+						Metadata: &eventsProto.Metadata{
+							Module:     "NGINX-AGENT",
+							Type:       "Nginx",
+							Category:   "Status",
+							EventLevel: "ERROR",
+						},
+						Data: &eventsProto.Event_ActivityEvent{
+							ActivityEvent: &eventsProto.ActivityEvent{
+								Message:    "renewal of certificate /etc/nginx/example.crt for example.com, www.example.com failed: authorization of example.com failed",
+								Dimensions: expectedNginxDimensions,
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name:    "test unknown message",
 			message: core.NewMessage(core.UNKNOWN, "unknown message"),
@@ -614,6 +835,37 @@ func TestActivityEvents_Process(t *testing.T) {
 	}
 }
 
+func TestErrorLogEventLimiter(t *testing.T) {
+	now := time.Now()
+	newEvent := func(nginxId, message string) *metrics.NginxErrorLogEvent {
+		return &metrics.NginxErrorLogEvent{NginxId: nginxId, Item: &tailer.NginxErrorItem{Level: "error", Message: message}}
+	}
+
+	limiter := newErrorLogEventLimiter(time.Minute, 2)
+	assert.True(t, limiter.allow(newEvent("1", "a"), now))
+	// repeated messages are suppressed within the dedup window
+	assert.False(t, limiter.allow(newEvent("1", "a"), now.Add(time.Second)))
+	assert.True(t, limiter.allow(newEvent("2", "a"), now.Add(time.Second)))
+	assert.True(t, limiter.allow(newEvent("1", "b"), now.Add(time.Second)))
+	// the rate limit is reached
+	assert.False(t, limiter.allow(newEvent("1", "c"), now.Add(2*time.Second)))
+	// messages suppressed by the rate limit are not deduplicated
+	assert.True(t, limiter.allow(newEvent("1", "c"), now.Add(time.Minute)))
+	assert.True(t, limiter.allow(newEvent("1", "a"), now.Add(time.Minute)))
+	assert.False(t, limiter.allow(newEvent("1", "d"), now.Add(time.Minute)))
+
+	// the state of a stopped instance is removed
+	limiter.forget("1")
+	assert.Len(t, limiter.lastSeen, 1)
+	assert.NotContains(t, limiter.rateWindows, "1")
+	assert.True(t, limiter.allow(newEvent("1", "a"), now.Add(time.Minute)))
+
+	unlimited := newErrorLogEventLimiter(0, 0)
+	for i := 0; i < 10; i++ {
+		assert.True(t, unlimited.allow(newEvent("1", "a"), now))
+	}
+}
+
 func TestGenerateAgentStopEvent(t *testing.T) {
 	expectedCommonDimensions := []*commonProto.Dimension{
 		{
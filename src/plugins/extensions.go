@@ -53,11 +53,15 @@ func (e *Extensions) Process(msg *core.Message) {
 					}
 					e.conf = conf
 
-					advancedMetrics := extensions.NewAdvancedMetrics(
+					advancedMetrics, err := extensions.NewAdvancedMetrics(
 						e.env,
 						e.conf,
 						config.Viper.Get(agent_config.AdvancedMetricsExtensionPluginConfigKey),
 					)
+					if err != nil {
+						log.Warnf("Unable to load the Advanced Metrics plugin due to the following error: %v", err)
+						return
+					}
 					err = e.pipeline.Register(e.conf.QueueSize, nil, []core.ExtensionPlugin{advancedMetrics})
 					if err != nil {
 						log.Warnf("Unable to register %s extension, %v", data, err)
@@ -101,6 +105,25 @@ func (e *Extensions) Process(msg *core.Message) {
 					}
 					napMonitoring.Init(e.pipeline)
 				}
+			} else if data == agent_config.PhpFpmMetricsExtensionPlugin {
+				if !e.pipeline.IsPluginAlreadyRegistered(agent_config.PhpFpmMetricsExtensionPlugin) {
+					conf, err := config.GetConfig(e.conf.ClientID)
+					if err != nil {
+						log.Warnf("Unable to get agent config, %v", err)
+					}
+					e.conf = conf
+
+					phpFpmMetrics, err := extensions.NewPhpFpmMetrics(e.env, e.conf, config.Viper.Get(agent_config.PhpFpmMetricsExtensionPluginConfigKey))
+					if err != nil {
+						log.Warnf("Unable to load the PhpFpm Metrics plugin due to the following error: %v", err)
+						break
+					}
+					err = e.pipeline.Register(e.conf.QueueSize, nil, []core.ExtensionPlugin{phpFpmMetrics})
+					if err != nil {
+						log.Errorf("Unable to register %s extension, %v", data, err)
+					}
+					phpFpmMetrics.Init(e.pipeline)
+				}
 			}
 		}
 	}
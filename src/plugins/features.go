@@ -144,7 +144,7 @@ func (f *Features) enableMetricsFeature(data string) []core.Plugin {
 
 		metrics := NewMetrics(f.conf, f.env, f.binary, f.processes)
 		metricsThrottle := NewMetricsThrottle(f.conf, f.env)
-		metricsSender := NewMetricsSender(f.commander)
+		metricsSender := NewMetricsSender(f.commander, f.conf)
 
 		return []core.Plugin{metrics, metricsThrottle, metricsSender}
 	}
@@ -195,7 +195,7 @@ func (f *Features) enableMetricsSenderFeature(data string) []core.Plugin {
 		}
 		f.conf = conf
 
-		metricsSender := NewMetricsSender(f.commander)
+		metricsSender := NewMetricsSender(f.commander, f.conf)
 
 		return []core.Plugin{metricsSender}
 	}
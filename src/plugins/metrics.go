@@ -31,6 +31,8 @@ type Metrics struct {
 	collectors               []metrics.Collector
 	buf                      chan *metrics.StatsEntityWrapper
 	errors                   chan error
+	errorLogEvents           chan *metrics.NginxErrorLogEvent
+	certificateEvents        chan *metrics.CertificateExpiryEvent
 	collectorConfigsMap      map[string]*metrics.NginxCollectorConfig
 	ctx                      context.Context
 	wg                       sync.WaitGroup
@@ -44,13 +46,17 @@ type Metrics struct {
 }
 
 func NewMetrics(config *config.Config, env core.Environment, binary core.NginxBinary, processes []*core.Process) *Metrics {
-	collectorConfigsMap := createCollectorConfigsMap(config, env, binary, processes)
+	errorLogEvents := make(chan *metrics.NginxErrorLogEvent, 100)
+	certificateEvents := make(chan *metrics.CertificateExpiryEvent, 100)
+	collectorConfigsMap := createCollectorConfigsMap(config, env, binary, processes, errorLogEvents, certificateEvents)
 	return &Metrics{
 		collectorsUpdate:         atomic.NewBool(false),
 		ticker:                   time.NewTicker(config.AgentMetrics.CollectionInterval),
 		interval:                 config.AgentMetrics.CollectionInterval,
 		buf:                      make(chan *metrics.StatsEntityWrapper, 4096),
 		errors:                   make(chan error),
+		errorLogEvents:           errorLogEvents,
+		certificateEvents:        certificateEvents,
 		collectorConfigsMap:      collectorConfigsMap,
 		wg:                       sync.WaitGroup{},
 		collectorsMutex:          sync.RWMutex{},
@@ -80,7 +86,7 @@ func (m *Metrics) Process(msg *core.Message) {
 	case msg.Exact(core.AgentConfigChanged), msg.Exact(core.NginxConfigApplySucceeded):
 		// If the agent config on disk changed or the NGINX statusAPI was updated
 		// Then update Metrics with relevant config info
-		collectorConfigsMap := createCollectorConfigsMap(m.conf, m.env, m.binary, m.getNginxProccessInfo())
+		collectorConfigsMap := createCollectorConfigsMap(m.conf, m.env, m.binary, m.getNginxProccessInfo(), m.errorLogEvents, m.certificateEvents)
 		m.collectorConfigsMapMutex.Lock()
 		m.collectorConfigsMap = collectorConfigsMap
 		m.collectorConfigsMapMutex.Unlock()
@@ -101,7 +107,7 @@ func (m *Metrics) Process(msg *core.Message) {
 
 	case msg.Exact(core.NginxDetailProcUpdate):
 		m.syncProcessInfo(msg.Data().([]*core.Process))
-		collectorConfigsMap := createCollectorConfigsMap(m.conf, m.env, m.binary, m.getNginxProccessInfo())
+		collectorConfigsMap := createCollectorConfigsMap(m.conf, m.env, m.binary, m.getNginxProccessInfo(), m.errorLogEvents, m.certificateEvents)
 		for key, collectorConfig := range collectorConfigsMap {
 			if _, ok := m.collectorConfigsMap[key]; !ok {
 				log.Debugf("Adding new nginx collector for nginx id: %s", collectorConfig.NginxId)
@@ -204,6 +210,12 @@ func (m *Metrics) metricsGoroutine() {
 				m.collectorsUpdate.Store(false)
 			}
 
+		case event := <-m.errorLogEvents:
+			m.pipeline.Process(core.NewMessage(core.NginxErrorLogEvent, event))
+
+		case event := <-m.certificateEvents:
+			m.pipeline.Process(core.NewMessage(core.CertificateExpiry, event))
+
 		case err := <-m.errors:
 			log.Errorf("Error in metricsGoroutine %v", err)
 		}
@@ -254,6 +266,7 @@ func (m *Metrics) registerStatsSources() {
 	if m.conf.IsFeatureEnabled(agent_config.FeatureMetrics) || m.conf.IsFeatureEnabled(agent_config.FeatureMetricsCollection) {
 		tempCollectors = append(tempCollectors,
 			collectors.NewSystemCollector(m.env, m.conf),
+			collectors.NewAgentCollector(m.env, m.conf, m.pipeline.GetStats(), m.certificateEvents),
 		)
 
 		if m.env.IsContainer() {
@@ -304,7 +317,7 @@ func (m *Metrics) syncAgentConfigChange() {
 	m.conf = conf
 }
 
-func createCollectorConfigsMap(config *config.Config, env core.Environment, binary core.NginxBinary, processes []*core.Process) map[string]*metrics.NginxCollectorConfig {
+func createCollectorConfigsMap(config *config.Config, env core.Environment, binary core.NginxBinary, processes []*core.Process, errorLogEvents chan<- *metrics.NginxErrorLogEvent, certificateEvents chan<- *metrics.CertificateExpiryEvent) map[string]*metrics.NginxCollectorConfig {
 	collectorConfigsMap := make(map[string]*metrics.NginxCollectorConfig)
 
 	for _, p := range processes {
@@ -325,16 +338,33 @@ func createCollectorConfigsMap(config *config.Config, env core.Environment, bina
 			log.Warnf("Error reading access and error logs from config %s %v", detail.ConfPath, err)
 		}
 
+		upstreams, err := sdk.GetUpstreamServers(detail.ConfPath, config.IgnoreDirectives)
+		if err != nil {
+			log.Debugf("Error reading upstreams from config %s %v", detail.ConfPath, err)
+		}
+
+		certificates, err := sdk.GetCertificateFiles(detail.ConfPath, config.IgnoreDirectives)
+		if err != nil {
+			log.Debugf("Error reading certificates from config %s %v", detail.ConfPath, err)
+		}
+
 		collectorConfigsMap[detail.NginxId] = &metrics.NginxCollectorConfig{
-			StubStatus:         stubStatusApi,
-			PlusAPI:            plusApi,
-			BinPath:            detail.ProcessPath,
-			ConfPath:           detail.ConfPath,
-			CollectionInterval: config.AgentMetrics.CollectionInterval,
-			AccessLogs:         sdk.GetAccessLogs(accessLogs),
-			ErrorLogs:          sdk.GetErrorLogs(errorLogs),
-			NginxId:            detail.NginxId,
-			ClientVersion:      config.Nginx.NginxClientVersion,
+			StubStatus:              stubStatusApi,
+			PlusAPI:                 plusApi,
+			BinPath:                 detail.ProcessPath,
+			ConfPath:                detail.ConfPath,
+			CollectionInterval:      config.AgentMetrics.CollectionInterval,
+			AccessLogs:              sdk.GetAccessLogs(accessLogs),
+			ErrorLogs:               sdk.GetErrorLogs(errorLogs),
+			NginxId:                 detail.NginxId,
+			ClientVersion:           config.Nginx.NginxClientVersion,
+			AccessLogMetrics:        config.AccessLogMetrics,
+			ErrorLogEvents:          config.ErrorLogEvents,
+			ErrorLogEventChannel:    errorLogEvents,
+			Upstreams:               upstreams,
+			CertificateMonitor:      config.CertificateMonitor,
+			Certificates:            certificates,
+			CertificateEventChannel: certificateEvents,
 		}
 	}
 	return collectorConfigsMap
@@ -373,3 +403,9 @@ func (m *Metrics) syncProcessInfo(processInfo []*core.Process) {
 	defer m.processesMutex.Unlock()
 	m.processes = processInfo
 }
+
+// NewNginxCollectorConfigs returns the metrics collector configuration of every NGINX master
+// process, keyed by NGINX ID, without forwarding the error log and certificate expiry events
+func NewNginxCollectorConfigs(config *config.Config, env core.Environment, binary core.NginxBinary, processes []*core.Process) map[string]*metrics.NginxCollectorConfig {
+	return createCollectorConfigsMap(config, env, binary, processes, nil, nil)
+}
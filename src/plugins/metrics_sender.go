@@ -17,25 +17,43 @@ import (
 	"github.com/nginx/agent/sdk/v2/proto"
 	models "github.com/nginx/agent/sdk/v2/proto/events"
 	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/buffer"
+	"github.com/nginx/agent/v2/src/core/config"
 
 	log "github.com/sirupsen/logrus"
 	"go.uber.org/atomic"
 )
 
+const megabyte = 1024 * 1024
+
 type MetricsSender struct {
 	reporter    client.MetricReporter
 	pipeline    core.MessagePipeInterface
 	ctx         context.Context
 	started     *atomic.Bool
 	readyToSend *atomic.Bool
+	buffer      *buffer.DiskBuffer
+	replaying   *atomic.Bool
 }
 
-func NewMetricsSender(reporter client.MetricReporter) *MetricsSender {
-	return &MetricsSender{
+func NewMetricsSender(reporter client.MetricReporter, conf *config.Config) *MetricsSender {
+	metricsSender := &MetricsSender{
 		reporter:    reporter,
 		started:     atomic.NewBool(false),
 		readyToSend: atomic.NewBool(false),
+		replaying:   atomic.NewBool(false),
+	}
+
+	if conf != nil && conf.DiskBuffer.Enable {
+		diskBuffer, err := buffer.NewDiskBuffer(conf.DiskBuffer.Path, int64(conf.DiskBuffer.MaxSizeMB)*megabyte, conf.DiskBuffer.MaxAge)
+		if err != nil {
+			log.Errorf("Unable to create the disk buffer, undelivered reports will not be persisted: %v", err)
+		} else {
+			metricsSender.buffer = diskBuffer
+		}
 	}
+
+	return metricsSender
 }
 
 func (r *MetricsSender) Init(pipeline core.MessagePipeInterface) {
@@ -61,6 +79,7 @@ func (r *MetricsSender) Info() *core.Info {
 func (r *MetricsSender) Process(msg *core.Message) {
 	if msg.Exact(core.AgentConnected) {
 		r.readyToSend.Toggle()
+		r.replayBuffer()
 		return
 	}
 
@@ -71,31 +90,19 @@ func (r *MetricsSender) Process(msg *core.Message) {
 			return
 		}
 		for _, p := range payloads {
+			// keep the reports in order by buffering behind the reports that are not sent yet
+			if r.buffer != nil && (!r.readyToSend.Load() || r.buffer.Len() > 0) {
+				r.bufferPayload(p)
+				r.replayBuffer()
+				continue
+			}
+
 			if !r.readyToSend.Load() {
 				continue
 			}
 
-			switch report := p.(type) {
-			case *proto.MetricsReport:
-				message := client.MessageFromMetrics(report)
-				err := r.reporter.Send(r.ctx, message)
-				if err != nil {
-					log.Errorf("Failed to send MetricsReport: %v", err)
-				} else {
-					r.pipeline.Process(core.NewMessage(core.MetricReportSent, nil))
-				}
-			case *models.EventReport:
-				err := r.reporter.Send(r.ctx, client.MessageFromEvents(report))
-				if err != nil {
-					l := len(report.Events)
-					var sb strings.Builder
-					for i := 0; i < l-1; i++ {
-						sb.WriteString(report.Events[i].GetSecurityViolationEvent().SupportID)
-						sb.WriteString(", ")
-					}
-					sb.WriteString(report.Events[l-1].GetSecurityViolationEvent().SupportID)
-					log.Errorf("Failed to send EventReport with error: %v, supportID list: %s", err, sb.String())
-				}
+			if err := r.send(p); err != nil && r.buffer != nil {
+				r.bufferPayload(p)
 			}
 		}
 	} else if msg.Exact(core.AgentConfigChanged) {
@@ -108,6 +115,69 @@ func (r *MetricsSender) Process(msg *core.Message) {
 	}
 }
 
+func (r *MetricsSender) send(payload core.Payload) error {
+	switch report := payload.(type) {
+	case *proto.MetricsReport:
+		message := client.MessageFromMetrics(report)
+		err := r.reporter.Send(r.ctx, message)
+		if err != nil {
+			log.Errorf("Failed to send MetricsReport: %v", err)
+			return err
+		}
+		r.pipeline.Process(core.NewMessage(core.MetricReportSent, nil))
+	case *models.EventReport:
+		err := r.reporter.Send(r.ctx, client.MessageFromEvents(report))
+		if err != nil {
+			l := len(report.Events)
+			var sb strings.Builder
+			for i := 0; i < l-1; i++ {
+				sb.WriteString(report.Events[i].GetSecurityViolationEvent().SupportID)
+				sb.WriteString(", ")
+			}
+			sb.WriteString(report.Events[l-1].GetSecurityViolationEvent().SupportID)
+			log.Errorf("Failed to send EventReport with error: %v, supportID list: %s", err, sb.String())
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *MetricsSender) bufferPayload(payload core.Payload) {
+	switch payload.(type) {
+	case *proto.MetricsReport, *models.EventReport:
+		if err := r.buffer.Push(payload); err != nil {
+			log.Errorf("Failed to buffer undelivered report: %v", err)
+		}
+	}
+}
+
+// replayBuffer sends the buffered reports in order until the buffer is empty or a send
+// fails. Only one replay runs at a time.
+func (r *MetricsSender) replayBuffer() {
+	if r.buffer == nil || !r.readyToSend.Load() || r.buffer.Len() == 0 {
+		return
+	}
+	if !r.replaying.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		defer r.replaying.Store(false)
+
+		log.Infof("MetricsSender replaying %d buffered reports", r.buffer.Len())
+		for r.readyToSend.Load() && r.ctx.Err() == nil {
+			seq, payload, err := r.buffer.Peek()
+			if err != nil || payload == nil {
+				return
+			}
+			if err := r.send(payload); err != nil {
+				return
+			}
+			r.buffer.Remove(seq)
+		}
+	}()
+}
+
 func (r *MetricsSender) metricSenderBackoff(agentConfig *proto.AgentConfig) {
 	log.Debugf("update metric reporter client configuration to %+v", agentConfig)
 
@@ -15,8 +15,10 @@ import (
 	"time"
 
 	"github.com/nginx/agent/sdk/v2/backoff"
+	"github.com/nginx/agent/sdk/v2/client"
 	"github.com/nginx/agent/sdk/v2/proto"
 	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
 	tutils "github.com/nginx/agent/v2/test/utils"
 
 	"github.com/gogo/protobuf/types"
@@ -43,7 +45,7 @@ func TestMetricsSenderSendMetrics(t *testing.T) {
 			ctx := context.TODO()
 			mockMetricsReportClient := tutils.NewMockMetricsReportClient()
 			mockMetricsReportClient.Mock.On("Send", ctx, mock.Anything).Return(test.err)
-			pluginUnderTest := NewMetricsSender(mockMetricsReportClient)
+			pluginUnderTest := NewMetricsSender(mockMetricsReportClient, tutils.GetMockAgentConfig())
 
 			assert.False(t, pluginUnderTest.started.Load())
 			assert.False(t, pluginUnderTest.readyToSend.Load())
@@ -110,7 +112,7 @@ func TestMetricsSenderBackoff(t *testing.T) {
 		t.Run(test.name, func(_ *testing.T) {
 			ctx := context.TODO()
 			mockMetricsReportClient := tutils.NewMockMetricsReportClient()
-			pluginUnderTest := NewMetricsSender(mockMetricsReportClient)
+			pluginUnderTest := NewMetricsSender(mockMetricsReportClient, tutils.GetMockAgentConfig())
 
 			pluginUnderTest.Init(core.NewMockMessagePipe(ctx))
 			pluginUnderTest.Process(core.NewMessage(core.AgentConnected, nil))
@@ -129,7 +131,50 @@ func TestMetricsSenderBackoff(t *testing.T) {
 	}
 }
 
+func TestMetricsSenderDiskBuffer(t *testing.T) {
+	ctx := context.TODO()
+	mockMetricsReportClient := tutils.NewMockMetricsReportClient()
+	mockMetricsReportClient.Mock.On("Send", ctx, mock.Anything).Return(errors.New("send err")).Once()
+	mockMetricsReportClient.Mock.On("Send", ctx, mock.Anything).Return(nil)
+
+	conf := tutils.GetMockAgentConfig()
+	conf.DiskBuffer = config.DiskBuffer{Enable: true, Path: t.TempDir(), MaxSizeMB: 1}
+
+	pluginUnderTest := NewMetricsSender(mockMetricsReportClient, conf)
+	assert.NotNil(t, pluginUnderTest.buffer)
+	pluginUnderTest.Init(core.NewMockMessagePipe(ctx))
+
+	report := func(value float64) *proto.MetricsReport {
+		return &proto.MetricsReport{
+			Type: proto.MetricsReport_SYSTEM,
+			Data: []*proto.StatsEntity{{Simplemetrics: []*proto.SimpleMetric{{Name: "Metric A", Value: value}}}},
+		}
+	}
+
+	// reports are buffered until the agent is connected
+	pluginUnderTest.Process(core.NewMessage(core.CommMetrics, []core.Payload{report(1)}))
+	assert.Equal(t, 1, pluginUnderTest.buffer.Len())
+	mockMetricsReportClient.AssertNotCalled(t, "Send", ctx, mock.Anything)
+
+	// the first replay fails and the report stays buffered
+	pluginUnderTest.Process(core.NewMessage(core.AgentConnected, nil))
+	assert.Eventually(t, func() bool { return !pluginUnderTest.replaying.Load() }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, pluginUnderTest.buffer.Len())
+
+	// new reports are queued behind the buffered report and replayed in order
+	pluginUnderTest.Process(core.NewMessage(core.CommMetrics, []core.Payload{report(2)}))
+	assert.Eventually(t, func() bool { return pluginUnderTest.buffer.Len() == 0 }, 5*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return !pluginUnderTest.replaying.Load() }, 5*time.Second, 10*time.Millisecond)
+
+	calls := mockMetricsReportClient.Calls
+	assert.Len(t, calls, 3)
+	assert.Equal(t, float64(1), calls[1].Arguments.Get(1).(client.Message).Raw().(*proto.MetricsReport).Data[0].Simplemetrics[0].Value)
+	assert.Equal(t, float64(2), calls[2].Arguments.Get(1).(client.Message).Raw().(*proto.MetricsReport).Data[0].Simplemetrics[0].Value)
+
+	pluginUnderTest.Close()
+}
+
 func TestMetricsSenderSubscriptions(t *testing.T) {
-	pluginUnderTest := NewMetricsSender(tutils.NewMockMetricsReportClient())
+	pluginUnderTest := NewMetricsSender(tutils.NewMockMetricsReportClient(), tutils.GetMockAgentConfig())
 	assert.Equal(t, []string{core.CommMetrics, core.AgentConnected, core.AgentConfigChanged}, pluginUnderTest.Subscriptions())
 }
@@ -166,6 +166,11 @@ func TestMetricsProcessNginxDetailProcUpdate(t *testing.T) {
 
 			metricsPlugin.Process(tc.message)
 
+			for _, collectorConfig := range tc.expectedCollectorConfigMap {
+				collectorConfig.ErrorLogEventChannel = metricsPlugin.errorLogEvents
+				collectorConfig.CertificateEventChannel = metricsPlugin.certificateEvents
+			}
+
 			assert.Equal(t, tc.expectedNumberOfCollectors, len(metricsPlugin.collectors))
 			assert.Equal(t, tc.expectedCollectorConfigMap, metricsPlugin.collectorConfigsMap)
 
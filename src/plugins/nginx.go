@@ -30,6 +30,7 @@ import (
 	"github.com/nginx/agent/v2/src/core"
 	"github.com/nginx/agent/v2/src/core/config"
 	"github.com/nginx/agent/v2/src/core/payloads"
+	"github.com/nginx/agent/v2/src/core/revisions"
 	"github.com/nginx/agent/v2/src/core/tailer"
 	"github.com/nginx/agent/v2/src/extensions/nginx-app-protect/nap"
 )
@@ -38,6 +39,8 @@ const (
 	configAppliedProcessedResponse  = "config apply request successfully processed"
 	configAppliedResponse           = "config applied successfully"
 	nginxConfigAsyncFeatureDisabled = "nginx-config-async feature is disabled"
+	configRevisionsDisabled         = "config revisions are disabled"
+	commandChannelAppliedBy         = "command-channel"
 )
 
 var (
@@ -64,6 +67,7 @@ type Nginx struct {
 	isFeatureNginxConfigEnabled    bool
 	configApplyStatusChannel       chan *proto.Command_NginxConfigResponse
 	nginxAppProtectSoftwareDetails *proto.AppProtectWAFDetails
+	revisionStore                  *revisions.Store
 }
 
 type ConfigRollbackResponse struct {
@@ -83,6 +87,7 @@ type NginxReloadResponse struct {
 type NginxConfigValidationResponse struct {
 	err           error
 	correlationId string
+	appliedBy     string
 	nginxDetails  *proto.NginxDetails
 	config        *proto.NginxConfig
 	configApply   *sdk.ConfigApply
@@ -103,9 +108,25 @@ func NewNginx(cmdr client.Commander, nginxBinary core.NginxBinary, env core.Envi
 		isFeatureNginxConfigEnabled:    isFeatureNginxConfigEnabled,
 		configApplyStatusChannel:       make(chan *proto.Command_NginxConfigResponse, 1),
 		nginxAppProtectSoftwareDetails: &proto.AppProtectWAFDetails{},
+		revisionStore:                  newConfigRevisionStore(loadedConfig),
 	}
 }
 
+// newConfigRevisionStore returns the store of applied NGINX configs, or nil if config revisions are disabled
+func newConfigRevisionStore(conf *config.Config) *revisions.Store {
+	if !conf.ConfigRevisions.Enable {
+		return nil
+	}
+
+	store, err := revisions.NewStore(conf.ConfigRevisions.Path, conf.ConfigRevisions.MaxRevisions)
+	if err != nil {
+		log.Errorf("Config revisions are disabled: %v", err)
+		return nil
+	}
+
+	return store
+}
+
 // Init initializes the plugin
 func (n *Nginx) Init(pipeline core.MessagePipeInterface) {
 	log.Info("NginxBinary initializing")
@@ -136,7 +157,7 @@ func (n *Nginx) Process(message *core.Message) {
 			n.processCmd(cmd)
 		case *AgentAPIConfigApplyRequest:
 			if n.isFeatureNginxConfigEnabled {
-				status := n.writeConfigAndReloadNginx(cmd.correlationId, cmd.config, proto.NginxConfigAction_APPLY)
+				status := n.writeConfigAndReloadNginx(cmd.correlationId, cmd.appliedBy, cmd.config, proto.NginxConfigAction_APPLY)
 				if status.NginxConfigResponse.GetStatus().GetMessage() != configAppliedProcessedResponse {
 					n.messagePipeline.Process(core.NewMessage(core.AgentAPIConfigApplyResponse, status))
 				}
@@ -161,6 +182,13 @@ func (n *Nginx) Process(message *core.Message) {
 				log.Warnf("Error uploading config: %v", err)
 			}
 		}
+	case core.NginxReload, core.NginxStop, core.NginxStart, core.NginxReopenLogs, core.NginxUpgrade:
+		switch request := message.Data().(type) {
+		case *NginxLifecycleRequest:
+			go n.runLifecycleAction(request)
+		default:
+			log.Warnf("Invalid message received, %T, for topic, %s", message.Data(), message.Topic())
+		}
 	case core.NginxDetailProcUpdate:
 		procs := message.Data().([]*core.Process)
 		n.syncProcessInfo(procs)
@@ -215,6 +243,11 @@ func (n *Nginx) Subscriptions() []string {
 		core.NginxConfigValidationSucceeded,
 		core.NginxConfigValidationFailed,
 		core.AgentStarted,
+		core.NginxReload,
+		core.NginxStop,
+		core.NginxStart,
+		core.NginxReopenLogs,
+		core.NginxUpgrade,
 	}
 }
 
@@ -311,9 +344,12 @@ func (n *Nginx) processCmd(cmd *proto.Command) {
 			status.NginxConfigResponse.Status = newErrStatus("Config test not implemented").CmdStatus
 			status.NginxConfigResponse.Action = proto.NginxConfigAction_TEST
 		case proto.NginxConfigAction_ROLLBACK:
-			// TODO: Rollback config?
-			status.NginxConfigResponse.Status = newErrStatus("Config rollback not implemented").CmdStatus
-			status.NginxConfigResponse.Action = proto.NginxConfigAction_ROLLBACK
+			if n.isFeatureNginxConfigEnabled {
+				status = n.revertConfig(cmd.GetMeta().GetMessageId(), commandChannelAppliedBy, commandData.NginxConfig.GetConfigData())
+			} else {
+				status.NginxConfigResponse.Status = newErrStatus("unable to use nginx config functionality as nginx-config feature is disabled").CmdStatus
+				status.NginxConfigResponse.Action = proto.NginxConfigAction_ROLLBACK
+			}
 		case proto.NginxConfigAction_RETURN:
 			// TODO: Upload config
 			status.NginxConfigResponse.Status = newErrStatus("Config return not implemented").CmdStatus
@@ -349,13 +385,40 @@ func (n *Nginx) applyConfig(cmd *proto.Command, cfg *proto.Command_NginxConfig)
 		return status
 	}
 
-	status = n.writeConfigAndReloadNginx(cmd.Meta.MessageId, config, cmd.GetNginxConfig().GetAction())
+	status = n.writeConfigAndReloadNginx(cmd.Meta.MessageId, commandChannelAppliedBy, config, cmd.GetNginxConfig().GetAction())
 
 	log.Debug("Config Apply Complete")
 	return status
 }
 
-func (n *Nginx) writeConfigAndReloadNginx(correlationId string, config *proto.NginxConfig, action proto.NginxConfigAction) *proto.Command_NginxConfigResponse {
+// revertConfig applies a stored config revision. The revision is identified by the checksum of the config descriptor.
+func (n *Nginx) revertConfig(correlationId, appliedBy string, configData *proto.ConfigDescriptor) *proto.Command_NginxConfigResponse {
+	log.Debugf("Reverting to config revision %s for nginx instance %s", configData.GetChecksum(), configData.GetNginxId())
+	status := &proto.Command_NginxConfigResponse{
+		NginxConfigResponse: &proto.NginxConfigResponse{
+			Action:     proto.NginxConfigAction_ROLLBACK,
+			ConfigData: configData,
+		},
+	}
+
+	if n.revisionStore == nil {
+		status.NginxConfigResponse.Status = newErrStatus(configRevisionsDisabled).CmdStatus
+		return status
+	}
+
+	_, config, err := n.revisionStore.Get(configData.GetNginxId(), configData.GetChecksum())
+	if err != nil {
+		status.NginxConfigResponse.Status = newErrStatus("Config rollback failed: " + err.Error()).CmdStatus
+		return status
+	}
+
+	status = n.writeConfigAndReloadNginx(correlationId, appliedBy, config, proto.NginxConfigAction_ROLLBACK)
+	status.NginxConfigResponse.Action = proto.NginxConfigAction_ROLLBACK
+
+	return status
+}
+
+func (n *Nginx) writeConfigAndReloadNginx(correlationId, appliedBy string, config *proto.NginxConfig, action proto.NginxConfigAction) *proto.Command_NginxConfigResponse {
 	status := &proto.Command_NginxConfigResponse{
 		NginxConfigResponse: &proto.NginxConfigResponse{
 			Status:     newOKStatus(configAppliedProcessedResponse).CmdStatus,
@@ -420,7 +483,7 @@ func (n *Nginx) writeConfigAndReloadNginx(correlationId string, config *proto.Ng
 		return n.handleErrorStatus(status, message)
 	}
 
-	go n.validateConfig(nginx, correlationId, config, configApply)
+	go n.validateConfig(nginx, correlationId, appliedBy, config, configApply)
 
 	// If the NGINX config can be validated with the validationTimeout the result will be returned straight away.
 	// This is timeout is temporary to ensure we support backwards compatibility. In a future release this timeout
@@ -437,7 +500,7 @@ func (n *Nginx) writeConfigAndReloadNginx(correlationId string, config *proto.Ng
 // This function will run a nginx config validation in a separate go routine. If the validation takes less than 15 seconds then the result is returned straight away,
 // otherwise nil is returned and the validation continues on in the background until it is complete. The result is always added to the message pipeline for other plugins
 // to use.
-func (n *Nginx) validateConfig(nginx *proto.NginxDetails, correlationId string, config *proto.NginxConfig, configApply *sdk.ConfigApply) {
+func (n *Nginx) validateConfig(nginx *proto.NginxDetails, correlationId, appliedBy string, config *proto.NginxConfig, configApply *sdk.ConfigApply) {
 	start := time.Now()
 
 	err := n.nginxBinary.ValidateConfig(nginx.NginxId, nginx.ProcessPath, nginx.ConfPath, config, configApply)
@@ -452,6 +515,7 @@ func (n *Nginx) validateConfig(nginx *proto.NginxDetails, correlationId string,
 		response := &NginxConfigValidationResponse{
 			err:           fmt.Errorf("error running nginx -t -c %s:\n %v", nginx.ConfPath, err),
 			correlationId: correlationId,
+			appliedBy:     appliedBy,
 			nginxDetails:  nginx,
 			config:        config,
 			configApply:   configApply,
@@ -462,6 +526,7 @@ func (n *Nginx) validateConfig(nginx *proto.NginxDetails, correlationId string,
 		response := &NginxConfigValidationResponse{
 			err:           nil,
 			correlationId: correlationId,
+			appliedBy:     appliedBy,
 			nginxDetails:  nginx,
 			config:        config,
 			configApply:   configApply,
@@ -509,6 +574,8 @@ func (n *Nginx) completeConfigApply(response *NginxConfigValidationResponse) (st
 			}
 		}
 
+		n.addConfigRevision(response)
+
 		// Upload NGINX config only if GPRC server is configured
 		if n.config.IsGrpcServerConfigured() {
 			err := n.uploadConfig(
@@ -553,6 +620,33 @@ func (n *Nginx) completeConfigApply(response *NginxConfigValidationResponse) (st
 	return status
 }
 
+// addConfigRevision stores the config that is on disk after a successful config apply
+func (n *Nginx) addConfigRevision(response *NginxConfigValidationResponse) {
+	if n.revisionStore == nil {
+		return
+	}
+
+	nginxId := response.config.GetConfigData().GetNginxId()
+	config, err := n.nginxBinary.ReadConfig(response.nginxDetails.GetConfPath(), nginxId, n.env.GetSystemUUID())
+	if err != nil {
+		log.Errorf("Unable to read config for config revision of nginx instance %s: %v", nginxId, err)
+		return
+	}
+
+	revision, err := n.revisionStore.Add(revisions.Revision{
+		NginxId:       nginxId,
+		CorrelationId: response.correlationId,
+		AppliedBy:     response.appliedBy,
+		AppliedAt:     time.Now(),
+	}, config)
+	if err != nil {
+		log.Errorf("Unable to store config revision of nginx instance %s: %v", nginxId, err)
+		return
+	}
+
+	log.Infof("Stored config revision %s for nginx instance %s", revision.ID, nginxId)
+}
+
 func (n *Nginx) reloadNginx(nginxDetails *proto.NginxDetails) error {
 	log.Info("Monitoring post reload for changes")
 	n.monitorMutex.Lock()
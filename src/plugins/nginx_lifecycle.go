@@ -0,0 +1,243 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+)
+
+const (
+	NginxLifecycleReload     = "reload"
+	NginxLifecycleStop       = "stop"
+	NginxLifecycleStart      = "start"
+	NginxLifecycleReopenLogs = "reopen-logs"
+	NginxLifecycleUpgrade    = "upgrade"
+
+	nginxMasterProcessCommandPrefix = "nginx: master process"
+)
+
+var (
+	// nginxLifecycleTopics are the topics the NGINX lifecycle requests are published to, by action
+	nginxLifecycleTopics = map[string]string{
+		NginxLifecycleReload:     core.NginxReload,
+		NginxLifecycleStop:       core.NginxStop,
+		NginxLifecycleStart:      core.NginxStart,
+		NginxLifecycleReopenLogs: core.NginxReopenLogs,
+		NginxLifecycleUpgrade:    core.NginxUpgrade,
+	}
+
+	nginxLifecycleTimeout      = 30 * time.Second
+	nginxLifecyclePollInterval = 500 * time.Millisecond
+
+	errNginxLifecycleTimeout = errors.New("timed out waiting for NGINX")
+)
+
+// NginxLifecycleRequest is a request to run a lifecycle action on an NGINX instance
+type NginxLifecycleRequest struct {
+	correlationId string
+	action        string
+	requestedBy   string
+	nginxDetails  *proto.NginxDetails
+}
+
+// NginxLifecycleStatus is the status of a lifecycle action run on an NGINX instance
+// swagger:model NginxLifecycleStatus
+type NginxLifecycleStatus struct {
+	// Correlation ID of the lifecycle request
+	// example: 6204037c-30e6-408b-8aaa-dd8219860b4b
+	CorrelationId string `json:"correlation_id"`
+	// NGINX ID
+	// example: b636d4376dea15405589692d3c5d3869ff3a9b26b0e7bb4bb1aa7e658ace1437
+	NginxId string `json:"nginx_id"`
+	// Lifecycle action
+	// example: reload
+	Action string `json:"action"`
+	// Status of the lifecycle action, one of PENDING, OK or ERROR
+	// example: OK
+	Status string `json:"status"`
+	// Message
+	// example: nginx master process (pid: 8) reloaded
+	Message string `json:"message"`
+	// Time the lifecycle action was requested
+	// example: 2023-01-01T12:00:00Z
+	StartedAt time.Time `json:"started_at"`
+	// Time the lifecycle action completed
+	// example: 2023-01-01T12:00:01Z
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// details of the NGINX instance the action was run on
+	nginxDetails *proto.NginxDetails
+}
+
+// processExists reports whether a process with the pid is running
+var processExists = func(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// runLifecycleAction runs a lifecycle action on an NGINX instance and publishes the result
+func (n *Nginx) runLifecycleAction(request *NginxLifecycleRequest) {
+	log.Infof("Running NGINX %s for nginx instance %s requested by %s", request.action, request.nginxDetails.GetNginxId(), request.requestedBy)
+
+	status := &NginxLifecycleStatus{
+		CorrelationId: request.correlationId,
+		NginxId:       request.nginxDetails.GetNginxId(),
+		Action:        request.action,
+		StartedAt:     time.Now(),
+		nginxDetails:  request.nginxDetails,
+	}
+
+	var message string
+	var err error
+	switch request.action {
+	case NginxLifecycleReload:
+		message, err = n.reloadNginxInstance(request.nginxDetails)
+	case NginxLifecycleStop:
+		message, err = n.stopNginxInstance(request.nginxDetails)
+	case NginxLifecycleStart:
+		message, err = n.startNginxInstance(request.nginxDetails)
+	case NginxLifecycleReopenLogs:
+		message, err = n.reopenNginxLogs(request.nginxDetails)
+	case NginxLifecycleUpgrade:
+		message, err = n.upgradeNginxBinary(request.nginxDetails)
+	default:
+		err = fmt.Errorf("unknown NGINX lifecycle action %s", request.action)
+	}
+
+	completedAt := time.Now()
+	status.CompletedAt = &completedAt
+	if err != nil {
+		log.Errorf("NGINX %s for nginx instance %s failed: %v", request.action, status.NginxId, err)
+		status.Status = errorStatus
+		status.Message = err.Error()
+	} else {
+		log.Infof("NGINX %s for nginx instance %s succeeded: %s", request.action, status.NginxId, message)
+		status.Status = okStatus
+		status.Message = message
+	}
+
+	n.messagePipeline.Process(core.NewMessage(core.NginxLifecycleComplete, status))
+}
+
+func (n *Nginx) reloadNginxInstance(nginxDetails *proto.NginxDetails) (string, error) {
+	if err := n.reloadNginx(nginxDetails); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("nginx master process (pid: %s) reloaded", nginxDetails.GetProcessId()), nil
+}
+
+// stopNginxInstance gracefully shuts down the master process and waits for it to exit
+func (n *Nginx) stopNginxInstance(nginxDetails *proto.NginxDetails) (string, error) {
+	if err := n.nginxBinary.Signal(nginxDetails.GetProcessId(), syscall.SIGQUIT); err != nil {
+		return "", err
+	}
+
+	if err := waitForProcessExit(nginxDetails.GetProcessId()); err != nil {
+		return "", fmt.Errorf("nginx master process (pid: %s) did not exit: %v", nginxDetails.GetProcessId(), err)
+	}
+	return fmt.Sprintf("nginx master process (pid: %s) stopped", nginxDetails.GetProcessId()), nil
+}
+
+// startNginxInstance starts NGINX with the binary and config of the instance and waits for its master process
+func (n *Nginx) startNginxInstance(nginxDetails *proto.NginxDetails) (string, error) {
+	if err := n.nginxBinary.Start(nginxDetails.GetNginxId(), nginxDetails.GetProcessPath(), nginxDetails.GetConfPath()); err != nil {
+		return "", err
+	}
+
+	process, err := n.waitForMasterProcess(func(process *core.Process) bool {
+		return process.IsMaster && n.nginxBinary.GetNginxIDForProcess(process) == nginxDetails.GetNginxId()
+	})
+	if err != nil {
+		return "", fmt.Errorf("nginx master process did not start: %v", err)
+	}
+	return fmt.Sprintf("nginx master process (pid: %d) started", process.Pid), nil
+}
+
+func (n *Nginx) reopenNginxLogs(nginxDetails *proto.NginxDetails) (string, error) {
+	if err := n.nginxBinary.Signal(nginxDetails.GetProcessId(), syscall.SIGUSR1); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("nginx master process (pid: %s) reopened its log files", nginxDetails.GetProcessId()), nil
+}
+
+// upgradeNginxBinary upgrades the NGINX executable on the fly. A new master process is started
+// with USR2, the old worker processes are shut down with WINCH and the old master process is shut
+// down with QUIT once the new master process is running.
+func (n *Nginx) upgradeNginxBinary(nginxDetails *proto.NginxDetails) (string, error) {
+	oldPid := nginxDetails.GetProcessId()
+	oldMasterPid, err := strconv.Atoi(oldPid)
+	if err != nil {
+		return "", fmt.Errorf("invalid nginx master process id %s: %v", oldPid, err)
+	}
+
+	if err := n.nginxBinary.Signal(oldPid, syscall.SIGUSR2); err != nil {
+		return "", err
+	}
+
+	// The new master process is a child of the old master process until the old master process
+	// exits, so it is not reported as a master process and is matched by its command line instead
+	process, err := n.waitForMasterProcess(func(process *core.Process) bool {
+		return int(process.ParentPid) == oldMasterPid && strings.HasPrefix(process.Command, nginxMasterProcessCommandPrefix)
+	})
+	if err != nil {
+		return "", fmt.Errorf("new nginx master process did not start, the old master process (pid: %s) is still running: %v", oldPid, err)
+	}
+
+	if err := n.nginxBinary.Signal(oldPid, syscall.SIGWINCH); err != nil {
+		return "", err
+	}
+	if err := n.nginxBinary.Signal(oldPid, syscall.SIGQUIT); err != nil {
+		return "", err
+	}
+	if err := waitForProcessExit(oldPid); err != nil {
+		return "", fmt.Errorf("old nginx master process (pid: %s) did not exit: %v", oldPid, err)
+	}
+
+	return fmt.Sprintf("nginx master process (pid: %s) replaced by new master process (pid: %d)", oldPid, process.Pid), nil
+}
+
+// waitForMasterProcess polls the running NGINX processes until the master process matches
+func (n *Nginx) waitForMasterProcess(matches func(process *core.Process) bool) (*core.Process, error) {
+	deadline := time.Now().Add(nginxLifecycleTimeout)
+	for {
+		for _, process := range n.env.Processes() {
+			if matches(process) {
+				return process, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return nil, errNginxLifecycleTimeout
+		}
+		time.Sleep(nginxLifecyclePollInterval)
+	}
+}
+
+func waitForProcessExit(processId string) error {
+	pid, err := strconv.Atoi(processId)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(nginxLifecycleTimeout)
+	for processExists(pid) {
+		if time.Now().After(deadline) {
+			return errNginxLifecycleTimeout
+		}
+		time.Sleep(nginxLifecyclePollInterval)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+	tutils "github.com/nginx/agent/v2/test/utils"
+)
+
+func TestNginx_runLifecycleAction(t *testing.T) {
+	nginxDetails := &proto.NginxDetails{
+		NginxId:     "12345",
+		ProcessId:   "1",
+		ProcessPath: "/usr/sbin/nginx",
+		ConfPath:    "/etc/nginx/nginx.conf",
+	}
+
+	tests := []struct {
+		name            string
+		action          string
+		processes       []*core.Process
+		runningPids     map[int]bool
+		setup           func(binary *tutils.MockNginxBinary)
+		expectedStatus  string
+		expectedMessage string
+		expectedSignals []syscall.Signal
+	}{
+		{
+			name:            "reopen logs",
+			action:          NginxLifecycleReopenLogs,
+			expectedStatus:  okStatus,
+			expectedMessage: "nginx master process (pid: 1) reopened its log files",
+			expectedSignals: []syscall.Signal{syscall.SIGUSR1},
+		},
+		{
+			name:            "stop",
+			action:          NginxLifecycleStop,
+			expectedStatus:  okStatus,
+			expectedMessage: "nginx master process (pid: 1) stopped",
+			expectedSignals: []syscall.Signal{syscall.SIGQUIT},
+		},
+		{
+			name:            "stop timed out",
+			action:          NginxLifecycleStop,
+			runningPids:     map[int]bool{1: true},
+			expectedStatus:  errorStatus,
+			expectedMessage: "nginx master process (pid: 1) did not exit: timed out waiting for NGINX",
+			expectedSignals: []syscall.Signal{syscall.SIGQUIT},
+		},
+		{
+			name:   "signal failed",
+			action: NginxLifecycleStop,
+			setup: func(binary *tutils.MockNginxBinary) {
+				binary.On("Signal", "1", syscall.SIGQUIT).Return(errors.New("operation not permitted")).Once()
+			},
+			expectedStatus:  errorStatus,
+			expectedMessage: "operation not permitted",
+			expectedSignals: []syscall.Signal{syscall.SIGQUIT},
+		},
+		{
+			name:   "start",
+			action: NginxLifecycleStart,
+			processes: []*core.Process{
+				// the mock NGINX binary returns the process name as the NGINX ID
+				{Pid: 20, Name: "12345", IsMaster: true, Command: "nginx: master process /usr/sbin/nginx"},
+				{Pid: 21, ParentPid: 20, Name: "12345", Command: "nginx: worker process"},
+			},
+			setup: func(binary *tutils.MockNginxBinary) {
+				binary.On("Start", "12345", "/usr/sbin/nginx", "/etc/nginx/nginx.conf").Return(nil)
+				binary.On("GetNginxIDForProcess", mock.Anything).Return()
+			},
+			expectedStatus:  okStatus,
+			expectedMessage: "nginx master process (pid: 20) started",
+		},
+		{
+			name:   "start failed",
+			action: NginxLifecycleStart,
+			setup: func(binary *tutils.MockNginxBinary) {
+				binary.On("Start", "12345", "/usr/sbin/nginx", "/etc/nginx/nginx.conf").Return(errors.New("bind() to 0.0.0.0:80 failed"))
+			},
+			expectedStatus:  errorStatus,
+			expectedMessage: "bind() to 0.0.0.0:80 failed",
+		},
+		{
+			name:   "upgrade",
+			action: NginxLifecycleUpgrade,
+			// the new master process is a child of the old master process, so it is not a master process
+			// in the process list until the old master process exits
+			processes: []*core.Process{
+				{Pid: 1, Name: "nginx", IsMaster: true, Command: "nginx: master process /usr/sbin/nginx"},
+				{Pid: 2, ParentPid: 1, Name: "nginx", Command: "nginx: worker process"},
+				{Pid: 30, ParentPid: 1, Name: "nginx", Command: "nginx: master process /usr/sbin/nginx"},
+				{Pid: 31, ParentPid: 30, Name: "nginx", Command: "nginx: worker process"},
+			},
+			expectedStatus:  okStatus,
+			expectedMessage: "nginx master process (pid: 1) replaced by new master process (pid: 30)",
+			expectedSignals: []syscall.Signal{syscall.SIGUSR2, syscall.SIGWINCH, syscall.SIGQUIT},
+		},
+		{
+			name:   "upgrade without new master process",
+			action: NginxLifecycleUpgrade,
+			processes: []*core.Process{
+				{Pid: 1, Name: "nginx", IsMaster: true, Command: "nginx: master process /usr/sbin/nginx"},
+				{Pid: 2, ParentPid: 1, Name: "nginx", Command: "nginx: worker process"},
+			},
+			expectedStatus:  errorStatus,
+			expectedMessage: "new nginx master process did not start, the old master process (pid: 1) is still running: timed out waiting for NGINX",
+			expectedSignals: []syscall.Signal{syscall.SIGUSR2},
+		},
+	}
+
+	originalProcessExists := processExists
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nginxLifecycleTimeout = 50 * time.Millisecond
+			nginxLifecyclePollInterval = 10 * time.Millisecond
+			processExists = func(pid int) bool { return tt.runningPids[pid] }
+			defer func() {
+				nginxLifecycleTimeout = 30 * time.Second
+				nginxLifecyclePollInterval = 500 * time.Millisecond
+				processExists = originalProcessExists
+			}()
+
+			binary := tutils.NewMockNginxBinary()
+			if tt.setup != nil {
+				tt.setup(binary)
+			}
+			binary.On("Signal", "1", mock.Anything).Return(nil)
+
+			env := tutils.NewMockEnvironment()
+			env.On("Processes", mock.Anything).Return(tt.processes)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			pipeline := core.NewMockMessagePipe(ctx)
+
+			pluginUnderTest := NewNginx(nil, binary, env, &config.Config{}, tt.processes)
+			pluginUnderTest.messagePipeline = pipeline
+
+			pluginUnderTest.runLifecycleAction(&NginxLifecycleRequest{
+				correlationId: "123",
+				action:        tt.action,
+				requestedBy:   "agent-api",
+				nginxDetails:  nginxDetails,
+			})
+
+			messages := pipeline.GetMessages()
+			require.Len(t, messages, 1)
+			assert.Equal(t, core.NginxLifecycleComplete, messages[0].Topic())
+
+			status := messages[0].Data().(*NginxLifecycleStatus)
+			assert.Equal(t, "123", status.CorrelationId)
+			assert.Equal(t, "12345", status.NginxId)
+			assert.Equal(t, tt.action, status.Action)
+			assert.Equal(t, tt.expectedStatus, status.Status)
+			assert.Equal(t, tt.expectedMessage, status.Message)
+			assert.NotNil(t, status.CompletedAt)
+
+			var signals []syscall.Signal
+			for _, call := range binary.Calls {
+				if call.Method == "Signal" {
+					signals = append(signals, call.Arguments.Get(1).(syscall.Signal))
+				}
+			}
+			assert.Equal(t, tt.expectedSignals, signals)
+		})
+	}
+}
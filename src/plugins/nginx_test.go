@@ -31,6 +31,7 @@ import (
 	"github.com/nginx/agent/v2/src/core"
 	loadedConfig "github.com/nginx/agent/v2/src/core/config"
 	"github.com/nginx/agent/v2/src/core/payloads"
+	"github.com/nginx/agent/v2/src/core/revisions"
 	tutils "github.com/nginx/agent/v2/test/utils"
 )
 
@@ -771,6 +772,11 @@ func TestNginx_Subscriptions(t *testing.T) {
 		core.NginxConfigValidationSucceeded,
 		core.NginxConfigValidationFailed,
 		core.AgentStarted,
+		core.NginxReload,
+		core.NginxStop,
+		core.NginxStart,
+		core.NginxReopenLogs,
+		core.NginxUpgrade,
 	}
 	pluginUnderTest := NewNginx(nil, nil, tutils.GetMockEnvWithProcess(), &loadedConfig.Config{}, tutils.GetProcesses())
 
@@ -817,7 +823,7 @@ func TestNginx_validateConfig(t *testing.T) {
 			messagePipe := core.SetupMockMessagePipe(t, context.TODO(), []core.Plugin{pluginUnderTest}, []core.ExtensionPlugin{})
 			messagePipe.Run()
 
-			pluginUnderTest.validateConfig(&proto.NginxDetails{}, "123", &proto.NginxConfig{}, &sdk.ConfigApply{})
+			pluginUnderTest.validateConfig(&proto.NginxDetails{}, "123", "", &proto.NginxConfig{}, &sdk.ConfigApply{})
 
 			assert.Eventually(
 				t,
@@ -1224,3 +1230,86 @@ func TestNginx_monitorLog(t *testing.T) {
 		})
 	}
 }
+
+func TestNginx_addConfigRevision(t *testing.T) {
+	conf := &loadedConfig.Config{
+		Features:        []string{agent_config.FeatureNginxConfigAsync},
+		ConfigRevisions: loadedConfig.ConfigRevisions{Enable: true, Path: t.TempDir(), MaxRevisions: 10},
+	}
+
+	env := tutils.GetMockEnv()
+	binary := tutils.NewMockNginxBinary()
+	binary.On("ReadConfig", "/etc/nginx/nginx.conf", "1", mock.Anything).Return(nginxConfigFromFiles(t, map[string]string{"/etc/nginx/nginx.conf": "worker_processes 2;"}), nil)
+
+	pluginUnderTest := NewNginx(tutils.NewMockCommandClient(), binary, env, conf, tutils.GetProcesses())
+	require.NotNil(t, pluginUnderTest.revisionStore)
+
+	pluginUnderTest.addConfigRevision(&NginxConfigValidationResponse{
+		correlationId: "123",
+		appliedBy:     commandChannelAppliedBy,
+		nginxDetails:  &proto.NginxDetails{NginxId: "1", ConfPath: "/etc/nginx/nginx.conf"},
+		config:        &proto.NginxConfig{ConfigData: &proto.ConfigDescriptor{NginxId: "1"}},
+	})
+
+	storedRevisions, err := pluginUnderTest.revisionStore.List("1")
+	require.NoError(t, err)
+	require.Len(t, storedRevisions, 1)
+	assert.Equal(t, "123", storedRevisions[0].CorrelationId)
+	assert.Equal(t, commandChannelAppliedBy, storedRevisions[0].AppliedBy)
+	assert.Equal(t, []string{"/etc/nginx/nginx.conf"}, storedRevisions[0].Files)
+}
+
+func TestNginx_revertConfig(t *testing.T) {
+	revisionsPath := t.TempDir()
+	store, err := revisions.NewStore(revisionsPath, 10)
+	require.NoError(t, err)
+	revision, err := store.Add(revisions.Revision{NginxId: "1"}, nginxConfigFromFiles(t, map[string]string{"/etc/nginx/nginx.conf": "worker_processes 2;"}))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		enable          bool
+		revisionId      string
+		expectedMessage string
+	}{
+		{
+			name:            "config revisions disabled",
+			enable:          false,
+			revisionId:      revision.ID,
+			expectedMessage: configRevisionsDisabled,
+		},
+		{
+			name:            "unknown revision",
+			enable:          true,
+			revisionId:      "123",
+			expectedMessage: "Config rollback failed: config revision not found: 123",
+		},
+		{
+			name:            "stored revision",
+			enable:          true,
+			revisionId:      revision.ID,
+			expectedMessage: "Config apply failed (preflight): no Nginx instance found for 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &loadedConfig.Config{
+				Features:        []string{agent_config.FeatureNginxConfigAsync},
+				ConfigRevisions: loadedConfig.ConfigRevisions{Enable: tt.enable, Path: revisionsPath, MaxRevisions: 10},
+			}
+
+			binary := tutils.NewMockNginxBinary()
+			binary.On("GetNginxDetailsByID", "1").Return((*proto.NginxDetails)(nil))
+
+			pluginUnderTest := NewNginx(tutils.NewMockCommandClient(), binary, tutils.GetMockEnv(), conf, tutils.GetProcesses())
+			pluginUnderTest.messagePipeline = core.NewMockMessagePipe(context.TODO())
+
+			status := pluginUnderTest.revertConfig("456", commandChannelAppliedBy, &proto.ConfigDescriptor{NginxId: "1", Checksum: tt.revisionId})
+
+			assert.Equal(t, proto.NginxConfigAction_ROLLBACK, status.NginxConfigResponse.GetAction())
+			assert.Equal(t, proto.CommandStatusResponse_CMD_ERROR, status.NginxConfigResponse.GetStatus().GetStatus())
+			assert.Equal(t, tt.expectedMessage, status.NginxConfigResponse.GetStatus().GetError())
+		})
+	}
+}
@@ -0,0 +1,218 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	cenkalti_backoff "github.com/cenkalti/backoff/v4"
+	"github.com/nginx/agent/sdk/v2/backoff"
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+	otlp_metrics "github.com/nginx/agent/v2/src/extensions/otlp-metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	otlpExporterPluginName    = "otlp-exporter"
+	otlpExporterPluginVersion = "v0.0.1"
+	otlpServiceName           = "nginx-agent"
+	// the number of batches buffered while the collector is unavailable before the oldest reports are dropped
+	otlpMaxBufferedBatches = 10
+)
+
+// OTLPExporter pushes the metrics reports published on the message pipe to an
+// OpenTelemetry collector over OTLP/gRPC or OTLP/HTTP
+type OTLPExporter struct {
+	pipeline           core.MessagePipeInterface
+	ctx                context.Context
+	ctxCancel          context.CancelFunc
+	client             otlp_metrics.Client
+	resource           *otlp_metrics.Resource
+	collectionInterval time.Duration
+	timeout            time.Duration
+	batchSize          int
+	maxBufferSize      int
+	flushInterval      time.Duration
+	backoffSettings    backoff.BackoffSettings
+	reports            []*proto.MetricsReport
+	flush              chan struct{}
+	mu                 sync.Mutex
+	wg                 sync.WaitGroup
+}
+
+func NewOTLPExporter(conf *config.Config, env core.Environment) (*OTLPExporter, error) {
+	client, err := otlp_metrics.NewClient(conf.OTLP)
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize := conf.OTLP.BatchSize
+	if batchSize <= 0 {
+		batchSize = config.Defaults.OTLP.BatchSize
+	}
+	flushInterval := conf.OTLP.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = config.Defaults.OTLP.FlushInterval
+	}
+	timeout := conf.OTLP.Timeout
+	if timeout <= 0 {
+		timeout = config.Defaults.OTLP.Timeout
+	}
+
+	return &OTLPExporter{
+		client: client,
+		resource: &otlp_metrics.Resource{
+			ServiceName:    otlpServiceName,
+			ServiceVersion: conf.Version,
+			HostName:       env.GetHostname(),
+			HostID:         env.GetSystemUUID(),
+		},
+		collectionInterval: conf.AgentMetrics.CollectionInterval,
+		timeout:            timeout,
+		batchSize:          batchSize,
+		maxBufferSize:      batchSize * otlpMaxBufferedBatches,
+		flushInterval:      flushInterval,
+		backoffSettings:    conf.GetMetricsBackoffSettings(),
+		reports:            make([]*proto.MetricsReport, 0),
+		flush:              make(chan struct{}, 1),
+	}, nil
+}
+
+func (e *OTLPExporter) Init(pipeline core.MessagePipeInterface) {
+	log.Info("OTLPExporter initializing")
+	e.pipeline = pipeline
+	e.ctx, e.ctxCancel = context.WithCancel(pipeline.Context())
+
+	e.wg.Add(1)
+	go e.run()
+}
+
+func (e *OTLPExporter) Close() {
+	log.Info("OTLPExporter is wrapping up")
+	e.ctxCancel()
+	e.wg.Wait()
+
+	if err := e.client.Close(); err != nil {
+		log.Warnf("Error closing the OTLP client: %v", err)
+	}
+}
+
+func (e *OTLPExporter) Info() *core.Info {
+	return core.NewInfo(otlpExporterPluginName, otlpExporterPluginVersion)
+}
+
+func (e *OTLPExporter) Process(msg *core.Message) {
+	if !msg.Exact(core.MetricReport) {
+		return
+	}
+
+	bundle, ok := msg.Data().(*metrics.MetricsReportBundle)
+	if !ok {
+		log.Warnf("Failed to coerce Message to *metrics.MetricsReportBundle: %v", msg.Data())
+		return
+	}
+
+	e.mu.Lock()
+	for _, report := range bundle.Data {
+		if len(report.Data) > 0 {
+			e.reports = append(e.reports, report)
+		}
+	}
+	if dropped := len(e.reports) - e.maxBufferSize; dropped > 0 {
+		log.Warnf("OTLPExporter buffer is full, dropping the %d oldest metrics reports", dropped)
+		e.reports = e.reports[dropped:]
+	}
+	bufferSize := len(e.reports)
+	e.mu.Unlock()
+
+	log.Tracef("OTLPExporter buffer size: %d of %d", bufferSize, e.batchSize)
+	if bufferSize >= e.batchSize {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *OTLPExporter) Subscriptions() []string {
+	return []string{core.MetricReport}
+}
+
+func (e *OTLPExporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+			e.export()
+		case <-e.flush:
+			e.export()
+		}
+	}
+}
+
+// export sends the buffered reports to the collector, retrying with the metrics backoff
+// settings. Reports are put back in the buffer if they could not be sent so they are
+// retried on the next flush, unless the collector rejected them permanently.
+func (e *OTLPExporter) export() {
+	e.mu.Lock()
+	reports := e.reports
+	e.reports = make([]*proto.MetricsReport, 0)
+	e.mu.Unlock()
+
+	if len(reports) == 0 {
+		return
+	}
+
+	request := otlp_metrics.ToExportRequest(e.resource, reports, e.collectionInterval)
+
+	// backoff.WaitUntil unwraps permanent errors, so they are recorded here
+	var permanent *cenkalti_backoff.PermanentError
+	err := backoff.WaitUntil(e.ctx, e.backoffSettings, func() error {
+		ctx, cancel := context.WithTimeout(e.ctx, e.timeout)
+		defer cancel()
+		err := e.client.Export(ctx, request)
+		errors.As(err, &permanent)
+		return err
+	})
+	if err == nil {
+		log.Debugf("OTLPExporter exported %d metrics reports", len(reports))
+		return
+	}
+
+	if e.ctx.Err() != nil {
+		return
+	}
+
+	if permanent != nil {
+		log.Errorf("OTLP collector rejected %d metrics reports, dropping them: %v", len(reports), err)
+		return
+	}
+
+	log.Warnf("Failed to export %d metrics reports over OTLP: %v", len(reports), err)
+
+	e.mu.Lock()
+	e.reports = append(reports, e.reports...)
+	if dropped := len(e.reports) - e.maxBufferSize; dropped > 0 {
+		log.Warnf("OTLPExporter buffer is full, dropping the %d oldest metrics reports", dropped)
+		e.reports = e.reports[dropped:]
+	}
+	e.mu.Unlock()
+}
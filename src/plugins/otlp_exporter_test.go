@@ -0,0 +1,165 @@
+/**
+ * Copyright (c) F5, Inc.
+ *
+ * This source code is licensed under the Apache License, Version 2.0 license found in the
+ * LICENSE file in the root directory of this source tree.
+ */
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/nginx/agent/sdk/v2/proto"
+	"github.com/nginx/agent/v2/src/core"
+	"github.com/nginx/agent/v2/src/core/config"
+	"github.com/nginx/agent/v2/src/core/metrics"
+	tutils "github.com/nginx/agent/v2/test/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collectormetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	requests := make(chan *collectormetrics.ExportMetricsServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		request := &collectormetrics.ExportMetricsServiceRequest{}
+		assert.NoError(t, protobuf.Unmarshal(body, request))
+		requests <- request
+	}))
+	defer server.Close()
+
+	conf := tutils.GetMockAgentConfig()
+	conf.OTLP = config.OTLP{
+		Endpoint:      server.URL,
+		Protocol:      "http",
+		Timeout:       time.Second,
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+	}
+
+	exporter, err := NewOTLPExporter(conf, tutils.GetMockEnv())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pipe := core.NewMockMessagePipe(ctx)
+	pipe.Register(10, []core.Plugin{exporter}, []core.ExtensionPlugin{})
+	pipe.Run()
+	defer exporter.Close()
+
+	assert.Equal(t, []string{core.MetricReport}, exporter.Subscriptions())
+
+	report := func(name string) *proto.MetricsReport {
+		return &proto.MetricsReport{
+			Type: proto.MetricsReport_SYSTEM,
+			Data: []*proto.StatsEntity{{Simplemetrics: []*proto.SimpleMetric{{Name: name, Value: 1}}}},
+		}
+	}
+
+	exporter.Process(core.NewMessage(core.MetricReport, &metrics.MetricsReportBundle{Data: []*proto.MetricsReport{report("system.cpu.idle")}}))
+	exporter.Process(core.NewMessage(core.MetricReport, &metrics.MetricsReportBundle{Data: []*proto.MetricsReport{report("system.io.iops_r")}}))
+
+	select {
+	case request := <-requests:
+		otlpMetrics := request.ResourceMetrics[0].ScopeMetrics[0].Metrics
+		require.Len(t, otlpMetrics, 2)
+		assert.NotNil(t, otlpMetrics[0].GetGauge())
+		assert.NotNil(t, otlpMetrics[1].GetSum())
+	case <-time.After(5 * time.Second):
+		t.Fatal("metrics were not exported")
+	}
+}
+
+func TestOTLPExporter_BufferLimit(t *testing.T) {
+	conf := tutils.GetMockAgentConfig()
+	conf.OTLP = config.OTLP{Endpoint: "127.0.0.1:4317", Protocol: "grpc", BatchSize: 1}
+
+	exporter, err := NewOTLPExporter(conf, tutils.GetMockEnv())
+	require.NoError(t, err)
+	defer exporter.client.Close()
+
+	for i := 0; i < otlpMaxBufferedBatches+5; i++ {
+		exporter.Process(core.NewMessage(core.MetricReport, &metrics.MetricsReportBundle{Data: []*proto.MetricsReport{
+			{Data: []*proto.StatsEntity{{Simplemetrics: []*proto.SimpleMetric{{Name: "system.cpu.idle", Value: float64(i)}}}}},
+		}}))
+	}
+
+	assert.Len(t, exporter.reports, otlpMaxBufferedBatches)
+	assert.Equal(t, float64(5), exporter.reports[0].Data[0].Simplemetrics[0].Value)
+}
+
+type fakeOTLPClient struct {
+	err     error
+	exports int
+}
+
+func (c *fakeOTLPClient) Export(_ context.Context, _ *collectormetrics.ExportMetricsServiceRequest) error {
+	c.exports++
+	return c.err
+}
+
+func (c *fakeOTLPClient) Close() error {
+	return nil
+}
+
+func TestOTLPExporter_export(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		expectedExports int
+		expectedReports int
+	}{
+		{
+			name:            "permanent error",
+			err:             backoff.Permanent(errors.New("OTLP collector rejected 1 data points")),
+			expectedExports: 1,
+			expectedReports: 0,
+		},
+		{
+			name:            "transient error",
+			err:             errors.New("connection refused"),
+			expectedExports: 2,
+			expectedReports: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := tutils.GetMockAgentConfig()
+			conf.OTLP = config.OTLP{Endpoint: "127.0.0.1:4317", Protocol: "grpc", Timeout: time.Second, BatchSize: 2}
+
+			exporter, err := NewOTLPExporter(conf, tutils.GetMockEnv())
+			require.NoError(t, err)
+			require.NoError(t, exporter.client.Close())
+
+			client := &fakeOTLPClient{err: tt.err}
+			exporter.client = client
+			exporter.ctx = context.Background()
+			exporter.backoffSettings.InitialInterval = 10 * time.Millisecond
+			exporter.backoffSettings.MaxElapsedTime = time.Millisecond
+
+			exporter.Process(core.NewMessage(core.MetricReport, &metrics.MetricsReportBundle{Data: []*proto.MetricsReport{
+				{Data: []*proto.StatsEntity{{Simplemetrics: []*proto.SimpleMetric{{Name: "system.cpu.idle", Value: 1}}}}},
+			}}))
+
+			exporter.export()
+			exporter.export()
+
+			assert.Equal(t, tt.expectedExports, client.exports)
+			assert.Len(t, exporter.reports, tt.expectedReports)
+		})
+	}
+}
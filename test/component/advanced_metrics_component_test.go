@@ -714,14 +714,14 @@ func TestAdvancedMetricsIsAbleToCollapseSamplesInPriorityTable(t *testing.T) {
 	wg.Wait()
 }
 
-func assertReceiveMetrics(t *testing.T, outChannel chan []*publisher.MetricSet, expectedMetrics []*publisher.MetricSet) []*publisher.MetricSet {
+func assertReceiveMetrics(t *testing.T, outChannel chan *publisher.MetricsReport, expectedMetrics []*publisher.MetricSet) []*publisher.MetricSet {
 	receivedMessages := make([]*publisher.MetricSet, 0)
 	assert.Eventually(t, func() bool {
 	r_loop:
 		for {
 			select {
 			case f := <-outChannel:
-				receivedMessages = append(receivedMessages, f...)
+				receivedMessages = append(receivedMessages, f.MetricSets...)
 			default:
 				break r_loop
 			}
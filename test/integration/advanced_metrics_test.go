@@ -271,7 +271,8 @@ func TestBasicHttpRequestMetrics(t *testing.T) {
 	}
 
 	select {
-	case metrics := <-advanced_metrics.OutChannel():
+	case report := <-advanced_metrics.OutChannel():
+		metrics := report.MetricSets
 		assert.NotEmpty(t, metrics)
 		assert.Len(t, metrics, 1)
 		validator.AssertMetricSetEqual(t, expectedMetrics, expectedDimensions, metrics[0])
@@ -439,7 +440,8 @@ func TestOutcomRejectedWithWafMetrics(t *testing.T) {
 	}
 
 	select {
-	case metrics := <-advanced_metrics.OutChannel():
+	case report := <-advanced_metrics.OutChannel():
+		metrics := report.MetricSets
 		assert.NotEmpty(t, metrics)
 		assert.Len(t, metrics, 1)
 		validator.AssertMetricSetEqual(t, expectedMetrics, expectedDimensions, metrics[0])
@@ -597,7 +599,8 @@ func TestUrlWithStringFieldEscapeCharacter(t *testing.T) {
 	}
 
 	select {
-	case metrics := <-advanced_metrics.OutChannel():
+	case report := <-advanced_metrics.OutChannel():
+		metrics := report.MetricSets
 		assert.NotEmpty(t, metrics)
 		assert.Len(t, metrics, 1)
 		validator.AssertMetricSetEqual(t, expectedMetrics, expectedDimensions, metrics[0])
@@ -754,7 +757,8 @@ func TestHttpRequestMetricsWithNonZeroLatency(t *testing.T) {
 	}
 
 	select {
-	case metrics := <-advanced_metrics.OutChannel():
+	case report := <-advanced_metrics.OutChannel():
+		metrics := report.MetricSets
 		assert.NotEmpty(t, metrics)
 		assert.Len(t, metrics, 1)
 		validator.AssertMetricSetEqual(t, expectedMetrics, expectedDimensions, metrics[0])
@@ -915,7 +919,8 @@ func TestHttpRequestMetricsWith5kResponseSize(t *testing.T) {
 	}
 
 	select {
-	case metrics := <-advanced_metrics.OutChannel():
+	case report := <-advanced_metrics.OutChannel():
+		metrics := report.MetricSets
 		assert.NotEmpty(t, metrics)
 		assert.Len(t, metrics, 1)
 		validator.AssertMetricSetEqual(t, expectedMetrics, expectedDimensions, metrics[0])
@@ -1019,7 +1024,8 @@ func TestStreamTcpMetrics(t *testing.T) {
 	}
 
 	select {
-	case metrics := <-advanced_metrics.OutChannel():
+	case report := <-advanced_metrics.OutChannel():
+		metrics := report.MetricSets
 		assert.NotEmpty(t, metrics)
 		assert.Len(t, metrics, 1)
 		validator.AssertMetricSetEqual(t, expectedMetrics, expectedDimensions, metrics[0])
@@ -1129,7 +1135,8 @@ func TestStreamUdpMetrics(t *testing.T) {
 	}
 
 	select {
-	case metrics := <-advanced_metrics.OutChannel():
+	case report := <-advanced_metrics.OutChannel():
+		metrics := report.MetricSets
 		assert.NotEmpty(t, metrics)
 		assert.Len(t, metrics, 1)
 		validator.AssertMetricSetEqual(t, expectedMetrics, expectedDimensions, metrics[0])
@@ -1237,7 +1244,8 @@ func TestAppCentricACMDimensions(t *testing.T) {
 	}
 
 	select {
-	case metrics := <-acm.OutChannel():
+	case report := <-acm.OutChannel():
+		metrics := report.MetricSets
 		assert.NotEmpty(t, metrics)
 		assert.Len(t, metrics, 1)
 		validator.AssertDimensionsEqual(t, expectedDimensions, metrics[0])
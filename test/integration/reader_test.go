@@ -24,7 +24,7 @@ func TestReaderIsAbleToHandleMultipleClients(t *testing.T) {
 	source := rand.NewSource(time.Now().Unix())
 	addr := fmt.Sprintf("/tmp/advanced_metrics_reader_test_%d.sr", source.Int63())
 
-	r := reader.NewReader(addr)
+	r := reader.NewReader([]reader.Endpoint{{Network: reader.UnixNetwork, Address: addr}})
 	outChannel := r.OutChannel()
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -85,7 +85,7 @@ func TestReaderIsAbleToHandlePartiallySendFrame(t *testing.T) {
 	source := rand.NewSource(time.Now().Unix())
 	addr := fmt.Sprintf("/tmp/advanced_metrics_reader_test_%d.sr", source.Int63())
 
-	r := reader.NewReader(addr)
+	r := reader.NewReader([]reader.Endpoint{{Network: reader.UnixNetwork, Address: addr}})
 	outChannel := r.OutChannel()
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -133,7 +133,7 @@ func TestReaderIsAbleToCloseOngoingConnections(t *testing.T) {
 	source := rand.NewSource(time.Now().Unix())
 	addr := fmt.Sprintf("/tmp/advanced_metrics_reader_test_%d.sr", source.Int63())
 
-	r := reader.NewReader(addr)
+	r := reader.NewReader([]reader.Endpoint{{Network: reader.UnixNetwork, Address: addr}})
 	outChannel := r.OutChannel()
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -182,7 +182,7 @@ func TestReaderWithGeneratedData(t *testing.T) {
 	source := rand.NewSource(time.Now().Unix())
 	addr := fmt.Sprintf("/tmp/advanced_metrics_reader_test_%d.sr", source.Int63())
 
-	r := reader.NewReader(addr)
+	r := reader.NewReader([]reader.Endpoint{{Network: reader.UnixNetwork, Address: addr}})
 	outChannel := r.OutChannel()
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -178,7 +178,8 @@ func main() {
 		log.Fatal(err)
 	}
 	go func() {
-		for f := range app.OutChannel() {
+		for report := range app.OutChannel() {
+			f := report.MetricSets
 			for _, m := range f {
 				messagesProcessed.Inc()
 				metricsProcessedOnOutput.Add(float64(len(m.Metrics)))
@@ -115,6 +115,11 @@ func (m *MockEnvironment) Processes() (result []*core.Process) {
 	return ret.Get(0).([]*core.Process)
 }
 
+func (m *MockEnvironment) ProcessesByName(namePrefix string) (result []*core.Process) {
+	ret := m.Called(namePrefix)
+	return ret.Get(0).([]*core.Process)
+}
+
 func (m *MockEnvironment) WriteFiles(backup core.ConfigApplyMarker, files []*proto.File, prefix string, allowedDirs map[string]struct{}) error {
 	m.Called(backup, files, prefix, allowedDirs)
 	return nil
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"syscall"
+
 	"github.com/nginx/agent/sdk/v2"
 	"github.com/nginx/agent/sdk/v2/checksum"
 	"github.com/nginx/agent/sdk/v2/proto"
@@ -94,9 +96,9 @@ func (m *MockNginxBinary) ReadConfig(path, nginxId, systemId string) (*proto.Ngi
 	return config, err
 }
 
-func (m *MockNginxBinary) Start(nginxId, bin string) error {
-	m.Called(nginxId, bin)
-	return nil
+func (m *MockNginxBinary) Start(nginxId, bin, confPath string) error {
+	args := m.Called(nginxId, bin, confPath)
+	return args.Error(0)
 }
 
 func (m *MockNginxBinary) Stop(processId, bin string) error {
@@ -109,6 +111,11 @@ func (m *MockNginxBinary) Reload(processId, bin string) error {
 	return nil
 }
 
+func (m *MockNginxBinary) Signal(processId string, signal syscall.Signal) error {
+	args := m.Called(processId, signal)
+	return args.Error(0)
+}
+
 func (m *MockNginxBinary) ValidateConfig(processId, bin, configLocation string, config *proto.NginxConfig, configApply *sdk.ConfigApply) error {
 	args := m.Called(processId, bin, configLocation, config, configApply)
 	return args.Error(0)